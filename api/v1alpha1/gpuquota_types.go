@@ -0,0 +1,39 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GPUQuotaSpec defines the GPU quota of a team.
+type GPUQuotaSpec struct {
+	// MaxGPUs is the total number of GPUs the workspaces of the team can request.
+	// +kubebuilder:validation:Minimum=0
+	MaxGPUs int `json:"maxGPUs"`
+}
+
+// GPUQuota is the Schema for the gpuquotas API. A quota applies to the workspaces in its namespace.
+// If the quota has the team label, it only applies to the workspaces with the same team label.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=gpuquotas,scope=Namespaced
+// +kubebuilder:printcolumn:name="MaxGPUs",type="integer",JSONPath=".spec.maxGPUs",description=""
+type GPUQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GPUQuotaSpec `json:"spec,omitempty"`
+}
+
+// GPUQuotaList contains a list of GPUQuota
+// +kubebuilder:object:root=true
+type GPUQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GPUQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GPUQuota{}, &GPUQuotaList{})
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"knative.dev/pkg/apis"
+)
+
+// SKUAllowListConfigMapName is the name of the ConfigMap in the namespace of kaito that restricts the instance types
+// the workspaces of a namespace may request. Each key is a namespace and its value is a comma separated list of
+// instance types. The workspaces of the namespaces that are not listed may request any instance type.
+const SKUAllowListConfigMapName = "kaito-sku-allowlist"
+
+// SKUAllowList maps a namespace to the instance types its workspaces may request.
+type SKUAllowList map[string][]string
+
+// NewSKUAllowListFromConfigMap parses the SKU allow-list ConfigMap.
+func NewSKUAllowListFromConfigMap(cm *v1.ConfigMap) (SKUAllowList, error) {
+	allowList := SKUAllowList{}
+	for namespace, value := range cm.Data {
+		if msgs := validation.IsDNS1123Label(namespace); len(msgs) != 0 {
+			return nil, fmt.Errorf("invalid namespace %s: %s", namespace, strings.Join(msgs, ", "))
+		}
+		instanceTypes := lo.Compact(lo.Map(strings.Split(value, ","), func(instanceType string, _ int) string {
+			return strings.TrimSpace(instanceType)
+		}))
+		if len(instanceTypes) == 0 {
+			return nil, fmt.Errorf("namespace %s allows no instance type", namespace)
+		}
+		allowList[namespace] = instanceTypes
+	}
+	return allowList, nil
+}
+
+// IsAllowed returns true if the workspaces of the namespace may request the instance type.
+func (l SKUAllowList) IsAllowed(namespace, instanceType string) bool {
+	allowed, restricted := l[namespace]
+	if !restricted {
+		return true
+	}
+	// The instance types of the cloud providers are case insensitive.
+	return lo.ContainsBy(allowed, func(allowedType string) bool {
+		return strings.EqualFold(allowedType, instanceType)
+	})
+}
+
+type skuAllowListKey struct{}
+
+// WithSKUAllowList returns a context that carries the SKU allow-list the workspaces are validated against.
+func WithSKUAllowList(ctx context.Context, allowList SKUAllowList) context.Context {
+	return context.WithValue(ctx, skuAllowListKey{}, allowList)
+}
+
+// skuAllowListFrom returns the SKU allow-list of the context, which is empty if the context carries none.
+func skuAllowListFrom(ctx context.Context) SKUAllowList {
+	allowList, _ := ctx.Value(skuAllowListKey{}).(SKUAllowList)
+	return allowList
+}
+
+// validateAllowedInstanceTypes checks that the namespace of the workspace may request its instance types.
+func (r *ResourceSpec) validateAllowedInstanceTypes(ctx context.Context, namespace string) (errs *apis.FieldError) {
+	allowList := skuAllowListFrom(ctx)
+	if !allowList.IsAllowed(namespace, r.InstanceType) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Instance type %s is not allowed in namespace %s, allowed instance types are %s",
+			r.InstanceType, namespace, strings.Join(allowList[namespace], ", ")), "instanceType"))
+	}
+	for i, instanceType := range r.FallbackInstanceTypes {
+		if !allowList.IsAllowed(namespace, instanceType) {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Instance type %s is not allowed in namespace %s, allowed instance types are %s",
+				instanceType, namespace, strings.Join(allowList[namespace], ", ")), fmt.Sprintf("fallbackInstanceTypes[%d]", i)))
+		}
+	}
+	return errs
+}
@@ -4,9 +4,14 @@
 package v1alpha1
 
 import (
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 type GPUConfig struct {
@@ -29,6 +34,76 @@ func getSupportedSKUs() string {
 	return strings.Join(skus, ", ")
 }
 
+// skusWithGPUs returns the supported SKUs that have at least the given number of GPUs with at least the given memory
+// per GPU, in ascending order of their GPU count.
+func skusWithGPUs(gpuCount int, perGPUMemory int64) []string {
+	var configs []GPUConfig
+	for _, config := range SupportedGPUConfigs {
+		if config.GPUCount >= gpuCount && int64(config.GPUMem/config.GPUCount) >= perGPUMemory {
+			configs = append(configs, config)
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].GPUCount != configs[j].GPUCount {
+			return configs[i].GPUCount < configs[j].GPUCount
+		}
+		return configs[i].SKU < configs[j].SKU
+	})
+	skus := make([]string, 0, len(configs))
+	for _, config := range configs {
+		skus = append(skus, config.SKU)
+	}
+	return skus
+}
+
+// skusWithHostMemory returns the supported SKUs that have at least the given host memory and at least the given
+// number of GPUs with at least the given memory per GPU, in ascending order of their GPU count. The host memory of the
+// SKUs is known by the cloud provider.
+func skusWithHostMemory(hostMemory resource.Quantity, gpuCount int, perGPUMemory int64) []string {
+	return lo.Filter(skusWithGPUs(gpuCount, perGPUMemory), func(sku string, _ int) bool {
+		skuHostMemory := cloudprovider.Default.HostMemory(sku)
+		return skuHostMemory != nil && skuHostMemory.Cmp(hostMemory) >= 0
+	})
+}
+
+// skuSizeRegex splits the name of a SKU into its series, its size and its suffix, e.g., Standard_NC24ads_A100_v4 into
+// Standard_NC, 24 and ads_A100_v4. The SKUs of a family share the series and the suffix.
+var skuSizeRegex = regexp.MustCompile(`^(Standard_[A-Z]+)([0-9]+)(.*)$`)
+
+// largerSKUsInFamily returns the supported SKUs of the family of the SKU that have more GPUs or more GPU memory,
+// in ascending order of their GPU count and GPU memory.
+func largerSKUsInFamily(sku string) []string {
+	current, found := SupportedGPUConfigs[sku]
+	matches := skuSizeRegex.FindStringSubmatch(sku)
+	if !found || matches == nil {
+		return nil
+	}
+	var configs []GPUConfig
+	for _, config := range SupportedGPUConfigs {
+		m := skuSizeRegex.FindStringSubmatch(config.SKU)
+		if m == nil || m[1] != matches[1] || m[3] != matches[3] {
+			continue
+		}
+		if config.GPUCount > current.GPUCount || (config.GPUCount == current.GPUCount && config.GPUMem > current.GPUMem) {
+			configs = append(configs, config)
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].GPUCount != configs[j].GPUCount {
+			return configs[i].GPUCount < configs[j].GPUCount
+		}
+		if configs[i].GPUMem != configs[j].GPUMem {
+			return configs[i].GPUMem < configs[j].GPUMem
+		}
+		return configs[i].SKU < configs[j].SKU
+	})
+	skus := make([]string, 0, len(configs))
+	for _, config := range configs {
+		skus = append(skus, config.SKU)
+	}
+	return skus
+}
+
 var SupportedGPUConfigs = map[string]GPUConfig{
 	"Standard_NC6":      {SKU: "Standard_NC6", GPUCount: 1, GPUMem: 12, SupportedOS: []string{"Ubuntu"}, GPUDriver: "Nvidia470CudaDriver"},
 	"Standard_NC12":     {SKU: "Standard_NC12", GPUCount: 2, GPUMem: 24, SupportedOS: []string{"Ubuntu"}, GPUDriver: "Nvidia470CudaDriver"},
@@ -0,0 +1,161 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"knative.dev/pkg/apis"
+)
+
+// TimeSlicingConfigMapName is the name of the ConfigMap in the namespace of kaito that mirrors the time-slicing
+// configuration of the NVIDIA device plugin. Each key holds a device plugin configuration, e.g., one per node pool,
+// as in the ConfigMap of the GPU operator. The pods of the workspaces are not checked if it does not exist.
+const TimeSlicingConfigMapName = "kaito-time-slicing-config"
+
+// gpuResourceName is the resource the model servers of the presets request their GPUs as.
+const gpuResourceName = v1.ResourceName("nvidia.com/gpu")
+
+// devicePluginConfig is the part of the configuration of the NVIDIA device plugin that configures time-slicing.
+type devicePluginConfig struct {
+	Sharing struct {
+		TimeSlicing struct {
+			RenameByDefault            bool `yaml:"renameByDefault"`
+			FailRequestsGreaterThanOne bool `yaml:"failRequestsGreaterThanOne"`
+			Resources                  []struct {
+				Name     string `yaml:"name"`
+				Rename   string `yaml:"rename"`
+				Replicas int    `yaml:"replicas"`
+			} `yaml:"resources"`
+		} `yaml:"timeSlicing"`
+	} `yaml:"sharing"`
+}
+
+// TimeSlicedResource describes how the device plugin shares a GPU resource.
+type TimeSlicedResource struct {
+	// Replicas is the number of slices each GPU of a node is shared into.
+	Replicas int
+	// FailRequestsGreaterThanOne is true if the device plugin fails the containers that request more than one slice.
+	FailRequestsGreaterThanOne bool
+}
+
+// TimeSlicingConfig maps the GPU resources the device plugin exposes as time-sliced, e.g., nvidia.com/gpu.shared or
+// nvidia.com/gpu if the device plugin does not rename them, to how they are shared.
+type TimeSlicingConfig map[v1.ResourceName]TimeSlicedResource
+
+// NewTimeSlicingConfigFromConfigMap parses the time-slicing ConfigMap. A resource shared by several configurations
+// takes the largest number of replicas and only fails the larger requests if every configuration does, a pod that
+// requests more slices cannot be served by any node.
+func NewTimeSlicingConfigFromConfigMap(cm *v1.ConfigMap) (TimeSlicingConfig, error) {
+	config := TimeSlicingConfig{}
+	for key, value := range cm.Data {
+		devicePlugin := &devicePluginConfig{}
+		if err := yaml.Unmarshal([]byte(value), devicePlugin); err != nil {
+			return nil, fmt.Errorf("invalid device plugin config %s: %w", key, err)
+		}
+		timeSlicing := devicePlugin.Sharing.TimeSlicing
+		for _, resource := range timeSlicing.Resources {
+			if resource.Name == "" || resource.Replicas < 1 {
+				return nil, fmt.Errorf("device plugin config %s must share resource %q into at least 1 replica, got %d", key, resource.Name, resource.Replicas)
+			}
+			// The device plugin exposes the shared GPUs under their own name if it renames them.
+			name := resource.Rename
+			if name == "" && timeSlicing.RenameByDefault {
+				name = resource.Name + ".shared"
+			}
+			if name == "" {
+				name = resource.Name
+			}
+			shared, found := config[v1.ResourceName(name)]
+			if !found {
+				shared.FailRequestsGreaterThanOne = true
+			}
+			if resource.Replicas > shared.Replicas {
+				shared.Replicas = resource.Replicas
+			}
+			shared.FailRequestsGreaterThanOne = shared.FailRequestsGreaterThanOne && timeSlicing.FailRequestsGreaterThanOne
+			config[v1.ResourceName(name)] = shared
+		}
+	}
+	return config, nil
+}
+
+type timeSlicingConfigKey struct{}
+
+// WithTimeSlicingConfig returns a context that carries the time-slicing config the workspaces are validated against.
+func WithTimeSlicingConfig(ctx context.Context, config TimeSlicingConfig) context.Context {
+	return context.WithValue(ctx, timeSlicingConfigKey{}, config)
+}
+
+// timeSlicingConfigFrom returns the time-slicing config of the context, which is empty if the context carries none.
+func timeSlicingConfigFrom(ctx context.Context) TimeSlicingConfig {
+	config, _ := ctx.Value(timeSlicingConfigKey{}).(TimeSlicingConfig)
+	return config
+}
+
+// validateTimeSlicing checks that the pods of the Pod template or of the presets request no more slices of the
+// time-sliced GPUs than the device plugin shares on a node of the instance type, i.e., the replicas of each of its
+// GPUs, and that no container requests more than one slice if the device plugin fails the larger requests. The pods
+// would fail to start on every node otherwise. The number of slices is not checked if the GPUs of the instance type
+// are not known.
+func (i *InferenceSpec) validateTimeSlicing(ctx context.Context, instanceType string) (errs *apis.FieldError) {
+	config := timeSlicingConfigFrom(ctx)
+	if len(config) == 0 {
+		return nil
+	}
+	if i.Template != nil {
+		errs = errs.Also(config.validatePod(i.Template.Spec.Containers, instanceType).ViaField("template.spec.containers"))
+	}
+	for _, preset := range i.presets() {
+		if !plugin.KaitoModelRegister.Has(string(preset.Name)) {
+			continue
+		}
+		// The model server of a preset requests the GPUs of its replica as whole GPUs.
+		gpuCount := resource.MustParse(plugin.KaitoModelRegister.MustGet(string(preset.Name)).GetInferenceParameters().GPUCountRequirement)
+		gpus := resource.NewQuantity(i.GetGPUsPerReplica(gpuCount.Value()), resource.DecimalSI)
+		container := v1.Container{Name: string(preset.Name), Resources: v1.ResourceRequirements{Limits: v1.ResourceList{gpuResourceName: *gpus}}}
+		errs = errs.Also(config.validatePod([]v1.Container{container}, instanceType).ViaField("preset"))
+	}
+	return errs
+}
+
+// validatePod checks the slices of the time-sliced resources that the containers of a pod request.
+func (c TimeSlicingConfig) validatePod(containers []v1.Container, instanceType string) (errs *apis.FieldError) {
+	names := make([]v1.ResourceName, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool { return names[a] < names[b] })
+
+	gpusPerNode := int64(0)
+	if skuConfig, found := SupportedGPUConfigs[instanceType]; found {
+		gpusPerNode = int64(skuConfig.GPUCount)
+	}
+	for _, name := range names {
+		shared := c[name]
+		requested := int64(0)
+		for _, container := range containers {
+			// The limit of an extended resource is its request if the request is not set.
+			quantity, found := container.Resources.Requests[name]
+			if !found {
+				quantity = container.Resources.Limits[name]
+			}
+			if shared.FailRequestsGreaterThanOne && quantity.Value() > 1 {
+				errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("The container %s requests %d slices of the time-sliced resource %s, but the device plugin fails the requests of more than one",
+					container.Name, quantity.Value(), name), apis.CurrentField))
+			}
+			requested += quantity.Value()
+		}
+		if slices := int64(shared.Replicas) * gpusPerNode; gpusPerNode > 0 && requested > slices {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("The pod requests %d slices of the time-sliced resource %s, but the device plugin only shares %d on a node of instance type %s",
+				requested, name, slices, instanceType), apis.CurrentField))
+		}
+	}
+	return errs
+}
@@ -10,12 +10,54 @@ const (
 	// WorkspaceConditionTypeMachineStatus is the state when checking machine status.
 	WorkspaceConditionTypeMachineStatus = ConditionType("MachineReady")
 
+	// WorkspaceConditionTypeNodesLaunching is the state when machines of the workspace have been launched, but their nodes have not joined the cluster yet.
+	WorkspaceConditionTypeNodesLaunching = ConditionType("NodesLaunching")
+
+	// WorkspaceConditionTypeIPExhausted is the state when nodes of the workspace fail to join the cluster because their subnet ran out of IP addresses.
+	WorkspaceConditionTypeIPExhausted = ConditionType("IPExhausted")
+
 	// WorkspaceConditionTypeResourceStatus is the state when Resource has been created.
 	WorkspaceConditionTypeResourceStatus = ConditionType("ResourceReady")
 
 	// WorkspaceConditionTypeInferenceStatus is the state when Inference has been created.
 	WorkspaceConditionTypeInferenceStatus = ConditionType("InferenceReady")
 
+	// WorkspaceConditionTypeTuningJobStatus is the state when the tuning Job has completed.
+	WorkspaceConditionTypeTuningJobStatus = ConditionType("TuningJobCompleted")
+
+	// WorkspaceConditionTypeTuningDatasetValid is the state when the sampled tuning datasets match the schema of the tuning job.
+	WorkspaceConditionTypeTuningDatasetValid = ConditionType("TuningDatasetValid")
+
+	// WorkspaceConditionTypeGPUCapacity is the state when the GPUs of the workspace nodes can run all inference replicas.
+	WorkspaceConditionTypeGPUCapacity = ConditionType("GPUCapacitySufficient")
+
+	// WorkspaceConditionTypePodsScheduled is the state when no inference pod of the workspace has failed to be scheduled for too long.
+	WorkspaceConditionTypePodsScheduled = ConditionType("PodsScheduled")
+
+	// WorkspaceConditionTypeInferenceAvailable is the state when the inference replicas of the workspace are ready to serve.
+	WorkspaceConditionTypeInferenceAvailable = ConditionType("InferenceAvailable")
+
+	// WorkspaceConditionTypeRateLimited is the state when the provisioning of the workspace backs off after it exhausted its retry budget.
+	WorkspaceConditionTypeRateLimited = ConditionType("RateLimited")
+
+	// WorkspaceConditionTypeProvisioningTimeout is the state when the nodes of the workspace were not provisioned within the provisioning timeout.
+	WorkspaceConditionTypeProvisioningTimeout = ConditionType("ProvisioningTimeout")
+
+	// WorkspaceConditionTypeSpecWarnings is the state when the workspace spec is valid but carries risky choices.
+	WorkspaceConditionTypeSpecWarnings = ConditionType("SpecWarnings")
+
+	// WorkspaceConditionTypeGPUResourceAvailable is the state when a node exposes every GPU resource the workspace requests.
+	WorkspaceConditionTypeGPUResourceAvailable = ConditionType("GPUResourceAvailable")
+
+	// WorkspaceConditionTypeReconcilePlan is the state when the reconcile plan of a plan-only workspace has been built but not executed.
+	WorkspaceConditionTypeReconcilePlan = ConditionType("ReconcilePlan")
+
+	// WorkspaceConditionTypeDriverVersion is the state when the NVIDIA driver of the workspace nodes meets the minimum version of the preset.
+	WorkspaceConditionTypeDriverVersion = ConditionType("DriverVersionSupported")
+
+	// WorkspaceConditionTypeInstanceTypeMigrated is the state when every machine of the workspace has been migrated to its instance type.
+	WorkspaceConditionTypeInstanceTypeMigrated = ConditionType("InstanceTypeMigrated")
+
 	//WorkspaceConditionTypeDeleting is the Workspace state when starts to get deleted.
 	WorkspaceConditionTypeDeleting = ConditionType("WorkspaceDeleting")
 
@@ -5,8 +5,31 @@ package v1alpha1
 
 import (
 	"context"
+
+	"github.com/azure/kaito/pkg/utils/plugin"
 )
 
 // SetDefaults for the Workspace
 func (w *Workspace) SetDefaults(_ context.Context) {
+	if w.Resource.Count == nil {
+		count := w.defaultNodeCount()
+		w.Resource.Count = &count
+	}
+}
+
+// defaultNodeCount returns the node count of the inference preset of the workspace, e.g., the nodes a distributed
+// preset is sharded across. It is 1 if the workspace does not run a preset that needs more nodes.
+func (w *Workspace) defaultNodeCount() int {
+	if w.Inference == nil || w.Inference.Preset == nil || !plugin.KaitoModelRegister.Has(string(w.Inference.Preset.Name)) {
+		return 1
+	}
+	params := plugin.KaitoModelRegister.MustGet(string(w.Inference.Preset.Name)).GetInferenceParameters()
+	count := params.DefaultNodeCount
+	if count < params.MinNodeCount {
+		count = params.MinNodeCount
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
 }
@@ -3,6 +3,8 @@
 
 package v1alpha1
 
+import "strings"
+
 const (
 
 	// Non-prefixed labels/annotations are reserved for end-use.
@@ -18,4 +20,71 @@ const (
 
 	// LabelWorkspaceName is the label for workspace namespace.
 	LabelWorkspaceNamespace = KAITOPrefix + "workspacenamespace"
+
+	// AnnotationRDMAEnabled marks the nodes on which the RDMA device plugin should expose RDMA devices.
+	AnnotationRDMAEnabled = KAITOPrefix + "rdma-enabled"
+
+	// LabelWarmPoolPreset is the label for the preset an idle warm pool machine is provisioned for.
+	LabelWarmPoolPreset = KAITOPrefix + "warm-pool-preset"
+
+	// AnnotationOutputUpload carries the state of an upload of the tuning output that runs outside of the tuning job,
+	// the nodes of the workspace are kept while it is OutputUploadInProgress. It is set on the tuning job.
+	AnnotationOutputUpload = KAITOPrefix + "output-upload"
+
+	// LabelTeam is the label for the team a workspace or a GPU quota belongs to.
+	LabelTeam = KAITOPrefix + "team"
+
+	// LabelVariantName is the label for the inference variant that a pod serves.
+	LabelVariantName = KAITOPrefix + "variant"
+
+	// LabelCanary is the label for the canary pod of a canary rollout of the inference workload.
+	LabelCanary = KAITOPrefix + "canary"
+
+	// AnnotationProvisioningParallelism limits how many machines of the provisioner it is set on are provisioned at once.
+	AnnotationProvisioningParallelism = KAITOPrefix + "provisioning-parallelism"
+
+	// AnnotationStableReplicas carries the replicas of the inference workload before its canary took the GPUs of a replica.
+	AnnotationStableReplicas = KAITOPrefix + "stable-replicas"
+
+	// AnnotationZoneSpreadReplaces carries the name of the machine that the machine it is set on replaces to spread the
+	// machines of a workspace across zones. The replaced machine is deleted once the replacement is ready.
+	AnnotationZoneSpreadReplaces = KAITOPrefix + "zone-spread-replaces"
+
+	// AnnotationMigrationReplaces carries the name of the machine of the old instance type that the machine it is set on
+	// replaces while the instance type of the workspace is migrated. The replaced machine is deleted once the replicas
+	// of the workload have moved to the replacement.
+	AnnotationMigrationReplaces = KAITOPrefix + "migration-replaces"
+
+	// AnnotationMigrationReplicas carries the replicas of the inference workload before it was scaled up by a surge
+	// replica for the migration of a machine to the new instance type.
+	AnnotationMigrationReplicas = KAITOPrefix + "migration-replicas"
+
+	// AnnotationPlanOnly makes the workspace it is set to "true" on report its reconcile plan in the ReconcilePlan
+	// condition instead of executing it, nothing in the cluster is changed for the workspace.
+	AnnotationPlanOnly = KAITOPrefix + "plan-only"
+)
+
+// reservedMetadataDomains are the domains whose label and annotation keys, including those of their subdomains, are
+// reserved for kaito, Karpenter and Kubernetes.
+var reservedMetadataDomains = []string{"kaito.sh", "karpenter.sh", "kubernetes.io", "k8s.io"}
+
+// IsReservedMetadataKey returns true if the label or annotation key is reserved for kaito, Karpenter or Kubernetes,
+// e.g., kaito.sh/workspace or node.kubernetes.io/instance-type.
+func IsReservedMetadataKey(key string) bool {
+	prefix, _, found := strings.Cut(key, "/")
+	if !found {
+		return false
+	}
+	for _, domain := range reservedMetadataDomains {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// OutputUploadInProgress and OutputUploadComplete are the values of AnnotationOutputUpload.
+	OutputUploadInProgress = "InProgress"
+	OutputUploadComplete   = "Complete"
 )
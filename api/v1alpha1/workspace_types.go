@@ -5,39 +5,124 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	ModelImageAccessModePublic  ModelImageAccessMode = "public"
 	ModelImageAccessModePrivate ModelImageAccessMode = "private"
+
+	// DefaultInferencePort is the port that the preset model servers listen on.
+	DefaultInferencePort = int32(5000)
+	// AuthProxyPort is the port that the auth proxy of the inference endpoint listens on.
+	AuthProxyPort = int32(5080)
+	// RAGEmbeddingPort is the port that the embedding model sidecar of the RAG setup listens on.
+	RAGEmbeddingPort = int32(5001)
+	// DefaultAuthTokenKey is the key of the bearer token in the auth Secret if the key is not specified.
+	DefaultAuthTokenKey = "token"
+	// MaxDownloadParallelism is the most files of a model that the model server downloads concurrently.
+	MaxDownloadParallelism = 32
+
+	// InferenceRuntimeTransformers serves the preset model with the kaito inference server based on the
+	// Hugging Face transformers library, which supports all presets.
+	InferenceRuntimeTransformers InferenceRuntime = "transformers"
+	// InferenceRuntimeVLLM serves the preset model with the OpenAI compatible server of vLLM.
+	InferenceRuntimeVLLM InferenceRuntime = "vllm"
+	// InferenceRuntimeTGI serves the preset model with Hugging Face Text Generation Inference.
+	InferenceRuntimeTGI InferenceRuntime = "tgi"
+
+	// RolloutStrategyRecreate deletes all inference pods before the new ones are created.
+	RolloutStrategyRecreate RolloutStrategy = "recreate"
+	// RolloutStrategyRolling replaces the inference pods one at a time.
+	RolloutStrategyRolling RolloutStrategy = "rolling"
+	// RolloutStrategyCanary runs a single canary pod of the new version, and only replaces the inference pods one at
+	// a time once the canary is ready.
+	RolloutStrategyCanary RolloutStrategy = "canary"
+
+	// The volumes that kaito adds to the workload pods, which the volumes of the workspace must not collide with.
+	SHMVolumeName             = "dshm"
+	CheckpointVolumeName      = "checkpoint-volume"
+	DataVolumeName            = "data-volume"
+	OutputVolumeName          = "output-volume"
+	ImagePushSecretVolumeName = "image-push-secret"
+	ModelCacheVolumeName      = "model-cache"
 )
 
+// InferenceRuntime is the model server that serves a preset model.
+type InferenceRuntime string
+
+// RolloutStrategy is how the inference pods are replaced when the model image or arguments change.
+type RolloutStrategy string
+
 // ResourceSpec describes the resource requirement of running the workload.
 // If the number of nodes in the cluster that meet the InstanceType and
 // LabelSelector requirements is small than the Count, controller
 // will provision new nodes before deploying the workload.
 // The final list of nodes used to run the workload is presented in workspace Status.
 type ResourceSpec struct {
-	// Count is the required number of GPU nodes.
+	// Count is the required number of GPU nodes. Defaults to the node count of the inference preset, e.g., the nodes
+	// a distributed preset is sharded across, or 1 if not specified. Once set, it may only be decreased, the least busy
+	// nodes are then drained and deleted.
 	// +optional
-	// +kubebuilder:default:=1
 	Count *int `json:"count,omitempty"`
 
+	// MinCount is the minimum number of GPU nodes when the nodes are autoscaled. If MinCount or MaxCount is set,
+	// a HorizontalPodAutoscaler scales the inference pods between them with the in-flight requests of the model server,
+	// and the number of nodes follows the inference pods, including the unschedulable ones. The metric is read from the
+	// custom metrics API, e.g., the Prometheus adapter, and requires a preset that exposes metrics.
+	// Count is the number of nodes provisioned before the inference workload runs. Defaults to Count if not specified.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MinCount *int `json:"minCount,omitempty"`
+
+	// MaxCount is the maximum number of GPU nodes when the nodes are autoscaled. Defaults to Count if not specified.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxCount *int `json:"maxCount,omitempty"`
+
 	// InstanceType specifies the GPU node SKU.
 	// This field defaults to "Standard_NC12s_v3" if not specified.
 	// +optional
 	// +kubebuilder:default:="Standard_NC12s_v3"
 	InstanceType string `json:"instanceType,omitempty"`
 
+	// FallbackInstanceTypes are GPU node SKUs that are used, in order, for new nodes when InstanceType
+	// is not provisionable in the region.
+	// +optional
+	FallbackInstanceTypes []string `json:"fallbackInstanceTypes,omitempty"`
+
 	// LabelSelector specifies the required labels for the GPU nodes.
 	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
 
+	// AllowSKUUpgrade allows the controller to upgrade the InstanceType to the next larger SKU of its family if the
+	// preset models do not fit on it, instead of rejecting the workspace. The upgrade is reported in an event.
+	// +optional
+	AllowSKUUpgrade bool `json:"allowSKUUpgrade,omitempty"`
+
 	// PreferredNodes is an optional node list specified by the user.
 	// If a node in the list does not have the required labels or
 	// the required instanceType, it will be ignored.
 	// +optional
 	PreferredNodes []string `json:"preferredNodes,omitempty"`
+
+	// RDMA specifies whether the GPU nodes require RDMA networking, e.g., InfiniBand for distributed training.
+	// If true, the InstanceType must be an RDMA capable SKU.
+	// +optional
+	RDMA bool `json:"rdma,omitempty"`
+
+	// PriorityClassName is the priority class of the inference and tuning pods, so that important workloads can
+	// preempt others when the GPUs are contended. The priority class must exist in the cluster. The machines have
+	// no priority, so it only applies to the pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// ProvisioningTimeout is how long the nodes of the workspace may take to be provisioned, e.g., 2h. Once it has
+	// passed, the workspace reports the instance types that are stuck in the ProvisioningTimeout condition and the
+	// provisioning is not retried until the spec of the workspace is changed. Defaults to the provisioning timeout of
+	// the controller if not specified.
+	// +optional
+	ProvisioningTimeout *metav1.Duration `json:"provisioningTimeout,omitempty"`
 }
 
 type ModelName string
@@ -54,6 +139,9 @@ type PresetMeta struct {
 	// +kubebuilder:default:="public"
 	// +optional
 	AccessMode ModelImageAccessMode `json:"accessMode,omitempty"`
+	// Version pins the version of the preset model image. The latest supported version is used if not specified.
+	// +optional
+	Version string `json:"version,omitempty"`
 }
 
 type PresetOptions struct {
@@ -63,6 +151,11 @@ type PresetOptions struct {
 	// ImagePullSecrets is a list of secret names in the same namespace used for pulling the model image.
 	// +optional
 	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// Checksum is the hex encoded sha256 checksum of the model weights in the image. If specified, the weights are
+	// verified before the model server starts and the pod fails on a mismatch.
+	// +kubebuilder:validation:Pattern=`^[a-f0-9]{64}$`
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // PresetSpec provides the information for rendering preset configurations to run the model inference service.
@@ -87,6 +180,205 @@ type InferenceSpec struct {
 	// Users can specify multiple adapters for the model and the respective weight of using each of them.
 	// +optional
 	Adapters []AdapterSpec `json:"adapters,omitempty"`
+	// Runtime is the model server that serves the preset model, transformers, vllm or tgi. The runtime selects the
+	// image and the arguments of the model server, and must be supported by the preset. Defaults to transformers.
+	// +kubebuilder:validation:Enum=transformers;vllm;tgi
+	// +optional
+	Runtime InferenceRuntime `json:"runtime,omitempty"`
+	// RolloutStrategy is how the inference pods are replaced when the model image or arguments change, recreate,
+	// rolling or canary. A canary pod of the new version must become ready before the other pods are replaced, so
+	// that a broken version keeps the old pods serving. Canary is only supported for a preset model that runs on a
+	// single node. The default update strategy of the workload is used if not specified.
+	// +kubebuilder:validation:Enum=recreate;rolling;canary
+	// +optional
+	RolloutStrategy RolloutStrategy `json:"rolloutStrategy,omitempty"`
+	// GPUsPerReplica is the number of GPUs that each replica of the preset model requests, e.g., to shard the model
+	// across more GPUs with tensor parallelism. It must not be less than the GPU count required by the preset,
+	// which is used if not specified.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	GPUsPerReplica int `json:"gpusPerReplica,omitempty"`
+	// ProcessesPerGPU is the number of model server processes that share the GPUs of a replica of the preset model,
+	// each process loads its own copy of the model to serve more requests in parallel. The GPUs of the instance type
+	// must fit the memory of the model once per process. Only the transformers runtime supports more than 1 process,
+	// which is used if not specified.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProcessesPerGPU int `json:"processesPerGPU,omitempty"`
+	// DownloadParallelism is the number of files of the preset model, e.g., the shards of a large model, that the
+	// model server downloads concurrently from the Hugging Face Hub before it loads the model. It only applies to the
+	// presets that download their weights with the transformers runtime, the files are downloaded one at a time if
+	// not specified.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=32
+	// +optional
+	DownloadParallelism int `json:"downloadParallelism,omitempty"`
+	// ResourceOverrides are the CPU and memory requests and limits of the model server container of the preset model,
+	// e.g., for models with heavy pre- or post-processing. The GPUs of the container remain managed by kaito.
+	// +optional
+	ResourceOverrides *ResourceOverrides `json:"resourceOverrides,omitempty"`
+	// Port is the port that the model server container listens on. It is used by the container port,
+	// the readiness and liveness probes and the target port of the service. The preset model images listen on port 5000.
+	// +kubebuilder:default:=5000
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// ImagePullPolicy is the pull policy of the image of the model server container, e.g., Never for air-gapped
+	// clusters whose nodes have the image prepulled. If not specified, tagged images are only pulled if not present.
+	// It applies to the containers of the Template that do not specify a pull policy.
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// TerminationGracePeriodSeconds is the time the model server is given to shut down, e.g., to unload a large
+	// model, before it is killed. If not specified, the grace period of the preset is used.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// DNSPolicy is the DNS policy of the inference pods, e.g., None to only resolve names with the DNSConfig.
+	// The cluster DNS is used if not specified.
+	// +kubebuilder:validation:Enum=ClusterFirst;ClusterFirstWithHostNet;Default;None
+	// +optional
+	DNSPolicy v1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// DNSConfig adds the nameservers, search domains and resolver options to the DNS configuration of the inference
+	// pods, e.g., for the models that are fetched from internal endpoints. It is required if DNSPolicy is None.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// Auth requires a bearer token for the requests to the inference endpoint. The requests to the service are
+	// routed through an auth proxy sidecar of the inference pods, which rejects the requests without the token.
+	// It can only be specified for preset models.
+	// +optional
+	Auth *InferenceAuth `json:"auth,omitempty"`
+	// EgressPolicy restricts the egress traffic of the inference pods with a NetworkPolicy, e.g., to keep a model
+	// from reaching the internet. The egress traffic is not restricted if not specified.
+	// +optional
+	EgressPolicy *EgressPolicy `json:"egressPolicy,omitempty"`
+	// Affinity is merged with the node affinity that schedules the inference pods on the GPU nodes of the workspace,
+	// e.g., to co-locate the pods with a vector database by pod affinity. The node selector terms are required
+	// in addition to the GPU node requirements.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// ServiceAccountName is the name of the service account that runs the inference pods, e.g., to fetch the model
+	// from private storage with workload identity. The default service account of the namespace is used if not specified.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// ServiceType is the type of the service that exposes the inference endpoint, ClusterIP or LoadBalancer.
+	// If not specified, a ClusterIP service is created unless the workspace has the kaito.sh/enablelb annotation.
+	// +kubebuilder:validation:Enum=ClusterIP;LoadBalancer
+	// +optional
+	ServiceType v1.ServiceType `json:"serviceType,omitempty"`
+	// ServiceAnnotations are added to the LoadBalancer service, e.g., to configure the load balancer of the cloud provider.
+	// +optional
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+	// TopologySpreadConstraints spread the inference replicas across failure domains. If not specified and
+	// the workspace runs more than one replica, the replicas are spread across zones with a maxSkew of 1.
+	// The label selector of a constraint defaults to the pods of the workspace.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	TopologySpreadConstraints []v1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// Variants serve multiple versions of the preset model behind the service of the workspace, e.g., for A/B testing.
+	// Each variant runs in its own Deployment and receives the share of the traffic given by its weight.
+	// Note that Variants cannot be specified together with Preset or Template.
+	// +optional
+	Variants []PresetVariant `json:"variants,omitempty"`
+	// Volumes are added to the inference pods, e.g., to mount shared datasets from existing persistent volume claims
+	// or configuration from ConfigMaps. Their names must not collide with the volumes that kaito manages.
+	// It can only be specified for preset models, the template specifies its own volumes.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts mount the Volumes into the model server container. It can only be specified for preset models.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+// GetRuntime returns the model server that serves the preset model, or transformers if not specified.
+func (i *InferenceSpec) GetRuntime() InferenceRuntime {
+	if i == nil || i.Runtime == "" {
+		return InferenceRuntimeTransformers
+	}
+	return i.Runtime
+}
+
+// GetGPUsPerReplica returns the number of GPUs that each replica of the preset model requests, or the GPU count
+// required by the preset if not specified.
+func (i *InferenceSpec) GetGPUsPerReplica(presetGPUCount int64) int64 {
+	if i == nil || i.GPUsPerReplica == 0 {
+		return presetGPUCount
+	}
+	return int64(i.GPUsPerReplica)
+}
+
+// ResourceOverrides override the CPU and memory resources of the model server container of a preset model.
+type ResourceOverrides struct {
+	// Requests are the CPU and memory the container requests.
+	// +optional
+	Requests v1.ResourceList `json:"requests,omitempty"`
+	// Limits are the CPU and memory the container is limited to.
+	// +optional
+	Limits v1.ResourceList `json:"limits,omitempty"`
+}
+
+// GetProcessesPerGPU returns the number of model server processes that share the GPUs of a replica, or 1 if not
+// specified.
+func (i *InferenceSpec) GetProcessesPerGPU() int {
+	if i == nil || i.ProcessesPerGPU == 0 {
+		return 1
+	}
+	return i.ProcessesPerGPU
+}
+
+// GetPort returns the port that the model server listens on, or the default port if not specified.
+func (i *InferenceSpec) GetPort() int32 {
+	if i == nil || i.Port == 0 {
+		return DefaultInferencePort
+	}
+	return i.Port
+}
+
+// InferenceAuth references the Secret that holds the bearer token of the inference endpoint.
+type InferenceAuth struct {
+	// SecretName is the name of the Secret in the namespace of the workspace that holds the token. The token may only
+	// contain the characters of a bearer token, i.e., letters, digits and -._~+/=.
+	SecretName string `json:"secretName"`
+	// SecretKey is the key of the token in the Secret.
+	// +kubebuilder:default:=token
+	// +optional
+	SecretKey string `json:"secretKey,omitempty"`
+}
+
+// GetSecretKey returns the key of the token in the Secret, or the default key if not specified.
+func (a *InferenceAuth) GetSecretKey() string {
+	if a.SecretKey == "" {
+		return DefaultAuthTokenKey
+	}
+	return a.SecretKey
+}
+
+// EgressPolicy lists the destinations the inference pods may connect to. The pods of the workspace may always
+// connect to each other, e.g., for distributed inference.
+type EgressPolicy struct {
+	// AllowedCIDRs are the IP blocks the inference pods may connect to, e.g., the address range of the storage
+	// the model weights are fetched from.
+	// +optional
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
+	// AllowDNS allows the DNS queries of the inference pods, which are needed to resolve the names of the services.
+	// +kubebuilder:default:=true
+	// +optional
+	AllowDNS *bool `json:"allowDNS,omitempty"`
+}
+
+// IsDNSAllowed returns true if the inference pods may send DNS queries, which they may unless disabled.
+func (e *EgressPolicy) IsDNSAllowed() bool {
+	return e.AllowDNS == nil || *e.AllowDNS
 }
 
 type AdapterSpec struct {
@@ -99,6 +391,19 @@ type AdapterSpec struct {
 	Strength *string `json:"strength,omitempty"`
 }
 
+// PresetVariant is a version of the preset model that serves a share of the inference traffic of the workspace.
+type PresetVariant struct {
+	// Name identifies the variant. It is appended to the workspace name to name the Deployment and the Service of the variant.
+	Name string `json:"name"`
+	// Preset describes the version of the model that the variant deploys.
+	Preset PresetSpec `json:"preset"`
+	// Weight is the percentage of the inference traffic that the variant serves. The weights of all variants must sum to 100.
+	// The traffic is split by running a number of replicas of each variant proportional to its weight.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+}
+
 type DataSource struct {
 	// The name of the dataset. The same name will be used as a container name.
 	// It must be a valid DNS subdomain value,
@@ -129,6 +434,89 @@ type DataDestination struct {
 	// information that is needed for running `docker push`.
 	// +optional
 	ImagePushSecret string `json:"imagePushSecret,omitempty"`
+	// URL of the object storage location where the output data is uploaded to,
+	// e.g., https://<account>.blob.core.windows.net/<container>/<path>.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// URLSecret is the name of the secret in the same namespace that contains the credentials used to upload
+	// the output data to the URL. The keys of the secret are exposed as environment variables to the uploader,
+	// e.g., AZURE_STORAGE_SAS_TOKEN.
+	// +optional
+	URLSecret string `json:"urlSecret,omitempty"`
+}
+
+// RAGSpec describes the retrieval-augmented generation setup of the inference workload. An embedding model runs as
+// a sidecar of the inference pods, and the model server is configured to retrieve the documents from the vector store.
+// The model server embeds each prompt with the sidecar, retrieves the TopK closest documents from the vector store and
+// prepends them to the prompt. Only the model server of the transformers runtime retrieves the documents.
+type RAGSpec struct {
+	// EmbeddingPreset is the name of the preset embedding model that runs as a sidecar of the inference pods.
+	// It must be a preset that embeds texts.
+	EmbeddingPreset ModelName `json:"embeddingPreset"`
+	// VectorStore describes the connection to the vector store that holds the document embeddings.
+	VectorStore VectorStoreSpec `json:"vectorStore"`
+	// TopK is the number of documents retrieved from the vector store for each request.
+	// +kubebuilder:default:=5
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TopK int `json:"topK,omitempty"`
+}
+
+type VectorStoreSpec struct {
+	// URL is the endpoint of the vector store. The model server posts the embedding of a prompt as the vector, along
+	// with the top_k and the collection, to the /query path of the URL and expects the documents in return.
+	URL string `json:"url"`
+	// Collection is the name of the collection, or index, that holds the documents.
+	// +optional
+	Collection string `json:"collection,omitempty"`
+	// CredentialsSecret is the name of the secret that holds the API key of the vector store in the "apiKey" key.
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+const DefaultModelCacheMountPath = "/mnt/hf-cache"
+
+// ModelCacheSpec describes the Hugging Face cache that the inference and tuning containers share, e.g., across the
+// pods and nodes of the workspace or with other workspaces, so that a model is downloaded only once. HF_HOME and
+// TRANSFORMERS_CACHE of the containers point to the cache.
+type ModelCacheSpec struct {
+	// PersistentVolumeClaim is the name of the persistent volume claim in the same namespace that holds the cache.
+	// The claim must support the ReadWriteMany access mode to be shared across nodes.
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	// MountPath is the path where the cache volume is mounted in the containers.
+	// +kubebuilder:default:="/mnt/hf-cache"
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// Size is the storage requested by the persistent volume claim, which kaito creates if it does not exist.
+	// The claim must exist if Size is not specified.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StorageClassName is the storage class of the persistent volume claim that kaito creates.
+	// The default storage class of the cluster is used if not specified.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// GetMountPath returns the path where the cache volume is mounted.
+func (m *ModelCacheSpec) GetMountPath() string {
+	if m.MountPath == "" {
+		return DefaultModelCacheMountPath
+	}
+	return m.MountPath
+}
+
+// CommonMetadata are the labels and annotations that kaito stamps on all objects it creates for the workspace, i.e.,
+// the machines, the inference and tuning workloads and their pods, the services and the other objects of the
+// workspace, e.g., to apply the cost center or team of the organization. The keys of kaito, Karpenter and
+// Kubernetes, e.g., kaito.sh/workspace, are reserved, and the labels and annotations kaito sets itself take
+// precedence. They only apply to the objects created after they are set.
+type CommonMetadata struct {
+	// Labels are added to the labels of the objects.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the annotations of the objects.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 type TuningMethod string
@@ -138,6 +526,8 @@ const (
 	TuningMethodQLora TuningMethod = "qlora"
 )
 
+const DefaultCheckpointMountPath = "/mnt/checkpoints"
+
 type TuningSpec struct {
 	// Preset describes which model to load for tuning.
 	// +optional
@@ -151,8 +541,49 @@ type TuningSpec struct {
 	Config string `json:"config,omitempty"`
 	// Input describes the input used by the tuning method.
 	Input *DataSource `json:"input"`
+	// ValidateDataset samples the datasets of the Input URLs before the nodes of the tuning job are provisioned and
+	// fails the workspace if a record lacks the context or response column of the DatasetConfig of the tuning
+	// ConfigMap, Context and Response by default. The result is kept in the TuningDatasetValid condition.
+	// Only JSON Lines, JSON and CSV datasets on public addresses are sampled, the datasets in images or host paths
+	// are not validated.
+	// +optional
+	ValidateDataset bool `json:"validateDataset,omitempty"`
 	// Output specified where to store the tuning output.
 	Output *DataDestination `json:"output"`
+	// Checkpoint specifies the persistent volume where the tuning checkpoints are stored.
+	// +optional
+	Checkpoint *CheckpointSpec `json:"checkpoint,omitempty"`
+	// Resume indicates whether an interrupted tuning run continues from the latest checkpoint.
+	// Checkpoint must be specified if Resume is true.
+	// +optional
+	Resume bool `json:"resume,omitempty"`
+	// Volumes are added to the tuning pod, e.g., to mount shared datasets from existing persistent volume claims
+	// or configuration from ConfigMaps. Their names must not collide with the volumes that kaito manages.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts mount the Volumes into the tuning container.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +optional
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+	// CleanupDelay is the time the nodes are kept after the tuning job completes, e.g., for an upload of the output
+	// that runs outside of the job. The nodes are released before the delay has passed once the job is annotated
+	// with kaito.sh/output-upload: Complete, and kept past the delay while it is annotated InProgress.
+	// The nodes are released right away if not specified.
+	// +optional
+	CleanupDelay *metav1.Duration `json:"cleanupDelay,omitempty"`
+}
+
+type CheckpointSpec struct {
+	// PersistentVolumeClaim is the name of the persistent volume claim in the same namespace used to store the checkpoints.
+	// The volume is kept when the tuning workload restarts.
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	// MountPath is the path where the checkpoint volume is mounted in the tuning container.
+	// +kubebuilder:default:="/mnt/checkpoints"
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
 }
 
 // WorkspaceStatus defines the observed state of Workspace
@@ -164,6 +595,45 @@ type WorkspaceStatus struct {
 	// Conditions report the current conditions of the workspace.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ProvisioningRetryBudget tracks the failed attempts to provision the nodes of the workspace.
+	// +optional
+	ProvisioningRetryBudget *ProvisioningRetryBudget `json:"provisioningRetryBudget,omitempty"`
+
+	// ProvisioningProgress is the percentage of the nodes the workspace needs that are ready, from 0 to 100.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ProvisioningProgress *int32 `json:"provisioningProgress,omitempty"`
+
+	// ProvisioningAttempt tracks when the provisioning of the nodes of the workspace started, for the provisioning timeout.
+	// +optional
+	ProvisioningAttempt *ProvisioningAttempt `json:"provisioningAttempt,omitempty"`
+}
+
+// ProvisioningAttempt is the provisioning of the nodes of a generation of the workspace. It is cleared once the
+// nodes are ready.
+type ProvisioningAttempt struct {
+	// ObservedGeneration is the generation of the workspace that the nodes are provisioned for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// StartTime is the time the provisioning of the nodes started.
+	// +optional
+	StartTime metav1.Time `json:"startTime,omitempty"`
+}
+
+// ProvisioningRetryBudget counts the failed provisioning attempts within a window. Once the attempts exhaust
+// the budget, the provisioning backs off until the window has passed or the spec of the workspace is changed.
+type ProvisioningRetryBudget struct {
+	// ObservedGeneration is the generation of the workspace that the attempts were made for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// FailedAttempts is the number of failed provisioning attempts since WindowStart.
+	// +optional
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+	// WindowStart is the time of the first failed provisioning attempt of the window.
+	// +optional
+	WindowStart metav1.Time `json:"windowStart,omitempty"`
 }
 
 // Workspace is the Schema for the workspaces API
@@ -172,6 +642,7 @@ type WorkspaceStatus struct {
 // +kubebuilder:resource:path=workspaces,scope=Namespaced,categories=workspace,shortName={wk,wks}
 // +kubebuilder:storageversion
 // +kubebuilder:printcolumn:name="Instance",type="string",JSONPath=".resource.instanceType",description=""
+// +kubebuilder:printcolumn:name="Progress",type="integer",JSONPath=".status.provisioningProgress",description="Percentage of the nodes that are ready"
 // +kubebuilder:printcolumn:name="ResourceReady",type="string",JSONPath=".status.conditions[?(@.type==\"ResourceReady\")].status",description=""
 // +kubebuilder:printcolumn:name="InferenceReady",type="string",JSONPath=".status.conditions[?(@.type==\"InferenceReady\")].status",description=""
 // +kubebuilder:printcolumn:name="WorkspaceReady",type="string",JSONPath=".status.conditions[?(@.type==\"WorkspaceReady\")].status",description=""
@@ -180,10 +651,17 @@ type Workspace struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Resource  ResourceSpec    `json:"resource,omitempty"`
-	Inference *InferenceSpec  `json:"inference,omitempty"`
-	Tuning    *TuningSpec     `json:"tuning,omitempty"`
-	Status    WorkspaceStatus `json:"status,omitempty"`
+	Resource  ResourceSpec   `json:"resource,omitempty"`
+	Inference *InferenceSpec `json:"inference,omitempty"`
+	Tuning    *TuningSpec    `json:"tuning,omitempty"`
+	RAG       *RAGSpec       `json:"rag,omitempty"`
+	// ModelCache is the Hugging Face cache that the inference and tuning containers share.
+	// +optional
+	ModelCache *ModelCacheSpec `json:"modelCache,omitempty"`
+	// CommonMetadata are the labels and annotations stamped on all objects kaito creates for the workspace.
+	// +optional
+	CommonMetadata *CommonMetadata `json:"commonMetadata,omitempty"`
+	Status         WorkspaceStatus `json:"status,omitempty"`
 }
 
 // WorkspaceList contains a list of Workspace
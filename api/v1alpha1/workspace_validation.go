@@ -6,14 +6,23 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"path"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 	"knative.dev/pkg/apis"
 )
@@ -21,8 +30,15 @@ import (
 const (
 	N_SERIES_PREFIX = "Standard_N"
 	D_SERIES_PREFIX = "Standard_D"
+
+	// maxDNSNameservers and maxDNSSearches are the limits of the DNS config of a pod.
+	maxDNSNameservers = 3
+	maxDNSSearches    = 32
 )
 
+// sha256ChecksumRegex matches a hex encoded sha256 checksum.
+var sha256ChecksumRegex = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
 func (w *Workspace) SupportedVerbs() []admissionregistrationv1.OperationType {
 	return []admissionregistrationv1.OperationType{
 		admissionregistrationv1.Create,
@@ -39,14 +55,27 @@ func (w *Workspace) Validate(ctx context.Context) (errs *apis.FieldError) {
 			w.validateCreate().ViaField("spec"),
 			// TODO: Consider validate resource based on Tuning Spec
 			w.Resource.validateCreate(*w.Inference).ViaField("resource"),
+			w.Resource.validateAllowedInstanceTypes(ctx, w.Namespace).ViaField("resource"),
 		)
 		if w.Inference != nil {
 			// TODO: Add Adapter Spec Validation - Including DataSource Validation for Adapter
-			errs = errs.Also(w.Inference.validateCreate().ViaField("inference"))
+			errs = errs.Also(
+				w.Inference.validateCreate().ViaField("inference"),
+				w.Inference.validateTimeSlicing(ctx, w.Resource.InstanceType).ViaField("inference"),
+			)
 		}
 		if w.Tuning != nil {
 			errs = errs.Also(w.Tuning.validateCreate().ViaField("tuning"))
 		}
+		if w.RAG != nil {
+			errs = errs.Also(w.RAG.validateCreate(w.Inference).ViaField("rag"))
+		}
+		if w.ModelCache != nil {
+			errs = errs.Also(w.ModelCache.validate().ViaField("modelCache"))
+		}
+		if w.CommonMetadata != nil {
+			errs = errs.Also(w.CommonMetadata.validate().ViaField("commonMetadata"))
+		}
 	} else {
 		klog.InfoS("Validate update", "workspace", fmt.Sprintf("%s/%s", w.Namespace, w.Name))
 		old := base.(*Workspace)
@@ -54,17 +83,48 @@ func (w *Workspace) Validate(ctx context.Context) (errs *apis.FieldError) {
 			w.validateUpdate(old).ViaField("spec"),
 			w.Resource.validateUpdate(&old.Resource).ViaField("resource"),
 		)
+		// The workspaces that were created before the namespace was restricted keep their instance types.
+		if w.Resource.InstanceType != old.Resource.InstanceType {
+			errs = errs.Also(w.Resource.validateAllowedInstanceTypes(ctx, w.Namespace).ViaField("resource"))
+		}
 		if w.Inference != nil {
 			// TODO: Add Adapter Spec Validation - Including DataSource Validation for Adapter
 			errs = errs.Also(w.Inference.validateUpdate(old.Inference).ViaField("inference"))
+			// The pods of the existing template keep running if the device plugin was reconfigured since.
+			if old.Inference != nil && (!reflect.DeepEqual(w.Inference.Template, old.Inference.Template) ||
+				w.Resource.InstanceType != old.Resource.InstanceType) {
+				errs = errs.Also(w.Inference.validateTimeSlicing(ctx, w.Resource.InstanceType).ViaField("inference"))
+			}
 		}
 		if w.Tuning != nil {
 			errs = errs.Also(w.Tuning.validateUpdate(old.Tuning).ViaField("tuning"))
 		}
+		if w.RAG != nil {
+			errs = errs.Also(w.RAG.validateCreate(w.Inference).ViaField("rag"))
+		}
+		if w.ModelCache != nil {
+			errs = errs.Also(w.ModelCache.validate().ViaField("modelCache"))
+		}
+		if w.CommonMetadata != nil {
+			errs = errs.Also(w.CommonMetadata.validate().ViaField("commonMetadata"))
+		}
+	}
+	// Warnings are returned to the user but do not block the admission.
+	for _, warning := range w.Warnings() {
+		errs = errs.Also(apis.ErrGeneric(warning).At(apis.WarningLevel))
 	}
 	return errs
 }
 
+// Warnings returns the spec choices that are valid but risky.
+func (w *Workspace) Warnings() []string {
+	var warnings []string
+	if w.Tuning != nil && w.Resource.capacityType() == v1alpha5.CapacityTypeSpot {
+		warnings = append(warnings, "Tuning on spot instances is not recommended, the tuning job loses its progress if the nodes are evicted")
+	}
+	return warnings
+}
+
 func (w *Workspace) validateCreate() (errs *apis.FieldError) {
 	if w.Inference == nil && w.Tuning == nil {
 		errs = errs.Also(apis.ErrGeneric("Either Inference or Tuning must be specified, not neither", ""))
@@ -72,6 +132,44 @@ func (w *Workspace) validateCreate() (errs *apis.FieldError) {
 	if w.Inference != nil && w.Tuning != nil {
 		errs = errs.Also(apis.ErrGeneric("Either Inference or Tuning must be specified, but not both", ""))
 	}
+	if w.RAG != nil && w.Inference == nil {
+		errs = errs.Also(apis.ErrGeneric("RAG can only be specified with Inference", "rag"))
+	}
+	errs = errs.Also(w.validateRegion())
+	errs = errs.Also(w.validateMinNodeCount())
+	errs = errs.Also(w.validateVariantNodeCount())
+	return errs
+}
+
+// validateVariantNodeCount checks that every variant that serves traffic runs at least one replica, one per node.
+func (w *Workspace) validateVariantNodeCount() (errs *apis.FieldError) {
+	if w.Inference == nil || w.Resource.Count == nil {
+		return nil
+	}
+	servingVariants := 0
+	for _, variant := range w.Inference.Variants {
+		if variant.Weight > 0 {
+			servingVariants++
+		}
+	}
+	if *w.Resource.Count < servingVariants {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("count %d must not be less than the number of variants with a weight, %d", *w.Resource.Count, servingVariants), "resource.count"))
+	}
+	return errs
+}
+
+// validateMinNodeCount checks that the count does not override the node count of the inference preset below the
+// minimum of the preset, e.g., a distributed preset that does not fit onto fewer nodes.
+func (w *Workspace) validateMinNodeCount() (errs *apis.FieldError) {
+	if w.Resource.Count == nil || w.Inference == nil || w.Inference.Preset == nil ||
+		!plugin.KaitoModelRegister.Has(string(w.Inference.Preset.Name)) {
+		return nil
+	}
+	minCount := plugin.KaitoModelRegister.MustGet(string(w.Inference.Preset.Name)).GetInferenceParameters().MinNodeCount
+	if *w.Resource.Count < minCount {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("count %d must not be less than %d, the minimum node count of preset %s",
+			*w.Resource.Count, minCount, w.Inference.Preset.Name), "resource.count"))
+	}
 	return errs
 }
 
@@ -83,9 +181,96 @@ func (w *Workspace) validateUpdate(old *Workspace) (errs *apis.FieldError) {
 	if (old.Tuning == nil && w.Tuning != nil) || (old.Tuning != nil && w.Tuning == nil) {
 		errs = errs.Also(apis.ErrGeneric("Tuning field cannot be toggled once set", "tuning"))
 	}
+	// A lowered count must still fit the presets and the variants.
+	if w.Resource.Count != nil && old.Resource.Count != nil && *w.Resource.Count < *old.Resource.Count {
+		errs = errs.Also(w.validateMinNodeCount())
+		errs = errs.Also(w.validateVariantNodeCount())
+	}
+	// The machines of an inference Deployment are migrated to a new instance type without downtime.
+	if w.Resource.InstanceType != old.Resource.InstanceType && !w.supportsInstanceTypeMigration() {
+		errs = errs.Also(apis.ErrGeneric("field is immutable unless the workspace runs the inference as a Deployment", "resource.instanceType"))
+	}
+	return errs
+}
+
+// AllowedRegions returns the regions all presets of the workspace are available in, and whether any preset restricts
+// its regions at all. The regions are empty if the restricted presets have no region in common.
+func (w *Workspace) AllowedRegions() ([]string, bool) {
+	var presetRegions [][]string
+	if w.Inference != nil {
+		for _, preset := range w.Inference.presets() {
+			if plugin.KaitoModelRegister.Has(string(preset.Name)) {
+				params := plugin.KaitoModelRegister.MustGet(string(preset.Name)).GetInferenceParameters()
+				presetRegions = append(presetRegions, params.AllowedRegions)
+			}
+		}
+	}
+	if w.Tuning != nil && w.Tuning.Preset != nil && plugin.KaitoModelRegister.Has(string(w.Tuning.Preset.Name)) {
+		if params := plugin.KaitoModelRegister.MustGet(string(w.Tuning.Preset.Name)).GetTuningParameters(); params != nil {
+			presetRegions = append(presetRegions, params.AllowedRegions)
+		}
+	}
+
+	var regions []string
+	restricted := false
+	for _, allowed := range presetRegions {
+		switch {
+		case len(allowed) == 0:
+			continue
+		case !restricted:
+			regions = allowed
+			restricted = true
+		default:
+			regions = lo.Intersect(regions, allowed)
+		}
+	}
+	return regions, restricted
+}
+
+// validateRegion checks that the presets of the workspace are available in the region required by the labelSelector.
+func (w *Workspace) validateRegion() (errs *apis.FieldError) {
+	regions, restricted := w.AllowedRegions()
+	if !restricted {
+		return nil
+	}
+	if len(regions) == 0 {
+		return errs.Also(apis.ErrGeneric("The presets of the workspace are not available in a common region", "inference"))
+	}
+	if region := w.Resource.region(); region != "" && !lo.Contains(regions, region) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Region %s is not allowed for the presets of the workspace, which are only available in %s",
+			region, strings.Join(regions, ", ")), "resource.labelSelector"))
+	}
 	return errs
 }
 
+// supportsInstanceTypeMigration returns true if the workspace runs the inference as a Deployment, whose replicas
+// can be shifted to the machines of another instance type one at a time.
+func (w *Workspace) supportsInstanceTypeMigration() bool {
+	return w.Inference != nil && w.Tuning == nil && w.Inference.runsAsDeployment()
+}
+
+// runsAsDeployment returns true if the inference runs as a Deployment, i.e., a Pod template or a preset that is not
+// distributed.
+func (i *InferenceSpec) runsAsDeployment() bool {
+	if i.Template != nil {
+		return true
+	}
+	if i.Preset == nil || !plugin.KaitoModelRegister.Has(string(i.Preset.Name)) {
+		return false
+	}
+	return !plugin.KaitoModelRegister.MustGet(string(i.Preset.Name)).SupportDistributedInference()
+}
+
+// UpgradedInstanceType returns the instance type the controller upgrades the workspace to, see
+// ResourceSpec.UpgradedInstanceType. Only the instance type of a workspace that supports the migration of its nodes
+// to another instance type is upgraded, since the webhook rejects the change otherwise.
+func (w *Workspace) UpgradedInstanceType() (string, bool) {
+	if !w.supportsInstanceTypeMigration() {
+		return "", false
+	}
+	return w.Resource.UpgradedInstanceType(*w.Inference)
+}
+
 func (r *TuningSpec) validateCreate() (errs *apis.FieldError) {
 	if r.Input == nil {
 		errs = errs.Also(apis.ErrMissingField("Input"))
@@ -102,11 +287,22 @@ func (r *TuningSpec) validateCreate() (errs *apis.FieldError) {
 		errs = errs.Also(apis.ErrMissingField("Preset"))
 	} else if presetName := string(r.Preset.Name); !isValidPreset(presetName) {
 		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported tuning preset name %s", presetName), "presetName"))
+	} else if version := r.Preset.PresetMeta.Version; version != "" && !plugin.KaitoModelRegister.HasVersion(presetName, version) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported version %s of tuning preset %s", version, presetName), "version"))
 	}
 	methodLowerCase := strings.ToLower(string(r.Method))
 	if methodLowerCase != string(TuningMethodLora) && methodLowerCase != string(TuningMethodQLora) {
 		errs = errs.Also(apis.ErrInvalidValue(r.Method, "Method"))
 	}
+	if r.Checkpoint != nil && r.Checkpoint.PersistentVolumeClaim == "" {
+		errs = errs.Also(apis.ErrMissingField("PersistentVolumeClaim").ViaField("Checkpoint"))
+	} else if r.Resume && r.Checkpoint == nil {
+		errs = errs.Also(apis.ErrGeneric("Checkpoint must be specified to resume tuning", "Checkpoint"))
+	}
+	errs = errs.Also(validateVolumes(r.Volumes, r.VolumeMounts))
+	if r.CleanupDelay != nil && r.CleanupDelay.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("CleanupDelay %s must not be negative", r.CleanupDelay.Duration), "CleanupDelay"))
+	}
 	return errs
 }
 
@@ -128,6 +324,9 @@ func (r *TuningSpec) validateUpdate(old *TuningSpec) (errs *apis.FieldError) {
 	if !reflect.DeepEqual(oldMethod, newMethod) {
 		errs = errs.Also(apis.ErrGeneric("Method cannot be changed", "Method"))
 	}
+	if !reflect.DeepEqual(old.Volumes, r.Volumes) || !reflect.DeepEqual(old.VolumeMounts, r.VolumeMounts) {
+		errs = errs.Also(apis.ErrGeneric("Volumes cannot be changed", "Volumes"))
+	}
 	// Consider supporting config fields changing
 	return errs
 }
@@ -195,11 +394,23 @@ func (r *DataDestination) validateCreate() (errs *apis.FieldError) {
 	}
 	if r.Image != "" {
 		destinationsSpecified++
+		if r.ImagePushSecret == "" {
+			errs = errs.Also(apis.ErrMissingField("ImagePushSecret"))
+		}
+	}
+	if r.URL != "" {
+		destinationsSpecified++
+		if u, err := url.ParseRequestURI(r.URL); err != nil || u.Host == "" {
+			errs = errs.Also(apis.ErrInvalidValue(r.URL, "URL"))
+		}
+		if r.URLSecret == "" {
+			errs = errs.Also(apis.ErrMissingField("URLSecret"))
+		}
 	}
 
 	// If no destination is specified, return an error
 	if destinationsSpecified == 0 {
-		errs = errs.Also(apis.ErrMissingField("At least one of HostPath or Image must be specified"))
+		errs = errs.Also(apis.ErrMissingField("At least one of HostPath, Image or URL must be specified"))
 	}
 	return errs
 }
@@ -215,22 +426,111 @@ func (r *DataDestination) validateUpdate(old *DataDestination) (errs *apis.Field
 	if old.ImagePushSecret != r.ImagePushSecret {
 		errs = errs.Also(apis.ErrInvalidValue("ImagePushSecret field cannot be changed once set", "ImagePushSecret"))
 	}
+	if old.URL != r.URL {
+		errs = errs.Also(apis.ErrInvalidValue("URL field cannot be changed once set", "URL"))
+	}
+	if old.URLSecret != r.URLSecret {
+		errs = errs.Also(apis.ErrInvalidValue("URLSecret field cannot be changed once set", "URLSecret"))
+	}
 	return errs
 }
 
 func (r *ResourceSpec) validateCreate(inference InferenceSpec) (errs *apis.FieldError) {
-	var presetName string
-	if inference.Preset != nil {
-		presetName = strings.ToLower(string(inference.Preset.Name))
+	// An instance type that is too small for the presets is upgraded by the controller if the workspace allows it.
+	if _, upgradable := r.UpgradedInstanceType(inference); !upgradable {
+		errs = errs.Also(r.validateInstanceType(string(r.InstanceType), inference, "instanceType"))
+	}
+	for i, instanceType := range r.FallbackInstanceTypes {
+		field := fmt.Sprintf("fallbackInstanceTypes[%d]", i)
+		if instanceType == r.InstanceType {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Fallback instance type %s is the same as the instance type", instanceType), field))
+			continue
+		}
+		// The same model image runs on the nodes of every instance type.
+		if cloudprovider.Default.Architecture(instanceType) != cloudprovider.Default.Architecture(r.InstanceType) {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Fallback instance type %s has a different CPU architecture than the instance type", instanceType), field))
+			continue
+		}
+		errs = errs.Also(r.validateInstanceType(instanceType, inference, field))
 	}
-	instanceType := string(r.InstanceType)
 
+	// Validate labelSelector
+	if _, err := metav1.LabelSelectorAsMap(r.LabelSelector); err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(err.Error(), "labelSelector"))
+	}
+
+	errs = errs.Also(r.validateProvisioningTimeout())
+
+	errs = errs.Also(r.validateNodeCountRange())
+	errs = errs.Also(r.validateAutoscaling(inference))
+
+	if r.PriorityClassName != "" {
+		if msgs := validation.IsDNS1123Subdomain(r.PriorityClassName); len(msgs) != 0 {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid priority class name %s: %s", r.PriorityClassName, strings.Join(msgs, ", ")), "priorityClassName"))
+		}
+	}
+
+	return errs
+}
+
+// validateProvisioningTimeout checks that the nodes are given time to be provisioned.
+func (r *ResourceSpec) validateProvisioningTimeout() (errs *apis.FieldError) {
+	if r.ProvisioningTimeout != nil && r.ProvisioningTimeout.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("provisioningTimeout %s must be positive", r.ProvisioningTimeout.Duration), "provisioningTimeout"))
+	}
+	return errs
+}
+
+// validateNodeCountRange checks that the node count is within the autoscaling range, i.e., minCount <= count <= maxCount.
+func (r *ResourceSpec) validateNodeCountRange() (errs *apis.FieldError) {
+	if r.Count == nil {
+		return nil
+	}
+	if r.MinCount != nil && *r.MinCount > *r.Count {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("minCount %d must not be greater than count %d", *r.MinCount, *r.Count), "minCount"))
+	}
+	if r.MaxCount != nil && *r.MaxCount < *r.Count {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("maxCount %d must not be less than count %d", *r.MaxCount, *r.Count), "maxCount"))
+	}
+	return errs
+}
+
+// validateAutoscaling checks that the inference of an autoscaling workspace can be scaled with its load, i.e., it runs
+// a single preset that is not distributed and whose model server exposes the in-flight requests on the metrics port.
+func (r *ResourceSpec) validateAutoscaling(inference InferenceSpec) (errs *apis.FieldError) {
+	if r.MinCount == nil && r.MaxCount == nil {
+		return nil
+	}
+	if inference.Preset == nil || inference.Template != nil || len(inference.Variants) != 0 {
+		return errs.Also(apis.ErrGeneric("Autoscaling is only supported for the inference of a single preset", "minCount", "maxCount"))
+	}
+	presetName := string(inference.Preset.Name)
+	if !plugin.KaitoModelRegister.Has(presetName) {
+		return nil // The preset is rejected by the inference validation.
+	}
+	model := plugin.KaitoModelRegister.MustGet(presetName)
+	if model.SupportDistributedInference() {
+		return errs.Also(apis.ErrGeneric(fmt.Sprintf("Autoscaling is not supported for the distributed preset %s", presetName), "minCount", "maxCount"))
+	}
+	if model.GetInferenceParameters().MetricsPort == 0 {
+		return errs.Also(apis.ErrGeneric(fmt.Sprintf("Autoscaling is not supported for the preset %s, which exposes no metrics", presetName), "minCount", "maxCount"))
+	}
+	return errs
+}
+
+// validateInstanceType checks that the instance type is supported and meets the requirements of the preset.
+func (r *ResourceSpec) validateInstanceType(instanceType string, inference InferenceSpec, field string) (errs *apis.FieldError) {
+	// A malformed name, e.g., with a typo, would never be provisioned.
+	if err := cloudprovider.Default.ValidateInstanceType(instanceType); err != nil {
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported instance type %s: %v", instanceType, err), field))
+	}
 	// Check if instancetype exists in our SKUs map
 	if skuConfig, exists := SupportedGPUConfigs[instanceType]; exists {
-		if inference.Preset != nil {
+		for _, preset := range inference.presets() {
+			presetName := strings.ToLower(string(preset.Name))
 			model := plugin.KaitoModelRegister.MustGet(presetName) // InferenceSpec has been validated so the name is valid.
 			// Validate GPU count for given SKU
-			machineCount := *r.Count
+			machineCount := lo.FromPtr(r.Count)
 			totalNumGPUs := machineCount * skuConfig.GPUCount
 			totalGPUMem := machineCount * skuConfig.GPUMem * skuConfig.GPUCount
 
@@ -239,40 +539,97 @@ func (r *ResourceSpec) validateCreate(inference InferenceSpec) (errs *apis.Field
 			modelTotalGPUMemory := resource.MustParse(model.GetInferenceParameters().TotalGPUMemoryRequirement)
 
 			// Separate the checks for specific error messages
-			if int64(totalNumGPUs) < modelGPUCount.Value() {
-				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient number of GPUs: Instance type %s provides %d, but preset %s requires at least %d", instanceType, totalNumGPUs, presetName, modelGPUCount.Value()), "instanceType"))
+			if !model.SupportDistributedInference() {
+				// Each replica of a single node model runs on one node, the GPUs of the other nodes do not add up.
+				gpusPerReplica := inference.GetGPUsPerReplica(modelGPUCount.Value())
+				if int64(skuConfig.GPUCount) < gpusPerReplica {
+					errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient number of GPUs: Instance type %s provides %d per node, but preset %s runs on a single node and requires at least %d, "+
+						"use an instance type with at least %d GPUs, e.g., %s, and set the count to the number of replicas", instanceType, skuConfig.GPUCount, presetName, gpusPerReplica,
+						gpusPerReplica, strings.Join(lo.Slice(skusWithGPUs(int(gpusPerReplica), modelPerGPUMemory.ScaledValue(resource.Giga)), 0, 3), ", ")), field))
+				}
+			} else if int64(totalNumGPUs) < modelGPUCount.Value() {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient number of GPUs: Instance type %s provides %d, but preset %s requires at least %d", instanceType, totalNumGPUs, presetName, modelGPUCount.Value()), field))
 			}
 			skuPerGPUMemory := skuConfig.GPUMem / skuConfig.GPUCount
-			if int64(skuPerGPUMemory) < modelPerGPUMemory.ScaledValue(resource.Giga) {
-				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient per GPU memory: Instance type %s provides %d per GPU, but preset %s requires at least %d per GPU", instanceType, skuPerGPUMemory, presetName, modelPerGPUMemory.ScaledValue(resource.Giga)), "instanceType"))
+			// Each model server process loads its own copy of the model onto the GPUs.
+			processes := int64(inference.GetProcessesPerGPU())
+			if processes > 1 && int64(skuPerGPUMemory) < processes*modelPerGPUMemory.ScaledValue(resource.Giga) {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("GPU memory over-subscribed: Instance type %s provides %d per GPU, but %d processes of preset %s require %d per GPU", instanceType, skuPerGPUMemory, processes, presetName, processes*modelPerGPUMemory.ScaledValue(resource.Giga)), field))
+			} else if int64(skuPerGPUMemory) < modelPerGPUMemory.ScaledValue(resource.Giga) {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient per GPU memory: Instance type %s provides %d per GPU, but preset %s requires at least %d per GPU", instanceType, skuPerGPUMemory, presetName, modelPerGPUMemory.ScaledValue(resource.Giga)), field))
 			}
 			if int64(totalGPUMem) < modelTotalGPUMemory.ScaledValue(resource.Giga) {
-				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient total GPU memory: Instance type %s has a total of %d, but preset %s requires at least %d", instanceType, totalGPUMem, presetName, modelTotalGPUMemory.ScaledValue(resource.Giga)), "instanceType"))
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient total GPU memory: Instance type %s has a total of %d, but preset %s requires at least %d", instanceType, totalGPUMem, presetName, modelTotalGPUMemory.ScaledValue(resource.Giga)), field))
+			}
+			if minHostMemory := model.GetInferenceParameters().MinHostMemory; minHostMemory != "" {
+				if hostMemory := cloudprovider.Default.HostMemory(instanceType); hostMemory != nil && hostMemory.Cmp(resource.MustParse(minHostMemory)) < 0 {
+					errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Insufficient host memory: Instance type %s provides %s, but preset %s requires at least %s to convert its weights, "+
+						"use an instance type with more memory, e.g., %s", instanceType, hostMemory.String(), presetName, minHostMemory,
+						strings.Join(lo.Slice(skusWithHostMemory(resource.MustParse(minHostMemory), skuConfig.GPUCount, int64(skuPerGPUMemory)), 0, 3), ", ")), field))
+				}
 			}
 		}
 	} else {
 		// Check for other instancetypes pattern matches
 		if !strings.HasPrefix(instanceType, N_SERIES_PREFIX) && !strings.HasPrefix(instanceType, D_SERIES_PREFIX) {
-			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported instance type %s. Supported SKUs: %s", instanceType, getSupportedSKUs()), "instanceType"))
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported instance type %s. Supported SKUs: %s", instanceType, getSupportedSKUs()), field))
 		}
 	}
 
-	// Validate labelSelector
-	if _, err := metav1.LabelSelectorAsMap(r.LabelSelector); err != nil {
-		errs = errs.Also(apis.ErrInvalidValue(err.Error(), "labelSelector"))
+	if r.RDMA && !cloudprovider.Default.SupportsRDMA(instanceType) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Instance type %s does not support RDMA", instanceType), field))
 	}
-
 	return errs
 }
 
+// UpgradedInstanceType returns the smallest larger SKU of the family of the instance type that fits the presets of
+// the inference, if the upgrade is allowed and the presets do not fit on the instance type. The inference must run as
+// a Deployment, whose nodes can be migrated to the upgraded instance type.
+func (r *ResourceSpec) UpgradedInstanceType(inference InferenceSpec) (string, bool) {
+	if !r.AllowSKUUpgrade || r.Count == nil || len(inference.presets()) == 0 || !inference.runsAsDeployment() {
+		return "", false
+	}
+	if r.validateInstanceType(r.InstanceType, inference, "instanceType") == nil {
+		return "", false
+	}
+	for _, sku := range largerSKUsInFamily(r.InstanceType) {
+		if r.validateInstanceType(sku, inference, "instanceType") == nil {
+			return sku, true
+		}
+	}
+	return "", false
+}
+
+// capacityType returns the capacity type required by the labelSelector, if any.
+func (r *ResourceSpec) capacityType() string {
+	if r.LabelSelector == nil {
+		return ""
+	}
+	return r.LabelSelector.MatchLabels[cloudprovider.Default.CapacityTypeLabel()]
+}
+
+// region returns the region required by the labelSelector, if any.
+func (r *ResourceSpec) region() string {
+	if r.LabelSelector == nil {
+		return ""
+	}
+	return r.LabelSelector.MatchLabels[v1.LabelTopologyRegion]
+}
+
 func (r *ResourceSpec) validateUpdate(old *ResourceSpec) (errs *apis.FieldError) {
-	// We disable changing node count for now.
-	if r.Count != nil && old.Count != nil && *r.Count != *old.Count {
-		errs = errs.Also(apis.ErrGeneric("field is immutable", "count"))
+	// The count may be lowered, the controller then drains and deletes the least busy nodes. New nodes are added by
+	// raising the maximum count of an autoscaling workspace.
+	if r.Count != nil && old.Count != nil && *r.Count > *old.Count {
+		errs = errs.Also(apis.ErrGeneric("field is immutable, it may only be decreased", "count"))
 	}
-	if r.InstanceType != old.InstanceType {
-		errs = errs.Also(apis.ErrGeneric("field is immutable", "instanceType"))
+	if !reflect.DeepEqual(r.FallbackInstanceTypes, old.FallbackInstanceTypes) {
+		errs = errs.Also(apis.ErrGeneric("field is immutable", "fallbackInstanceTypes"))
 	}
+	if r.RDMA != old.RDMA {
+		errs = errs.Also(apis.ErrGeneric("field is immutable", "rdma"))
+	}
+	errs = errs.Also(r.validateNodeCountRange())
+	errs = errs.Also(r.validateProvisioningTimeout())
 	newLabels, err0 := metav1.LabelSelectorAsMap(r.LabelSelector)
 	oldLabels, err1 := metav1.LabelSelectorAsMap(old.LabelSelector)
 	if err0 != nil || err1 != nil {
@@ -285,9 +642,85 @@ func (r *ResourceSpec) validateUpdate(old *ResourceSpec) (errs *apis.FieldError)
 	return errs
 }
 
+// validateCreate checks that the embedding preset embeds texts and that the model server of the inference can retrieve
+// the documents, i.e., that it is served by the transformers runtime and does not listen on the port of the sidecar.
+func (r *RAGSpec) validateCreate(inference *InferenceSpec) (errs *apis.FieldError) {
+	if r.EmbeddingPreset == "" {
+		errs = errs.Also(apis.ErrMissingField("embeddingPreset"))
+	} else if presetName := string(r.EmbeddingPreset); !isValidPreset(presetName) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported embedding preset name %s", presetName), "embeddingPreset"))
+	} else if !plugin.KaitoModelRegister.MustGet(presetName).GetInferenceParameters().Embedding {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Preset %s is not an embedding model", presetName), "embeddingPreset"))
+	}
+	if inference != nil {
+		if inference.Preset != nil && inference.GetRuntime() != InferenceRuntimeTransformers {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("RAG is not supported by the %s runtime, only the model server of the %s runtime retrieves the documents",
+				inference.GetRuntime(), InferenceRuntimeTransformers)))
+		}
+		if inference.GetPort() == RAGEmbeddingPort {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("The inference port %d is the port of the embedding sidecar", RAGEmbeddingPort)))
+		}
+	}
+	if r.VectorStore.URL == "" {
+		errs = errs.Also(apis.ErrMissingField("vectorStore.url"))
+	} else if _, err := url.ParseRequestURI(r.VectorStore.URL); err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid vector store URL %s: %v", r.VectorStore.URL, err), "vectorStore.url"))
+	}
+	if r.TopK < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("TopK %d must be positive", r.TopK), "topK"))
+	}
+	return errs
+}
+
+// validate checks that the cache references a persistent volume claim by a valid name, which is requested with
+// a positive size if kaito creates it, and is mounted at an absolute path.
+func (m *ModelCacheSpec) validate() (errs *apis.FieldError) {
+	if m.PersistentVolumeClaim == "" {
+		errs = errs.Also(apis.ErrMissingField("persistentVolumeClaim"))
+	} else if msgs := validation.IsDNS1123Subdomain(m.PersistentVolumeClaim); len(msgs) != 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid persistent volume claim name %s: %s", m.PersistentVolumeClaim,
+			strings.Join(msgs, ", ")), "persistentVolumeClaim"))
+	}
+	if m.MountPath != "" && !path.IsAbs(m.MountPath) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Mount path %s must be absolute", m.MountPath), "mountPath"))
+	}
+	if m.Size != nil && m.Size.Sign() <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Size %s must be positive", m.Size.String()), "size"))
+	}
+	if m.StorageClassName != nil && m.Size == nil {
+		errs = errs.Also(apis.ErrGeneric("StorageClassName only applies to the persistent volume claim that kaito creates, Size must be specified",
+			"storageClassName"))
+	}
+	return errs
+}
+
+// validate checks that the common labels and annotations are valid and that none of their keys is reserved.
+func (m *CommonMetadata) validate() (errs *apis.FieldError) {
+	for _, key := range lo.Keys(m.Labels) {
+		if IsReservedMetadataKey(key) {
+			errs = errs.Also(apis.ErrInvalidKeyName(key, "labels", "the key is reserved for kaito, Karpenter and Kubernetes"))
+			continue
+		}
+		msgs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(m.Labels[key])...)
+		if len(msgs) != 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(key, "labels", msgs...))
+		}
+	}
+	for _, key := range lo.Keys(m.Annotations) {
+		if IsReservedMetadataKey(key) {
+			errs = errs.Also(apis.ErrInvalidKeyName(key, "annotations", "the key is reserved for kaito, Karpenter and Kubernetes"))
+			continue
+		}
+		if msgs := validation.IsQualifiedName(strings.ToLower(key)); len(msgs) != 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(key, "annotations", msgs...))
+		}
+	}
+	return errs
+}
+
 func (i *InferenceSpec) validateCreate() (errs *apis.FieldError) {
 	// Check if both Preset and Template are not set
-	if i.Preset == nil && i.Template == nil {
+	if i.Preset == nil && i.Template == nil && len(i.Variants) == 0 {
 		errs = errs.Also(apis.ErrMissingField("Preset or Template must be specified"))
 	}
 
@@ -296,26 +729,400 @@ func (i *InferenceSpec) validateCreate() (errs *apis.FieldError) {
 		errs = errs.Also(apis.ErrGeneric("Preset and Template cannot be set at the same time"))
 	}
 
+	if len(i.Variants) != 0 && (i.Preset != nil || i.Template != nil) {
+		errs = errs.Also(apis.ErrGeneric("Variants cannot be set together with Preset or Template", "variants"))
+	}
+
 	if i.Preset != nil {
-		presetName := string(i.Preset.Name)
-		// Validate preset name
-		if !isValidPreset(presetName) {
-			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported inference preset name %s", presetName), "presetName"))
+		errs = errs.Also(i.Preset.validateCreate())
+	}
+	errs = errs.Also(validateVariants(i.Variants))
+	if i.Port < 0 || i.Port > 65535 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Port %d is out of the valid range 1-65535", i.Port), "port"))
+	}
+	switch i.ImagePullPolicy {
+	case "", v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported image pull policy %s, must be Always, IfNotPresent or Never", i.ImagePullPolicy), "imagePullPolicy"))
+	}
+	if i.TerminationGracePeriodSeconds != nil && *i.TerminationGracePeriodSeconds < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("TerminationGracePeriodSeconds %d must not be negative", *i.TerminationGracePeriodSeconds), "terminationGracePeriodSeconds"))
+	}
+	errs = errs.Also(i.validateRuntime())
+	errs = errs.Also(i.validateGPUsPerReplica())
+	errs = errs.Also(i.validateProcessesPerGPU())
+	errs = errs.Also(i.validateDownloadParallelism())
+	errs = errs.Also(i.validateResourceOverrides())
+	errs = errs.Also(i.validateRolloutStrategy())
+	errs = errs.Also(i.validateAuth())
+	errs = errs.Also(validateDNS(i.DNSPolicy, i.DNSConfig))
+	errs = errs.Also(i.EgressPolicy.validate())
+	if i.Template != nil && (len(i.Volumes) != 0 || len(i.VolumeMounts) != 0) {
+		errs = errs.Also(apis.ErrGeneric("volumes can only be specified for preset models, the template specifies its own volumes", "volumes"))
+	}
+	errs = errs.Also(validateVolumes(i.Volumes, i.VolumeMounts))
+	if i.ServiceAccountName != "" {
+		if msgs := validation.IsDNS1123Subdomain(i.ServiceAccountName); len(msgs) != 0 {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid service account name %s: %s", i.ServiceAccountName, strings.Join(msgs, ", ")), "serviceAccountName"))
+		}
+	}
+	switch i.ServiceType {
+	case "", v1.ServiceTypeClusterIP, v1.ServiceTypeLoadBalancer:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported service type %s, must be ClusterIP or LoadBalancer", i.ServiceType), "serviceType"))
+	}
+	if len(i.ServiceAnnotations) != 0 && i.ServiceType != v1.ServiceTypeLoadBalancer {
+		errs = errs.Also(apis.ErrGeneric("serviceAnnotations can only be specified for a LoadBalancer service", "serviceAnnotations"))
+	}
+	return errs
+}
+
+func (p *PresetSpec) validateCreate() (errs *apis.FieldError) {
+	presetName := string(p.Name)
+	// Validate preset name
+	if !isValidPreset(presetName) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported inference preset name %s", presetName), "presetName"))
+	} else if version := p.PresetMeta.Version; version != "" && !plugin.KaitoModelRegister.HasVersion(presetName, version) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported version %s of inference preset %s", version, presetName), "version"))
+	}
+	// Validate private preset has private image specified
+	if plugin.KaitoModelRegister.MustGet(presetName).GetInferenceParameters().ImageAccessMode == "private" &&
+		p.PresetMeta.AccessMode != "private" {
+		errs = errs.Also(apis.ErrGeneric("This preset only supports private AccessMode, AccessMode must be private to continue"))
+	}
+	// Additional validations for Preset
+	if p.PresetMeta.AccessMode == "private" && p.PresetOptions.Image == "" {
+		errs = errs.Also(apis.ErrGeneric("When AccessMode is private, an image must be provided in PresetOptions"))
+	}
+	// Note: we don't enforce private access mode to have image secrets, in case anonymous pulling is enabled
+	if checksum := p.PresetOptions.Checksum; checksum != "" && !sha256ChecksumRegex.MatchString(checksum) {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid checksum %s, must be a hex encoded sha256 checksum", checksum), "checksum"))
+	}
+	return errs
+}
+
+// validateDNS checks the DNS policy and config of the inference pods against the limits of the kubelet, i.e.,
+// at most 3 nameservers and 32 search domains, so that the pods are not rejected after the workspace is accepted.
+func validateDNS(policy v1.DNSPolicy, config *v1.PodDNSConfig) (errs *apis.FieldError) {
+	switch policy {
+	case "", v1.DNSClusterFirst, v1.DNSClusterFirstWithHostNet, v1.DNSDefault, v1.DNSNone:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported DNS policy %s, must be ClusterFirst, ClusterFirstWithHostNet, Default or None", policy), "dnsPolicy"))
+	}
+	if config == nil {
+		if policy == v1.DNSNone {
+			errs = errs.Also(apis.ErrMissingField("dnsConfig"))
+		}
+		return errs
+	}
+	if policy == v1.DNSNone && len(config.Nameservers) == 0 {
+		errs = errs.Also(apis.ErrGeneric("At least one nameserver must be specified if the DNS policy is None", "dnsConfig.nameservers"))
+	}
+	if len(config.Nameservers) > maxDNSNameservers {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("At most %d nameservers can be specified", maxDNSNameservers), "dnsConfig.nameservers"))
+	}
+	for _, nameserver := range config.Nameservers {
+		if net.ParseIP(nameserver) == nil {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Nameserver %s must be an IP address", nameserver), "dnsConfig.nameservers"))
+		}
+	}
+	if len(config.Searches) > maxDNSSearches {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("At most %d search domains can be specified", maxDNSSearches), "dnsConfig.searches"))
+	}
+	for _, search := range config.Searches {
+		// A search domain may be fully qualified, i.e., end with a dot.
+		if msgs := validation.IsDNS1123Subdomain(strings.TrimSuffix(search, ".")); len(msgs) != 0 {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid search domain %s: %s", search, strings.Join(msgs, ", ")), "dnsConfig.searches"))
+		}
+	}
+	for _, option := range config.Options {
+		if option.Name == "" {
+			errs = errs.Also(apis.ErrMissingField("dnsConfig.options.name"))
+		}
+	}
+	return errs
+}
+
+// kaitoVolumeNames are the names of the volumes that kaito adds to the workload pods.
+var kaitoVolumeNames = []string{SHMVolumeName, CheckpointVolumeName, DataVolumeName, OutputVolumeName, ImagePushSecretVolumeName,
+	ModelCacheVolumeName}
+
+// validateVolumes checks that the volumes of the workspace have unique names that do not collide with the volumes
+// kaito manages, and that the volume mounts reference them at distinct paths.
+func validateVolumes(volumes []v1.Volume, volumeMounts []v1.VolumeMount) (errs *apis.FieldError) {
+	names := map[string]bool{}
+	for _, volume := range volumes {
+		switch {
+		case lo.Contains(kaitoVolumeNames, volume.Name):
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Volume name %s collides with a volume that kaito manages", volume.Name), "volumes.name"))
+		case names[volume.Name]:
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Volume name %s is not unique", volume.Name), "volumes.name"))
+		default:
+			if msgs := validation.IsDNS1123Label(volume.Name); len(msgs) != 0 {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid volume name %s: %s", volume.Name, strings.Join(msgs, ", ")), "volumes.name"))
+			}
+		}
+		names[volume.Name] = true
+	}
+	mountPaths := map[string]bool{}
+	for _, volumeMount := range volumeMounts {
+		if !names[volumeMount.Name] {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Volume mount %s does not reference a volume of the workspace", volumeMount.Name), "volumeMounts.name"))
+		}
+		if volumeMount.MountPath == "" {
+			errs = errs.Also(apis.ErrMissingField("volumeMounts.mountPath"))
+		} else if mountPaths[volumeMount.MountPath] {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Mount path %s is not unique", volumeMount.MountPath), "volumeMounts.mountPath"))
+		}
+		mountPaths[volumeMount.MountPath] = true
+	}
+	return errs
+}
+
+// validate checks that the allowed destinations of the egress policy are CIDRs, e.g., 10.0.0.0/16.
+func (e *EgressPolicy) validate() (errs *apis.FieldError) {
+	if e == nil {
+		return nil
+	}
+	for i, cidr := range e.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid CIDR %s: %v", cidr, err), fmt.Sprintf("egressPolicy.allowedCIDRs[%d]", i)))
+		}
+	}
+	return errs
+}
+
+// validateVariants checks that the variants have unique names, run their presets as Deployments, and split
+// the whole inference traffic, i.e., their weights sum to 100.
+func validateVariants(variants []PresetVariant) (errs *apis.FieldError) {
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(variants) < 2 {
+		errs = errs.Also(apis.ErrInvalidValue("At least two variants must be specified to split the traffic", "variants"))
+	}
+	names := map[string]bool{}
+	var totalWeight int32
+	for idx, variant := range variants {
+		if msgs := validation.IsDNS1123Label(variant.Name); len(msgs) != 0 {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid variant name %s: %s", variant.Name, strings.Join(msgs, ", ")), "name").ViaFieldIndex("variants", idx))
+		} else if names[variant.Name] {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Duplicate variant name %s", variant.Name), "name").ViaFieldIndex("variants", idx))
+		}
+		names[variant.Name] = true
+
+		presetErrs := variant.Preset.validateCreate()
+		presetName := string(variant.Preset.Name)
+		if presetErrs == nil && plugin.KaitoModelRegister.MustGet(presetName).SupportDistributedInference() {
+			presetErrs = apis.ErrInvalidValue(fmt.Sprintf("Preset %s runs distributed inference, which is not supported by variants", presetName), "presetName")
+		}
+		errs = errs.Also(presetErrs.ViaField("preset").ViaFieldIndex("variants", idx))
+
+		if variant.Weight < 0 || variant.Weight > 100 {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Weight %d is out of the valid range 0-100", variant.Weight), "weight").ViaFieldIndex("variants", idx))
+		}
+		totalWeight += variant.Weight
+	}
+	if totalWeight != 100 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("The weights of the variants sum to %d, must sum to 100", totalWeight), "variants"))
+	}
+	return errs
+}
+
+// presets returns the preset of the inference, or the presets of its variants.
+// validateRuntime checks that the presets can be served with the runtime. The transformers runtime supports all
+// presets, the other runtimes need their own model images, which the preset must provide.
+func (i *InferenceSpec) validateRuntime() (errs *apis.FieldError) {
+	runtime := i.GetRuntime()
+	switch runtime {
+	case InferenceRuntimeTransformers:
+		return nil
+	case InferenceRuntimeVLLM, InferenceRuntimeTGI:
+	default:
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported runtime %s, must be transformers, vllm or tgi", runtime), "runtime"))
+	}
+	if i.Template != nil {
+		errs = errs.Also(apis.ErrGeneric("runtime can only be specified for preset models, the template specifies its own model server", "runtime"))
+	}
+	for _, preset := range i.presets() {
+		presetName := string(preset.Name)
+		if !plugin.KaitoModelRegister.Has(presetName) {
+			continue
 		}
-		// Validate private preset has private image specified
-		if plugin.KaitoModelRegister.MustGet(string(i.Preset.Name)).GetInferenceParameters().ImageAccessMode == "private" &&
-			i.Preset.PresetMeta.AccessMode != "private" {
-			errs = errs.Also(apis.ErrGeneric("This preset only supports private AccessMode, AccessMode must be private to continue"))
+		params := plugin.KaitoModelRegister.MustGet(presetName).GetInferenceParameters()
+		if _, ok := params.Runtimes[string(runtime)]; !ok {
+			supported := append([]string{string(InferenceRuntimeTransformers)}, lo.Keys(params.Runtimes)...)
+			sort.Strings(supported[1:])
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Runtime %s does not support preset %s, supported runtimes are %s",
+				runtime, presetName, strings.Join(supported, ", ")), "runtime"))
 		}
-		// Additional validations for Preset
-		if i.Preset.PresetMeta.AccessMode == "private" && i.Preset.PresetOptions.Image == "" {
-			errs = errs.Also(apis.ErrGeneric("When AccessMode is private, an image must be provided in PresetOptions"))
+	}
+	return errs
+}
+
+// validateGPUsPerReplica checks that a replica of the preset requests at least the GPUs the preset requires. The
+// replicas of the presets that support distributed inference run across several nodes, so their GPUs per node are
+// given by the preset.
+func (i *InferenceSpec) validateGPUsPerReplica() (errs *apis.FieldError) {
+	if i.GPUsPerReplica == 0 {
+		return nil
+	}
+	if i.GPUsPerReplica < 0 {
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("gpusPerReplica %d must be positive", i.GPUsPerReplica), "gpusPerReplica"))
+	}
+	if i.Preset == nil {
+		return errs.Also(apis.ErrGeneric("gpusPerReplica can only be specified for a preset model, not for a template or variants", "gpusPerReplica"))
+	}
+	presetName := string(i.Preset.Name)
+	if !plugin.KaitoModelRegister.Has(presetName) {
+		return nil
+	}
+	model := plugin.KaitoModelRegister.MustGet(presetName)
+	if model.SupportDistributedInference() {
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("gpusPerReplica cannot be specified for preset %s, whose replica runs across several nodes", presetName), "gpusPerReplica"))
+	}
+	gpuCount := resource.MustParse(model.GetInferenceParameters().GPUCountRequirement)
+	if int64(i.GPUsPerReplica) < gpuCount.Value() {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("gpusPerReplica %d is less than the %d GPUs preset %s requires", i.GPUsPerReplica, gpuCount.Value(), presetName), "gpusPerReplica"))
+	}
+	return errs
+}
+
+// validateDownloadParallelism checks that the concurrent downloads of the model files are in a sane range. Only the
+// kaito inference server of the transformers runtime downloads the files, the images of the other runtimes contain
+// the weights.
+func (i *InferenceSpec) validateDownloadParallelism() (errs *apis.FieldError) {
+	if i.DownloadParallelism == 0 {
+		return nil
+	}
+	if i.DownloadParallelism < 1 || i.DownloadParallelism > MaxDownloadParallelism {
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("downloadParallelism %d is out of the valid range 1-%d", i.DownloadParallelism, MaxDownloadParallelism), "downloadParallelism"))
+	}
+	if i.Preset == nil {
+		return errs.Also(apis.ErrGeneric("downloadParallelism can only be specified for a preset model, not for a template or variants", "downloadParallelism"))
+	}
+	if runtime := i.GetRuntime(); runtime != InferenceRuntimeTransformers {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("downloadParallelism cannot be specified with runtime %s, whose image contains the weights", runtime), "downloadParallelism"))
+	}
+	return errs
+}
+
+// validateProcessesPerGPU checks that the model server processes can share the GPUs of a replica. Only the kaito
+// inference server of the transformers runtime starts several processes, and a replica of a distributed preset
+// already spans its GPUs with one process per GPU. The memory of the processes is checked with the instance type.
+func (i *InferenceSpec) validateProcessesPerGPU() (errs *apis.FieldError) {
+	if i.ProcessesPerGPU == 0 {
+		return nil
+	}
+	if i.ProcessesPerGPU < 0 {
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("processesPerGPU %d must be positive", i.ProcessesPerGPU), "processesPerGPU"))
+	}
+	if i.ProcessesPerGPU == 1 {
+		return nil
+	}
+	if i.Preset == nil {
+		return errs.Also(apis.ErrGeneric("processesPerGPU can only be specified for a preset model, not for a template or variants", "processesPerGPU"))
+	}
+	if runtime := i.GetRuntime(); runtime != InferenceRuntimeTransformers {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("processesPerGPU cannot be more than 1 with runtime %s, only the transformers runtime supports several processes", runtime), "processesPerGPU"))
+	}
+	presetName := string(i.Preset.Name)
+	if plugin.KaitoModelRegister.Has(presetName) && plugin.KaitoModelRegister.MustGet(presetName).SupportDistributedInference() {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("processesPerGPU cannot be more than 1 for preset %s, whose replica runs across several nodes", presetName), "processesPerGPU"))
+	}
+	return errs
+}
+
+// validateResourceOverrides checks that the overrides only set the CPU and memory of the model server container, whose
+// GPUs are managed by kaito, and that no request exceeds its limit.
+func (i *InferenceSpec) validateResourceOverrides() (errs *apis.FieldError) {
+	if i.ResourceOverrides == nil {
+		return nil
+	}
+	if i.Template != nil {
+		return errs.Also(apis.ErrGeneric("resourceOverrides can only be specified for preset models, the template specifies its own resources", "resourceOverrides"))
+	}
+	for _, name := range sortedResourceNames(i.ResourceOverrides.Requests) {
+		if name != v1.ResourceCPU && name != v1.ResourceMemory {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported resource %s, only cpu and memory can be overridden", name), "resourceOverrides.requests"))
+		}
+	}
+	for _, name := range sortedResourceNames(i.ResourceOverrides.Limits) {
+		if name != v1.ResourceCPU && name != v1.ResourceMemory {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported resource %s, only cpu and memory can be overridden", name), "resourceOverrides.limits"))
+		}
+	}
+	for _, name := range sortedResourceNames(i.ResourceOverrides.Requests) {
+		request := i.ResourceOverrides.Requests[name]
+		if limit, found := i.ResourceOverrides.Limits[name]; found && request.Cmp(limit) > 0 {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s request %s must not exceed the limit %s", name, request.String(), limit.String()), "resourceOverrides.requests"))
 		}
-		// Note: we don't enforce private access mode to have image secrets, in case anonymous pulling is enabled
 	}
 	return errs
 }
 
+// sortedResourceNames returns the names of the resources in order, so that the errors are reported deterministically.
+func sortedResourceNames(resources v1.ResourceList) []v1.ResourceName {
+	names := lo.Keys(resources)
+	sort.Slice(names, func(a, b int) bool { return names[a] < names[b] })
+	return names
+}
+
+// validateRolloutStrategy checks that the workload of the workspace supports the rollout strategy. The pods of a
+// distributed preset are replaced by a StatefulSet, which can neither recreate them nor run a canary.
+func (i *InferenceSpec) validateRolloutStrategy() (errs *apis.FieldError) {
+	switch i.RolloutStrategy {
+	case "", RolloutStrategyRolling:
+		return nil
+	case RolloutStrategyRecreate, RolloutStrategyCanary:
+	default:
+		return errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Unsupported rollout strategy %s, must be recreate, rolling or canary", i.RolloutStrategy), "rolloutStrategy"))
+	}
+	if i.RolloutStrategy == RolloutStrategyCanary && i.Preset == nil {
+		return errs.Also(apis.ErrGeneric("canary rollout can only be specified for a preset model, not for a template or variants", "rolloutStrategy"))
+	}
+	for _, preset := range i.presets() {
+		presetName := string(preset.Name)
+		if plugin.KaitoModelRegister.Has(presetName) && plugin.KaitoModelRegister.MustGet(presetName).SupportDistributedInference() {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Rollout strategy %s is not supported for preset %s, whose replica runs across several nodes",
+				i.RolloutStrategy, presetName), "rolloutStrategy"))
+		}
+	}
+	return errs
+}
+
+// validateAuth checks the reference to the token Secret, and that the model server does not listen on the port of
+// the auth proxy.
+func (i *InferenceSpec) validateAuth() (errs *apis.FieldError) {
+	if i.Auth == nil {
+		return nil
+	}
+	if i.Template != nil {
+		errs = errs.Also(apis.ErrGeneric("auth can only be specified for preset models", "auth"))
+	}
+	if msgs := validation.IsDNS1123Subdomain(i.Auth.SecretName); len(msgs) != 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid secret name %s: %s", i.Auth.SecretName, strings.Join(msgs, ", ")), "auth.secretName"))
+	}
+	if msgs := validation.IsConfigMapKey(i.Auth.GetSecretKey()); len(msgs) != 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Invalid secret key %s: %s", i.Auth.SecretKey, strings.Join(msgs, ", ")), "auth.secretKey"))
+	}
+	if i.GetPort() == AuthProxyPort {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("Port %d is used by the auth proxy", AuthProxyPort), "port"))
+	}
+	return errs
+}
+
+func (i *InferenceSpec) presets() []*PresetSpec {
+	if i.Preset != nil {
+		return []*PresetSpec{i.Preset}
+	}
+	presets := make([]*PresetSpec, 0, len(i.Variants))
+	for idx := range i.Variants {
+		presets = append(presets, &i.Variants[idx].Preset)
+	}
+	return presets
+}
+
 func (i *InferenceSpec) validateUpdate(old *InferenceSpec) (errs *apis.FieldError) {
 	if !reflect.DeepEqual(i.Preset, old.Preset) {
 		errs = errs.Also(apis.ErrGeneric("field is immutable", "preset"))
@@ -324,6 +1131,49 @@ func (i *InferenceSpec) validateUpdate(old *InferenceSpec) (errs *apis.FieldErro
 	if (i.Template != nil && old.Template == nil) || (i.Template == nil && old.Template != nil) {
 		errs = errs.Also(apis.ErrGeneric("field cannot be unset/set if it was set/unset", "template"))
 	}
+	// inference.variants can be changed, e.g., to shift the traffic to a new version, but cannot be set/unset.
+	if (len(i.Variants) != 0) != (len(old.Variants) != 0) {
+		errs = errs.Also(apis.ErrGeneric("field cannot be unset/set if it was set/unset", "variants"))
+	}
+	if !reflect.DeepEqual(i.Variants, old.Variants) {
+		errs = errs.Also(validateVariants(i.Variants))
+	}
+	if i.DNSPolicy != old.DNSPolicy || !reflect.DeepEqual(i.DNSConfig, old.DNSConfig) {
+		errs = errs.Also(validateDNS(i.DNSPolicy, i.DNSConfig))
+	}
+	if i.GetRuntime() != old.GetRuntime() {
+		errs = errs.Also(i.validateRuntime())
+	}
+	if i.RolloutStrategy != old.RolloutStrategy {
+		errs = errs.Also(i.validateRolloutStrategy())
+	}
+	// The nodes of the workspace are sized for the GPUs of a replica.
+	if i.GPUsPerReplica != old.GPUsPerReplica {
+		errs = errs.Also(apis.ErrGeneric("field is immutable", "gpusPerReplica"))
+	}
+	// The nodes of the workspace are sized for the memory of the processes.
+	if i.ProcessesPerGPU != old.ProcessesPerGPU {
+		errs = errs.Also(apis.ErrGeneric("field is immutable", "processesPerGPU"))
+	}
+	if !reflect.DeepEqual(i.ResourceOverrides, old.ResourceOverrides) {
+		errs = errs.Also(i.validateResourceOverrides())
+	}
+	// The services of the workspace are not updated, so the auth proxy cannot be added or removed.
+	if (i.Auth != nil) != (old.Auth != nil) {
+		errs = errs.Also(apis.ErrGeneric("field cannot be unset/set if it was set/unset", "auth"))
+	} else if !reflect.DeepEqual(i.Auth, old.Auth) {
+		errs = errs.Also(i.validateAuth())
+	}
+	if !reflect.DeepEqual(i.EgressPolicy, old.EgressPolicy) {
+		errs = errs.Also(i.EgressPolicy.validate())
+	}
+	// The volumes of the existing inference pods are not updated.
+	if !reflect.DeepEqual(i.Volumes, old.Volumes) {
+		errs = errs.Also(apis.ErrGeneric("field is immutable", "volumes"))
+	}
+	if !reflect.DeepEqual(i.VolumeMounts, old.VolumeMounts) {
+		errs = errs.Also(apis.ErrGeneric("field is immutable", "volumeMounts"))
+	}
 
 	return errs
 }
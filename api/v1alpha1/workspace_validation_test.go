@@ -4,20 +4,29 @@
 package v1alpha1
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/azure/kaito/pkg/model"
 	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
 )
 
 var gpuCountRequirement string
 var totalGPUMemoryRequirement string
 var perGPUMemoryRequirement string
+var allowedRegions []string
+var minHostMemory string
 
 type testModel struct{}
 
@@ -26,6 +35,10 @@ func (*testModel) GetInferenceParameters() *model.PresetParam {
 		GPUCountRequirement:       gpuCountRequirement,
 		TotalGPUMemoryRequirement: totalGPUMemoryRequirement,
 		PerGPUMemoryRequirement:   perGPUMemoryRequirement,
+		Runtimes:                  map[string]map[string]string{"vllm": {}},
+		AllowedRegions:            allowedRegions,
+		MinHostMemory:             minHostMemory,
+		MetricsPort:               9090,
 	}
 }
 func (*testModel) GetTuningParameters() *model.PresetParam {
@@ -67,17 +80,62 @@ func (*testModelPrivate) SupportTuning() bool {
 	return true
 }
 
+// testModelDistributed is sharded across two nodes.
+type testModelDistributed struct{}
+
+func (*testModelDistributed) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		GPUCountRequirement:       gpuCountRequirement,
+		TotalGPUMemoryRequirement: totalGPUMemoryRequirement,
+		PerGPUMemoryRequirement:   perGPUMemoryRequirement,
+		DefaultNodeCount:          2,
+		MinNodeCount:              2,
+	}
+}
+func (*testModelDistributed) GetTuningParameters() *model.PresetParam {
+	return nil
+}
+func (*testModelDistributed) SupportDistributedInference() bool {
+	return true
+}
+func (*testModelDistributed) SupportTuning() bool {
+	return false
+}
+
+// testModelEmbedding embeds texts.
+type testModelEmbedding struct {
+	testModel
+}
+
+func (*testModelEmbedding) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		Embedding: true,
+	}
+}
+
 func RegisterValidationTestModels() {
 	var test testModel
 	var testPrivate testModelPrivate
+	var testDistributed testModelDistributed
+	var testEmbedding testModelEmbedding
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "test-validation",
 		Instance: &test,
+		Aliases:  []string{"test-validation-alias"},
+		Versions: []string{"0.0.1"},
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "private-test-validation",
 		Instance: &testPrivate,
 	})
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "distributed-test-validation",
+		Instance: &testDistributed,
+	})
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "embedding-test-validation",
+		Instance: &testEmbedding,
+	})
 }
 
 func pointerToInt(i int) *int {
@@ -92,7 +150,10 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 		modelGPUCount       string
 		modelPerGPUMemory   string
 		modelTotalGPUMemory string
+		modelMinHostMemory  string
 		preset              bool
+		gpusPerReplica      int
+		processesPerGPU     int
 		errContent          string // Content expect error to include, if any
 		expectErrs          bool
 	}{
@@ -109,6 +170,117 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 			errContent:          "",
 			expectErrs:          false,
 		},
+		{
+			name: "Unset count provides no GPU memory",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12s_v3",
+			},
+			modelGPUCount:       "2",
+			modelPerGPUMemory:   "14Gi",
+			modelTotalGPUMemory: "28Gi",
+			preset:              true,
+			errContent:          "has a total of 0",
+			expectErrs:          true,
+		},
+		{
+			name: "Sufficient host memory",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12s_v3",
+				Count:        pointerToInt(1),
+			},
+			modelGPUCount:       "2",
+			modelPerGPUMemory:   "14Gi",
+			modelTotalGPUMemory: "28Gi",
+			modelMinHostMemory:  "128Gi",
+			preset:              true,
+			errContent:          "",
+			expectErrs:          false,
+		},
+		{
+			name: "Insufficient host memory",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12s_v3",
+				Count:        pointerToInt(1),
+			},
+			modelGPUCount:       "2",
+			modelPerGPUMemory:   "14Gi",
+			modelTotalGPUMemory: "28Gi",
+			modelMinHostMemory:  "256Gi",
+			preset:              true,
+			errContent:          "Insufficient host memory: Instance type Standard_NC12s_v3 provides 224Gi, but preset test-validation requires at least 256Gi",
+			expectErrs:          true,
+		},
+		{
+			name: "Valid autoscaling range",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_ND96asr_v4",
+				Count:        pointerToInt(2),
+				MinCount:     pointerToInt(1),
+				MaxCount:     pointerToInt(4),
+			},
+			modelGPUCount:       "8",
+			modelPerGPUMemory:   "19Gi",
+			modelTotalGPUMemory: "152Gi",
+			preset:              true,
+			errContent:          "",
+			expectErrs:          false,
+		},
+		{
+			name: "Autoscaling a Pod template",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_ND96asr_v4",
+				Count:        pointerToInt(2),
+				MaxCount:     pointerToInt(4),
+			},
+			modelGPUCount:       "8",
+			modelPerGPUMemory:   "19Gi",
+			modelTotalGPUMemory: "152Gi",
+			preset:              false,
+			errContent:          "Autoscaling is only supported for the inference of a single preset",
+			expectErrs:          true,
+		},
+		{
+			name: "Invalid priority class name",
+			resourceSpec: &ResourceSpec{
+				InstanceType:      "Standard_ND96asr_v4",
+				Count:             pointerToInt(1),
+				PriorityClassName: "Production_Inference",
+			},
+			modelGPUCount:       "8",
+			modelPerGPUMemory:   "19Gi",
+			modelTotalGPUMemory: "152Gi",
+			preset:              true,
+			errContent:          "Invalid priority class name",
+			expectErrs:          true,
+		},
+		{
+			name: "MinCount greater than count",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_ND96asr_v4",
+				Count:        pointerToInt(1),
+				MinCount:     pointerToInt(2),
+			},
+			modelGPUCount:       "8",
+			modelPerGPUMemory:   "19Gi",
+			modelTotalGPUMemory: "152Gi",
+			preset:              true,
+			errContent:          "minCount 2 must not be greater than count 1",
+			expectErrs:          true,
+		},
+		{
+			name: "MaxCount less than count",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_ND96asr_v4",
+				Count:        pointerToInt(3),
+				MaxCount:     pointerToInt(2),
+			},
+			modelGPUCount:       "8",
+			modelPerGPUMemory:   "19Gi",
+			modelTotalGPUMemory: "152Gi",
+			preset:              true,
+			errContent:          "maxCount 2 must not be less than count 3",
+			expectErrs:          true,
+		},
 		{
 			name: "Insufficient total GPU memory",
 			resourceSpec: &ResourceSpec{
@@ -136,6 +308,88 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 			errContent:          "Insufficient number of GPUs",
 			expectErrs:          true,
 		},
+		{
+			name: "Insufficient number of GPUs with the SKU upgrade allowed",
+			resourceSpec: &ResourceSpec{
+				InstanceType:    "Standard_NC24ads_A100_v4",
+				Count:           pointerToInt(1),
+				AllowSKUUpgrade: true,
+			},
+			modelGPUCount:       "2",
+			modelPerGPUMemory:   "15Gi",
+			modelTotalGPUMemory: "30Gi",
+			preset:              true,
+			errContent:          "",
+			expectErrs:          false,
+		},
+		{
+			name: "Insufficient number of GPUs without a larger SKU in the family",
+			resourceSpec: &ResourceSpec{
+				InstanceType:    "Standard_NC24ads_A100_v4",
+				Count:           pointerToInt(1),
+				AllowSKUUpgrade: true,
+			},
+			modelGPUCount:       "8",
+			modelPerGPUMemory:   "15Gi",
+			modelTotalGPUMemory: "120Gi",
+			preset:              true,
+			errContent:          "Insufficient number of GPUs",
+			expectErrs:          true,
+		},
+		{
+			name: "Valid single node model on one multi GPU node",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC24",
+				Count:        pointerToInt(1),
+			},
+			modelGPUCount:       "4",
+			modelPerGPUMemory:   "8Gi",
+			modelTotalGPUMemory: "32Gi",
+			preset:              true,
+			errContent:          "",
+			expectErrs:          false,
+		},
+		{
+			name: "Single node model spread over single GPU nodes",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC6",
+				Count:        pointerToInt(4),
+			},
+			modelGPUCount:       "4",
+			modelPerGPUMemory:   "8Gi",
+			modelTotalGPUMemory: "32Gi",
+			preset:              true,
+			errContent:          "runs on a single node and requires at least 4, use an instance type with at least 4 GPUs, e.g., Standard_NC24",
+			expectErrs:          true,
+		},
+		{
+			name: "Replicas with several GPUs on multi GPU nodes",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC24",
+				Count:        pointerToInt(2),
+			},
+			modelGPUCount:       "1",
+			modelPerGPUMemory:   "8Gi",
+			modelTotalGPUMemory: "8Gi",
+			preset:              true,
+			gpusPerReplica:      4,
+			errContent:          "",
+			expectErrs:          false,
+		},
+		{
+			name: "Replica requests more GPUs than a node provides",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12",
+				Count:        pointerToInt(2),
+			},
+			modelGPUCount:       "1",
+			modelPerGPUMemory:   "8Gi",
+			modelTotalGPUMemory: "8Gi",
+			preset:              true,
+			gpusPerReplica:      4,
+			errContent:          "Instance type Standard_NC12 provides 2 per node, but preset test-validation runs on a single node and requires at least 4",
+			expectErrs:          true,
+		},
 		{
 			name: "Insufficient per GPU memory",
 			resourceSpec: &ResourceSpec{
@@ -149,6 +403,33 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 			errContent:          "Insufficient per GPU memory",
 			expectErrs:          true,
 		},
+		{
+			name: "Processes fit the memory of a GPU",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12s_v3",
+				Count:        pointerToInt(1),
+			},
+			modelGPUCount:       "1",
+			modelPerGPUMemory:   "6Gi",
+			modelTotalGPUMemory: "6Gi",
+			preset:              true,
+			processesPerGPU:     2,
+			expectErrs:          false,
+		},
+		{
+			name: "Processes over-subscribe the memory of a GPU",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12s_v3",
+				Count:        pointerToInt(1),
+			},
+			modelGPUCount:       "1",
+			modelPerGPUMemory:   "6Gi",
+			modelTotalGPUMemory: "6Gi",
+			preset:              true,
+			processesPerGPU:     3,
+			errContent:          "GPU memory over-subscribed: Instance type Standard_NC12s_v3 provides 16 per GPU, but 3 processes of preset test-validation require 21 per GPU",
+			expectErrs:          true,
+		},
 
 		{
 			name: "Invalid SKU",
@@ -159,6 +440,15 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 			errContent: "Unsupported instance",
 			expectErrs: true,
 		},
+		{
+			name: "Malformed SKU",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC12s-v3",
+				Count:        pointerToInt(1),
+			},
+			errContent: "did you mean Standard_NC12s_v3?",
+			expectErrs: true,
+		},
 		{
 			name: "Only Template set",
 			resourceSpec: &ResourceSpec{
@@ -172,7 +462,7 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 		{
 			name: "N-Prefix SKU",
 			resourceSpec: &ResourceSpec{
-				InstanceType: "Standard_Nsku",
+				InstanceType: "Standard_NC40ads_H100_v5",
 				Count:        pointerToInt(1),
 			},
 			errContent: "",
@@ -182,12 +472,80 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 		{
 			name: "D-Prefix SKU",
 			resourceSpec: &ResourceSpec{
-				InstanceType: "Standard_Dsku",
+				InstanceType: "Standard_D4s_v3",
 				Count:        pointerToInt(1),
 			},
 			errContent: "",
 			expectErrs: false,
 		},
+		{
+			name: "RDMA with RDMA capable SKU",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_ND96asr_v4",
+				Count:        pointerToInt(2),
+				RDMA:         true,
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "RDMA with non RDMA SKU",
+			resourceSpec: &ResourceSpec{
+				InstanceType: "Standard_NC24ads_A100_v4",
+				Count:        pointerToInt(2),
+				RDMA:         true,
+			},
+			errContent: "does not support RDMA",
+			expectErrs: true,
+		},
+		{
+			name: "Non-positive provisioning timeout",
+			resourceSpec: &ResourceSpec{
+				InstanceType:        "Standard_NC24ads_A100_v4",
+				Count:               pointerToInt(1),
+				ProvisioningTimeout: &metav1.Duration{Duration: 0},
+			},
+			errContent: "provisioningTimeout 0s must be positive",
+			expectErrs: true,
+		},
+		{
+			name: "Valid fallback instance types",
+			resourceSpec: &ResourceSpec{
+				InstanceType:          "Standard_NC6",
+				FallbackInstanceTypes: []string{"Standard_NC12s_v3", "Standard_NC24ads_A100_v4"},
+				Count:                 pointerToInt(1),
+			},
+			modelGPUCount:       "1",
+			modelPerGPUMemory:   "0",
+			modelTotalGPUMemory: "8Gi",
+			preset:              true,
+			errContent:          "",
+			expectErrs:          false,
+		},
+		{
+			name: "Fallback instance type with insufficient GPUs",
+			resourceSpec: &ResourceSpec{
+				InstanceType:          "Standard_NC12s_v3",
+				FallbackInstanceTypes: []string{"Standard_NC6"},
+				Count:                 pointerToInt(1),
+			},
+			modelGPUCount:       "2",
+			modelPerGPUMemory:   "0",
+			modelTotalGPUMemory: "8Gi",
+			preset:              true,
+			errContent:          "fallbackInstanceTypes[0]",
+			expectErrs:          true,
+		},
+		{
+			name: "Fallback instance type duplicates the instance type",
+			resourceSpec: &ResourceSpec{
+				InstanceType:          "Standard_NC12s_v3",
+				FallbackInstanceTypes: []string{"Standard_NC12s_v3"},
+				Count:                 pointerToInt(1),
+			},
+			errContent: "is the same as the instance type",
+			expectErrs: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -201,6 +559,8 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 							Name: ModelName("test-validation"),
 						},
 					},
+					GPUsPerReplica:  tc.gpusPerReplica,
+					ProcessesPerGPU: tc.processesPerGPU,
 				}
 			} else {
 				spec = InferenceSpec{
@@ -211,6 +571,7 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 			gpuCountRequirement = tc.modelGPUCount
 			totalGPUMemoryRequirement = tc.modelTotalGPUMemory
 			perGPUMemoryRequirement = tc.modelPerGPUMemory
+			minHostMemory = tc.modelMinHostMemory
 
 			errs := tc.resourceSpec.validateCreate(spec)
 			hasErrs := errs != nil
@@ -229,55 +590,195 @@ func TestResourceSpecValidateCreate(t *testing.T) {
 	}
 }
 
-func TestResourceSpecValidateUpdate(t *testing.T) {
-
+func TestValidateAutoscaling(t *testing.T) {
+	RegisterValidationTestModels()
 	tests := []struct {
-		name        string
-		newResource *ResourceSpec
-		oldResource *ResourceSpec
-		errContent  string // Content expected error to include, if any
-		expectErrs  bool
+		name       string
+		preset     string
+		errContent string
 	}{
 		{
-			name: "Immutable Count",
-			newResource: &ResourceSpec{
-				Count: pointerToInt(10),
-			},
-			oldResource: &ResourceSpec{
-				Count: pointerToInt(5),
-			},
-			errContent: "field is immutable",
-			expectErrs: true,
+			name:   "Preset exposing metrics",
+			preset: "test-validation",
 		},
 		{
-			name: "Immutable InstanceType",
-			newResource: &ResourceSpec{
-				InstanceType: "new_type",
-			},
-			oldResource: &ResourceSpec{
-				InstanceType: "old_type",
-			},
-			errContent: "field is immutable",
-			expectErrs: true,
+			name:       "Preset without metrics",
+			preset:     "private-test-validation",
+			errContent: "Autoscaling is not supported for the preset private-test-validation, which exposes no metrics",
 		},
 		{
-			name: "Immutable LabelSelector",
-			newResource: &ResourceSpec{
-				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key1": "value1"}},
-			},
-			oldResource: &ResourceSpec{
-				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key2": "value2"}},
-			},
-			errContent: "field is immutable",
-			expectErrs: true,
+			name:       "Distributed preset",
+			preset:     "distributed-test-validation",
+			errContent: "Autoscaling is not supported for the distributed preset distributed-test-validation",
 		},
-		{
-			name: "Valid Update",
-			newResource: &ResourceSpec{
-				Count:         pointerToInt(5),
-				InstanceType:  "same_type",
-				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key": "value"}},
-			},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceSpec := &ResourceSpec{Count: pointerToInt(2), MinCount: pointerToInt(1), MaxCount: pointerToInt(4)}
+			inference := InferenceSpec{Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName(tt.preset)}}}
+			errs := resourceSpec.validateAutoscaling(inference)
+			if (errs != nil) != (tt.errContent != "") {
+				t.Errorf("validateAutoscaling() errors = %v, expected error %q", errs, tt.errContent)
+			}
+			if errs != nil && !strings.Contains(errs.Error(), tt.errContent) {
+				t.Errorf("validateAutoscaling() error message = %v, expected to contain = %v", errs.Error(), tt.errContent)
+			}
+		})
+	}
+}
+
+func TestUpgradedInstanceType(t *testing.T) {
+	RegisterValidationTestModels()
+	tests := []struct {
+		name                 string
+		preset               string
+		instanceType         string
+		allowSKUUpgrade      bool
+		modelGPUCount        string
+		expectedInstanceType string
+		expectUpgrade        bool
+	}{
+		{
+			name:                 "Upgrade to the smallest SKU of the family that fits",
+			instanceType:         "Standard_NC6s_v3",
+			allowSKUUpgrade:      true,
+			modelGPUCount:        "2",
+			expectedInstanceType: "Standard_NC12s_v3",
+			expectUpgrade:        true,
+		},
+		{
+			name:          "Upgrade not allowed",
+			instanceType:  "Standard_NC6s_v3",
+			modelGPUCount: "2",
+		},
+		{
+			name:            "Instance type fits the preset",
+			instanceType:    "Standard_NC12s_v3",
+			allowSKUUpgrade: true,
+			modelGPUCount:   "2",
+		},
+		{
+			name:            "No SKU of the family fits the preset",
+			instanceType:    "Standard_NC6s_v3",
+			allowSKUUpgrade: true,
+			modelGPUCount:   "8",
+		},
+		{
+			name:            "Nodes of a distributed preset are not migrated",
+			preset:          "distributed-test-validation",
+			instanceType:    "Standard_NC6s_v3",
+			allowSKUUpgrade: true,
+			modelGPUCount:   "2",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gpuCountRequirement = tc.modelGPUCount
+			perGPUMemoryRequirement = "8Gi"
+			totalGPUMemoryRequirement = "16Gi"
+			resource := &ResourceSpec{
+				InstanceType:    tc.instanceType,
+				Count:           pointerToInt(1),
+				AllowSKUUpgrade: tc.allowSKUUpgrade,
+			}
+			preset := lo.Ternary(tc.preset != "", tc.preset, "test-validation")
+			inference := InferenceSpec{
+				Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName(preset)}},
+			}
+
+			instanceType, upgraded := resource.UpgradedInstanceType(inference)
+			if upgraded != tc.expectUpgrade || instanceType != tc.expectedInstanceType {
+				t.Errorf("UpgradedInstanceType() = %s, %t, expected %s, %t", instanceType, upgraded, tc.expectedInstanceType, tc.expectUpgrade)
+			}
+		})
+	}
+}
+
+func TestResourceSpecValidateUpdate(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		newResource *ResourceSpec
+		oldResource *ResourceSpec
+		errContent  string // Content expected error to include, if any
+		expectErrs  bool
+	}{
+		{
+			name: "Immutable Count",
+			newResource: &ResourceSpec{
+				Count: pointerToInt(10),
+			},
+			oldResource: &ResourceSpec{
+				Count: pointerToInt(5),
+			},
+			errContent: "field is immutable",
+			expectErrs: true,
+		},
+		{
+			name: "Decreased Count",
+			newResource: &ResourceSpec{
+				Count: pointerToInt(2),
+			},
+			oldResource: &ResourceSpec{
+				Count: pointerToInt(4),
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Mutable InstanceType",
+			newResource: &ResourceSpec{
+				InstanceType: "new_type",
+			},
+			oldResource: &ResourceSpec{
+				InstanceType: "old_type",
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Immutable RDMA",
+			newResource: &ResourceSpec{
+				RDMA: true,
+			},
+			oldResource: &ResourceSpec{
+				RDMA: false,
+			},
+			errContent: "field is immutable",
+			expectErrs: true,
+		},
+		{
+			name: "Immutable LabelSelector",
+			newResource: &ResourceSpec{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key1": "value1"}},
+			},
+			oldResource: &ResourceSpec{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key2": "value2"}},
+			},
+			errContent: "field is immutable",
+			expectErrs: true,
+		},
+		{
+			name: "Invalid autoscaling range",
+			newResource: &ResourceSpec{
+				Count:    pointerToInt(1),
+				MinCount: pointerToInt(3),
+			},
+			oldResource: &ResourceSpec{
+				Count: pointerToInt(1),
+			},
+			errContent: "minCount 3 must not be greater than count 1",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Update",
+			newResource: &ResourceSpec{
+				Count:         pointerToInt(5),
+				InstanceType:  "same_type",
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key": "value"}},
+			},
 			oldResource: &ResourceSpec{
 				Count:         pointerToInt(5),
 				InstanceType:  "same_type",
@@ -313,46 +814,555 @@ func TestInferenceSpecValidateCreate(t *testing.T) {
 	tests := []struct {
 		name          string
 		inferenceSpec *InferenceSpec
+		gpuCount      string
 		errContent    string // Content expected error to include, if any
 		expectErrs    bool
 	}{
 		{
-			name: "Invalid Preset Name",
+			name: "Invalid Preset Name",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("Invalid-Preset-Name"),
+					},
+				},
+			},
+			errContent: "model is not registered",
+			expectErrs: true,
+		},
+		{
+			name: "Only Template set",
+			inferenceSpec: &InferenceSpec{
+				Template: &v1.PodTemplateSpec{}, // Assuming a non-nil TemplateSpec implies it's set
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Valid Port",
+			inferenceSpec: &InferenceSpec{
+				Template: &v1.PodTemplateSpec{},
+				Port:     8080,
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Port Out Of Range",
+			inferenceSpec: &InferenceSpec{
+				Template: &v1.PodTemplateSpec{},
+				Port:     70000,
+			},
+			errContent: "Port 70000 is out of the valid range",
+			expectErrs: true,
+		},
+		{
+			name: "Rolling Rollout Strategy",
+			inferenceSpec: &InferenceSpec{
+				Template:        &v1.PodTemplateSpec{},
+				RolloutStrategy: RolloutStrategyRolling,
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Unsupported Rollout Strategy",
+			inferenceSpec: &InferenceSpec{
+				Template:        &v1.PodTemplateSpec{},
+				RolloutStrategy: RolloutStrategy("blue-green"),
+			},
+			errContent: "Unsupported rollout strategy blue-green",
+			expectErrs: true,
+		},
+		{
+			name: "Canary Rollout Strategy For Template",
+			inferenceSpec: &InferenceSpec{
+				Template:        &v1.PodTemplateSpec{},
+				RolloutStrategy: RolloutStrategyCanary,
+			},
+			errContent: "canary rollout can only be specified for a preset model",
+			expectErrs: true,
+		},
+		{
+			name: "Canary Rollout Strategy For Preset",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				RolloutStrategy: RolloutStrategyCanary,
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Valid Service Account Name",
+			inferenceSpec: &InferenceSpec{
+				Template:           &v1.PodTemplateSpec{},
+				ServiceAccountName: "model-reader",
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Invalid Service Account Name",
+			inferenceSpec: &InferenceSpec{
+				Template:           &v1.PodTemplateSpec{},
+				ServiceAccountName: "Model_Reader",
+			},
+			errContent: "Invalid service account name",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Checksum",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+					PresetOptions: PresetOptions{
+						Checksum: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+					},
+				},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Invalid Checksum",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+					PresetOptions: PresetOptions{
+						Checksum: "md5:098f6bcd4621d373cade4e832627b4f6",
+					},
+				},
+			},
+			errContent: "Invalid checksum",
+			expectErrs: true,
+		},
+		{
+			name: "LoadBalancer Service With Annotations",
+			inferenceSpec: &InferenceSpec{
+				Template:           &v1.PodTemplateSpec{},
+				ServiceType:        v1.ServiceTypeLoadBalancer,
+				ServiceAnnotations: map[string]string{"service.beta.kubernetes.io/azure-load-balancer-internal": "true"},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Unsupported Service Type",
+			inferenceSpec: &InferenceSpec{
+				Template:    &v1.PodTemplateSpec{},
+				ServiceType: v1.ServiceTypeNodePort,
+			},
+			errContent: "Unsupported service type NodePort",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Image Pull Policy",
+			inferenceSpec: &InferenceSpec{
+				Template:        &v1.PodTemplateSpec{},
+				ImagePullPolicy: v1.PullNever,
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Unsupported Image Pull Policy",
+			inferenceSpec: &InferenceSpec{
+				Template:        &v1.PodTemplateSpec{},
+				ImagePullPolicy: "Sometimes",
+			},
+			errContent: "Unsupported image pull policy Sometimes",
+			expectErrs: true,
+		},
+		{
+			name: "Negative Termination Grace Period",
+			inferenceSpec: &InferenceSpec{
+				Template:                      &v1.PodTemplateSpec{},
+				TerminationGracePeriodSeconds: lo.ToPtr(int64(-1)),
+			},
+			errContent: "TerminationGracePeriodSeconds -1 must not be negative",
+			expectErrs: true,
+		},
+		{
+			name: "Valid DNS Config",
+			inferenceSpec: &InferenceSpec{
+				Template:  &v1.PodTemplateSpec{},
+				DNSPolicy: v1.DNSNone,
+				DNSConfig: &v1.PodDNSConfig{
+					Nameservers: []string{"10.0.0.10"},
+					Searches:    []string{"models.internal.example.com."},
+				},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "DNS Policy None Without Nameservers",
+			inferenceSpec: &InferenceSpec{
+				Template:  &v1.PodTemplateSpec{},
+				DNSPolicy: v1.DNSNone,
+				DNSConfig: &v1.PodDNSConfig{Searches: []string{"models.internal.example.com"}},
+			},
+			errContent: "At least one nameserver must be specified if the DNS policy is None",
+			expectErrs: true,
+		},
+		{
+			name: "Invalid DNS Nameserver",
+			inferenceSpec: &InferenceSpec{
+				Template:  &v1.PodTemplateSpec{},
+				DNSConfig: &v1.PodDNSConfig{Nameservers: []string{"dns.example.com"}},
+			},
+			errContent: "Nameserver dns.example.com must be an IP address",
+			expectErrs: true,
+		},
+		{
+			name: "Unsupported DNS Policy",
+			inferenceSpec: &InferenceSpec{
+				Template:  &v1.PodTemplateSpec{},
+				DNSPolicy: "ClusterLast",
+			},
+			errContent: "Unsupported DNS policy ClusterLast",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Egress Policy",
+			inferenceSpec: &InferenceSpec{
+				Template:     &v1.PodTemplateSpec{},
+				EgressPolicy: &EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/16", "fd00::/8"}},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Invalid Egress CIDR",
+			inferenceSpec: &InferenceSpec{
+				Template:     &v1.PodTemplateSpec{},
+				EgressPolicy: &EgressPolicy{AllowedCIDRs: []string{"10.0.0.1"}},
+			},
+			errContent: "Invalid CIDR 10.0.0.1",
+			expectErrs: true,
+		},
+		{
+			name: "Service Annotations Without LoadBalancer",
+			inferenceSpec: &InferenceSpec{
+				Template:           &v1.PodTemplateSpec{},
+				ServiceAnnotations: map[string]string{"service.beta.kubernetes.io/azure-load-balancer-internal": "true"},
+			},
+			errContent: "serviceAnnotations can only be specified for a LoadBalancer service",
+			expectErrs: true,
+		},
+		{
+			name:          "Preset and Template Unset",
+			inferenceSpec: &InferenceSpec{},
+			errContent:    "Preset or Template must be specified",
+			expectErrs:    true,
+		},
+		{
+			name: "Preset and Template Set",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Template: &v1.PodTemplateSpec{}, // Assuming a non-nil TemplateSpec implies it's set
+			},
+			errContent: "Preset and Template cannot be set at the same time",
+			expectErrs: true,
+		},
+		{
+			name: "Preset Alias",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation-alias"),
+					},
+				},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Runtime Supported By Preset",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Runtime: InferenceRuntimeVLLM,
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Runtime Not Supported By Preset",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Runtime: InferenceRuntimeTGI,
+			},
+			errContent: "Runtime tgi does not support preset test-validation, supported runtimes are transformers, vllm",
+			expectErrs: true,
+		},
+		{
+			name: "Runtime With Template",
+			inferenceSpec: &InferenceSpec{
+				Template: &v1.PodTemplateSpec{},
+				Runtime:  InferenceRuntimeVLLM,
+			},
+			errContent: "runtime can only be specified for preset models",
+			expectErrs: true,
+		},
+		{
+			name: "Volume Collides With Shared Memory Volume",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Volumes: []v1.Volume{{Name: SHMVolumeName, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}},
+			},
+			errContent: "Volume name dshm collides with a volume that kaito manages",
+			expectErrs: true,
+		},
+		{
+			name: "Volume Mount Without Volume",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				VolumeMounts: []v1.VolumeMount{{Name: "datasets", MountPath: "/mnt/datasets"}},
+			},
+			errContent: "Volume mount datasets does not reference a volume of the workspace",
+			expectErrs: true,
+		},
+		{
+			name: "Volumes With Template",
+			inferenceSpec: &InferenceSpec{
+				Template: &v1.PodTemplateSpec{},
+				Volumes:  []v1.Volume{{Name: "datasets", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}},
+			},
+			errContent: "volumes can only be specified for preset models",
+			expectErrs: true,
+		},
+		{
+			name: "GPUs Per Replica Less Than Preset Requires",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				GPUsPerReplica: 1,
+			},
+			gpuCount:   "2",
+			errContent: "gpusPerReplica 1 is less than the 2 GPUs preset test-validation requires",
+			expectErrs: true,
+		},
+		{
+			name: "GPUs Per Replica With Template",
+			inferenceSpec: &InferenceSpec{
+				Template:       &v1.PodTemplateSpec{},
+				GPUsPerReplica: 2,
+			},
+			errContent: "gpusPerReplica can only be specified for a preset model",
+			expectErrs: true,
+		},
+		{
+			name: "Processes Per GPU With VLLM Runtime",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Runtime:         InferenceRuntimeVLLM,
+				ProcessesPerGPU: 2,
+			},
+			errContent: "processesPerGPU cannot be more than 1 with runtime vllm",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Download Parallelism",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				DownloadParallelism: 8,
+			},
+			expectErrs: false,
+		},
+		{
+			name: "Download Parallelism Out Of Range",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				DownloadParallelism: MaxDownloadParallelism + 1,
+			},
+			errContent: "downloadParallelism 33 is out of the valid range 1-32",
+			expectErrs: true,
+		},
+		{
+			name: "Download Parallelism With Template",
+			inferenceSpec: &InferenceSpec{
+				Template:            &v1.PodTemplateSpec{},
+				DownloadParallelism: 8,
+			},
+			errContent: "downloadParallelism can only be specified for a preset model",
+			expectErrs: true,
+		},
+		{
+			name: "Download Parallelism With VLLM Runtime",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Runtime:             InferenceRuntimeVLLM,
+				DownloadParallelism: 8,
+			},
+			errContent: "downloadParallelism cannot be specified with runtime vllm",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Resource Overrides",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				ResourceOverrides: &ResourceOverrides{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("16Gi")},
+					Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("32Gi")},
+				},
+			},
+			expectErrs: false,
+		},
+		{
+			name: "Resource Overrides Request Exceeds Limit",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				ResourceOverrides: &ResourceOverrides{
+					Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Gi")},
+					Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("32Gi")},
+				},
+			},
+			errContent: "memory request 64Gi must not exceed the limit 32Gi",
+			expectErrs: true,
+		},
+		{
+			name: "Resource Overrides With GPUs",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				ResourceOverrides: &ResourceOverrides{
+					Limits: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+				},
+			},
+			errContent: "Unsupported resource nvidia.com/gpu, only cpu and memory can be overridden",
+			expectErrs: true,
+		},
+		{
+			name: "Valid Auth",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Auth: &InferenceAuth{SecretName: "inference-token"},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Auth With Invalid Secret Name",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name: ModelName("test-validation"),
+					},
+				},
+				Auth: &InferenceAuth{SecretName: "Inference_Token"},
+			},
+			errContent: "Invalid secret name Inference_Token",
+			expectErrs: true,
+		},
+		{
+			name: "Auth With Template",
+			inferenceSpec: &InferenceSpec{
+				Template: &v1.PodTemplateSpec{},
+				Auth:     &InferenceAuth{SecretName: "inference-token"},
+			},
+			errContent: "auth can only be specified for preset models",
+			expectErrs: true,
+		},
+		{
+			name: "Auth With Port Of The Proxy",
 			inferenceSpec: &InferenceSpec{
 				Preset: &PresetSpec{
 					PresetMeta: PresetMeta{
-						Name: ModelName("Invalid-Preset-Name"),
+						Name: ModelName("test-validation"),
 					},
 				},
+				Port: AuthProxyPort,
+				Auth: &InferenceAuth{SecretName: "inference-token"},
 			},
-			errContent: "model is not registered",
+			errContent: "Port 5080 is used by the auth proxy",
 			expectErrs: true,
 		},
 		{
-			name: "Only Template set",
+			name: "Pinned Valid Version",
 			inferenceSpec: &InferenceSpec{
-				Template: &v1.PodTemplateSpec{}, // Assuming a non-nil TemplateSpec implies it's set
+				Preset: &PresetSpec{
+					PresetMeta: PresetMeta{
+						Name:    ModelName("test-validation"),
+						Version: "0.0.1",
+					},
+				},
 			},
 			errContent: "",
 			expectErrs: false,
 		},
 		{
-			name:          "Preset and Template Unset",
-			inferenceSpec: &InferenceSpec{},
-			errContent:    "Preset or Template must be specified",
-			expectErrs:    true,
-		},
-		{
-			name: "Preset and Template Set",
+			name: "Pinned Invalid Version",
 			inferenceSpec: &InferenceSpec{
 				Preset: &PresetSpec{
 					PresetMeta: PresetMeta{
-						Name: ModelName("test-validation"),
+						Name:    ModelName("test-validation"),
+						Version: "9.9.9",
 					},
 				},
-				Template: &v1.PodTemplateSpec{}, // Assuming a non-nil TemplateSpec implies it's set
 			},
-			errContent: "Preset and Template cannot be set at the same time",
+			errContent: "Unsupported version 9.9.9 of inference preset test-validation",
 			expectErrs: true,
 		},
 		{
@@ -395,10 +1405,68 @@ func TestInferenceSpecValidateCreate(t *testing.T) {
 			errContent: "",
 			expectErrs: false,
 		},
+		{
+			name: "Valid Variants",
+			inferenceSpec: &InferenceSpec{
+				Variants: []PresetVariant{
+					{Name: "a", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}}, Weight: 90},
+					{Name: "b", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation"), Version: "0.0.1"}}, Weight: 10},
+				},
+			},
+			errContent: "",
+			expectErrs: false,
+		},
+		{
+			name: "Variant Weights Not Summing To 100",
+			inferenceSpec: &InferenceSpec{
+				Variants: []PresetVariant{
+					{Name: "a", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}}, Weight: 50},
+					{Name: "b", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation"), Version: "0.0.1"}}, Weight: 40},
+				},
+			},
+			errContent: "The weights of the variants sum to 90, must sum to 100",
+			expectErrs: true,
+		},
+		{
+			name: "Duplicate Variant Names",
+			inferenceSpec: &InferenceSpec{
+				Variants: []PresetVariant{
+					{Name: "a", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}}, Weight: 50},
+					{Name: "a", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation"), Version: "0.0.1"}}, Weight: 50},
+				},
+			},
+			errContent: "Duplicate variant name a",
+			expectErrs: true,
+		},
+		{
+			name: "Single Variant",
+			inferenceSpec: &InferenceSpec{
+				Variants: []PresetVariant{
+					{Name: "a", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}}, Weight: 100},
+				},
+			},
+			errContent: "At least two variants must be specified",
+			expectErrs: true,
+		},
+		{
+			name: "Variants With Preset",
+			inferenceSpec: &InferenceSpec{
+				Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Variants: []PresetVariant{
+					{Name: "a", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}}, Weight: 50},
+					{Name: "b", Preset: PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation"), Version: "0.0.1"}}, Weight: 50},
+				},
+			},
+			errContent: "Variants cannot be set together with Preset or Template",
+			expectErrs: true,
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			if tc.gpuCount != "" {
+				gpuCountRequirement = tc.gpuCount
+			}
 			// If the test expects an error, setup defer function to catch the panic.
 			if tc.expectErrs {
 				defer func() {
@@ -510,6 +1578,7 @@ func TestInferenceSpecValidateUpdate(t *testing.T) {
 }
 
 func TestWorkspaceValidateCreate(t *testing.T) {
+	RegisterValidationTestModels()
 	tests := []struct {
 		name      string
 		workspace *Workspace
@@ -540,26 +1609,417 @@ func TestWorkspaceValidateCreate(t *testing.T) {
 			errField: "",
 		},
 		{
-			name: "Only Tuning specified",
-			workspace: &Workspace{
-				Tuning: &TuningSpec{Input: &DataSource{}},
-			},
-			wantErr:  false,
-			errField: "",
+			name: "Only Tuning specified",
+			workspace: &Workspace{
+				Tuning: &TuningSpec{Input: &DataSource{}},
+			},
+			wantErr:  false,
+			errField: "",
+		},
+		{
+			name: "RAG specified without Inference",
+			workspace: &Workspace{
+				Tuning: &TuningSpec{Input: &DataSource{}},
+				RAG:    &RAGSpec{},
+			},
+			wantErr:  true,
+			errField: "rag",
+		},
+		{
+			name: "Count of a distributed preset at its minimum",
+			workspace: &Workspace{
+				Resource: ResourceSpec{Count: pointerToInt(2)},
+				Inference: &InferenceSpec{
+					Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("distributed-test-validation")}},
+				},
+			},
+			wantErr:  false,
+			errField: "",
+		},
+		{
+			name: "Count of a distributed preset overridden below its minimum",
+			workspace: &Workspace{
+				Resource: ResourceSpec{Count: pointerToInt(1)},
+				Inference: &InferenceSpec{
+					Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("distributed-test-validation")}},
+				},
+			},
+			wantErr:  true,
+			errField: "count 1 must not be less than 2, the minimum node count of preset distributed-test-validation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.workspace.validateCreate()
+			if (errs != nil) != tt.wantErr {
+				t.Errorf("validateCreate() error = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if errs != nil && !strings.Contains(errs.Error(), tt.errField) {
+				t.Errorf("validateCreate() expected error to contain field %s, but got %s", tt.errField, errs.Error())
+			}
+		})
+	}
+}
+
+func TestWorkspaceSetDefaults(t *testing.T) {
+	RegisterValidationTestModels()
+	tests := []struct {
+		name          string
+		workspace     *Workspace
+		expectedCount int
+	}{
+		{
+			name: "Count defaults to the node count of a distributed preset",
+			workspace: &Workspace{
+				Inference: &InferenceSpec{
+					Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("distributed-test-validation")}},
+				},
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "Count defaults to a single node",
+			workspace: &Workspace{
+				Inference: &InferenceSpec{
+					Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				},
+			},
+			expectedCount: 1,
+		},
+		{
+			name:          "Count of a tuning workspace defaults to a single node",
+			workspace:     &Workspace{Tuning: &TuningSpec{}},
+			expectedCount: 1,
+		},
+		{
+			name: "Count set by the user is kept",
+			workspace: &Workspace{
+				Resource: ResourceSpec{Count: pointerToInt(3)},
+				Inference: &InferenceSpec{
+					Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("distributed-test-validation")}},
+				},
+			},
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.workspace.SetDefaults(context.Background())
+			if tt.workspace.Resource.Count == nil || *tt.workspace.Resource.Count != tt.expectedCount {
+				t.Errorf("SetDefaults() count = %v, expected %d", tt.workspace.Resource.Count, tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestWorkspaceValidateRegion(t *testing.T) {
+	RegisterValidationTestModels()
+	tests := []struct {
+		name           string
+		allowedRegions []string
+		region         string
+		errContent     string // Content expected error to include, if any
+	}{
+		{
+			name:   "Preset available in any region",
+			region: "westus",
+		},
+		{
+			name:           "Allowed region",
+			allowedRegions: []string{"eastus", "westeurope"},
+			region:         "westeurope",
+		},
+		{
+			name:           "No region required",
+			allowedRegions: []string{"eastus", "westeurope"},
+		},
+		{
+			name:           "Disallowed region",
+			allowedRegions: []string{"eastus", "westeurope"},
+			region:         "westus",
+			errContent:     "Region westus is not allowed for the presets of the workspace, which are only available in eastus, westeurope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowedRegions = tt.allowedRegions
+			defer func() { allowedRegions = nil }()
+
+			workspace := &Workspace{
+				Resource: ResourceSpec{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"apps": "test"}}},
+				Inference: &InferenceSpec{
+					Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				},
+			}
+			if tt.region != "" {
+				workspace.Resource.LabelSelector.MatchLabels[v1.LabelTopologyRegion] = tt.region
+			}
+
+			errs := workspace.validateRegion()
+			if (errs != nil) != (tt.errContent != "") {
+				t.Errorf("validateRegion() error = %v, expected error %q", errs, tt.errContent)
+			}
+			if errs != nil && !strings.Contains(errs.Error(), tt.errContent) {
+				t.Errorf("validateRegion() expected error to contain %s, but got %s", tt.errContent, errs.Error())
+			}
+		})
+	}
+}
+
+func TestWorkspaceWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		workspace    *Workspace
+		wantWarnings int
+	}{
+		{
+			name: "Tuning on spot instances",
+			workspace: &Workspace{
+				Resource: ResourceSpec{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{v1alpha5.LabelCapacityType: v1alpha5.CapacityTypeSpot},
+					},
+				},
+				Tuning: &TuningSpec{Input: &DataSource{}},
+			},
+			wantWarnings: 1,
+		},
+		{
+			name: "Tuning on on-demand instances",
+			workspace: &Workspace{
+				Resource: ResourceSpec{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{v1alpha5.LabelCapacityType: v1alpha5.CapacityTypeOnDemand},
+					},
+				},
+				Tuning: &TuningSpec{Input: &DataSource{}},
+			},
+			wantWarnings: 0,
+		},
+		{
+			name: "Inference on spot instances",
+			workspace: &Workspace{
+				Resource: ResourceSpec{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{v1alpha5.LabelCapacityType: v1alpha5.CapacityTypeSpot},
+					},
+				},
+				Inference: &InferenceSpec{},
+			},
+			wantWarnings: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := tt.workspace.Warnings()
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Warnings() = %v, want %d warnings", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateAllowedInstanceTypes(t *testing.T) {
+	allowList, err := NewSKUAllowListFromConfigMap(&v1.ConfigMap{
+		Data: map[string]string{"team-a": "Standard_NC12s_v3, standard_nc24s_v3"},
+	})
+	if err != nil {
+		t.Fatalf("NewSKUAllowListFromConfigMap() returned an unexpected error: %v", err)
+	}
+	ctx := WithSKUAllowList(context.Background(), allowList)
+
+	tests := []struct {
+		name       string
+		namespace  string
+		resource   ResourceSpec
+		errContent string
+	}{
+		{
+			name:      "Allowed SKU",
+			namespace: "team-a",
+			resource:  ResourceSpec{InstanceType: "Standard_NC24s_v3"},
+		},
+		{
+			name:       "Disallowed SKU",
+			namespace:  "team-a",
+			resource:   ResourceSpec{InstanceType: "Standard_NC96ads_A100_v4"},
+			errContent: "Instance type Standard_NC96ads_A100_v4 is not allowed in namespace team-a",
+		},
+		{
+			name:       "Disallowed fallback SKU",
+			namespace:  "team-a",
+			resource:   ResourceSpec{InstanceType: "Standard_NC12s_v3", FallbackInstanceTypes: []string{"Standard_NC6s_v3"}},
+			errContent: "Instance type Standard_NC6s_v3 is not allowed in namespace team-a",
+		},
+		{
+			name:      "Namespace without restrictions",
+			namespace: "team-b",
+			resource:  ResourceSpec{InstanceType: "Standard_NC96ads_A100_v4"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := tc.resource.validateAllowedInstanceTypes(ctx, tc.namespace)
+			if tc.errContent == "" {
+				if errs != nil {
+					t.Errorf("validateAllowedInstanceTypes() returned an unexpected error: %v", errs)
+				}
+				return
+			}
+			if errs == nil || !strings.Contains(errs.Error(), tc.errContent) {
+				t.Errorf("validateAllowedInstanceTypes() error = %v, want it to contain %q", errs, tc.errContent)
+			}
+		})
+	}
+
+	if _, err := NewSKUAllowListFromConfigMap(&v1.ConfigMap{Data: map[string]string{"team-a": " , "}}); err == nil {
+		t.Errorf("NewSKUAllowListFromConfigMap() expected an error for a namespace that allows no instance type")
+	}
+}
+
+func TestValidateTimeSlicing(t *testing.T) {
+	RegisterValidationTestModels()
+	gpuCountRequirement = "1"
+	config, err := NewTimeSlicingConfigFromConfigMap(&v1.ConfigMap{
+		Data: map[string]string{
+			"a100": `
+version: v1
+sharing:
+  timeSlicing:
+    renameByDefault: true
+    resources:
+    - name: nvidia.com/gpu
+      replicas: 4
+`,
+			"t4": `
+version: v1
+sharing:
+  timeSlicing:
+    failRequestsGreaterThanOne: true
+    resources:
+    - name: nvidia.com/gpu
+      rename: nvidia.com/t4.shared
+      replicas: 8
+`,
+			"v100": `
+version: v1
+sharing:
+  timeSlicing:
+    failRequestsGreaterThanOne: true
+    resources:
+    - name: nvidia.com/gpu
+      replicas: 2
+`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTimeSlicingConfigFromConfigMap() returned an unexpected error: %v", err)
+	}
+	ctx := WithTimeSlicingConfig(context.Background(), config)
+
+	templateRequesting := func(requests ...v1.ResourceList) *v1.PodTemplateSpec {
+		template := &v1.PodTemplateSpec{}
+		for i, request := range requests {
+			template.Spec.Containers = append(template.Spec.Containers, v1.Container{Name: fmt.Sprintf("container-%d", i), Resources: v1.ResourceRequirements{Limits: request}})
+		}
+		return template
+	}
+
+	tests := []struct {
+		name           string
+		instanceType   string
+		template       *v1.PodTemplateSpec
+		gpusPerReplica int
+		errContent     string
+	}{
+		{
+			name:         "Slices within the config",
+			instanceType: "Standard_NC24ads_A100_v4",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("2")}, v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("2")}),
+		},
+		{
+			name:         "More slices than the GPU of the node shares",
+			instanceType: "Standard_NC24ads_A100_v4",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("3")}, v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("2")}),
+			errContent:   "The pod requests 5 slices of the time-sliced resource nvidia.com/gpu.shared, but the device plugin only shares 4 on a node of instance type Standard_NC24ads_A100_v4",
+		},
+		{
+			name:         "Slices of all the GPUs of the node",
+			instanceType: "Standard_NC96ads_A100_v4",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("10")}, v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("6")}),
+		},
+		{
+			name:         "More slices than all the GPUs of the node share",
+			instanceType: "Standard_NC96ads_A100_v4",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("17")}),
+			errContent:   "The pod requests 17 slices of the time-sliced resource nvidia.com/gpu.shared, but the device plugin only shares 16",
+		},
+		{
+			name:         "Slices of an unknown instance type are not counted",
+			instanceType: "Standard_NC6s_unknown",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu.shared": resource.MustParse("64")}),
+		},
+		{
+			name:         "One slice per container of a resource that fails larger requests",
+			instanceType: "Standard_NC4as_T4_v3",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/t4.shared": resource.MustParse("1")}, v1.ResourceList{"nvidia.com/t4.shared": resource.MustParse("1")}),
+		},
+		{
+			name:         "More than one slice of a resource that fails larger requests",
+			instanceType: "Standard_NC64as_T4_v3",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/t4.shared": resource.MustParse("2")}),
+			errContent:   "The container container-0 requests 2 slices of the time-sliced resource nvidia.com/t4.shared, but the device plugin fails the requests of more than one",
+		},
+		{
+			name:         "GPUs that are not renamed are shared",
+			instanceType: "Standard_NC64as_T4_v3",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}, v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}),
+		},
+		{
+			name:         "More than one GPU that is not renamed",
+			instanceType: "Standard_NC64as_T4_v3",
+			template:     templateRequesting(v1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")}),
+			errContent:   "The container container-0 requests 2 slices of the time-sliced resource nvidia.com/gpu",
+		},
+		{
+			name:         "Preset within the config",
+			instanceType: "Standard_NC64as_T4_v3",
+		},
+		{
+			name:           "Preset that requests more than one GPU that is not renamed",
+			instanceType:   "Standard_NC64as_T4_v3",
+			gpusPerReplica: 2,
+			errContent:     "The container test-validation requests 2 slices of the time-sliced resource nvidia.com/gpu, but the device plugin fails the requests of more than one: preset",
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			errs := tt.workspace.validateCreate()
-			if (errs != nil) != tt.wantErr {
-				t.Errorf("validateCreate() error = %v, wantErr %v", errs, tt.wantErr)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inference := &InferenceSpec{Template: tc.template, GPUsPerReplica: tc.gpusPerReplica}
+			if tc.template == nil {
+				inference.Preset = &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}}
 			}
-			if errs != nil && !strings.Contains(errs.Error(), tt.errField) {
-				t.Errorf("validateCreate() expected error to contain field %s, but got %s", tt.errField, errs.Error())
+			errs := inference.validateTimeSlicing(ctx, tc.instanceType)
+			if tc.errContent == "" {
+				if errs != nil {
+					t.Errorf("validateTimeSlicing() returned an unexpected error: %v", errs)
+				}
+				return
+			}
+			if errs == nil || !strings.Contains(errs.Error(), tc.errContent) {
+				t.Errorf("validateTimeSlicing() error = %v, want it to contain %q", errs, tc.errContent)
 			}
 		})
 	}
+
+	if _, err := NewTimeSlicingConfigFromConfigMap(&v1.ConfigMap{Data: map[string]string{"any": "sharing:\n  timeSlicing:\n    resources:\n    - name: nvidia.com/gpu\n"}}); err == nil {
+		t.Errorf("NewTimeSlicingConfigFromConfigMap() expected an error for a resource without replicas")
+	}
 }
 
 func TestWorkspaceValidateUpdate(t *testing.T) {
@@ -616,8 +2076,34 @@ func TestWorkspaceValidateUpdate(t *testing.T) {
 			},
 			expectErrs: false,
 		},
+		{
+			name: "InstanceType of an inference Deployment changed",
+			oldWorkspace: &Workspace{
+				Resource:  ResourceSpec{InstanceType: "Standard_NC12s_v3"},
+				Inference: &InferenceSpec{Preset: &PresetSpec{PresetMeta: PresetMeta{Name: "test-validation"}}},
+			},
+			newWorkspace: &Workspace{
+				Resource:  ResourceSpec{InstanceType: "Standard_ND96asr_v4"},
+				Inference: &InferenceSpec{Preset: &PresetSpec{PresetMeta: PresetMeta{Name: "test-validation"}}},
+			},
+			expectErrs: false,
+		},
+		{
+			name: "InstanceType of a tuning workspace changed",
+			oldWorkspace: &Workspace{
+				Resource: ResourceSpec{InstanceType: "Standard_NC12s_v3"},
+				Tuning:   &TuningSpec{Input: &DataSource{}},
+			},
+			newWorkspace: &Workspace{
+				Resource: ResourceSpec{InstanceType: "Standard_ND96asr_v4"},
+				Tuning:   &TuningSpec{Input: &DataSource{}},
+			},
+			expectErrs: true,
+			errFields:  []string{"resource.instanceType"},
+		},
 	}
 
+	RegisterValidationTestModels()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			errs := tt.newWorkspace.validateUpdate(tt.oldWorkspace)
@@ -638,6 +2124,297 @@ func TestWorkspaceValidateUpdate(t *testing.T) {
 	}
 }
 
+func TestWorkspaceValidateCountUpdate(t *testing.T) {
+	RegisterValidationTestModels()
+	tests := []struct {
+		name       string
+		preset     string
+		oldCount   int
+		newCount   int
+		errContent string
+	}{
+		{
+			name:     "Count decreased",
+			preset:   "test-validation",
+			oldCount: 4,
+			newCount: 2,
+		},
+		{
+			name:       "Count increased",
+			preset:     "test-validation",
+			oldCount:   2,
+			newCount:   4,
+			errContent: "field is immutable, it may only be decreased: resource.count",
+		},
+		{
+			name:       "Count of a distributed preset decreased below its minimum",
+			preset:     "distributed-test-validation",
+			oldCount:   3,
+			newCount:   1,
+			errContent: "count 1 must not be less than 2, the minimum node count of preset distributed-test-validation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := func(count int) *Workspace {
+				return &Workspace{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+					Resource:   ResourceSpec{InstanceType: "Standard_NC12s_v3", Count: pointerToInt(count)},
+					Inference:  &InferenceSpec{Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName(tt.preset)}}},
+				}
+			}
+			errs := workspace(tt.newCount).Validate(apis.WithinUpdate(context.Background(), workspace(tt.oldCount)))
+			if errs != nil {
+				errs = errs.Filter(apis.ErrorLevel)
+			}
+			if (errs != nil) != (tt.errContent != "") {
+				t.Errorf("Validate() errors = %v, expected error %q", errs, tt.errContent)
+			}
+			if errs != nil && !strings.Contains(errs.Error(), tt.errContent) {
+				t.Errorf("Validate() error message = %v, expected to contain = %v", errs.Error(), tt.errContent)
+			}
+		})
+	}
+}
+
+func TestRAGSpecValidateCreate(t *testing.T) {
+	RegisterValidationTestModels()
+	tests := []struct {
+		name      string
+		ragSpec   *RAGSpec
+		inference *InferenceSpec
+		wantErr   bool
+		errFields []string // Fields we expect to have errors
+	}{
+		{
+			name: "All fields valid",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("embedding-test-validation"),
+				VectorStore:     VectorStoreSpec{URL: "http://vector-db.default.svc:6333", Collection: "docs"},
+				TopK:            5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Missing embedding preset",
+			ragSpec: &RAGSpec{
+				VectorStore: VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+			},
+			wantErr:   true,
+			errFields: []string{"embeddingPreset"},
+		},
+		{
+			name: "Unregistered embedding preset",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("invalid-embedding-preset"),
+				VectorStore:     VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+			},
+			wantErr:   true,
+			errFields: []string{"embeddingPreset"},
+		},
+		{
+			name: "Embedding preset that does not embed texts",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("test-validation"),
+				VectorStore:     VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+			},
+			wantErr:   true,
+			errFields: []string{"Preset test-validation is not an embedding model"},
+		},
+		{
+			name: "Runtime whose model server does not retrieve the documents",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("embedding-test-validation"),
+				VectorStore:     VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+			},
+			inference: &InferenceSpec{
+				Preset:  &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Runtime: InferenceRuntimeVLLM,
+			},
+			wantErr:   true,
+			errFields: []string{"RAG is not supported by the vllm runtime"},
+		},
+		{
+			name: "Inference port of the embedding sidecar",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("embedding-test-validation"),
+				VectorStore:     VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+			},
+			inference: &InferenceSpec{
+				Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Port:   RAGEmbeddingPort,
+			},
+			wantErr:   true,
+			errFields: []string{"The inference port 5001 is the port of the embedding sidecar"},
+		},
+		{
+			name: "Invalid vector store URL",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("embedding-test-validation"),
+				VectorStore:     VectorStoreSpec{URL: "vector-db"},
+			},
+			wantErr:   true,
+			errFields: []string{"vectorStore.url"},
+		},
+		{
+			name: "Negative topK",
+			ragSpec: &RAGSpec{
+				EmbeddingPreset: ModelName("embedding-test-validation"),
+				VectorStore:     VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+				TopK:            -1,
+			},
+			wantErr:   true,
+			errFields: []string{"topK"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.ragSpec.validateCreate(tt.inference)
+			hasErrs := errs != nil
+			if hasErrs != tt.wantErr {
+				t.Errorf("validateCreate() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if hasErrs {
+				for _, field := range tt.errFields {
+					if !strings.Contains(errs.Error(), field) {
+						t.Errorf("validateCreate() expected errors to contain field %s, but got %s", field, errs.Error())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestModelCacheSpecValidate(t *testing.T) {
+	size := resource.MustParse("100Gi")
+	zero := resource.MustParse("0")
+	tests := []struct {
+		name       string
+		modelCache *ModelCacheSpec
+		wantErr    bool
+		errFields  []string // Fields we expect to have errors
+	}{
+		{
+			name:       "Existing persistent volume claim",
+			modelCache: &ModelCacheSpec{PersistentVolumeClaim: "hf-cache", MountPath: "/mnt/cache"},
+			wantErr:    false,
+		},
+		{
+			name:       "Persistent volume claim created by kaito",
+			modelCache: &ModelCacheSpec{PersistentVolumeClaim: "hf-cache", Size: &size, StorageClassName: lo.ToPtr("azurefile-csi")},
+			wantErr:    false,
+		},
+		{
+			name:       "Missing persistent volume claim",
+			modelCache: &ModelCacheSpec{},
+			wantErr:    true,
+			errFields:  []string{"persistentVolumeClaim"},
+		},
+		{
+			name:       "Invalid persistent volume claim name",
+			modelCache: &ModelCacheSpec{PersistentVolumeClaim: "HF_Cache"},
+			wantErr:    true,
+			errFields:  []string{"persistentVolumeClaim"},
+		},
+		{
+			name:       "Relative mount path",
+			modelCache: &ModelCacheSpec{PersistentVolumeClaim: "hf-cache", MountPath: "cache"},
+			wantErr:    true,
+			errFields:  []string{"mountPath"},
+		},
+		{
+			name:       "Zero size",
+			modelCache: &ModelCacheSpec{PersistentVolumeClaim: "hf-cache", Size: &zero},
+			wantErr:    true,
+			errFields:  []string{"size"},
+		},
+		{
+			name:       "Storage class without size",
+			modelCache: &ModelCacheSpec{PersistentVolumeClaim: "hf-cache", StorageClassName: lo.ToPtr("azurefile-csi")},
+			wantErr:    true,
+			errFields:  []string{"storageClassName"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.modelCache.validate()
+			hasErrs := errs != nil
+			if hasErrs != tt.wantErr {
+				t.Errorf("validate() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if hasErrs {
+				for _, field := range tt.errFields {
+					if !strings.Contains(errs.Error(), field) {
+						t.Errorf("validate() expected errors to contain field %s, but got %s", field, errs.Error())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCommonMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		commonMetadata *CommonMetadata
+		wantErr        bool
+		errFields      []string // Fields we expect to have errors
+	}{
+		{
+			name: "Valid labels and annotations",
+			commonMetadata: &CommonMetadata{
+				Labels:      map[string]string{"cost-center": "1234", "example.com/team": "ml"},
+				Annotations: map[string]string{"example.com/owner": "ML Platform <ml-platform@example.com>"},
+			},
+			wantErr: false,
+		},
+		{
+			name:           "Reserved label of kaito",
+			commonMetadata: &CommonMetadata{Labels: map[string]string{LabelWorkspaceName: "other"}},
+			wantErr:        true,
+			errFields:      []string{"labels"},
+		},
+		{
+			name:           "Reserved annotation of a Kubernetes subdomain",
+			commonMetadata: &CommonMetadata{Annotations: map[string]string{"node.kubernetes.io/instance-type": "Standard_NC12s_v3"}},
+			wantErr:        true,
+			errFields:      []string{"annotations"},
+		},
+		{
+			name:           "Invalid label value",
+			commonMetadata: &CommonMetadata{Labels: map[string]string{"team": "machine learning"}},
+			wantErr:        true,
+			errFields:      []string{"labels"},
+		},
+		{
+			name:           "Invalid annotation key",
+			commonMetadata: &CommonMetadata{Annotations: map[string]string{"owner email": "ml-platform@example.com"}},
+			wantErr:        true,
+			errFields:      []string{"annotations"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.commonMetadata.validate()
+			hasErrs := errs != nil
+			if hasErrs != tt.wantErr {
+				t.Errorf("validate() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if hasErrs {
+				for _, field := range tt.errFields {
+					if !strings.Contains(errs.Error(), field) {
+						t.Errorf("validate() expected errors to contain field %s, but got %s", field, errs.Error())
+					}
+				}
+			}
+		})
+	}
+}
+
 func TestTuningSpecValidateCreate(t *testing.T) {
 	RegisterValidationTestModels()
 	tests := []struct {
@@ -709,6 +2486,79 @@ func TestTuningSpecValidateCreate(t *testing.T) {
 			wantErr:   true,
 			errFields: []string{"Method"},
 		},
+		{
+			name: "Resume from checkpoint",
+			tuningSpec: &TuningSpec{
+				Input:      &DataSource{Name: "valid-input", HostPath: "valid-input"},
+				Output:     &DataDestination{HostPath: "valid-output"},
+				Preset:     &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Method:     TuningMethodLora,
+				Checkpoint: &CheckpointSpec{PersistentVolumeClaim: "checkpoints"},
+				Resume:     true,
+			},
+			wantErr:   false,
+			errFields: nil,
+		},
+		{
+			name: "Resume without checkpoint",
+			tuningSpec: &TuningSpec{
+				Input:  &DataSource{Name: "valid-input", HostPath: "valid-input"},
+				Output: &DataDestination{HostPath: "valid-output"},
+				Preset: &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Method: TuningMethodLora,
+				Resume: true,
+			},
+			wantErr:   true,
+			errFields: []string{"Checkpoint"},
+		},
+		{
+			name: "Negative cleanup delay",
+			tuningSpec: &TuningSpec{
+				Input:        &DataSource{Name: "valid-input", HostPath: "valid-input"},
+				Output:       &DataDestination{HostPath: "valid-output"},
+				Preset:       &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Method:       TuningMethodLora,
+				CleanupDelay: &metav1.Duration{Duration: -time.Minute},
+			},
+			wantErr:   true,
+			errFields: []string{"CleanupDelay"},
+		},
+		{
+			name: "Checkpoint without persistent volume claim",
+			tuningSpec: &TuningSpec{
+				Input:      &DataSource{Name: "valid-input", HostPath: "valid-input"},
+				Output:     &DataDestination{HostPath: "valid-output"},
+				Preset:     &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Method:     TuningMethodLora,
+				Checkpoint: &CheckpointSpec{},
+			},
+			wantErr:   true,
+			errFields: []string{"PersistentVolumeClaim"},
+		},
+		{
+			name: "Volume mounted from a persistent volume claim",
+			tuningSpec: &TuningSpec{
+				Input:        &DataSource{Name: "valid-input", HostPath: "valid-input"},
+				Output:       &DataDestination{HostPath: "valid-output"},
+				Preset:       &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Method:       TuningMethodLora,
+				Volumes:      []v1.Volume{{Name: "datasets", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "datasets"}}}},
+				VolumeMounts: []v1.VolumeMount{{Name: "datasets", MountPath: "/mnt/datasets"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Volume collides with the output volume",
+			tuningSpec: &TuningSpec{
+				Input:   &DataSource{Name: "valid-input", HostPath: "valid-input"},
+				Output:  &DataDestination{HostPath: "valid-output"},
+				Preset:  &PresetSpec{PresetMeta: PresetMeta{Name: ModelName("test-validation")}},
+				Method:  TuningMethodLora,
+				Volumes: []v1.Volume{{Name: OutputVolumeName, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}},
+			},
+			wantErr:   true,
+			errFields: []string{"collides with a volume that kaito manages"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1007,7 +2857,7 @@ func TestDataDestinationValidateCreate(t *testing.T) {
 			name:            "No fields specified",
 			dataDestination: &DataDestination{},
 			wantErr:         true,
-			errField:        "At least one of HostPath or Image must be specified",
+			errField:        "At least one of HostPath, Image or URL must be specified",
 		},
 		{
 			name: "HostPath specified only",
@@ -1018,16 +2868,51 @@ func TestDataDestinationValidateCreate(t *testing.T) {
 		},
 		{
 			name: "Image specified only",
+			dataDestination: &DataDestination{
+				Image:           "data-image:latest",
+				ImagePushSecret: "push-secret",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Image specified without push secret",
 			dataDestination: &DataDestination{
 				Image: "data-image:latest",
 			},
+			wantErr:  true,
+			errField: "ImagePushSecret",
+		},
+		{
+			name: "URL specified only",
+			dataDestination: &DataDestination{
+				URL:       "https://account.blob.core.windows.net/container/output",
+				URLSecret: "storage-secret",
+			},
 			wantErr: false,
 		},
+		{
+			name: "URL specified without secret",
+			dataDestination: &DataDestination{
+				URL: "https://account.blob.core.windows.net/container/output",
+			},
+			wantErr:  true,
+			errField: "URLSecret",
+		},
+		{
+			name: "Invalid URL",
+			dataDestination: &DataDestination{
+				URL:       "not-a-url",
+				URLSecret: "storage-secret",
+			},
+			wantErr:  true,
+			errField: "URL",
+		},
 		{
 			name: "Both fields specified",
 			dataDestination: &DataDestination{
-				HostPath: "/data/path",
-				Image:    "data-image:latest",
+				HostPath:        "/data/path",
+				Image:           "data-image:latest",
+				ImagePushSecret: "push-secret",
 			},
 			wantErr: false,
 		},
@@ -1104,6 +2989,19 @@ func TestDataDestinationValidateUpdate(t *testing.T) {
 			wantErr:   true,
 			errFields: []string{"ImagePushSecret"},
 		},
+		{
+			name: "URL changed",
+			oldDest: &DataDestination{
+				URL:       "https://account.blob.core.windows.net/old",
+				URLSecret: "secret",
+			},
+			newDest: &DataDestination{
+				URL:       "https://account.blob.core.windows.net/new",
+				URLSecret: "secret",
+			},
+			wantErr:   true,
+			errFields: []string{"URL"},
+		},
 	}
 
 	for _, tt := range tests {
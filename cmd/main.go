@@ -9,7 +9,13 @@ import (
 	"time"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/audit"
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/controllers"
+	"github.com/azure/kaito/pkg/gpumetrics"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/notification"
+	"github.com/azure/kaito/pkg/registry"
 	"github.com/azure/kaito/pkg/webhooks"
 	"k8s.io/klog/v2"
 	"knative.dev/pkg/injection/sharedmain"
@@ -23,6 +29,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -60,6 +67,27 @@ func main() {
 	var enableLeaderElection bool
 	var enableWebhook bool
 	var probeAddr string
+	var cloudProviderName string
+	var failureWebhookURL string
+	var region string
+	var provisioningRequeueInterval time.Duration
+	var readyRequeueInterval time.Duration
+	var schedulingFailureThreshold time.Duration
+	var provisioningRetryBudget int
+	var provisioningRetryWindow time.Duration
+	var provisioningTimeout time.Duration
+	var skuReprobeInterval time.Duration
+	var maxMachineCreateAttempts int
+	var mirrorRegistry string
+	var enableAuditLog bool
+	var auditLogPath string
+	var gpuMetricsURL string
+	var scaleDownUtilizationThreshold float64
+	var scaleDownWindow time.Duration
+	var inferenceSmokeTest bool
+	var defaultProvisioningParallelism int
+	var provisioningParallelism string
+	var warmPools string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -67,6 +95,56 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&enableWebhook, "webhook", true,
 		"Enable webhook for controller manager. Default is true.")
+	flag.StringVar(&cloudProviderName, "cloud-provider", "",
+		"The cloud provider that GPU nodes are provisioned from. If empty, the nodes are provisioned as from azure, "+
+			"and the instance types are accepted if they follow the naming scheme of any provider.")
+	flag.StringVar(&failureWebhookURL, "failure-webhook-url", "",
+		"The URL that node provisioning failures are posted to. Failures are not posted if empty.")
+	flag.StringVar(&region, "region", "",
+		"The region GPU nodes are provisioned in. The availability of the instance types is not tracked if empty.")
+	flag.DurationVar(&provisioningRequeueInterval, "provisioning-requeue-interval", controllers.DefaultRequeueIntervals.Provisioning,
+		"The interval a workspace is reconciled at while it is provisioning.")
+	flag.DurationVar(&readyRequeueInterval, "ready-requeue-interval", controllers.DefaultRequeueIntervals.Ready,
+		"The interval a workspace is reconciled at once it is ready.")
+	flag.DurationVar(&schedulingFailureThreshold, "scheduling-failure-threshold", controllers.DefaultSchedulingFailureThreshold,
+		"How long an inference pod may stay unschedulable before the failure is reported in the workspace status.")
+	flag.IntVar(&provisioningRetryBudget, "provisioning-retry-budget", int(controllers.DefaultRetryBudget.MaxFailures),
+		"The number of failed provisioning attempts of a workspace within the retry window after which the provisioning backs off.")
+	flag.DurationVar(&provisioningRetryWindow, "provisioning-retry-window", controllers.DefaultRetryBudget.Window,
+		"The window the failed provisioning attempts of a workspace are counted in.")
+	flag.DurationVar(&provisioningTimeout, "provisioning-timeout", controllers.DefaultProvisioningTimeout,
+		"How long the nodes of a workspace may take to be provisioned before the provisioning stops until the workspace is changed. "+
+			"A workspace may configure its own timeout.")
+	flag.DurationVar(&skuReprobeInterval, "sku-reprobe-interval", controllers.DefaultSKUReprobeInterval,
+		"The interval the instance types of a workspace that could not be provisioned for lack of capacity are checked again at.")
+	flag.IntVar(&maxMachineCreateAttempts, "max-machine-create-attempts-per-reconcile", machine.DefaultMaxCreateAttempts,
+		"The number of attempts to create a machine within a reconcile, after which the workspace is requeued.")
+	flag.BoolVar(&machine.EnableGPUStartupTaint, "gpu-startup-taint", machine.EnableGPUStartupTaint,
+		"Provision the GPU nodes with the startup taint "+machine.GPUStartupTaintKey+", which is removed once their GPUs are registered. "+
+			"The NVIDIA device plugin must tolerate the taint. Default is false.")
+	flag.BoolVar(&enableAuditLog, "audit-log", true,
+		"Write the audit records of the provisioning decisions as JSON lines to stdout. Default is true.")
+	flag.StringVar(&auditLogPath, "audit-log-path", "",
+		"The file that the audit records are appended to as JSON lines instead of stdout, if --audit-log is enabled. Default is stdout.")
+	flag.StringVar(&mirrorRegistry, "mirror-registry", "",
+		"The mirror registry of an air-gapped cluster that the images of the workspaces are checked in before their nodes are provisioned. "+
+			"The images are not checked if empty.")
+	flag.StringVar(&gpuMetricsURL, "gpu-metrics-prometheus-url", "",
+		"The URL of the Prometheus that collects the GPU metrics of the nodes. Idle nodes are not scaled down if empty.")
+	flag.Float64Var(&scaleDownUtilizationThreshold, "scale-down-utilization-threshold", controllers.DefaultScaleDownUtilizationThreshold,
+		"The GPU memory utilization, between 0 and 1, below which the node of an autoscaling workspace is idle.")
+	flag.DurationVar(&scaleDownWindow, "scale-down-window", controllers.DefaultScaleDownWindow,
+		"How long the GPU memory utilization of a node must stay below the threshold before the node is scaled down.")
+	flag.BoolVar(&inferenceSmokeTest, "inference-smoke-test", false,
+		"Only mark a preset inference workspace ready once its model server answers a canned inference request. Default is false.")
+	flag.IntVar(&defaultProvisioningParallelism, "default-provisioning-parallelism", 0,
+		"The number of machines a provisioner may provision at once, unless the provisioner is annotated with "+
+			kaitov1alpha1.AnnotationProvisioningParallelism+" or configured with --provisioning-parallelism. Unlimited if 0.")
+	flag.StringVar(&provisioningParallelism, "provisioning-parallelism", "",
+		"The number of machines each provisioner may provision at once, as a comma separated list of <provisioner>=<limit> pairs, e.g., default=4,slow=1.")
+	flag.StringVar(&warmPools, "warm-pools", "",
+		"The warm pools of idle machines that the new workspaces of a preset claim, as a comma separated list of "+
+			"<preset>:<instance type>=<count> entries, e.g., falcon-7b:Standard_NC12s_v3=2. No machines are kept idle if empty.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -75,7 +153,26 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	var err error
+	provider := cloudprovider.Default
+	if cloudProviderName != "" {
+		if provider, err = cloudprovider.Get(cloudProviderName); err != nil {
+			klog.ErrorS(err, "unable to get the cloud provider")
+			exitWithErrorFunc()
+		}
+		// The webhooks and the manifests that are not built by the reconciler use the default cloud provider.
+		cloudprovider.Default = provider
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	// The machines are stored in the newest version of the karpenter API that the cluster serves.
+	if machine.DefaultAPI, err = machine.DetectAPI(discovery.NewDiscoveryClientForConfigOrDie(restConfig)); err != nil {
+		klog.ErrorS(err, "unable to detect the version of the karpenter API")
+		exitWithErrorFunc()
+	}
+	klog.InfoS("Detected the version of the karpenter API", "kind", machine.DefaultAPI.GroupVersionKind())
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		HealthProbeBindAddress: probeAddr,
@@ -98,17 +195,86 @@ func main() {
 		exitWithErrorFunc()
 	}
 
-	if err = (&controllers.WorkspaceReconciler{
-		Client:   mgr.GetClient(),
-		Log:      log.Log.WithName("controllers").WithName("Workspace"),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("KAITO-Workspace-controller"),
-	}).SetupWithManager(mgr); err != nil {
+	workspaceReconciler := &controllers.WorkspaceReconciler{
+		Client:        mgr.GetClient(),
+		Log:           log.Log.WithName("controllers").WithName("Workspace"),
+		Scheme:        mgr.GetScheme(),
+		Recorder:      mgr.GetEventRecorderFor("KAITO-Workspace-controller"),
+		CloudProvider: provider,
+		Region:        region,
+		RequeueIntervals: controllers.RequeueIntervals{
+			Provisioning: provisioningRequeueInterval,
+			Ready:        readyRequeueInterval,
+		},
+		SchedulingFailureThreshold: schedulingFailureThreshold,
+		RetryBudget: controllers.RetryBudget{
+			MaxFailures: int32(provisioningRetryBudget),
+			Window:      provisioningRetryWindow,
+		},
+		ProvisioningTimeout:                  provisioningTimeout,
+		SKUReprobeInterval:                   skuReprobeInterval,
+		MaxMachineCreateAttemptsPerReconcile: maxMachineCreateAttempts,
+		InferenceSmokeTest:                   inferenceSmokeTest,
+	}
+	provisioningParallelismLimits, err := machine.ParseProvisioningParallelism(provisioningParallelism)
+	if err != nil {
+		klog.ErrorS(err, "invalid provisioning parallelism")
+		exitWithErrorFunc()
+	}
+	workspaceReconciler.ProvisioningParallelism = &machine.ProvisioningParallelism{
+		Default: defaultProvisioningParallelism,
+		Limits:  provisioningParallelismLimits,
+	}
+	pools, err := machine.ParseWarmPools(warmPools)
+	if err != nil {
+		klog.ErrorS(err, "invalid warm pools")
+		exitWithErrorFunc()
+	}
+	if len(pools) > 0 {
+		if err := mgr.Add(&controllers.WarmPoolProvisioner{
+			Client:        mgr.GetClient(),
+			CloudProvider: provider,
+			Namespace:     os.Getenv("SYSTEM_NAMESPACE"),
+			Pools:         pools,
+		}); err != nil {
+			klog.ErrorS(err, "unable to set up the warm pool provisioner")
+			exitWithErrorFunc()
+		}
+	}
+	if failureWebhookURL != "" {
+		workspaceReconciler.NotificationSink = notification.NewWebhookSink(failureWebhookURL)
+	}
+	var auditSink *audit.JSONLinesSink
+	if enableAuditLog {
+		auditSink = audit.NewStdoutSink()
+		if auditLogPath != "" {
+			if auditSink, err = audit.NewFileSink(auditLogPath); err != nil {
+				klog.ErrorS(err, "unable to open the audit log", "path", auditLogPath)
+				exitWithErrorFunc()
+			}
+		}
+		workspaceReconciler.AuditSink = auditSink
+	}
+	if mirrorRegistry != "" {
+		workspaceReconciler.ImageMirror = registry.NewMirrorClient(mirrorRegistry)
+	}
+	if gpuMetricsURL != "" {
+		workspaceReconciler.GPUScaleDown = &controllers.GPUScaleDown{
+			Source:               gpumetrics.NewPrometheusSource(gpuMetricsURL),
+			UtilizationThreshold: scaleDownUtilizationThreshold,
+			Window:               scaleDownWindow,
+		}
+	}
+	if err = workspaceReconciler.SetupWithManager(mgr); err != nil {
 		klog.ErrorS(err, "unable to create controller", "controller", "Workspace")
 		exitWithErrorFunc()
 	}
 	//+kubebuilder:scaffold:builder
 
+	if err := mgr.AddMetricsExtraHandler(controllers.ReadinessPath, workspaceReconciler.ReadinessHandler()); err != nil {
+		klog.ErrorS(err, "unable to set up the workspace readiness endpoint")
+		exitWithErrorFunc()
+	}
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		klog.ErrorS(err, "unable to set up health check")
 		exitWithErrorFunc()
@@ -140,8 +306,15 @@ func main() {
 	}
 
 	klog.InfoS("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		klog.ErrorS(err, "problem running manager")
+	mgrErr := mgr.Start(ctrl.SetupSignalHandler())
+	// The reconcilers have stopped once the manager returns, no more records are written to the audit log.
+	if auditSink != nil {
+		if err := auditSink.Close(); err != nil {
+			klog.ErrorS(err, "unable to close the audit log", "path", auditLogPath)
+		}
+	}
+	if mgrErr != nil {
+		klog.ErrorS(mgrErr, "problem running manager")
 		exitWithErrorFunc()
 	}
 }
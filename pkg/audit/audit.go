@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Action is a provisioning decision that kaito audits.
+type Action string
+
+const (
+	// ActionSKUSelected is recorded when the instance type of the workspace is selected for a new machine.
+	ActionSKUSelected Action = "SKUSelected"
+	// ActionFallbackTriggered is recorded when a fallback instance type is selected because the instance type of the
+	// workspace cannot be provisioned.
+	ActionFallbackTriggered Action = "FallbackTriggered"
+	// ActionNodeProvisioned is recorded when a node is ready for the workspace.
+	ActionNodeProvisioned Action = "NodeProvisioned"
+	// ActionNodeDeleted is recorded when the machine of a node of the workspace is deleted.
+	ActionNodeDeleted Action = "NodeDeleted"
+)
+
+// Record is an audited provisioning decision of a workspace.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Action    Action    `json:"action"`
+	Workspace string    `json:"workspace"`
+	Namespace string    `json:"namespace"`
+	// Team is the team that owns the workspace, if the workspace is labeled with one.
+	Team    string `json:"team,omitempty"`
+	SKU     string `json:"sku,omitempty"`
+	Machine string `json:"machine,omitempty"`
+	Node    string `json:"node,omitempty"`
+	// Reason explains why the decision was taken.
+	Reason string `json:"reason"`
+}
+
+// AuditSink is where kaito records the audit trail of its provisioning decisions.
+type AuditSink interface {
+	Emit(ctx context.Context, record Record) error
+}
+
+// JSONLinesSink writes each record as a line of JSON.
+type JSONLinesSink struct {
+	mu     sync.Mutex
+	Writer io.Writer
+	// closer is the file the sink opened, if any.
+	closer io.Closer
+}
+
+var _ AuditSink = &JSONLinesSink{}
+
+// NewStdoutSink returns a sink that writes the records to stdout, the logs of kaito are written to stderr.
+func NewStdoutSink() *JSONLinesSink {
+	return &JSONLinesSink{Writer: os.Stdout}
+}
+
+// NewFileSink returns a sink that appends the records to the file at the path, so that the audit trail is kept apart
+// from the logs of kaito. The file is created if it does not exist, only its owner can read it.
+func NewFileSink(path string) (*JSONLinesSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesSink{Writer: file, closer: file}, nil
+}
+
+func (s *JSONLinesSink) Emit(ctx context.Context, record Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	// The lines of concurrent reconciles must not interleave.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.Writer.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file the sink opened. The writer of a sink that was not opened by NewFileSink, e.g., stdout, is
+// left open.
+func (s *JSONLinesSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closer.Close()
+}
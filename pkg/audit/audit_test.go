@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestJSONLinesSinkEmit(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	sink := &JSONLinesSink{Writer: buffer}
+
+	records := []Record{
+		{Action: ActionSKUSelected, Workspace: "ws", Namespace: "default", Team: "search", SKU: "Standard_NC12s_v3", Reason: "selected"},
+		{Action: ActionNodeDeleted, Workspace: "ws", Namespace: "default", Machine: "ws0", Node: "node-1", Reason: "deleted"},
+	}
+	for _, record := range records {
+		assert.NilError(t, sink.Emit(context.Background(), record))
+	}
+
+	scanner := bufio.NewScanner(buffer)
+	var lines int
+	for ; scanner.Scan(); lines++ {
+		decoded := Record{}
+		assert.NilError(t, json.Unmarshal(scanner.Bytes(), &decoded))
+		assert.Check(t, !decoded.Time.IsZero(), "Expected the record to be timestamped")
+		decoded.Time = records[lines].Time
+		assert.DeepEqual(t, decoded, records[lines])
+	}
+	assert.Equal(t, lines, len(records))
+}
+
+func TestNewFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// The records of a restarted kaito are appended to the audit trail.
+	for _, workspace := range []string{"ws1", "ws2"} {
+		sink, err := NewFileSink(path)
+		assert.NilError(t, err)
+		assert.NilError(t, sink.Emit(context.Background(), Record{Action: ActionSKUSelected, Workspace: workspace, Namespace: "default"}))
+		assert.NilError(t, sink.Close())
+		assert.Check(t, sink.Emit(context.Background(), Record{Action: ActionSKUSelected}) != nil, "Expected an error for a closed sink")
+	}
+
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o600))
+	content, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	assert.Equal(t, len(lines), 2)
+	for i, workspace := range []string{"ws1", "ws2"} {
+		decoded := Record{}
+		assert.NilError(t, json.Unmarshal(lines[i], &decoded))
+		assert.Equal(t, decoded.Workspace, workspace)
+	}
+
+	_, err = NewFileSink(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Check(t, err != nil, "Expected an error for a directory that does not exist")
+}
+
+func TestCloseKeepsWriterOpen(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &JSONLinesSink{Writer: &buf}
+
+	// The writer of a sink that did not open a file, e.g., stdout, is not closed.
+	assert.NilError(t, sink.Close())
+	assert.NilError(t, sink.Emit(context.Background(), Record{Action: ActionSKUSelected, Workspace: "ws1"}))
+	assert.Check(t, buf.Len() > 0, "Expected the record to be written")
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cloudprovider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// DefaultSKUCacheTTL is how long a SKU that failed to launch is considered unavailable. The capacity of a region
+// changes frequently, so the SKU is only skipped briefly.
+const DefaultSKUCacheTTL = 5 * time.Minute
+
+type skuCacheKey struct {
+	provider string
+	region   string
+	sku      string
+}
+
+// SKUCache remembers the SKUs that failed to launch in a region, e.g., because they were out of capacity or quota.
+// The cloud providers do not expose which SKUs are provisionable, so the availability is learned from the launches.
+type SKUCache struct {
+	ttl time.Duration
+	now func() time.Time
+	mu  sync.Mutex
+	// unavailable maps the SKUs that failed to launch to the time they may be launched again.
+	unavailable map[skuCacheKey]time.Time
+}
+
+// NewSKUCache returns a SKU cache whose entries expire after the given ttl.
+func NewSKUCache(ttl time.Duration) *SKUCache {
+	return &SKUCache{
+		ttl:         ttl,
+		now:         time.Now,
+		unavailable: map[skuCacheKey]time.Time{},
+	}
+}
+
+var defaultSKUCache = NewSKUCache(DefaultSKUCacheTTL)
+
+// AvailableRegionSKUs returns the SKUs that have not failed to launch in the region recently, in the order they are given.
+func AvailableRegionSKUs(region string, skus []string, provider CloudProvider) []string {
+	return defaultSKUCache.Available(region, skus, provider)
+}
+
+// MarkRegionSKUUnavailable records that the SKU failed to launch in the region, so that it is skipped until the
+// cache entry expires.
+func MarkRegionSKUUnavailable(region, sku string, provider CloudProvider) {
+	defaultSKUCache.MarkUnavailable(region, sku, provider)
+}
+
+// Available returns the SKUs without a valid unavailability entry in the region. Without a region, the availability
+// is not tracked and all SKUs are returned.
+func (c *SKUCache) Available(region string, skus []string, provider CloudProvider) []string {
+	if region == "" {
+		return skus
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	return lo.Filter(skus, func(sku string, _ int) bool {
+		availableAt, found := c.unavailable[skuCacheKey{provider: provider.Name(), region: region, sku: sku}]
+		return !found || !now.Before(availableAt)
+	})
+}
+
+// MarkUnavailable records that the SKU failed to launch in the region.
+func (c *SKUCache) MarkUnavailable(region, sku string, provider CloudProvider) {
+	if region == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unavailable[skuCacheKey{provider: provider.Name(), region: region, sku: sku}] = c.now().Add(c.ttl)
+}
@@ -0,0 +1,41 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cloudprovider
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestSKUCache(t *testing.T) {
+	skus := []string{"Standard_NC24ads_A100_v4", "Standard_NC12s_v3", "Standard_NC6s_v3"}
+	cache := NewSKUCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	provider := &AzureProvider{}
+
+	// All SKUs are available until they fail to launch.
+	assert.DeepEqual(t, cache.Available("eastus", skus, provider), skus)
+
+	cache.MarkUnavailable("eastus", "Standard_NC24ads_A100_v4", provider)
+	cache.MarkUnavailable("eastus", "Standard_NC6s_v3", provider)
+	assert.DeepEqual(t, cache.Available("eastus", skus, provider), []string{"Standard_NC12s_v3"})
+
+	// Another region and another provider are tracked separately.
+	assert.DeepEqual(t, cache.Available("westus", skus, provider), skus)
+	assert.DeepEqual(t, cache.Available("eastus", skus, &AWSProvider{}), skus)
+
+	// The availability is not tracked without a region.
+	cache.MarkUnavailable("", "Standard_NC12s_v3", provider)
+	assert.DeepEqual(t, cache.Available("", skus, provider), skus)
+
+	// The SKUs are available again once the entries have expired.
+	now = now.Add(30 * time.Second)
+	cache.MarkUnavailable("eastus", "Standard_NC12s_v3", provider)
+	now = now.Add(45 * time.Second)
+	assert.DeepEqual(t, cache.Available("eastus", skus, provider), []string{"Standard_NC24ads_A100_v4", "Standard_NC6s_v3"})
+	now = now.Add(time.Minute)
+	assert.DeepEqual(t, cache.Available("eastus", skus, provider), skus)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cloudprovider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	awsProviderIDPrefix = "aws://"
+)
+
+// awsGPUFamilies lists the EC2 instance families that come with NVIDIA GPUs.
+var awsGPUFamilies = []string{"p2", "p3", "p3dn", "p4d", "p4de", "p5", "g3", "g3s", "g4dn", "g5", "g5g", "g6"}
+
+// awsEFAInstanceTypes lists the GPU instance types that support the Elastic Fabric Adapter.
+var awsEFAInstanceTypes = []string{"p3dn.24xlarge", "p4d.24xlarge", "p4de.24xlarge", "p5.48xlarge", "g5.48xlarge"}
+
+// awsArchAttributeRegex captures the attributes following the generation of an instance family, e.g., "g" in g5g,
+// which denotes a Graviton processor.
+var awsArchAttributeRegex = regexp.MustCompile(`^[a-z]+[0-9]+([a-z]*)$`)
+
+// awsInstanceTypeRegex matches the names of EC2 instance types, e.g., g5.12xlarge, and captures the family.
+var awsInstanceTypeRegex = regexp.MustCompile(`^([a-z]+[0-9][a-z0-9]*)\.(?:nano|micro|small|medium|large|[0-9]*xlarge|metal)$`)
+
+// awsHostMemoryGiB is the host memory of the EC2 GPU instance types in GiB.
+var awsHostMemoryGiB = map[string]int64{
+	"p3.2xlarge":    61,
+	"p3.8xlarge":    244,
+	"p3.16xlarge":   488,
+	"p3dn.24xlarge": 768,
+	"p4d.24xlarge":  1152,
+	"p4de.24xlarge": 1152,
+	"p5.48xlarge":   2048,
+	"g4dn.xlarge":   16,
+	"g4dn.2xlarge":  32,
+	"g4dn.4xlarge":  64,
+	"g4dn.8xlarge":  128,
+	"g4dn.12xlarge": 192,
+	"g4dn.16xlarge": 256,
+	"g5.xlarge":     16,
+	"g5.2xlarge":    32,
+	"g5.4xlarge":    64,
+	"g5.8xlarge":    128,
+	"g5.12xlarge":   192,
+	"g5.16xlarge":   256,
+	"g5.24xlarge":   384,
+	"g5.48xlarge":   768,
+}
+
+// AWSProvider is a stub implementation for provisioning GPU machines in AWS.
+type AWSProvider struct{}
+
+var _ CloudProvider = &AWSProvider{}
+
+func (*AWSProvider) Name() string {
+	return ProviderAWS
+}
+
+func (*AWSProvider) IsGPUInstanceType(instanceType string) bool {
+	family, _, found := strings.Cut(instanceType, ".")
+	if !found {
+		return false
+	}
+	for _, f := range awsGPUFamilies {
+		if family == f {
+			return true
+		}
+	}
+	return false
+}
+
+func (*AWSProvider) InstanceTypeLabel() string {
+	return corev1.LabelInstanceTypeStable
+}
+
+func (*AWSProvider) CapacityTypeLabel() string {
+	return v1alpha5.LabelCapacityType
+}
+
+// ParseProviderID returns the instance ID from a provider ID like aws:///<zone>/<instance-id>.
+func (*AWSProvider) ParseProviderID(providerID string) (string, error) {
+	if !strings.HasPrefix(providerID, awsProviderIDPrefix) {
+		return "", fmt.Errorf("invalid aws provider ID %q", providerID)
+	}
+	segments := strings.Split(strings.TrimPrefix(providerID, awsProviderIDPrefix), "/")
+	id := segments[len(segments)-1]
+	if !strings.HasPrefix(id, "i-") {
+		return "", fmt.Errorf("invalid aws provider ID %q", providerID)
+	}
+	return id, nil
+}
+
+func (*AWSProvider) SupportsRDMA(instanceType string) bool {
+	for _, t := range awsEFAInstanceTypes {
+		if instanceType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateInstanceType checks the instance type name against the naming scheme of EC2 and the GPU families.
+func (*AWSProvider) ValidateInstanceType(instanceType string) error {
+	matches := awsInstanceTypeRegex.FindStringSubmatch(instanceType)
+	if matches == nil {
+		return fmt.Errorf("invalid EC2 instance type %s, expected a name like g5.12xlarge", instanceType)
+	}
+	if family := matches[1]; !lo.Contains(awsGPUFamilies, family) {
+		return fmt.Errorf("unsupported family %s of EC2 instance type %s, supported families: %s", family, instanceType,
+			strings.Join(awsGPUFamilies, ", "))
+	}
+	return nil
+}
+
+func (*AWSProvider) HostMemory(instanceType string) *resource.Quantity {
+	return hostMemory(awsHostMemoryGiB, instanceType)
+}
+
+// Architecture returns arm64 for the Graviton instance types, e.g., g5g.xlarge.
+func (*AWSProvider) Architecture(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	matches := awsArchAttributeRegex.FindStringSubmatch(family)
+	if matches != nil && strings.Contains(matches[1], "g") {
+		return ArchARM64
+	}
+	return ArchAMD64
+}
@@ -0,0 +1,149 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cloudprovider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	azureProviderIDPrefix = "azure://"
+	azureGPUSKUPrefix     = "Standard_N"
+)
+
+// azureSKUSizeRegex captures the additive features of an Azure SKU name, e.g., "rs" in Standard_NC24rs_v3.
+var azureSKUSizeRegex = regexp.MustCompile(`^Standard_[A-Z]+[0-9]+([a-z]*)`)
+
+// azureSKUNameRegex matches the names of Azure SKUs, e.g., Standard_NC24ads_A100_v4 or the constrained vCPU size
+// Standard_E4-2ds_v4, and captures the family, e.g., "NC".
+var azureSKUNameRegex = regexp.MustCompile(`^Standard_([A-Z]+)(?:[0-9]+(?:-[0-9]+)?[a-z]*)?(?:_[A-Z][A-Z0-9]*)*(?:_v[0-9]+)?$`)
+
+// azureInstanceFamilies are the SKU families kaito runs on, the N-series GPU families and the D-series.
+var azureInstanceFamilies = []string{"D", "NC", "NCC", "ND", "NG", "NP", "NV"}
+
+// azureHostMemoryGiB is the host memory of the Azure GPU SKUs in GiB.
+var azureHostMemoryGiB = map[string]int64{
+	"Standard_NC6":              56,
+	"Standard_NC12":             112,
+	"Standard_NC24":             224,
+	"Standard_NC24r":            224,
+	"Standard_NV6":              56,
+	"Standard_NV12":             112,
+	"Standard_NV24":             224,
+	"Standard_NV12s_v3":         112,
+	"Standard_NV24s_v3":         224,
+	"Standard_NV48s_v3":         448,
+	"Standard_ND6s":             112,
+	"Standard_ND12s":            224,
+	"Standard_ND24s":            448,
+	"Standard_ND24rs":           448,
+	"Standard_NC6s_v2":          112,
+	"Standard_NC12s_v2":         224,
+	"Standard_NC24s_v2":         448,
+	"Standard_NC24rs_v2":        448,
+	"Standard_NC6s_v3":          112,
+	"Standard_NC12s_v3":         224,
+	"Standard_NC24s_v3":         448,
+	"Standard_NC24rs_v3":        448,
+	"Standard_ND40rs_v2":        672,
+	"Standard_NC4as_T4_v3":      28,
+	"Standard_NC8as_T4_v3":      56,
+	"Standard_NC16as_T4_v3":     110,
+	"Standard_NC64as_T4_v3":     440,
+	"Standard_ND96asr_v4":       900,
+	"Standard_ND96amsr_A100_v4": 1900,
+	"Standard_NC24ads_A100_v4":  220,
+	"Standard_NC48ads_A100_v4":  440,
+	"Standard_NC96ads_A100_v4":  880,
+	"Standard_NV6ads_A10_v5":    55,
+	"Standard_NV12ads_A10_v5":   110,
+	"Standard_NV18ads_A10_v5":   220,
+	"Standard_NV36ads_A10_v5":   440,
+	"Standard_NV36adms_A10_v5":  880,
+	"Standard_NV72ads_A10_v5":   880,
+}
+
+// AzureProvider is the default cloud provider.
+type AzureProvider struct{}
+
+var _ CloudProvider = &AzureProvider{}
+
+func (*AzureProvider) Name() string {
+	return ProviderAzure
+}
+
+func (*AzureProvider) IsGPUInstanceType(instanceType string) bool {
+	return strings.HasPrefix(instanceType, azureGPUSKUPrefix)
+}
+
+func (*AzureProvider) InstanceTypeLabel() string {
+	return corev1.LabelInstanceTypeStable
+}
+
+func (*AzureProvider) CapacityTypeLabel() string {
+	return v1alpha5.LabelCapacityType
+}
+
+// ParseProviderID returns the VM name from a provider ID like
+// azure:///subscriptions/<id>/resourceGroups/<rg>/providers/Microsoft.Compute/virtualMachines/<name>.
+func (*AzureProvider) ParseProviderID(providerID string) (string, error) {
+	if !strings.HasPrefix(providerID, azureProviderIDPrefix) {
+		return "", fmt.Errorf("invalid azure provider ID %q", providerID)
+	}
+	segments := strings.Split(strings.TrimRight(providerID, "/"), "/")
+	name := segments[len(segments)-1]
+	if name == "" || len(segments) < 2 || !strings.EqualFold(segments[len(segments)-2], "virtualMachines") {
+		return "", fmt.Errorf("invalid azure provider ID %q", providerID)
+	}
+	return name, nil
+}
+
+// SupportsRDMA returns true if the SKU carries the "r" additive feature, which denotes an RDMA capable SKU.
+func (*AzureProvider) SupportsRDMA(instanceType string) bool {
+	matches := azureSKUSizeRegex.FindStringSubmatch(instanceType)
+	if matches == nil {
+		return false
+	}
+	return strings.Contains(matches[1], "r")
+}
+
+// ValidateInstanceType checks the SKU name against the naming scheme of Azure, and suggests the name with the
+// underscores Azure uses if the name has a hyphen instead.
+func (*AzureProvider) ValidateInstanceType(instanceType string) error {
+	matches := azureSKUNameRegex.FindStringSubmatch(instanceType)
+	if matches == nil {
+		if suggestion := strings.ReplaceAll(instanceType, "-", "_"); azureSKUNameRegex.MatchString(suggestion) {
+			return fmt.Errorf("invalid Azure SKU name %s, did you mean %s?", instanceType, suggestion)
+		}
+		return fmt.Errorf("invalid Azure SKU name %s, expected a name like Standard_NC12s_v3", instanceType)
+	}
+	if family := matches[1]; !lo.Contains(azureInstanceFamilies, family) {
+		return fmt.Errorf("unsupported family %s of Azure SKU %s, supported families: %s", family, instanceType,
+			strings.Join(azureInstanceFamilies, ", "))
+	}
+	return nil
+}
+
+func (*AzureProvider) HostMemory(instanceType string) *resource.Quantity {
+	return hostMemory(azureHostMemoryGiB, instanceType)
+}
+
+// Architecture returns arm64 for the Grace Hopper SKUs and the SKUs that carry the "p" additive feature,
+// which denotes an ARM based processor.
+func (*AzureProvider) Architecture(instanceType string) string {
+	if strings.Contains(instanceType, "GH200") {
+		return ArchARM64
+	}
+	matches := azureSKUSizeRegex.FindStringSubmatch(instanceType)
+	if matches != nil && strings.Contains(matches[1], "p") {
+		return ArchARM64
+	}
+	return ArchAMD64
+}
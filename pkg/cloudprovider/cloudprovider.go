@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cloudprovider
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	ProviderAzure = "azure"
+	ProviderAWS   = "aws"
+
+	ArchAMD64 = "amd64"
+	ArchARM64 = "arm64"
+)
+
+// CloudProvider abstracts the cloud specific details that are needed to provision GPU machines.
+type CloudProvider interface {
+	// Name returns the name of the cloud provider.
+	Name() string
+	// IsGPUInstanceType returns true if the instance type name belongs to a GPU SKU family of the provider.
+	IsGPUInstanceType(instanceType string) bool
+	// InstanceTypeLabel returns the node label key that carries the instance type.
+	InstanceTypeLabel() string
+	// CapacityTypeLabel returns the node label key that carries the capacity type, e.g., spot or on-demand.
+	CapacityTypeLabel() string
+	// ParseProviderID extracts the instance identifier from a node's spec.providerID.
+	ParseProviderID(providerID string) (string, error)
+	// SupportsRDMA returns true if the instance type comes with RDMA capable (InfiniBand) networking.
+	SupportsRDMA(instanceType string) bool
+	// ValidateInstanceType returns an error if the instance type does not follow the naming scheme of the provider
+	// or belongs to an instance family that kaito does not run on, e.g., due to a typo.
+	ValidateInstanceType(instanceType string) error
+	// Architecture returns the CPU architecture of the instance type, i.e., ArchAMD64 or ArchARM64.
+	Architecture(instanceType string) string
+	// HostMemory returns the host memory of the instance type, e.g., to convert the model weights before they are
+	// loaded onto the GPUs. It returns nil if the host memory of the instance type is not known.
+	HostMemory(instanceType string) *resource.Quantity
+}
+
+var (
+	// Default is the cloud provider used when none is specified. The manager sets it from its --cloud-provider flag,
+	// it is an UnknownProvider if the flag is not set.
+	Default CloudProvider = &UnknownProvider{}
+
+	providers = map[string]CloudProvider{
+		ProviderAzure: &AzureProvider{},
+		ProviderAWS:   &AWSProvider{},
+	}
+)
+
+// UnknownProvider is the cloud provider of a cluster whose provider is not configured. The nodes are provisioned as
+// from azure, but an instance type is valid if it follows the naming scheme of any provider, so that the webhook does
+// not reject the instance types of another provider.
+type UnknownProvider struct {
+	AzureProvider
+}
+
+var _ CloudProvider = &UnknownProvider{}
+
+func (p *UnknownProvider) ValidateInstanceType(instanceType string) error {
+	err := p.AzureProvider.ValidateInstanceType(instanceType)
+	if err == nil {
+		return nil
+	}
+	for _, provider := range providers {
+		if provider.ValidateInstanceType(instanceType) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// hostMemory returns the host memory in GiB from the table of the provider, or nil if the instance type is not in it.
+func hostMemory(gibByInstanceType map[string]int64, instanceType string) *resource.Quantity {
+	gib, ok := gibByInstanceType[instanceType]
+	if !ok {
+		return nil
+	}
+	return resource.NewQuantity(gib<<30, resource.BinarySI)
+}
+
+// Get returns the cloud provider registered with the given name.
+func Get(name string) (CloudProvider, error) {
+	if p, ok := providers[name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unsupported cloud provider %q", name)
+}
@@ -0,0 +1,303 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cloudprovider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGet(t *testing.T) {
+	p, err := Get(ProviderAzure)
+	assert.Check(t, err == nil, "Not expected to return error")
+	assert.Equal(t, p.Name(), ProviderAzure)
+
+	p, err = Get(ProviderAWS)
+	assert.Check(t, err == nil, "Not expected to return error")
+	assert.Equal(t, p.Name(), ProviderAWS)
+
+	_, err = Get("unknown")
+	assert.Error(t, err, `unsupported cloud provider "unknown"`)
+}
+
+func TestIsGPUInstanceType(t *testing.T) {
+	testcases := map[string]struct {
+		provider     CloudProvider
+		instanceType string
+		expected     bool
+	}{
+		"Azure GPU SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC12s_v3",
+			expected:     true,
+		},
+		"Azure CPU SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_D4s_v3",
+			expected:     false,
+		},
+		"AWS GPU instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "g5.12xlarge",
+			expected:     true,
+		},
+		"AWS CPU instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "m5.large",
+			expected:     false,
+		},
+		"AWS instance type without size": {
+			provider:     &AWSProvider{},
+			instanceType: "p4d",
+			expected:     false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, tc.provider.IsGPUInstanceType(tc.instanceType), tc.expected)
+		})
+	}
+}
+
+func TestParseProviderID(t *testing.T) {
+	testcases := map[string]struct {
+		provider      CloudProvider
+		providerID    string
+		expected      string
+		expectedError bool
+	}{
+		"Azure VM": {
+			provider:   &AzureProvider{},
+			providerID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/aks-ws1234",
+			expected:   "aks-ws1234",
+		},
+		"Azure VMSS instance": {
+			provider:   &AzureProvider{},
+			providerID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-pool/virtualMachines/3",
+			expected:   "3",
+		},
+		"Azure provider with AWS ID": {
+			provider:      &AzureProvider{},
+			providerID:    "aws:///us-west-2a/i-0123456789abcdef0",
+			expectedError: true,
+		},
+		"AWS instance": {
+			provider:   &AWSProvider{},
+			providerID: "aws:///us-west-2a/i-0123456789abcdef0",
+			expected:   "i-0123456789abcdef0",
+		},
+		"AWS malformed ID": {
+			provider:      &AWSProvider{},
+			providerID:    "aws:///us-west-2a/",
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			id, err := tc.provider.ParseProviderID(tc.providerID)
+			if tc.expectedError {
+				assert.Check(t, err != nil, "Expected to return error")
+			} else {
+				assert.Check(t, err == nil, "Not expected to return error")
+				assert.Equal(t, id, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSupportsRDMA(t *testing.T) {
+	testcases := map[string]struct {
+		provider     CloudProvider
+		instanceType string
+		expected     bool
+	}{
+		"Azure RDMA SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC24rs_v3",
+			expected:     true,
+		},
+		"Azure RDMA SKU with accelerator": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_ND96amsr_A100_v4",
+			expected:     true,
+		},
+		"Azure non RDMA SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC24ads_A100_v4",
+			expected:     false,
+		},
+		"AWS EFA instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "p4d.24xlarge",
+			expected:     true,
+		},
+		"AWS non EFA instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "g5.xlarge",
+			expected:     false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, tc.provider.SupportsRDMA(tc.instanceType), tc.expected)
+		})
+	}
+}
+
+func TestArchitecture(t *testing.T) {
+	testcases := map[string]struct {
+		provider     CloudProvider
+		instanceType string
+		expected     string
+	}{
+		"Azure x86 SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC24ads_A100_v4",
+			expected:     ArchAMD64,
+		},
+		"Azure Grace Hopper SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_ND_GH200_v6",
+			expected:     ArchARM64,
+		},
+		"Azure ARM SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_D4ps_v5",
+			expected:     ArchARM64,
+		},
+		"AWS x86 instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "g4dn.xlarge",
+			expected:     ArchAMD64,
+		},
+		"AWS Graviton instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "g5g.xlarge",
+			expected:     ArchARM64,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, tc.provider.Architecture(tc.instanceType), tc.expected)
+		})
+	}
+}
+
+func TestHostMemory(t *testing.T) {
+	testcases := map[string]struct {
+		provider     CloudProvider
+		instanceType string
+		expected     string
+	}{
+		"Azure SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC12s_v3",
+			expected:     "224Gi",
+		},
+		"Azure SKU of the unknown provider": {
+			provider:     &UnknownProvider{},
+			instanceType: "Standard_NC24ads_A100_v4",
+			expected:     "220Gi",
+		},
+		"Azure SKU with unknown host memory": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_ND_GH200_v6",
+		},
+		"AWS instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "g5.12xlarge",
+			expected:     "192Gi",
+		},
+		"Azure SKU on AWS": {
+			provider:     &AWSProvider{},
+			instanceType: "Standard_NC12s_v3",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			hostMemory := tc.provider.HostMemory(tc.instanceType)
+			if tc.expected == "" {
+				assert.Check(t, hostMemory == nil, "Expected the host memory to be unknown")
+				return
+			}
+			assert.Check(t, hostMemory != nil && hostMemory.Cmp(resource.MustParse(tc.expected)) == 0, "Expected %s, got %v", tc.expected, hostMemory)
+		})
+	}
+}
+
+func TestValidateInstanceType(t *testing.T) {
+	testcases := map[string]struct {
+		provider      CloudProvider
+		instanceType  string
+		expectedError string
+	}{
+		"Azure GPU SKU": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC12s_v3",
+		},
+		"Azure GPU SKU with accelerator": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_ND96amsr_A100_v4",
+		},
+		"Azure SKU without version": {
+			provider:     &AzureProvider{},
+			instanceType: "Standard_NC6",
+		},
+		"Azure SKU with a wrong separator": {
+			provider:      &AzureProvider{},
+			instanceType:  "Standard_NC12s-v3",
+			expectedError: "invalid Azure SKU name Standard_NC12s-v3, did you mean Standard_NC12s_v3?",
+		},
+		"Azure SKU without prefix": {
+			provider:      &AzureProvider{},
+			instanceType:  "NC12s_v3",
+			expectedError: "expected a name like Standard_NC12s_v3",
+		},
+		"Azure SKU of an unsupported family": {
+			provider:      &AzureProvider{},
+			instanceType:  "Standard_E4s_v3",
+			expectedError: "unsupported family E of Azure SKU Standard_E4s_v3",
+		},
+		"AWS GPU instance type": {
+			provider:     &AWSProvider{},
+			instanceType: "g5.12xlarge",
+		},
+		"Azure SKU under the AWS provider": {
+			provider:      &AWSProvider{},
+			instanceType:  "Standard_NC12s_v3",
+			expectedError: "invalid EC2 instance type Standard_NC12s_v3",
+		},
+		"AWS instance type under an unknown provider": {
+			provider:     &UnknownProvider{},
+			instanceType: "g5.12xlarge",
+		},
+		"Azure SKU with a wrong separator under an unknown provider": {
+			provider:      &UnknownProvider{},
+			instanceType:  "Standard_NC12s-v3",
+			expectedError: "invalid Azure SKU name Standard_NC12s-v3, did you mean Standard_NC12s_v3?",
+		},
+		"AWS instance type of a CPU family": {
+			provider:      &AWSProvider{},
+			instanceType:  "m5.xlarge",
+			expectedError: "unsupported family m5 of EC2 instance type m5.xlarge",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			err := tc.provider.ValidateInstanceType(tc.instanceType)
+			if tc.expectedError == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+		})
+	}
+}
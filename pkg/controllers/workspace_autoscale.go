@@ -0,0 +1,140 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultAutoscaleTargetInFlightRequests is the average number of in-flight inference requests per pod the
+// HorizontalPodAutoscaler of an autoscaling workspace keeps.
+const DefaultAutoscaleTargetInFlightRequests = 4
+
+// autoscalingEnabled returns true if the node pool of the workspace scales between a minimum and a maximum count.
+func autoscalingEnabled(wObj *kaitov1alpha1.Workspace) bool {
+	return wObj.Resource.MinCount != nil || wObj.Resource.MaxCount != nil
+}
+
+// nodeCountRange returns the minimum and maximum node count of the workspace. Both default to the count.
+func nodeCountRange(wObj *kaitov1alpha1.Workspace) (int, int) {
+	count := lo.FromPtr(wObj.Resource.Count)
+	minCount := lo.FromPtr(wObj.Resource.MinCount)
+	if wObj.Resource.MinCount == nil {
+		minCount = count
+	}
+	maxCount := lo.FromPtr(wObj.Resource.MaxCount)
+	if wObj.Resource.MaxCount == nil {
+		maxCount = count
+	}
+	return minCount, maxCount
+}
+
+// isPodUnschedulable returns true if the scheduler could not find a node for the pod.
+func isPodUnschedulable(pod *corev1.Pod) bool {
+	_, found := lo.Find(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+		return condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable
+	})
+	return found
+}
+
+// desiredNodeCount returns the number of nodes the workspace needs and the nodes that currently run its pods.
+// Without autoscaling, the count of the workspace is returned. Otherwise, every scheduled or unschedulable
+// inference pod needs a node, and the demand is kept between the minimum and the maximum count. The nodes
+// running pods are returned so that idle nodes are released first when scaling down.
+func (c *WorkspaceReconciler) desiredNodeCount(ctx context.Context, wObj *kaitov1alpha1.Workspace) (int, []string, error) {
+	count := lo.FromPtr(wObj.Resource.Count)
+	if !autoscalingEnabled(wObj) || wObj.Inference == nil {
+		return count, wObj.Status.WorkerNodes, nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.Client.List(ctx, podList, client.InNamespace(wObj.Namespace),
+		client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: wObj.Name}); err != nil {
+		return 0, nil, err
+	}
+
+	var busyNodes []string
+	pending := 0
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Spec.NodeName != "" {
+			busyNodes = append(busyNodes, pod.Spec.NodeName)
+		} else if isPodUnschedulable(pod) {
+			pending++
+		}
+	}
+	busyNodes = lo.Uniq(busyNodes)
+
+	// The workload has not been scheduled yet, start with the requested count.
+	if len(busyNodes) == 0 && pending == 0 {
+		return count, wObj.Status.WorkerNodes, nil
+	}
+
+	minCount, maxCount := nodeCountRange(wObj)
+	desired := lo.Clamp(len(busyNodes)+pending, minCount, maxCount)
+	klog.InfoS("autoscaling workspace nodes", "workspace", klog.KObj(wObj), "busyNodes", len(busyNodes),
+		"unschedulablePods", pending, "desired", desired)
+	return desired, busyNodes, nil
+}
+
+// generateHorizontalPodAutoscaler returns the HorizontalPodAutoscaler of an autoscaling workspace, which scales its
+// inference Deployment between the minimum and the maximum count with the in-flight requests of the model servers.
+// It returns nil if the workspace is not autoscaled, or its model server does not serve the metric, i.e., the inference
+// is distributed, uses variants or a Pod template, or the preset exposes no metrics.
+func generateHorizontalPodAutoscaler(ctx context.Context, wObj *kaitov1alpha1.Workspace) *autoscalingv2.HorizontalPodAutoscaler {
+	if !autoscalingEnabled(wObj) || wObj.Inference == nil || wObj.Inference.Preset == nil || len(wObj.Inference.Variants) != 0 {
+		return nil
+	}
+	model := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name))
+	if model.SupportDistributedInference() || model.GetInferenceParameters().MetricsPort == 0 {
+		return nil
+	}
+	minCount, maxCount := nodeCountRange(wObj)
+	return resources.GenerateHorizontalPodAutoscalerManifest(ctx, wObj, int32(minCount), int32(maxCount),
+		inference.InFlightRequestsMetric, DefaultAutoscaleTargetInFlightRequests)
+}
+
+// ensureHorizontalPodAutoscaler scales the inference pods of an autoscaling workspace with its load, the nodes follow
+// the pods: the reconcile plan provisions a node for every unschedulable pod and releases the nodes without pods.
+// The metric is read from the custom metrics API, e.g., served by the Prometheus adapter from the metrics port of the
+// model servers. The HorizontalPodAutoscaler is deleted once the workspace is no longer autoscaled.
+func (c *WorkspaceReconciler) ensureHorizontalPodAutoscaler(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	desired := generateHorizontalPodAutoscaler(ctx, wObj)
+	existing := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := c.Client.Get(ctx, client.ObjectKey{Name: wObj.Name, Namespace: wObj.Namespace}, existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	switch {
+	case desired == nil && !exists:
+		return nil
+	case desired == nil:
+		klog.InfoS("DeleteHorizontalPodAutoscaler", "hpa", klog.KObj(existing))
+		return client.IgnoreNotFound(c.Client.Delete(ctx, existing, &client.DeleteOptions{}))
+	case !exists:
+		return client.IgnoreAlreadyExists(resources.CreateResource(ctx, desired, c.Client))
+	case reflect.DeepEqual(existing.Spec, desired.Spec):
+		return nil
+	}
+	existing.Spec = desired.Spec
+	klog.InfoS("UpdateHorizontalPodAutoscaler", "hpa", klog.KObj(existing))
+	return c.Client.Update(ctx, existing, &client.UpdateOptions{})
+}
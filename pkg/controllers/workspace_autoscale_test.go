@@ -0,0 +1,255 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func mockAutoscalingNode(name string) *corev1.Node {
+	node := utils.MockNodeList.Items[0].DeepCopy()
+	node.Name = name
+	return node
+}
+
+func mockAutoscalingMachine(name, nodeName string) *v1alpha5.Machine {
+	m := utils.MockMachine.DeepCopy()
+	m.Name = name
+	m.Status.NodeName = nodeName
+	return m
+}
+
+func mockWorkspacePod(name, nodeName string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: utils.MockWorkspaceWithPreset.Namespace,
+			Labels: map[string]string{
+				kaitov1alpha1.LabelWorkspaceName: utils.MockWorkspaceWithPreset.Name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+		},
+	}
+	if nodeName == "" {
+		pod.Status.Conditions = []corev1.PodCondition{
+			{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionFalse,
+				Reason: corev1.PodReasonUnschedulable,
+			},
+		}
+	}
+	return pod
+}
+
+func TestBuildReconcilePlanAutoscaling(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		count             int
+		minCount          *int
+		maxCount          *int
+		nodes             []*corev1.Node
+		machines          []*v1alpha5.Machine
+		pods              []*corev1.Pod
+		expectedNodeCount int
+		expectedCreate    int
+		expectedDelete    []string
+		expectedNodeNames []string
+	}{
+		"Unschedulable pods scale up toward the maximum count": {
+			count:    1,
+			maxCount: lo.ToPtr(3),
+			nodes:    []*corev1.Node{mockAutoscalingNode("node1")},
+			machines: []*v1alpha5.Machine{mockAutoscalingMachine("machine1", "node1")},
+			pods: []*corev1.Pod{
+				mockWorkspacePod("pod1", "node1"),
+				mockWorkspacePod("pod2", ""),
+				mockWorkspacePod("pod3", ""),
+				mockWorkspacePod("pod4", ""),
+			},
+			expectedNodeCount: 3,
+			expectedCreate:    2,
+			expectedNodeNames: []string{"node1"},
+		},
+		"Unschedulable pods within the range scale up to the demand": {
+			count:    1,
+			maxCount: lo.ToPtr(3),
+			nodes:    []*corev1.Node{mockAutoscalingNode("node1")},
+			machines: []*v1alpha5.Machine{mockAutoscalingMachine("machine1", "node1")},
+			pods: []*corev1.Pod{
+				mockWorkspacePod("pod1", "node1"),
+				mockWorkspacePod("pod2", ""),
+			},
+			expectedNodeCount: 2,
+			expectedCreate:    1,
+			expectedNodeNames: []string{"node1"},
+		},
+		"Idle nodes scale down toward the minimum count": {
+			count:    3,
+			minCount: lo.ToPtr(1),
+			nodes: []*corev1.Node{
+				mockAutoscalingNode("node1"),
+				mockAutoscalingNode("node2"),
+				mockAutoscalingNode("node3"),
+			},
+			machines: []*v1alpha5.Machine{
+				mockAutoscalingMachine("machine1", "node1"),
+				mockAutoscalingMachine("machine2", "node2"),
+				mockAutoscalingMachine("machine3", "node3"),
+			},
+			pods:              []*corev1.Pod{mockWorkspacePod("pod1", "node2")},
+			expectedNodeCount: 1,
+			expectedDelete:    []string{"machine1", "machine3"},
+			expectedNodeNames: []string{"node2"},
+		},
+		"Workspace without pods keeps the count": {
+			count:    2,
+			minCount: lo.ToPtr(1),
+			maxCount: lo.ToPtr(3),
+			nodes: []*corev1.Node{
+				mockAutoscalingNode("node1"),
+				mockAutoscalingNode("node2"),
+			},
+			machines: []*v1alpha5.Machine{
+				mockAutoscalingMachine("machine1", "node1"),
+				mockAutoscalingMachine("machine2", "node2"),
+			},
+			expectedNodeCount: 2,
+			expectedNodeNames: []string{"node1", "node2"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			nodeMap := mockClient.CreateMapWithType(&corev1.NodeList{})
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			for _, node := range tc.nodes {
+				nodeMap[client.ObjectKeyFromObject(node)] = node
+			}
+			for _, m := range tc.machines {
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			for _, pod := range tc.pods {
+				podMap[client.ObjectKeyFromObject(pod)] = pod
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+			wObj.Resource.Count = lo.ToPtr(tc.count)
+			wObj.Resource.MinCount = tc.minCount
+			wObj.Resource.MaxCount = tc.maxCount
+
+			plan, err := reconciler.BuildReconcilePlan(context.Background(), wObj)
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			assert.Equal(t, plan.NodeCount, tc.expectedNodeCount)
+			assert.Equal(t, plan.MachinesToCreate, tc.expectedCreate)
+			assert.Equal(t, len(plan.MachinesToDelete), len(tc.expectedDelete))
+			for i := range tc.expectedDelete {
+				assert.Equal(t, plan.MachinesToDelete[i].Name, tc.expectedDelete[i])
+			}
+			assert.Equal(t, len(plan.SelectedNodes), len(tc.expectedNodeNames))
+			for i := range tc.expectedNodeNames {
+				assert.Equal(t, plan.SelectedNodes[i].Name, tc.expectedNodeNames[i])
+			}
+		})
+	}
+}
+
+func TestEnsureHorizontalPodAutoscaler(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		presetName     string
+		maxCount       *int
+		existing       bool
+		expectedCreate bool
+		expectedDelete bool
+	}{
+		"Autoscaling workspace creates the HorizontalPodAutoscaler": {
+			presetName:     "test-metrics-model",
+			maxCount:       lo.ToPtr(3),
+			expectedCreate: true,
+		},
+		"Workspace without autoscaling has no HorizontalPodAutoscaler": {
+			presetName: "test-metrics-model",
+		},
+		"Preset without metrics deletes the HorizontalPodAutoscaler": {
+			presetName:     "test-model",
+			maxCount:       lo.ToPtr(3),
+			existing:       true,
+			expectedDelete: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			if tc.existing {
+				hpa := &autoscalingv2.HorizontalPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: utils.MockWorkspaceWithPreset.Name, Namespace: utils.MockWorkspaceWithPreset.Namespace},
+				}
+				mockClient.CreateOrUpdateObjectInMap(hpa)
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything).Return(nil)
+			} else {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything).
+					Return(errors.NewNotFound(schema.GroupResource{}, utils.MockWorkspaceWithPreset.Name))
+			}
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+			wObj.Inference.Preset.Name = kaitov1alpha1.ModelName(tc.presetName)
+			wObj.Resource.MaxCount = tc.maxCount
+
+			err := reconciler.ensureHorizontalPodAutoscaler(context.Background(), wObj)
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			if tc.expectedCreate {
+				mockClient.AssertCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything)
+				hpa := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*autoscalingv2.HorizontalPodAutoscaler)
+				assert.Equal(t, hpa.Spec.ScaleTargetRef.Name, wObj.Name)
+				assert.Equal(t, *hpa.Spec.MinReplicas, int32(1))
+				assert.Equal(t, hpa.Spec.MaxReplicas, int32(3))
+			} else {
+				mockClient.AssertNotCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything)
+			}
+			if tc.expectedDelete {
+				mockClient.AssertCalled(t, "Delete", mock.IsType(context.Background()), mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Delete", mock.IsType(context.Background()), mock.IsType(&autoscalingv2.HorizontalPodAutoscaler{}), mock.Anything)
+			}
+		})
+	}
+}
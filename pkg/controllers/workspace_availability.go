@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The reasons of the InferenceAvailable condition.
+const (
+	// inferenceAvailable is reported when all inference replicas are ready.
+	inferenceAvailable = "Available"
+	// inferenceDegraded is reported when some inference replicas are ready, the workspace serves at a reduced capacity.
+	inferenceDegraded = "Degraded"
+	// inferenceUnavailable is reported when no inference replica is ready.
+	inferenceUnavailable = "Unavailable"
+)
+
+// errInferenceDegraded is joined to the error of an inference workload whose replicas only partly became ready in
+// time, e.g., because the others cannot be scheduled. The workspace keeps serving with the ready replicas.
+var errInferenceDegraded = errors.New("inference is degraded, the workspace serves with the ready replicas only")
+
+// inferenceAvailability returns the status, reason and message of the InferenceAvailable condition for the given
+// number of ready and desired inference replicas.
+func inferenceAvailability(readyReplicas, desiredReplicas int32) (metav1.ConditionStatus, string, string) {
+	message := fmt.Sprintf("%d of %d inference replicas are ready", readyReplicas, desiredReplicas)
+	switch {
+	case readyReplicas >= desiredReplicas:
+		return metav1.ConditionTrue, inferenceAvailable, message
+	case readyReplicas > 0:
+		return metav1.ConditionTrue, inferenceDegraded, message
+	default:
+		return metav1.ConditionFalse, inferenceUnavailable, message
+	}
+}
+
+// updateInferenceAvailability reports in the InferenceAvailable condition how many replicas of the inference
+// workloads of the workspace are ready, and returns the reason of the condition. Nothing is reported until the
+// workloads have been created.
+func (c *WorkspaceReconciler) updateInferenceAvailability(ctx context.Context, wObj *kaitov1alpha1.Workspace) (string, error) {
+	workloads, err := c.inferenceWorkloads(ctx, wObj)
+	if err != nil || len(workloads) == 0 {
+		return "", err
+	}
+
+	var readyReplicas, desiredReplicas int32
+	for _, workload := range workloads {
+		replicas := workloadReplicas(workload)
+		readyReplicas += replicas.Ready
+		desiredReplicas += replicas.Desired
+	}
+
+	status, reason, message := inferenceAvailability(readyReplicas, desiredReplicas)
+	if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeInferenceAvailable,
+		status, reason, message); err != nil {
+		return "", err
+	}
+	return reason, nil
+}
+
+// inferenceWorkloads returns the existing inference workloads of the workspace.
+func (c *WorkspaceReconciler) inferenceWorkloads(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]client.Object, error) {
+	var candidates []client.Object
+	switch {
+	case len(wObj.Inference.Variants) != 0:
+		for i := range wObj.Inference.Variants {
+			candidates = append(candidates, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+				Name: inference.VariantName(wObj, &wObj.Inference.Variants[i]), Namespace: wObj.Namespace}})
+		}
+	case wObj.Inference.Preset != nil:
+		presetName := string(wObj.Inference.Preset.Name)
+		if !plugin.KaitoModelRegister.Has(presetName) {
+			return nil, nil
+		}
+		objMeta := metav1.ObjectMeta{Name: wObj.Name, Namespace: wObj.Namespace}
+		if plugin.KaitoModelRegister.MustGet(presetName).SupportDistributedInference() {
+			candidates = append(candidates, &appsv1.StatefulSet{ObjectMeta: objMeta})
+		} else {
+			candidates = append(candidates, &appsv1.Deployment{ObjectMeta: objMeta})
+		}
+	case wObj.Inference.Template != nil:
+		candidates = append(candidates, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: wObj.Name, Namespace: wObj.Namespace}})
+	}
+
+	workloads := make([]client.Object, 0, len(candidates))
+	for _, obj := range candidates {
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		workloads = append(workloads, obj)
+	}
+	return workloads, nil
+}
+
+// replicaCounts are the replica counts of an inference workload.
+type replicaCounts struct {
+	Ready     int32
+	Available int32
+	Desired   int32
+}
+
+// workloadReplicas returns the replica counts of a Deployment or a StatefulSet.
+func workloadReplicas(workload client.Object) replicaCounts {
+	switch obj := workload.(type) {
+	case *appsv1.Deployment:
+		return replicaCounts{Ready: obj.Status.ReadyReplicas, Available: obj.Status.AvailableReplicas, Desired: lo.FromPtr(obj.Spec.Replicas)}
+	case *appsv1.StatefulSet:
+		return replicaCounts{Ready: obj.Status.ReadyReplicas, Available: obj.Status.AvailableReplicas, Desired: lo.FromPtr(obj.Spec.Replicas)}
+	}
+	return replicaCounts{}
+}
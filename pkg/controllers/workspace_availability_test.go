@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateInferenceAvailability(t *testing.T) {
+	utils.RegisterTestModel()
+
+	testcases := map[string]struct {
+		workloadNotFound bool
+		readyReplicas    int32
+		expectedStatus   metav1.ConditionStatus
+		expectedReason   string
+		expectedMessage  string
+	}{
+		"All replicas are ready": {
+			readyReplicas:   4,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  inferenceAvailable,
+			expectedMessage: "4 of 4 inference replicas are ready",
+		},
+		"Some replicas are ready": {
+			readyReplicas:   2,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  inferenceDegraded,
+			expectedMessage: "2 of 4 inference replicas are ready",
+		},
+		"No replica is ready": {
+			readyReplicas:   0,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  inferenceUnavailable,
+			expectedMessage: "0 of 4 inference replicas are ready",
+		},
+		"Workload has not been created": {
+			workloadNotFound: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+
+			if tc.workloadNotFound {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+			} else {
+				depObj := &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: workspace.Name, Namespace: workspace.Namespace},
+					Spec:       appsv1.DeploymentSpec{Replicas: lo.ToPtr(int32(4))},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: tc.readyReplicas},
+				}
+				mockClient.CreateOrUpdateObjectInMap(depObj)
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			}
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			reason, err := reconciler.updateInferenceAvailability(context.Background(), workspace)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, reason, tc.expectedReason)
+
+			if tc.workloadNotFound {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeInferenceAvailable))
+			assert.Check(t, condition != nil, "expected the inference available condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			assert.Equal(t, condition.Reason, tc.expectedReason)
+			assert.Equal(t, condition.Message, tc.expectedMessage)
+		})
+	}
+}
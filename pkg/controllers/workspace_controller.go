@@ -4,9 +4,10 @@ package controllers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"reflect"
 	"sort"
-	"strings"
 	"time"
 
 	"github.com/azure/kaito/pkg/tuning"
@@ -14,23 +15,35 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/audit"
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/inference"
 	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/metrics"
+	"github.com/azure/kaito/pkg/notification"
+	"github.com/azure/kaito/pkg/quota"
+	"github.com/azure/kaito/pkg/registry"
 	"github.com/azure/kaito/pkg/resources"
 	"github.com/azure/kaito/pkg/utils"
 	"github.com/azure/kaito/pkg/utils/plugin"
 	"github.com/go-logr/logr"
 	"github.com/samber/lo"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -39,7 +52,6 @@ import (
 )
 
 const (
-	gpuSkuPrefix             = "Standard_N"
 	nodePluginInstallTimeout = 60 * time.Second
 )
 
@@ -48,6 +60,71 @@ type WorkspaceReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// AuditSink records the provisioning decisions, e.g., the selected instance types and the deleted nodes. Optional.
+	AuditSink audit.AuditSink
+	// CloudProvider is the provider GPU machines are provisioned from. Defaults to Azure if not set.
+	CloudProvider cloudprovider.CloudProvider
+	// GPUScaleDown removes the nodes of autoscaling workspaces whose GPU memory stays idle. Optional.
+	GPUScaleDown *GPUScaleDown
+	// ImageMirror checks that the images of a workspace exist in the mirror registry of an air-gapped cluster before its
+	// nodes are provisioned. Optional.
+	ImageMirror registry.Client
+	// InferenceSmokeTest gates the Ready condition of the preset inference workspaces on a canned inference request
+	// that the model server must answer once the workload is ready.
+	InferenceSmokeTest bool
+	// MaxMachineCreateAttemptsPerReconcile is the number of attempts to create a machine within a reconcile, after
+	// which the workspace is requeued. Defaults to machine.DefaultMaxCreateAttempts if not set.
+	MaxMachineCreateAttemptsPerReconcile int
+	// NotificationSink is notified when the nodes of a workspace cannot be provisioned. Optional.
+	NotificationSink notification.NotificationSink
+	// PreProvisionHook is invoked before a machine is created. Defaults to a hook that allows every provisioning.
+	PreProvisionHook machine.PreProvisionHook
+	// ProvisioningParallelism limits how many machines of each provisioner are provisioned at once. Optional.
+	ProvisioningParallelism *machine.ProvisioningParallelism
+	// Region is the region GPU machines are provisioned in. The availability of the instance types is only tracked if it is set.
+	Region string
+	// RequeueIntervals configures how soon a workspace is reconciled again. Defaults to DefaultRequeueIntervals if not set.
+	RequeueIntervals RequeueIntervals
+	// ProvisioningTimeout is how long the nodes of a workspace may take to be provisioned unless the workspace configures
+	// its own timeout. Defaults to DefaultProvisioningTimeout if not set.
+	ProvisioningTimeout time.Duration
+	// RetryBudget configures how many provisioning attempts may fail before the provisioning of a workspace backs off.
+	// Defaults to DefaultRetryBudget if not set.
+	RetryBudget RetryBudget
+	// SKUReprobeInterval is how often the instance types of a workspace that could not be provisioned for lack of
+	// capacity are checked again. Defaults to DefaultSKUReprobeInterval if not set.
+	SKUReprobeInterval time.Duration
+	// SchedulingFailureThreshold is how long an inference pod may stay unschedulable before it is reported in the
+	// workspace status. Defaults to DefaultSchedulingFailureThreshold if not set.
+	SchedulingFailureThreshold time.Duration
+}
+
+func (c *WorkspaceReconciler) cloudProvider() cloudprovider.CloudProvider {
+	if c.CloudProvider == nil {
+		return cloudprovider.Default
+	}
+	return c.CloudProvider
+}
+
+func (c *WorkspaceReconciler) preProvisionHook() machine.PreProvisionHook {
+	if c.PreProvisionHook == nil {
+		return machine.NoopPreProvisionHook{}
+	}
+	return c.PreProvisionHook
+}
+
+// recordEvent records an event for the workspace. The reconciler of the unit tests may have no recorder.
+func (c *WorkspaceReconciler) recordEvent(wObj *kaitov1alpha1.Workspace, eventType, reason, message string) {
+	if c.Recorder != nil {
+		c.Recorder.Event(wObj, eventType, reason, message)
+	}
+}
+
+func (c *WorkspaceReconciler) maxMachineCreateAttempts() int {
+	if c.MaxMachineCreateAttemptsPerReconcile <= 0 {
+		return machine.DefaultMaxCreateAttempts
+	}
+	return c.MaxMachineCreateAttemptsPerReconcile
 }
 
 func (c *WorkspaceReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
@@ -58,6 +135,8 @@ func (c *WorkspaceReconciler) Reconcile(ctx context.Context, req reconcile.Reque
 		}
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
+	// The defaults are set by the webhook, a workspace admitted without it still needs e.g. its node count.
+	workspaceObj.SetDefaults(ctx)
 
 	klog.InfoS("Reconciling", "workspace", req.NamespacedName)
 
@@ -82,22 +161,143 @@ func (c *WorkspaceReconciler) Reconcile(ctx context.Context, req reconcile.Reque
 			return reconcile.Result{}, fmt.Errorf("The preset model name %s is not registered for workspace %s/%s", string(workspaceObj.Inference.Preset.Name), workspaceObj.Namespace, workspaceObj.Name)
 		}
 	}
+	if workspaceObj.Inference != nil {
+		for _, variant := range workspaceObj.Inference.Variants {
+			if !plugin.KaitoModelRegister.Has(string(variant.Preset.Name)) {
+				return reconcile.Result{}, fmt.Errorf("The preset model name %s of variant %s is not registered for workspace %s/%s", string(variant.Preset.Name), variant.Name, workspaceObj.Namespace, workspaceObj.Name)
+			}
+		}
+	}
 
 	return c.addOrUpdateWorkspace(ctx, workspaceObj)
 }
 
 func (c *WorkspaceReconciler) addOrUpdateWorkspace(ctx context.Context, wObj *kaitov1alpha1.Workspace) (reconcile.Result, error) {
+	if isPlanOnly(wObj) {
+		return reconcile.Result{}, c.reportReconcilePlan(ctx, wObj)
+	}
+	if err := c.upgradeInstanceType(ctx, wObj); err != nil {
+		return reconcile.Result{}, err
+	}
+	// Neither the spec warnings nor the unavailable GPU resources block the workspace, the nodes may be configured later.
+	if err := c.updateSpecWarningsCondition(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update the spec warnings of the workspace", "workspace", klog.KObj(wObj))
+	}
+	if err := c.updateGPUResourceCondition(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to check the GPU resource names", "workspace", klog.KObj(wObj))
+	}
+
+	if err := c.validatePriorityClass(ctx, wObj); err != nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeReady, metav1.ConditionFalse,
+			"priorityClassNotFound", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, updateErr
+		}
+		return reconcile.Result{}, err
+	}
+	if err := c.ensureModelCache(ctx, wObj); err != nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeReady, metav1.ConditionFalse,
+			"modelCacheUnavailable", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, updateErr
+		}
+		return reconcile.Result{}, err
+	}
+
+	// Move the workloads away from the nodes that karpenter is about to remove.
+	c.preDrainDisruptingMachines(ctx, wObj)
+	// Move the workloads away from the nodes whose GPUs report hardware errors, and replace the nodes.
+	c.replaceFaultyGPUNodes(ctx, wObj)
+	// Let the pods be scheduled on the new nodes whose GPUs have been registered.
+	c.removeGPUStartupTaints(ctx, wObj)
+
+	// Release the nodes whose GPU memory stays idle, the autoscaling then deletes their machines.
+	if err := c.scaleDownIdleNodes(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to scale down the idle nodes", "workspace", klog.KObj(wObj))
+	}
+	// Drain the least busy nodes if the count was lowered, the reconcile plan then deletes their machines.
+	if err := c.scaleInNodes(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to scale in the nodes", "workspace", klog.KObj(wObj))
+	}
+
+	if phase, err := c.migrateInstanceType(ctx, wObj); err != nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
+			"instanceTypeMigrationFailed", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, updateErr
+		}
+		return reconcile.Result{}, err
+	} else if phase != machine.MigrationPhaseNone {
+		// The machines of the old instance type are still in use, do not provision machines for them until they are replaced.
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeInstanceTypeMigrated, metav1.ConditionFalse,
+			string(phase), fmt.Sprintf("migrating the machines to instance type %s", wObj.Resource.InstanceType)); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, updateErr
+		}
+		return reconcile.Result{RequeueAfter: c.RequeueIntervals.ComputeRequeueAfter(WorkspaceStateProvisioning)}, nil
+	} else if meta.IsStatusConditionFalse(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeInstanceTypeMigrated)) {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeInstanceTypeMigrated, metav1.ConditionTrue,
+			"instanceTypeMigrated", fmt.Sprintf("the machines have been migrated to instance type %s", wObj.Resource.InstanceType)); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, updateErr
+		}
+	}
+
 	// Read ResourceSpec
-	err := c.applyWorkspaceResource(ctx, wObj)
+	var err error
+	if !tuningCompleted(wObj) {
+		// The machines of a completed tuning workspace have been released, do not provision them again.
+		// A workspace whose nodes were not provisioned in time waits until its spec is changed.
+		timedOut, timeoutErr := c.checkProvisioningTimeout(ctx, wObj, time.Now())
+		if timeoutErr != nil {
+			return reconcile.Result{}, timeoutErr
+		}
+		if timedOut {
+			return reconcile.Result{}, nil
+		}
+		// A workspace whose instance types were unavailable waits until the capacity returns.
+		wait, reprobeErr := c.reprobeInstanceTypes(ctx, wObj)
+		if reprobeErr != nil {
+			return reconcile.Result{}, reprobeErr
+		}
+		if wait > 0 {
+			return reconcile.Result{RequeueAfter: wait}, nil
+		}
+		// A malformed tuning dataset fails the workspace before its nodes are provisioned, until the spec is changed.
+		valid, datasetErr := c.validateTuningDataset(ctx, wObj)
+		if datasetErr != nil {
+			return reconcile.Result{}, datasetErr
+		}
+		if !valid {
+			return reconcile.Result{}, nil
+		}
+		// A workspace that keeps failing to provision backs off until the window of its retry budget has passed.
+		backoff, budgetErr := c.checkRetryBudget(ctx, wObj)
+		if budgetErr != nil {
+			return reconcile.Result{}, budgetErr
+		}
+		if backoff > 0 {
+			return reconcile.Result{RequeueAfter: backoff}, nil
+		}
+		err = c.applyWorkspaceResource(ctx, wObj)
+	}
 	if err != nil {
 		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeReady, metav1.ConditionFalse,
 			"workspaceFailed", err.Error()); updateErr != nil {
 			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
 			return reconcile.Result{}, updateErr
 		}
-		// if error is	due to machine instance types unavailability, stop reconcile.
+		// if error is	due to machine instance types unavailability, retry once they are provisionable again.
 		if err.Error() == machine.ErrorInstanceTypesUnavailable {
-			return reconcile.Result{Requeue: false}, err
+			// A fallback instance type that has not failed to launch yet is tried right away.
+			if len(cloudprovider.AvailableRegionSKUs(c.Region, machine.CandidateInstanceTypes(wObj), c.cloudProvider())) != 0 {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			c.notifyProvisioningFailure(ctx, wObj, err)
+			return reconcile.Result{RequeueAfter: c.skuReprobeInterval()}, nil
+		}
+		if requeueAfter, requeue := c.provisioningRequeueAfter(err); requeue {
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
 		}
 		return reconcile.Result{}, err
 	}
@@ -111,18 +311,52 @@ func (c *WorkspaceReconciler) addOrUpdateWorkspace(ctx context.Context, wObj *ka
 		return reconcile.Result{}, err
 	}
 
+	// The objects that the spec no longer renders are deleted first, e.g., to free the GPUs of a renamed variant.
+	if err := c.deleteOrphanedObjects(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to delete the orphaned objects", "workspace", klog.KObj(wObj))
+		return reconcile.Result{}, err
+	}
+
+	// The nodes of a completed tuning job may be kept for a while, e.g., until its output is uploaded.
+	var tuningCleanupAfter time.Duration
 	if wObj.Tuning != nil {
-		if err = c.applyTuning(ctx, wObj); err != nil {
+		if tuningCleanupAfter, err = c.applyTuning(ctx, wObj); err != nil {
 			return reconcile.Result{}, err
 		}
 	}
 	if wObj.Inference != nil {
-		if err = c.applyInference(ctx, wObj); err != nil {
+		if err = c.checkGPUCapacity(ctx, wObj); err == nil {
+			err = c.applyInference(ctx, wObj)
+		}
+		if err == nil {
+			err = c.ensureModelInfoConfigMap(ctx, wObj)
+		}
+		if err == nil {
+			err = c.ensureEgressNetworkPolicy(ctx, wObj)
+		}
+		if err == nil {
+			err = c.ensureHorizontalPodAutoscaler(ctx, wObj)
+		}
+		// The workspace is only ready once the model server answers an inference request.
+		if err == nil {
+			err = c.runInferenceSmokeTest(ctx, wObj)
+		}
+		if err != nil {
+			reason := "workspaceFailed"
+			if goerrors.Is(err, errInferenceDegraded) {
+				reason = "workspaceDegraded"
+			} else if goerrors.Is(err, errSmokeTestFailed) {
+				reason = "smokeTestFailed"
+			}
 			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeReady, metav1.ConditionFalse,
-				"workspaceFailed", err.Error()); updateErr != nil {
+				reason, err.Error()); updateErr != nil {
 				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
 				return reconcile.Result{}, updateErr
 			}
+			// A model server that does not answer yet, e.g., while it loads the model, is tested again shortly.
+			if goerrors.Is(err, errSmokeTestFailed) {
+				return reconcile.Result{RequeueAfter: c.RequeueIntervals.ComputeRequeueAfter(WorkspaceStateProvisioning)}, nil
+			}
 			return reconcile.Result{}, err
 		}
 	}
@@ -133,7 +367,11 @@ func (c *WorkspaceReconciler) addOrUpdateWorkspace(ctx context.Context, wObj *ka
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{}, nil
+	requeueAfter := c.RequeueIntervals.ComputeRequeueAfter(workspaceState(wObj))
+	if tuningCleanupAfter > 0 {
+		requeueAfter = lo.Min([]time.Duration{requeueAfter, tuningCleanupAfter})
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (c *WorkspaceReconciler) deleteWorkspace(ctx context.Context, wObj *kaitov1alpha1.Workspace) (reconcile.Result, error) {
@@ -148,7 +386,9 @@ func (c *WorkspaceReconciler) deleteWorkspace(ctx context.Context, wObj *kaitov1
 	return c.garbageCollectWorkspace(ctx, wObj)
 }
 
-func selectWorkspaceNodes(qualified []*corev1.Node, preferred []string, previous []string, count int) []*corev1.Node {
+// selectWorkspaceNodes returns count of the qualified nodes, preferring the preferred nodes, then the previous nodes,
+// then the nodes with more free GPUs, then the nodes created by kaito.
+func selectWorkspaceNodes(qualified []*corev1.Node, preferred []string, previous []string, freeGPUs map[string]int64, count int) []*corev1.Node {
 
 	sort.Slice(qualified, func(i, j int) bool {
 		iPreferred := utils.Contains(preferred, qualified[i].Name)
@@ -166,6 +406,9 @@ func selectWorkspaceNodes(qualified []*corev1.Node, preferred []string, previous
 				return true
 			} else if !iPrevious && jPrevious {
 				return false
+			} else if iFree, jFree := freeGPUs[qualified[i].Name], freeGPUs[qualified[j].Name]; iFree != jFree {
+				// Choose the node that fits the workload with the most headroom.
+				return iFree > jFree
 			} else { // either all are previous, or none is previous
 				_, iCreatedByKaito := qualified[i].Labels["kaito.sh/machine-type"]
 				_, jCreatedByKaito := qualified[j].Labels["kaito.sh/machine-type"]
@@ -197,27 +440,105 @@ func (c *WorkspaceReconciler) applyWorkspaceResource(ctx context.Context, wObj *
 		return err
 	}
 
-	// Find all nodes that match the labelSelector and instanceType, they are not necessarily created by machines.
-	validNodes, err := c.getAllQualifiedNodes(ctx, wObj)
+	// Compare the qualified nodes, which are not necessarily created by machines, with the desired node count.
+	plan, err := c.BuildReconcilePlan(ctx, wObj)
 	if err != nil {
 		return err
 	}
+	klog.InfoS("reconcile plan", "workspace", klog.KObj(wObj), "plan", plan.String())
 
-	selectedNodes := selectWorkspaceNodes(validNodes, wObj.Resource.PreferredNodes, wObj.Status.WorkerNodes, lo.FromPtr(wObj.Resource.Count))
+	selectedNodes := plan.SelectedNodes
 
-	newNodesCount := lo.FromPtr(wObj.Resource.Count) - len(selectedNodes)
+	newNodesCount := plan.MachinesToCreate + len(plan.MachinesToReplace)
+	metrics.UpdateWorkspaceNodes(wObj, plan.NodeCount, len(selectedNodes), newNodesCount > 0)
+	if err := c.updateStatusProvisioningProgressIfNotMatch(ctx, wObj, len(selectedNodes), plan.NodeCount); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
 
 	if newNodesCount > 0 {
 		klog.InfoS("need to create more nodes", "NodeCount", newNodesCount)
+		// The provisioning times out if the nodes are not ready in time, however often it is attempted.
+		if err := c.startProvisioningAttempt(ctx, wObj, time.Now()); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return err
+		}
+		// New nodes that the labelSelector does not match could never run the workload.
+		if err := machine.ValidateLabelSelectorMatchesNodes(wObj, c.cloudProvider()); err != nil {
+			if !conditionMatches(wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse, "labelSelectorMismatch", err.Error()) {
+				c.recordEvent(wObj, corev1.EventTypeWarning, "LabelSelectorMismatch", err.Error())
+			}
+			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse,
+				"labelSelectorMismatch", err.Error()); updateErr != nil {
+				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+				return updateErr
+			}
+			return err
+		}
+		if err := quota.CheckGPUQuota(ctx, wObj, c.Client); err != nil {
+			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse,
+				"gpuQuotaExceeded", err.Error()); updateErr != nil {
+				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+				return updateErr
+			}
+			return err
+		}
+		// Nodes are useless if the namespace does not allow the pods to request their GPUs.
+		if err := c.checkResourceQuotaHeadroom(ctx, wObj); err != nil {
+			if !conditionMatches(wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse, "resourceQuotaExceeded", err.Error()) {
+				c.recordEvent(wObj, corev1.EventTypeWarning, "ResourceQuotaExceeded", err.Error())
+			}
+			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse,
+				"resourceQuotaExceeded", err.Error()); updateErr != nil {
+				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+				return updateErr
+			}
+			return err
+		}
+		// The nodes of an air-gapped cluster can only pull the images that are mirrored.
+		if err := c.checkMirrorImages(ctx, wObj); err != nil {
+			if !conditionMatches(wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse, "imagesMissingInMirror", err.Error()) {
+				c.recordEvent(wObj, corev1.EventTypeWarning, "ImagesMissingInMirror", err.Error())
+			}
+			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse,
+				"imagesMissingInMirror", err.Error()); updateErr != nil {
+				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+				return updateErr
+			}
+			return err
+		}
+		// Machines beyond the limits of the provisioner would never be launched and strand the workspace.
+		if err := machine.CheckProvisionerLimits(ctx, wObj, wObj.Resource.InstanceType, newNodesCount, c.cloudProvider(), c.Client); err != nil {
+			if !conditionMatches(wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse, "provisionerLimitExceeded", err.Error()) {
+				c.recordEvent(wObj, corev1.EventTypeWarning, "ProvisionerLimitExceeded", err.Error())
+			}
+			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse,
+				"provisionerLimitExceeded", err.Error()); updateErr != nil {
+				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+				return updateErr
+			}
+			return err
+		}
 		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionUnknown,
 			"CreateMachinePending", fmt.Sprintf("creating %d machines", newNodesCount)); err != nil {
 			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
 			return err
 		}
 
-		for i := 0; i < newNodesCount; i++ {
-			newNode, err := c.createAndValidateNode(ctx, wObj)
+		machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+		if err != nil {
+			return err
+		}
+		for _, index := range machine.FreeMachineIndices(wObj, machines.Items, newNodesCount) {
+			newNode, err := c.createAndValidateNode(ctx, wObj, index)
 			if err != nil {
+				// A delayed provisioning has not been attempted.
+				var delayedErr *machine.ProvisioningDelayedError
+				if !goerrors.As(err, &delayedErr) {
+					if budgetErr := c.recordProvisioningFailure(ctx, wObj); budgetErr != nil {
+						klog.ErrorS(budgetErr, "failed to record the provisioning failure", "workspace", klog.KObj(wObj))
+					}
+				}
 				if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeResourceStatus, metav1.ConditionFalse,
 					"workspaceResourceStatusFailed", err.Error()); updateErr != nil {
 					klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
@@ -226,11 +547,35 @@ func (c *WorkspaceReconciler) applyWorkspaceResource(ctx context.Context, wObj *
 				return err
 			}
 			selectedNodes = append(selectedNodes, newNode)
+			if err := c.updateStatusProvisioningProgressIfNotMatch(ctx, wObj, len(selectedNodes), plan.NodeCount); err != nil {
+				klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+				return err
+			}
 		}
 	}
 
+	metrics.UpdateWorkspaceNodes(wObj, plan.NodeCount, len(selectedNodes), false)
+	if err := c.updateNodesLaunchingCondition(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
+	if err := c.updateIPExhaustionCondition(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
+
+	// Drifted and excess machines are removed only after the new nodes are ready.
+	for i, m := range append(plan.MachinesToReplace, plan.MachinesToDelete...) {
+		if err := machine.DeleteMachine(ctx, m, c.Client); client.IgnoreNotFound(err) != nil {
+			klog.ErrorS(err, "failed to delete the machine", "machine", klog.KObj(m))
+			return err
+		}
+		c.auditNodeDeleted(ctx, wObj, m, lo.Ternary(i < len(plan.MachinesToReplace),
+			"the machine drifted from the workspace and is replaced by a ready machine", "the workspace no longer needs the machine"))
+	}
+
 	// Ensure all gpu plugins are running successfully.
-	if strings.Contains(wObj.Resource.InstanceType, gpuSkuPrefix) { // GPU skus
+	if c.cloudProvider().IsGPUInstanceType(wObj.Resource.InstanceType) { // GPU skus
 		for i := range selectedNodes {
 			err = c.ensureNodePlugins(ctx, wObj, selectedNodes[i])
 			if err != nil {
@@ -244,11 +589,24 @@ func (c *WorkspaceReconciler) applyWorkspaceResource(ctx context.Context, wObj *
 		}
 	}
 
+	// Spreading the machines across zones is best effort, it does not fail the workspace.
+	if err := machine.EnsureZoneSpread(ctx, wObj, c.Client); err != nil {
+		klog.ErrorS(err, "failed to spread the machines across zones", "workspace", klog.KObj(wObj))
+	}
+
 	if err = c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionTrue,
 		"installNodePluginsSuccess", "machines plugins have been installed successfully"); err != nil {
 		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
 		return err
 	}
+	if err = c.resetRetryBudget(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
+	if err = c.resetProvisioningAttempt(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
 
 	// Add the valid nodes names to the WorkspaceStatus.WorkerNodes.
 	err = c.updateStatusNodeListIfNotMatch(ctx, wObj, selectedNodes)
@@ -270,18 +628,19 @@ func (c *WorkspaceReconciler) applyWorkspaceResource(ctx context.Context, wObj *
 	return nil
 }
 
-// getAllQualifiedNodes returns all nodes that match the labelSelector and instanceType.
-func (c *WorkspaceReconciler) getAllQualifiedNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]*corev1.Node, error) {
+// getAllQualifiedNodes returns all nodes that match the labelSelector and instanceType and that fit the pods of the
+// workspace, see matchNodes, along with the number of GPUs they have free for the workspace.
+func (c *WorkspaceReconciler) getAllQualifiedNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]*corev1.Node, map[string]int64, error) {
 	var qualifiedNodes []*corev1.Node
 
 	nodeList, err := resources.ListNodes(ctx, c.Client, wObj.Resource.LabelSelector.MatchLabels)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(nodeList.Items) == 0 {
 		klog.InfoS("no current nodes match the workspace resource spec", "workspace", klog.KObj(wObj))
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	for index := range nodeList.Items {
@@ -300,42 +659,266 @@ func (c *WorkspaceReconciler) getAllQualifiedNodes(ctx context.Context, wObj *ka
 		}
 	}
 
-	return qualifiedNodes, nil
+	return c.matchNodes(ctx, wObj, qualifiedNodes)
 }
 
 // check if node has the required instanceType
 func (c *WorkspaceReconciler) validateNodeInstanceType(ctx context.Context, wObj *kaitov1alpha1.Workspace, nodeObj *corev1.Node) bool {
-	if instanceTypeLabel, found := nodeObj.Labels[corev1.LabelInstanceTypeStable]; found {
-		if instanceTypeLabel != wObj.Resource.InstanceType {
+	if instanceTypeLabel, found := nodeObj.Labels[c.cloudProvider().InstanceTypeLabel()]; found {
+		if !lo.Contains(machine.CandidateInstanceTypes(wObj), instanceTypeLabel) {
 			return false
 		}
 	}
 	return true
 }
 
-// createAndValidateNode creates a new machine and validates status.
-func (c *WorkspaceReconciler) createAndValidateNode(ctx context.Context, wObj *kaitov1alpha1.Workspace) (*corev1.Node, error) {
-	var machineOSDiskSize string
-	if wObj.Inference != nil && wObj.Inference.Preset != nil && wObj.Inference.Preset.Name != "" {
-		presetName := string(wObj.Inference.Preset.Name)
-		machineOSDiskSize = plugin.KaitoModelRegister.MustGet(presetName).GetInferenceParameters().DiskStorageRequirement
+// migrateInstanceType replaces the machines of the workspace whose instance type is no longer a candidate, i.e.,
+// after the instance type of the workspace has been changed, with machines of the new instance type. It returns the
+// phase that the migration waits in, the workspace is reconciled again to take the next step.
+func (c *WorkspaceReconciler) migrateInstanceType(ctx context.Context, wObj *kaitov1alpha1.Workspace) (machine.MigrationPhase, error) {
+	if wObj.Inference == nil {
+		return machine.MigrationPhaseNone, nil
 	}
-	if machineOSDiskSize == "" {
-		machineOSDiskSize = "0" // The default OS size is used
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		return machine.MigrationPhaseNone, err
+	}
+	candidates := machine.CandidateInstanceTypes(wObj)
+	outdated := lo.ContainsBy(machines.Items, func(m v1alpha5.Machine) bool {
+		return m.DeletionTimestamp == nil && !lo.Contains(candidates, machine.MachineInstanceType(&m, c.cloudProvider()))
+	})
+	if !outdated {
+		return machine.MigrationPhaseNone, nil
 	}
+	return machine.MigrateSKU(ctx, wObj, wObj.Resource.InstanceType, c.cloudProvider(), c.Client)
+}
 
-Retry_withdifferentname:
-	newMachine := machine.GenerateMachineManifest(ctx, machineOSDiskSize, wObj)
+// preDrainDisruptingMachines pre-drains the nodes of the workspace machines that are being disrupted. Errors are only logged
+// because the nodes are removed by karpenter anyway.
+func (c *WorkspaceReconciler) preDrainDisruptingMachines(ctx context.Context, wObj *kaitov1alpha1.Workspace) {
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		klog.ErrorS(err, "failed to list machines", "workspace", klog.KObj(wObj))
+		return
+	}
+	for i := range machines.Items {
+		if err := machine.OnMachineDisrupting(ctx, &machines.Items[i], c.Client); err != nil {
+			klog.ErrorS(err, "failed to pre-drain the node of a disrupting machine", "machine", klog.KObj(&machines.Items[i]))
+		}
+	}
+}
 
-	if err := machine.CreateMachine(ctx, newMachine, c.Client); err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			klog.InfoS("There exists a machine with the same name, retry with a different name", "machine", klog.KObj(newMachine))
-			goto Retry_withdifferentname
-		} else {
+// replaceFaultyGPUNodes takes the worker nodes of the workspace that report a GPU hardware error out of service.
+// Errors are only logged, the nodes are handled again in the next reconcile.
+func (c *WorkspaceReconciler) replaceFaultyGPUNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace) {
+	for _, nodeName := range wObj.Status.WorkerNodes {
+		nodeObj, err := resources.GetNode(ctx, nodeName, c.Client)
+		if err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				klog.ErrorS(err, "failed to get the worker node", "node", nodeName)
+			}
+			continue
+		}
+		if !machine.HasGPUHardwareError(nodeObj) {
+			continue
+		}
+		// The node is cordoned first, the error is only reported once.
+		if !nodeObj.Spec.Unschedulable {
+			c.recordEvent(wObj, corev1.EventTypeWarning, "GPUHardwareError",
+				fmt.Sprintf("Node %s reports a GPU hardware error, moving the replicas off the node and replacing it", nodeName))
+		}
+		deleted, err := machine.HandleGPUHardwareError(ctx, nodeObj, c.Client)
+		if err != nil {
+			klog.ErrorS(err, "failed to replace the node with a GPU hardware error", "node", nodeName)
+		} else if deleted != nil {
+			c.auditNodeDeleted(ctx, wObj, deleted, "the node reports a GPU hardware error")
+		}
+	}
+}
+
+// removeGPUStartupTaints removes the GPU startup taint from the nodes of the workspace whose GPUs have been registered.
+// The workspace is reconciled once the device plugin reports the GPUs of its node, see nodeGPUCapacityChanged. A node
+// whose GPUs are not registered in time is reported, e.g., if the device plugin does not tolerate the taint.
+func (c *WorkspaceReconciler) removeGPUStartupTaints(ctx context.Context, wObj *kaitov1alpha1.Workspace) {
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		klog.ErrorS(err, "failed to list the machines of the workspace", "workspace", klog.KObj(wObj))
+		return
+	}
+	for i := range machines.Items {
+		machineObj := &machines.Items[i]
+		if !machine.HasGPUStartupTaint(machineObj) {
+			continue
+		}
+		free, err := machine.GateGPUStartupTaint(ctx, machineObj, c.Client)
+		if err != nil {
+			klog.ErrorS(err, "failed to remove the GPU startup taint", "machine", klog.KObj(machineObj))
+			continue
+		}
+		if !free && machine.GPUStartupOverdue(machineObj, time.Now()) {
+			c.recordEvent(wObj, corev1.EventTypeWarning, "GPUStartupTaintNotRemoved",
+				fmt.Sprintf("Node %s of machine %s has not registered its GPUs within %s, the startup taint %s is not removed",
+					machineObj.Status.NodeName, machineObj.Name, machine.GPUStartupTimeout, machine.GPUStartupTaintKey))
+		}
+	}
+}
+
+// provisioningRequeueAfter returns when the workspace is reconciled again if the provisioning failed with an error
+// that a later reconcile resolves, instead of returning the error.
+func (c *WorkspaceReconciler) provisioningRequeueAfter(err error) (time.Duration, bool) {
+	// a delayed provisioning is retried once the delay has passed.
+	var delayedErr *machine.ProvisioningDelayedError
+	if goerrors.As(err, &delayedErr) {
+		return delayedErr.RetryAfter, true
+	}
+	// a machine that is not created within the attempts of this reconcile is created by the next one.
+	var exhaustedErr *machine.CreateAttemptsExhaustedError
+	if goerrors.As(err, &exhaustedErr) {
+		return c.RequeueIntervals.ComputeRequeueAfter(WorkspaceStateProvisioning), true
+	}
+	return 0, false
+}
+
+// checkPreProvisionHook returns an error if the pre-provision hook delays or vetoes the creation of a machine for the workspace.
+func (c *WorkspaceReconciler) checkPreProvisionHook(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	result, err := c.preProvisionHook().BeforeProvision(ctx, wObj)
+	if err != nil {
+		klog.ErrorS(err, "failed to run the pre-provision hook", "workspace", klog.KObj(wObj))
+		return err
+	}
+	if result.Allowed {
+		return nil
+	}
 
-			klog.ErrorS(err, "failed to create machine", "machine", newMachine.Name)
+	reason := "machineProvisioningVetoed"
+	err = fmt.Errorf("machine provisioning is vetoed: %s", result.Reason)
+	if result.RetryAfter > 0 {
+		reason = "machineProvisioningDelayed"
+		err = &machine.ProvisioningDelayedError{RetryAfter: result.RetryAfter, Reason: result.Reason}
+	}
+	klog.InfoS("machine provisioning is not allowed by the pre-provision hook", "workspace", klog.KObj(wObj), "reason", result.Reason)
+	if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
+		reason, err.Error()); updateErr != nil {
+		klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return updateErr
+	}
+	return err
+}
+
+// acquireProvisioningParallelism reserves the provisioning of the machine by its provisioner. It returns an error if
+// the provisioner is already provisioning as many machines as it may at once.
+func (c *WorkspaceReconciler) acquireProvisioningParallelism(ctx context.Context, wObj *kaitov1alpha1.Workspace, machineObj *v1alpha5.Machine) (func(), error) {
+	if c.ProvisioningParallelism == nil {
+		return func() {}, nil
+	}
+	release, err := c.ProvisioningParallelism.Acquire(ctx, machineObj, c.Client)
+	if err != nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
+			"machineProvisioningDelayed", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return nil, updateErr
+		}
+		return nil, err
+	}
+	return release, nil
+}
+
+// createAndValidateNode creates the machine with the given index for the workspace and returns its node once it is ready.
+func (c *WorkspaceReconciler) createAndValidateNode(ctx context.Context, wObj *kaitov1alpha1.Workspace, index int) (*corev1.Node, error) {
+	// An idle node pre-provisioned for the preset avoids waiting for a new machine.
+	if warmNode, err := machine.ClaimFromWarmPool(ctx, wObj, c.Client); err != nil {
+		klog.ErrorS(err, "failed to claim a node from the warm pool", "workspace", klog.KObj(wObj))
+	} else if warmNode != nil {
+		c.emitAudit(ctx, wObj, audit.Record{
+			Action: audit.ActionNodeProvisioned,
+			SKU:    wObj.Resource.InstanceType,
+			Node:   warmNode.Name,
+			Reason: "an idle node of the preset is claimed from the warm pool",
+		})
+		return warmNode, nil
+	}
+
+	if err := c.checkPreProvisionHook(ctx, wObj); err != nil {
+		return nil, err
+	}
+
+	instanceType, err := c.selectInstanceType(ctx, wObj)
+	if err != nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
+			"instanceTypesUnavailable", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return nil, updateErr
+		}
+		return nil, err
+	}
+
+	newMachine := machine.GenerateMachineManifest(ctx, machineOSDiskSize(wObj), wObj, index, instanceType, c.cloudProvider())
+
+	// The provisioner of the machine may only provision a limited number of machines at once.
+	release, err := c.acquireProvisioningParallelism(ctx, wObj, newMachine)
+	if err != nil {
+		return nil, err
+	}
+	// The machine names are deterministic, the machine of this index may have been created by an earlier reconcile.
+	err = machine.CreateOrAdoptMachine(ctx, wObj, newMachine, index, c.maxMachineCreateAttempts(), c.Client)
+	release()
+	if err != nil {
+		klog.ErrorS(err, "failed to create machine", "machine", newMachine.Name)
+		// The fallback instance types are selected until the capacity of the instance type returns.
+		if err.Error() == machine.ErrorInstanceTypesUnavailable {
+			cloudprovider.MarkRegionSKUUnavailable(c.Region, instanceType, c.cloudProvider())
+		}
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
+			"machineFailedCreation", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return nil, updateErr
+		}
+		return nil, err
+	}
+
+	// check machine status until it is ready, the machine is reported while its node joins the cluster.
+	err = machine.CheckMachineStatusWithLaunchedHook(ctx, newMachine, c.Client, func() {
+		if err := c.updateNodesLaunchingCondition(ctx, wObj); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		}
+	})
+	if err != nil {
+		// The nodes that cannot get IP addresses are reported along with the subnet changes that fix them.
+		if updateErr := c.updateIPExhaustionCondition(ctx, wObj); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		}
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
+			"checkMachineStatusFailed", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return nil, updateErr
+		}
+		return nil, err
+	}
+
+	// get the node object from the machine status nodeName.
+	newNode, err := resources.GetNode(ctx, newMachine.Status.NodeName, c.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	// A faulty GPU node is cordoned and its machine is deleted, so that a new machine is created in the next reconcile.
+	if c.cloudProvider().IsGPUInstanceType(wObj.Resource.InstanceType) {
+		healthy, err := resources.RunGPUHealthCheck(ctx, newNode.Name, c.Client)
+		if err != nil {
+			return nil, err
+		}
+		if !healthy {
+			err = fmt.Errorf("GPU health check failed on node %s", newNode.Name)
+			if cordonErr := resources.CordonNode(ctx, newNode.Name, c.Client); cordonErr != nil {
+				klog.ErrorS(cordonErr, "failed to cordon the node", "node", newNode.Name)
+			}
+			if deleteErr := machine.DeleteMachine(ctx, newMachine, c.Client); client.IgnoreNotFound(deleteErr) != nil {
+				klog.ErrorS(deleteErr, "failed to delete the machine", "machine", klog.KObj(newMachine))
+			} else {
+				c.auditNodeDeleted(ctx, wObj, newMachine, err.Error())
+			}
 			if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
-				"machineFailedCreation", err.Error()); updateErr != nil {
+				"gpuHealthCheckFailed", err.Error()); updateErr != nil {
 				klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
 				return nil, updateErr
 			}
@@ -343,19 +926,174 @@ Retry_withdifferentname:
 		}
 	}
 
-	// check machine status until it is ready
-	err := machine.CheckMachineStatus(ctx, newMachine, c.Client)
-	if err != nil {
+	// The model image may require a minimum driver version, which is only known once the node has joined the cluster.
+	if err := c.checkDriverVersion(ctx, wObj, newNode); err != nil {
+		klog.ErrorS(err, "the node driver version does not meet the preset requirement", "node", newNode.Name)
 		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeMachineStatus, metav1.ConditionFalse,
-			"checkMachineStatusFailed", err.Error()); updateErr != nil {
+			"driverVersionUnsupported", err.Error()); updateErr != nil {
 			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
 			return nil, updateErr
 		}
 		return nil, err
 	}
+	c.emitAudit(ctx, wObj, audit.Record{
+		Action:  audit.ActionNodeProvisioned,
+		SKU:     instanceType,
+		Machine: newMachine.Name,
+		Node:    newNode.Name,
+		Reason:  "the machine is ready and its node passed the checks of the workspace",
+	})
+	return newNode, nil
+}
 
-	// get the node object from the machine status nodeName.
-	return resources.GetNode(ctx, newMachine.Status.NodeName, c.Client)
+// validatePriorityClass checks that the priority class of the workspace exists, otherwise its pods cannot be created.
+func (c *WorkspaceReconciler) validatePriorityClass(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if wObj.Resource.PriorityClassName == "" {
+		return nil
+	}
+	priorityClass := &schedulingv1.PriorityClass{}
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: wObj.Resource.PriorityClassName}, priorityClass); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("the priority class %s of workspace %s/%s does not exist", wObj.Resource.PriorityClassName, wObj.Namespace, wObj.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// inferencePresets returns the preset of the inference of the workspace, or the presets of its variants.
+func inferencePresets(wObj *kaitov1alpha1.Workspace) []*kaitov1alpha1.PresetSpec {
+	if wObj.Inference.Preset != nil {
+		return []*kaitov1alpha1.PresetSpec{wObj.Inference.Preset}
+	}
+	return lo.Map(wObj.Inference.Variants, func(variant kaitov1alpha1.PresetVariant, _ int) *kaitov1alpha1.PresetSpec {
+		return variant.Preset.DeepCopy()
+	})
+}
+
+// machineOSDiskSize returns the OS disk size of the machines, which is the disk storage required by the inference preset.
+// Any replica of the inference variants may run on a machine, so the largest requirement of the variants is used.
+func machineOSDiskSize(wObj *kaitov1alpha1.Workspace) string {
+	var diskSize string
+	if wObj.Inference != nil {
+		for _, preset := range inferencePresets(wObj) {
+			if preset.Name == "" {
+				continue
+			}
+			required := plugin.KaitoModelRegister.MustGet(string(preset.Name)).GetInferenceParameters().DiskStorageRequirement
+			if required == "" {
+				continue
+			}
+			requiredQuantity := resource.MustParse(required)
+			if diskSize == "" || requiredQuantity.Cmp(resource.MustParse(diskSize)) > 0 {
+				diskSize = required
+			}
+		}
+	}
+	if diskSize == "" {
+		diskSize = "0" // The default OS size is used
+	}
+	return diskSize
+}
+
+// selectInstanceType returns the first instance type of the workspace that has not failed to launch in the region
+// recently.
+func (c *WorkspaceReconciler) selectInstanceType(ctx context.Context, wObj *kaitov1alpha1.Workspace) (string, error) {
+	available := cloudprovider.AvailableRegionSKUs(c.Region, machine.CandidateInstanceTypes(wObj), c.cloudProvider())
+	if len(available) == 0 {
+		return "", fmt.Errorf(machine.ErrorInstanceTypesUnavailable)
+	}
+	if available[0] != wObj.Resource.InstanceType {
+		klog.InfoS("instance type failed to launch in the region, using a fallback", "workspace", klog.KObj(wObj),
+			"instanceType", wObj.Resource.InstanceType, "fallback", available[0], "region", c.Region)
+		c.emitAudit(ctx, wObj, audit.Record{
+			Action: audit.ActionFallbackTriggered,
+			SKU:    available[0],
+			Reason: fmt.Sprintf("instance type %s recently failed to launch in region %s", wObj.Resource.InstanceType, c.Region),
+		})
+		return available[0], nil
+	}
+	c.emitAudit(ctx, wObj, audit.Record{
+		Action: audit.ActionSKUSelected,
+		SKU:    available[0],
+		Reason: "the instance type of the workspace has not failed to launch recently",
+	})
+	return available[0], nil
+}
+
+// presetMinDriverVersion returns the minimum driver version required by the preset of the workspace, if any.
+func presetMinDriverVersion(wObj *kaitov1alpha1.Workspace) string {
+	if wObj.Inference != nil && wObj.Inference.Preset != nil && wObj.Inference.Preset.Name != "" {
+		return plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name)).GetInferenceParameters().MinDriverVersion
+	}
+	if wObj.Tuning != nil && wObj.Tuning.Preset != nil && wObj.Tuning.Preset.Name != "" {
+		return plugin.KaitoModelRegister.MustGet(string(wObj.Tuning.Preset.Name)).GetTuningParameters().MinDriverVersion
+	}
+	return ""
+}
+
+// checkDriverVersion verifies the NVIDIA driver version of the node against the minimum of the preset and records the
+// result in the DriverVersionSupported condition. Without the driver labels of the GPU feature discovery the version
+// cannot be verified, the condition is then Unknown and the node is used.
+func (c *WorkspaceReconciler) checkDriverVersion(ctx context.Context, wObj *kaitov1alpha1.Workspace, nodeObj *corev1.Node) error {
+	minVersion := presetMinDriverVersion(wObj)
+	if minVersion == "" {
+		return nil
+	}
+	status, reason, message := metav1.ConditionTrue, "driverVersionSupported",
+		fmt.Sprintf("the NVIDIA driver of the nodes meets the minimum version %s", minVersion)
+	err := resources.CheckNvidiaDriverVersion(nodeObj, minVersion)
+	if _, found := nodeObj.Labels[resources.LabelKeyNvidiaDriverMajor]; !found {
+		status, reason, err = metav1.ConditionUnknown, "driverVersionUnknown", nil
+		message = fmt.Sprintf("node %s has no %s label, the driver version cannot be verified against %s",
+			nodeObj.Name, resources.LabelKeyNvidiaDriverMajor, minVersion)
+	} else if err != nil {
+		status, reason, message = metav1.ConditionFalse, "driverVersionUnsupported", err.Error()
+	}
+	if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeDriverVersion, status,
+		reason, message); updateErr != nil {
+		klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+	}
+	return err
+}
+
+// notifyProvisioningFailure reports a permanent provisioning failure to the notification sink, if any.
+func (c *WorkspaceReconciler) notifyProvisioningFailure(ctx context.Context, wObj *kaitov1alpha1.Workspace, err error) {
+	if c.NotificationSink == nil {
+		return
+	}
+	if notifyErr := c.NotificationSink.NotifyProvisioningFailure(ctx, notification.ProvisioningFailure{
+		Workspace: wObj.Name,
+		Namespace: wObj.Namespace,
+		SKU:       wObj.Resource.InstanceType,
+		Reason:    err.Error(),
+	}); notifyErr != nil {
+		klog.ErrorS(notifyErr, "failed to report the provisioning failure", "workspace", klog.KObj(wObj))
+	}
+}
+
+// emitAudit records a provisioning decision of the workspace in the audit sink, if any.
+func (c *WorkspaceReconciler) emitAudit(ctx context.Context, wObj *kaitov1alpha1.Workspace, record audit.Record) {
+	if c.AuditSink == nil {
+		return
+	}
+	record.Workspace = wObj.Name
+	record.Namespace = wObj.Namespace
+	record.Team = wObj.Labels[kaitov1alpha1.LabelTeam]
+	if err := c.AuditSink.Emit(ctx, record); err != nil {
+		klog.ErrorS(err, "failed to emit the audit record", "workspace", klog.KObj(wObj), "action", record.Action)
+	}
+}
+
+// auditNodeDeleted records the deletion of a machine of the workspace in the audit sink, if any.
+func (c *WorkspaceReconciler) auditNodeDeleted(ctx context.Context, wObj *kaitov1alpha1.Workspace, m *v1alpha5.Machine, reason string) {
+	c.emitAudit(ctx, wObj, audit.Record{
+		Action:  audit.ActionNodeDeleted,
+		SKU:     machine.MachineInstanceType(m, c.cloudProvider()),
+		Machine: m.Name,
+		Node:    m.Status.NodeName,
+		Reason:  reason,
+	})
 }
 
 // ensureNodePlugins ensures node plugins are installed.
@@ -391,47 +1129,62 @@ func (c *WorkspaceReconciler) ensureNodePlugins(ctx context.Context, wObj *kaito
 	}
 }
 
+// ensureService creates the services of the workspace that do not exist, e.g., the service of a new variant.
 func (c *WorkspaceReconciler) ensureService(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
-	serviceType := corev1.ServiceTypeClusterIP
-	wAnnotation := wObj.GetAnnotations()
-
-	if len(wAnnotation) != 0 {
-		val, found := wAnnotation[kaitov1alpha1.AnnotationEnableLB]
-		if found && val == "True" {
-			serviceType = corev1.ServiceTypeLoadBalancer
+	for _, serviceObj := range generateServiceManifests(ctx, wObj) {
+		existingSVC := &corev1.Service{}
+		if err := resources.GetResource(ctx, serviceObj.Name, serviceObj.Namespace, c.Client, existingSVC); err == nil {
+			continue
+		} else if !apierrors.IsNotFound(err) {
+			return err
 		}
-	}
-
-	existingSVC := &corev1.Service{}
-	err := resources.GetResource(ctx, wObj.Name, wObj.Namespace, c.Client, existingSVC)
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
+		if err := resources.CreateResource(ctx, serviceObj, c.Client); err != nil {
 			return err
 		}
-	} else {
+	}
+	return nil
+}
+
+// generateServiceManifests returns the services of a preset inference workspace, including the headless service
+// of distributed inference.
+func generateServiceManifests(ctx context.Context, wObj *kaitov1alpha1.Workspace) []*corev1.Service {
+	if wObj.Inference == nil || (wObj.Inference.Preset == nil && len(wObj.Inference.Variants) == 0) {
 		return nil
 	}
 
-	if wObj.Inference != nil && wObj.Inference.Preset != nil {
-		presetName := string(wObj.Inference.Preset.Name)
-		model := plugin.KaitoModelRegister.MustGet(presetName)
-		serviceObj := resources.GenerateServiceManifest(ctx, wObj, serviceType, model.SupportDistributedInference())
-		err = resources.CreateResource(ctx, serviceObj, c.Client)
-		if err != nil {
-			return err
-		}
-		if model.SupportDistributedInference() {
-			headlessService := resources.GenerateHeadlessServiceManifest(ctx, wObj)
-			err = resources.CreateResource(ctx, headlessService, c.Client)
-			if err != nil {
-				return err
+	serviceType := wObj.Inference.ServiceType
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+		wAnnotation := wObj.GetAnnotations()
+
+		if len(wAnnotation) != 0 {
+			val, found := wAnnotation[kaitov1alpha1.AnnotationEnableLB]
+			if found && val == "True" {
+				serviceType = corev1.ServiceTypeLoadBalancer
 			}
 		}
 	}
-	return nil
+
+	// The service of the workspace selects the pods of all variants, which split the traffic by their replicas.
+	if len(wObj.Inference.Variants) != 0 {
+		metricsPort := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Variants[0].Preset.Name)).GetInferenceParameters().MetricsPort
+		services := []*corev1.Service{resources.GenerateServiceManifest(ctx, wObj, serviceType, false, metricsPort)}
+		return append(services, inference.GenerateVariantServiceManifests(ctx, wObj)...)
+	}
+
+	presetName := string(wObj.Inference.Preset.Name)
+	model := plugin.KaitoModelRegister.MustGet(presetName)
+	services := []*corev1.Service{resources.GenerateServiceManifest(ctx, wObj, serviceType, model.SupportDistributedInference(),
+		model.GetInferenceParameters().MetricsPort)}
+	if model.SupportDistributedInference() {
+		services = append(services, resources.GenerateHeadlessServiceManifest(ctx, wObj))
+	}
+	return services
 }
 
-func (c *WorkspaceReconciler) applyTuning(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+// applyTuning creates the tuning job of the workspace and tracks its completion. It returns the time after which the
+// nodes of the completed job are checked again for their release, which is zero once they are released.
+func (c *WorkspaceReconciler) applyTuning(ctx context.Context, wObj *kaitov1alpha1.Workspace) (time.Duration, error) {
 	var err error
 	func() {
 		if wObj.Tuning.Preset != nil {
@@ -442,28 +1195,40 @@ func (c *WorkspaceReconciler) applyTuning(ctx context.Context, wObj *kaitov1alph
 			existingObj := &batchv1.Job{}
 			if err = resources.GetResource(ctx, wObj.Name, wObj.Namespace, c.Client, existingObj); err == nil {
 				klog.InfoS("A tuning workload already exists for workspace", "workspace", klog.KObj(wObj))
-				if err = resources.CheckResourceStatus(existingObj, c.Client, tuningParam.ReadinessTimeout); err != nil {
-					return
-				}
 			} else if apierrors.IsNotFound(err) {
-				var workloadObj client.Object
 				// Need to create a new workload
-				workloadObj, err = tuning.CreatePresetTuning(ctx, wObj, tuningParam, c.Client)
-				if err != nil {
-					return
-				}
-				if err = resources.CheckResourceStatus(workloadObj, c.Client, tuningParam.ReadinessTimeout); err != nil {
-					return
-				}
+				_, err = tuning.CreatePresetTuning(ctx, wObj, tuningParam, c.Client)
 			}
 		}
 	}()
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	// The Job is owned by the workspace, a change of its status triggers another reconcile.
+	completed, cleanupAfter, err := tuning.WatchTuningJobCompletion(ctx, wObj, c.Client)
+	if err != nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeTuningJobStatus, metav1.ConditionFalse,
+			"tuningJobFailed", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return 0, updateErr
+		}
+		return 0, err
+	}
+	if completed {
+		if err = c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeTuningJobStatus, metav1.ConditionTrue,
+			"tuningJobSucceeded", "tuning job has completed"); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return 0, err
+		}
+	}
+	return cleanupAfter, nil
+}
+
+// tuningCompleted returns true if the tuning job of the workspace has completed.
+func tuningCompleted(wObj *kaitov1alpha1.Workspace) bool {
+	return wObj.Tuning != nil && meta.IsStatusConditionTrue(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeTuningJobStatus))
 }
 
 // applyInference applies inference spec.
@@ -480,23 +1245,26 @@ func (c *WorkspaceReconciler) applyInference(ctx context.Context, wObj *kaitov1a
 			if err = resources.CheckResourceStatus(workloadObj, c.Client, time.Duration(10)*time.Minute); err != nil {
 				return
 			}
+		} else if len(wObj.Inference.Variants) != 0 {
+			var deployments []*appsv1.Deployment
+			if deployments, err = inference.ReconcileVariantInference(ctx, wObj, c.cloudProvider(), c.Client); err != nil {
+				return
+			}
+			for i, depObj := range deployments {
+				readinessTimeout := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Variants[i].Preset.Name)).GetInferenceParameters().ReadinessTimeout
+				if err = resources.CheckResourceStatus(depObj, c.Client, readinessTimeout); err != nil {
+					return
+				}
+			}
 		} else if wObj.Inference != nil && wObj.Inference.Preset != nil {
 			presetName := string(wObj.Inference.Preset.Name)
 			model := plugin.KaitoModelRegister.MustGet(presetName)
 
 			inferenceParam := model.GetInferenceParameters()
 
-			// TODO: we only do create if it does not exist for preset model. Need to document it.
-
+			// The existing workload is rolled out if it drifted from the workspace, e.g., after the preset image is upgraded.
 			var existingObj client.Object
-			if model.SupportDistributedInference() {
-				existingObj = &appsv1.StatefulSet{}
-			} else {
-				existingObj = &appsv1.Deployment{}
-
-			}
-
-			if err = resources.GetResource(ctx, wObj.Name, wObj.Namespace, c.Client, existingObj); err == nil {
+			if existingObj, err = inference.ReconcileInferenceWorkload(ctx, wObj, c.cloudProvider(), c.Client); err == nil {
 				klog.InfoS("An inference workload already exists for workspace", "workspace", klog.KObj(wObj))
 				if err = resources.CheckResourceStatus(existingObj, c.Client, inferenceParam.ReadinessTimeout); err != nil {
 					return
@@ -504,7 +1272,7 @@ func (c *WorkspaceReconciler) applyInference(ctx context.Context, wObj *kaitov1a
 			} else if apierrors.IsNotFound(err) {
 				var workloadObj client.Object
 				// Need to create a new workload
-				workloadObj, err = inference.CreatePresetInference(ctx, wObj, inferenceParam, model.SupportDistributedInference(), c.Client)
+				workloadObj, err = inference.CreatePresetInference(ctx, wObj, inferenceParam, model.SupportDistributedInference(), c.cloudProvider(), c.Client)
 				if err != nil {
 					return
 				}
@@ -516,8 +1284,18 @@ func (c *WorkspaceReconciler) applyInference(ctx context.Context, wObj *kaitov1a
 	}()
 
 	if err != nil {
+		reason := "WorkspaceInferenceStatusFailed"
+		// The replicas that became ready in time keep serving, e.g., while the others cannot be scheduled.
+		if goerrors.Is(err, context.DeadlineExceeded) {
+			if availability, availabilityErr := c.updateInferenceAvailability(ctx, wObj); availabilityErr != nil {
+				klog.ErrorS(availabilityErr, "failed to update the inference availability", "workspace", klog.KObj(wObj))
+			} else if availability == inferenceDegraded {
+				reason = "WorkspaceInferenceStatusDegraded"
+				err = goerrors.Join(err, errInferenceDegraded)
+			}
+		}
 		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeInferenceStatus, metav1.ConditionFalse,
-			"WorkspaceInferenceStatusFailed", err.Error()); updateErr != nil {
+			reason, err.Error()); updateErr != nil {
 			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
 			return updateErr
 		} else {
@@ -534,6 +1312,51 @@ func (c *WorkspaceReconciler) applyInference(ctx context.Context, wObj *kaitov1a
 	return nil
 }
 
+// ensureModelInfoConfigMap creates the ConfigMap that describes the deployed model, or updates it if it is out of date.
+func (c *WorkspaceReconciler) ensureModelInfoConfigMap(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	desired := inference.BuildModelInfoConfigMap(wObj)
+
+	existing := &corev1.ConfigMap{}
+	if err := resources.GetResource(ctx, desired.Name, desired.Namespace, c.Client, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		return client.IgnoreAlreadyExists(resources.CreateResource(ctx, desired, c.Client))
+	}
+
+	if reflect.DeepEqual(existing.Data, desired.Data) {
+		return nil
+	}
+	existing.Data = desired.Data
+	klog.InfoS("UpdateConfigMap", "configmap", klog.KObj(existing))
+	return c.Update(ctx, existing, &client.UpdateOptions{})
+}
+
+// ensureEgressNetworkPolicy creates the NetworkPolicy that restricts the egress traffic of the inference pods, or
+// updates it if the egress policy of the workspace has changed. The policy of a workspace that no longer restricts
+// the egress traffic is deleted with the orphaned objects.
+func (c *WorkspaceReconciler) ensureEgressNetworkPolicy(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	desired := resources.GenerateEgressNetworkPolicyManifest(ctx, wObj)
+	if desired == nil {
+		return nil
+	}
+
+	existing := &networkingv1.NetworkPolicy{}
+	if err := resources.GetResource(ctx, desired.Name, desired.Namespace, c.Client, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		return client.IgnoreAlreadyExists(resources.CreateResource(ctx, desired, c.Client))
+	}
+
+	if reflect.DeepEqual(existing.Spec, desired.Spec) {
+		return nil
+	}
+	existing.Spec = desired.Spec
+	klog.InfoS("UpdateNetworkPolicy", "networkpolicy", klog.KObj(existing))
+	return c.Update(ctx, existing, &client.UpdateOptions{})
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (c *WorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	c.Recorder = mgr.GetEventRecorderFor("Workspace")
@@ -543,12 +1366,19 @@ func (c *WorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}); err != nil {
 		return err
 	}
+	if err := c.setupSchedulingWatcher(mgr); err != nil {
+		return err
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kaitov1alpha1.Workspace{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&appsv1.StatefulSet{}).
-		Watches(&v1alpha5.Machine{}, c.watchMachines()).
+		Owns(&batchv1.Job{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Watches(machine.DefaultAPI.NewObject(), c.watchMachines()).
+		Watches(&corev1.Node{}, c.watchNodes(), builder.WithPredicates(nodeGPUCapacityChanged())).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 5}).
 		Complete(c)
 }
@@ -557,12 +1387,12 @@ func (c *WorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 func (c *WorkspaceReconciler) watchMachines() handler.EventHandler {
 	return handler.EnqueueRequestsFromMapFunc(
 		func(ctx context.Context, o client.Object) []reconcile.Request {
-			machineObj := o.(*v1alpha5.Machine)
-			name, ok := machineObj.Labels[kaitov1alpha1.LabelWorkspaceName]
+			// The machine is a node claim on the clusters of the newer karpenter versions, see machine.DefaultAPI.
+			name, ok := o.GetLabels()[kaitov1alpha1.LabelWorkspaceName]
 			if !ok {
 				return nil
 			}
-			namespace, ok := machineObj.Labels[kaitov1alpha1.LabelWorkspaceNamespace]
+			namespace, ok := o.GetLabels()[kaitov1alpha1.LabelWorkspaceNamespace]
 			if !ok {
 				return nil
 			}
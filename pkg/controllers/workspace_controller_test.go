@@ -5,6 +5,7 @@ package controllers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
@@ -12,14 +13,24 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/audit"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/inference"
 	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/resources"
 	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/mock"
 	"gotest.tools/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"knative.dev/pkg/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -30,9 +41,29 @@ func TestSelectWorkspaceNodes(t *testing.T) {
 		qualified []*corev1.Node
 		preferred []string
 		previous  []string
+		freeGPUs  map[string]int64
 		count     int
 		expected  []string
 	}{
+		"two qualified nodes, the one with more free GPUs is selected": {
+			qualified: []*corev1.Node{
+				{
+					ObjectMeta: v1.ObjectMeta{
+						Name: "node1",
+					},
+				},
+				{
+					ObjectMeta: v1.ObjectMeta{
+						Name: "node2",
+					},
+				},
+			},
+			preferred: []string{},
+			previous:  []string{},
+			freeGPUs:  map[string]int64{"node1": 1, "node2": 4},
+			count:     1,
+			expected:  []string{"node2"},
+		},
 		"two qualified nodes, need one": {
 			qualified: []*corev1.Node{
 				{
@@ -205,7 +236,7 @@ func TestSelectWorkspaceNodes(t *testing.T) {
 	for k, tc := range testcases {
 		t.Run(k, func(t *testing.T) {
 
-			selectedNodes := selectWorkspaceNodes(tc.qualified, tc.preferred, tc.previous, tc.count)
+			selectedNodes := selectWorkspaceNodes(tc.qualified, tc.preferred, tc.previous, tc.freeGPUs, tc.count)
 
 			selectedNodesArray := []string{}
 
@@ -233,6 +264,7 @@ func TestCreateAndValidateNode(t *testing.T) {
 	}{
 		"Node is not created because machine creation fails": {
 			callMocks: func(c *utils.MockClient) {
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
 				c.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
 				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
 				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
@@ -250,9 +282,15 @@ func TestCreateAndValidateNode(t *testing.T) {
 		},
 		"A machine is successfully created": {
 			callMocks: func(c *utils.MockClient) {
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
 				c.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
 				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
 				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+				c.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					args.Get(2).(*corev1.Pod).Status.Phase = corev1.PodSucceeded
+				})
+				c.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
 			},
 			machineConditions: apis.Conditions{
 				{
@@ -284,7 +322,7 @@ func TestCreateAndValidateNode(t *testing.T) {
 			}
 			ctx := context.Background()
 
-			node, err := reconciler.createAndValidateNode(ctx, &tc.workspace)
+			node, err := reconciler.createAndValidateNode(ctx, &tc.workspace, 0)
 			if tc.expectedError == nil {
 				assert.Check(t, err == nil, "Not expected to return error")
 				assert.Check(t, node != nil, "Response node should not be nil")
@@ -295,6 +333,252 @@ func TestCreateAndValidateNode(t *testing.T) {
 	}
 }
 
+type testPreProvisionHook struct {
+	result machine.PreProvisionResult
+}
+
+func (h *testPreProvisionHook) BeforeProvision(ctx context.Context, workspaceObj *v1alpha1.Workspace) (machine.PreProvisionResult, error) {
+	return h.result, nil
+}
+
+func TestCreateAndValidateNodeWithPreProvisionHook(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		hook                  machine.PreProvisionHook
+		expectedMachineCreate bool
+		expectedError         error
+	}{
+		"Provisioning is skipped when the hook vetoes it": {
+			hook:          &testPreProvisionHook{result: machine.PreProvisionResult{Reason: "outside business hours"}},
+			expectedError: errors.New("machine provisioning is vetoed: outside business hours"),
+		},
+		"Provisioning is delayed by the hook": {
+			hook:          &testPreProvisionHook{result: machine.PreProvisionResult{RetryAfter: time.Hour, Reason: "waiting for approval"}},
+			expectedError: &machine.ProvisioningDelayedError{RetryAfter: time.Hour, Reason: "waiting for approval"},
+		},
+		"Provisioning proceeds when the hook allows it": {
+			hook:                  &testPreProvisionHook{result: machine.PreProvisionResult{Allowed: true}},
+			expectedMachineCreate: true,
+			expectedError:         errors.New(machine.ErrorInstanceTypesUnavailable),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockMachine := &v1alpha5.Machine{}
+
+			mockClient.UpdateCb = func(key types.NamespacedName) {
+				mockClient.GetObjectFromMap(mockMachine, key)
+				mockMachine.Status.Conditions = apis.Conditions{
+					{
+						Type:    v1alpha5.MachineLaunched,
+						Status:  corev1.ConditionFalse,
+						Message: machine.ErrorInstanceTypesUnavailable,
+					},
+				}
+				mockClient.CreateOrUpdateObjectInMap(mockMachine)
+			}
+
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client:           mockClient,
+				Scheme:           utils.NewTestScheme(),
+				PreProvisionHook: tc.hook,
+			}
+			ctx := context.Background()
+
+			_, err := reconciler.createAndValidateNode(ctx, utils.MockWorkspaceWithPreset, 0)
+			assert.Equal(t, tc.expectedError.Error(), err.Error())
+			if tc.expectedMachineCreate {
+				mockClient.AssertCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreateAndValidateNodeWithMaxMachineCreateAttempts(t *testing.T) {
+	utils.RegisterTestModel()
+	mockClient := utils.NewClient()
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+	mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(errors.New("Failed to create machine"))
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+	mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+	reconciler := &WorkspaceReconciler{
+		Client:                               mockClient,
+		Scheme:                               utils.NewTestScheme(),
+		MaxMachineCreateAttemptsPerReconcile: 2,
+	}
+
+	_, err := reconciler.createAndValidateNode(context.Background(), utils.MockWorkspaceWithPreset, 0)
+	mockClient.AssertNumberOfCalls(t, "Create", 2)
+	var exhaustedErr *machine.CreateAttemptsExhaustedError
+	assert.Check(t, errors.As(err, &exhaustedErr), "unexpected error: %v", err)
+}
+
+func TestProvisioningRequeueAfter(t *testing.T) {
+	testcases := map[string]struct {
+		err             error
+		expectedRequeue bool
+		expectedAfter   time.Duration
+	}{
+		"Delayed provisioning is requeued after the delay": {
+			err:             &machine.ProvisioningDelayedError{RetryAfter: time.Hour, Reason: "waiting for approval"},
+			expectedRequeue: true,
+			expectedAfter:   time.Hour,
+		},
+		"Exhausted machine creation attempts are requeued at the provisioning interval": {
+			err:             fmt.Errorf("failed to provision: %w", &machine.CreateAttemptsExhaustedError{Machine: "ws0", Attempts: 2, Err: errors.New("Failed to create machine")}),
+			expectedRequeue: true,
+			expectedAfter:   30 * time.Second,
+		},
+		"Other errors are returned": {
+			err: errors.New("Failed to create machine"),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			reconciler := &WorkspaceReconciler{
+				RequeueIntervals: RequeueIntervals{Provisioning: 30 * time.Second},
+			}
+			after, requeue := reconciler.provisioningRequeueAfter(tc.err)
+			assert.Equal(t, requeue, tc.expectedRequeue)
+			assert.Equal(t, after, tc.expectedAfter)
+		})
+	}
+}
+
+// recordingAuditSink keeps the emitted audit records.
+type recordingAuditSink struct {
+	records []audit.Record
+}
+
+func (s *recordingAuditSink) Emit(_ context.Context, record audit.Record) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestAuditProvisionAndDelete(t *testing.T) {
+	utils.RegisterTestModel()
+	mockClient := utils.NewClient()
+	mockMachine := &v1alpha5.Machine{}
+	mockClient.UpdateCb = func(key types.NamespacedName) {
+		mockClient.GetObjectFromMap(mockMachine, key)
+		mockMachine.Status.NodeName = "node1"
+		mockMachine.Status.Conditions = apis.Conditions{{Type: apis.ConditionReady, Status: corev1.ConditionTrue}}
+		mockClient.CreateOrUpdateObjectInMap(mockMachine)
+	}
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+	mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+	mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(2).(*corev1.Pod).Status.Phase = corev1.PodSucceeded
+	})
+	mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+	mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+	mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+	sink := &recordingAuditSink{}
+	reconciler := &WorkspaceReconciler{
+		Client:    mockClient,
+		Scheme:    utils.NewTestScheme(),
+		AuditSink: sink,
+	}
+	wObj := utils.MockWorkspaceDistributedModel.DeepCopy()
+	wObj.Labels = map[string]string{v1alpha1.LabelTeam: "search"}
+
+	node, err := reconciler.createAndValidateNode(context.Background(), wObj, 0)
+	assert.NilError(t, err)
+
+	// The workspace is deleted with the machine it provisioned.
+	machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+	machineMap[client.ObjectKeyFromObject(mockMachine)] = mockMachine
+	_, err = reconciler.garbageCollectWorkspace(context.Background(), wObj)
+	assert.NilError(t, err)
+
+	actions := lo.Map(sink.records, func(record audit.Record, _ int) audit.Action { return record.Action })
+	assert.DeepEqual(t, actions, []audit.Action{audit.ActionSKUSelected, audit.ActionNodeProvisioned, audit.ActionNodeDeleted})
+	for _, record := range sink.records {
+		assert.Equal(t, record.Workspace, wObj.Name)
+		assert.Equal(t, record.Namespace, wObj.Namespace)
+		assert.Equal(t, record.Team, "search")
+		assert.Equal(t, record.SKU, wObj.Resource.InstanceType)
+		assert.Check(t, record.Reason != "", "Expected the reason of the %s decision", record.Action)
+	}
+	assert.Equal(t, sink.records[1].Machine, mockMachine.Name)
+	assert.Equal(t, sink.records[1].Node, node.Name)
+	assert.Equal(t, sink.records[2].Machine, mockMachine.Name)
+	assert.Equal(t, sink.records[2].Reason, "the workspace is deleted")
+}
+
+func TestSelectInstanceType(t *testing.T) {
+	testcases := map[string]struct {
+		region                string
+		fallbackInstanceTypes []string
+		failedToLaunch        []string
+		expectedInstanceType  string
+		expectedError         error
+	}{
+		"Instance type has not failed to launch": {
+			region:                "selectinstancetype-1",
+			fallbackInstanceTypes: []string{"Standard_NC24s_v3"},
+			expectedInstanceType:  "Standard_NC12s_v3",
+		},
+		"Fallback instance type is used once the instance type failed to launch": {
+			region:                "selectinstancetype-2",
+			fallbackInstanceTypes: []string{"Standard_NC24s_v3", "Standard_NC24ads_A100_v4"},
+			failedToLaunch:        []string{"Standard_NC12s_v3", "Standard_NC24s_v3"},
+			expectedInstanceType:  "Standard_NC24ads_A100_v4",
+		},
+		"All instance types failed to launch": {
+			region:                "selectinstancetype-3",
+			fallbackInstanceTypes: []string{"Standard_NC24s_v3"},
+			failedToLaunch:        []string{"Standard_NC12s_v3", "Standard_NC24s_v3"},
+			expectedError:         errors.New(machine.ErrorInstanceTypesUnavailable),
+		},
+		"Availability is not tracked without a region": {
+			fallbackInstanceTypes: []string{"Standard_NC24s_v3"},
+			failedToLaunch:        []string{"Standard_NC12s_v3"},
+			expectedInstanceType:  "Standard_NC12s_v3",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			provider := &cloudprovider.AzureProvider{}
+			for _, instanceType := range tc.failedToLaunch {
+				cloudprovider.MarkRegionSKUUnavailable(tc.region, instanceType, provider)
+			}
+			reconciler := &WorkspaceReconciler{
+				CloudProvider: provider,
+				Region:        tc.region,
+			}
+			wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+			wObj.Resource.InstanceType = "Standard_NC12s_v3"
+			wObj.Resource.FallbackInstanceTypes = tc.fallbackInstanceTypes
+
+			instanceType, err := reconciler.selectInstanceType(context.Background(), wObj)
+			if tc.expectedError != nil {
+				assert.Equal(t, tc.expectedError.Error(), err.Error())
+			} else {
+				assert.Check(t, err == nil, "Not expected to return error")
+				assert.Equal(t, instanceType, tc.expectedInstanceType)
+			}
+		})
+	}
+}
+
 func TestEnsureService(t *testing.T) {
 	utils.RegisterTestModel()
 	testcases := map[string]struct {
@@ -345,6 +629,133 @@ func TestEnsureService(t *testing.T) {
 
 }
 
+func TestEnsureModelInfoConfigMap(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		existingData   map[string]string
+		expectedCreate bool
+		expectedUpdate bool
+	}{
+		"ConfigMap does not exist": {
+			expectedCreate: true,
+		},
+		"ConfigMap is up to date": {
+			existingData: inference.BuildModelInfoConfigMap(utils.MockWorkspaceWithPreset).Data,
+		},
+		"ConfigMap is out of date": {
+			existingData:   map[string]string{"modelName": "old-model"},
+			expectedUpdate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			if tc.existingData != nil {
+				existing := inference.BuildModelInfoConfigMap(utils.MockWorkspaceWithPreset)
+				existing.Data = tc.existingData
+				mockClient.CreateOrUpdateObjectInMap(existing)
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.ConfigMap{}), mock.Anything).Return(nil)
+			} else {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.ConfigMap{}), mock.Anything).Return(utils.NotFoundError())
+			}
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.ConfigMap{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.ConfigMap{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			err := reconciler.ensureModelInfoConfigMap(context.Background(), utils.MockWorkspaceWithPreset)
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			expectedData := inference.BuildModelInfoConfigMap(utils.MockWorkspaceWithPreset).Data
+			if tc.expectedCreate {
+				mockClient.AssertCalled(t, "Create", mock.IsType(context.Background()), mock.MatchedBy(func(cm *corev1.ConfigMap) bool {
+					return reflect.DeepEqual(cm.Data, expectedData)
+				}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&corev1.ConfigMap{}), mock.Anything)
+			}
+			if tc.expectedUpdate {
+				mockClient.AssertCalled(t, "Update", mock.IsType(context.Background()), mock.MatchedBy(func(cm *corev1.ConfigMap) bool {
+					return reflect.DeepEqual(cm.Data, expectedData)
+				}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Update", mock.IsType(context.Background()), mock.IsType(&corev1.ConfigMap{}), mock.Anything)
+			}
+		})
+	}
+}
+
+func TestEnsureEgressNetworkPolicy(t *testing.T) {
+	testcases := map[string]struct {
+		egressPolicy   *v1alpha1.EgressPolicy
+		existingCIDRs  []string
+		expectedCreate bool
+		expectedUpdate bool
+	}{
+		"Egress is not restricted": {},
+		"NetworkPolicy does not exist": {
+			egressPolicy:   &v1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/16"}},
+			expectedCreate: true,
+		},
+		"NetworkPolicy is up to date": {
+			egressPolicy:  &v1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/16"}},
+			existingCIDRs: []string{"10.0.0.0/16"},
+		},
+		"NetworkPolicy is out of date": {
+			egressPolicy:   &v1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/16"}},
+			existingCIDRs:  []string{"10.1.0.0/16"},
+			expectedUpdate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.EgressPolicy = tc.egressPolicy
+
+			mockClient := utils.NewClient()
+			if tc.existingCIDRs != nil {
+				existingWorkspace := workspace.DeepCopy()
+				existingWorkspace.Inference.EgressPolicy = &v1alpha1.EgressPolicy{AllowedCIDRs: tc.existingCIDRs}
+				mockClient.CreateOrUpdateObjectInMap(resources.GenerateEgressNetworkPolicyManifest(context.Background(), existingWorkspace))
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything).Return(nil)
+			} else {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything).Return(utils.NotFoundError())
+			}
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			err := reconciler.ensureEgressNetworkPolicy(context.Background(), workspace)
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			if tc.egressPolicy == nil {
+				mockClient.AssertNotCalled(t, "Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tc.expectedCreate {
+				mockClient.AssertCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Create", mock.IsType(context.Background()), mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything)
+			}
+			if tc.expectedUpdate {
+				mockClient.AssertCalled(t, "Update", mock.IsType(context.Background()), mock.MatchedBy(func(policy *networkingv1.NetworkPolicy) bool {
+					return policy.Spec.Egress[len(policy.Spec.Egress)-1].To[0].IPBlock.CIDR == "10.0.0.0/16"
+				}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Update", mock.IsType(context.Background()), mock.IsType(&networkingv1.NetworkPolicy{}), mock.Anything)
+			}
+		})
+	}
+}
+
 func TestApplyInferenceWithPreset(t *testing.T) {
 	utils.RegisterTestModel()
 	testcases := map[string]struct {
@@ -531,7 +942,7 @@ func TestGetAllQualifiedNodes(t *testing.T) {
 
 			tc.callMocks(mockClient)
 
-			nodes, err := reconciler.getAllQualifiedNodes(ctx, mockWorkspace)
+			nodes, _, err := reconciler.getAllQualifiedNodes(ctx, mockWorkspace)
 			if tc.expectedError == nil {
 				assert.Check(t, err == nil, "Not expected to return error")
 				assert.Check(t, nodes != nil, "Response node array should not be nil")
@@ -711,6 +1122,7 @@ func TestApplyWorkspaceResource(t *testing.T) {
 				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
 
 				c.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.StatefulSet{}), mock.Anything).Return(utils.NotFoundError())
 
 				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
 				c.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
@@ -754,3 +1166,173 @@ func TestApplyWorkspaceResource(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePriorityClass(t *testing.T) {
+	testcases := map[string]struct {
+		priorityClassName string
+		callMocks         func(c *utils.MockClient)
+		expectedError     error
+	}{
+		"Workspace without a priority class": {
+			callMocks: func(c *utils.MockClient) {},
+		},
+		"Priority class exists": {
+			priorityClassName: "production-inference",
+			callMocks: func(c *utils.MockClient) {
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&schedulingv1.PriorityClass{}), mock.Anything).Return(nil)
+			},
+		},
+		"Priority class does not exist": {
+			priorityClassName: "production-inference",
+			callMocks: func(c *utils.MockClient) {
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&schedulingv1.PriorityClass{}), mock.Anything).Return(utils.NotFoundError())
+			},
+			expectedError: errors.New("the priority class production-inference of workspace kaito/testWorkspace does not exist"),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			tc.callMocks(mockClient)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Resource.PriorityClassName = tc.priorityClassName
+
+			err := reconciler.validatePriorityClass(context.Background(), workspace)
+			if tc.expectedError == nil {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Equal(t, tc.expectedError.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestCheckDriverVersion(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		presetName     string
+		driverLabels   map[string]string
+		expectedStatus v1.ConditionStatus
+		expectedError  bool
+		expectNoUpdate bool
+	}{
+		"Preset without a minimum driver version is not checked": {
+			presetName:     "test-model",
+			expectNoUpdate: true,
+		},
+		"Supported driver version": {
+			presetName:     "test-driver-model",
+			driverLabels:   map[string]string{resources.LabelKeyNvidiaDriverMajor: "535", resources.LabelKeyNvidiaDriverMinor: "129", resources.LabelKeyNvidiaDriverRev: "03"},
+			expectedStatus: v1.ConditionTrue,
+		},
+		"Unsupported driver version": {
+			presetName:     "test-driver-model",
+			driverLabels:   map[string]string{resources.LabelKeyNvidiaDriverMajor: "525", resources.LabelKeyNvidiaDriverMinor: "60", resources.LabelKeyNvidiaDriverRev: "13"},
+			expectedStatus: v1.ConditionFalse,
+			expectedError:  true,
+		},
+		"Node without driver labels is used": {
+			presetName:     "test-driver-model",
+			expectedStatus: v1.ConditionUnknown,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+			wObj.Inference.Preset.Name = v1alpha1.ModelName(tc.presetName)
+			mockClient.CreateOrUpdateObjectInMap(wObj)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+			node := utils.MockNodeList.Items[0].DeepCopy()
+			node.Labels = lo.Assign(node.Labels, tc.driverLabels)
+
+			err := reconciler.checkDriverVersion(context.Background(), wObj, node)
+			assert.Equal(t, err != nil, tc.expectedError)
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeDriverVersion))
+			assert.Check(t, condition != nil, "expected the DriverVersionSupported condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+		})
+	}
+}
+
+func TestRemoveGPUStartupTaints(t *testing.T) {
+	sku := corev1.Taint{Key: "sku", Value: machine.GPUString, Effect: corev1.TaintEffectNoSchedule}
+	testcases := map[string]struct {
+		gpuCapacity        string
+		registeredAgo      time.Duration
+		expectTaintRemoval bool
+		expectedEvent      bool
+	}{
+		"Startup taint is removed once the GPUs are registered": {
+			gpuCapacity:        "2",
+			registeredAgo:      time.Minute,
+			expectTaintRemoval: true,
+		},
+		"Node whose GPUs are not registered yet keeps the startup taint": {
+			registeredAgo: time.Minute,
+		},
+		"Node whose GPUs are not registered in time is reported": {
+			registeredAgo: machine.GPUStartupTimeout + time.Minute,
+			expectedEvent: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			machineObj := utils.MockMachine.DeepCopy()
+			machineObj.Spec.StartupTaints = []corev1.Taint{machine.GPUStartupTaint}
+			machineObj.Status.NodeName = "node1"
+			machineObj.Status.Conditions = apis.Conditions{{
+				Type:               v1alpha5.MachineRegistered,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: apis.VolatileTime{Inner: v1.NewTime(time.Now().Add(-tc.registeredAgo))},
+			}}
+			mockClient.CreateMapWithType(&v1alpha5.MachineList{})[client.ObjectKeyFromObject(machineObj)] = machineObj
+			nodeObj := &corev1.Node{
+				ObjectMeta: v1.ObjectMeta{Name: "node1"},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{sku, machine.GPUStartupTaint}},
+			}
+			if tc.gpuCapacity != "" {
+				nodeObj.Status.Capacity = corev1.ResourceList{resources.CapacityNvidiaGPU: resource.MustParse(tc.gpuCapacity)}
+			}
+			mockClient.CreateOrUpdateObjectInMap(nodeObj)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: recorder,
+			}
+			reconciler.removeGPUStartupTaints(context.Background(), utils.MockWorkspaceWithPreset.DeepCopy())
+
+			if tc.expectTaintRemoval {
+				mockClient.AssertCalled(t, "Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			}
+			assert.Equal(t, len(recorder.Events) == 1, tc.expectedEvent)
+		})
+	}
+}
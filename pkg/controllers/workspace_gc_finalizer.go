@@ -7,6 +7,7 @@ import (
 
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
 	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/metrics"
 	"github.com/azure/kaito/pkg/utils"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -24,10 +25,11 @@ func (c *WorkspaceReconciler) garbageCollectWorkspace(ctx context.Context, wObj
 	}
 	// We should delete all the machines that are created by this workspace
 	for i := range mList.Items {
-		if deleteErr := c.Delete(ctx, &mList.Items[i], &client.DeleteOptions{}); deleteErr != nil {
+		if deleteErr := machine.DeleteMachine(ctx, &mList.Items[i], c.Client); deleteErr != nil {
 			klog.ErrorS(deleteErr, "failed to delete the machine", "machine", klog.KObj(&mList.Items[i]))
 			return ctrl.Result{}, deleteErr
 		}
+		c.auditNodeDeleted(ctx, wObj, &mList.Items[i], "the workspace is deleted")
 	}
 
 	staleWObj := wObj.DeepCopy()
@@ -40,5 +42,6 @@ func (c *WorkspaceReconciler) garbageCollectWorkspace(ctx context.Context, wObj
 	klog.InfoS("successfully removed the workspace finalizers",
 		"workspace", klog.KObj(wObj))
 	controllerutil.RemoveFinalizer(wObj, utils.WorkspaceFinalizer)
+	metrics.DeleteWorkspaceMetrics(wObj)
 	return ctrl.Result{}, nil
 }
@@ -0,0 +1,160 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// checkGPUCapacity verifies that the nodes of the workspace have enough GPUs to run all inference replicas.
+// Otherwise, the replicas that do not fit would stay pending without any hint, so the InsufficientGPUCapacity
+// condition is set with the requested and the available number of GPUs.
+func (c *WorkspaceReconciler) checkGPUCapacity(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	required, err := c.requiredGPUs(ctx, wObj)
+	if err != nil {
+		return err
+	}
+	capacity, err := c.gpuCapacity(ctx, wObj)
+	if err != nil {
+		return err
+	}
+
+	if required > capacity {
+		err := fmt.Errorf("the inference replicas of workspace %s/%s require %d GPUs, but the ready nodes of the workspace only have %d GPUs",
+			wObj.Namespace, wObj.Name, required, capacity)
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeGPUCapacity, metav1.ConditionFalse,
+			"InsufficientGPUCapacity", err.Error()); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return updateErr
+		}
+		return err
+	}
+
+	if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeGPUCapacity, metav1.ConditionTrue,
+		"SufficientGPUCapacity", fmt.Sprintf("%d of %d GPUs are required by the inference replicas", required, capacity)); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
+	return nil
+}
+
+// requiredGPUs returns the number of GPUs requested by all inference replicas of the workspace. The replicas of
+// an existing workload are used since they may have been scaled, otherwise the workspace count is used.
+func (c *WorkspaceReconciler) requiredGPUs(ctx context.Context, wObj *kaitov1alpha1.Workspace) (int, error) {
+	replicas := lo.FromPtr(wObj.Resource.Count)
+	var gpusPerReplica int64
+
+	var existingObj client.Object
+	switch {
+	case len(wObj.Inference.Variants) != 0:
+		return variantRequiredGPUs(wObj), nil
+	case wObj.Inference.Preset != nil:
+		model := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name))
+		gpuCount := resource.MustParse(model.GetInferenceParameters().GPUCountRequirement)
+		gpusPerReplica = wObj.Inference.GetGPUsPerReplica(gpuCount.Value())
+		if model.SupportDistributedInference() {
+			existingObj = &appsv1.StatefulSet{}
+		} else {
+			existingObj = &appsv1.Deployment{}
+		}
+	case wObj.Inference.Template != nil:
+		gpusPerReplica = podGPURequests(&wObj.Inference.Template.Spec)
+		existingObj = &appsv1.Deployment{}
+	default:
+		return 0, nil
+	}
+
+	if err := resources.GetResource(ctx, wObj.Name, wObj.Namespace, c.Client, existingObj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return 0, err
+		}
+	} else {
+		switch workload := existingObj.(type) {
+		case *appsv1.Deployment:
+			if workload.Spec.Replicas != nil {
+				replicas = int(*workload.Spec.Replicas)
+			}
+		case *appsv1.StatefulSet:
+			if workload.Spec.Replicas != nil {
+				replicas = int(*workload.Spec.Replicas)
+			}
+		}
+	}
+
+	return replicas * int(gpusPerReplica), nil
+}
+
+// variantRequiredGPUs returns the number of GPUs requested by the replicas of all inference variants of the workspace.
+func variantRequiredGPUs(wObj *kaitov1alpha1.Workspace) int {
+	required := 0
+	replicas := inference.VariantReplicas(wObj.Inference.Variants, lo.FromPtr(wObj.Resource.Count))
+	for i, variant := range wObj.Inference.Variants {
+		model := plugin.KaitoModelRegister.MustGet(string(variant.Preset.Name))
+		gpuCount := resource.MustParse(model.GetInferenceParameters().GPUCountRequirement)
+		required += replicas[i] * int(gpuCount.Value())
+	}
+	return required
+}
+
+// podGPURequests returns the number of GPUs requested by the containers of the pod.
+func podGPURequests(podSpec *corev1.PodSpec) int64 {
+	var gpus int64
+	for _, container := range podSpec.Containers {
+		quantity, found := container.Resources.Requests[resources.CapacityNvidiaGPU]
+		if !found {
+			quantity = container.Resources.Limits[resources.CapacityNvidiaGPU]
+		}
+		gpus += quantity.Value()
+	}
+	return gpus
+}
+
+// gpuCapacity returns the total number of GPUs of the nodes of the Ready machines of the workspace, including
+// the existing nodes the workspace runs on.
+func (c *WorkspaceReconciler) gpuCapacity(ctx context.Context, wObj *kaitov1alpha1.Workspace) (int, error) {
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		return 0, err
+	}
+
+	nodeNames := append([]string{}, wObj.Status.WorkerNodes...)
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		_, ready := lo.Find(m.GetConditions(), func(condition apis.Condition) bool {
+			return condition.Type == apis.ConditionReady && condition.Status == corev1.ConditionTrue
+		})
+		if ready && m.DeletionTimestamp == nil && m.Status.NodeName != "" {
+			nodeNames = append(nodeNames, m.Status.NodeName)
+		}
+	}
+
+	var capacity int64
+	for _, nodeName := range lo.Uniq(nodeNames) {
+		node := &corev1.Node{}
+		if err := resources.GetResource(ctx, nodeName, "", c.Client, node); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return 0, err
+		}
+		quantity := node.Status.Capacity[resources.CapacityNvidiaGPU]
+		capacity += quantity.Value()
+	}
+	return int(capacity), nil
+}
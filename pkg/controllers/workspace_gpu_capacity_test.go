@@ -0,0 +1,157 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func mockGPUNode(name string, gpus string) *corev1.Node {
+	node := utils.MockNodeList.Items[0].DeepCopy()
+	node.Name = name
+	node.Status.Capacity = corev1.ResourceList{
+		utils.CapacityNvidiaGPU: resource.MustParse(gpus),
+	}
+	return node
+}
+
+func mockGPUMachine(name, nodeName string, ready bool) *v1alpha5.Machine {
+	m := utils.MockMachine.DeepCopy()
+	m.Name = name
+	m.Status.NodeName = nodeName
+	m.Status.Conditions = apis.Conditions{
+		{
+			Type:   apis.ConditionReady,
+			Status: lo.Ternary(ready, corev1.ConditionTrue, corev1.ConditionFalse),
+		},
+	}
+	return m
+}
+
+func TestCheckGPUCapacity(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		count          int
+		gpusPerReplica int
+		replicas       *int32
+		nodes          []*corev1.Node
+		machines       []*v1alpha5.Machine
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+		expectedError  string
+	}{
+		"Sufficient GPU capacity": {
+			count:          2,
+			nodes:          []*corev1.Node{mockGPUNode("node1", "1"), mockGPUNode("node2", "1")},
+			machines:       []*v1alpha5.Machine{mockGPUMachine("machine1", "node1", true), mockGPUMachine("machine2", "node2", true)},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "SufficientGPUCapacity",
+		},
+		"Insufficient GPU capacity": {
+			count:          3,
+			nodes:          []*corev1.Node{mockGPUNode("node1", "1"), mockGPUNode("node2", "1")},
+			machines:       []*v1alpha5.Machine{mockGPUMachine("machine1", "node1", true), mockGPUMachine("machine2", "node2", true)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "InsufficientGPUCapacity",
+			expectedError:  "require 3 GPUs, but the ready nodes of the workspace only have 2 GPUs",
+		},
+		"Nodes of machines that are not ready are not counted": {
+			count:          2,
+			nodes:          []*corev1.Node{mockGPUNode("node1", "1"), mockGPUNode("node2", "1")},
+			machines:       []*v1alpha5.Machine{mockGPUMachine("machine1", "node1", true), mockGPUMachine("machine2", "node2", false)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "InsufficientGPUCapacity",
+			expectedError:  "require 2 GPUs, but the ready nodes of the workspace only have 1 GPUs",
+		},
+		"Replicas of the scaled workload exceed the GPU capacity": {
+			count:          1,
+			replicas:       lo.ToPtr(int32(4)),
+			nodes:          []*corev1.Node{mockGPUNode("node1", "2")},
+			machines:       []*v1alpha5.Machine{mockGPUMachine("machine1", "node1", true)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "InsufficientGPUCapacity",
+			expectedError:  "require 4 GPUs, but the ready nodes of the workspace only have 2 GPUs",
+		},
+		"Replicas with several GPUs fit the GPU capacity": {
+			count:          2,
+			gpusPerReplica: 2,
+			nodes:          []*corev1.Node{mockGPUNode("node1", "2"), mockGPUNode("node2", "2")},
+			machines:       []*v1alpha5.Machine{mockGPUMachine("machine1", "node1", true), mockGPUMachine("machine2", "node2", true)},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "SufficientGPUCapacity",
+		},
+		"Replicas with several GPUs exceed the GPU capacity": {
+			count:          2,
+			gpusPerReplica: 2,
+			nodes:          []*corev1.Node{mockGPUNode("node1", "2"), mockGPUNode("node2", "1")},
+			machines:       []*v1alpha5.Machine{mockGPUMachine("machine1", "node1", true), mockGPUMachine("machine2", "node2", true)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "InsufficientGPUCapacity",
+			expectedError:  "require 4 GPUs, but the ready nodes of the workspace only have 3 GPUs",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+			wObj.Resource.Count = lo.ToPtr(tc.count)
+			wObj.Inference.GPUsPerReplica = tc.gpusPerReplica
+
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			for _, node := range tc.nodes {
+				mockClient.CreateOrUpdateObjectInMap(node)
+			}
+			for _, m := range tc.machines {
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			if tc.replicas != nil {
+				mockClient.CreateOrUpdateObjectInMap(&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: wObj.Name, Namespace: wObj.Namespace},
+					Spec:       appsv1.DeploymentSpec{Replicas: tc.replicas},
+				})
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			} else {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			err := reconciler.checkGPUCapacity(context.Background(), wObj)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "unexpected error: %v", err)
+			}
+
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeGPUCapacity))
+			assert.Check(t, condition != nil, "expected the GPU capacity condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			assert.Equal(t, condition.Reason, tc.expectedReason)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nvidiaResourcePrefix is the prefix of the resources exposed by the NVIDIA device plugin, i.e., whole GPUs
+// (nvidia.com/gpu), MIG profiles (e.g., nvidia.com/mig-1g.5gb) and time-sliced GPUs (e.g., nvidia.com/gpu.shared).
+const nvidiaResourcePrefix = "nvidia.com/"
+
+// requestedGPUResourceNames returns the sorted NVIDIA resource names requested by the pods of the workspace.
+// The presets request whole GPUs, the Pod template may request MIG profiles or time-sliced GPUs.
+func requestedGPUResourceNames(wObj *kaitov1alpha1.Workspace) []corev1.ResourceName {
+	if wObj.Inference == nil || wObj.Inference.Template == nil {
+		return []corev1.ResourceName{resources.CapacityNvidiaGPU}
+	}
+
+	var names []corev1.ResourceName
+	for _, container := range wObj.Inference.Template.Spec.Containers {
+		for _, list := range []corev1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+			for name := range list {
+				if strings.HasPrefix(string(name), nvidiaResourcePrefix) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	names = lo.Uniq(names)
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// unavailableGPUResourceNames returns the GPU resource names requested by the workspace that no node in the cluster
+// exposes, whose pods would stay pending forever. The nodes kaito provisions only expose whole GPUs, so MIG profiles
+// and time-sliced GPUs must be exposed by the existing nodes, which requires a device plugin configuration.
+func (c *WorkspaceReconciler) unavailableGPUResourceNames(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]corev1.ResourceName, error) {
+	requested := requestedGPUResourceNames(wObj)
+	if len(requested) == 0 {
+		return nil, nil
+	}
+
+	nodeList, err := resources.ListNodes(ctx, c.Client, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var unavailable []corev1.ResourceName
+	for _, name := range requested {
+		if name == resources.CapacityNvidiaGPU && c.cloudProvider().IsGPUInstanceType(wObj.Resource.InstanceType) {
+			continue
+		}
+		exposed := lo.ContainsBy(nodeList.Items, func(node corev1.Node) bool {
+			_, allocatable := node.Status.Allocatable[name]
+			_, capacity := node.Status.Capacity[name]
+			return allocatable || capacity
+		})
+		if !exposed {
+			unavailable = append(unavailable, name)
+		}
+	}
+	return unavailable, nil
+}
+
+// updateGPUResourceCondition reports the GPU resource names that no node exposes in the GPUResourceAvailable
+// condition. The warning event is only recorded when the unavailable names change, not on every reconcile. The
+// condition is only set to True once it has been False, a workspace whose GPU resources were always exposed does not
+// carry it.
+func (c *WorkspaceReconciler) updateGPUResourceCondition(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	unavailable, err := c.unavailableGPUResourceNames(ctx, wObj)
+	if err != nil {
+		return err
+	}
+	if len(unavailable) > 0 {
+		names := lo.Map(unavailable, func(name corev1.ResourceName, _ int) string { return string(name) })
+		message := fmt.Sprintf("No node exposes the requested GPU resources %s, configure the device plugin of the nodes to expose them",
+			strings.Join(names, ", "))
+		if !conditionMatches(wObj, kaitov1alpha1.WorkspaceConditionTypeGPUResourceAvailable, metav1.ConditionFalse, "GPUResourceUnavailable", message) {
+			c.recordEvent(wObj, corev1.EventTypeWarning, "GPUResourceUnavailable", message)
+		}
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeGPUResourceAvailable, metav1.ConditionFalse,
+			"GPUResourceUnavailable", message)
+	}
+	if meta.IsStatusConditionFalse(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeGPUResourceAvailable)) {
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeGPUResourceAvailable, metav1.ConditionTrue,
+			"GPUResourceAvailable", "the nodes expose the requested GPU resources")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestUnavailableGPUResourceNames(t *testing.T) {
+	migProfile := corev1.ResourceName("nvidia.com/mig-1g.5gb")
+	sharedGPU := corev1.ResourceName("nvidia.com/gpu.shared")
+
+	testcases := map[string]struct {
+		requested           []corev1.ResourceName
+		instanceType        string
+		nodeResources       []corev1.ResourceName
+		expectedUnavailable []corev1.ResourceName
+	}{
+		"Whole GPUs of the provisioned nodes": {
+			requested:    []corev1.ResourceName{utils.CapacityNvidiaGPU},
+			instanceType: "Standard_NC12s_v3",
+		},
+		"Whole GPUs without a GPU instance type or node": {
+			requested:           []corev1.ResourceName{utils.CapacityNvidiaGPU},
+			instanceType:        "Standard_D4s_v3",
+			expectedUnavailable: []corev1.ResourceName{utils.CapacityNvidiaGPU},
+		},
+		"MIG profile exposed by a node": {
+			requested:     []corev1.ResourceName{migProfile},
+			instanceType:  "Standard_NC24ads_A100_v4",
+			nodeResources: []corev1.ResourceName{migProfile},
+		},
+		"MIG profile not exposed by any node": {
+			requested:           []corev1.ResourceName{migProfile},
+			instanceType:        "Standard_NC24ads_A100_v4",
+			nodeResources:       []corev1.ResourceName{utils.CapacityNvidiaGPU},
+			expectedUnavailable: []corev1.ResourceName{migProfile},
+		},
+		"Time-sliced GPU not exposed by any node": {
+			requested:           []corev1.ResourceName{migProfile, sharedGPU},
+			instanceType:        "Standard_NC24ads_A100_v4",
+			nodeResources:       []corev1.ResourceName{migProfile},
+			expectedUnavailable: []corev1.ResourceName{sharedGPU},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			wObj := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+			wObj.Resource.InstanceType = tc.instanceType
+			limits := corev1.ResourceList{}
+			for _, name := range tc.requested {
+				limits[name] = resource.MustParse("1")
+			}
+			wObj.Inference.Template.Spec.Containers = []corev1.Container{
+				{Name: "model-server", Resources: corev1.ResourceRequirements{Limits: limits}},
+			}
+
+			nodeMap := mockClient.CreateMapWithType(&corev1.NodeList{})
+			if len(tc.nodeResources) > 0 {
+				node := utils.MockNodeList.Items[0].DeepCopy()
+				node.Status.Allocatable = corev1.ResourceList{}
+				for _, name := range tc.nodeResources {
+					node.Status.Allocatable[name] = resource.MustParse("7")
+				}
+				nodeMap[client.ObjectKeyFromObject(node)] = node
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			unavailable, err := reconciler.unavailableGPUResourceNames(context.Background(), wObj)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.DeepEqual(t, unavailable, tc.expectedUnavailable)
+		})
+	}
+}
+
+func TestUpdateGPUResourceCondition(t *testing.T) {
+	migProfile := corev1.ResourceName("nvidia.com/mig-1g.5gb")
+	unavailableMessage := "No node exposes the requested GPU resources nvidia.com/mig-1g.5gb, configure the device plugin of the nodes to expose them"
+
+	testcases := map[string]struct {
+		nodeResources  []corev1.ResourceName
+		conditions     []metav1.Condition
+		expectedStatus metav1.ConditionStatus
+		expectedEvents int
+		expectNoUpdate bool
+	}{
+		"Unavailable GPU resources are recorded once": {
+			expectedStatus: metav1.ConditionFalse,
+			expectedEvents: 1,
+		},
+		"Unavailable GPU resources already reported are not recorded again": {
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeGPUResourceAvailable), Status: metav1.ConditionFalse, Reason: "GPUResourceUnavailable", Message: unavailableMessage},
+			},
+			expectNoUpdate: true,
+		},
+		"Exposed GPU resources clear the condition": {
+			nodeResources: []corev1.ResourceName{migProfile},
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeGPUResourceAvailable), Status: metav1.ConditionFalse, Reason: "GPUResourceUnavailable", Message: unavailableMessage},
+			},
+			expectedStatus: metav1.ConditionTrue,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			wObj := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+			wObj.Resource.InstanceType = "Standard_NC24ads_A100_v4"
+			wObj.Inference.Template.Spec.Containers = []corev1.Container{
+				{Name: "model-server", Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{migProfile: resource.MustParse("1")}}},
+			}
+			wObj.Status.Conditions = tc.conditions
+			mockClient.CreateOrUpdateObjectInMap(wObj)
+
+			nodeMap := mockClient.CreateMapWithType(&corev1.NodeList{})
+			if len(tc.nodeResources) > 0 {
+				node := utils.MockNodeList.Items[0].DeepCopy()
+				node.Status.Allocatable = corev1.ResourceList{}
+				for _, name := range tc.nodeResources {
+					node.Status.Allocatable[name] = resource.MustParse("7")
+				}
+				nodeMap[client.ObjectKeyFromObject(node)] = node
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: recorder,
+			}
+
+			assert.NilError(t, reconciler.updateGPUResourceCondition(context.Background(), wObj))
+			assert.Equal(t, len(recorder.Events), tc.expectedEvents)
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeGPUResourceAvailable))
+			assert.Check(t, condition != nil, "expected the GPUResourceAvailable condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/tuning"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// checkMirrorImages returns an error that lists the images of the workspace that the mirror registry does not serve,
+// the nodes of an air-gapped cluster could never start the workload otherwise. The images are not checked if no
+// mirror is configured. An image whose check fails, e.g., because the mirror requires credentials, is not reported.
+func (c *WorkspaceReconciler) checkMirrorImages(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if c.ImageMirror == nil {
+		return nil
+	}
+	images, err := c.workspaceImages(ctx, wObj)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, image := range images {
+		exists, err := c.ImageMirror.ManifestExists(ctx, image)
+		if err != nil {
+			klog.ErrorS(err, "failed to check the image in the mirror registry", "image", image, "workspace", klog.KObj(wObj))
+			continue
+		}
+		if !exists {
+			missing = append(missing, image)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the images of workspace %s/%s are missing in the mirror registry: %s",
+		wObj.Namespace, wObj.Name, strings.Join(missing, ", "))
+}
+
+// workspaceImages returns the images the pods of the workspace run, i.e., the model server image of the preset and
+// its runtime, the sidecars and the init containers, sorted and without duplicates.
+func (c *WorkspaceReconciler) workspaceImages(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]string, error) {
+	podSpecs, err := c.workspacePodSpecs(ctx, wObj)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []string
+	for _, podSpec := range podSpecs {
+		for _, container := range podSpec.InitContainers {
+			images = append(images, container.Image)
+		}
+		for _, container := range podSpec.Containers {
+			images = append(images, container.Image)
+		}
+	}
+	images = lo.Uniq(images)
+	sort.Strings(images)
+	return images, nil
+}
+
+// workspacePodSpecs renders the specs of the pods of the workspace, i.e., of its inference workloads and its tuning job.
+func (c *WorkspaceReconciler) workspacePodSpecs(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]*corev1.PodSpec, error) {
+	var podSpecs []*corev1.PodSpec
+	if wObj.Inference != nil {
+		switch {
+		case wObj.Inference.Template != nil:
+			depObj := inference.GenerateTemplateInferenceManifest(ctx, wObj)
+			podSpecs = append(podSpecs, &depObj.Spec.Template.Spec)
+		case len(wObj.Inference.Variants) != 0:
+			deployments, err := inference.GenerateVariantInferenceManifests(ctx, wObj, c.cloudProvider())
+			if err != nil {
+				return nil, err
+			}
+			for _, depObj := range deployments {
+				podSpecs = append(podSpecs, &depObj.Spec.Template.Spec)
+			}
+		case wObj.Inference.Preset != nil:
+			// The distributed inference runs the same images in a StatefulSet.
+			inferenceParam := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name)).GetInferenceParameters()
+			depObj := inference.GeneratePresetInferenceManifest(ctx, wObj, inferenceParam, false, c.cloudProvider()).(*appsv1.Deployment)
+			podSpecs = append(podSpecs, &depObj.Spec.Template.Spec)
+		}
+	}
+	if wObj.Tuning != nil && wObj.Tuning.Preset != nil {
+		tuningParam := plugin.KaitoModelRegister.MustGet(string(wObj.Tuning.Preset.Name)).GetTuningParameters()
+		jobObj := tuning.GeneratePresetTuningManifest(ctx, wObj, tuningParam)
+		podSpecs = append(podSpecs, &jobObj.Spec.Template.Spec)
+	}
+	return podSpecs, nil
+}
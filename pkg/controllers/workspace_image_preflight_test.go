@@ -0,0 +1,92 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/utils"
+	"gotest.tools/assert"
+)
+
+// testMirror serves all images except the missing ones, and fails to check the images of the failing registry.
+type testMirror struct {
+	missing []string
+	checked []string
+}
+
+func (m *testMirror) ManifestExists(_ context.Context, image string) (bool, error) {
+	m.checked = append(m.checked, image)
+	if strings.HasPrefix(image, "failing.registry.io/") {
+		return false, errors.New("mirror registry returned status code 401")
+	}
+	for _, missing := range m.missing {
+		if strings.Contains(image, missing) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func TestCheckMirrorImages(t *testing.T) {
+	utils.RegisterTestModel()
+	authWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	authWorkspace.Inference.Auth = &v1alpha1.InferenceAuth{SecretName: "inference-token"}
+
+	testcases := map[string]struct {
+		mirror        *testMirror
+		registry      string
+		workspaceAuth bool
+		expectedError string
+	}{
+		"Images are not checked without a mirror": {},
+		"Images are present in the mirror": {
+			mirror: &testMirror{},
+		},
+		"Preset image is missing in the mirror": {
+			mirror:        &testMirror{missing: []string{"kaito-test-model"}},
+			expectedError: "the images of workspace kaito/testWorkspace are missing in the mirror registry: ",
+		},
+		"Sidecar image is missing in the mirror": {
+			mirror:        &testMirror{missing: []string{"nginx"}},
+			workspaceAuth: true,
+			expectedError: "the images of workspace kaito/testWorkspace are missing in the mirror registry: " + inference.AuthProxyImage,
+		},
+		"Images that cannot be checked are not reported": {
+			mirror:   &testMirror{missing: []string{"kaito-test-model"}},
+			registry: "failing.registry.io",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			t.Setenv("PRESET_REGISTRY_NAME", tc.registry)
+			wObj := utils.MockWorkspaceWithPreset
+			if tc.workspaceAuth {
+				wObj = authWorkspace
+			}
+			reconciler := &WorkspaceReconciler{
+				Client: utils.NewClient(),
+				Scheme: utils.NewTestScheme(),
+			}
+			if tc.mirror != nil {
+				reconciler.ImageMirror = tc.mirror
+			}
+
+			err := reconciler.checkMirrorImages(context.Background(), wObj)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error: %v", err)
+			} else {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "unexpected error: %v", err)
+			}
+			if tc.mirror != nil {
+				assert.Check(t, len(tc.mirror.checked) > 0, "Expected the images to be checked")
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateIPExhaustionCondition reports the machines of the workspace whose nodes cannot get IP addresses in the
+// IPExhausted condition, along with the subnet changes that fix them. The condition is only set to False once it has
+// been True, a workspace whose machines have never run out of IP addresses does not carry it.
+func (c *WorkspaceReconciler) updateIPExhaustionCondition(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		klog.ErrorS(err, "failed to list the machines of the workspace", "workspace", klog.KObj(wObj))
+		return err
+	}
+	var failures []string
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if !m.DeletionTimestamp.IsZero() {
+			continue
+		}
+		var nodeObj *corev1.Node
+		if m.Status.NodeName != "" {
+			nodeObj = &corev1.Node{}
+			if err := c.Client.Get(ctx, client.ObjectKey{Name: m.Status.NodeName}, nodeObj); err != nil {
+				if !apierrors.IsNotFound(err) {
+					klog.ErrorS(err, "failed to get the node of the machine", "machine", klog.KObj(m), "node", m.Status.NodeName)
+					return err
+				}
+				nodeObj = nil
+			}
+		}
+		if message, found := machine.DetectIPExhaustion(m, nodeObj); found {
+			failures = append(failures, fmt.Sprintf("machine %s: %s", m.Name, message))
+		}
+	}
+
+	exhausted := meta.IsStatusConditionTrue(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeIPExhausted))
+	if len(failures) > 0 {
+		message := fmt.Sprintf("%d machine(s) failed to join the cluster because no IP addresses are available for their nodes, "+
+			"use a larger subnet or a subnet with free IP addresses for the nodes, or lower the max pods per node: %s",
+			len(failures), strings.Join(failures, "; "))
+		if !exhausted {
+			c.recordEvent(wObj, corev1.EventTypeWarning, machine.FailureCategoryIPExhaustion, message)
+		}
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeIPExhausted, metav1.ConditionTrue,
+			machine.FailureCategoryIPExhaustion, message)
+	}
+	if exhausted {
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeIPExhausted, metav1.ConditionFalse,
+			"IPAddressesAvailable", "the nodes of the workspace got IP addresses")
+	}
+	return nil
+}
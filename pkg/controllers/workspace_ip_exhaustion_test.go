@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestUpdateIPExhaustionCondition(t *testing.T) {
+	fullSubnetMachine := mockMachineWithConditions("machine-1", corev1.ConditionFalse, corev1.ConditionFalse)
+	fullSubnetMachine.Status.Conditions[0].Message = `Code="SubnetIsFull" Message="Subnet aks-subnet with address prefix 10.224.0.0/24 does not have enough capacity for 31 IP addresses."`
+
+	notJoinedMachine := mockMachineWithConditions("machine-2", corev1.ConditionTrue, corev1.ConditionFalse)
+	notJoinedMachine.Status.NodeName = "node-2"
+	exhaustedNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Message: "failed to allocate for range 0: no IP addresses available in range set"},
+		}},
+	}
+
+	testcases := map[string]struct {
+		machines        []*v1alpha5.Machine
+		conditions      []metav1.Condition
+		expectedStatus  metav1.ConditionStatus
+		expectedContent []string
+		expectNoUpdate  bool
+	}{
+		"Machines whose nodes cannot get IP addresses are reported": {
+			machines:       []*v1alpha5.Machine{fullSubnetMachine, notJoinedMachine},
+			expectedStatus: metav1.ConditionTrue,
+			expectedContent: []string{"2 machine(s) failed to join the cluster", "use a larger subnet",
+				"machine machine-1: SubnetIsFull", "machine machine-2: failed to allocate for range 0"},
+		},
+		"Nodes that got IP addresses clear the condition": {
+			machines: []*v1alpha5.Machine{
+				mockMachineWithConditions("machine-1", corev1.ConditionTrue, corev1.ConditionTrue),
+			},
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeIPExhausted), Status: metav1.ConditionTrue, Reason: "IPExhaustion"},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedContent: []string{"the nodes of the workspace got IP addresses"},
+		},
+		"Workspace whose machines never ran out of IP addresses is not reported": {
+			machines: []*v1alpha5.Machine{
+				mockMachineWithConditions("machine-1", corev1.ConditionTrue, corev1.ConditionFalse),
+			},
+			expectNoUpdate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Status.Conditions = tc.conditions
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.CreateOrUpdateObjectInMap(exhaustedNode)
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			for _, m := range tc.machines {
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			assert.NilError(t, reconciler.updateIPExhaustionCondition(context.Background(), workspace))
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeIPExhausted))
+			assert.Check(t, condition != nil, "expected the IPExhausted condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			for _, content := range tc.expectedContent {
+				assert.Check(t, strings.Contains(condition.Message, content), "expected %q in the message %q", content, condition.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ensureModelCache checks that the persistent volume claim of the model cache of the workspace exists, otherwise the
+// pods that mount it stay pending. The claim is created if the workspace specifies its size. It is not owned by the
+// workspace, since the cache may be shared with other workspaces.
+func (c *WorkspaceReconciler) ensureModelCache(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	cache := wObj.ModelCache
+	if cache == nil {
+		return nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := resources.GetResource(ctx, cache.PersistentVolumeClaim, wObj.Namespace, c.Client, pvc)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+	if cache.Size == nil {
+		return fmt.Errorf("the persistent volume claim %s of the model cache of workspace %s/%s does not exist, create it or specify its size",
+			cache.PersistentVolumeClaim, wObj.Namespace, wObj.Name)
+	}
+
+	klog.InfoS("creating the persistent volume claim of the model cache", "workspace", klog.KObj(wObj), "pvc", cache.PersistentVolumeClaim)
+	// The workspaces that share the cache may create the claim at the same time.
+	return client.IgnoreAlreadyExists(resources.CreateResource(ctx, generateModelCachePVC(wObj), c.Client))
+}
+
+// generateModelCachePVC returns the persistent volume claim of the model cache, which the pods on all nodes of the
+// workspace mount.
+func generateModelCachePVC(wObj *kaitov1alpha1.Workspace) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        wObj.ModelCache.PersistentVolumeClaim,
+			Namespace:   wObj.Namespace,
+			Labels:      resources.WithCommonLabels(wObj, nil),
+			Annotations: resources.WithCommonAnnotations(wObj, nil),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			StorageClassName: wObj.ModelCache.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: *wObj.ModelCache.Size},
+			},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestEnsureModelCache(t *testing.T) {
+	size := resource.MustParse("100Gi")
+	testcases := map[string]struct {
+		modelCache    *v1alpha1.ModelCacheSpec
+		pvcExists     bool
+		expectCreate  bool
+		expectedError string
+	}{
+		"Workspace without a model cache": {},
+		"Existing persistent volume claim is used": {
+			modelCache: &v1alpha1.ModelCacheSpec{PersistentVolumeClaim: "hf-cache"},
+			pvcExists:  true,
+		},
+		"Missing persistent volume claim without a size": {
+			modelCache:    &v1alpha1.ModelCacheSpec{PersistentVolumeClaim: "hf-cache"},
+			expectedError: "the persistent volume claim hf-cache of the model cache of workspace kaito/testWorkspace does not exist, create it or specify its size",
+		},
+		"Missing persistent volume claim with a size is created": {
+			modelCache:   &v1alpha1.ModelCacheSpec{PersistentVolumeClaim: "hf-cache", Size: &size, StorageClassName: lo.ToPtr("azurefile-csi")},
+			expectCreate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.ModelCache = tc.modelCache
+			var getErr error
+			if !tc.pvcExists {
+				getErr = apierrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "hf-cache")
+			}
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.PersistentVolumeClaim{}), mock.Anything).Return(getErr)
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.PersistentVolumeClaim{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			err := reconciler.ensureModelCache(context.Background(), workspace)
+			if tc.expectedError != "" {
+				assert.Error(t, err, tc.expectedError)
+				return
+			}
+			assert.NilError(t, err)
+			if !tc.expectCreate {
+				mockClient.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			pvc := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*corev1.PersistentVolumeClaim)
+			assert.Equal(t, pvc.Name, "hf-cache")
+			assert.Equal(t, pvc.Namespace, workspace.Namespace)
+			assert.DeepEqual(t, pvc.Spec.AccessModes, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany})
+			assert.Equal(t, *pvc.Spec.StorageClassName, "azurefile-csi")
+			assert.Check(t, pvc.Spec.Resources.Requests.Storage().Equal(size))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nodeRequirements are the requirements of the pods of a workspace on an existing node, beyond its labels.
+type nodeRequirements struct {
+	// tolerations are the tolerations of the pods, a node must not have taints that none of them tolerates.
+	tolerations []corev1.Toleration
+	// gpus is the largest number of GPUs a pod of the workspace requests.
+	gpus int64
+	// regions are the regions the presets of the workspace are restricted to, if restricted.
+	regions    []string
+	restricted bool
+}
+
+// workspaceNodeRequirements returns the requirements of the pods of the workspace on the existing nodes.
+func (c *WorkspaceReconciler) workspaceNodeRequirements(ctx context.Context, wObj *kaitov1alpha1.Workspace) (*nodeRequirements, error) {
+	podSpecs, err := c.workspacePodSpecs(ctx, wObj)
+	if err != nil {
+		return nil, err
+	}
+	requirements := &nodeRequirements{}
+	for _, podSpec := range podSpecs {
+		requirements.tolerations = append(requirements.tolerations, podSpec.Tolerations...)
+		requirements.gpus = lo.Max([]int64{requirements.gpus, podGPURequests(podSpec)})
+	}
+	requirements.regions, requirements.restricted = wObj.AllowedRegions()
+	return requirements, nil
+}
+
+// matchNodes returns the nodes that fit the pods of the workspace, along with the number of GPUs each of them has
+// free for the workspace. A node does not fit if it has a taint the pods do not tolerate, if it is in a region the
+// presets are not available in, or if the pods of other workloads leave fewer GPUs than a pod of the workspace
+// requests. The free GPUs of a node are unknown until its device plugin reports them, such a node is kept.
+func (c *WorkspaceReconciler) matchNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace, nodes []*corev1.Node) ([]*corev1.Node, map[string]int64, error) {
+	if len(nodes) == 0 {
+		return nodes, nil, nil
+	}
+	requirements, err := c.workspaceNodeRequirements(ctx, wObj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var matched []*corev1.Node
+	freeGPUs := map[string]int64{}
+	for _, node := range nodes {
+		if reason := requirements.mismatch(node); reason != "" {
+			klog.InfoS("skipping the node that does not fit the workspace", "workspace", klog.KObj(wObj), "node", node.Name, "reason", reason)
+			continue
+		}
+		allocatable, found := node.Status.Allocatable[resources.CapacityNvidiaGPU]
+		if !found || requirements.gpus == 0 {
+			matched = append(matched, node)
+			continue
+		}
+		used, err := c.otherWorkloadGPUs(ctx, wObj, node.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		free := allocatable.Value() - used
+		if free < requirements.gpus {
+			klog.InfoS("skipping the node that does not fit the workspace", "workspace", klog.KObj(wObj), "node", node.Name,
+				"reason", fmt.Sprintf("only %d of %d GPUs are free, a pod requests %d", lo.Max([]int64{free, 0}), allocatable.Value(), requirements.gpus))
+			continue
+		}
+		freeGPUs[node.Name] = free
+		matched = append(matched, node)
+	}
+	return matched, freeGPUs, nil
+}
+
+// mismatch returns why the node does not fit the pods, or an empty string if it fits.
+func (r *nodeRequirements) mismatch(node *corev1.Node) string {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		// The pods may still be scheduled on the node if the taint is only a preference. A cordoned node is
+		// handled by the machines of the workspace, e.g., it is about to be scaled down. The GPU startup taint is
+		// removed once the GPUs of the node are registered.
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule || taint.Key == corev1.TaintNodeUnschedulable ||
+			taint.Key == machine.GPUStartupTaintKey {
+			continue
+		}
+		if !lo.ContainsBy(r.tolerations, func(toleration corev1.Toleration) bool { return toleration.ToleratesTaint(taint) }) {
+			return fmt.Sprintf("the pods do not tolerate the taint %s", taint.ToString())
+		}
+	}
+	if region, found := node.Labels[corev1.LabelTopologyRegion]; found && r.restricted && !lo.Contains(r.regions, region) {
+		return fmt.Sprintf("the presets are not available in region %s", region)
+	}
+	return ""
+}
+
+// otherWorkloadGPUs returns the number of GPUs that the pods of other workloads request on the node.
+func (c *WorkspaceReconciler) otherWorkloadGPUs(ctx context.Context, wObj *kaitov1alpha1.Workspace, nodeName string) (int64, error) {
+	pods := &corev1.PodList{}
+	if err := c.Client.List(ctx, pods, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		klog.ErrorS(err, "failed to list the pods of the node", "node", nodeName)
+		return 0, err
+	}
+	var used int64
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName != nodeName || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.Namespace == wObj.Namespace && pod.Labels[kaitov1alpha1.LabelWorkspaceName] == wObj.Name {
+			continue
+		}
+		used += podGPURequests(&pod.Spec)
+	}
+	return used, nil
+}
@@ -0,0 +1,127 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func mockAllocatableGPUNode(name string, gpus string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"apps":                         "test",
+				corev1.LabelInstanceTypeStable: "Standard_NC12s_v3",
+			},
+		},
+		Spec: corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse(gpus)},
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func mockGPUPod(name, namespace, nodeName string, gpus string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{
+				{
+					Name: "workload",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse(gpus)},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestGetAllQualifiedNodesMatchesNodes(t *testing.T) {
+	utils.RegisterTestModel()
+	ownPod := mockGPUPod("own-pod", utils.MockWorkspaceWithPreset.Namespace, "full-node", "1")
+	ownPod.Labels = map[string]string{kaitov1alpha1.LabelWorkspaceName: utils.MockWorkspaceWithPreset.Name}
+	completedPod := mockGPUPod("completed-pod", "other", "free-node", "1")
+	completedPod.Status.Phase = corev1.PodSucceeded
+
+	testcases := map[string]struct {
+		nodes            []*corev1.Node
+		pods             []*corev1.Pod
+		expectedNodes    []string
+		expectedFreeGPUs map[string]int64
+	}{
+		"GPU-full node is skipped in favor of a free one": {
+			nodes:            []*corev1.Node{mockAllocatableGPUNode("full-node", "1"), mockAllocatableGPUNode("free-node", "1")},
+			pods:             []*corev1.Pod{mockGPUPod("other-pod", "other", "full-node", "1"), completedPod},
+			expectedNodes:    []string{"free-node"},
+			expectedFreeGPUs: map[string]int64{"free-node": 1},
+		},
+		"GPUs used by the workspace itself are free for it": {
+			nodes:            []*corev1.Node{mockAllocatableGPUNode("full-node", "1")},
+			pods:             []*corev1.Pod{ownPod},
+			expectedNodes:    []string{"full-node"},
+			expectedFreeGPUs: map[string]int64{"full-node": 1},
+		},
+		"Node with a taint the pods do not tolerate is skipped": {
+			nodes: []*corev1.Node{
+				mockAllocatableGPUNode("tainted-node", "1", corev1.Taint{Key: "dedicated", Value: "training", Effect: corev1.TaintEffectNoSchedule}),
+				mockAllocatableGPUNode("sku-node", "1", corev1.Taint{Key: "sku", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}),
+				mockAllocatableGPUNode("preferred-taint-node", "1", corev1.Taint{Key: "dedicated", Value: "training", Effect: corev1.TaintEffectPreferNoSchedule}),
+			},
+			expectedNodes:    []string{"preferred-taint-node", "sku-node"},
+			expectedFreeGPUs: map[string]int64{"sku-node": 1, "preferred-taint-node": 1},
+		},
+		"Node waiting for its GPUs to be registered is kept": {
+			nodes:            []*corev1.Node{mockAllocatableGPUNode("starting-node", "1", machine.GPUStartupTaint)},
+			expectedNodes:    []string{"starting-node"},
+			expectedFreeGPUs: map[string]int64{"starting-node": 1},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			nodeMap := mockClient.CreateMapWithType(&corev1.NodeList{})
+			for _, node := range tc.nodes {
+				nodeMap[client.ObjectKeyFromObject(node)] = node
+			}
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			for _, pod := range tc.pods {
+				podMap[client.ObjectKeyFromObject(pod)] = pod
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			nodes, freeGPUs, err := reconciler.getAllQualifiedNodes(context.Background(), utils.MockWorkspaceWithPreset)
+			assert.NilError(t, err)
+			var names []string
+			for _, node := range nodes {
+				names = append(names, node.Name)
+			}
+			sort.Strings(names)
+			assert.DeepEqual(t, names, tc.expectedNodes)
+			assert.DeepEqual(t, freeGPUs, tc.expectedFreeGPUs)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// nodeGPUCapacityChanged filters the node updates that change the GPU capacity or allocatable of the node,
+// e.g., when the device plugin restarts or a GPU fails, or that report or clear a GPU hardware error.
+func nodeGPUCapacityChanged() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			oldCapacity, newCapacity := oldNode.Status.Capacity[resources.CapacityNvidiaGPU], newNode.Status.Capacity[resources.CapacityNvidiaGPU]
+			oldAllocatable, newAllocatable := oldNode.Status.Allocatable[resources.CapacityNvidiaGPU], newNode.Status.Allocatable[resources.CapacityNvidiaGPU]
+			return !oldCapacity.Equal(newCapacity) || !oldAllocatable.Equal(newAllocatable) ||
+				machine.HasGPUHardwareError(oldNode) != machine.HasGPUHardwareError(newNode)
+		},
+	}
+}
+
+// watchNodes enqueues the workspaces that use the node, i.e., the workspace of the machine that provisioned the node
+// and the workspaces that list the node as a worker node.
+func (c *WorkspaceReconciler) watchNodes() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(c.workspacesOfNode)
+}
+
+func (c *WorkspaceReconciler) workspacesOfNode(ctx context.Context, o client.Object) []reconcile.Request {
+	nodeObj, ok := o.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	name, nameFound := nodeObj.Labels[kaitov1alpha1.LabelWorkspaceName]
+	namespace, namespaceFound := nodeObj.Labels[kaitov1alpha1.LabelWorkspaceNamespace]
+	if nameFound && namespaceFound {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: name, Namespace: namespace}})
+	}
+
+	workspaces := &kaitov1alpha1.WorkspaceList{}
+	if err := c.Client.List(ctx, workspaces); err != nil {
+		klog.ErrorS(err, "failed to list workspaces", "node", klog.KObj(nodeObj))
+		return requests
+	}
+	for i := range workspaces.Items {
+		if lo.Contains(workspaces.Items[i].Status.WorkerNodes, nodeObj.Name) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&workspaces.Items[i])})
+		}
+	}
+	return requests
+}
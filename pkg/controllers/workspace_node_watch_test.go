@@ -0,0 +1,112 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNodeGPUCapacityChanged(t *testing.T) {
+	testcases := map[string]struct {
+		update   func(node *corev1.Node)
+		expected bool
+	}{
+		"GPU capacity changed": {
+			update: func(node *corev1.Node) {
+				node.Status.Capacity[utils.CapacityNvidiaGPU] = resource.MustParse("0")
+			},
+			expected: true,
+		},
+		"GPU allocatable changed": {
+			update: func(node *corev1.Node) {
+				node.Status.Allocatable = corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse("5")}
+			},
+			expected: true,
+		},
+		"GPU hardware error reported": {
+			update: func(node *corev1.Node) {
+				node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+					Type:   machine.NodeConditionGPUHardwareError,
+					Status: corev1.ConditionTrue,
+				})
+			},
+			expected: true,
+		},
+		"Labels changed": {
+			update: func(node *corev1.Node) {
+				node.Labels["team"] = "ml"
+			},
+			expected: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			oldNode := mockGPUNode("node1", "2")
+			newNode := oldNode.DeepCopy()
+			tc.update(newNode)
+
+			changed := nodeGPUCapacityChanged().Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode})
+			assert.Equal(t, changed, tc.expected)
+		})
+	}
+}
+
+func TestWorkspacesOfNode(t *testing.T) {
+	workerNodeWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workerNodeWorkspace.Name = "workerNodeWorkspace"
+	workerNodeWorkspace.Status.WorkerNodes = []string{"node1"}
+	otherWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	otherWorkspace.Name = "otherWorkspace"
+	otherWorkspace.Status.WorkerNodes = []string{"node2"}
+
+	machineNode := mockGPUNode("node1", "2")
+	machineNode.Labels[v1alpha1.LabelWorkspaceName] = "testWorkspace"
+	machineNode.Labels[v1alpha1.LabelWorkspaceNamespace] = "kaito"
+
+	testcases := map[string]struct {
+		node             *corev1.Node
+		expectedRequests []reconcile.Request
+	}{
+		"Node of a machine and a worker node of another workspace": {
+			node: machineNode,
+			expectedRequests: []reconcile.Request{
+				{NamespacedName: client.ObjectKey{Name: "testWorkspace", Namespace: "kaito"}},
+				{NamespacedName: client.ObjectKeyFromObject(workerNodeWorkspace)},
+			},
+		},
+		"Node used by no workspace": {
+			node: mockGPUNode("node3", "2"),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspaceMap := mockClient.CreateMapWithType(&v1alpha1.WorkspaceList{})
+			workspaceMap[client.ObjectKeyFromObject(workerNodeWorkspace)] = workerNodeWorkspace
+			workspaceMap[client.ObjectKeyFromObject(otherWorkspace)] = otherWorkspace
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha1.WorkspaceList{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			requests := reconciler.workspacesOfNode(context.Background(), tc.node)
+			assert.DeepEqual(t, requests, tc.expectedRequests)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// updateNodesLaunchingCondition reports the machines of the workspace that have been launched, but whose nodes have
+// not joined the cluster yet, in the NodesLaunching condition. The condition is only set to False once it has been
+// True, a workspace whose machines have never been launching does not carry it.
+func (c *WorkspaceReconciler) updateNodesLaunchingCondition(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		klog.ErrorS(err, "failed to list the machines of the workspace", "workspace", klog.KObj(wObj))
+		return err
+	}
+	launching := 0
+	for i := range machines.Items {
+		if machine.IsMachineLaunching(&machines.Items[i]) {
+			launching++
+		}
+	}
+
+	if launching > 0 {
+		message := fmt.Sprintf("%d %s launching, waiting to join cluster", launching, lo.Ternary(launching == 1, "node", "nodes"))
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeNodesLaunching, metav1.ConditionTrue,
+			"NodesLaunching", message); err != nil {
+			return err
+		}
+		// The condition is cleared later in the same reconcile once the nodes have joined.
+		meta.SetStatusCondition(&wObj.Status.Conditions, metav1.Condition{Type: string(kaitov1alpha1.WorkspaceConditionTypeNodesLaunching),
+			Status: metav1.ConditionTrue, Reason: "NodesLaunching", Message: message, ObservedGeneration: wObj.Generation})
+		return nil
+	}
+	if meta.IsStatusConditionTrue(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeNodesLaunching)) {
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeNodesLaunching, metav1.ConditionFalse,
+			"NodesJoined", "the nodes of all launched machines have joined the cluster")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func mockMachineWithConditions(name string, launched, ready corev1.ConditionStatus) *v1alpha5.Machine {
+	m := mockAutoscalingMachine(name, "")
+	m.Status.Conditions = apis.Conditions{
+		{Type: v1alpha5.MachineLaunched, Status: launched},
+		{Type: apis.ConditionReady, Status: ready},
+	}
+	return m
+}
+
+func TestUpdateNodesLaunchingCondition(t *testing.T) {
+	testcases := map[string]struct {
+		machines        []*v1alpha5.Machine
+		conditions      []metav1.Condition
+		expectedStatus  metav1.ConditionStatus
+		expectedMessage string
+		expectNoUpdate  bool
+	}{
+		"Launched machines whose nodes have not joined are launching": {
+			machines: []*v1alpha5.Machine{
+				mockMachineWithConditions("machine-1", corev1.ConditionTrue, corev1.ConditionFalse),
+				mockMachineWithConditions("machine-2", corev1.ConditionTrue, corev1.ConditionFalse),
+				mockMachineWithConditions("machine-3", corev1.ConditionTrue, corev1.ConditionUnknown),
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "3 nodes launching, waiting to join cluster",
+		},
+		"Machines that are not launched or are ready are not launching": {
+			machines: []*v1alpha5.Machine{
+				mockMachineWithConditions("machine-1", corev1.ConditionFalse, corev1.ConditionFalse),
+				mockMachineWithConditions("machine-2", corev1.ConditionTrue, corev1.ConditionTrue),
+				mockMachineWithConditions("machine-3", corev1.ConditionTrue, corev1.ConditionFalse),
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "1 node launching, waiting to join cluster",
+		},
+		"Joined nodes clear the condition": {
+			machines: []*v1alpha5.Machine{
+				mockMachineWithConditions("machine-1", corev1.ConditionTrue, corev1.ConditionTrue),
+			},
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeNodesLaunching), Status: metav1.ConditionTrue, Reason: "NodesLaunching"},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "the nodes of all launched machines have joined the cluster",
+		},
+		"Workspace without launching machines is not reported": {
+			machines: []*v1alpha5.Machine{
+				mockMachineWithConditions("machine-1", corev1.ConditionTrue, corev1.ConditionTrue),
+			},
+			expectNoUpdate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Status.Conditions = tc.conditions
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			for _, m := range tc.machines {
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			assert.NilError(t, reconciler.updateNodesLaunchingCondition(context.Background(), workspace))
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeNodesLaunching))
+			assert.Check(t, condition != nil, "expected the NodesLaunching condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			assert.Equal(t, condition.Message, tc.expectedMessage)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deleteOrphanedObjects deletes the Deployments, StatefulSets, Services and NetworkPolicies that kaito created for the
+// workspace but that its current spec no longer renders, e.g., the Deployment and the Service of a renamed variant.
+// The objects of the workspace carry its name label and are controlled by it, the objects created by users are left
+// untouched.
+func (c *WorkspaceReconciler) deleteOrphanedObjects(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if wObj.Inference == nil {
+		return nil
+	}
+	desiredObjs, err := RenderWorkspaceManifests(wObj)
+	if err != nil {
+		return err
+	}
+	desired := sets.New[string]()
+	for _, obj := range desiredObjs {
+		desired.Insert(objectKindName(obj))
+	}
+	// The canary Deployment of a canary rollout is not rendered, it is deleted once the stable pods are rolled out.
+	if wObj.Inference.RolloutStrategy == kaitov1alpha1.RolloutStrategyCanary {
+		desired.Insert(objectKindName(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: inference.CanaryName(wObj)}}))
+	}
+
+	for _, list := range []client.ObjectList{&appsv1.DeploymentList{}, &appsv1.StatefulSetList{}, &corev1.ServiceList{}, &networkingv1.NetworkPolicyList{}} {
+		if err := c.Client.List(ctx, list, client.InNamespace(wObj.Namespace),
+			client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: wObj.Name}); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok || !metav1.IsControlledBy(obj, wObj) || desired.Has(objectKindName(obj)) {
+				continue
+			}
+			klog.InfoS("Deleting an object that the workspace no longer renders", "workspace", klog.KObj(wObj),
+				"kind", fmt.Sprintf("%T", obj), "object", klog.KObj(obj))
+			if err := c.Client.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// objectKindName identifies an object of the workspace by its type and name.
+func objectKindName(obj client.Object) string {
+	return fmt.Sprintf("%T/%s", obj, obj.GetName())
+}
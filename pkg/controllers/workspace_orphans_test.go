@@ -0,0 +1,147 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDeleteOrphanedObjects(t *testing.T) {
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.UID = "workspace-uid"
+	workspace.Inference.Preset = nil
+	workspace.Inference.Variants = []v1alpha1.PresetVariant{
+		{Name: "stable", Preset: v1alpha1.PresetSpec{PresetMeta: v1alpha1.PresetMeta{Name: "test-model"}}, Weight: 50},
+		{Name: "canary", Preset: v1alpha1.PresetSpec{PresetMeta: v1alpha1.PresetMeta{Name: "test-model"}}, Weight: 50},
+	}
+
+	managedMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       workspace.Namespace,
+			Labels:          map[string]string{v1alpha1.LabelWorkspaceName: workspace.Name},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Workspace", Name: workspace.Name, UID: workspace.UID, Controller: lo.ToPtr(true)}},
+		}
+	}
+	// The user created Deployment carries the workspace label, but is not controlled by the workspace.
+	userMeta := metav1.ObjectMeta{
+		Name:      "testWorkspace-dashboard",
+		Namespace: workspace.Namespace,
+		Labels:    map[string]string{v1alpha1.LabelWorkspaceName: workspace.Name},
+	}
+
+	mockClient := utils.NewClient()
+	deploymentMap := mockClient.CreateMapWithType(&appsv1.DeploymentList{})
+	for _, depObj := range []*appsv1.Deployment{
+		{ObjectMeta: managedMeta("testWorkspace-stable")},
+		{ObjectMeta: managedMeta("testWorkspace-canary")},
+		{ObjectMeta: managedMeta("testWorkspace-preview")},
+		{ObjectMeta: userMeta},
+	} {
+		deploymentMap[client.ObjectKeyFromObject(depObj)] = depObj
+	}
+	serviceMap := mockClient.CreateMapWithType(&corev1.ServiceList{})
+	for _, serviceObj := range []*corev1.Service{
+		{ObjectMeta: managedMeta("testWorkspace")},
+		{ObjectMeta: managedMeta("testWorkspace-stable")},
+		{ObjectMeta: managedMeta("testWorkspace-canary")},
+		{ObjectMeta: managedMeta("testWorkspace-preview")},
+	} {
+		serviceMap[client.ObjectKeyFromObject(serviceObj)] = serviceObj
+	}
+	// The egress NetworkPolicy is no longer rendered once the egress policy is removed from the workspace.
+	networkPolicyObj := &networkingv1.NetworkPolicy{ObjectMeta: managedMeta("testWorkspace-egress")}
+	mockClient.CreateMapWithType(&networkingv1.NetworkPolicyList{})[client.ObjectKeyFromObject(networkPolicyObj)] = networkPolicyObj
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&appsv1.DeploymentList{}), mock.Anything).Return(nil)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&appsv1.StatefulSetList{}), mock.Anything).Return(nil)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.ServiceList{}), mock.Anything).Return(nil)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&networkingv1.NetworkPolicyList{}), mock.Anything).Return(nil)
+	mockClient.On("Delete", mock.IsType(context.Background()), mock.Anything, mock.Anything).Return(nil)
+
+	reconciler := &WorkspaceReconciler{
+		Client: mockClient,
+		Scheme: utils.NewTestScheme(),
+	}
+	err := reconciler.deleteOrphanedObjects(context.Background(), workspace)
+	assert.Check(t, err == nil, "Not expected to return error")
+
+	var deleted []string
+	for _, call := range mockClient.Calls {
+		if call.Method == "Delete" {
+			deleted = append(deleted, objectKindName(call.Arguments.Get(1).(client.Object)))
+		}
+	}
+	sort.Strings(deleted)
+	assert.DeepEqual(t, deleted, []string{"*v1.Deployment/testWorkspace-preview", "*v1.NetworkPolicy/testWorkspace-egress", "*v1.Service/testWorkspace-preview"})
+}
+
+func TestDeleteOrphanedObjectsDuringCanaryRollout(t *testing.T) {
+	utils.RegisterTestModel()
+	t.Setenv("PRESET_REGISTRY_NAME", "registry.example.com")
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.UID = "workspace-uid"
+	workspace.Inference.RolloutStrategy = v1alpha1.RolloutStrategyCanary
+	ownerRefs := []metav1.OwnerReference{{Kind: "Workspace", Name: workspace.Name, UID: workspace.UID, Controller: lo.ToPtr(true)}}
+
+	mockClient := utils.NewClient()
+	desiredObj, err := inference.GenerateInferenceManifest(context.Background(), workspace, cloudprovider.Default, mockClient)
+	assert.Check(t, err == nil, "Not expected to return error")
+	// The canary of the new image took the GPUs of one of the two stable pods, and is ready.
+	canary := desiredObj.(*appsv1.Deployment).DeepCopy()
+	canary.Name = inference.CanaryName(workspace)
+	canary.Labels = map[string]string{v1alpha1.LabelWorkspaceName: workspace.Name}
+	canary.OwnerReferences = ownerRefs
+	canary.Annotations = map[string]string{v1alpha1.AnnotationStableReplicas: "2"}
+	canary.Spec.Replicas = lo.ToPtr(int32(1))
+	canary.Status.ReadyReplicas = 1
+	stable := desiredObj.(*appsv1.Deployment).DeepCopy()
+	stable.Labels = map[string]string{v1alpha1.LabelWorkspaceName: workspace.Name}
+	stable.OwnerReferences = ownerRefs
+	stable.Spec.Replicas = lo.ToPtr(int32(1))
+	stable.Spec.Template.Spec.Containers[0].Image = "registry.example.com/kaito-test-model:0.0.1"
+	mockClient.CreateOrUpdateObjectInMap(canary)
+	mockClient.CreateOrUpdateObjectInMap(stable)
+	deploymentMap := mockClient.CreateMapWithType(&appsv1.DeploymentList{})
+	for _, depObj := range []*appsv1.Deployment{stable, canary} {
+		deploymentMap[client.ObjectKeyFromObject(depObj)] = depObj
+	}
+	mockClient.On("List", mock.IsType(context.Background()), mock.Anything, mock.Anything).Return(nil)
+	// The readiness of the canary is polled with a timeout context.
+	mockClient.On("Get", mock.Anything, mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+	mockClient.On("Patch", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("Delete", mock.IsType(context.Background()), mock.Anything, mock.Anything).Return(nil)
+
+	reconciler := &WorkspaceReconciler{
+		Client: mockClient,
+		Scheme: utils.NewTestScheme(),
+	}
+	err = reconciler.deleteOrphanedObjects(context.Background(), workspace)
+	assert.Check(t, err == nil, "Not expected to return error")
+	mockClient.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+
+	obj, err := inference.ReconcileInferenceWorkload(context.Background(), workspace, cloudprovider.Default, mockClient)
+	assert.Check(t, err == nil, "Not expected to return error")
+
+	// The stable replicas are restored from the canary, which is deleted once the stable pods are rolled out.
+	assert.Equal(t, lo.FromPtr(obj.(*appsv1.Deployment).Spec.Replicas), int32(2))
+	mockClient.AssertNumberOfCalls(t, "Patch", 1)
+	mockClient.AssertNumberOfCalls(t, "Delete", 1)
+	deleteCall, _ := lo.Find(mockClient.Calls, func(call mock.Call) bool { return call.Method == "Delete" })
+	assert.Equal(t, deleteCall.Arguments.Get(1).(client.Object).GetName(), canary.Name)
+}
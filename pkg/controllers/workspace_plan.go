@@ -0,0 +1,181 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReconcilePlan describes the changes needed to bring a workspace to its desired state.
+// Building a plan does not change anything in the cluster.
+type ReconcilePlan struct {
+	// NodeCount is the number of nodes the workspace needs, it differs from the count if autoscaling is enabled.
+	NodeCount int
+	// SelectedNodes are the existing nodes that keep serving the workspace.
+	SelectedNodes []*corev1.Node
+	// MachinesToCreate is the number of new machines to provision.
+	MachinesToCreate int
+	// MachinesToReplace are the drifted or expired machines of the workspace. Each one is deleted once a new machine is ready.
+	MachinesToReplace []*v1alpha5.Machine
+	// MachinesToDelete are the machines of the workspace that are no longer needed.
+	MachinesToDelete []*v1alpha5.Machine
+	// CreateWorkload is true if the tuning or inference workload of the workspace does not exist yet.
+	CreateWorkload bool
+}
+
+// IsNoop returns true if the workspace already matches its desired state.
+func (p *ReconcilePlan) IsNoop() bool {
+	return p.MachinesToCreate == 0 && len(p.MachinesToReplace) == 0 && len(p.MachinesToDelete) == 0 && !p.CreateWorkload
+}
+
+func (p *ReconcilePlan) String() string {
+	machineNames := func(machines []*v1alpha5.Machine) []string {
+		return lo.Map(machines, func(m *v1alpha5.Machine, _ int) string { return m.Name })
+	}
+	return fmt.Sprintf("create %d machines, replace machines %v, delete machines %v, create workload: %t",
+		p.MachinesToCreate, machineNames(p.MachinesToReplace), machineNames(p.MachinesToDelete), p.CreateWorkload)
+}
+
+// BuildReconcilePlan compares the desired state of the workspace with the machines, nodes and workloads
+// in the cluster and returns the plan to reconcile them, without executing it.
+func (c *WorkspaceReconciler) BuildReconcilePlan(ctx context.Context, wObj *kaitov1alpha1.Workspace) (*ReconcilePlan, error) {
+	plan := &ReconcilePlan{}
+
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes of drifted or expired machines are not reused, the machines are replaced instead. Their pods have been
+	// pre-drained, so they are rescheduled on the new nodes before the machines are deleted.
+	var drifted []*v1alpha5.Machine
+	machinesByNode := map[string]*v1alpha5.Machine{}
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if m.DeletionTimestamp != nil || m.Status.NodeName == "" {
+			continue
+		}
+		if machine.NeedsReplacement(m) {
+			drifted = append(drifted, m)
+			continue
+		}
+		machinesByNode[m.Status.NodeName] = m
+	}
+
+	qualifiedNodes, freeGPUs, err := c.getAllQualifiedNodes(ctx, wObj)
+	if err != nil {
+		return nil, err
+	}
+	candidates := lo.Filter(qualifiedNodes, func(node *corev1.Node, _ int) bool {
+		return !lo.ContainsBy(drifted, func(m *v1alpha5.Machine) bool { return m.Status.NodeName == node.Name })
+	})
+
+	count, busyNodes, err := c.desiredNodeCount(ctx, wObj)
+	if err != nil {
+		return nil, err
+	}
+	plan.NodeCount = count
+	// Cordoned nodes, e.g., the nodes drained because the count was lowered, no longer keep serving the workspace first.
+	previous := lo.Reject(busyNodes, func(nodeName string, _ int) bool {
+		return lo.ContainsBy(candidates, func(node *corev1.Node) bool { return node.Name == nodeName && node.Spec.Unschedulable })
+	})
+	plan.SelectedNodes = selectWorkspaceNodes(candidates, wObj.Resource.PreferredNodes, previous, freeGPUs, count)
+
+	missing := count - len(plan.SelectedNodes)
+	if missing < 0 {
+		missing = 0
+	}
+	// Only replace as many drifted or expired machines as needed, the remaining ones are deleted.
+	replaceCount := missing
+	if replaceCount > len(drifted) {
+		replaceCount = len(drifted)
+	}
+	plan.MachinesToReplace = drifted[:replaceCount]
+	plan.MachinesToDelete = append(plan.MachinesToDelete, drifted[replaceCount:]...)
+	plan.MachinesToCreate = missing - replaceCount
+
+	// Machines whose nodes are ready but not selected exceed the requested count.
+	for _, node := range candidates {
+		if m, found := machinesByNode[node.Name]; found && !lo.Contains(plan.SelectedNodes, node) {
+			plan.MachinesToDelete = append(plan.MachinesToDelete, m)
+		}
+	}
+
+	workloadExists, err := c.workloadExists(ctx, wObj)
+	if err != nil {
+		return nil, err
+	}
+	plan.CreateWorkload = !workloadExists
+
+	return plan, nil
+}
+
+// isPlanOnly returns true if the workspace asks for its reconcile plan without executing it, see AnnotationPlanOnly.
+func isPlanOnly(wObj *kaitov1alpha1.Workspace) bool {
+	return wObj.Annotations[kaitov1alpha1.AnnotationPlanOnly] == "true"
+}
+
+// reportReconcilePlan builds the reconcile plan of the workspace and reports it in the ReconcilePlan condition. Unlike
+// a regular reconcile, it neither creates nor deletes machines or workloads.
+func (c *WorkspaceReconciler) reportReconcilePlan(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	plan, err := c.BuildReconcilePlan(ctx, wObj)
+	if err != nil {
+		return err
+	}
+	klog.InfoS("reconcile plan of a plan-only workspace", "workspace", klog.KObj(wObj), "plan", plan.String())
+	if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeReconcilePlan, metav1.ConditionTrue,
+		"reconcilePlanBuilt", plan.String()); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return err
+	}
+	return nil
+}
+
+// workloadExists checks if the tuning or inference workload of the workspace has been created.
+func (c *WorkspaceReconciler) workloadExists(ctx context.Context, wObj *kaitov1alpha1.Workspace) (bool, error) {
+	var existingObj client.Object
+	name := wObj.Name
+	switch {
+	case wObj.Tuning != nil:
+		existingObj = &batchv1.Job{}
+	case wObj.Inference != nil && len(wObj.Inference.Variants) != 0:
+		// The Deployments of the variants are created together.
+		existingObj = &appsv1.Deployment{}
+		name = inference.VariantName(wObj, &wObj.Inference.Variants[0])
+	case wObj.Inference != nil && wObj.Inference.Preset != nil:
+		model := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name))
+		if model.SupportDistributedInference() {
+			existingObj = &appsv1.StatefulSet{}
+		} else {
+			existingObj = &appsv1.Deployment{}
+		}
+	case wObj.Inference != nil && wObj.Inference.Template != nil:
+		existingObj = &appsv1.Deployment{}
+	default:
+		return true, nil
+	}
+
+	if err := resources.GetResource(ctx, name, wObj.Namespace, c.Client, existingObj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,179 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBuildReconcilePlan(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		callMocks         func(c *utils.MockClient)
+		expectedCreate    int
+		expectedReplace   []string
+		expectedDelete    []string
+		expectedWorkload  bool
+		expectedNoopPlan  bool
+		expectedNodeNames []string
+	}{
+		"Fresh workspace creates machines and workload": {
+			callMocks: func(c *utils.MockClient) {
+				c.CreateMapWithType(&v1alpha5.MachineList{})
+				c.CreateMapWithType(&corev1.NodeList{})
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+			},
+			expectedCreate:   1,
+			expectedWorkload: true,
+		},
+		"Satisfied workspace is a no-op": {
+			callMocks: func(c *utils.MockClient) {
+				c.CreateMapWithType(&v1alpha5.MachineList{})
+				nodeMap := c.CreateMapWithType(utils.MockNodeList)
+				for _, obj := range utils.MockNodeList.Items {
+					n := obj
+					nodeMap[client.ObjectKeyFromObject(&n)] = &n
+				}
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			},
+			expectedNoopPlan:  true,
+			expectedNodeNames: []string{"node1"},
+		},
+		"Drifted workspace replaces the drifted machine": {
+			callMocks: func(c *utils.MockClient) {
+				driftedMachine := utils.MockMachine.DeepCopy()
+				driftedMachine.Status.NodeName = "node1"
+				driftedMachine.Status.Conditions = apis.Conditions{
+					{
+						Type:   v1alpha5.MachineDrifted,
+						Status: corev1.ConditionTrue,
+					},
+				}
+				machineMap := c.CreateMapWithType(utils.MockMachineList)
+				machineMap[client.ObjectKeyFromObject(driftedMachine)] = driftedMachine
+
+				nodeMap := c.CreateMapWithType(utils.MockNodeList)
+				for _, obj := range utils.MockNodeList.Items {
+					n := obj
+					nodeMap[client.ObjectKeyFromObject(&n)] = &n
+				}
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			},
+			expectedReplace: []string{utils.MockMachine.Name},
+		},
+		"Expiring machine is replaced before it is removed": {
+			callMocks: func(c *utils.MockClient) {
+				expiredMachine := utils.MockMachine.DeepCopy()
+				expiredMachine.Status.NodeName = "node1"
+				expiredMachine.Status.Conditions = apis.Conditions{
+					{
+						Type:   v1alpha5.MachineExpired,
+						Status: corev1.ConditionTrue,
+					},
+				}
+				machineMap := c.CreateMapWithType(utils.MockMachineList)
+				machineMap[client.ObjectKeyFromObject(expiredMachine)] = expiredMachine
+
+				nodeMap := c.CreateMapWithType(utils.MockNodeList)
+				for _, obj := range utils.MockNodeList.Items {
+					n := obj
+					nodeMap[client.ObjectKeyFromObject(&n)] = &n
+				}
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			},
+			expectedReplace: []string{utils.MockMachine.Name},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			tc.callMocks(mockClient)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			plan, err := reconciler.BuildReconcilePlan(context.Background(), utils.MockWorkspaceWithPreset.DeepCopy())
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			assert.Equal(t, plan.IsNoop(), tc.expectedNoopPlan)
+			assert.Equal(t, plan.MachinesToCreate, tc.expectedCreate)
+			assert.Equal(t, plan.CreateWorkload, tc.expectedWorkload)
+			assert.Equal(t, len(plan.MachinesToReplace), len(tc.expectedReplace))
+			for i := range tc.expectedReplace {
+				assert.Equal(t, plan.MachinesToReplace[i].Name, tc.expectedReplace[i])
+			}
+			assert.Equal(t, len(plan.MachinesToDelete), len(tc.expectedDelete))
+			assert.Equal(t, len(plan.SelectedNodes), len(tc.expectedNodeNames))
+			for i := range tc.expectedNodeNames {
+				assert.Equal(t, plan.SelectedNodes[i].Name, tc.expectedNodeNames[i])
+			}
+		})
+	}
+}
+
+func TestPlanOnlyWorkspace(t *testing.T) {
+	utils.RegisterTestModel()
+	mockClient := utils.NewClient()
+	driftedMachine := utils.MockMachine.DeepCopy()
+	driftedMachine.Status.NodeName = "node1"
+	driftedMachine.Status.Conditions = apis.Conditions{
+		{
+			Type:   v1alpha5.MachineDrifted,
+			Status: corev1.ConditionTrue,
+		},
+	}
+	machineMap := mockClient.CreateMapWithType(utils.MockMachineList)
+	machineMap[client.ObjectKeyFromObject(driftedMachine)] = driftedMachine
+	nodeMap := mockClient.CreateMapWithType(utils.MockNodeList)
+	for _, obj := range utils.MockNodeList.Items {
+		n := obj
+		nodeMap[client.ObjectKeyFromObject(&n)] = &n
+	}
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Annotations = map[string]string{v1alpha1.AnnotationPlanOnly: "true"}
+	mockClient.CreateOrUpdateObjectInMap(workspace)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+	mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+	reconciler := &WorkspaceReconciler{
+		Client: mockClient,
+		Scheme: utils.NewTestScheme(),
+	}
+
+	_, err := reconciler.addOrUpdateWorkspace(context.Background(), workspace)
+	assert.NilError(t, err)
+	mockClient.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	mockClient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mockClient.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+	condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeReconcilePlan))
+	assert.Check(t, condition != nil, "expected the ReconcilePlan condition to be set")
+	assert.Check(t, strings.Contains(condition.Message, "replace machines ["+utils.MockMachine.Name+"]"), condition.Message)
+}
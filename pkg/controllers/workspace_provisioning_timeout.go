@@ -0,0 +1,169 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultProvisioningTimeout is how long the nodes of a workspace may take to be provisioned if neither the workspace
+// nor the controller configure it.
+const DefaultProvisioningTimeout = 2 * time.Hour
+
+// provisioningTimeout returns how long the nodes of the workspace may take to be provisioned.
+func (c *WorkspaceReconciler) provisioningTimeout(wObj *kaitov1alpha1.Workspace) time.Duration {
+	if timeout := wObj.Resource.ProvisioningTimeout; timeout != nil && timeout.Duration > 0 {
+		return timeout.Duration
+	}
+	if c.ProvisioningTimeout > 0 {
+		return c.ProvisioningTimeout
+	}
+	return DefaultProvisioningTimeout
+}
+
+// provisioningTimedOut reports whether the provisioning of the current generation of the workspace has timed out.
+func provisioningTimedOut(wObj *kaitov1alpha1.Workspace) bool {
+	condition := meta.FindStatusCondition(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeProvisioningTimeout))
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.ObservedGeneration == wObj.Generation
+}
+
+// checkProvisioningTimeout reports whether the provisioning of the workspace must stop. Once the nodes have not been
+// provisioned within the timeout, the ProvisioningTimeout condition reports the stuck instance types and the reasons
+// they were last seen failing with, and the workspace is not provisioned again until its spec is changed.
+func (c *WorkspaceReconciler) checkProvisioningTimeout(ctx context.Context, wObj *kaitov1alpha1.Workspace, now time.Time) (bool, error) {
+	if provisioningTimedOut(wObj) {
+		return true, nil
+	}
+	// The timeout of an earlier generation no longer applies, the changed spec is provisioned.
+	if meta.IsStatusConditionTrue(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeProvisioningTimeout)) {
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeProvisioningTimeout, metav1.ConditionFalse,
+			"workspaceChanged", "the workspace was changed, the provisioning is retried"); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return false, err
+		}
+	}
+
+	attempt := wObj.Status.ProvisioningAttempt
+	if attempt == nil || attempt.ObservedGeneration != wObj.Generation {
+		return false, nil
+	}
+	timeout := c.provisioningTimeout(wObj)
+	if now.Sub(attempt.StartTime.Time) < timeout {
+		return false, nil
+	}
+
+	instanceTypes, reasons, err := c.stuckProvisioning(ctx, wObj)
+	if err != nil {
+		return false, err
+	}
+	message := fmt.Sprintf("the nodes were not provisioned within %s, stuck instance types: %s, last seen reasons: %s. "+
+		"The provisioning is retried once the workspace is changed", timeout, strings.Join(instanceTypes, ", "),
+		lo.Ternary(len(reasons) == 0, "unknown", strings.Join(reasons, "; ")))
+	klog.InfoS("provisioning timed out", "workspace", klog.KObj(wObj), "startTime", attempt.StartTime, "timeout", timeout)
+	c.recordEvent(wObj, corev1.EventTypeWarning, "ProvisioningTimeout", message)
+	c.notifyProvisioningFailure(ctx, wObj, errors.New(message))
+	if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeProvisioningTimeout, metav1.ConditionTrue,
+		"provisioningTimedOut", message); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return false, err
+	}
+	return true, nil
+}
+
+// stuckProvisioning returns the instance types of the machines of the workspace that are not ready, along with the
+// reasons of their failing conditions and of the failing conditions of the workspace. The instance types of the
+// workspace are stuck if it has no machine that is not ready, e.g., the machines could not be created.
+func (c *WorkspaceReconciler) stuckProvisioning(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]string, []string, error) {
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		klog.ErrorS(err, "failed to list the machines of the workspace", "workspace", klog.KObj(wObj))
+		return nil, nil, err
+	}
+	var instanceTypes, reasons []string
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if lo.ContainsBy(m.GetConditions(), func(condition apis.Condition) bool {
+			return condition.Type == apis.ConditionReady && condition.Status == corev1.ConditionTrue
+		}) {
+			continue
+		}
+		if instanceType := machine.MachineInstanceType(m, c.cloudProvider()); instanceType != "" {
+			instanceTypes = append(instanceTypes, instanceType)
+		}
+		for _, condition := range m.GetConditions() {
+			if condition.Status == corev1.ConditionFalse && condition.Message != "" {
+				reasons = append(reasons, fmt.Sprintf("machine %s %s: %s", m.Name, condition.Type, condition.Message))
+			}
+		}
+	}
+	if len(instanceTypes) == 0 {
+		instanceTypes = machine.CandidateInstanceTypes(wObj)
+	}
+	for _, cType := range []kaitov1alpha1.ConditionType{kaitov1alpha1.WorkspaceConditionTypeMachineStatus, kaitov1alpha1.WorkspaceConditionTypeResourceStatus} {
+		if condition := meta.FindStatusCondition(wObj.Status.Conditions, string(cType)); condition != nil &&
+			condition.Status != metav1.ConditionTrue && condition.Message != "" {
+			reasons = append(reasons, fmt.Sprintf("%s %s: %s", cType, condition.Reason, condition.Message))
+		}
+	}
+	instanceTypes = lo.Uniq(instanceTypes)
+	sort.Strings(instanceTypes)
+	return instanceTypes, lo.Uniq(reasons), nil
+}
+
+// startProvisioningAttempt records when the provisioning of the nodes of the current generation of the workspace
+// started, unless it has already started.
+func (c *WorkspaceReconciler) startProvisioningAttempt(ctx context.Context, wObj *kaitov1alpha1.Workspace, now time.Time) error {
+	if attempt := wObj.Status.ProvisioningAttempt; attempt != nil && attempt.ObservedGeneration == wObj.Generation {
+		return nil
+	}
+	attempt := &kaitov1alpha1.ProvisioningAttempt{ObservedGeneration: wObj.Generation, StartTime: metav1.NewTime(now)}
+	if err := c.updateStatusProvisioningAttempt(ctx, wObj, attempt); err != nil {
+		return err
+	}
+	wObj.Status.ProvisioningAttempt = attempt
+	return nil
+}
+
+// resetProvisioningAttempt clears the provisioning attempt of the workspace once its nodes are ready.
+func (c *WorkspaceReconciler) resetProvisioningAttempt(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if wObj.Status.ProvisioningAttempt == nil {
+		return nil
+	}
+	if err := c.updateStatusProvisioningAttempt(ctx, wObj, nil); err != nil {
+		return err
+	}
+	wObj.Status.ProvisioningAttempt = nil
+	return nil
+}
+
+func (c *WorkspaceReconciler) updateStatusProvisioningAttempt(ctx context.Context, wObj *kaitov1alpha1.Workspace, attempt *kaitov1alpha1.ProvisioningAttempt) error {
+	klog.InfoS("updateStatusProvisioningAttempt", "workspace", klog.KObj(wObj), "attempt", attempt)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &kaitov1alpha1.Workspace{}
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(wObj), latest); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		latest.Status.ProvisioningAttempt = attempt
+		return c.Client.Status().Update(ctx, latest)
+	})
+}
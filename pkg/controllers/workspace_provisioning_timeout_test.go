@@ -0,0 +1,165 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCheckProvisioningTimeout(t *testing.T) {
+	now := time.Now()
+	stuckMachine := utils.MockMachine.DeepCopy()
+	stuckMachine.Namespace = utils.MockWorkspaceWithPreset.Namespace
+	stuckMachine.Status.Conditions = apis.Conditions{
+		{Type: v1alpha5.MachineLaunched, Status: corev1.ConditionFalse, Reason: "LaunchFailed", Message: "insufficient capacity for Standard_NC12s_v3"},
+	}
+
+	testcases := map[string]struct {
+		generation          int64
+		attempt             *v1alpha1.ProvisioningAttempt
+		provisioningTimeout *metav1.Duration
+		conditions          []metav1.Condition
+		expectStop          bool
+		expectedStatus      metav1.ConditionStatus
+		expectedContent     []string
+		expectNoUpdate      bool
+	}{
+		"Provisioning within the timeout continues": {
+			generation:     1,
+			attempt:        &v1alpha1.ProvisioningAttempt{ObservedGeneration: 1, StartTime: metav1.NewTime(now.Add(-10 * time.Minute))},
+			expectNoUpdate: true,
+		},
+		"Provisioning beyond the timeout stops with the stuck instance types": {
+			generation:      1,
+			attempt:         &v1alpha1.ProvisioningAttempt{ObservedGeneration: 1, StartTime: metav1.NewTime(now.Add(-3 * time.Hour))},
+			expectStop:      true,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedContent: []string{"within 2h0m0s", "stuck instance types: Standard_NC12s_v3", "insufficient capacity for Standard_NC12s_v3"},
+		},
+		"Timeout of the workspace overrides the timeout of the controller": {
+			generation:          1,
+			attempt:             &v1alpha1.ProvisioningAttempt{ObservedGeneration: 1, StartTime: metav1.NewTime(now.Add(-20 * time.Minute))},
+			provisioningTimeout: &metav1.Duration{Duration: 15 * time.Minute},
+			expectStop:          true,
+			expectedStatus:      metav1.ConditionTrue,
+			expectedContent:     []string{"within 15m0s"},
+		},
+		"Timed out workspace is not provisioned again": {
+			generation: 1,
+			attempt:    &v1alpha1.ProvisioningAttempt{ObservedGeneration: 1, StartTime: metav1.NewTime(now.Add(-3 * time.Hour))},
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeProvisioningTimeout), Status: metav1.ConditionTrue, Reason: "provisioningTimedOut", ObservedGeneration: 1},
+			},
+			expectStop:     true,
+			expectNoUpdate: true,
+		},
+		"Changed workspace is provisioned again": {
+			generation: 2,
+			attempt:    &v1alpha1.ProvisioningAttempt{ObservedGeneration: 1, StartTime: metav1.NewTime(now.Add(-3 * time.Hour))},
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeProvisioningTimeout), Status: metav1.ConditionTrue, Reason: "provisioningTimedOut", ObservedGeneration: 1},
+			},
+			expectedStatus: metav1.ConditionFalse,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Generation = tc.generation
+			workspace.Resource.ProvisioningTimeout = tc.provisioningTimeout
+			workspace.Status.Conditions = tc.conditions
+			workspace.Status.ProvisioningAttempt = tc.attempt
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.CreateMapWithType(&v1alpha5.MachineList{})[client.ObjectKeyFromObject(stuckMachine)] = stuckMachine
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			stop, err := reconciler.checkProvisioningTimeout(context.Background(), workspace, now)
+			assert.NilError(t, err)
+			assert.Equal(t, stop, tc.expectStop)
+
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeProvisioningTimeout))
+			assert.Check(t, condition != nil, "expected the provisioning timeout condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			for _, content := range tc.expectedContent {
+				assert.Check(t, strings.Contains(condition.Message, content), "expected %q in the message %q", content, condition.Message)
+			}
+		})
+	}
+}
+
+func TestStartProvisioningAttempt(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	testcases := map[string]struct {
+		attempt           *v1alpha1.ProvisioningAttempt
+		expectedStartTime time.Time
+		expectNoUpdate    bool
+	}{
+		"Provisioning is started": {
+			expectedStartTime: start.Add(time.Hour),
+		},
+		"Provisioning of the same generation keeps its start time": {
+			attempt:        &v1alpha1.ProvisioningAttempt{ObservedGeneration: 1, StartTime: metav1.NewTime(start)},
+			expectNoUpdate: true,
+		},
+		"Provisioning of a changed workspace starts again": {
+			attempt:           &v1alpha1.ProvisioningAttempt{ObservedGeneration: 0, StartTime: metav1.NewTime(start)},
+			expectedStartTime: start.Add(time.Hour),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Generation = 1
+			workspace.Status.ProvisioningAttempt = tc.attempt
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			assert.NilError(t, reconciler.startProvisioningAttempt(context.Background(), workspace, start.Add(time.Hour)))
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			assert.Equal(t, updated.Status.ProvisioningAttempt.ObservedGeneration, int64(1))
+			assert.Check(t, updated.Status.ProvisioningAttempt.StartTime.Time.Equal(tc.expectedStartTime))
+		})
+	}
+}
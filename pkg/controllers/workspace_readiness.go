@@ -0,0 +1,139 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadinessPath is the path prefix of the endpoint that reports whether a workspace is fully ready, i.e.,
+// /readyz/workspaces/<namespace>/<name>, e.g., for a deployment pipeline to gate on.
+const ReadinessPath = "/readyz/workspaces/"
+
+// WorkspaceReadiness is the JSON body of the readiness endpoint.
+type WorkspaceReadiness struct {
+	Workspace         string `json:"workspace"`
+	Ready             bool   `json:"ready"`
+	Machines          int    `json:"machines"`
+	ReadyMachines     int    `json:"readyMachines"`
+	DesiredReplicas   int32  `json:"desiredReplicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+	Message           string `json:"message,omitempty"`
+}
+
+// ReadinessHandler returns the handler of the readiness endpoint. It responds 200 if all machines of the workspace
+// are Ready and all inference replicas are available, and 503 otherwise, along with the readiness of the workspace.
+func (c *WorkspaceReconciler) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(c.serveReadiness)
+}
+
+func (c *WorkspaceReconciler) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	namespace, name, found := strings.Cut(strings.TrimPrefix(r.URL.Path, ReadinessPath), "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		http.Error(w, fmt.Sprintf("expected the path %s<namespace>/<name>", ReadinessPath), http.StatusBadRequest)
+		return
+	}
+
+	wObj := &kaitov1alpha1.Workspace{}
+	if err := c.Client.Get(r.Context(), client.ObjectKey{Namespace: namespace, Name: name}, wObj); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("workspace %s/%s not found", namespace, name), http.StatusNotFound)
+			return
+		}
+		klog.ErrorS(err, "failed to get the workspace", "workspace", klog.KRef(namespace, name))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	readiness, err := c.workspaceReadiness(r.Context(), wObj)
+	if err != nil {
+		klog.ErrorS(err, "failed to check the readiness of the workspace", "workspace", klog.KObj(wObj))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if readiness.Ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(readiness); err != nil {
+		klog.ErrorS(err, "failed to write the readiness of the workspace", "workspace", klog.KObj(wObj))
+	}
+}
+
+// workspaceReadiness checks that the machines of the workspace are Ready and its inference replicas are available.
+// A tuning workspace is ready once its job has completed.
+func (c *WorkspaceReconciler) workspaceReadiness(ctx context.Context, wObj *kaitov1alpha1.Workspace) (*WorkspaceReadiness, error) {
+	readiness := &WorkspaceReadiness{Workspace: fmt.Sprintf("%s/%s", wObj.Namespace, wObj.Name)}
+	if wObj.DeletionTimestamp != nil {
+		readiness.Message = "the workspace is being deleted"
+		return readiness, nil
+	}
+
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, c.Client)
+	if err != nil {
+		return nil, err
+	}
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if m.DeletionTimestamp != nil {
+			continue
+		}
+		readiness.Machines++
+		if _, ready := lo.Find(m.GetConditions(), func(condition apis.Condition) bool {
+			return condition.Type == apis.ConditionReady && condition.Status == corev1.ConditionTrue
+		}); ready {
+			readiness.ReadyMachines++
+		}
+	}
+
+	var workloadsReady bool
+	if wObj.Inference != nil {
+		workloads, err := c.inferenceWorkloads(ctx, wObj)
+		if err != nil {
+			return nil, err
+		}
+		for _, workload := range workloads {
+			replicas := workloadReplicas(workload)
+			readiness.DesiredReplicas += replicas.Desired
+			readiness.AvailableReplicas += replicas.Available
+		}
+		workloadsReady = len(workloads) != 0 && readiness.AvailableReplicas >= readiness.DesiredReplicas
+	} else {
+		workloadsReady = tuningCompleted(wObj)
+	}
+
+	switch {
+	case readiness.ReadyMachines < readiness.Machines:
+		readiness.Message = fmt.Sprintf("%d of %d machines are ready", readiness.ReadyMachines, readiness.Machines)
+	case wObj.Inference != nil && !workloadsReady:
+		readiness.Message = fmt.Sprintf("%d of %d inference replicas are available", readiness.AvailableReplicas, readiness.DesiredReplicas)
+	case wObj.Inference == nil && !workloadsReady:
+		readiness.Message = "the tuning job has not completed"
+		if condition := meta.FindStatusCondition(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeTuningJobStatus)); condition != nil {
+			readiness.Message = condition.Message
+		}
+	default:
+		readiness.Ready = true
+	}
+	return readiness, nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	path := ReadinessPath + workspace.Namespace + "/" + workspace.Name
+
+	testcases := map[string]struct {
+		path              string
+		workspaceNotFound bool
+		machineReady      corev1.ConditionStatus
+		availableReplicas int32
+		expectedCode      int
+		expectedMessage   string
+	}{
+		"Machines are ready and replicas are available": {
+			path:              path,
+			machineReady:      corev1.ConditionTrue,
+			availableReplicas: 2,
+			expectedCode:      http.StatusOK,
+		},
+		"Machine is not ready": {
+			path:              path,
+			machineReady:      corev1.ConditionFalse,
+			availableReplicas: 2,
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedMessage:   "0 of 1 machines are ready",
+		},
+		"Replicas are not available": {
+			path:              path,
+			machineReady:      corev1.ConditionTrue,
+			availableReplicas: 1,
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedMessage:   "1 of 2 inference replicas are available",
+		},
+		"Workspace is not found": {
+			path:              path,
+			workspaceNotFound: true,
+			expectedCode:      http.StatusNotFound,
+		},
+		"Malformed path": {
+			path:         ReadinessPath + workspace.Namespace,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			if tc.workspaceNotFound {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(utils.NotFoundError())
+			} else {
+				mockClient.CreateOrUpdateObjectInMap(workspace)
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			}
+
+			machine := utils.MockMachine.DeepCopy()
+			machine.Status.Conditions = apis.Conditions{{Type: apis.ConditionReady, Status: tc.machineReady}}
+			mockClient.CreateMapWithType(&v1alpha5.MachineList{})[client.ObjectKeyFromObject(machine)] = machine
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+
+			depObj := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: workspace.Name, Namespace: workspace.Namespace},
+				Spec:       appsv1.DeploymentSpec{Replicas: lo.ToPtr(int32(2))},
+				Status:     appsv1.DeploymentStatus{AvailableReplicas: tc.availableReplicas},
+			}
+			mockClient.CreateOrUpdateObjectInMap(depObj)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			recorder := httptest.NewRecorder()
+			reconciler.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, recorder.Code, tc.expectedCode)
+			if tc.expectedCode != http.StatusOK && tc.expectedCode != http.StatusServiceUnavailable {
+				return
+			}
+
+			readiness := &WorkspaceReadiness{}
+			assert.NilError(t, json.NewDecoder(recorder.Body).Decode(readiness))
+			assert.Equal(t, readiness.Ready, tc.expectedCode == http.StatusOK)
+			assert.Equal(t, readiness.Message, tc.expectedMessage)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/tuning"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RenderWorkspaceManifests returns the objects kaito creates for the workspace, i.e., the machines, the inference
+// workload with its services, model info ConfigMap and egress NetworkPolicy, or the tuning Job, without applying them to a cluster.
+// The objects can be serialized for review, e.g., to be committed to a GitOps repository. Since no cluster is
+// queried, the machines use the instance type of the workspace and the torch parameters of distributed inference
+// that depend on the service address are not set.
+func RenderWorkspaceManifests(wObj *kaitov1alpha1.Workspace) ([]client.Object, error) {
+	ctx := context.Background()
+	provider := cloudprovider.Default
+
+	// The OS disk size of the machines depends on the preset models.
+	if wObj.Inference != nil {
+		for _, preset := range inferencePresets(wObj) {
+			if presetName := string(preset.Name); !plugin.KaitoModelRegister.Has(presetName) {
+				return nil, fmt.Errorf("the preset model name %s is not registered for workspace %s/%s", presetName, wObj.Namespace, wObj.Name)
+			}
+		}
+	}
+
+	var objs []client.Object
+	for index := 0; index < lo.FromPtr(wObj.Resource.Count); index++ {
+		objs = append(objs, machine.GenerateMachineManifest(ctx, machineOSDiskSize(wObj), wObj, index, wObj.Resource.InstanceType, provider))
+	}
+
+	switch {
+	case wObj.Tuning != nil:
+		if wObj.Tuning.Preset == nil {
+			return nil, fmt.Errorf("the tuning of workspace %s/%s has no preset", wObj.Namespace, wObj.Name)
+		}
+		presetName := string(wObj.Tuning.Preset.Name)
+		if !plugin.KaitoModelRegister.Has(presetName) {
+			return nil, fmt.Errorf("the preset model name %s is not registered for workspace %s/%s", presetName, wObj.Namespace, wObj.Name)
+		}
+		tuningParam := plugin.KaitoModelRegister.MustGet(presetName).GetTuningParameters()
+		objs = append(objs, tuning.GeneratePresetTuningManifest(ctx, wObj, tuningParam))
+	case wObj.Inference != nil && len(wObj.Inference.Variants) != 0:
+		deployments, err := inference.GenerateVariantInferenceManifests(ctx, wObj, provider)
+		if err != nil {
+			return nil, err
+		}
+		for _, depObj := range deployments {
+			objs = append(objs, depObj)
+		}
+		for _, serviceObj := range generateServiceManifests(ctx, wObj) {
+			objs = append(objs, serviceObj)
+		}
+		objs = append(objs, inference.BuildModelInfoConfigMap(wObj))
+	case wObj.Inference != nil && wObj.Inference.Preset != nil:
+		model := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name))
+		objs = append(objs, inference.GeneratePresetInferenceManifest(ctx, wObj, model.GetInferenceParameters(),
+			model.SupportDistributedInference(), provider))
+		for _, serviceObj := range generateServiceManifests(ctx, wObj) {
+			objs = append(objs, serviceObj)
+		}
+		objs = append(objs, inference.BuildModelInfoConfigMap(wObj))
+	case wObj.Inference != nil && wObj.Inference.Template != nil:
+		objs = append(objs, inference.GenerateTemplateInferenceManifest(ctx, wObj))
+		objs = append(objs, inference.BuildModelInfoConfigMap(wObj))
+	}
+	if policy := resources.GenerateEgressNetworkPolicyManifest(ctx, wObj); policy != nil {
+		objs = append(objs, policy)
+	}
+	return objs, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRenderWorkspaceManifests(t *testing.T) {
+	utils.RegisterTestModel()
+
+	inferenceWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	inferenceWorkspace.Resource.Count = lo.ToPtr(2)
+
+	tuningWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	tuningWorkspace.Inference = nil
+	tuningWorkspace.Tuning = &v1alpha1.TuningSpec{
+		Preset: &v1alpha1.PresetSpec{
+			PresetMeta: v1alpha1.PresetMeta{
+				Name: "test-model",
+			},
+		},
+		Input:  &v1alpha1.DataSource{URLs: []string{"https://example.com/data.parquet"}},
+		Output: &v1alpha1.DataDestination{Image: "example.azurecr.io/adapter:0.0.1", ImagePushSecret: "push-secret"},
+	}
+
+	unregisteredWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	unregisteredWorkspace.Inference.Preset.Name = "unregistered-model"
+
+	testcases := map[string]struct {
+		workspace     *v1alpha1.Workspace
+		expectedObjs  []string
+		expectedError bool
+	}{
+		"Inference workspace": {
+			workspace: inferenceWorkspace,
+			expectedObjs: []string{
+				"Machine",
+				"Machine",
+				"Deployment/testWorkspace",
+				"Service/testWorkspace",
+				"ConfigMap/testWorkspace-model-info",
+			},
+		},
+		"Distributed inference workspace": {
+			workspace: utils.MockWorkspaceDistributedModel,
+			expectedObjs: []string{
+				"Machine",
+				"StatefulSet/testWorkspace",
+				"Service/testWorkspace",
+				"Service/testWorkspace-headless",
+				"ConfigMap/testWorkspace-model-info",
+			},
+		},
+		"Tuning workspace": {
+			workspace: tuningWorkspace,
+			expectedObjs: []string{
+				"Machine",
+				"Job/testWorkspace",
+			},
+		},
+		"Unregistered preset": {
+			workspace:     unregisteredWorkspace,
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			objs, err := RenderWorkspaceManifests(tc.workspace)
+			if tc.expectedError {
+				assert.Check(t, err != nil, "Expected to return error")
+				return
+			}
+			assert.Check(t, err == nil, "Not expected to return error")
+			rendered := lo.Map(objs, func(obj client.Object, _ int) string {
+				switch o := obj.(type) {
+				case *v1alpha5.Machine:
+					// The machine names are generated.
+					return "Machine"
+				case *appsv1.Deployment:
+					return fmt.Sprintf("Deployment/%s", o.Name)
+				case *appsv1.StatefulSet:
+					return fmt.Sprintf("StatefulSet/%s", o.Name)
+				case *batchv1.Job:
+					return fmt.Sprintf("Job/%s", o.Name)
+				case *corev1.Service:
+					return fmt.Sprintf("Service/%s", o.Name)
+				case *corev1.ConfigMap:
+					return fmt.Sprintf("ConfigMap/%s", o.Name)
+				}
+				return fmt.Sprintf("%T/%s", obj, obj.GetName())
+			})
+			assert.DeepEqual(t, rendered, tc.expectedObjs)
+		})
+	}
+}
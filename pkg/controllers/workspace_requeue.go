@@ -0,0 +1,60 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+)
+
+// WorkspaceState is the state of a workspace at the end of a successful reconciliation.
+type WorkspaceState string
+
+const (
+	// WorkspaceStateProvisioning means the workspace is still converging, e.g., its tuning job is running.
+	WorkspaceStateProvisioning WorkspaceState = "Provisioning"
+	// WorkspaceStateReady means the resources and the workload of the workspace are ready.
+	WorkspaceStateReady WorkspaceState = "Ready"
+)
+
+// RequeueIntervals configures how soon a workspace is reconciled again after a successful reconciliation,
+// in addition to the reconciliations triggered by the watched objects.
+type RequeueIntervals struct {
+	// Provisioning is the interval while the workspace is provisioning, which picks up the progress quickly.
+	Provisioning time.Duration
+	// Ready is the interval once the workspace is ready, which detects the drift that no watch reports.
+	Ready time.Duration
+}
+
+// DefaultRequeueIntervals are used for the intervals that are not configured.
+var DefaultRequeueIntervals = RequeueIntervals{
+	Provisioning: 15 * time.Second,
+	Ready:        10 * time.Minute,
+}
+
+// ComputeRequeueAfter returns the time after which a workspace in the state is reconciled again.
+// The intervals that are not set fall back to DefaultRequeueIntervals.
+func (r RequeueIntervals) ComputeRequeueAfter(state WorkspaceState) time.Duration {
+	switch state {
+	case WorkspaceStateProvisioning:
+		if r.Provisioning > 0 {
+			return r.Provisioning
+		}
+		return DefaultRequeueIntervals.Provisioning
+	default:
+		if r.Ready > 0 {
+			return r.Ready
+		}
+		return DefaultRequeueIntervals.Ready
+	}
+}
+
+// workspaceState returns the state of a workspace whose reconciliation succeeded. The nodes and the inference
+// workload are ready once reconciled, only a tuning job keeps running after its reconciliation.
+func workspaceState(wObj *kaitov1alpha1.Workspace) WorkspaceState {
+	if wObj.Tuning != nil && !tuningCompleted(wObj) {
+		return WorkspaceStateProvisioning
+	}
+	return WorkspaceStateReady
+}
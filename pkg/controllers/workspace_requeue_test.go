@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputeRequeueAfter(t *testing.T) {
+	testcases := map[string]struct {
+		intervals     RequeueIntervals
+		state         WorkspaceState
+		expectedAfter time.Duration
+	}{
+		"Short default interval while provisioning": {
+			state:         WorkspaceStateProvisioning,
+			expectedAfter: DefaultRequeueIntervals.Provisioning,
+		},
+		"Long default interval when ready": {
+			state:         WorkspaceStateReady,
+			expectedAfter: DefaultRequeueIntervals.Ready,
+		},
+		"Configured interval while provisioning": {
+			intervals:     RequeueIntervals{Provisioning: 5 * time.Second, Ready: time.Hour},
+			state:         WorkspaceStateProvisioning,
+			expectedAfter: 5 * time.Second,
+		},
+		"Configured interval when ready": {
+			intervals:     RequeueIntervals{Provisioning: 5 * time.Second, Ready: time.Hour},
+			state:         WorkspaceStateReady,
+			expectedAfter: time.Hour,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, tc.intervals.ComputeRequeueAfter(tc.state), tc.expectedAfter)
+		})
+	}
+}
+
+func TestWorkspaceState(t *testing.T) {
+	tuningWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	tuningWorkspace.Inference = nil
+	tuningWorkspace.Tuning = &v1alpha1.TuningSpec{}
+
+	completedTuningWorkspace := tuningWorkspace.DeepCopy()
+	completedTuningWorkspace.Status.Conditions = []metav1.Condition{
+		{Type: string(v1alpha1.WorkspaceConditionTypeTuningJobStatus), Status: metav1.ConditionTrue},
+	}
+
+	testcases := map[string]struct {
+		workspace     *v1alpha1.Workspace
+		expectedState WorkspaceState
+	}{
+		"Inference workspace": {
+			workspace:     utils.MockWorkspaceWithPreset,
+			expectedState: WorkspaceStateReady,
+		},
+		"Running tuning job": {
+			workspace:     tuningWorkspace,
+			expectedState: WorkspaceStateProvisioning,
+		},
+		"Completed tuning job": {
+			workspace:     completedTuningWorkspace,
+			expectedState: WorkspaceStateReady,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, workspaceState(tc.workspace), tc.expectedState)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resourceQuotaGPURequests is the resource of a ResourceQuota that limits the GPUs requested by the pods of a namespace.
+var resourceQuotaGPURequests = corev1.ResourceName("requests." + resources.CapacityNvidiaGPU)
+
+// checkResourceQuotaHeadroom returns an error if a ResourceQuota of the namespace does not leave enough GPU requests
+// for the pods of the workspace. The GPUs of the nodes would be useless, since the pods that request them could not
+// be created. The GPUs requested by the existing pods of the workspace are already used in the quota.
+func (c *WorkspaceReconciler) checkResourceQuotaHeadroom(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	quotaList := &corev1.ResourceQuotaList{}
+	if err := c.Client.List(ctx, quotaList, client.InNamespace(wObj.Namespace)); err != nil {
+		klog.ErrorS(err, "failed to list resource quotas", "namespace", wObj.Namespace)
+		return err
+	}
+	quotas := make([]*corev1.ResourceQuota, 0, len(quotaList.Items))
+	for i := range quotaList.Items {
+		if _, found := quotaList.Items[i].Spec.Hard[resourceQuotaGPURequests]; found {
+			quotas = append(quotas, &quotaList.Items[i])
+		}
+	}
+	if len(quotas) == 0 {
+		return nil
+	}
+
+	required, err := c.workspaceGPURequests(ctx, wObj)
+	if err != nil {
+		return err
+	}
+	podList := &corev1.PodList{}
+	if err := c.Client.List(ctx, podList, client.InNamespace(wObj.Namespace),
+		client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: wObj.Name}); err != nil {
+		return err
+	}
+	var used int64
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp == nil && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			used += podGPURequests(&pod.Spec)
+		}
+	}
+	missing := int64(required) - used
+	if missing <= 0 {
+		return nil
+	}
+
+	for _, quota := range quotas {
+		hard := quota.Spec.Hard[resourceQuotaGPURequests]
+		quotaUsed := quota.Status.Used[resourceQuotaGPURequests]
+		headroom := hard.Value() - quotaUsed.Value()
+		if missing > headroom {
+			return fmt.Errorf("the pods of workspace %s/%s request %d more GPUs, but ResourceQuota %s only leaves %d of %d %s in the namespace",
+				wObj.Namespace, wObj.Name, missing, quota.Name, headroom, hard.Value(), resourceQuotaGPURequests)
+		}
+	}
+	return nil
+}
+
+// workspaceGPURequests returns the number of GPUs requested by all pods of the workspace.
+func (c *WorkspaceReconciler) workspaceGPURequests(ctx context.Context, wObj *kaitov1alpha1.Workspace) (int, error) {
+	switch {
+	case wObj.Inference != nil:
+		return c.requiredGPUs(ctx, wObj)
+	case wObj.Tuning != nil && wObj.Tuning.Preset != nil:
+		// The tuning job runs a single pod.
+		model := plugin.KaitoModelRegister.MustGet(string(wObj.Tuning.Preset.Name))
+		gpuCount := resource.MustParse(model.GetTuningParameters().GPUCountRequirement)
+		return int(gpuCount.Value()), nil
+	default:
+		return 0, nil
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func mockGPUResourceQuota(hard, used string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu-quota", Namespace: utils.MockWorkspaceWithPreset.Namespace},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{resourceQuotaGPURequests: resource.MustParse(hard)},
+		},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{resourceQuotaGPURequests: resource.MustParse(hard)},
+			Used: corev1.ResourceList{resourceQuotaGPURequests: resource.MustParse(used)},
+		},
+	}
+}
+
+func TestCheckResourceQuotaHeadroom(t *testing.T) {
+	utils.RegisterTestModel()
+	runningPod := mockWorkspacePod("running-pod", "node1")
+	runningPod.Spec.Containers = []corev1.Container{
+		{
+			Name: "inference",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse("1")},
+			},
+		},
+	}
+
+	testcases := map[string]struct {
+		count         int
+		quotas        []*corev1.ResourceQuota
+		pods          []*corev1.Pod
+		expectedError string
+	}{
+		"Namespace without a GPU resource quota": {
+			count: 2,
+			quotas: []*corev1.ResourceQuota{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "cpu-quota", Namespace: utils.MockWorkspaceWithPreset.Namespace},
+					Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1")}},
+				},
+			},
+		},
+		"GPU resource quota has headroom": {
+			count:  2,
+			quotas: []*corev1.ResourceQuota{mockGPUResourceQuota("4", "2")},
+		},
+		"GPU resource quota is exhausted": {
+			count:         2,
+			quotas:        []*corev1.ResourceQuota{mockGPUResourceQuota("4", "3")},
+			expectedError: "request 2 more GPUs, but ResourceQuota gpu-quota only leaves 1 of 4 requests.nvidia.com/gpu in the namespace",
+		},
+		"GPUs of the running pods of the workspace are already used": {
+			count:  2,
+			quotas: []*corev1.ResourceQuota{mockGPUResourceQuota("4", "3")},
+			pods:   []*corev1.Pod{runningPod},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+			wObj.Resource.Count = lo.ToPtr(tc.count)
+
+			quotaMap := mockClient.CreateMapWithType(&corev1.ResourceQuotaList{})
+			for _, quota := range tc.quotas {
+				quotaMap[client.ObjectKeyFromObject(quota)] = quota
+			}
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			for _, pod := range tc.pods {
+				podMap[client.ObjectKeyFromObject(pod)] = pod
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.ResourceQuotaList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			err := reconciler.checkResourceQuotaHeadroom(context.Background(), wObj)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error: %v", err)
+			} else {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RetryBudget configures how many provisioning attempts of a workspace may fail within a window before
+// the provisioning backs off, e.g., to stop launching an instance type that is out of capacity in the region.
+type RetryBudget struct {
+	// MaxFailures is the number of failed attempts that exhausts the budget.
+	MaxFailures int32
+	// Window is the period the failed attempts are counted in. The budget is reset once it has passed.
+	Window time.Duration
+}
+
+// DefaultRetryBudget is used for the settings of the retry budget that are not configured.
+var DefaultRetryBudget = RetryBudget{
+	MaxFailures: 5,
+	Window:      time.Hour,
+}
+
+func (b RetryBudget) maxFailures() int32 {
+	if b.MaxFailures <= 0 {
+		return DefaultRetryBudget.MaxFailures
+	}
+	return b.MaxFailures
+}
+
+func (b RetryBudget) window() time.Duration {
+	if b.Window <= 0 {
+		return DefaultRetryBudget.Window
+	}
+	return b.Window
+}
+
+// Backoff returns how long the provisioning of the workspace must wait, which is zero unless the failed attempts
+// of the current window and generation have exhausted the budget.
+func (b RetryBudget) Backoff(wObj *kaitov1alpha1.Workspace, now time.Time) time.Duration {
+	status := wObj.Status.ProvisioningRetryBudget
+	if status == nil || status.ObservedGeneration != wObj.Generation || status.FailedAttempts < b.maxFailures() {
+		return 0
+	}
+	if remaining := status.WindowStart.Add(b.window()).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// RecordFailure returns the retry budget of the workspace after a failed attempt at the given time. A new window
+// is started if the current one has passed or the spec of the workspace has changed since.
+func (b RetryBudget) RecordFailure(wObj *kaitov1alpha1.Workspace, now time.Time) *kaitov1alpha1.ProvisioningRetryBudget {
+	status := wObj.Status.ProvisioningRetryBudget
+	if status == nil || status.ObservedGeneration != wObj.Generation || !now.Before(status.WindowStart.Add(b.window())) {
+		return &kaitov1alpha1.ProvisioningRetryBudget{
+			ObservedGeneration: wObj.Generation,
+			FailedAttempts:     1,
+			WindowStart:        metav1.NewTime(now),
+		}
+	}
+	updated := status.DeepCopy()
+	updated.FailedAttempts++
+	return updated
+}
+
+// checkRetryBudget reports in the RateLimited condition whether the provisioning of the workspace backs off, and
+// returns how long it must wait.
+func (c *WorkspaceReconciler) checkRetryBudget(ctx context.Context, wObj *kaitov1alpha1.Workspace) (time.Duration, error) {
+	backoff := c.RetryBudget.Backoff(wObj, time.Now())
+	if backoff > 0 {
+		status := wObj.Status.ProvisioningRetryBudget
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeRateLimited, metav1.ConditionTrue,
+			"retryBudgetExhausted", fmt.Sprintf("%d provisioning attempts failed since %s, retrying after %s unless the workspace is changed",
+				status.FailedAttempts, status.WindowStart.UTC().Format(time.RFC3339), status.WindowStart.Add(c.RetryBudget.window()).UTC().Format(time.RFC3339))); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return 0, err
+		}
+		return backoff, nil
+	}
+	// The condition is only cleared once the provisioning has been rate limited.
+	if meta.IsStatusConditionTrue(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeRateLimited)) {
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeRateLimited, metav1.ConditionFalse,
+			"retryBudgetAvailable", "the provisioning is retried"); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// recordProvisioningFailure counts a failed provisioning attempt against the retry budget of the workspace.
+func (c *WorkspaceReconciler) recordProvisioningFailure(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	return c.updateStatusRetryBudget(ctx, wObj, c.RetryBudget.RecordFailure(wObj, time.Now()))
+}
+
+// resetRetryBudget clears the failed provisioning attempts of the workspace once its nodes are provisioned.
+func (c *WorkspaceReconciler) resetRetryBudget(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if wObj.Status.ProvisioningRetryBudget == nil {
+		return nil
+	}
+	return c.updateStatusRetryBudget(ctx, wObj, nil)
+}
+
+func (c *WorkspaceReconciler) updateStatusRetryBudget(ctx context.Context, wObj *kaitov1alpha1.Workspace, budget *kaitov1alpha1.ProvisioningRetryBudget) error {
+	klog.InfoS("updateStatusRetryBudget", "workspace", klog.KObj(wObj), "budget", budget)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &kaitov1alpha1.Workspace{}
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(wObj), latest); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		latest.Status.ProvisioningRetryBudget = budget
+		return c.Client.Status().Update(ctx, latest)
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRetryBudget(t *testing.T) {
+	budget := RetryBudget{MaxFailures: 3, Window: time.Hour}
+	start := time.Now()
+	wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+	wObj.Generation = 1
+
+	// The budget is exhausted by the third failure within the window.
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, budget.Backoff(wObj, start), time.Duration(0))
+		wObj.Status.ProvisioningRetryBudget = budget.RecordFailure(wObj, start.Add(time.Duration(i)*time.Minute))
+		assert.Equal(t, wObj.Status.ProvisioningRetryBudget.FailedAttempts, int32(i))
+	}
+	assert.Equal(t, budget.Backoff(wObj, start.Add(10*time.Minute)), 51*time.Minute)
+
+	// The provisioning is retried once the window has passed, and the next failure starts a new window.
+	recovered := start.Add(time.Minute + time.Hour)
+	assert.Equal(t, budget.Backoff(wObj, recovered), time.Duration(0))
+	renewed := budget.RecordFailure(wObj, recovered)
+	assert.Equal(t, renewed.FailedAttempts, int32(1))
+	assert.Check(t, renewed.WindowStart.Time.Equal(recovered), "expected a new window")
+
+	// A spec change resets the budget.
+	wObj.Generation = 2
+	assert.Equal(t, budget.Backoff(wObj, start.Add(10*time.Minute)), time.Duration(0))
+	assert.Equal(t, budget.RecordFailure(wObj, start.Add(10*time.Minute)).FailedAttempts, int32(1))
+}
+
+func TestCheckRetryBudget(t *testing.T) {
+	testcases := map[string]struct {
+		windowStart    time.Time
+		conditions     []metav1.Condition
+		expectBackoff  bool
+		expectedStatus metav1.ConditionStatus
+		expectNoUpdate bool
+	}{
+		"Exhausted budget backs off": {
+			windowStart:    time.Now().Add(-10 * time.Minute),
+			expectBackoff:  true,
+			expectedStatus: metav1.ConditionTrue,
+		},
+		"Budget recovers after the window": {
+			windowStart: time.Now().Add(-2 * time.Hour),
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeRateLimited), Status: metav1.ConditionTrue, Reason: "retryBudgetExhausted"},
+			},
+			expectedStatus: metav1.ConditionFalse,
+		},
+		"Budget that was never exhausted is not reported": {
+			windowStart:    time.Now().Add(-2 * time.Hour),
+			expectNoUpdate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Status.Conditions = tc.conditions
+			workspace.Status.ProvisioningRetryBudget = &v1alpha1.ProvisioningRetryBudget{
+				FailedAttempts: DefaultRetryBudget.MaxFailures,
+				WindowStart:    metav1.NewTime(tc.windowStart),
+			}
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			backoff, err := reconciler.checkRetryBudget(context.Background(), workspace)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, backoff > 0, tc.expectBackoff)
+
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeRateLimited))
+			assert.Check(t, condition != nil, "expected the rate limited condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+		})
+	}
+}
+
+func TestRecordProvisioningFailure(t *testing.T) {
+	mockClient := utils.NewClient()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Status.ProvisioningRetryBudget = &v1alpha1.ProvisioningRetryBudget{
+		FailedAttempts: 1,
+		WindowStart:    metav1.NewTime(time.Now().Add(-time.Minute)),
+	}
+	mockClient.CreateOrUpdateObjectInMap(workspace)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+	mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+	reconciler := &WorkspaceReconciler{
+		Client: mockClient,
+		Scheme: utils.NewTestScheme(),
+	}
+
+	assert.NilError(t, reconciler.recordProvisioningFailure(context.Background(), workspace))
+	updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+	assert.Equal(t, updated.Status.ProvisioningRetryBudget.FailedAttempts, int32(2))
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/gpumetrics"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultScaleDownUtilizationThreshold is the GPU memory utilization below which a node is idle.
+	DefaultScaleDownUtilizationThreshold = 0.1
+	// DefaultScaleDownWindow is how long the GPU memory utilization of a node must stay low before it is removed.
+	DefaultScaleDownWindow = 30 * time.Minute
+
+	// podDeletionCostAnnotation makes the ReplicaSet controller remove the pods of the idle nodes first.
+	podDeletionCostAnnotation = "controller.kubernetes.io/pod-deletion-cost"
+	idlePodDeletionCost       = "-1000"
+)
+
+// GPUScaleDown configures the scale down of the autoscaling workspaces whose nodes leave their GPU memory idle.
+type GPUScaleDown struct {
+	// Source reports the GPU memory utilization of the nodes.
+	Source gpumetrics.Source
+	// UtilizationThreshold is the GPU memory utilization, between 0 and 1, below which a node is idle.
+	// Defaults to DefaultScaleDownUtilizationThreshold if not set.
+	UtilizationThreshold float64
+	// Window is how long the GPU memory utilization of a node must stay below the threshold.
+	// Defaults to DefaultScaleDownWindow if not set.
+	Window time.Duration
+}
+
+func (s *GPUScaleDown) utilizationThreshold() float64 {
+	if s.UtilizationThreshold <= 0 {
+		return DefaultScaleDownUtilizationThreshold
+	}
+	return s.UtilizationThreshold
+}
+
+func (s *GPUScaleDown) window() time.Duration {
+	if s.Window <= 0 {
+		return DefaultScaleDownWindow
+	}
+	return s.Window
+}
+
+// ComputeScaleDownCandidates returns the nodes of the workspace that can be removed because the peak utilization of
+// their GPU memory stayed below the threshold for the whole window, the least utilized first. At most as many nodes
+// are returned as the workspace has above its minimum count. Nodes younger than the window or without samples are
+// kept, their utilization is not known yet.
+func (s *GPUScaleDown) ComputeScaleDownCandidates(ctx context.Context, wObj *kaitov1alpha1.Workspace, kubeClient client.Client) ([]string, error) {
+	if !autoscalingEnabled(wObj) || wObj.Inference == nil || len(wObj.Inference.Variants) != 0 {
+		return nil, nil
+	}
+	minCount, _ := nodeCountRange(wObj)
+	removable := len(wObj.Status.WorkerNodes) - minCount
+	if removable <= 0 {
+		return nil, nil
+	}
+
+	utilization, err := s.Source.NodeGPUMemoryUtilization(ctx, wObj.Status.WorkerNodes, s.window())
+	if err != nil {
+		return nil, err
+	}
+
+	var idleNodes []string
+	for _, nodeName := range wObj.Status.WorkerNodes {
+		peak, found := utilization[nodeName]
+		if !found || peak >= s.utilizationThreshold() {
+			continue
+		}
+		node, err := resources.GetNode(ctx, nodeName, kubeClient)
+		if err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		if time.Since(node.CreationTimestamp.Time) < s.window() {
+			continue
+		}
+		idleNodes = append(idleNodes, nodeName)
+	}
+	sort.SliceStable(idleNodes, func(i, j int) bool {
+		return utilization[idleNodes[i]] < utilization[idleNodes[j]]
+	})
+
+	if len(idleNodes) > removable {
+		idleNodes = idleNodes[:removable]
+	}
+	return idleNodes, nil
+}
+
+// scaleDownIdleNodes drains the nodes of the workspace whose GPU memory stays idle. The nodes are cordoned and the
+// Deployment of the workspace is scaled down by the pods they run, which are removed first. The nodes no longer run
+// workspace pods, so the reconcile plan deletes their machines. Only the single Deployment of a workspace is scaled.
+func (c *WorkspaceReconciler) scaleDownIdleNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if c.GPUScaleDown == nil {
+		return nil
+	}
+	candidates, err := c.GPUScaleDown.ComputeScaleDownCandidates(ctx, wObj, c.Client)
+	if err != nil || len(candidates) == 0 {
+		return err
+	}
+
+	workload := &appsv1.Deployment{}
+	if err := resources.GetResource(ctx, wObj.Name, wObj.Namespace, c.Client, workload); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	for _, nodeName := range candidates {
+		klog.InfoS("scaling down the idle node of the workspace", "workspace", klog.KObj(wObj), "node", nodeName)
+	}
+	idlePods, err := c.drainWorkspaceNodes(ctx, wObj, candidates)
+	if err != nil {
+		return err
+	}
+
+	replicas := lo.FromPtr(workload.Spec.Replicas) - idlePods
+	if idlePods == 0 || replicas < 0 {
+		return nil
+	}
+	klog.InfoS("scaling down the workload of the workspace", "workspace", klog.KObj(wObj), "replicas", replicas)
+	workload.Spec.Replicas = lo.ToPtr(replicas)
+	return c.Client.Update(ctx, workload, &client.UpdateOptions{})
+}
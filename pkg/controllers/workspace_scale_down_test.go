@@ -0,0 +1,96 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeGPUMetricsSource struct {
+	utilization map[string]float64
+	err         error
+}
+
+func (f *fakeGPUMetricsSource) NodeGPUMemoryUtilization(_ context.Context, _ []string, _ time.Duration) (map[string]float64, error) {
+	return f.utilization, f.err
+}
+
+func TestComputeScaleDownCandidates(t *testing.T) {
+	testcases := map[string]struct {
+		minCount           *int
+		workerNodes        []string
+		utilization        map[string]float64
+		metricsErr         error
+		newNodes           []string
+		expectedCandidates []string
+		expectedError      bool
+	}{
+		"Workspace without autoscaling is not scaled down": {
+			workerNodes: []string{"node-1", "node-2"},
+			utilization: map[string]float64{"node-1": 0, "node-2": 0},
+		},
+		"Idle nodes are removed and busy ones are kept": {
+			minCount:           lo.ToPtr(1),
+			workerNodes:        []string{"node-1", "node-2", "node-3", "node-4"},
+			utilization:        map[string]float64{"node-1": 0.8, "node-2": 0.05, "node-3": 0.5, "node-4": 0.01},
+			expectedCandidates: []string{"node-4", "node-2"},
+		},
+		"Least utilized idle nodes are removed down to the minimum count": {
+			minCount:           lo.ToPtr(2),
+			workerNodes:        []string{"node-1", "node-2", "node-3"},
+			utilization:        map[string]float64{"node-1": 0.08, "node-2": 0.02, "node-3": 0.05},
+			expectedCandidates: []string{"node-2"},
+		},
+		"Nodes without samples or younger than the window are kept": {
+			minCount:    lo.ToPtr(1),
+			workerNodes: []string{"node-1", "node-2", "node-3"},
+			utilization: map[string]float64{"node-1": 0.9, "node-3": 0.01},
+			newNodes:    []string{"node-3"},
+		},
+		"Workspace at its minimum count is not scaled down": {
+			minCount:    lo.ToPtr(2),
+			workerNodes: []string{"node-1", "node-2"},
+			utilization: map[string]float64{"node-1": 0, "node-2": 0},
+		},
+		"Metrics are unavailable": {
+			minCount:      lo.ToPtr(1),
+			workerNodes:   []string{"node-1", "node-2"},
+			metricsErr:    errors.New("prometheus is unavailable"),
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			for _, nodeName := range tc.workerNodes {
+				node := mockAutoscalingNode(nodeName)
+				if lo.Contains(tc.newNodes, nodeName) {
+					node.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+				}
+				mockClient.CreateOrUpdateObjectInMap(node)
+			}
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Resource.Count = lo.ToPtr(len(tc.workerNodes))
+			workspace.Resource.MinCount = tc.minCount
+			workspace.Status.WorkerNodes = tc.workerNodes
+
+			scaleDown := &GPUScaleDown{Source: &fakeGPUMetricsSource{utilization: tc.utilization, err: tc.metricsErr}}
+			candidates, err := scaleDown.ComputeScaleDownCandidates(context.Background(), workspace, mockClient)
+			assert.Equal(t, err != nil, tc.expectedError)
+			assert.DeepEqual(t, candidates, tc.expectedCandidates)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// computeScaleInCandidates returns the worker nodes of the workspace to remove because its count was lowered, the
+// least disruptive first: the nodes running the fewest workspace pods, then the nodes whose GPU memory is the least
+// utilized if the utilization is known, then by name. Preferred nodes are removed last. Exactly as many nodes are
+// returned as the workspace has above its count, it never goes below it.
+func (c *WorkspaceReconciler) computeScaleInCandidates(ctx context.Context, wObj *kaitov1alpha1.Workspace) ([]string, error) {
+	if autoscalingEnabled(wObj) || wObj.Inference == nil || len(wObj.Inference.Variants) != 0 {
+		return nil, nil
+	}
+	removable := len(wObj.Status.WorkerNodes) - lo.FromPtr(wObj.Resource.Count)
+	if removable <= 0 {
+		return nil, nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.Client.List(ctx, podList, client.InNamespace(wObj.Namespace),
+		client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: wObj.Name}); err != nil {
+		return nil, err
+	}
+	podsPerNode := map[string]int{}
+	for i := range podList.Items {
+		if pod := &podList.Items[i]; pod.DeletionTimestamp.IsZero() && pod.Spec.NodeName != "" {
+			podsPerNode[pod.Spec.NodeName]++
+		}
+	}
+
+	var utilization map[string]float64
+	if c.GPUScaleDown != nil {
+		var err error
+		if utilization, err = c.GPUScaleDown.Source.NodeGPUMemoryUtilization(ctx, wObj.Status.WorkerNodes, c.GPUScaleDown.window()); err != nil {
+			// The nodes are still ranked by the pods they run.
+			klog.ErrorS(err, "failed to get the GPU memory utilization of the nodes", "workspace", klog.KObj(wObj))
+		}
+	}
+
+	nodeNames := append([]string{}, wObj.Status.WorkerNodes...)
+	sort.SliceStable(nodeNames, func(i, j int) bool {
+		iPreferred := lo.Contains(wObj.Resource.PreferredNodes, nodeNames[i])
+		jPreferred := lo.Contains(wObj.Resource.PreferredNodes, nodeNames[j])
+		if iPreferred != jPreferred {
+			return jPreferred
+		}
+		if iPods, jPods := podsPerNode[nodeNames[i]], podsPerNode[nodeNames[j]]; iPods != jPods {
+			return iPods < jPods
+		}
+		if iUtilization, jUtilization := utilization[nodeNames[i]], utilization[nodeNames[j]]; iUtilization != jUtilization {
+			return iUtilization < jUtilization
+		}
+		return nodeNames[i] < nodeNames[j]
+	})
+	return nodeNames[:removable], nil
+}
+
+// scaleInNodes drains the nodes of the workspace that exceed its lowered count. The nodes are cordoned and the
+// Deployment of the workspace is scaled down to the count, the pods of the drained nodes are removed first. The
+// cordoned nodes are selected last, so the reconcile plan deletes their machines.
+func (c *WorkspaceReconciler) scaleInNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	candidates, err := c.computeScaleInCandidates(ctx, wObj)
+	if err != nil || len(candidates) == 0 {
+		return err
+	}
+	klog.InfoS("scaling in the nodes of the workspace", "workspace", klog.KObj(wObj), "nodes", candidates)
+	if _, err := c.drainWorkspaceNodes(ctx, wObj, candidates); err != nil {
+		return err
+	}
+
+	workload := &appsv1.Deployment{}
+	if err := resources.GetResource(ctx, wObj.Name, wObj.Namespace, c.Client, workload); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	replicas := int32(lo.FromPtr(wObj.Resource.Count))
+	if lo.FromPtr(workload.Spec.Replicas) <= replicas {
+		return nil
+	}
+	klog.InfoS("scaling in the workload of the workspace", "workspace", klog.KObj(wObj), "replicas", replicas)
+	workload.Spec.Replicas = lo.ToPtr(replicas)
+	return c.Client.Update(ctx, workload, &client.UpdateOptions{})
+}
+
+// drainWorkspaceNodes cordons the nodes and marks the workspace pods they run to be removed first when the
+// Deployment of the workspace is scaled down. It returns the number of pods the nodes run.
+func (c *WorkspaceReconciler) drainWorkspaceNodes(ctx context.Context, wObj *kaitov1alpha1.Workspace, nodeNames []string) (int32, error) {
+	pods := &corev1.PodList{}
+	if err := c.Client.List(ctx, pods, client.InNamespace(wObj.Namespace),
+		client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: wObj.Name}); err != nil {
+		klog.ErrorS(err, "failed to list the pods of the workspace", "workspace", klog.KObj(wObj))
+		return 0, err
+	}
+
+	var drainedPods int32
+	for _, nodeName := range nodeNames {
+		if err := resources.CordonNode(ctx, nodeName, c.Client); client.IgnoreNotFound(err) != nil {
+			return 0, err
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Spec.NodeName != nodeName || !pod.DeletionTimestamp.IsZero() {
+				continue
+			}
+			drainedPods++
+			if pod.Annotations[podDeletionCostAnnotation] == idlePodDeletionCost {
+				continue
+			}
+			pod.Annotations = lo.Assign(pod.Annotations, map[string]string{podDeletionCostAnnotation: idlePodDeletionCost})
+			if err := c.Client.Update(ctx, pod, &client.UpdateOptions{}); client.IgnoreNotFound(err) != nil {
+				klog.ErrorS(err, "failed to update pod", "pod", klog.KObj(pod))
+				return 0, err
+			}
+		}
+	}
+	return drainedPods, nil
+}
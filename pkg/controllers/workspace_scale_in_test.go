@@ -0,0 +1,159 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestScaleInNodes(t *testing.T) {
+	workerNodes := []string{"node-1", "node-2", "node-3", "node-4"}
+	pods := []*corev1.Pod{
+		mockWorkspacePod("pod-1", "node-1"),
+		mockWorkspacePod("pod-2", "node-1"),
+		mockWorkspacePod("pod-3", "node-2"),
+		mockWorkspacePod("pod-4", "node-4"),
+	}
+	testcases := map[string]struct {
+		count            int
+		maxCount         *int
+		preferredNodes   []string
+		utilization      map[string]float64
+		expectedDrained  []string
+		expectedReplicas int32
+	}{
+		"Lowered count drains the least busy nodes": {
+			count:            2,
+			expectedDrained:  []string{"node-3", "node-2"},
+			expectedReplicas: 2,
+		},
+		"Nodes running as many pods are drained by their GPU memory utilization": {
+			count:            2,
+			utilization:      map[string]float64{"node-1": 0.9, "node-2": 0.6, "node-3": 0, "node-4": 0.2},
+			expectedDrained:  []string{"node-3", "node-4"},
+			expectedReplicas: 2,
+		},
+		"Preferred nodes are drained last": {
+			count:            2,
+			preferredNodes:   []string{"node-3"},
+			utilization:      map[string]float64{"node-1": 0.9, "node-2": 0.6, "node-3": 0, "node-4": 0.2},
+			expectedDrained:  []string{"node-4", "node-2"},
+			expectedReplicas: 2,
+		},
+		"Workspace at its count is not scaled in": {
+			count: 4,
+		},
+		"Autoscaling workspace is not scaled in": {
+			count:    2,
+			maxCount: lo.ToPtr(4),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			for _, nodeName := range workerNodes {
+				mockClient.CreateOrUpdateObjectInMap(mockAutoscalingNode(nodeName))
+			}
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			for _, pod := range pods {
+				p := pod.DeepCopy()
+				podMap[client.ObjectKeyFromObject(p)] = p
+			}
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Resource.Count = lo.ToPtr(tc.count)
+			workspace.Resource.MaxCount = tc.maxCount
+			workspace.Resource.PreferredNodes = tc.preferredNodes
+			workspace.Status.WorkerNodes = workerNodes
+			deployment := &appsv1.Deployment{}
+			deployment.Name = workspace.Name
+			deployment.Namespace = workspace.Namespace
+			deployment.Spec.Replicas = lo.ToPtr(int32(len(workerNodes)))
+			mockClient.CreateOrUpdateObjectInMap(deployment)
+
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.Anything, mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+			if tc.utilization != nil {
+				reconciler.GPUScaleDown = &GPUScaleDown{Source: &fakeGPUMetricsSource{utilization: tc.utilization}}
+			}
+
+			assert.NilError(t, reconciler.scaleInNodes(context.Background(), workspace))
+
+			var cordoned []string
+			var replicas int32
+			drainedPods := 0
+			for _, call := range mockClient.Calls {
+				if call.Method != "Update" {
+					continue
+				}
+				switch obj := call.Arguments.Get(1).(type) {
+				case *corev1.Node:
+					assert.Check(t, obj.Spec.Unschedulable, "expected node %s to be cordoned", obj.Name)
+					cordoned = append(cordoned, obj.Name)
+				case *corev1.Pod:
+					assert.Check(t, lo.Contains(tc.expectedDrained, obj.Spec.NodeName), "expected pod %s not to be drained", obj.Name)
+					assert.Equal(t, obj.Annotations[podDeletionCostAnnotation], idlePodDeletionCost)
+					drainedPods++
+				case *appsv1.Deployment:
+					replicas = lo.FromPtr(obj.Spec.Replicas)
+				}
+			}
+			assert.DeepEqual(t, cordoned, tc.expectedDrained)
+			assert.Equal(t, drainedPods, len(lo.Filter(pods, func(pod *corev1.Pod, _ int) bool {
+				return lo.Contains(tc.expectedDrained, pod.Spec.NodeName)
+			})))
+			assert.Equal(t, replicas, tc.expectedReplicas)
+		})
+	}
+}
+
+func TestBuildReconcilePlanScaleIn(t *testing.T) {
+	utils.RegisterTestModel()
+	mockClient := utils.NewClient()
+	machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+	nodeMap := mockClient.CreateMapWithType(&corev1.NodeList{})
+	for i, nodeName := range []string{"node-1", "node-2", "node-3", "node-4"} {
+		node := mockAutoscalingNode(nodeName)
+		// The nodes drained because the count was lowered.
+		node.Spec.Unschedulable = nodeName == "node-1" || nodeName == "node-3"
+		nodeMap[client.ObjectKeyFromObject(node)] = node
+		m := mockAutoscalingMachine(fmt.Sprintf("machine-%d", i+1), nodeName)
+		machineMap[client.ObjectKeyFromObject(m)] = m
+	}
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+
+	reconciler := &WorkspaceReconciler{
+		Client: mockClient,
+		Scheme: utils.NewTestScheme(),
+	}
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Resource.Count = lo.ToPtr(2)
+	workspace.Status.WorkerNodes = []string{"node-1", "node-2", "node-3", "node-4"}
+
+	plan, err := reconciler.BuildReconcilePlan(context.Background(), workspace)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, lo.Map(plan.SelectedNodes, func(node *corev1.Node, _ int) string { return node.Name }), []string{"node-2", "node-4"})
+	deleted := lo.Map(plan.MachinesToDelete, func(m *v1alpha5.Machine, _ int) string { return m.Name })
+	assert.Equal(t, len(deleted), 2)
+	assert.Check(t, lo.Contains(deleted, "machine-1") && lo.Contains(deleted, "machine-3"), "expected the machines of the drained nodes to be deleted, got %v", deleted)
+}
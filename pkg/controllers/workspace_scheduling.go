@@ -0,0 +1,169 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// DefaultSchedulingFailureThreshold is how long an inference pod may stay unschedulable before it is reported.
+	DefaultSchedulingFailureThreshold = 5 * time.Minute
+
+	// reasonFailedScheduling is the reason of the events the scheduler emits when it cannot place a pod.
+	reasonFailedScheduling = "FailedScheduling"
+)
+
+// schedulingFailureThreshold returns the threshold after which unschedulable pods are reported.
+func (c *WorkspaceReconciler) schedulingFailureThreshold() time.Duration {
+	if c.SchedulingFailureThreshold <= 0 {
+		return DefaultSchedulingFailureThreshold
+	}
+	return c.SchedulingFailureThreshold
+}
+
+// setupSchedulingWatcher sets up the controller that reports the inference pods that stay unschedulable, e.g., due to
+// insufficient resources, in the PodsScheduled condition of their workspace, and how many of them are ready in the
+// InferenceAvailable condition. It runs separately from the workspace controller, which blocks while it waits for the
+// inference workload to become ready.
+func (c *WorkspaceReconciler) setupSchedulingWatcher(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("workspace-scheduling").
+		For(&kaitov1alpha1.Workspace{}).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(workspaceOfPod)).
+		Complete(reconcile.Func(c.reconcilePodScheduling))
+}
+
+// workspaceOfPod enqueues the workspace that runs the pod, which is in the namespace of the workspace.
+func workspaceOfPod(ctx context.Context, o client.Object) []reconcile.Request {
+	name, ok := o.GetLabels()[kaitov1alpha1.LabelWorkspaceName]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: name, Namespace: o.GetNamespace()}}}
+}
+
+func (c *WorkspaceReconciler) reconcilePodScheduling(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	wObj := &kaitov1alpha1.Workspace{}
+	if err := c.Client.Get(ctx, req.NamespacedName, wObj); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if wObj.Inference == nil || wObj.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+
+	// The readiness of the pods changes the availability of the inference.
+	if _, err := c.updateInferenceAvailability(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update the inference availability", "workspace", klog.KObj(wObj))
+		return reconcile.Result{}, err
+	}
+
+	failure, requeueAfter, err := c.podSchedulingFailure(ctx, wObj)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if failure != "" {
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypePodsScheduled, metav1.ConditionFalse,
+			"PodsUnschedulable", failure); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, err
+		}
+		// The scheduler keeps retrying, so the reported reason is refreshed.
+		return reconcile.Result{RequeueAfter: c.schedulingFailureThreshold()}, nil
+	}
+	// The condition is only cleared once a failure has been reported.
+	if meta.FindStatusCondition(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypePodsScheduled)) != nil {
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypePodsScheduled, metav1.ConditionTrue,
+			"PodsScheduled", "No inference pod has been unschedulable for too long"); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// podSchedulingFailure summarizes why the inference pods of the workspace that have been unschedulable for longer
+// than the threshold cannot be scheduled, using the latest FailedScheduling event of the pods. An empty summary is
+// returned if there is no such pod, along with the time after which a pod that is unschedulable for a shorter time
+// reaches the threshold.
+func (c *WorkspaceReconciler) podSchedulingFailure(ctx context.Context, wObj *kaitov1alpha1.Workspace) (string, time.Duration, error) {
+	podList := &corev1.PodList{}
+	if err := c.Client.List(ctx, podList, client.InNamespace(wObj.Namespace),
+		client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: wObj.Name}); err != nil {
+		return "", 0, err
+	}
+
+	threshold := c.schedulingFailureThreshold()
+	var requeueAfter time.Duration
+	var fallbackReason string
+	unschedulable := map[types.UID]*corev1.Pod{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil || pod.Spec.NodeName != "" || !isPodUnschedulable(pod) {
+			continue
+		}
+		scheduled, _ := lo.Find(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+			return condition.Type == corev1.PodScheduled
+		})
+		if unschedulableFor := time.Since(scheduled.LastTransitionTime.Time); unschedulableFor < threshold {
+			if remaining := threshold - unschedulableFor; requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			continue
+		}
+		if len(unschedulable) == 0 {
+			fallbackReason = scheduled.Message
+		}
+		unschedulable[pod.UID] = pod
+	}
+	if len(unschedulable) == 0 {
+		return "", requeueAfter, nil
+	}
+
+	eventList := &corev1.EventList{}
+	if err := c.Client.List(ctx, eventList, client.InNamespace(wObj.Namespace)); err != nil {
+		return "", 0, err
+	}
+	var latest *corev1.Event
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		if event.Reason != reasonFailedScheduling || event.InvolvedObject.Kind != "Pod" || unschedulable[event.InvolvedObject.UID] == nil {
+			continue
+		}
+		if latest == nil || eventTime(event).After(eventTime(latest)) {
+			latest = event
+		}
+	}
+
+	// The events may have expired, the scheduler also reports the reason in the condition of the pod.
+	reason := fallbackReason
+	if latest != nil {
+		reason = latest.Message
+	}
+	return fmt.Sprintf("%d inference pods have been unschedulable for more than %s: %s", len(unschedulable), threshold, reason), 0, nil
+}
+
+// eventTime returns the time an event was last observed.
+func eventTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
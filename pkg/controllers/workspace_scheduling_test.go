@@ -0,0 +1,144 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func mockPendingPod(wObj *v1alpha1.Workspace, name string, pendingFor time.Duration) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: wObj.Namespace,
+			UID:       types.UID(name),
+			Labels:    map[string]string{v1alpha1.LabelWorkspaceName: wObj.Name},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{
+				{
+					Type:               corev1.PodScheduled,
+					Status:             corev1.ConditionFalse,
+					Reason:             corev1.PodReasonUnschedulable,
+					Message:            "0/2 nodes are available: 2 Insufficient nvidia.com/gpu.",
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-pendingFor)),
+				},
+			},
+		},
+	}
+}
+
+func mockFailedSchedulingEvent(pod *corev1.Pod, message string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name + ".failedscheduling", Namespace: pod.Namespace},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			UID:       pod.UID,
+		},
+		Reason:        reasonFailedScheduling,
+		Message:       message,
+		LastTimestamp: metav1.Now(),
+	}
+}
+
+func TestReconcilePodScheduling(t *testing.T) {
+	wObj := utils.MockWorkspaceWithPreset.DeepCopy()
+	longPending := mockPendingPod(wObj, "pod-long-pending", 10*time.Minute)
+	shortPending := mockPendingPod(wObj, "pod-short-pending", time.Minute)
+
+	testcases := map[string]struct {
+		conditions        []metav1.Condition
+		pods              []*corev1.Pod
+		events            []*corev1.Event
+		expectedStatus    metav1.ConditionStatus
+		expectedMessage   string
+		expectedRequeue   bool
+		expectNoCondition bool
+	}{
+		"Pod pending past the threshold with a FailedScheduling event": {
+			pods:            []*corev1.Pod{longPending},
+			events:          []*corev1.Event{mockFailedSchedulingEvent(longPending, "0/3 nodes are available: 3 node(s) had untolerated taint.")},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "1 inference pods have been unschedulable for more than 5m0s: 0/3 nodes are available: 3 node(s) had untolerated taint.",
+			expectedRequeue: true,
+		},
+		"Pod pending past the threshold without events": {
+			pods:            []*corev1.Pod{longPending},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "0/2 nodes are available: 2 Insufficient nvidia.com/gpu.",
+			expectedRequeue: true,
+		},
+		"Pod pending within the threshold": {
+			pods:              []*corev1.Pod{shortPending},
+			events:            []*corev1.Event{mockFailedSchedulingEvent(shortPending, "0/2 nodes are available")},
+			expectedRequeue:   true,
+			expectNoCondition: true,
+		},
+		"Reported failure is cleared once the pods are scheduled": {
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypePodsScheduled), Status: metav1.ConditionFalse, Reason: "PodsUnschedulable"},
+			},
+			expectedStatus: metav1.ConditionTrue,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := wObj.DeepCopy()
+			workspace.Status.Conditions = tc.conditions
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			for _, pod := range tc.pods {
+				podMap[client.ObjectKeyFromObject(pod)] = pod
+			}
+			eventMap := mockClient.CreateMapWithType(&corev1.EventList{})
+			for _, event := range tc.events {
+				eventMap[client.ObjectKeyFromObject(event)] = event
+			}
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.EventList{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			result, err := reconciler.reconcilePodScheduling(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(workspace)})
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, result.RequeueAfter > 0, tc.expectedRequeue)
+
+			if tc.expectNoCondition {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypePodsScheduled))
+			assert.Check(t, condition != nil, "expected the pods scheduled condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+			assert.Check(t, strings.Contains(condition.Message, tc.expectedMessage), "unexpected message: %s", condition.Message)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/machine"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// DefaultSKUReprobeInterval is how often the instance types of a workspace that could not be provisioned for lack of
+// capacity are checked again. The instance types that failed to launch are skipped for as long, so checking more often
+// has no effect.
+const DefaultSKUReprobeInterval = cloudprovider.DefaultSKUCacheTTL
+
+func (c *WorkspaceReconciler) skuReprobeInterval() time.Duration {
+	if c.SKUReprobeInterval <= 0 {
+		return DefaultSKUReprobeInterval
+	}
+	return c.SKUReprobeInterval
+}
+
+// instanceTypesUnavailable reports whether the last provisioning attempt of the workspace failed because none of
+// its instance types could be provisioned, e.g., they were out of capacity or quota in the region.
+func instanceTypesUnavailable(wObj *kaitov1alpha1.Workspace) bool {
+	condition := meta.FindStatusCondition(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeMachineStatus))
+	return condition != nil && condition.Status == metav1.ConditionFalse &&
+		(condition.Reason == "instanceTypesUnavailable" || condition.Message == machine.ErrorInstanceTypesUnavailable)
+}
+
+// reprobeInstanceTypes checks the instance types of a workspace that could not be provisioned for lack of capacity,
+// and returns how long the provisioning must wait for the capacity to return. Once an instance type has not failed to
+// launch for a while, or a fallback instance type has not been tried yet, the failure is cleared and the retry budget
+// is reset, so that a rate limited workspace is retried right away.
+func (c *WorkspaceReconciler) reprobeInstanceTypes(ctx context.Context, wObj *kaitov1alpha1.Workspace) (time.Duration, error) {
+	if !instanceTypesUnavailable(wObj) {
+		return 0, nil
+	}
+	available := cloudprovider.AvailableRegionSKUs(c.Region, machine.CandidateInstanceTypes(wObj), c.cloudProvider())
+	if len(available) == 0 {
+		klog.InfoS("instance types are still unavailable", "workspace", klog.KObj(wObj), "region", c.Region)
+		return c.skuReprobeInterval(), nil
+	}
+
+	message := fmt.Sprintf("instance types %s may be provisionable again, retrying the provisioning", strings.Join(available, ", "))
+	for _, cType := range []kaitov1alpha1.ConditionType{kaitov1alpha1.WorkspaceConditionTypeMachineStatus, kaitov1alpha1.WorkspaceConditionTypeReady} {
+		if err := c.updateStatusConditionIfNotMatch(ctx, wObj, cType, metav1.ConditionUnknown, "instanceTypesAvailable", message); err != nil {
+			klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return 0, err
+		}
+	}
+	if err := c.resetRetryBudget(ctx, wObj); err != nil {
+		klog.ErrorS(err, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return 0, err
+	}
+	// The retry budget of this reconciliation must not back off either.
+	wObj.Status.ProvisioningRetryBudget = nil
+	return 0, nil
+}
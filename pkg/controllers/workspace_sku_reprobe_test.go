@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReprobeInstanceTypes(t *testing.T) {
+	unavailable := metav1.Condition{
+		Type:    string(v1alpha1.WorkspaceConditionTypeMachineStatus),
+		Status:  metav1.ConditionFalse,
+		Reason:  "instanceTypesUnavailable",
+		Message: machine.ErrorInstanceTypesUnavailable,
+	}
+	rateLimited := metav1.Condition{
+		Type:   string(v1alpha1.WorkspaceConditionTypeRateLimited),
+		Status: metav1.ConditionTrue,
+		Reason: "retryBudgetExhausted",
+	}
+	testcases := map[string]struct {
+		region         string
+		failedToLaunch bool
+		conditions     []metav1.Condition
+		expectedWait   time.Duration
+		expectRetry    bool
+	}{
+		"Workspace that did not fail for lack of capacity is not retried": {
+			region: "reprobe-1",
+		},
+		"Instance types are still unavailable": {
+			region:         "reprobe-2",
+			failedToLaunch: true,
+			conditions:     []metav1.Condition{unavailable},
+			expectedWait:   DefaultSKUReprobeInterval,
+		},
+		"Unavailable instance type becomes available": {
+			region:      "reprobe-3",
+			conditions:  []metav1.Condition{unavailable},
+			expectRetry: true,
+		},
+		"Rate limited workspace is retried once the instance type is available": {
+			region: "reprobe-4",
+			conditions: []metav1.Condition{
+				{
+					Type:    string(v1alpha1.WorkspaceConditionTypeMachineStatus),
+					Status:  metav1.ConditionFalse,
+					Reason:  "machineFailedCreation",
+					Message: machine.ErrorInstanceTypesUnavailable,
+				},
+				rateLimited,
+			},
+			expectRetry: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Resource.InstanceType = "Standard_NC12s_v3"
+			workspace.Status.Conditions = tc.conditions
+			workspace.Status.ProvisioningRetryBudget = &v1alpha1.ProvisioningRetryBudget{
+				FailedAttempts: DefaultRetryBudget.MaxFailures,
+				WindowStart:    metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+			}
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			provider := &cloudprovider.AzureProvider{}
+			if tc.failedToLaunch {
+				cloudprovider.MarkRegionSKUUnavailable(tc.region, workspace.Resource.InstanceType, provider)
+			}
+			reconciler := &WorkspaceReconciler{
+				Client:        mockClient,
+				Scheme:        utils.NewTestScheme(),
+				CloudProvider: provider,
+				Region:        tc.region,
+			}
+
+			wait, err := reconciler.reprobeInstanceTypes(context.Background(), workspace)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, wait, tc.expectedWait)
+
+			if !tc.expectRetry {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			// The failure is cleared and the workspace is provisioning again.
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeMachineStatus))
+			assert.Equal(t, condition.Status, metav1.ConditionUnknown)
+			assert.Equal(t, condition.Reason, "instanceTypesAvailable")
+			updated = mockClient.StatusMock.Calls[len(mockClient.StatusMock.Calls)-1].Arguments.Get(1).(*v1alpha1.Workspace)
+			assert.Check(t, updated.Status.ProvisioningRetryBudget == nil, "expected the retry budget to be reset")
+
+			// The exhausted retry budget no longer backs off.
+			backoff, err := reconciler.checkRetryBudget(context.Background(), workspace)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, backoff, time.Duration(0))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// upgradeInstanceType replaces the instance type of the workspace with the next larger SKU of its family if the
+// preset models do not fit on it and the workspace allows the upgrade, so that the nodes are provisioned with the
+// larger SKU. The upgrade is recorded as an event of the workspace.
+func (c *WorkspaceReconciler) upgradeInstanceType(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	upgraded, found := wObj.UpgradedInstanceType()
+	if !found {
+		return nil
+	}
+
+	original := wObj.Resource.InstanceType
+	klog.InfoS("upgrading the instance type of the workspace", "workspace", klog.KObj(wObj), "from", original, "to", upgraded)
+	updateCopy := wObj.DeepCopy()
+	updateCopy.Resource.InstanceType = upgraded
+	if err := c.Client.Update(ctx, updateCopy, &client.UpdateOptions{}); err != nil {
+		klog.ErrorS(err, "failed to upgrade the instance type of the workspace", "workspace", klog.KObj(wObj))
+		return err
+	}
+	wObj.Resource.InstanceType = upgraded
+	c.recordEvent(wObj, corev1.EventTypeNormal, "InstanceTypeUpgraded",
+		fmt.Sprintf("Instance type %s is too small for the preset models, upgraded to %s", original, upgraded))
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/apis"
+)
+
+func TestUpgradeInstanceType(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		preset               string
+		expectedInstanceType string
+		expectUpgrade        bool
+		updateErr            error
+		expectedEvent        string
+	}{
+		"Instance type of a preset that does not fit is upgraded": {
+			preset:               "test-large-model",
+			expectedInstanceType: "Standard_NC12s_v3",
+			expectUpgrade:        true,
+			expectedEvent:        "Normal InstanceTypeUpgraded Instance type Standard_NC6s_v3 is too small for the preset models, upgraded to Standard_NC12s_v3",
+		},
+		"Failed upgrade is not recorded": {
+			preset:               "test-large-model",
+			updateErr:            errors.New("conflict"),
+			expectedInstanceType: "Standard_NC6s_v3",
+		},
+		"Instance type of a preset that fits is kept": {
+			preset:               "test-model",
+			expectedInstanceType: "Standard_NC6s_v3",
+		},
+		"Instance type of a distributed preset is kept": {
+			preset:               "test-large-distributed-model",
+			expectedInstanceType: "Standard_NC6s_v3",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(tc.updateErr)
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: recorder,
+			}
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Resource.InstanceType = "Standard_NC6s_v3"
+			workspace.Resource.Count = lo.ToPtr(1)
+			workspace.Resource.AllowSKUUpgrade = true
+			workspace.Inference.Preset.Name = v1alpha1.ModelName(tc.preset)
+			original := workspace.DeepCopy()
+
+			err := reconciler.upgradeInstanceType(context.Background(), workspace)
+			assert.Equal(t, err, tc.updateErr)
+			assert.Equal(t, workspace.Resource.InstanceType, tc.expectedInstanceType)
+
+			if !tc.expectUpgrade {
+				assert.Equal(t, len(recorder.Events), 0)
+				if tc.updateErr == nil {
+					mockClient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				}
+				return
+			}
+			mockClient.AssertNumberOfCalls(t, "Update", 1)
+			assert.Equal(t, len(recorder.Events), 1)
+			assert.Equal(t, <-recorder.Events, tc.expectedEvent)
+			updated := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*v1alpha1.Workspace)
+			assert.Equal(t, updated.Resource.InstanceType, tc.expectedInstanceType)
+
+			// The webhook must admit the upgraded workspace, or the update is rejected on every reconcile.
+			errs := updated.Validate(apis.WithinUpdate(context.Background(), original))
+			assert.Check(t, errs.Filter(apis.ErrorLevel) == nil, "Not expected the webhook to reject the upgrade: %v", errs)
+		})
+	}
+}
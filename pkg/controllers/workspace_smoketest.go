@@ -0,0 +1,72 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/inference"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// errSmokeTestFailed is joined to the error of a smoke test whose inference request the model server did not answer
+// with a valid response.
+var errSmokeTestFailed = errors.New("inference smoke test failed")
+
+// inferenceEndpoint returns the endpoint the smoke test of the workspace is run against. It is a variable for tests.
+var inferenceEndpoint = inference.InferenceEndpoint
+
+// runInferenceSmokeTest runs the canned inference request of the preset of the workspace against its endpoint if
+// smoke tests are enabled. The smoke test is run until the workspace is ready, not again for the same generation.
+// A failed smoke test is retried by a later reconcile.
+// Only the preset models served by the transformers runtime are tested, the other runtimes and the custom Pod
+// templates serve other APIs.
+func (c *WorkspaceReconciler) runInferenceSmokeTest(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	if !c.InferenceSmokeTest || wObj.Inference == nil || wObj.Inference.Preset == nil || len(wObj.Inference.Variants) != 0 ||
+		wObj.Inference.GetRuntime() != kaitov1alpha1.InferenceRuntimeTransformers {
+		return nil
+	}
+	if condition := meta.FindStatusCondition(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeReady)); condition != nil &&
+		condition.Status == metav1.ConditionTrue && condition.ObservedGeneration == wObj.Generation {
+		return nil
+	}
+
+	token, err := c.inferenceAuthToken(ctx, wObj)
+	if err != nil {
+		return err
+	}
+	model := plugin.KaitoModelRegister.MustGet(string(wObj.Inference.Preset.Name))
+	if err := inference.RunInferenceSmokeTest(ctx, inferenceEndpoint(wObj), token, model.GetInferenceParameters()); err != nil {
+		klog.ErrorS(err, "inference smoke test failed", "workspace", klog.KObj(wObj))
+		return fmt.Errorf("%w: %v", errSmokeTestFailed, err)
+	}
+	klog.InfoS("inference smoke test succeeded", "workspace", klog.KObj(wObj))
+	return nil
+}
+
+// inferenceAuthToken returns the bearer token that the inference endpoint of the workspace requires, or an empty
+// token if the endpoint requires none.
+func (c *WorkspaceReconciler) inferenceAuthToken(ctx context.Context, wObj *kaitov1alpha1.Workspace) (string, error) {
+	auth := wObj.Inference.Auth
+	if auth == nil {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	if err := resources.GetResource(ctx, auth.SecretName, wObj.Namespace, c.Client, secret); err != nil {
+		klog.ErrorS(err, "failed to get the auth secret of the inference endpoint", "workspace", klog.KObj(wObj), "secret", auth.SecretName)
+		return "", err
+	}
+	token, found := secret.Data[auth.GetSecretKey()]
+	if !found {
+		return "", fmt.Errorf("auth secret %s has no key %s", auth.SecretName, auth.GetSecretKey())
+	}
+	return string(token), nil
+}
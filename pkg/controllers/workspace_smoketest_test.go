@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunInferenceSmokeTest(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		enabled         bool
+		runtime         v1alpha1.InferenceRuntime
+		auth            bool
+		conditions      []metav1.Condition
+		statusCode      int
+		expectRequest   bool
+		expectSmokeFail bool
+	}{
+		"Smoke test is disabled": {
+			statusCode: http.StatusInternalServerError,
+		},
+		"Model server answers the smoke test": {
+			enabled:       true,
+			statusCode:    http.StatusOK,
+			expectRequest: true,
+		},
+		"Model server fails the smoke test": {
+			enabled:         true,
+			statusCode:      http.StatusInternalServerError,
+			expectRequest:   true,
+			expectSmokeFail: true,
+		},
+		"Smoke test sends the bearer token of the endpoint": {
+			enabled:       true,
+			auth:          true,
+			statusCode:    http.StatusOK,
+			expectRequest: true,
+		},
+		"Ready workspace is not tested again": {
+			enabled: true,
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeReady), Status: metav1.ConditionTrue, Reason: "workspaceReady", ObservedGeneration: 1},
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		"Runtime other than transformers is not tested": {
+			enabled:    true,
+			runtime:    v1alpha1.InferenceRuntimeVLLM,
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			requested := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = true
+				if authorization := r.Header.Get("Authorization"); authorization != lo.Ternary(tc.auth, "Bearer secret-token", "") {
+					t.Errorf("smoke test request has the authorization %q", authorization)
+				}
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(`{"Result": "Kubernetes is a container orchestrator."}`))
+			}))
+			defer server.Close()
+			defer func(endpoint func(*v1alpha1.Workspace) string) { inferenceEndpoint = endpoint }(inferenceEndpoint)
+			inferenceEndpoint = func(*v1alpha1.Workspace) string { return server.URL }
+
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Generation = 1
+			workspace.Inference.Runtime = tc.runtime
+			workspace.Status.Conditions = tc.conditions
+			mockClient := utils.NewClient()
+			if tc.auth {
+				workspace.Inference.Auth = &v1alpha1.InferenceAuth{SecretName: "inference-token"}
+				mockClient.CreateOrUpdateObjectInMap(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "inference-token", Namespace: workspace.Namespace},
+					Data:       map[string][]byte{v1alpha1.DefaultAuthTokenKey: []byte("secret-token")},
+				})
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Secret{}), mock.Anything).Return(nil)
+			}
+			reconciler := &WorkspaceReconciler{
+				Client:             mockClient,
+				Scheme:             utils.NewTestScheme(),
+				InferenceSmokeTest: tc.enabled,
+			}
+
+			err := reconciler.runInferenceSmokeTest(context.Background(), workspace)
+			assert.Equal(t, requested, tc.expectRequest)
+			assert.Equal(t, errors.Is(err, errSmokeTestFailed), tc.expectSmokeFail)
+			if !tc.expectSmokeFail {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// updateSpecWarningsCondition reports the risky spec choices of the workspace, see Workspace.Warnings, in the
+// SpecWarnings condition. A warning event is only recorded for each warning when the warnings change, not on every
+// reconcile. The condition is only set to False once it has been True.
+func (c *WorkspaceReconciler) updateSpecWarningsCondition(ctx context.Context, wObj *kaitov1alpha1.Workspace) error {
+	warnings := wObj.Warnings()
+	if len(warnings) > 0 {
+		message := strings.Join(warnings, "; ")
+		if !conditionMatches(wObj, kaitov1alpha1.WorkspaceConditionTypeSpecWarnings, metav1.ConditionTrue, "WorkspaceSpecWarning", message) {
+			for _, warning := range warnings {
+				c.recordEvent(wObj, corev1.EventTypeWarning, "WorkspaceSpecWarning", warning)
+			}
+		}
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeSpecWarnings, metav1.ConditionTrue,
+			"WorkspaceSpecWarning", message)
+	}
+	if meta.IsStatusConditionTrue(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeSpecWarnings)) {
+		return c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeSpecWarnings, metav1.ConditionFalse,
+			"NoSpecWarnings", "the workspace spec carries no warnings")
+	}
+	return nil
+}
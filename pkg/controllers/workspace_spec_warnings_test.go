@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestUpdateSpecWarningsCondition(t *testing.T) {
+	spotTuningMessage := "Tuning on spot instances is not recommended, the tuning job loses its progress if the nodes are evicted"
+
+	testcases := map[string]struct {
+		spotTuning     bool
+		conditions     []metav1.Condition
+		expectedStatus metav1.ConditionStatus
+		expectedEvents []string
+		expectNoUpdate bool
+	}{
+		"New warnings are recorded once": {
+			spotTuning:     true,
+			expectedStatus: metav1.ConditionTrue,
+			expectedEvents: []string{corev1.EventTypeWarning + " WorkspaceSpecWarning " + spotTuningMessage},
+		},
+		"Warnings already reported are not recorded again": {
+			spotTuning: true,
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeSpecWarnings), Status: metav1.ConditionTrue, Reason: "WorkspaceSpecWarning", Message: spotTuningMessage},
+			},
+			expectNoUpdate: true,
+		},
+		"Resolved warnings clear the condition": {
+			conditions: []metav1.Condition{
+				{Type: string(v1alpha1.WorkspaceConditionTypeSpecWarnings), Status: metav1.ConditionTrue, Reason: "WorkspaceSpecWarning", Message: spotTuningMessage},
+			},
+			expectedStatus: metav1.ConditionFalse,
+		},
+		"Workspace that never had warnings is not reported": {
+			expectNoUpdate: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			if tc.spotTuning {
+				workspace.Inference = nil
+				workspace.Tuning = &v1alpha1.TuningSpec{}
+				workspace.Resource.LabelSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{v1alpha5.LabelCapacityType: v1alpha5.CapacityTypeSpot},
+				}
+			}
+			workspace.Status.Conditions = tc.conditions
+			mockClient.CreateOrUpdateObjectInMap(workspace)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: recorder,
+			}
+
+			assert.NilError(t, reconciler.updateSpecWarningsCondition(context.Background(), workspace))
+			assert.Equal(t, len(recorder.Events), len(tc.expectedEvents))
+			for _, expected := range tc.expectedEvents {
+				assert.Equal(t, <-recorder.Events, expected)
+			}
+			if tc.expectNoUpdate {
+				mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.StatusMock.Calls[0].Arguments.Get(1).(*v1alpha1.Workspace)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, string(v1alpha1.WorkspaceConditionTypeSpecWarnings))
+			assert.Check(t, condition != nil, "expected the SpecWarnings condition to be set")
+			assert.Equal(t, condition.Status, tc.expectedStatus)
+		})
+	}
+}
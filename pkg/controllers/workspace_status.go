@@ -19,37 +19,45 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// updateWorkspaceStatus applies the condition and the worker nodes to the latest version of the workspace status.
+// A conflicting update, e.g., by a concurrent reconcile, is retried on the refreshed workspace.
 func (c *WorkspaceReconciler) updateWorkspaceStatus(ctx context.Context, name *client.ObjectKey, condition *metav1.Condition, workerNodes []string) error {
-	return retry.OnError(retry.DefaultRetry,
-		func(err error) bool {
-			return apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err)
-		},
-		func() error {
-			// Read the latest version to avoid update conflict.
-			wObj := &kaitov1alpha1.Workspace{}
-			if err := c.Client.Get(ctx, *name, wObj); err != nil {
-				if !errors.IsNotFound(err) {
-					return err
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return retry.OnError(retry.DefaultRetry,
+			func(err error) bool {
+				return apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err)
+			},
+			func() error {
+				// Read the latest version to avoid update conflict.
+				wObj := &kaitov1alpha1.Workspace{}
+				if err := c.Client.Get(ctx, *name, wObj); err != nil {
+					if !errors.IsNotFound(err) {
+						return err
+					}
+					return nil
 				}
-				return nil
-			}
-			if condition != nil {
-				meta.SetStatusCondition(&wObj.Status.Conditions, *condition)
-			}
-			if workerNodes != nil {
-				wObj.Status.WorkerNodes = workerNodes
-			}
-			return c.Client.Status().Update(ctx, wObj)
-		})
+				if condition != nil {
+					meta.SetStatusCondition(&wObj.Status.Conditions, *condition)
+				}
+				if workerNodes != nil {
+					wObj.Status.WorkerNodes = workerNodes
+				}
+				return c.Client.Status().Update(ctx, wObj)
+			})
+	})
+}
+
+// conditionMatches returns true if the workspace carries the condition with the given status, reason and message.
+func conditionMatches(wObj *kaitov1alpha1.Workspace, cType kaitov1alpha1.ConditionType, cStatus metav1.ConditionStatus, cReason, cMessage string) bool {
+	curCondition := meta.FindStatusCondition(wObj.Status.Conditions, string(cType))
+	return curCondition != nil && curCondition.Status == cStatus && curCondition.Reason == cReason && curCondition.Message == cMessage
 }
 
 func (c *WorkspaceReconciler) updateStatusConditionIfNotMatch(ctx context.Context, wObj *kaitov1alpha1.Workspace, cType kaitov1alpha1.ConditionType,
 	cStatus metav1.ConditionStatus, cReason, cMessage string) error {
-	if curCondition := meta.FindStatusCondition(wObj.Status.Conditions, string(cType)); curCondition != nil {
-		if curCondition.Status == cStatus && curCondition.Reason == cReason && curCondition.Message == cMessage {
-			// Nonthing to change
-			return nil
-		}
+	if conditionMatches(wObj, cType, cStatus, cReason, cMessage) {
+		// Nonthing to change
+		return nil
 	}
 	klog.InfoS("updateStatusCondition", "workspace", klog.KObj(wObj), "conditionType", cType, "status", cStatus, "reason", cReason, "message", cMessage)
 	cObj := metav1.Condition{
@@ -74,3 +82,35 @@ func (c *WorkspaceReconciler) updateStatusNodeListIfNotMatch(ctx context.Context
 	klog.InfoS("updateStatusNodeList", "workspace", klog.KObj(wObj))
 	return c.updateWorkspaceStatus(ctx, &client.ObjectKey{Name: wObj.Name, Namespace: wObj.Namespace}, nil, nodeNameList)
 }
+
+// provisioningProgress returns the percentage of the desired nodes that are ready. A workspace that needs no nodes
+// has nothing left to provision, and the nodes beyond the desired count do not add to the progress.
+func provisioningProgress(readyNodes, desiredNodes int) int32 {
+	if desiredNodes <= 0 {
+		return 100
+	}
+	return int32(lo.Min([]int{readyNodes, desiredNodes}) * 100 / desiredNodes)
+}
+
+func (c *WorkspaceReconciler) updateStatusProvisioningProgressIfNotMatch(ctx context.Context, wObj *kaitov1alpha1.Workspace, readyNodes, desiredNodes int) error {
+	progress := provisioningProgress(readyNodes, desiredNodes)
+	if wObj.Status.ProvisioningProgress != nil && *wObj.Status.ProvisioningProgress == progress {
+		return nil
+	}
+	klog.InfoS("updateStatusProvisioningProgress", "workspace", klog.KObj(wObj), "readyNodes", readyNodes, "desiredNodes", desiredNodes, "progress", progress)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &kaitov1alpha1.Workspace{}
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(wObj), latest); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		latest.Status.ProvisioningProgress = &progress
+		if err := c.Client.Status().Update(ctx, latest); err != nil {
+			return err
+		}
+		wObj.Status.ProvisioningProgress = &progress
+		return nil
+	})
+}
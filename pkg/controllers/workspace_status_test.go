@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+)
+
+func TestUpdateStatusConditionIfNotMatchRetriesOnConflict(t *testing.T) {
+	conflictErr := apierrors.NewConflict(schema.GroupResource{Group: "kaito.sh", Resource: "workspaces"}, "testWorkspace", errors.New("the object has been modified"))
+	testcases := map[string]struct {
+		callMocks           func(c *utils.MockClient)
+		expectedUpdateCalls int
+		expectedError       error
+	}{
+		"Conflict on the first attempt is retried": {
+			callMocks: func(c *utils.MockClient) {
+				c.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(conflictErr).Once()
+				c.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			},
+			expectedUpdateCalls: 2,
+		},
+		"Persistent conflict fails after the retries": {
+			callMocks: func(c *utils.MockClient) {
+				c.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(conflictErr)
+			},
+			expectedUpdateCalls: retry.DefaultRetry.Steps,
+			expectedError:       conflictErr,
+		},
+		"Other errors are not retried": {
+			callMocks: func(c *utils.MockClient) {
+				c.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(errors.New("forbidden"))
+			},
+			expectedUpdateCalls: 1,
+			expectedError:       errors.New("forbidden"),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			tc.callMocks(mockClient)
+
+			reconciler := &WorkspaceReconciler{
+				Client: mockClient,
+				Scheme: utils.NewTestScheme(),
+			}
+
+			err := reconciler.updateStatusConditionIfNotMatch(context.Background(), utils.MockWorkspaceWithPreset,
+				v1alpha1.WorkspaceConditionTypeReady, metav1.ConditionTrue, "workspaceReady", "workspace is ready")
+			if tc.expectedError == nil {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Equal(t, tc.expectedError.Error(), err.Error())
+			}
+			mockClient.StatusMock.AssertNumberOfCalls(t, "Update", tc.expectedUpdateCalls)
+			// The workspace is read again before every attempt.
+			mockClient.AssertNumberOfCalls(t, "Get", tc.expectedUpdateCalls)
+		})
+	}
+}
+
+func TestProvisioningProgress(t *testing.T) {
+	testcases := map[string]struct {
+		readyNodes       int
+		desiredNodes     int
+		expectedProgress int32
+	}{
+		"No node is ready": {
+			readyNodes:       0,
+			desiredNodes:     4,
+			expectedProgress: 0,
+		},
+		"Half of the nodes are ready": {
+			readyNodes:       1,
+			desiredNodes:     2,
+			expectedProgress: 50,
+		},
+		"All nodes are ready": {
+			readyNodes:       3,
+			desiredNodes:     3,
+			expectedProgress: 100,
+		},
+		"Progress is rounded down": {
+			readyNodes:       2,
+			desiredNodes:     3,
+			expectedProgress: 66,
+		},
+		"Nodes beyond the desired count do not exceed 100": {
+			readyNodes:       3,
+			desiredNodes:     2,
+			expectedProgress: 100,
+		},
+		"Workspace that needs no nodes is complete": {
+			readyNodes:       0,
+			desiredNodes:     0,
+			expectedProgress: 100,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			assert.Equal(t, provisioningProgress(tc.readyNodes, tc.desiredNodes), tc.expectedProgress)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxSummaryEvents is the number of most recent events in the status summary of a workspace.
+const maxSummaryEvents = 10
+
+// WorkspaceSummary is the machine-readable status of a workspace, e.g., for `kaito status -o json`. It gathers the
+// state spread across the workspace, its machines, its service and its events, so that a client needs a single call.
+type WorkspaceSummary struct {
+	Workspace  string             `json:"workspace"`
+	Conditions []metav1.Condition `json:"conditions"`
+	Machines   []MachineSummary   `json:"machines"`
+	Endpoint   *EndpointSummary   `json:"endpoint,omitempty"`
+	Events     []EventSummary     `json:"events"`
+}
+
+// MachineSummary is the state of a machine of the workspace.
+type MachineSummary struct {
+	Name         string `json:"name"`
+	InstanceType string `json:"instanceType"`
+	NodeName     string `json:"nodeName,omitempty"`
+	Ready        bool   `json:"ready"`
+	// Message explains why the machine is not ready, if it is known.
+	Message  string `json:"message,omitempty"`
+	Deleting bool   `json:"deleting,omitempty"`
+}
+
+// EndpointSummary is the endpoint the inference service of the workspace serves on.
+type EndpointSummary struct {
+	// URL is the endpoint within the cluster.
+	URL string `json:"url"`
+	// ExternalURL is the endpoint of the load balancer of the service, once it is assigned.
+	ExternalURL string `json:"externalURL,omitempty"`
+	// AuthRequired is true if the requests need a bearer token.
+	AuthRequired bool `json:"authRequired"`
+}
+
+// EventSummary is an event of the workspace or of one of its machines.
+type EventSummary struct {
+	Type          string      `json:"type"`
+	Reason        string      `json:"reason"`
+	Object        string      `json:"object"`
+	Message       string      `json:"message"`
+	Count         int32       `json:"count,omitempty"`
+	LastTimestamp metav1.Time `json:"lastTimestamp"`
+}
+
+// SummarizeWorkspace compiles the conditions of the workspace, the states of its machines, the endpoint of its
+// inference service and its most recent events, the newest first. The endpoint is omitted for a tuning workspace,
+// and until the service is created.
+func SummarizeWorkspace(ctx context.Context, wObj *kaitov1alpha1.Workspace, kubeClient client.Client,
+	provider cloudprovider.CloudProvider) (*WorkspaceSummary, error) {
+	summary := &WorkspaceSummary{
+		Workspace:  fmt.Sprintf("%s/%s", wObj.Namespace, wObj.Name),
+		Conditions: lo.Ternary(wObj.Status.Conditions != nil, wObj.Status.Conditions, []metav1.Condition{}),
+		Machines:   []MachineSummary{},
+		Events:     []EventSummary{},
+	}
+
+	machines, err := machine.ListMachinesByWorkspace(ctx, wObj, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(machines.Items, func(i, j int) bool { return machines.Items[i].Name < machines.Items[j].Name })
+	machineUIDs := make(map[types.UID]bool, len(machines.Items))
+	for i := range machines.Items {
+		summary.Machines = append(summary.Machines, summarizeMachine(&machines.Items[i], provider))
+		machineUIDs[machines.Items[i].UID] = true
+	}
+
+	if wObj.Inference != nil {
+		if summary.Endpoint, err = summarizeEndpoint(ctx, wObj, kubeClient); err != nil {
+			return nil, err
+		}
+	}
+
+	eventList := &corev1.EventList{}
+	if err := kubeClient.List(ctx, eventList, client.InNamespace(wObj.Namespace)); err != nil {
+		return nil, err
+	}
+	var events []*corev1.Event
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		involved := event.InvolvedObject
+		ofWorkspace := involved.Kind == "Workspace" && involved.Name == wObj.Name && (wObj.UID == "" || involved.UID == wObj.UID)
+		if ofWorkspace || (involved.Kind == "Machine" && machineUIDs[involved.UID]) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return eventTime(events[i]).After(eventTime(events[j])) })
+	for _, event := range lo.Slice(events, 0, maxSummaryEvents) {
+		summary.Events = append(summary.Events, EventSummary{
+			Type:          event.Type,
+			Reason:        event.Reason,
+			Object:        fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name),
+			Message:       event.Message,
+			Count:         event.Count,
+			LastTimestamp: metav1.NewTime(eventTime(event)),
+		})
+	}
+	return summary, nil
+}
+
+// summarizeMachine returns the state of the machine. The message of its first false condition explains why it is not
+// ready, e.g., that the instance type is out of capacity.
+func summarizeMachine(m *v1alpha5.Machine, provider cloudprovider.CloudProvider) MachineSummary {
+	summary := MachineSummary{
+		Name:         m.Name,
+		InstanceType: machine.MachineInstanceType(m, provider),
+		NodeName:     m.Status.NodeName,
+		Deleting:     m.DeletionTimestamp != nil,
+	}
+	_, summary.Ready = lo.Find(m.GetConditions(), func(condition apis.Condition) bool {
+		return condition.Type == apis.ConditionReady && condition.Status == corev1.ConditionTrue
+	})
+	if !summary.Ready {
+		if condition, found := lo.Find(m.GetConditions(), func(condition apis.Condition) bool {
+			return condition.Status == corev1.ConditionFalse && condition.Message != ""
+		}); found {
+			summary.Message = condition.Message
+		}
+	}
+	return summary
+}
+
+// summarizeEndpoint returns the endpoint of the service of the workspace, or nil if it does not exist yet.
+func summarizeEndpoint(ctx context.Context, wObj *kaitov1alpha1.Workspace, kubeClient client.Client) (*EndpointSummary, error) {
+	service := &corev1.Service{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: wObj.Name, Namespace: wObj.Namespace}, service); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	port := int32(80)
+	if httpPort, found := lo.Find(service.Spec.Ports, func(p corev1.ServicePort) bool { return p.Name == "http" }); found {
+		port = httpPort.Port
+	}
+
+	endpoint := &EndpointSummary{
+		URL:          fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", service.Name, service.Namespace, port),
+		AuthRequired: wObj.Inference.Auth != nil,
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if host := lo.Ternary(ingress.IP != "", ingress.IP, ingress.Hostname); host != "" {
+			endpoint.ExternalURL = fmt.Sprintf("http://%s:%d", host, port)
+			break
+		}
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSummarizeWorkspace(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.UID = "workspace-uid"
+	workspace.Status.Conditions = []metav1.Condition{
+		{Type: string(v1alpha1.WorkspaceConditionTypeResourceStatus), Status: metav1.ConditionFalse, Reason: "workspaceResourceStatusFailed"},
+	}
+
+	mockClient := utils.NewClient()
+	readyMachine := utils.MockMachine.DeepCopy()
+	readyMachine.Name = "ws-ready"
+	readyMachine.UID = "ready-machine-uid"
+	readyMachine.Status.NodeName = "node-1"
+	readyMachine.Status.Conditions = apis.Conditions{{Type: apis.ConditionReady, Status: corev1.ConditionTrue}}
+	pendingMachine := utils.MockMachine.DeepCopy()
+	pendingMachine.Name = "ws-pending"
+	pendingMachine.UID = "pending-machine-uid"
+	pendingMachine.Status.Conditions = apis.Conditions{
+		{Type: apis.ConditionReady, Status: corev1.ConditionFalse},
+		{Type: v1alpha5.MachineLaunched, Status: corev1.ConditionFalse, Message: "the instance type is out of capacity"},
+	}
+	machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+	machineMap[client.ObjectKeyFromObject(readyMachine)] = readyMachine
+	machineMap[client.ObjectKeyFromObject(pendingMachine)] = pendingMachine
+
+	mockClient.CreateOrUpdateObjectInMap(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: workspace.Name, Namespace: workspace.Namespace},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "torch", Port: 29500}, {Name: "http", Port: 80}},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "20.1.2.3"}}},
+		},
+	})
+
+	now := time.Now()
+	eventMap := mockClient.CreateMapWithType(&corev1.EventList{})
+	for _, event := range []*corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "workspace-event", Namespace: workspace.Namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Workspace", Name: workspace.Name, UID: workspace.UID},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "ResourceQuotaExceeded",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Hour)),
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "machine-event", Namespace: workspace.Namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Machine", Name: pendingMachine.Name, UID: pendingMachine.UID},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "LaunchFailed",
+			LastTimestamp:  metav1.NewTime(now),
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "other-event", Namespace: workspace.Namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Workspace", Name: "other-workspace", UID: "other-uid"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         "Created",
+			LastTimestamp:  metav1.NewTime(now),
+		},
+	} {
+		eventMap[client.ObjectKeyFromObject(event)] = event
+	}
+
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.EventList{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Service{}), mock.Anything).Return(nil)
+
+	summary, err := SummarizeWorkspace(context.Background(), workspace, mockClient, &cloudprovider.AzureProvider{})
+	assert.NilError(t, err)
+
+	assert.Equal(t, summary.Workspace, workspace.Namespace+"/"+workspace.Name)
+	assert.DeepEqual(t, summary.Conditions, workspace.Status.Conditions)
+	assert.DeepEqual(t, summary.Machines, []MachineSummary{
+		{Name: "ws-pending", InstanceType: "Standard_NC12s_v3", Message: "the instance type is out of capacity"},
+		{Name: "ws-ready", InstanceType: "Standard_NC12s_v3", NodeName: "node-1", Ready: true},
+	})
+	assert.DeepEqual(t, summary.Endpoint, &EndpointSummary{
+		URL:         "http://testWorkspace.kaito.svc.cluster.local:80",
+		ExternalURL: "http://20.1.2.3:80",
+	})
+	assert.Equal(t, len(summary.Events), 2)
+	assert.Equal(t, summary.Events[0].Reason, "LaunchFailed")
+	assert.Equal(t, summary.Events[0].Object, "Machine/ws-pending")
+	assert.Equal(t, summary.Events[1].Reason, "ResourceQuotaExceeded")
+
+	// The summary is serializable for the clients.
+	encoded, err := json.Marshal(summary)
+	assert.NilError(t, err)
+	decoded := &WorkspaceSummary{}
+	assert.NilError(t, json.Unmarshal(encoded, decoded))
+	assert.Equal(t, decoded.Endpoint.ExternalURL, summary.Endpoint.ExternalURL)
+	assert.Equal(t, len(decoded.Machines), 2)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/tuning"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// validateTuningDataset checks the datasets of the tuning input before the nodes of the tuning job are provisioned,
+// if the workspace asks for it, see tuning.ValidateInputDataset. The datasets are sampled once per generation of the
+// workspace, the result is kept in the TuningDatasetValid condition. It returns false if the datasets are malformed,
+// the workspace then waits until its spec is changed. An error is returned if the datasets could not be sampled.
+func (c *WorkspaceReconciler) validateTuningDataset(ctx context.Context, wObj *kaitov1alpha1.Workspace) (bool, error) {
+	if wObj.Tuning == nil || !wObj.Tuning.ValidateDataset {
+		return true, nil
+	}
+	cond := meta.FindStatusCondition(wObj.Status.Conditions, string(kaitov1alpha1.WorkspaceConditionTypeTuningDatasetValid))
+	if cond != nil && cond.ObservedGeneration == wObj.GetGeneration() {
+		return cond.Status == metav1.ConditionTrue, nil
+	}
+
+	columns, err := tuning.GetDatasetColumns(ctx, wObj, c.Client)
+	if err == nil {
+		err = tuning.ValidateInputDataset(ctx, wObj, columns)
+	}
+	if err == nil {
+		if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeTuningDatasetValid, metav1.ConditionTrue,
+			"tuningDatasetValid", "The sampled tuning datasets match the schema of the tuning job"); updateErr != nil {
+			klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+			return false, updateErr
+		}
+		return true, nil
+	}
+	var invalidErr *tuning.InvalidDatasetError
+	if !errors.As(err, &invalidErr) {
+		klog.ErrorS(err, "failed to validate the tuning dataset", "workspace", klog.KObj(wObj))
+		return false, err
+	}
+
+	klog.ErrorS(err, "the tuning dataset is invalid", "workspace", klog.KObj(wObj))
+	c.recordEvent(wObj, corev1.EventTypeWarning, "TuningDatasetInvalid", err.Error())
+	if updateErr := c.updateStatusConditionIfNotMatch(ctx, wObj, kaitov1alpha1.WorkspaceConditionTypeTuningDatasetValid, metav1.ConditionFalse,
+		"tuningDatasetInvalid", err.Error()); updateErr != nil {
+		klog.ErrorS(updateErr, "failed to update workspace status", "workspace", klog.KObj(wObj))
+		return false, updateErr
+	}
+	return false, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestValidateTuningDataset(t *testing.T) {
+	testcases := map[string]struct {
+		condition     *metav1.Condition
+		expectedValid bool
+		expectedError string
+	}{
+		"Valid dataset of the current generation is not sampled again": {
+			condition: &metav1.Condition{
+				Type:               string(v1alpha1.WorkspaceConditionTypeTuningDatasetValid),
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: 2,
+			},
+			expectedValid: true,
+		},
+		"Invalid dataset of the current generation is not sampled again": {
+			condition: &metav1.Condition{
+				Type:               string(v1alpha1.WorkspaceConditionTypeTuningDatasetValid),
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: 2,
+			},
+		},
+		"Dataset of a previous generation is sampled again": {
+			condition: &metav1.Condition{
+				Type:               string(v1alpha1.WorkspaceConditionTypeTuningDatasetValid),
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: 1,
+			},
+			expectedError: "the address 127.0.0.1 is not public",
+		},
+		"Dataset on a private address is not sampled": {
+			expectedError: "the address 127.0.0.1 is not public",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.StatusMock.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha1.Workspace{}), mock.Anything).Return(nil)
+			reconciler := &WorkspaceReconciler{
+				Client:   mockClient,
+				Scheme:   utils.NewTestScheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Generation = 2
+			workspace.Tuning = &v1alpha1.TuningSpec{
+				Method:          v1alpha1.TuningMethodLora,
+				Input:           &v1alpha1.DataSource{URLs: []string{"http://127.0.0.1:8080/dataset.jsonl"}},
+				ValidateDataset: true,
+			}
+			if tc.condition != nil {
+				workspace.Status.Conditions = []metav1.Condition{*tc.condition}
+			}
+
+			valid, err := reconciler.validateTuningDataset(context.Background(), workspace)
+			assert.Equal(t, valid, tc.expectedValid)
+			if tc.expectedError != "" {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "expected error %q, got %v", tc.expectedError, err)
+			} else {
+				assert.Check(t, err == nil, "Not expected to return error")
+			}
+			// Neither the cached result nor a failure to sample the dataset changes the condition.
+			mockClient.StatusMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
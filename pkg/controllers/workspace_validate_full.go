@@ -0,0 +1,76 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/quota"
+	"github.com/samber/lo"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The checks of ValidateWorkspaceFull.
+const (
+	// CheckSpec is the validation of the webhook, e.g., of the presets and the instance type.
+	CheckSpec = "spec"
+	// CheckLabelSelector checks that the labelSelector matches the nodes provisioned for the workspace.
+	CheckLabelSelector = "labelSelector"
+	// CheckGPUQuota checks the GPU quota of the team of the workspace.
+	CheckGPUQuota = "gpuQuota"
+	// CheckResourceQuota checks the GPU requests the ResourceQuotas of the namespace leave for the pods.
+	CheckResourceQuota = "resourceQuota"
+	// CheckProvisionerLimits checks the resource limits of the provisioner the machines are created with.
+	CheckProvisionerLimits = "provisionerLimits"
+)
+
+// Problem is an issue that keeps a workspace from being admitted or from being provisioned.
+type Problem struct {
+	// Check is the check that found the problem, e.g., CheckSpec.
+	Check string
+	// Field is the field of the workspace the problem is in, if known.
+	Field string
+	// Message describes the problem.
+	Message string
+}
+
+// ValidateWorkspaceFull runs the validation of the webhook and the checks of the cluster state that precede the
+// provisioning of the nodes against the workspace, e.g., in CI, and returns the problems they found. It has no side
+// effects, the workspace is neither created nor changed. A check that fails to read the cluster state reports the
+// failure as a problem. The ResourceQuotas are only checked if the spec is valid, since the pods of the workspace
+// are rendered to check them.
+func ValidateWorkspaceFull(ctx context.Context, wObj *kaitov1alpha1.Workspace, kubeClient client.Client) []Problem {
+	// The workspace is validated as the webhook admits it, after its defaults are set.
+	workspace := wObj.DeepCopy()
+	workspace.SetDefaults(ctx)
+
+	var problems []Problem
+	if errs := workspace.Validate(apis.WithinCreate(ctx)).Filter(apis.ErrorLevel); errs != nil {
+		for _, fieldErr := range errs.WrappedErrors() {
+			problems = append(problems, Problem{Check: CheckSpec, Field: strings.Join(fieldErr.Paths, ", "), Message: fieldErr.Message})
+		}
+	}
+	specValid := len(problems) == 0
+
+	c := &WorkspaceReconciler{Client: kubeClient}
+	if err := machine.ValidateLabelSelectorMatchesNodes(workspace, c.cloudProvider()); err != nil {
+		problems = append(problems, Problem{Check: CheckLabelSelector, Field: "resource.labelSelector", Message: err.Error()})
+	}
+	if err := quota.CheckGPUQuota(ctx, workspace, kubeClient); err != nil {
+		problems = append(problems, Problem{Check: CheckGPUQuota, Message: err.Error()})
+	}
+	if specValid {
+		if err := c.checkResourceQuotaHeadroom(ctx, workspace); err != nil {
+			problems = append(problems, Problem{Check: CheckResourceQuota, Message: err.Error()})
+		}
+	}
+	if err := machine.CheckProvisionerLimits(ctx, workspace, workspace.Resource.InstanceType, lo.FromPtr(workspace.Resource.Count),
+		c.cloudProvider(), kubeClient); err != nil {
+		problems = append(problems, Problem{Check: CheckProvisionerLimits, Field: "resource.count", Message: err.Error()})
+	}
+	return problems
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestValidateWorkspaceFull(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		workspace      func(*v1alpha1.Workspace)
+		maxGPUs        int
+		provisionerErr error
+		expectedChecks []string
+	}{
+		"Valid workspace has no problems": {
+			workspace: func(w *v1alpha1.Workspace) {},
+		},
+		"Workspace exceeding the GPU quota": {
+			workspace:      func(w *v1alpha1.Workspace) {},
+			maxGPUs:        1,
+			expectedChecks: []string{CheckGPUQuota},
+		},
+		"Workspace with several problems": {
+			workspace: func(w *v1alpha1.Workspace) {
+				w.Inference.Runtime = "unknown"
+				w.Resource.LabelSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"apps": "test"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "apps", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"test"}},
+					},
+				}
+			},
+			maxGPUs:        1,
+			provisionerErr: errors.New("connection refused"),
+			expectedChecks: []string{CheckSpec, CheckLabelSelector, CheckGPUQuota, CheckProvisionerLimits},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			tc.workspace(workspace)
+			if tc.maxGPUs > 0 {
+				quotaObj := &v1alpha1.GPUQuota{
+					ObjectMeta: metav1.ObjectMeta{Name: "team-quota", Namespace: workspace.Namespace},
+					Spec:       v1alpha1.GPUQuotaSpec{MaxGPUs: tc.maxGPUs},
+				}
+				mockClient.CreateMapWithType(&v1alpha1.GPUQuotaList{})[client.ObjectKeyFromObject(quotaObj)] = quotaObj
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha1.GPUQuotaList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha1.WorkspaceList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.ResourceQuotaList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Provisioner{}), mock.Anything).Return(tc.provisionerErr)
+
+			problems := ValidateWorkspaceFull(context.Background(), workspace, mockClient)
+			var checks []string
+			for _, problem := range problems {
+				assert.Check(t, problem.Message != "", "expected the problem of %s to have a message", problem.Check)
+				if !lo.Contains(checks, problem.Check) {
+					checks = append(checks, problem.Check)
+				}
+			}
+			assert.DeepEqual(t, checks, tc.expectedChecks)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultWarmPoolInterval is how often the warm pools are topped up with idle machines.
+const DefaultWarmPoolInterval = time.Minute
+
+// WarmPoolProvisioner keeps the warm pools topped up with the idle machines that the new workspaces of their presets
+// claim, see machine.ClaimFromWarmPool. It is added to the manager and only runs on the leader.
+type WarmPoolProvisioner struct {
+	Client client.Client
+	// CloudProvider is the provider the machines are provisioned from. Defaults to cloudprovider.Default if not set.
+	CloudProvider cloudprovider.CloudProvider
+	// Namespace is the namespace the machines of the pools are provisioned for.
+	Namespace string
+	Pools     []machine.WarmPool
+	// Interval is how often the pools are topped up. Defaults to DefaultWarmPoolInterval if not set.
+	Interval time.Duration
+}
+
+// Start tops up the warm pools until the context is canceled.
+func (p *WarmPoolProvisioner) Start(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultWarmPoolInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.provision(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// provision tops up each warm pool. Errors are only logged, the pools are topped up again in the next interval.
+func (p *WarmPoolProvisioner) provision(ctx context.Context) {
+	provider := p.CloudProvider
+	if provider == nil {
+		provider = cloudprovider.Default
+	}
+	for _, pool := range p.Pools {
+		if !plugin.KaitoModelRegister.Has(pool.PresetName) {
+			klog.ErrorS(nil, "the preset of the warm pool is not registered", "preset", pool.PresetName)
+			continue
+		}
+		// The machines of the pool are claimed by the workspaces of the preset, so they get its OS disk size.
+		diskSize := plugin.KaitoModelRegister.MustGet(pool.PresetName).GetInferenceParameters().DiskStorageRequirement
+		if diskSize == "" {
+			diskSize = "0" // The default OS size is used
+		}
+		if err := machine.ProvisionWarmPool(ctx, pool.PresetName, pool.InstanceType, p.Namespace, diskSize, pool.Count,
+			p.Client, provider); err != nil {
+			klog.ErrorS(err, "failed to provision the warm pool", "preset", pool.PresetName, "instanceType", pool.InstanceType)
+		}
+	}
+}
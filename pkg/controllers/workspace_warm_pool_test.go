@@ -0,0 +1,34 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/machine"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWarmPoolProvisioner(t *testing.T) {
+	utils.RegisterTestModel()
+	mockClient := utils.NewClient()
+	mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+	mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+	mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+
+	provisioner := &WarmPoolProvisioner{
+		Client:    mockClient,
+		Namespace: "kaito",
+		Pools: []machine.WarmPool{
+			{PresetName: "test-model", InstanceType: "Standard_NC12s_v3", Count: 2},
+			{PresetName: "unregistered-model", InstanceType: "Standard_NC12s_v3", Count: 1},
+		},
+	}
+	provisioner.provision(context.Background())
+	// The pool of the unregistered preset is skipped.
+	mockClient.AssertNumberOfCalls(t, "Create", 2)
+}
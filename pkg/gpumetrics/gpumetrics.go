@@ -0,0 +1,109 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package gpumetrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/klog/v2"
+)
+
+const (
+	queryTimeout = 30 * time.Second
+	// hostnameLabel is the label of the DCGM exporter metrics that holds the name of the node.
+	hostnameLabel = "Hostname"
+)
+
+// Source reports the GPU memory utilization of the nodes.
+type Source interface {
+	// NodeGPUMemoryUtilization returns the peak GPU memory utilization, between 0 and 1, of each node within the
+	// window. Nodes without samples are not returned.
+	NodeGPUMemoryUtilization(ctx context.Context, nodeNames []string, window time.Duration) (map[string]float64, error)
+}
+
+// PrometheusSource queries the metrics of the NVIDIA DCGM exporter from Prometheus.
+type PrometheusSource struct {
+	URL    string
+	Client *http.Client
+}
+
+var _ Source = &PrometheusSource{}
+
+func NewPrometheusSource(url string) *PrometheusSource {
+	return &PrometheusSource{
+		URL:    url,
+		Client: &http.Client{Timeout: queryTimeout},
+	}
+}
+
+// queryResponse is the response of the Prometheus query API for an instant vector. The value of a sample is a
+// pair of its timestamp and its value as a string.
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func (p *PrometheusSource) NodeGPUMemoryUtilization(ctx context.Context, nodeNames []string, window time.Duration) (map[string]float64, error) {
+	klog.InfoS("NodeGPUMemoryUtilization", "nodes", nodeNames, "window", window)
+	if len(nodeNames) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	// The utilization of a node is the one of its fullest GPU.
+	query := fmt.Sprintf("max by (%s) (max_over_time((DCGM_FI_DEV_FB_USED / (DCGM_FI_DEV_FB_USED + DCGM_FI_DEV_FB_FREE))[%s:]))",
+		hostnameLabel, promDuration(window))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("prometheus %s returned status code %d", p.URL, resp.StatusCode)
+	}
+	result := &queryResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", result.Error)
+	}
+
+	utilization := map[string]float64{}
+	for _, sample := range result.Data.Result {
+		nodeName := sample.Metric[hostnameLabel]
+		if !lo.Contains(nodeNames, nodeName) || len(sample.Value) != 2 {
+			continue
+		}
+		rawValue, _ := sample.Value[1].(string)
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU memory utilization %q of node %s: %w", rawValue, nodeName, err)
+		}
+		utilization[nodeName] = value
+	}
+	return utilization, nil
+}
+
+// promDuration formats the duration in seconds, which Prometheus accepts in range selectors.
+func promDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
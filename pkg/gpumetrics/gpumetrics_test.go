@@ -0,0 +1,62 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package gpumetrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestPrometheusSourceNodeGPUMemoryUtilization(t *testing.T) {
+	testcases := map[string]struct {
+		statusCode          int
+		body                string
+		expectedUtilization map[string]float64
+		expectedError       bool
+	}{
+		"Utilization of the requested nodes": {
+			statusCode: http.StatusOK,
+			body: `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"Hostname":"node-1"},"value":[1700000000,"0.05"]},
+				{"metric":{"Hostname":"node-2"},"value":[1700000000,"0.85"]},
+				{"metric":{"Hostname":"other-node"},"value":[1700000000,"0.5"]}]}}`,
+			expectedUtilization: map[string]float64{"node-1": 0.05, "node-2": 0.85},
+		},
+		"Query fails": {
+			statusCode:    http.StatusOK,
+			body:          `{"status":"error","error":"parse error"}`,
+			expectedError: true,
+		},
+		"Prometheus is unavailable": {
+			statusCode:    http.StatusServiceUnavailable,
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, r.URL.Path, "/api/v1/query")
+				query = r.URL.Query().Get("query")
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			utilization, err := NewPrometheusSource(server.URL).NodeGPUMemoryUtilization(context.Background(),
+				[]string{"node-1", "node-2"}, 30*time.Minute)
+			assert.Check(t, strings.Contains(query, "[1800s:]"), "unexpected query %s", query)
+			assert.Equal(t, err != nil, tc.expectedError)
+			if !tc.expectedError {
+				assert.DeepEqual(t, utilization, tc.expectedUtilization)
+			}
+		})
+	}
+}
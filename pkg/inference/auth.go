@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"fmt"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// AuthProxyContainerName is the name of the sidecar that checks the bearer token of the inference requests.
+	AuthProxyContainerName = "auth-proxy"
+	// authTokenVolume is the volume of the Secret with the token, mounted into the auth proxy at authTokenDir.
+	authTokenVolume = "auth-token"
+	authTokenDir    = "/etc/auth-proxy"
+	// authTokenFile is the file of the token in authTokenDir.
+	authTokenFile = "token"
+	// localhost is the address the model server listens on when it is behind the auth proxy.
+	localhost = "127.0.0.1"
+)
+
+// AuthProxyImage is the nginx image of the auth proxy sidecar.
+var AuthProxyImage = "mcr.microsoft.com/cbl-mariner/base/nginx:1.22"
+
+// modelServerHost returns the address the model server listens on. The model server of a workspace that requires a
+// token only listens on localhost, so that it is only reachable through the auth proxy. It returns an empty string
+// if the model server listens on all addresses.
+func modelServerHost(workspaceObj *kaitov1alpha1.Workspace) string {
+	if workspaceObj.Inference.Auth == nil {
+		return ""
+	}
+	return localhost
+}
+
+// configureAuthProxy adds a sidecar to the inference pod that forwards the requests with the bearer token of the
+// workspace to the model server and rejects the others with 401. The service of the workspace targets the port of
+// the proxy. The model server, which is the first container of the pod, only listens on localhost, so its probes
+// are forwarded by the proxy without a token and its preStop hook calls it from within the container. The pod is not
+// changed if the workspace does not require a token.
+func configureAuthProxy(workspaceObj *kaitov1alpha1.Workspace, podSpec *corev1.PodSpec) {
+	auth := workspaceObj.Inference.Auth
+	if auth == nil || len(podSpec.Containers) == 0 {
+		return
+	}
+	port := workspaceObj.Inference.GetPort()
+
+	// The probes only see the health of the model server, nothing else is served without a token.
+	var probeLocations []string
+	modelServer := &podSpec.Containers[0]
+	for _, probe := range []*corev1.Probe{modelServer.LivenessProbe, modelServer.ReadinessProbe, modelServer.StartupProbe} {
+		if probe == nil || probe.HTTPGet == nil {
+			continue
+		}
+		probe.HTTPGet.Port = intstr.FromInt(int(kaitov1alpha1.AuthProxyPort))
+		probeLocations = append(probeLocations, fmt.Sprintf(`  location = %s {
+    proxy_pass http://%s:%d;
+  }
+`, probe.HTTPGet.Path, localhost, port))
+	}
+	if lifecycle := modelServer.Lifecycle; lifecycle != nil && lifecycle.PreStop != nil && lifecycle.PreStop.HTTPGet != nil {
+		lifecycle.PreStop = &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: []string{"python3", "-c",
+				fmt.Sprintf("import urllib.request; urllib.request.urlopen('http://%s:%d%s')", localhost, port, lifecycle.PreStop.HTTPGet.Path)}},
+		}
+	}
+
+	// The shell writes the token from the Secret into the config of the proxy, so that it is not part of the pod
+	// spec. Only the characters of a bearer token (RFC 6750) are accepted, so that the token cannot change the config.
+	// The generation of a response may take minutes and is streamed, so the responses are not buffered.
+	script := fmt.Sprintf(`TOKEN=$(cat %[4]s/%[5]s)
+case "$TOKEN" in
+  ""|*[!A-Za-z0-9._~+/=-]*)
+    echo "the token of the auth proxy is empty or has characters that are not allowed in a bearer token" >&2
+    exit 1;;
+esac
+cat > /etc/nginx/conf.d/default.conf <<EOF
+server {
+  listen %[1]d;
+%[6]s  location / {
+    if (\$http_authorization != "Bearer $TOKEN") {
+      return 401;
+    }
+    proxy_pass http://%[3]s:%[2]d;
+    proxy_buffering off;
+    proxy_read_timeout 600s;
+  }
+}
+EOF
+exec nginx -g 'daemon off;'`, kaitov1alpha1.AuthProxyPort, port, localhost, authTokenDir, authTokenFile, strings.Join(lo.Uniq(probeLocations), ""))
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: authTokenVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: auth.SecretName,
+				Items:      []corev1.KeyToPath{{Key: auth.GetSecretKey(), Path: authTokenFile}},
+			},
+		},
+	})
+	podSpec.Containers = append(podSpec.Containers, corev1.Container{
+		Name:         AuthProxyContainerName,
+		Image:        AuthProxyImage,
+		Command:      []string{"/bin/sh", "-c", script},
+		VolumeMounts: []corev1.VolumeMount{{Name: authTokenVolume, MountPath: authTokenDir, ReadOnly: true}},
+		Ports:        []corev1.ContainerPort{{Name: "auth-proxy", ContainerPort: kaitov1alpha1.AuthProxyPort, Protocol: corev1.ProtocolTCP}},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(int(kaitov1alpha1.AuthProxyPort))},
+			},
+			PeriodSeconds: 10,
+		},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+		},
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGeneratePresetInferenceManifestWithAuth(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		auth        *kaitov1alpha1.InferenceAuth
+		expectProxy bool
+		expectedKey string
+	}{
+		"No auth": {},
+		"Auth with the default key": {
+			auth:        &kaitov1alpha1.InferenceAuth{SecretName: "inference-token"},
+			expectProxy: true,
+			expectedKey: kaitov1alpha1.DefaultAuthTokenKey,
+		},
+		"Auth with a custom key": {
+			auth:        &kaitov1alpha1.InferenceAuth{SecretName: "inference-token", SecretKey: "api-key"},
+			expectProxy: true,
+			expectedKey: "api-key",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.Auth = tc.auth
+			inferenceObj := &model.PresetParam{
+				GPUCountRequirement: "1",
+				BaseCommand:         "accelerate launch",
+				Tag:                 "0.0.1",
+			}
+
+			obj := GeneratePresetInferenceManifest(context.TODO(), workspace, inferenceObj, false, cloudprovider.Default)
+			containers := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers
+
+			// The model server only listens on localhost behind the proxy, its probes are forwarded by the proxy.
+			modelServer := containers[0]
+			if listensOnLocalhost := strings.Contains(modelServer.Command[len(modelServer.Command)-1], "--host=127.0.0.1"); listensOnLocalhost != tc.expectProxy {
+				t.Errorf("model server listens on localhost %t, expect %t", listensOnLocalhost, tc.expectProxy)
+			}
+			expectedProbePort := lo.Ternary(tc.expectProxy, kaitov1alpha1.AuthProxyPort, int32(5000))
+			if port := modelServer.ReadinessProbe.HTTPGet.Port.IntValue(); port != int(expectedProbePort) {
+				t.Errorf("readiness probe port is %d, expect %d", port, expectedProbePort)
+			}
+
+			var found bool
+			for _, container := range containers {
+				if container.Name != AuthProxyContainerName {
+					continue
+				}
+				found = true
+				if container.Image != AuthProxyImage {
+					t.Errorf("auth proxy image is %s, expect %s", container.Image, AuthProxyImage)
+				}
+				if len(container.Env) != 0 {
+					t.Errorf("auth proxy has env %v, expect the token to be mounted", container.Env)
+				}
+				volume, ok := lo.Find(obj.(*appsv1.Deployment).Spec.Template.Spec.Volumes, func(v corev1.Volume) bool { return v.Name == authTokenVolume })
+				if !ok || volume.Secret == nil || volume.Secret.SecretName != tc.auth.SecretName || volume.Secret.Items[0].Key != tc.expectedKey {
+					t.Errorf("auth proxy token volume is %v, expect secret %s/%s", volume, tc.auth.SecretName, tc.expectedKey)
+				}
+				if container.Ports[0].ContainerPort != kaitov1alpha1.AuthProxyPort {
+					t.Errorf("auth proxy port is %d, expect %d", container.Ports[0].ContainerPort, kaitov1alpha1.AuthProxyPort)
+				}
+				if script := container.Command[len(container.Command)-1]; !strings.Contains(script, "proxy_pass http://127.0.0.1:5000;") {
+					t.Errorf("auth proxy does not forward to the model server: %s", script)
+				}
+				if script := container.Command[len(container.Command)-1]; !strings.Contains(script, "location = /healthz {") {
+					t.Errorf("auth proxy does not forward the probes of the model server: %s", script)
+				}
+			}
+			if found != tc.expectProxy {
+				t.Errorf("auth proxy found %t, expect %t", found, tc.expectProxy)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// VerifyWeightsContainerName is the name of the init container that verifies the checksum of the model weights.
+	VerifyWeightsContainerName = "verify-weights"
+	// DefaultWeightsPath is the directory of the model weights in the preset model images.
+	DefaultWeightsPath = "/workspace/tfs/weights"
+)
+
+// weightsChecksum returns the checksum that the model weights of the workspace are verified against. The checksum of
+// the workspace takes precedence over the checksum of the preset. It returns an empty string if neither is specified.
+func weightsChecksum(workspaceObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam) string {
+	if preset := workspaceObj.Inference.Preset; preset != nil && preset.PresetOptions.Checksum != "" {
+		return preset.PresetOptions.Checksum
+	}
+	return inferenceObj.Checksum
+}
+
+// configureWeightsVerification adds an init container to the inference pod that verifies the model weights in the
+// image of the model server, which is the first container of the pod, before the model server starts. The init
+// container computes the checksum of the weights as described by model.PresetParam.Checksum and fails with the
+// mismatch as its termination message. The pod is not changed if no checksum is specified.
+func configureWeightsVerification(workspaceObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam, podSpec *corev1.PodSpec) {
+	checksum := weightsChecksum(workspaceObj, inferenceObj)
+	if checksum == "" || len(podSpec.Containers) == 0 {
+		return
+	}
+	weightsPath := inferenceObj.WeightsPath
+	if weightsPath == "" {
+		weightsPath = DefaultWeightsPath
+	}
+
+	script := fmt.Sprintf(`cd %[1]s && actual=$(find . -type f | LC_ALL=C sort | xargs -d '\n' sha256sum | sha256sum | cut -d ' ' -f 1) && `+
+		`if [ "$actual" != "%[2]s" ]; then echo "checksum mismatch of the model weights in %[1]s: expected %[2]s, got $actual" >&2; exit 1; fi`,
+		weightsPath, checksum)
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:                     VerifyWeightsContainerName,
+		Image:                    podSpec.Containers[0].Image,
+		Command:                  []string{"/bin/sh", "-c", script},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func writeWeights(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestVerifyWeightsChecksum(t *testing.T) {
+	weights := map[string]string{
+		"config.json":                      `{"model_type": "test"}`,
+		"model-00001-of-00002.safetensors": "shard 1",
+		"model-00002-of-00002.safetensors": "shard 2",
+		"tokenizer/tokenizer.json":         "tokenizer",
+	}
+	// The checksum of the weights as computed by
+	// find . -type f | LC_ALL=C sort | xargs -d '\n' sha256sum | sha256sum
+	checksum := "eb2f368bf6fa641cac58ef3fc18a008da52b6b48430a40434afd514574f5f66e"
+
+	testcases := map[string]struct {
+		files         map[string]string
+		expectedError string
+	}{
+		"Matching checksum": {
+			files: weights,
+		},
+		"Mismatching checksum of a tampered shard": {
+			files: map[string]string{
+				"config.json":                      `{"model_type": "test"}`,
+				"model-00001-of-00002.safetensors": "shard 1",
+				"model-00002-of-00002.safetensors": "tampered shard 2",
+				"tokenizer/tokenizer.json":         "tokenizer",
+			},
+			expectedError: "checksum mismatch of the model weights",
+		},
+		"Mismatching checksum of a missing shard": {
+			files: map[string]string{
+				"config.json":                      `{"model_type": "test"}`,
+				"model-00001-of-00002.safetensors": "shard 1",
+				"tokenizer/tokenizer.json":         "tokenizer",
+			},
+			expectedError: "checksum mismatch of the model weights",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			inferenceObj := &model.PresetParam{GPUCountRequirement: "1", Checksum: checksum, WeightsPath: writeWeights(t, tc.files)}
+			obj := GeneratePresetInferenceManifest(context.TODO(), workspace, inferenceObj, false, cloudprovider.Default)
+			initContainers := obj.(*appsv1.Deployment).Spec.Template.Spec.InitContainers
+			if len(initContainers) != 1 {
+				t.Fatalf("%s: expected the weights verification init container, got %d init containers", k, len(initContainers))
+			}
+
+			// The script of the init container verifies the weights.
+			command := initContainers[0].Command
+			output, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("%s: unexpected error %v: %s", k, err, output)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(string(output), tc.expectedError) {
+				t.Errorf("%s: expected error %q, got %v: %s", k, tc.expectedError, err, output)
+			}
+		})
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithChecksum(t *testing.T) {
+	utils.RegisterTestModel()
+	presetChecksum := strings.Repeat("a", 64)
+	workspaceChecksum := strings.Repeat("b", 64)
+
+	testcases := map[string]struct {
+		presetChecksum    string
+		workspaceChecksum string
+		weightsPath       string
+		expectedChecksum  string
+		expectedPath      string
+	}{
+		"Without checksum": {},
+		"Checksum of the preset": {
+			presetChecksum:   presetChecksum,
+			expectedChecksum: presetChecksum,
+			expectedPath:     DefaultWeightsPath,
+		},
+		"Checksum of the workspace overrides the preset": {
+			presetChecksum:    presetChecksum,
+			workspaceChecksum: workspaceChecksum,
+			weightsPath:       "/workspace/llama/llama-2/weights",
+			expectedChecksum:  workspaceChecksum,
+			expectedPath:      "/workspace/llama/llama-2/weights",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.Preset.PresetOptions.Checksum = tc.workspaceChecksum
+			inferenceObj := &model.PresetParam{GPUCountRequirement: "1", Checksum: tc.presetChecksum, WeightsPath: tc.weightsPath}
+
+			obj := GeneratePresetInferenceManifest(context.TODO(), workspace, inferenceObj, false, cloudprovider.Default)
+			podSpec := obj.(*appsv1.Deployment).Spec.Template.Spec
+
+			if tc.expectedChecksum == "" {
+				if len(podSpec.InitContainers) != 0 {
+					t.Errorf("%s: expected no init container, got %d", k, len(podSpec.InitContainers))
+				}
+				return
+			}
+			if len(podSpec.InitContainers) != 1 {
+				t.Fatalf("%s: expected the weights verification init container, got %d init containers", k, len(podSpec.InitContainers))
+			}
+			initContainer := podSpec.InitContainers[0]
+			if initContainer.Name != VerifyWeightsContainerName || initContainer.Image != podSpec.Containers[0].Image {
+				t.Errorf("%s: unexpected init container %s with image %s", k, initContainer.Name, initContainer.Image)
+			}
+			script := initContainer.Command[len(initContainer.Command)-1]
+			if !strings.Contains(script, "cd "+tc.expectedPath+" ") || !strings.Contains(script, tc.expectedChecksum) {
+				t.Errorf("%s: expected the script to verify %s against %s, got %s", k, tc.expectedPath, tc.expectedChecksum, script)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"strconv"
+
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// MetricsPath is the path the model server exposes Prometheus metrics on.
+	MetricsPath = "/metrics"
+	// InFlightRequestsMetric is the metric of the model server that counts the inference requests its pod is serving.
+	InFlightRequestsMetric = "kaito_inference_requests_in_flight"
+	// DefaultMetricsPort is the port the model servers of the presets expose Prometheus metrics on.
+	DefaultMetricsPort int32 = 9090
+
+	// The annotations that configure Prometheus to scrape the metrics of the inference pods.
+	AnnotationPrometheusScrape = "prometheus.io/scrape"
+	AnnotationPrometheusPort   = "prometheus.io/port"
+	AnnotationPrometheusPath   = "prometheus.io/path"
+)
+
+// configureMetrics exposes the metrics port of the model server, which is the first container of the pod, and
+// annotates the pod to be scraped by Prometheus. The pod is not changed if the preset does not support metrics.
+func configureMetrics(inferenceObj *model.PresetParam, template *corev1.PodTemplateSpec) {
+	if inferenceObj.MetricsPort == 0 || len(template.Spec.Containers) == 0 {
+		return
+	}
+
+	template.Spec.Containers[0].Ports = append(template.Spec.Containers[0].Ports, corev1.ContainerPort{
+		Name:          resources.MetricsPortName,
+		ContainerPort: inferenceObj.MetricsPort,
+		Protocol:      corev1.ProtocolTCP,
+	})
+	template.Annotations = lo.Assign(template.Annotations, map[string]string{
+		AnnotationPrometheusScrape: "true",
+		AnnotationPrometheusPort:   strconv.Itoa(int(inferenceObj.MetricsPort)),
+		AnnotationPrometheusPath:   MetricsPath,
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGeneratePresetInferenceManifestWithMetrics(t *testing.T) {
+	utils.RegisterTestModel()
+
+	testcases := map[string]struct {
+		metricsPort         int32
+		expectedAnnotations map[string]string
+	}{
+		"Preset without metrics support": {},
+		"Preset with metrics support": {
+			metricsPort: 9090,
+			expectedAnnotations: map[string]string{
+				AnnotationPrometheusScrape: "true",
+				AnnotationPrometheusPort:   "9090",
+				AnnotationPrometheusPath:   MetricsPath,
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			inferenceObj := &model.PresetParam{GPUCountRequirement: "1", MetricsPort: tc.metricsPort}
+
+			obj := GeneratePresetInferenceManifest(context.TODO(), workspace, inferenceObj, false, cloudprovider.Default)
+			template := obj.(*appsv1.Deployment).Spec.Template
+
+			metricsPort, found := lo.Find(template.Spec.Containers[0].Ports, func(p corev1.ContainerPort) bool {
+				return p.Name == resources.MetricsPortName
+			})
+			if found != (tc.metricsPort != 0) {
+				t.Errorf("%s: expected the metrics port to be exposed: %t, got %v", k, tc.metricsPort != 0, template.Spec.Containers[0].Ports)
+			}
+			if found && metricsPort.ContainerPort != tc.metricsPort {
+				t.Errorf("%s: metrics port is %d, expect %d", k, metricsPort.ContainerPort, tc.metricsPort)
+			}
+			if !reflect.DeepEqual(template.Annotations, tc.expectedAnnotations) {
+				t.Errorf("%s: pod annotations are %v, expect %v", k, template.Annotations, tc.expectedAnnotations)
+			}
+			command := template.Spec.Containers[0].Command[len(template.Spec.Containers[0].Command)-1]
+			if served := strings.Contains(command, fmt.Sprintf("--metrics_port=%d", tc.metricsPort)); served != (tc.metricsPort != 0) {
+				t.Errorf("%s: expected the model server command %q to serve the metrics: %t", k, command, tc.metricsPort != 0)
+			}
+		})
+	}
+}
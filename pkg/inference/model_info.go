@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The keys of the model info ConfigMap. They are read by other applications and must not be changed.
+const (
+	ModelInfoKeyModelName    = "modelName"
+	ModelInfoKeyModelVersion = "modelVersion"
+	ModelInfoKeyEndpoint     = "endpoint"
+	ModelInfoKeyAdapters     = "adapters"
+	ModelInfoKeyInstanceType = "instanceType"
+)
+
+// modelInfoAdapter describes an adapter of the model in the model info ConfigMap.
+type modelInfoAdapter struct {
+	Name     string `json:"name"`
+	Strength string `json:"strength,omitempty"`
+}
+
+// ModelInfoConfigMapName returns the name of the ConfigMap that describes the model deployed by the workspace.
+func ModelInfoConfigMapName(workspaceObj *kaitov1alpha1.Workspace) string {
+	return fmt.Sprintf("%s-model-info", workspaceObj.Name)
+}
+
+// BuildModelInfoConfigMap builds the ConfigMap that describes the model deployed by the workspace, i.e., the model
+// name and version, the endpoint of the inference service, the adapters and the instance type of the nodes.
+// The model name, version and endpoint are empty if the workspace runs a custom Pod template.
+func BuildModelInfoConfigMap(workspaceObj *kaitov1alpha1.Workspace) *corev1.ConfigMap {
+	var modelName, modelVersion, endpoint string
+	if workspaceObj.Inference != nil && workspaceObj.Inference.Preset != nil {
+		// The canonical preset name is used, in case the workspace uses an alias.
+		modelName, _ = plugin.KaitoModelRegister.Lookup(string(workspaceObj.Inference.Preset.Name))
+		modelVersion = workspaceObj.Inference.Preset.Version
+		if modelVersion == "" {
+			modelVersion = plugin.KaitoModelRegister.MustGet(modelName).GetInferenceParameters().Tag
+		}
+		endpoint = InferenceEndpoint(workspaceObj)
+	}
+
+	var adapters []modelInfoAdapter
+	if workspaceObj.Inference != nil {
+		adapters = lo.Map(workspaceObj.Inference.Adapters, func(adapter kaitov1alpha1.AdapterSpec, _ int) modelInfoAdapter {
+			info := modelInfoAdapter{Strength: lo.FromPtr(adapter.Strength)}
+			if adapter.Source != nil {
+				info.Name = adapter.Source.Name
+			}
+			return info
+		})
+	}
+	if adapters == nil {
+		adapters = []modelInfoAdapter{}
+	}
+	adaptersJSON, _ := json.Marshal(adapters) // A list of strings is always encodable.
+
+	return &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      ModelInfoConfigMapName(workspaceObj),
+			Namespace: workspaceObj.Namespace,
+			Labels: resources.WithCommonLabels(workspaceObj, map[string]string{
+				kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name,
+			}),
+			Annotations: resources.WithCommonAnnotations(workspaceObj, nil),
+			OwnerReferences: []v1.OwnerReference{
+				{
+					APIVersion: kaitov1alpha1.GroupVersion.String(),
+					Kind:       "Workspace",
+					UID:        workspaceObj.UID,
+					Name:       workspaceObj.Name,
+					Controller: lo.ToPtr(true),
+				},
+			},
+		},
+		Data: map[string]string{
+			ModelInfoKeyModelName:    modelName,
+			ModelInfoKeyModelVersion: modelVersion,
+			ModelInfoKeyEndpoint:     endpoint,
+			ModelInfoKeyAdapters:     string(adaptersJSON),
+			ModelInfoKeyInstanceType: workspaceObj.Resource.InstanceType,
+		},
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"gotest.tools/assert"
+)
+
+func TestBuildModelInfoConfigMap(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		workspace    func() *v1alpha1.Workspace
+		expectedData map[string]string
+	}{
+		"Preset with version and adapters": {
+			workspace: func() *v1alpha1.Workspace {
+				ws := utils.MockWorkspaceWithPreset.DeepCopy()
+				ws.Inference.Preset.Version = "0.0.2"
+				ws.Inference.Adapters = []v1alpha1.AdapterSpec{
+					{Source: &v1alpha1.DataSource{Name: "adapter-a"}, Strength: lo.ToPtr("0.5")},
+					{Source: &v1alpha1.DataSource{Name: "adapter-b"}},
+				}
+				return ws
+			},
+			expectedData: map[string]string{
+				"modelName":    "test-model",
+				"modelVersion": "0.0.2",
+				"endpoint":     "http://testWorkspace.kaito.svc.cluster.local:80",
+				"adapters":     `[{"name":"adapter-a","strength":"0.5"},{"name":"adapter-b"}]`,
+				"instanceType": "Standard_NC12s_v3",
+			},
+		},
+		"Preset without adapters": {
+			workspace: func() *v1alpha1.Workspace {
+				return utils.MockWorkspaceWithPreset.DeepCopy()
+			},
+			expectedData: map[string]string{
+				"modelName":    "test-model",
+				"modelVersion": "",
+				"endpoint":     "http://testWorkspace.kaito.svc.cluster.local:80",
+				"adapters":     "[]",
+				"instanceType": "Standard_NC12s_v3",
+			},
+		},
+		"Pod template": {
+			workspace: func() *v1alpha1.Workspace {
+				return utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+			},
+			expectedData: map[string]string{
+				"modelName":    "",
+				"modelVersion": "",
+				"endpoint":     "",
+				"adapters":     "[]",
+				"instanceType": "Standard_NC12s_v3",
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			ws := tc.workspace()
+			configMap := BuildModelInfoConfigMap(ws)
+
+			assert.Equal(t, configMap.Name, "testWorkspace-model-info")
+			assert.Equal(t, configMap.Namespace, ws.Namespace)
+			assert.Equal(t, configMap.OwnerReferences[0].Name, ws.Name)
+			assert.DeepEqual(t, configMap.Data, tc.expectedData)
+		})
+	}
+}
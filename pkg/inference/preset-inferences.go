@@ -8,10 +8,15 @@ import (
 	"github.com/azure/kaito/pkg/utils"
 	"os"
 	"strconv"
+	"time"
 
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/model"
 	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -21,51 +26,155 @@ import (
 
 const (
 	ProbePath     = "/healthz"
-	Port5000      = int32(5000)
 	InferenceFile = "inference_api.py"
+
+	// DefaultStartupTimeout is the time the model server is given to load a model whose preset does not specify one.
+	DefaultStartupTimeout = 10 * time.Minute
+	startupProbePeriod    = 10 * time.Second
+	arm64ImageTagSuffix   = "-arm64"
+
+	DrainPath = "/drain"
+	// DefaultDrainGracePeriod is the time the in-flight requests are given to finish if the preset does not specify one.
+	DefaultDrainGracePeriod = 2 * time.Minute
+	// DefaultMinDriverVersion is the minimum NVIDIA driver version of CUDA 12.1, which the PyTorch wheels of the preset
+	// images are built with.
+	DefaultMinDriverVersion = "525.60.13"
+	// drainShutdownPeriod is added to the termination grace period so that the server can shut down after draining.
+	drainShutdownPeriod = 30 * time.Second
 )
 
 var (
-	containerPorts = []corev1.ContainerPort{{
-		ContainerPort: Port5000,
+	// DefaultLivenessConfig configures the liveness probe of the presets that do not specify one.
+	DefaultLivenessConfig = model.LivenessConfig{
+		Path:             ProbePath,
+		InitialDelay:     10 * time.Minute,
+		Period:           10 * time.Second,
+		Timeout:          5 * time.Second,
+		FailureThreshold: 6,
+	}
+
+	tolerations = []corev1.Toleration{
+		{
+			Effect:   corev1.TaintEffectNoSchedule,
+			Operator: corev1.TolerationOpEqual,
+			Key:      resources.GPUString,
+		},
+		{
+			Effect: corev1.TaintEffectNoSchedule,
+			Value:  resources.GPUString,
+			Key:    "sku",
+		},
+	}
+)
+
+func getContainerPorts(port int32) []corev1.ContainerPort {
+	return []corev1.ContainerPort{{
+		ContainerPort: port,
 	},
 	}
+}
 
-	livenessProbe = &corev1.Probe{
+// getLivenessProbe returns the probe that restarts a hung model server, configured by the preset. The server is only
+// restarted once it has not answered for FailureThreshold probes in a row, so that a busy server does not flap.
+func getLivenessProbe(port int32, config *model.LivenessConfig) *corev1.Probe {
+	liveness := DefaultLivenessConfig
+	if config != nil {
+		if config.Path != "" {
+			liveness.Path = config.Path
+		}
+		if config.InitialDelay > 0 {
+			liveness.InitialDelay = config.InitialDelay
+		}
+		if config.Period > 0 {
+			liveness.Period = config.Period
+		}
+		if config.Timeout > 0 {
+			liveness.Timeout = config.Timeout
+		}
+		if config.FailureThreshold > 0 {
+			liveness.FailureThreshold = config.FailureThreshold
+		}
+	}
+	return &corev1.Probe{
 		ProbeHandler: corev1.ProbeHandler{
 			HTTPGet: &corev1.HTTPGetAction{
-				Port: intstr.FromInt(5000),
-				Path: ProbePath,
+				Port: intstr.FromInt(int(port)),
+				Path: liveness.Path,
 			},
 		},
-		InitialDelaySeconds: 600, // 10 minutes
-		PeriodSeconds:       10,
+		InitialDelaySeconds: int32(liveness.InitialDelay.Seconds()),
+		PeriodSeconds:       int32(liveness.Period.Seconds()),
+		TimeoutSeconds:      int32(liveness.Timeout.Seconds()),
+		FailureThreshold:    liveness.FailureThreshold,
 	}
+}
 
-	readinessProbe = &corev1.Probe{
+func getReadinessProbe(port int32) *corev1.Probe {
+	return &corev1.Probe{
 		ProbeHandler: corev1.ProbeHandler{
 			HTTPGet: &corev1.HTTPGetAction{
-				Port: intstr.FromInt(5000),
+				Port: intstr.FromInt(int(port)),
 				Path: ProbePath,
 			},
 		},
 		InitialDelaySeconds: 30,
 		PeriodSeconds:       10,
 	}
+}
 
-	tolerations = []corev1.Toleration{
-		{
-			Effect:   corev1.TaintEffectNoSchedule,
-			Operator: corev1.TolerationOpEqual,
-			Key:      resources.GPUString,
+// getStartupProbe returns a probe that lets the model server load the model for up to startupTimeout
+// before the kubelet restarts it. The liveness and readiness probes only start once it has succeeded.
+func getStartupProbe(port int32, startupTimeout time.Duration) *corev1.Probe {
+	if startupTimeout <= 0 {
+		startupTimeout = DefaultStartupTimeout
+	}
+	failureThreshold := int32((startupTimeout + startupProbePeriod - 1) / startupProbePeriod)
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(int(port)),
+				Path: ProbePath,
+			},
 		},
-		{
-			Effect: corev1.TaintEffectNoSchedule,
-			Value:  resources.GPUString,
-			Key:    "sku",
+		PeriodSeconds:    int32(startupProbePeriod.Seconds()),
+		FailureThreshold: failureThreshold,
+	}
+}
+
+// BuildPreStopDrainHook returns the preStop hook that drains the model server before the pod is terminated and
+// the termination grace period that covers it. It returns nil if the model server of the preset does not support draining.
+func BuildPreStopDrainHook(presetObj *model.PresetParam, port int32) (*corev1.Lifecycle, *int64) {
+	if !presetObj.SupportsDrain {
+		return nil, nil
+	}
+	gracePeriod := presetObj.DrainGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultDrainGracePeriod
+	}
+	lifecycle := &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(int(port)),
+				Path: fmt.Sprintf("%s?timeout=%d", DrainPath, int64(gracePeriod.Seconds())),
+			},
 		},
 	}
-)
+	return lifecycle, lo.ToPtr(int64((gracePeriod + drainShutdownPeriod).Seconds()))
+}
+
+// terminationGracePeriod returns the termination grace period of the inference pods in seconds. The grace period of
+// the workspace takes precedence, otherwise the longer of the grace period of the preset and the one that draining
+// needs is used. It returns nil for the default grace period of Kubernetes.
+func terminationGracePeriod(workspaceObj *kaitov1alpha1.Workspace, presetObj *model.PresetParam, drainGracePeriod *int64) *int64 {
+	if seconds := workspaceObj.Inference.TerminationGracePeriodSeconds; seconds != nil {
+		return lo.ToPtr(*seconds)
+	}
+	gracePeriod := drainGracePeriod
+	if seconds := int64(presetObj.TerminationGracePeriod.Seconds()); seconds > 0 && (gracePeriod == nil || seconds > *gracePeriod) {
+		gracePeriod = &seconds
+	}
+	return gracePeriod
+}
 
 func updateTorchParamsForDistributedInference(ctx context.Context, kubeClient client.Client, wObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam) error {
 	existingService := &corev1.Service{}
@@ -74,7 +183,10 @@ func updateTorchParamsForDistributedInference(ctx context.Context, kubeClient cl
 		return err
 	}
 
-	nodes := *wObj.Resource.Count
+	nodes := lo.FromPtr(wObj.Resource.Count)
+	if nodes == 0 {
+		return fmt.Errorf("the node count of workspace %s is not set", wObj.Name)
+	}
 	inferenceObj.TorchRunParams["nnodes"] = strconv.Itoa(nodes)
 	inferenceObj.TorchRunParams["nproc_per_node"] = strconv.Itoa(inferenceObj.WorldSize / nodes)
 	if nodes > 1 {
@@ -92,7 +204,10 @@ func updateTorchParamsForDistributedInference(ctx context.Context, kubeClient cl
 	return nil
 }
 
-func GetInferenceImageInfo(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, presetObj *model.PresetParam) (string, []corev1.LocalObjectReference) {
+// GetInferenceImageInfo returns the model image for the given CPU architecture and its pull secrets. The public preset
+// images of a runtime other than transformers carry the runtime as a tag suffix and the images for arm64 nodes carry
+// the "-arm64" tag suffix, e.g., 0.0.4-vllm-arm64. Private images are used as specified by the user.
+func GetInferenceImageInfo(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, presetObj *model.PresetParam, arch string) (string, []corev1.LocalObjectReference) {
 	imagePullSecretRefs := []corev1.LocalObjectReference{}
 	if presetObj.ImageAccessMode == "private" {
 		imageName := workspaceObj.Inference.Preset.PresetOptions.Image
@@ -101,8 +216,16 @@ func GetInferenceImageInfo(ctx context.Context, workspaceObj *kaitov1alpha1.Work
 		}
 		return imageName, imagePullSecretRefs
 	} else {
-		imageName := string(workspaceObj.Inference.Preset.Name)
+		// The image is named after the canonical preset name, in case the workspace uses an alias.
+		imageName, _ := plugin.KaitoModelRegister.Lookup(string(workspaceObj.Inference.Preset.Name))
 		imageTag := presetObj.Tag
+		if workspaceObj.Inference.Preset.Version != "" {
+			imageTag = workspaceObj.Inference.Preset.Version
+		}
+		imageTag = runtimeImageTag(imageTag, workspaceObj.Inference.GetRuntime())
+		if arch == cloudprovider.ArchARM64 {
+			imageTag += arm64ImageTagSuffix
+		}
 		registryName := os.Getenv("PRESET_REGISTRY_NAME")
 		imageName = fmt.Sprintf("%s/kaito-%s:%s", registryName, imageName, imageTag)
 		return imageName, imagePullSecretRefs
@@ -110,7 +233,7 @@ func GetInferenceImageInfo(ctx context.Context, workspaceObj *kaitov1alpha1.Work
 }
 
 func CreatePresetInference(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace,
-	inferenceObj *model.PresetParam, supportDistributedInference bool, kubeClient client.Client) (client.Object, error) {
+	inferenceObj *model.PresetParam, supportDistributedInference bool, provider cloudprovider.CloudProvider, kubeClient client.Client) (client.Object, error) {
 	if inferenceObj.TorchRunParams != nil && supportDistributedInference {
 		if err := updateTorchParamsForDistributedInference(ctx, kubeClient, workspaceObj, inferenceObj); err != nil {
 			klog.ErrorS(err, "failed to update torch params", "workspace", workspaceObj)
@@ -118,6 +241,18 @@ func CreatePresetInference(ctx context.Context, workspaceObj *kaitov1alpha1.Work
 		}
 	}
 
+	depObj := GeneratePresetInferenceManifest(ctx, workspaceObj, inferenceObj, supportDistributedInference, provider)
+	err := resources.CreateResource(ctx, depObj, kubeClient)
+	if client.IgnoreAlreadyExists(err) != nil {
+		return nil, err
+	}
+	return depObj, nil
+}
+
+// GeneratePresetInferenceManifest returns the Deployment, or the StatefulSet for distributed inference, that runs
+// the preset model of the workspace. The torch parameters of distributed inference are used as they are.
+func GeneratePresetInferenceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace,
+	inferenceObj *model.PresetParam, supportDistributedInference bool, provider cloudprovider.CloudProvider) client.Object {
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
 	volume, volumeMount := utils.ConfigSHMVolume(workspaceObj)
@@ -127,42 +262,98 @@ func CreatePresetInference(ctx context.Context, workspaceObj *kaitov1alpha1.Work
 	if volumeMount.Name != "" {
 		volumeMounts = append(volumeMounts, volumeMount)
 	}
-	commands, resourceReq := prepareInferenceParameters(ctx, inferenceObj)
-	image, imagePullSecrets := GetInferenceImageInfo(ctx, workspaceObj, inferenceObj)
+	// The volumes of the workspace, e.g., with shared datasets, are mounted into the model server container.
+	volumes = append(volumes, workspaceObj.Inference.Volumes...)
+	volumeMounts = append(volumeMounts, workspaceObj.Inference.VolumeMounts...)
+	commands, resourceReq := prepareInferenceParameters(ctx, workspaceObj, inferenceObj)
+	image, imagePullSecrets := GetInferenceImageInfo(ctx, workspaceObj, inferenceObj, provider.Architecture(workspaceObj.Resource.InstanceType))
+
+	port := workspaceObj.Inference.GetPort()
+	containerPorts, livenessProbe, readinessProbe := getContainerPorts(port), getLivenessProbe(port, inferenceObj.LivenessConfig), getReadinessProbe(port)
+	startupProbe := getStartupProbe(port, inferenceObj.StartupTimeout)
 
 	var depObj client.Object
 	if supportDistributedInference {
-		depObj = resources.GenerateStatefulSetManifest(ctx, workspaceObj, image, imagePullSecrets, *workspaceObj.Resource.Count, commands,
-			containerPorts, livenessProbe, readinessProbe, resourceReq, tolerations, volumes, volumeMounts)
+		depObj = resources.GenerateStatefulSetManifest(ctx, workspaceObj, image, imagePullSecrets, lo.FromPtr(workspaceObj.Resource.Count), commands,
+			containerPorts, livenessProbe, readinessProbe, startupProbe, resourceReq, tolerations, volumes, volumeMounts)
 	} else {
-		depObj = resources.GenerateDeploymentManifest(ctx, workspaceObj, image, imagePullSecrets, *workspaceObj.Resource.Count, commands,
-			containerPorts, livenessProbe, readinessProbe, resourceReq, tolerations, volumes, volumeMounts)
+		depObj = resources.GenerateDeploymentManifest(ctx, workspaceObj, image, imagePullSecrets, lo.FromPtr(workspaceObj.Resource.Count), commands,
+			containerPorts, livenessProbe, readinessProbe, startupProbe, resourceReq, tolerations, volumes, volumeMounts)
 	}
-	err := resources.CreateResource(ctx, depObj, kubeClient)
-	if client.IgnoreAlreadyExists(err) != nil {
-		return nil, err
+	var template *corev1.PodTemplateSpec
+	switch workload := depObj.(type) {
+	case *appsv1.Deployment:
+		template = &workload.Spec.Template
+	case *appsv1.StatefulSet:
+		template = &workload.Spec.Template
 	}
-	return depObj, nil
+	podSpec := &template.Spec
+	// The in-flight requests are completed before the pod is terminated, e.g., when its node is drained.
+	// The drain endpoint is only served by the kaito inference server of the transformers runtime.
+	var drainGracePeriod *int64
+	if workspaceObj.Inference.GetRuntime() == kaitov1alpha1.InferenceRuntimeTransformers {
+		var lifecycle *corev1.Lifecycle
+		if lifecycle, drainGracePeriod = BuildPreStopDrainHook(inferenceObj, port); lifecycle != nil {
+			podSpec.Containers[0].Lifecycle = lifecycle
+		}
+	}
+	podSpec.TerminationGracePeriodSeconds = terminationGracePeriod(workspaceObj, inferenceObj, drainGracePeriod)
+	configureRuntime(workspaceObj, inferenceObj, podSpec)
+	configureAuthProxy(workspaceObj, podSpec)
+	configureRAG(workspaceObj, podSpec)
+	configureWeightsVerification(workspaceObj, inferenceObj, podSpec)
+	utils.ConfigModelCacheVolume(workspaceObj, podSpec)
+	configureMetrics(inferenceObj, template)
+	return depObj
 }
 
 // prepareInferenceParameters builds a PyTorch command:
 // torchrun <TORCH_PARAMS> <OPTIONAL_RDZV_PARAMS> baseCommand <MODEL_PARAMS>
 // and sets the GPU resources required for inference.
 // Returns the command and resource configuration.
-func prepareInferenceParameters(ctx context.Context, inferenceObj *model.PresetParam) ([]string, corev1.ResourceRequirements) {
+func prepareInferenceParameters(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam) ([]string, corev1.ResourceRequirements) {
 	torchCommand := utils.BuildCmdStr(inferenceObj.BaseCommand, inferenceObj.TorchRunParams)
 	torchCommand = utils.BuildCmdStr(torchCommand, inferenceObj.TorchRunRdzvParams)
-	modelCommand := utils.BuildCmdStr(InferenceFile, inferenceObj.ModelRunParams)
+	modelRunParams := inferenceObj.ModelRunParams
+	// The model server listens on the inference port of the workspace.
+	modelRunParams = lo.Assign(modelRunParams, map[string]string{"port": strconv.Itoa(int(workspaceObj.Inference.GetPort()))})
+	if host := modelServerHost(workspaceObj); host != "" {
+		modelRunParams = lo.Assign(modelRunParams, map[string]string{"host": host})
+	}
+	if inferenceObj.MetricsPort != 0 {
+		modelRunParams = lo.Assign(modelRunParams, map[string]string{"metrics_port": strconv.Itoa(int(inferenceObj.MetricsPort))})
+	}
+	// The files of a model that the model server downloads are fetched concurrently.
+	if parallelism := workspaceObj.Inference.DownloadParallelism; parallelism > 1 {
+		modelRunParams = lo.Assign(modelRunParams, map[string]string{"download_workers": strconv.Itoa(parallelism)})
+	}
+	// The processes of the model server share the GPUs of the replica, each of them loads the model.
+	if processes := workspaceObj.Inference.GetProcessesPerGPU(); processes > 1 {
+		modelRunParams = lo.Assign(modelRunParams, map[string]string{"workers": strconv.Itoa(processes)})
+	}
+	modelCommand := utils.BuildCmdStr(InferenceFile, modelRunParams)
 	commands := utils.ShellCmd(torchCommand + " " + modelCommand)
 
+	gpus := *resource.NewQuantity(gpusPerReplica(workspaceObj, inferenceObj), resource.DecimalSI)
 	resourceRequirements := corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
-			corev1.ResourceName(resources.CapacityNvidiaGPU): resource.MustParse(inferenceObj.GPUCountRequirement),
+			corev1.ResourceName(resources.CapacityNvidiaGPU): gpus,
 		},
 		Limits: corev1.ResourceList{
-			corev1.ResourceName(resources.CapacityNvidiaGPU): resource.MustParse(inferenceObj.GPUCountRequirement),
+			corev1.ResourceName(resources.CapacityNvidiaGPU): gpus,
 		},
 	}
+	// The CPU and memory may be overridden, the GPUs of the container are always the GPUs of a replica.
+	if overrides := workspaceObj.Inference.ResourceOverrides; overrides != nil {
+		resourceRequirements.Requests = lo.Assign(overrides.Requests, resourceRequirements.Requests)
+		resourceRequirements.Limits = lo.Assign(overrides.Limits, resourceRequirements.Limits)
+	}
 
 	return commands, resourceRequirements
 }
+
+// gpusPerReplica returns the number of GPUs that each replica of the preset model of the workspace requests.
+func gpusPerReplica(workspaceObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam) int64 {
+	gpuCount := resource.MustParse(inferenceObj.GPUCountRequirement)
+	return workspaceObj.Inference.GetGPUsPerReplica(gpuCount.Value())
+}
@@ -5,16 +5,23 @@ package inference
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/resources"
 	"github.com/azure/kaito/pkg/utils"
 	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/mock"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -80,7 +87,7 @@ func TestCreatePresetInference(t *testing.T) {
 			}
 			mockClient.CreateOrUpdateObjectInMap(svc)
 
-			createdObject, _ := CreatePresetInference(context.TODO(), workspace, inferenceObj, useHeadlessSvc, mockClient)
+			createdObject, _ := CreatePresetInference(context.TODO(), workspace, inferenceObj, useHeadlessSvc, cloudprovider.Default, mockClient)
 			createdWorkload := ""
 			switch createdObject.(type) {
 			case *appsv1.Deployment:
@@ -116,6 +123,53 @@ func TestCreatePresetInference(t *testing.T) {
 	}
 }
 
+func TestCreatePresetInferenceWithPort(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		port         int32
+		expectedPort int32
+	}{
+		"Default port": {
+			port:         0,
+			expectedPort: 5000,
+		},
+		"Custom port": {
+			port:         8080,
+			expectedPort: 8080,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.Port = tc.port
+			inferenceObj := plugin.KaitoModelRegister.MustGet("test-model").GetInferenceParameters()
+
+			createdObject, err := CreatePresetInference(context.Background(), workspace, inferenceObj, false, cloudprovider.Default, mockClient)
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", k, err)
+			}
+
+			container := createdObject.(*appsv1.Deployment).Spec.Template.Spec.Containers[0]
+			if container.Ports[0].ContainerPort != tc.expectedPort {
+				t.Errorf("%s: container port is %d, expect %d", k, container.Ports[0].ContainerPort, tc.expectedPort)
+			}
+			if container.ReadinessProbe.HTTPGet.Port.IntVal != tc.expectedPort {
+				t.Errorf("%s: readiness probe port is %d, expect %d", k, container.ReadinessProbe.HTTPGet.Port.IntVal, tc.expectedPort)
+			}
+			if container.LivenessProbe.HTTPGet.Port.IntVal != tc.expectedPort {
+				t.Errorf("%s: liveness probe port is %d, expect %d", k, container.LivenessProbe.HTTPGet.Port.IntVal, tc.expectedPort)
+			}
+			if command := container.Command[len(container.Command)-1]; !strings.Contains(command, fmt.Sprintf("--port=%d", tc.expectedPort)) {
+				t.Errorf("%s: model server command %q does not listen on port %d", k, command, tc.expectedPort)
+			}
+		})
+	}
+}
+
 func toParameterMap(in []string) map[string]string {
 	ret := make(map[string]string)
 	for _, each := range in {
@@ -131,3 +185,400 @@ func toParameterMap(in []string) map[string]string {
 	}
 	return ret
 }
+
+func TestGetStartupProbe(t *testing.T) {
+	testcases := map[string]struct {
+		preset                   *model.PresetParam
+		expectedFailureThreshold int32
+	}{
+		"Large preset": {
+			preset:                   &model.PresetParam{StartupTimeout: 30 * time.Minute},
+			expectedFailureThreshold: 180,
+		},
+		"Small preset without startup timeout": {
+			preset:                   &model.PresetParam{},
+			expectedFailureThreshold: 60,
+		},
+		"Startup timeout is rounded up to the probe period": {
+			preset:                   &model.PresetParam{StartupTimeout: 95 * time.Second},
+			expectedFailureThreshold: 10,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			probe := getStartupProbe(5000, tc.preset.StartupTimeout)
+			if probe.FailureThreshold != tc.expectedFailureThreshold {
+				t.Errorf("%s: FailureThreshold is %d, expected %d", k, probe.FailureThreshold, tc.expectedFailureThreshold)
+			}
+			if probe.PeriodSeconds != 10 {
+				t.Errorf("%s: PeriodSeconds is %d, expected 10", k, probe.PeriodSeconds)
+			}
+			if probe.HTTPGet.Port.IntValue() != 5000 || probe.HTTPGet.Path != ProbePath {
+				t.Errorf("%s: unexpected probe handler %v", k, probe.HTTPGet)
+			}
+		})
+	}
+}
+
+func TestGetLivenessProbe(t *testing.T) {
+	testcases := map[string]struct {
+		preset                   *model.PresetParam
+		expectedPath             string
+		expectedInitialDelay     int32
+		expectedTimeout          int32
+		expectedFailureThreshold int32
+	}{
+		"Preset with liveness config": {
+			preset: &model.PresetParam{LivenessConfig: &model.LivenessConfig{
+				Path:             "/health",
+				InitialDelay:     20 * time.Minute,
+				Timeout:          10 * time.Second,
+				FailureThreshold: 12,
+			}},
+			expectedPath:             "/health",
+			expectedInitialDelay:     1200,
+			expectedTimeout:          10,
+			expectedFailureThreshold: 12,
+		},
+		"Unset fields take the defaults": {
+			preset:                   &model.PresetParam{LivenessConfig: &model.LivenessConfig{FailureThreshold: 12}},
+			expectedPath:             ProbePath,
+			expectedInitialDelay:     600,
+			expectedTimeout:          5,
+			expectedFailureThreshold: 12,
+		},
+		"Preset without liveness config": {
+			preset:                   &model.PresetParam{},
+			expectedPath:             ProbePath,
+			expectedInitialDelay:     600,
+			expectedTimeout:          5,
+			expectedFailureThreshold: 6,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			probe := getLivenessProbe(5000, tc.preset.LivenessConfig)
+			if probe.HTTPGet.Port.IntValue() != 5000 || probe.HTTPGet.Path != tc.expectedPath {
+				t.Errorf("%s: unexpected probe handler %v", k, probe.HTTPGet)
+			}
+			if probe.InitialDelaySeconds != tc.expectedInitialDelay {
+				t.Errorf("%s: InitialDelaySeconds is %d, expected %d", k, probe.InitialDelaySeconds, tc.expectedInitialDelay)
+			}
+			if probe.PeriodSeconds != 10 {
+				t.Errorf("%s: PeriodSeconds is %d, expected 10", k, probe.PeriodSeconds)
+			}
+			if probe.TimeoutSeconds != tc.expectedTimeout {
+				t.Errorf("%s: TimeoutSeconds is %d, expected %d", k, probe.TimeoutSeconds, tc.expectedTimeout)
+			}
+			if probe.FailureThreshold != tc.expectedFailureThreshold {
+				t.Errorf("%s: FailureThreshold is %d, expected %d", k, probe.FailureThreshold, tc.expectedFailureThreshold)
+			}
+		})
+	}
+}
+
+func TestGetInferenceImageInfoArch(t *testing.T) {
+	t.Setenv("PRESET_REGISTRY_NAME", "kaitoregistry")
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.Preset.Version = "0.0.1"
+	presetObj := &model.PresetParam{}
+
+	testcases := map[string]struct {
+		arch          string
+		expectedImage string
+	}{
+		"amd64": {
+			arch:          cloudprovider.ArchAMD64,
+			expectedImage: "kaitoregistry/kaito-test-model:0.0.1",
+		},
+		"arm64": {
+			arch:          cloudprovider.ArchARM64,
+			expectedImage: "kaitoregistry/kaito-test-model:0.0.1-arm64",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			image, _ := GetInferenceImageInfo(context.TODO(), workspace, presetObj, tc.arch)
+			if image != tc.expectedImage {
+				t.Errorf("%s: image is %s, expected %s", k, image, tc.expectedImage)
+			}
+		})
+	}
+}
+
+func TestBuildPreStopDrainHook(t *testing.T) {
+	testcases := map[string]struct {
+		preset              *model.PresetParam
+		expectedPath        string
+		expectedGracePeriod int64
+	}{
+		"Preset supporting drain": {
+			preset:              &model.PresetParam{SupportsDrain: true},
+			expectedPath:        "/drain?timeout=120",
+			expectedGracePeriod: 150,
+		},
+		"Preset supporting drain with a grace period": {
+			preset:              &model.PresetParam{SupportsDrain: true, DrainGracePeriod: 10 * time.Minute},
+			expectedPath:        "/drain?timeout=600",
+			expectedGracePeriod: 630,
+		},
+		"Preset not supporting drain": {
+			preset: &model.PresetParam{},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			lifecycle, gracePeriod := BuildPreStopDrainHook(tc.preset, 5000)
+			if tc.expectedPath == "" {
+				if lifecycle != nil || gracePeriod != nil {
+					t.Errorf("%s: expected no preStop hook, got %v", k, lifecycle)
+				}
+				return
+			}
+			if lifecycle == nil || lifecycle.PreStop == nil || lifecycle.PreStop.HTTPGet == nil {
+				t.Fatalf("%s: expected a preStop HTTP hook", k)
+			}
+			if lifecycle.PreStop.HTTPGet.Path != tc.expectedPath || lifecycle.PreStop.HTTPGet.Port.IntValue() != 5000 {
+				t.Errorf("%s: unexpected preStop hook %v", k, lifecycle.PreStop.HTTPGet)
+			}
+			if *gracePeriod != tc.expectedGracePeriod {
+				t.Errorf("%s: termination grace period is %d, expected %d", k, *gracePeriod, tc.expectedGracePeriod)
+			}
+		})
+	}
+}
+
+func TestCreatePresetInferenceWithDrainHook(t *testing.T) {
+	utils.RegisterTestModel()
+	mockClient := utils.NewClient()
+	mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	inferenceObj := &model.PresetParam{GPUCountRequirement: "1", SupportsDrain: true}
+
+	createdObject, err := CreatePresetInference(context.Background(), workspace, inferenceObj, false, cloudprovider.Default, mockClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podSpec := createdObject.(*appsv1.Deployment).Spec.Template.Spec
+	if podSpec.Containers[0].Lifecycle == nil || podSpec.Containers[0].Lifecycle.PreStop == nil {
+		t.Errorf("expected the preStop drain hook to be added")
+	}
+	if podSpec.TerminationGracePeriodSeconds == nil || *podSpec.TerminationGracePeriodSeconds != 150 {
+		t.Errorf("unexpected termination grace period %v", podSpec.TerminationGracePeriodSeconds)
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithTerminationGracePeriod(t *testing.T) {
+	utils.RegisterTestModel()
+
+	testcases := map[string]struct {
+		preset              *model.PresetParam
+		workspaceSeconds    *int64
+		expectedGracePeriod *int64
+	}{
+		"Default grace period": {
+			preset: &model.PresetParam{GPUCountRequirement: "1"},
+		},
+		"Preset grace period": {
+			preset:              &model.PresetParam{GPUCountRequirement: "1", TerminationGracePeriod: 2 * time.Minute},
+			expectedGracePeriod: lo.ToPtr(int64(120)),
+		},
+		"Grace period draining needs is longer than the preset one": {
+			preset:              &model.PresetParam{GPUCountRequirement: "1", SupportsDrain: true, TerminationGracePeriod: time.Minute},
+			expectedGracePeriod: lo.ToPtr(int64(150)),
+		},
+		"Workspace grace period takes precedence": {
+			preset:              &model.PresetParam{GPUCountRequirement: "1", TerminationGracePeriod: 2 * time.Minute},
+			workspaceSeconds:    lo.ToPtr(int64(600)),
+			expectedGracePeriod: lo.ToPtr(int64(600)),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.TerminationGracePeriodSeconds = tc.workspaceSeconds
+
+			obj := GeneratePresetInferenceManifest(context.Background(), workspace, tc.preset, false, cloudprovider.Default)
+			gracePeriod := obj.(*appsv1.Deployment).Spec.Template.Spec.TerminationGracePeriodSeconds
+			if !reflect.DeepEqual(gracePeriod, tc.expectedGracePeriod) {
+				t.Errorf("%s: termination grace period is %v, expected %v", k, lo.FromPtr(gracePeriod), lo.FromPtr(tc.expectedGracePeriod))
+			}
+		})
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithGPUsPerReplica(t *testing.T) {
+	utils.RegisterTestModel()
+
+	testcases := map[string]struct {
+		gpusPerReplica int
+		runtime        kaitov1alpha1.InferenceRuntime
+		expectedGPUs   int64
+		expectedShards string
+	}{
+		"GPU count of the preset": {
+			expectedGPUs: 2,
+		},
+		"GPUs per replica of the workspace": {
+			gpusPerReplica: 4,
+			expectedGPUs:   4,
+		},
+		"Tensor parallelism across the GPUs of a replica": {
+			gpusPerReplica: 4,
+			runtime:        kaitov1alpha1.InferenceRuntimeVLLM,
+			expectedGPUs:   4,
+			expectedShards: "--tensor-parallel-size=4",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.GPUsPerReplica = tc.gpusPerReplica
+			workspace.Inference.Runtime = tc.runtime
+			preset := &model.PresetParam{
+				GPUCountRequirement: "2",
+				Runtimes:            map[string]map[string]string{string(kaitov1alpha1.InferenceRuntimeVLLM): {}},
+			}
+
+			obj := GeneratePresetInferenceManifest(context.Background(), workspace, preset, false, cloudprovider.Default)
+			container := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0]
+			for _, list := range []corev1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+				gpus := list[resources.CapacityNvidiaGPU]
+				if gpus.Value() != tc.expectedGPUs {
+					t.Errorf("%s: GPUs are %d, expected %d", k, gpus.Value(), tc.expectedGPUs)
+				}
+			}
+			if command := container.Command[len(container.Command)-1]; !strings.Contains(command, tc.expectedShards) {
+				t.Errorf("%s: command %q does not contain %q", k, command, tc.expectedShards)
+			}
+		})
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithDownloadParallelism(t *testing.T) {
+	utils.RegisterTestModel()
+
+	testcases := map[string]struct {
+		downloadParallelism int
+		expectedArg         string
+	}{
+		"Files are downloaded one at a time by default": {},
+		"Files are downloaded one at a time": {
+			downloadParallelism: 1,
+		},
+		"Files are downloaded concurrently": {
+			downloadParallelism: 8,
+			expectedArg:         "--download_workers=8",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.DownloadParallelism = tc.downloadParallelism
+
+			obj := GeneratePresetInferenceManifest(context.Background(), workspace, &model.PresetParam{GPUCountRequirement: "1"}, false, cloudprovider.Default)
+			container := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0]
+			command := container.Command[len(container.Command)-1]
+			if tc.expectedArg == "" && strings.Contains(command, "--download_workers") {
+				t.Errorf("%s: command %q is not expected to set the download workers", k, command)
+			}
+			if !strings.Contains(command, tc.expectedArg) {
+				t.Errorf("%s: command %q does not contain %q", k, command, tc.expectedArg)
+			}
+		})
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithResourceOverrides(t *testing.T) {
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.ResourceOverrides = &kaitov1alpha1.ResourceOverrides{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("16Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8"), resources.CapacityNvidiaGPU: resource.MustParse("8")},
+	}
+
+	obj := GeneratePresetInferenceManifest(context.Background(), workspace, &model.PresetParam{GPUCountRequirement: "2"}, false, cloudprovider.Default)
+	container := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0]
+	expected := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:          resource.MustParse("4"),
+			corev1.ResourceMemory:       resource.MustParse("16Gi"),
+			resources.CapacityNvidiaGPU: resource.MustParse("2"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:          resource.MustParse("8"),
+			resources.CapacityNvidiaGPU: resource.MustParse("2"),
+		},
+	}
+	for name, quantity := range expected.Requests {
+		if actual := container.Resources.Requests[name]; actual.Cmp(quantity) != 0 {
+			t.Errorf("%s request is %s, expected %s", name, actual.String(), quantity.String())
+		}
+	}
+	for name, quantity := range expected.Limits {
+		if actual := container.Resources.Limits[name]; actual.Cmp(quantity) != 0 {
+			t.Errorf("%s limit is %s, expected %s", name, actual.String(), quantity.String())
+		}
+	}
+	if len(container.Resources.Requests) != len(expected.Requests) || len(container.Resources.Limits) != len(expected.Limits) {
+		t.Errorf("resources are %v, expected %v", container.Resources, expected)
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithVolumes(t *testing.T) {
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.Volumes = []corev1.Volume{{
+		Name:         "prompts",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "prompts"}}},
+	}}
+	workspace.Inference.VolumeMounts = []corev1.VolumeMount{{Name: "prompts", MountPath: "/etc/prompts"}}
+
+	obj := GeneratePresetInferenceManifest(context.Background(), workspace, &model.PresetParam{GPUCountRequirement: "1"}, false, cloudprovider.Default)
+	podSpec := obj.(*appsv1.Deployment).Spec.Template.Spec
+	if !reflect.DeepEqual(podSpec.Volumes, workspace.Inference.Volumes) {
+		t.Errorf("volumes are %v, expected %v", podSpec.Volumes, workspace.Inference.Volumes)
+	}
+	if !reflect.DeepEqual(podSpec.Containers[0].VolumeMounts, workspace.Inference.VolumeMounts) {
+		t.Errorf("volume mounts are %v, expected %v", podSpec.Containers[0].VolumeMounts, workspace.Inference.VolumeMounts)
+	}
+}
+
+func TestGeneratePresetInferenceManifestWithModelCache(t *testing.T) {
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.ModelCache = &kaitov1alpha1.ModelCacheSpec{PersistentVolumeClaim: "hf-cache", MountPath: "/mnt/cache"}
+
+	// The checksum adds the init container that verifies the model weights before the model server starts.
+	obj := GeneratePresetInferenceManifest(context.Background(), workspace, &model.PresetParam{GPUCountRequirement: "1", Checksum: "abc"}, false, cloudprovider.Default)
+	podSpec := obj.(*appsv1.Deployment).Spec.Template.Spec
+	volume, found := lo.Find(podSpec.Volumes, func(v corev1.Volume) bool { return v.Name == kaitov1alpha1.ModelCacheVolumeName })
+	if !found || volume.PersistentVolumeClaim == nil || volume.PersistentVolumeClaim.ClaimName != "hf-cache" {
+		t.Errorf("volumes are %v, expected the model cache volume of claim hf-cache", podSpec.Volumes)
+	}
+	if len(podSpec.InitContainers) == 0 {
+		t.Fatalf("expected the init container that verifies the model weights")
+	}
+	expectedEnv := []corev1.EnvVar{{Name: utils.EnvHFHome, Value: "/mnt/cache"}, {Name: utils.EnvTransformersCache, Value: "/mnt/cache/hub"}}
+	expectedMount := corev1.VolumeMount{Name: kaitov1alpha1.ModelCacheVolumeName, MountPath: "/mnt/cache"}
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		cacheEnv := lo.Filter(container.Env, func(e corev1.EnvVar, _ int) bool {
+			return e.Name == utils.EnvHFHome || e.Name == utils.EnvTransformersCache
+		})
+		if !reflect.DeepEqual(cacheEnv, expectedEnv) {
+			t.Errorf("cache environment of container %s is %v, expected %v", container.Name, cacheEnv, expectedEnv)
+		}
+		if !lo.Contains(container.VolumeMounts, expectedMount) {
+			t.Errorf("volume mounts of container %s are %v, expected %v", container.Name, container.VolumeMounts, expectedMount)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// EmbeddingContainerName is the name of the sidecar container that runs the embedding model.
+	EmbeddingContainerName = "embedding"
+	// DefaultRAGTopK is the number of documents retrieved for each request if the workspace does not specify it.
+	DefaultRAGTopK = 5
+	// VectorStoreAPIKeyKey is the key of the vector store API key in the credentials secret.
+	VectorStoreAPIKeyKey = "apiKey"
+
+	// The environment variables that configure the retrieval of the model server.
+	EnvEmbeddingEndpoint     = "EMBEDDING_ENDPOINT"
+	EnvVectorStoreURL        = "VECTOR_STORE_URL"
+	EnvVectorStoreCollection = "VECTOR_STORE_COLLECTION"
+	EnvVectorStoreAPIKey     = "VECTOR_STORE_API_KEY"
+	EnvRAGTopK               = "RAG_TOP_K"
+)
+
+// GetEmbeddingImage returns the image of the embedding preset.
+func GetEmbeddingImage(ragSpec *kaitov1alpha1.RAGSpec) string {
+	// The image is named after the canonical preset name, in case the workspace uses an alias.
+	imageName, _ := plugin.KaitoModelRegister.Lookup(string(ragSpec.EmbeddingPreset))
+	imageTag := plugin.KaitoModelRegister.MustGet(imageName).GetInferenceParameters().Tag
+	registryName := os.Getenv("PRESET_REGISTRY_NAME")
+	return fmt.Sprintf("%s/kaito-%s:%s", registryName, imageName, imageTag)
+}
+
+// getEmbeddingCommand returns the command of the embedding model server, which listens on the embedding port
+// rather than the inference port of its image.
+func getEmbeddingCommand(ragSpec *kaitov1alpha1.RAGSpec) []string {
+	embeddingObj := plugin.KaitoModelRegister.MustGet(string(ragSpec.EmbeddingPreset)).GetInferenceParameters()
+	modelRunParams := lo.Assign(embeddingObj.ModelRunParams, map[string]string{"port": strconv.Itoa(int(kaitov1alpha1.RAGEmbeddingPort))})
+	return utils.ShellCmd(strings.TrimSpace(embeddingObj.BaseCommand + " " + utils.BuildCmdStr(InferenceFile, modelRunParams)))
+}
+
+// configureRAG adds the embedding model sidecar to the inference pod and configures the model server, which is
+// the first container of the pod, to embed the requests with the sidecar and retrieve the documents from the
+// vector store. The pod is not changed if the workspace does not specify RAG.
+func configureRAG(workspaceObj *kaitov1alpha1.Workspace, podSpec *corev1.PodSpec) {
+	ragSpec := workspaceObj.RAG
+	if ragSpec == nil || len(podSpec.Containers) == 0 {
+		return
+	}
+
+	podSpec.Containers = append(podSpec.Containers, corev1.Container{
+		Name:    EmbeddingContainerName,
+		Image:   GetEmbeddingImage(ragSpec),
+		Command: getEmbeddingCommand(ragSpec),
+		Ports: []corev1.ContainerPort{{
+			Name:          EmbeddingContainerName,
+			ContainerPort: kaitov1alpha1.RAGEmbeddingPort,
+		}},
+		ReadinessProbe: getReadinessProbe(kaitov1alpha1.RAGEmbeddingPort),
+	})
+
+	topK := ragSpec.TopK
+	if topK == 0 {
+		topK = DefaultRAGTopK
+	}
+	env := []corev1.EnvVar{
+		{Name: EnvEmbeddingEndpoint, Value: fmt.Sprintf("http://localhost:%d", kaitov1alpha1.RAGEmbeddingPort)},
+		{Name: EnvVectorStoreURL, Value: ragSpec.VectorStore.URL},
+		{Name: EnvRAGTopK, Value: strconv.Itoa(topK)},
+	}
+	if ragSpec.VectorStore.Collection != "" {
+		env = append(env, corev1.EnvVar{Name: EnvVectorStoreCollection, Value: ragSpec.VectorStore.Collection})
+	}
+	if ragSpec.VectorStore.CredentialsSecret != "" {
+		env = append(env, corev1.EnvVar{
+			Name: EnvVectorStoreAPIKey,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: ragSpec.VectorStore.CredentialsSecret},
+					Key:                  VectorStoreAPIKeyKey,
+				},
+			},
+		})
+	}
+	podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, env...)
+}
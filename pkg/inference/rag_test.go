@@ -0,0 +1,132 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGeneratePresetInferenceManifestWithRAG(t *testing.T) {
+	utils.RegisterTestModel()
+	t.Setenv("PRESET_REGISTRY_NAME", "registry.example.com")
+
+	testcases := map[string]struct {
+		rag         *kaitov1alpha1.RAGSpec
+		expectedEnv map[string]string
+		secretEnv   bool
+	}{
+		"Without RAG": {},
+		"With RAG": {
+			rag: &kaitov1alpha1.RAGSpec{
+				EmbeddingPreset: "test-embedding-model",
+				VectorStore: kaitov1alpha1.VectorStoreSpec{
+					URL:               "http://vector-db.default.svc:6333",
+					Collection:        "docs",
+					CredentialsSecret: "vector-db-credentials",
+				},
+				TopK: 3,
+			},
+			expectedEnv: map[string]string{
+				EnvEmbeddingEndpoint:     "http://localhost:5001",
+				EnvVectorStoreURL:        "http://vector-db.default.svc:6333",
+				EnvVectorStoreCollection: "docs",
+				EnvRAGTopK:               "3",
+			},
+			secretEnv: true,
+		},
+		"With RAG and the default topK": {
+			rag: &kaitov1alpha1.RAGSpec{
+				EmbeddingPreset: "test-embedding-model",
+				VectorStore:     kaitov1alpha1.VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+			},
+			expectedEnv: map[string]string{
+				EnvEmbeddingEndpoint: "http://localhost:5001",
+				EnvVectorStoreURL:    "http://vector-db.default.svc:6333",
+				EnvRAGTopK:           "5",
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.RAG = tc.rag
+			inferenceObj := &model.PresetParam{GPUCountRequirement: "1"}
+
+			obj := GeneratePresetInferenceManifest(context.TODO(), workspace, inferenceObj, false, cloudprovider.Default)
+			containers := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers
+
+			if tc.rag == nil {
+				if len(containers) != 1 {
+					t.Errorf("expected only the model server container, got %d containers", len(containers))
+				}
+				return
+			}
+
+			if len(containers) != 2 {
+				t.Fatalf("expected the embedding sidecar to be added, got %d containers", len(containers))
+			}
+			sidecar := containers[1]
+			if sidecar.Name != EmbeddingContainerName || sidecar.Image != "registry.example.com/kaito-test-embedding-model:" {
+				t.Errorf("unexpected embedding sidecar %s with image %s", sidecar.Name, sidecar.Image)
+			}
+			if len(sidecar.Ports) != 1 || sidecar.Ports[0].ContainerPort != kaitov1alpha1.RAGEmbeddingPort {
+				t.Errorf("unexpected embedding sidecar ports %v", sidecar.Ports)
+			}
+			// The embedding model server listens on the embedding port rather than the inference port of its preset.
+			expectedCommand := []string{"/bin/sh", "-c", "python3 inference_api.py --port=5001"}
+			if !reflect.DeepEqual(sidecar.Command, expectedCommand) {
+				t.Errorf("expected the embedding sidecar command %v, got %v", expectedCommand, sidecar.Command)
+			}
+			if _, found := sidecar.Resources.Limits[corev1.ResourceName(utils.CapacityNvidiaGPU)]; found {
+				t.Errorf("the embedding sidecar must not request GPUs")
+			}
+
+			env := containers[0].Env
+			for name, value := range tc.expectedEnv {
+				envVar, found := lo.Find(env, func(e corev1.EnvVar) bool { return e.Name == name })
+				if !found || envVar.Value != value {
+					t.Errorf("expected env %s=%s, got %v", name, value, envVar)
+				}
+			}
+			apiKey, found := lo.Find(env, func(e corev1.EnvVar) bool { return e.Name == EnvVectorStoreAPIKey })
+			if found != tc.secretEnv {
+				t.Errorf("expected the API key env to be set: %t, got %v", tc.secretEnv, env)
+			}
+			if found && (apiKey.ValueFrom == nil || apiKey.ValueFrom.SecretKeyRef.Name != tc.rag.VectorStore.CredentialsSecret ||
+				apiKey.ValueFrom.SecretKeyRef.Key != VectorStoreAPIKeyKey) {
+				t.Errorf("unexpected API key env %v", apiKey)
+			}
+		})
+	}
+}
+
+func TestGenerateTemplateInferenceManifestWithRAG(t *testing.T) {
+	utils.RegisterTestModel()
+	workspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	workspace.Inference.Template.Spec.Containers = []corev1.Container{{Name: "model-server", Image: "model-server:latest"}}
+	workspace.RAG = &kaitov1alpha1.RAGSpec{
+		EmbeddingPreset: "test-embedding-model",
+		VectorStore:     kaitov1alpha1.VectorStoreSpec{URL: "http://vector-db.default.svc:6333"},
+	}
+
+	obj := GenerateTemplateInferenceManifest(context.TODO(), workspace)
+	containers := obj.Spec.Template.Spec.Containers
+	templateContainers := len(workspace.Inference.Template.Spec.Containers)
+	if len(containers) != templateContainers+1 || containers[templateContainers].Name != EmbeddingContainerName {
+		t.Errorf("expected the embedding sidecar to be appended to the template containers, got %v", containers)
+	}
+	if len(workspace.Inference.Template.Spec.Containers[0].Env) != 0 {
+		t.Errorf("the Pod template of the workspace is modified")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GenerateInferenceManifest returns the inference workload of the workspace as it is desired by the current spec.
+func GenerateInferenceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, provider cloudprovider.CloudProvider,
+	kubeClient client.Client) (client.Object, error) {
+	if workspaceObj.Inference.Template != nil {
+		return GenerateTemplateInferenceManifest(ctx, workspaceObj), nil
+	}
+
+	presetName := string(workspaceObj.Inference.Preset.Name)
+	if !plugin.KaitoModelRegister.Has(presetName) {
+		return nil, fmt.Errorf("the preset model name %s is not registered for workspace %s/%s", presetName, workspaceObj.Namespace, workspaceObj.Name)
+	}
+	model := plugin.KaitoModelRegister.MustGet(presetName)
+	inferenceObj := model.GetInferenceParameters()
+	if inferenceObj.TorchRunParams != nil && model.SupportDistributedInference() {
+		if err := updateTorchParamsForDistributedInference(ctx, kubeClient, workspaceObj, inferenceObj); err != nil {
+			return nil, err
+		}
+	}
+	return GeneratePresetInferenceManifest(ctx, workspaceObj, inferenceObj, model.SupportDistributedInference(), provider), nil
+}
+
+// ReconcileInferenceWorkload compares the existing inference workload of the workspace with the one generated from
+// the current spec, e.g., after the preset image is upgraded or the Pod template is changed, and patches the image,
+// command, args and resources of the drifted containers. The patch changes the Pod template, so the workload rolls
+// out the new pods with its update strategy. A canary rollout only patches the workload once its canary is ready.
+// The existing workload is returned, and a NotFound error if there is none.
+func ReconcileInferenceWorkload(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, provider cloudprovider.CloudProvider,
+	kubeClient client.Client) (client.Object, error) {
+	desiredObj, err := GenerateInferenceManifest(ctx, workspaceObj, provider, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var existingObj client.Object
+	switch desiredObj.(type) {
+	case *appsv1.StatefulSet:
+		existingObj = &appsv1.StatefulSet{}
+	default:
+		existingObj = &appsv1.Deployment{}
+	}
+	if err := resources.GetResource(ctx, workspaceObj.Name, workspaceObj.Namespace, kubeClient, existingObj); err != nil {
+		return nil, err
+	}
+
+	existing, isDeployment := existingObj.(*appsv1.Deployment)
+	var canary *appsv1.Deployment
+	if isDeployment && workspaceObj.Inference.RolloutStrategy == kaitov1alpha1.RolloutStrategyCanary {
+		if canary, err = runCanary(ctx, workspaceObj, existing, desiredObj.(*appsv1.Deployment), kubeClient); err != nil {
+			return nil, err
+		}
+	}
+
+	original := existingObj.DeepCopyObject().(client.Object)
+	changed := patchContainerDrift(podSpecOf(existingObj), podSpecOf(desiredObj))
+	if changed {
+		klog.InfoS("The inference workload drifted from the workspace, rolling it out", "workspace", klog.KObj(workspaceObj))
+	}
+	if isDeployment {
+		changed = syncDeploymentStrategy(existing, desiredObj.(*appsv1.Deployment)) || changed
+	}
+	// The stable pods replace the canary, whose GPUs they get back.
+	if canary != nil {
+		existing.Spec.Replicas = lo.ToPtr(stableReplicas(workspaceObj, canary))
+		changed = true
+	}
+	if !changed {
+		return existingObj, nil
+	}
+	if err := kubeClient.Patch(ctx, existingObj, client.MergeFrom(original)); err != nil {
+		return nil, err
+	}
+	if canary != nil {
+		if err := kubeClient.Delete(ctx, canary, &client.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+	}
+	return existingObj, nil
+}
+
+func podSpecOf(obj client.Object) *corev1.PodSpec {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return &workload.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &workload.Spec.Template.Spec
+	}
+	return nil
+}
+
+// patchContainerDrift sets the image, command, args and resources of the existing containers to the desired ones
+// with the same name, and reports whether any of them changed.
+func patchContainerDrift(existing, desired *corev1.PodSpec) bool {
+	drifted := false
+	for i := range existing.Containers {
+		container := &existing.Containers[i]
+		for _, desiredContainer := range desired.Containers {
+			if desiredContainer.Name != container.Name {
+				continue
+			}
+			if container.Image != desiredContainer.Image ||
+				!equality.Semantic.DeepEqual(container.Command, desiredContainer.Command) ||
+				!equality.Semantic.DeepEqual(container.Args, desiredContainer.Args) ||
+				!equality.Semantic.DeepEqual(container.Resources, desiredContainer.Resources) {
+				container.Image = desiredContainer.Image
+				container.Command = desiredContainer.Command
+				container.Args = desiredContainer.Args
+				container.Resources = desiredContainer.Resources
+				drifted = true
+			}
+			// The API server defaults the pull policy of the existing container, only an explicit policy is compared.
+			if desiredContainer.ImagePullPolicy != "" && container.ImagePullPolicy != desiredContainer.ImagePullPolicy {
+				container.ImagePullPolicy = desiredContainer.ImagePullPolicy
+				drifted = true
+			}
+			break
+		}
+	}
+	return drifted
+}
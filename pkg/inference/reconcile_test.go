@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileInferenceWorkload(t *testing.T) {
+	utils.RegisterTestModel()
+	t.Setenv("PRESET_REGISTRY_NAME", "registry.example.com")
+
+	testcases := map[string]struct {
+		existing      func(desired *appsv1.Deployment) *appsv1.Deployment
+		expectedPatch bool
+	}{
+		"Workload is up to date": {
+			existing: func(desired *appsv1.Deployment) *appsv1.Deployment {
+				return desired
+			},
+		},
+		"Image drifted": {
+			existing: func(desired *appsv1.Deployment) *appsv1.Deployment {
+				desired.Spec.Template.Spec.Containers[0].Image = "registry.example.com/kaito-test-model:0.0.1"
+				return desired
+			},
+			expectedPatch: true,
+		},
+		"Resources drifted": {
+			existing: func(desired *appsv1.Deployment) *appsv1.Deployment {
+				desired.Spec.Template.Spec.Containers[0].Resources.Limits[utils.CapacityNvidiaGPU] = resource.MustParse("2")
+				return desired
+			},
+			expectedPatch: true,
+		},
+		"Workload does not exist": {},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+
+			desiredObj, err := GenerateInferenceManifest(context.Background(), workspace, cloudprovider.Default, mockClient)
+			if err != nil {
+				t.Fatalf("failed to generate the inference workload: %v", err)
+			}
+			desired := desiredObj.(*appsv1.Deployment)
+
+			if tc.existing == nil {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+				if _, err := ReconcileInferenceWorkload(context.Background(), workspace, cloudprovider.Default, mockClient); !apierrors.IsNotFound(err) {
+					t.Errorf("expected a NotFound error, got %v", err)
+				}
+				return
+			}
+
+			mockClient.CreateOrUpdateObjectInMap(tc.existing(desired.DeepCopy()))
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			mockClient.On("Patch", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything, mock.Anything).Return(nil)
+
+			obj, err := ReconcileInferenceWorkload(context.Background(), workspace, cloudprovider.Default, mockClient)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !tc.expectedPatch {
+				mockClient.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			mockClient.AssertNumberOfCalls(t, "Patch", 1)
+			patched := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0]
+			expected := desired.Spec.Template.Spec.Containers[0]
+			if patched.Image != expected.Image {
+				t.Errorf("expected the image to be patched to %s, got %s", expected.Image, patched.Image)
+			}
+			if !patched.Resources.Limits[utils.CapacityNvidiaGPU].Equal(expected.Resources.Limits[utils.CapacityNvidiaGPU]) {
+				t.Errorf("expected the GPU limit to be patched to %v, got %v", expected.Resources.Limits, patched.Resources.Limits)
+			}
+		})
+	}
+}
+
+func TestReconcileInferenceWorkloadWithCanary(t *testing.T) {
+	utils.RegisterTestModel()
+	t.Setenv("PRESET_REGISTRY_NAME", "registry.example.com")
+
+	mockClient := utils.NewClient()
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.RolloutStrategy = kaitov1alpha1.RolloutStrategyCanary
+
+	desiredObj, err := GenerateInferenceManifest(context.Background(), workspace, cloudprovider.Default, mockClient)
+	if err != nil {
+		t.Fatalf("failed to generate the inference workload: %v", err)
+	}
+	stable := desiredObj.(*appsv1.Deployment).DeepCopy()
+	stable.Spec.Replicas = lo.ToPtr(int32(2))
+	stable.Spec.Template.Spec.Containers[0].Image = "registry.example.com/kaito-test-model:0.0.1"
+	mockClient.CreateOrUpdateObjectInMap(stable)
+
+	canaryKey := client.ObjectKey{Name: CanaryName(workspace), Namespace: workspace.Namespace}
+	// The canary pod becomes ready as soon as it is created.
+	mockClient.UpdateCb = func(key types.NamespacedName) {
+		if obj, found := mockClient.CreateMapWithType(&appsv1.Deployment{})[key]; found && key == canaryKey {
+			obj.(*appsv1.Deployment).Status.ReadyReplicas = 1
+		}
+	}
+	mockClient.On("Get", mock.IsType(context.Background()), canaryKey, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError()).Once()
+	// The readiness of the canary is polled with a timeout context.
+	mockClient.On("Get", mock.Anything, mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+	mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+	mockClient.On("Patch", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+
+	obj, err := ReconcileInferenceWorkload(context.Background(), workspace, cloudprovider.Default, mockClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	createCall, found := lo.Find(mockClient.Calls, func(call mock.Call) bool { return call.Method == "Create" })
+	if !found {
+		t.Fatalf("expected the canary to be created")
+	}
+	canary := createCall.Arguments.Get(1).(*appsv1.Deployment)
+	if canary.Name != canaryKey.Name || lo.FromPtr(canary.Spec.Replicas) != 1 ||
+		canary.Spec.Template.Spec.Containers[0].Image != desiredObj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0].Image {
+		t.Errorf("expected a canary with a single pod of the desired image, got %s with %d replicas of %s",
+			canary.Name, lo.FromPtr(canary.Spec.Replicas), canary.Spec.Template.Spec.Containers[0].Image)
+	}
+	if canary.Spec.Selector.MatchLabels[kaitov1alpha1.LabelCanary] != "true" {
+		t.Errorf("expected the canary label in the selector of the canary, got %v", canary.Spec.Selector.MatchLabels)
+	}
+	mockClient.AssertNumberOfCalls(t, "Patch", 2)
+	mockClient.AssertNumberOfCalls(t, "Delete", 1)
+
+	rolledOut := obj.(*appsv1.Deployment)
+	if replicas := lo.FromPtr(rolledOut.Spec.Replicas); replicas != 2 {
+		t.Errorf("expected the stable replicas to be restored to 2, got %d", replicas)
+	}
+	if rolledOut.Spec.Template.Spec.Containers[0].Image != desiredObj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0].Image {
+		t.Errorf("expected the stable image to be rolled out, got %s", rolledOut.Spec.Template.Spec.Containers[0].Image)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CanaryName returns the name of the canary Deployment of a canary rollout of the workspace.
+func CanaryName(workspaceObj *kaitov1alpha1.Workspace) string {
+	return workspaceObj.Name + "-canary"
+}
+
+// generateCanaryManifest returns a Deployment that runs a single pod of the desired inference workload. The pod has
+// the labels of the workspace, so the service of the workspace sends it a share of the requests.
+func generateCanaryManifest(workspaceObj *kaitov1alpha1.Workspace, desired *appsv1.Deployment, stableReplicas int32) *appsv1.Deployment {
+	canary := desired.DeepCopy()
+	canary.Name = CanaryName(workspaceObj)
+	canary.Annotations = lo.Assign(canary.Annotations, map[string]string{
+		kaitov1alpha1.AnnotationStableReplicas: strconv.Itoa(int(stableReplicas)),
+	})
+	canary.Spec.Replicas = lo.ToPtr(int32(1))
+	canary.Spec.Selector = canary.Spec.Selector.DeepCopy()
+	canary.Spec.Selector.MatchLabels[kaitov1alpha1.LabelCanary] = "true"
+	canary.Spec.Template.Labels = lo.Assign(canary.Spec.Template.Labels, map[string]string{kaitov1alpha1.LabelCanary: "true"})
+	// A single pod has nothing to spread.
+	canary.Spec.Template.Spec.TopologySpreadConstraints = nil
+	return canary
+}
+
+// stableReplicas returns the replicas of the stable Deployment before the canary took the GPUs of one of them.
+func stableReplicas(workspaceObj *kaitov1alpha1.Workspace, canary *appsv1.Deployment) int32 {
+	replicas, err := strconv.Atoi(canary.Annotations[kaitov1alpha1.AnnotationStableReplicas])
+	if err != nil {
+		return int32(lo.FromPtr(workspaceObj.Resource.Count))
+	}
+	return int32(replicas)
+}
+
+// runCanary runs the first stage of a canary rollout of the stable Deployment to the desired one. A canary Deployment
+// with a single pod of the desired version replaces one pod of the stable Deployment, whose GPUs it takes, and must
+// become ready before the stable pods are replaced. Until then, the stable pods keep serving and an error is returned.
+// The ready canary is returned once the stable pods may be rolled out, or if the stable Deployment no longer drifts,
+// e.g., the change was reverted. The caller then restores the stable replicas and deletes the canary. No canary is
+// returned if there is nothing to roll out.
+func runCanary(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, stable, desired *appsv1.Deployment,
+	kubeClient client.Client) (*appsv1.Deployment, error) {
+	canary := &appsv1.Deployment{}
+	// GetResource retries a NotFound error, while most workspaces have no canary.
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: CanaryName(workspaceObj), Namespace: workspaceObj.Namespace}, canary)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	canaryExists := err == nil
+
+	if !patchContainerDrift(&stable.DeepCopy().Spec.Template.Spec, &desired.Spec.Template.Spec) {
+		if canaryExists {
+			return canary, nil
+		}
+		return nil, nil
+	}
+
+	if !canaryExists {
+		klog.InfoS("starting the canary rollout of the inference workload", "workspace", klog.KObj(workspaceObj))
+		canary = generateCanaryManifest(workspaceObj, desired, lo.FromPtr(stable.Spec.Replicas))
+		if err := resources.CreateResource(ctx, canary, kubeClient); client.IgnoreAlreadyExists(err) != nil {
+			return nil, err
+		}
+	} else if original := canary.DeepCopy(); patchContainerDrift(&canary.Spec.Template.Spec, &desired.Spec.Template.Spec) {
+		// The workspace changed again during the canary stage, the canary runs the latest version.
+		if err := kubeClient.Patch(ctx, canary, client.MergeFrom(original)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The canary pod takes the GPUs of a stable pod. The replicas are computed from the ones recorded by the canary,
+	// so that a reconcile after the stable Deployment was scaled down does not scale it down again.
+	if replicas := lo.Max([]int32{stableReplicas(workspaceObj, canary) - 1, 0}); lo.FromPtr(stable.Spec.Replicas) != replicas {
+		original := stable.DeepCopy()
+		stable.Spec.Replicas = lo.ToPtr(replicas)
+		if err := kubeClient.Patch(ctx, stable, client.MergeFrom(original)); err != nil {
+			return nil, err
+		}
+	}
+
+	readinessTimeout := plugin.KaitoModelRegister.MustGet(string(workspaceObj.Inference.Preset.Name)).GetInferenceParameters().ReadinessTimeout
+	if err := resources.CheckResourceStatus(canary, kubeClient, readinessTimeout); err != nil {
+		return nil, fmt.Errorf("the canary of workspace %s/%s is not ready, the rollout is paused: %w", workspaceObj.Namespace, workspaceObj.Name, err)
+	}
+	klog.InfoS("the canary of the inference workload is ready, rolling out the workload", "workspace", klog.KObj(workspaceObj))
+	return canary, nil
+}
+
+// syncDeploymentStrategy sets the update strategy of the existing Deployment to the desired one, and reports whether
+// it changed. The default strategy of a workspace without a rollout strategy is left to the API server.
+func syncDeploymentStrategy(existing, desired *appsv1.Deployment) bool {
+	if desired.Spec.Strategy.Type == "" || equality.Semantic.DeepEqual(existing.Spec.Strategy, desired.Spec.Strategy) {
+		return false
+	}
+	existing.Spec.Strategy = desired.Spec.Strategy
+	return true
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"strconv"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// runtimeServer describes the model server of an inference runtime other than transformers.
+type runtimeServer struct {
+	// baseCommand starts the model server.
+	baseCommand string
+	// modelFlag, portFlag and shardFlag are the flags of the model weights directory, the port and the number of GPUs
+	// the model is sharded across. hostFlag is the flag of the address the server listens on.
+	modelFlag, portFlag, shardFlag, hostFlag string
+	// healthPath is the path of the health endpoint that the probes check.
+	healthPath string
+}
+
+var runtimeServers = map[kaitov1alpha1.InferenceRuntime]runtimeServer{
+	kaitov1alpha1.InferenceRuntimeVLLM: {
+		baseCommand: "python3 -m vllm.entrypoints.openai.api_server",
+		modelFlag:   "model",
+		portFlag:    "port",
+		shardFlag:   "tensor-parallel-size",
+		hostFlag:    "host",
+		healthPath:  "/health",
+	},
+	kaitov1alpha1.InferenceRuntimeTGI: {
+		baseCommand: "text-generation-launcher",
+		modelFlag:   "model-id",
+		portFlag:    "port",
+		shardFlag:   "num-shard",
+		hostFlag:    "hostname",
+		healthPath:  "/health",
+	},
+}
+
+// runtimeImageTag returns the tag of the model image that serves the model with the runtime. The images of the
+// runtimes other than transformers carry the runtime as a tag suffix, e.g., 0.0.4-vllm.
+func runtimeImageTag(tag string, runtime kaitov1alpha1.InferenceRuntime) string {
+	if runtime == kaitov1alpha1.InferenceRuntimeTransformers {
+		return tag
+	}
+	return tag + "-" + string(runtime)
+}
+
+// configureRuntime replaces the command of the model server, which is the first container of the pod, with the
+// server of the runtime of the workspace, which serves the weights of the model image on the inference port, and
+// points the probes to its health endpoint. The pod is not changed for the transformers runtime.
+func configureRuntime(workspaceObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam, podSpec *corev1.PodSpec) {
+	runtime := workspaceObj.Inference.GetRuntime()
+	server, ok := runtimeServers[runtime]
+	if !ok || len(podSpec.Containers) == 0 {
+		return
+	}
+	weightsPath := inferenceObj.WeightsPath
+	if weightsPath == "" {
+		weightsPath = DefaultWeightsPath
+	}
+
+	// The arguments of the preset take precedence over the defaults.
+	params := lo.Assign(map[string]string{
+		server.modelFlag: weightsPath,
+		server.portFlag:  strconv.Itoa(int(workspaceObj.Inference.GetPort())),
+		server.shardFlag: strconv.FormatInt(gpusPerReplica(workspaceObj, inferenceObj), 10),
+	}, inferenceObj.Runtimes[string(runtime)])
+	if host := modelServerHost(workspaceObj); host != "" {
+		params[server.hostFlag] = host
+	}
+	container := &podSpec.Containers[0]
+	container.Command = utils.ShellCmd(utils.BuildCmdStr(server.baseCommand, params))
+	for _, probe := range []*corev1.Probe{container.LivenessProbe, container.ReadinessProbe, container.StartupProbe} {
+		if probe != nil && probe.HTTPGet != nil {
+			probe.HTTPGet.Path = server.healthPath
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestGeneratePresetInferenceManifestWithRuntime(t *testing.T) {
+	utils.RegisterTestModel()
+	testcases := map[string]struct {
+		runtime          kaitov1alpha1.InferenceRuntime
+		auth             *kaitov1alpha1.InferenceAuth
+		expectedImageTag string
+		expectedArgs     []string
+		expectedProbe    string
+		expectDrain      bool
+	}{
+		"Default runtime": {
+			expectedImageTag: ":0.0.1",
+			expectedArgs:     []string{"accelerate launch", InferenceFile},
+			expectedProbe:    ProbePath,
+			expectDrain:      true,
+		},
+		"vLLM": {
+			runtime:          kaitov1alpha1.InferenceRuntimeVLLM,
+			expectedImageTag: ":0.0.1-vllm",
+			expectedArgs: []string{"python3 -m vllm.entrypoints.openai.api_server", "--model=" + DefaultWeightsPath, "--port=5000",
+				"--tensor-parallel-size=2", "--dtype=bfloat16"},
+			expectedProbe: "/health",
+		},
+		"TGI": {
+			runtime:          kaitov1alpha1.InferenceRuntimeTGI,
+			expectedImageTag: ":0.0.1-tgi",
+			expectedArgs:     []string{"text-generation-launcher", "--model-id=" + DefaultWeightsPath, "--port=5000", "--num-shard=2"},
+			expectedProbe:    "/health",
+		},
+		"vLLM behind the auth proxy": {
+			runtime:          kaitov1alpha1.InferenceRuntimeVLLM,
+			auth:             &kaitov1alpha1.InferenceAuth{SecretName: "inference-token"},
+			expectedImageTag: ":0.0.1-vllm",
+			expectedArgs:     []string{"python3 -m vllm.entrypoints.openai.api_server", "--port=5000", "--host=127.0.0.1"},
+			expectedProbe:    "/health",
+		},
+		"TGI behind the auth proxy": {
+			runtime:          kaitov1alpha1.InferenceRuntimeTGI,
+			auth:             &kaitov1alpha1.InferenceAuth{SecretName: "inference-token"},
+			expectedImageTag: ":0.0.1-tgi",
+			expectedArgs:     []string{"text-generation-launcher", "--port=5000", "--hostname=127.0.0.1"},
+			expectedProbe:    "/health",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.Runtime = tc.runtime
+			workspace.Inference.Auth = tc.auth
+			inferenceObj := &model.PresetParam{
+				GPUCountRequirement: "2",
+				BaseCommand:         "accelerate launch",
+				Tag:                 "0.0.1",
+				SupportsDrain:       true,
+				Runtimes: map[string]map[string]string{
+					string(kaitov1alpha1.InferenceRuntimeVLLM): {"dtype": "bfloat16"},
+					string(kaitov1alpha1.InferenceRuntimeTGI):  {},
+				},
+			}
+
+			obj := GeneratePresetInferenceManifest(context.TODO(), workspace, inferenceObj, false, cloudprovider.Default)
+			container := obj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0]
+
+			if !strings.HasSuffix(container.Image, tc.expectedImageTag) {
+				t.Errorf("image is %s, expect the tag %s", container.Image, tc.expectedImageTag)
+			}
+			command := container.Command[len(container.Command)-1]
+			for _, arg := range tc.expectedArgs {
+				if !strings.Contains(command, arg) {
+					t.Errorf("command %q does not contain %q", command, arg)
+				}
+			}
+			if container.ReadinessProbe.HTTPGet.Path != tc.expectedProbe || container.StartupProbe.HTTPGet.Path != tc.expectedProbe ||
+				container.LivenessProbe.HTTPGet.Path != tc.expectedProbe {
+				t.Errorf("probes do not check %s", tc.expectedProbe)
+			}
+			if (container.Lifecycle != nil) != tc.expectDrain {
+				t.Errorf("drain hook is %v, expect drain %t", container.Lifecycle, tc.expectDrain)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/model"
+	"k8s.io/klog/v2"
+)
+
+// smokeTestTimeout is the time the model server is given to answer the smoke test. The request blocks a worker of
+// the controller, a model server that is still slow after the model is loaded is tested again by a later reconcile.
+const smokeTestTimeout = 15 * time.Second
+
+// DefaultSmokeTest is the canned request of the kaito inference server of the transformers runtime.
+var DefaultSmokeTest = model.SmokeTest{
+	Path:        "/chat",
+	Body:        `{"prompt": "What is Kubernetes?", "generate_kwargs": {"max_length": 32}}`,
+	ResultField: "Result",
+}
+
+var smokeTestClient = &http.Client{Timeout: smokeTestTimeout}
+
+// InferenceEndpoint returns the endpoint of the inference service of the workspace within the cluster.
+func InferenceEndpoint(workspaceObj *kaitov1alpha1.Workspace) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:80", workspaceObj.Name, workspaceObj.Namespace)
+}
+
+// RunInferenceSmokeTest posts the canned inference request of the preset to the endpoint and checks that the model
+// server answers it with a valid response, i.e., a 2xx status and a JSON body with a non-empty result. The request
+// carries the bearer token if it is not empty, i.e., if the endpoint requires one.
+func RunInferenceSmokeTest(ctx context.Context, endpoint, token string, preset *model.PresetParam) error {
+	smokeTest := DefaultSmokeTest
+	if preset != nil && preset.SmokeTest != nil {
+		smokeTest = *preset.SmokeTest
+	}
+	url := strings.TrimSuffix(endpoint, "/") + smokeTest.Path
+	klog.InfoS("RunInferenceSmokeTest", "url", url)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(smokeTest.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := smokeTestClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("smoke test request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the smoke test response of %s: %w", url, err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("smoke test request to %s returned status code %d: %s", url, resp.StatusCode, truncate(string(body)))
+	}
+	response := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("smoke test response of %s is not a JSON object: %s", url, truncate(string(body)))
+	}
+	result := bytes.TrimSpace(response[smokeTest.ResultField])
+	switch string(result) {
+	case "", "null", `""`, "[]", "{}":
+		return fmt.Errorf("smoke test response of %s has no %s: %s", url, smokeTest.ResultField, truncate(string(body)))
+	}
+	return nil
+}
+
+// truncate shortens a response body for an error message.
+func truncate(body string) string {
+	const maxLength = 256
+	if len(body) <= maxLength {
+		return body
+	}
+	return body[:maxLength] + "..."
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azure/kaito/pkg/model"
+	"github.com/samber/lo"
+)
+
+func TestRunInferenceSmokeTest(t *testing.T) {
+	testcases := map[string]struct {
+		preset         *model.PresetParam
+		token          string
+		statusCode     int
+		response       string
+		expectedPath   string
+		expectedBody   string
+		expectedErrMsg string
+	}{
+		"Valid response of the transformers server": {
+			statusCode:   http.StatusOK,
+			response:     `{"Result": "Kubernetes is a container orchestrator."}`,
+			expectedPath: DefaultSmokeTest.Path,
+			expectedBody: DefaultSmokeTest.Body,
+		},
+		"Request with the bearer token of the endpoint": {
+			token:        "secret-token",
+			statusCode:   http.StatusOK,
+			response:     `{"Result": "Kubernetes is a container orchestrator."}`,
+			expectedPath: DefaultSmokeTest.Path,
+			expectedBody: DefaultSmokeTest.Body,
+		},
+		"Valid response of the smoke test of the preset": {
+			preset: &model.PresetParam{
+				SmokeTest: &model.SmokeTest{Path: "/generate", Body: `{"prompts": ["Kubernetes is"]}`, ResultField: "results"},
+			},
+			statusCode:   http.StatusOK,
+			response:     `{"results": [{"prompt": "Kubernetes is", "response": "a container orchestrator."}]}`,
+			expectedPath: "/generate",
+			expectedBody: `{"prompts": ["Kubernetes is"]}`,
+		},
+		"Failing model server": {
+			statusCode:     http.StatusInternalServerError,
+			response:       `{"detail": "Pipeline not initialized"}`,
+			expectedPath:   DefaultSmokeTest.Path,
+			expectedBody:   DefaultSmokeTest.Body,
+			expectedErrMsg: "returned status code 500",
+		},
+		"Response without a result": {
+			statusCode:     http.StatusOK,
+			response:       `{"Result": ""}`,
+			expectedPath:   DefaultSmokeTest.Path,
+			expectedBody:   DefaultSmokeTest.Body,
+			expectedErrMsg: "has no Result",
+		},
+		"Response that is not JSON": {
+			statusCode:     http.StatusOK,
+			response:       "<html>Bad Gateway</html>",
+			expectedPath:   DefaultSmokeTest.Path,
+			expectedBody:   DefaultSmokeTest.Body,
+			expectedErrMsg: "is not a JSON object",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.URL.Path != tc.expectedPath || string(body) != tc.expectedBody {
+					t.Errorf("smoke test request is %s %s %s, expect POST %s %s", r.Method, r.URL.Path, body, tc.expectedPath, tc.expectedBody)
+				}
+				if authorization := r.Header.Get("Authorization"); authorization != lo.Ternary(tc.token == "", "", "Bearer "+tc.token) {
+					t.Errorf("smoke test request has the authorization %q, expect the token %q", authorization, tc.token)
+				}
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+
+			err := RunInferenceSmokeTest(context.Background(), server.URL, tc.token, tc.preset)
+			if tc.expectedErrMsg == "" && err != nil {
+				t.Errorf("expect the smoke test to succeed, got %v", err)
+			}
+			if tc.expectedErrMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErrMsg)) {
+				t.Errorf("expect the smoke test to fail with %q, got %v", tc.expectedErrMsg, err)
+			}
+		})
+	}
+}
@@ -7,14 +7,24 @@ import (
 
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
 	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func CreateTemplateInference(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) (client.Object, error) {
-	depObj := resources.GenerateDeploymentManifestWithPodTemplate(ctx, workspaceObj, tolerations)
+	depObj := GenerateTemplateInferenceManifest(ctx, workspaceObj)
 	err := resources.CreateResource(ctx, client.Object(depObj), kubeClient)
 	if client.IgnoreAlreadyExists(err) != nil {
 		return nil, err
 	}
 	return depObj, nil
 }
+
+// GenerateTemplateInferenceManifest returns the Deployment that runs the Pod template of the workspace.
+func GenerateTemplateInferenceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace) *appsv1.Deployment {
+	depObj := resources.GenerateDeploymentManifestWithPodTemplate(ctx, workspaceObj, tolerations)
+	configureRAG(workspaceObj, &depObj.Spec.Template.Spec)
+	utils.ConfigModelCacheVolume(workspaceObj, &depObj.Spec.Template.Spec)
+	return depObj
+}
@@ -0,0 +1,164 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VariantName returns the name of the Deployment and the Service of an inference variant of the workspace.
+func VariantName(workspaceObj *kaitov1alpha1.Workspace, variant *kaitov1alpha1.PresetVariant) string {
+	return fmt.Sprintf("%s-%s", workspaceObj.Name, variant.Name)
+}
+
+// VariantReplicas splits the replicas of the workspace among its variants in proportion to their weights.
+// The service of the workspace balances the requests evenly across the ready pods of all variants, so the share of
+// the replicas of a variant is the share of the traffic it serves. Every variant with a weight runs at least one replica.
+func VariantReplicas(variants []kaitov1alpha1.PresetVariant, count int) []int {
+	replicas := make([]int, len(variants))
+	assigned := 0
+	for i, variant := range variants {
+		replicas[i] = int(variant.Weight) * count / 100
+		if replicas[i] == 0 && variant.Weight > 0 {
+			replicas[i] = 1
+		}
+		assigned += replicas[i]
+	}
+
+	// deficit is how many replicas a variant lacks to serve exactly its weight.
+	deficit := func(i int) float64 {
+		return float64(variants[i].Weight)*float64(count)/100 - float64(replicas[i])
+	}
+	for ; assigned < count; assigned++ {
+		neediest := 0
+		for i := range variants {
+			if deficit(i) > deficit(neediest) {
+				neediest = i
+			}
+		}
+		replicas[neediest]++
+	}
+	for ; assigned > count; assigned-- {
+		richest := -1
+		for i := range variants {
+			if replicas[i] > 1 && (richest < 0 || deficit(i) < deficit(richest)) {
+				richest = i
+			}
+		}
+		if richest < 0 {
+			break
+		}
+		replicas[richest]--
+	}
+	return replicas
+}
+
+// GenerateVariantInferenceManifests returns a Deployment for each inference variant of the workspace. The pods of
+// a variant carry the workspace label, which the service of the workspace selects, and the label of the variant.
+func GenerateVariantInferenceManifests(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace,
+	provider cloudprovider.CloudProvider) ([]*appsv1.Deployment, error) {
+	variants := workspaceObj.Inference.Variants
+	replicas := VariantReplicas(variants, lo.FromPtr(workspaceObj.Resource.Count))
+
+	deployments := make([]*appsv1.Deployment, 0, len(variants))
+	for i := range variants {
+		variant := &variants[i]
+		presetName := string(variant.Preset.Name)
+		if !plugin.KaitoModelRegister.Has(presetName) {
+			return nil, fmt.Errorf("the preset model name %s of variant %s is not registered for workspace %s/%s",
+				presetName, variant.Name, workspaceObj.Namespace, workspaceObj.Name)
+		}
+
+		variantObj := variantWorkspace(workspaceObj, variant, replicas[i])
+		inferenceObj := plugin.KaitoModelRegister.MustGet(presetName).GetInferenceParameters()
+		depObj := GeneratePresetInferenceManifest(ctx, variantObj, inferenceObj, false, provider).(*appsv1.Deployment)
+
+		depObj.Name = VariantName(workspaceObj, variant)
+		depObj.Spec.Selector.MatchLabels[kaitov1alpha1.LabelVariantName] = variant.Name
+		depObj.Spec.Template.Labels[kaitov1alpha1.LabelVariantName] = variant.Name
+		deployments = append(deployments, depObj)
+	}
+	return deployments, nil
+}
+
+// GenerateVariantServiceManifests returns a Service for each inference variant of the workspace, which sends
+// the requests to the pods of that variant only, e.g., to compare the variants directly.
+func GenerateVariantServiceManifests(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace) []*corev1.Service {
+	services := make([]*corev1.Service, 0, len(workspaceObj.Inference.Variants))
+	for i := range workspaceObj.Inference.Variants {
+		variant := &workspaceObj.Inference.Variants[i]
+		var metricsPort int32
+		if presetName := string(variant.Preset.Name); plugin.KaitoModelRegister.Has(presetName) {
+			metricsPort = plugin.KaitoModelRegister.MustGet(presetName).GetInferenceParameters().MetricsPort
+		}
+		serviceObj := resources.GenerateServiceManifest(ctx, workspaceObj, corev1.ServiceTypeClusterIP, false, metricsPort)
+		serviceObj.Name = VariantName(workspaceObj, variant)
+		serviceObj.Annotations = resources.WithCommonAnnotations(workspaceObj, nil)
+		serviceObj.Spec.Selector[kaitov1alpha1.LabelVariantName] = variant.Name
+		services = append(services, serviceObj)
+	}
+	return services
+}
+
+// ReconcileVariantInference creates the Deployments of the inference variants of the workspace that do not exist
+// yet, and updates the replicas and the drifted containers of the existing ones, e.g., after the weights of the
+// variants are changed to shift the traffic to a new version. The Deployments are returned in the order of the variants.
+func ReconcileVariantInference(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, provider cloudprovider.CloudProvider,
+	kubeClient client.Client) ([]*appsv1.Deployment, error) {
+	desiredObjs, err := GenerateVariantInferenceManifests(ctx, workspaceObj, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]*appsv1.Deployment, 0, len(desiredObjs))
+	for _, desiredObj := range desiredObjs {
+		existingObj := &appsv1.Deployment{}
+		if err := resources.GetResource(ctx, desiredObj.Name, desiredObj.Namespace, kubeClient, existingObj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			if err := resources.CreateResource(ctx, desiredObj, kubeClient); client.IgnoreAlreadyExists(err) != nil {
+				return nil, err
+			}
+			deployments = append(deployments, desiredObj)
+			continue
+		}
+
+		original := existingObj.DeepCopy()
+		drifted := patchContainerDrift(&existingObj.Spec.Template.Spec, &desiredObj.Spec.Template.Spec)
+		if lo.FromPtr(existingObj.Spec.Replicas) != lo.FromPtr(desiredObj.Spec.Replicas) {
+			existingObj.Spec.Replicas = desiredObj.Spec.Replicas
+			drifted = true
+		}
+		if drifted {
+			klog.InfoS("The inference variant drifted from the workspace, rolling it out", "workspace", klog.KObj(workspaceObj),
+				"deployment", existingObj.Name)
+			if err := kubeClient.Patch(ctx, existingObj, client.MergeFrom(original)); err != nil {
+				return nil, err
+			}
+		}
+		deployments = append(deployments, existingObj)
+	}
+	return deployments, nil
+}
+
+// variantWorkspace returns a copy of the workspace that deploys the preset of the variant with the given replicas.
+func variantWorkspace(workspaceObj *kaitov1alpha1.Workspace, variant *kaitov1alpha1.PresetVariant, replicas int) *kaitov1alpha1.Workspace {
+	variantObj := workspaceObj.DeepCopy()
+	variantObj.Inference.Preset = variant.Preset.DeepCopy()
+	variantObj.Inference.Variants = nil
+	variantObj.Resource.Count = lo.ToPtr(replicas)
+	return variantObj
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+)
+
+func TestVariantReplicas(t *testing.T) {
+	testcases := map[string]struct {
+		weights          []int32
+		count            int
+		expectedReplicas []int
+	}{
+		"Even split": {
+			weights:          []int32{50, 50},
+			count:            4,
+			expectedReplicas: []int{2, 2},
+		},
+		"Uneven split": {
+			weights:          []int32{70, 30},
+			count:            10,
+			expectedReplicas: []int{7, 3},
+		},
+		"Remainder goes to the most under-served variant": {
+			weights:          []int32{60, 40},
+			count:            3,
+			expectedReplicas: []int{2, 1},
+		},
+		"Small weight still runs a replica": {
+			weights:          []int32{95, 5},
+			count:            4,
+			expectedReplicas: []int{3, 1},
+		},
+		"Zero weight runs no replica": {
+			weights:          []int32{100, 0},
+			count:            2,
+			expectedReplicas: []int{2, 0},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			variants := lo.Map(tc.weights, func(weight int32, _ int) v1alpha1.PresetVariant {
+				return v1alpha1.PresetVariant{Weight: weight}
+			})
+			if replicas := VariantReplicas(variants, tc.count); !reflect.DeepEqual(replicas, tc.expectedReplicas) {
+				t.Errorf("%s: replicas are %v, expect %v", k, replicas, tc.expectedReplicas)
+			}
+		})
+	}
+}
+
+func TestGenerateVariantInferenceManifests(t *testing.T) {
+	utils.RegisterTestModel()
+	t.Setenv("PRESET_REGISTRY_NAME", "registry.example.com")
+
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Resource.Count = lo.ToPtr(4)
+	workspace.Inference.Preset = nil
+	workspace.Inference.Variants = []v1alpha1.PresetVariant{
+		{Name: "stable", Preset: v1alpha1.PresetSpec{PresetMeta: v1alpha1.PresetMeta{Name: "test-model"}}, Weight: 75},
+		{Name: "canary", Preset: v1alpha1.PresetSpec{PresetMeta: v1alpha1.PresetMeta{Name: "test-model", Version: "0.0.2"}}, Weight: 25},
+	}
+
+	deployments, err := GenerateVariantInferenceManifests(context.Background(), workspace, cloudprovider.Default)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deployments) != 2 {
+		t.Fatalf("expected 2 deployments, got %d", len(deployments))
+	}
+
+	expected := []struct {
+		name     string
+		variant  string
+		replicas int32
+		image    string
+	}{
+		{name: workspace.Name + "-stable", variant: "stable", replicas: 3, image: "registry.example.com/kaito-test-model:"},
+		{name: workspace.Name + "-canary", variant: "canary", replicas: 1, image: "registry.example.com/kaito-test-model:0.0.2"},
+	}
+	for i, depObj := range deployments {
+		if depObj.Name != expected[i].name {
+			t.Errorf("deployment %d is named %s, expect %s", i, depObj.Name, expected[i].name)
+		}
+		if replicas := lo.FromPtr(depObj.Spec.Replicas); replicas != expected[i].replicas {
+			t.Errorf("deployment %s runs %d replicas, expect %d", depObj.Name, replicas, expected[i].replicas)
+		}
+		if image := depObj.Spec.Template.Spec.Containers[0].Image; image != expected[i].image {
+			t.Errorf("deployment %s runs image %s, expect %s", depObj.Name, image, expected[i].image)
+		}
+		// The service of the workspace selects the pods of all variants, the service of a variant only its own pods.
+		labels := depObj.Spec.Template.Labels
+		if labels[v1alpha1.LabelWorkspaceName] != workspace.Name || labels[v1alpha1.LabelVariantName] != expected[i].variant {
+			t.Errorf("deployment %s has pod labels %v", depObj.Name, labels)
+		}
+		if !reflect.DeepEqual(depObj.Spec.Selector.MatchLabels, labels) {
+			t.Errorf("deployment %s selects %v, expect %v", depObj.Name, depObj.Spec.Selector.MatchLabels, labels)
+		}
+	}
+
+	services := GenerateVariantServiceManifests(context.Background(), workspace)
+	if len(services) != 2 || services[1].Name != workspace.Name+"-canary" ||
+		services[1].Spec.Selector[v1alpha1.LabelVariantName] != "canary" {
+		t.Errorf("unexpected variant services %v", services)
+	}
+}
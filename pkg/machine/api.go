@@ -0,0 +1,321 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// KarpenterGroup is the API group of the machines and of the node claims that replaced them.
+	KarpenterGroup = "karpenter.sh"
+	// NodeClaimV1Beta1 and NodeClaimV1 are the versions of karpenter that serve node claims instead of machines.
+	NodeClaimV1Beta1 = "v1beta1"
+	NodeClaimV1      = "v1"
+
+	nodeClaimKind     = "NodeClaim"
+	nodeClaimResource = "nodeclaims"
+	nodePoolKind      = "NodePool"
+)
+
+// API converts the machines of kaito from and to the version of the karpenter API that the cluster serves. The
+// machines are built and inspected as v1alpha5 Machines, and converted right before they are sent to the API server.
+type API interface {
+	// GroupVersionKind is the kind of the objects the machines are stored as.
+	GroupVersionKind() schema.GroupVersionKind
+	// NewObject returns an empty object of the kind, e.g., to watch the machines.
+	NewObject() client.Object
+	// NewList returns an empty list of the kind.
+	NewList() client.ObjectList
+	// ToObject converts the machine to an object of the kind.
+	ToObject(machineObj *v1alpha5.Machine) (client.Object, error)
+	// FromObject converts an object of the kind into the machine.
+	FromObject(obj client.Object, machineObj *v1alpha5.Machine) error
+	// FromList converts a list of the kind to machines.
+	FromList(list client.ObjectList) ([]v1alpha5.Machine, error)
+	// NewProvisioner returns an empty object of the kind that provisions the machines, i.e., a Provisioner or a
+	// NodePool.
+	NewProvisioner() client.Object
+}
+
+// DefaultAPI is the API of the machines, it is detected at startup, see DetectAPI.
+var DefaultAPI API = MachineAPI{}
+
+// DetectAPI returns the API of the newest version of the karpenter machines that the cluster serves. The node claims
+// replaced the v1alpha5 Machines, which are assumed if the cluster serves no node claims.
+func DetectAPI(discoveryClient discovery.DiscoveryInterface) (API, error) {
+	for _, version := range []string{NodeClaimV1, NodeClaimV1Beta1} {
+		resources, err := discoveryClient.ServerResourcesForGroupVersion(schema.GroupVersion{Group: KarpenterGroup, Version: version}.String())
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if lo.ContainsBy(resources.APIResources, func(r metav1.APIResource) bool { return r.Name == nodeClaimResource }) {
+			return NodeClaimAPI{Version: version}, nil
+		}
+	}
+	return MachineAPI{}, nil
+}
+
+// MachineAPI stores the machines as v1alpha5 Machines, the conversions return the machines as they are.
+type MachineAPI struct{}
+
+func (MachineAPI) GroupVersionKind() schema.GroupVersionKind {
+	return v1alpha5.SchemeGroupVersion.WithKind("Machine")
+}
+
+func (MachineAPI) NewObject() client.Object {
+	return &v1alpha5.Machine{}
+}
+
+func (MachineAPI) NewList() client.ObjectList {
+	return &v1alpha5.MachineList{}
+}
+
+func (MachineAPI) NewProvisioner() client.Object {
+	return &v1alpha5.Provisioner{}
+}
+
+func (MachineAPI) ToObject(machineObj *v1alpha5.Machine) (client.Object, error) {
+	return machineObj, nil
+}
+
+func (MachineAPI) FromObject(obj client.Object, machineObj *v1alpha5.Machine) error {
+	m, ok := obj.(*v1alpha5.Machine)
+	if !ok {
+		return fmt.Errorf("expected a Machine, got %T", obj)
+	}
+	if m != machineObj {
+		m.DeepCopyInto(machineObj)
+	}
+	return nil
+}
+
+func (MachineAPI) FromList(list client.ObjectList) ([]v1alpha5.Machine, error) {
+	machineList, ok := list.(*v1alpha5.MachineList)
+	if !ok {
+		return nil, fmt.Errorf("expected a MachineList, got %T", list)
+	}
+	return machineList.Items, nil
+}
+
+// NodeClaimAPI stores the machines as cluster-scoped karpenter node claims of the version. The node claims are
+// handled as unstructured objects, so that kaito does not depend on the karpenter release that introduced them.
+type NodeClaimAPI struct {
+	// Version is NodeClaimV1Beta1 or NodeClaimV1.
+	Version string
+}
+
+func (a NodeClaimAPI) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: KarpenterGroup, Version: a.Version, Kind: nodeClaimKind}
+}
+
+func (a NodeClaimAPI) NewObject() client.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(a.GroupVersionKind())
+	return obj
+}
+
+func (a NodeClaimAPI) NewList() client.ObjectList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(a.GroupVersionKind().GroupVersion().WithKind(nodeClaimKind + "List"))
+	return list
+}
+
+// NewProvisioner returns an empty NodePool, which replaced the Provisioner along with the machines.
+func (a NodeClaimAPI) NewProvisioner() client.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(a.GroupVersionKind().GroupVersion().WithKind(nodePoolKind))
+	return obj
+}
+
+// ToObject converts the machine to a node claim. The node claims are named like the machines, but the node claims
+// reference their node class in nodeClassRef, and their condition types drop the Machine prefix. The kubelet
+// configuration moved to the node class in v1, it is dropped.
+func (a NodeClaimAPI) ToObject(machineObj *v1alpha5.Machine) (client.Object, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(machineObj)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetGroupVersionKind(a.GroupVersionKind())
+
+	if spec, found := content["spec"].(map[string]interface{}); found {
+		if ref := machineObj.Spec.MachineTemplateRef; ref != nil {
+			nodeClassRef := map[string]interface{}{"name": ref.Name}
+			if ref.Kind != "" {
+				nodeClassRef["kind"] = ref.Kind
+			}
+			if a.Version == NodeClaimV1 {
+				if group := groupOf(ref.APIVersion); group != "" {
+					nodeClassRef["group"] = group
+				}
+			} else if ref.APIVersion != "" {
+				nodeClassRef["apiVersion"] = ref.APIVersion
+			}
+			spec["nodeClassRef"] = nodeClassRef
+		}
+		delete(spec, "machineTemplateRef")
+		if a.Version == NodeClaimV1 {
+			delete(spec, "kubelet")
+		}
+	}
+	renameConditionTypes(content, func(conditionType string) string {
+		if conditionType == string(apis.ConditionReady) {
+			return conditionType
+		}
+		return strings.TrimPrefix(conditionType, machineConditionPrefix)
+	})
+	// The node claims are cluster-scoped.
+	obj.SetNamespace("")
+	return obj, nil
+}
+
+// FromObject converts the node claim into the machine. The namespace of the machine is kept, since the node claims
+// have none.
+func (a NodeClaimAPI) FromObject(obj client.Object, machineObj *v1alpha5.Machine) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("expected an unstructured NodeClaim, got %T", obj)
+	}
+	content := runtime.DeepCopyJSON(u.Object)
+	if spec, found := content["spec"].(map[string]interface{}); found {
+		if nodeClassRef, found := spec["nodeClassRef"].(map[string]interface{}); found {
+			ref := map[string]interface{}{}
+			for _, key := range []string{"name", "kind", "apiVersion"} {
+				if value, found := nodeClassRef[key]; found {
+					ref[key] = value
+				}
+			}
+			if group, found := nodeClassRef["group"].(string); found && group != "" {
+				ref["apiVersion"] = group
+			}
+			spec["machineTemplateRef"] = ref
+		}
+		delete(spec, "nodeClassRef")
+	}
+	renameConditionTypes(content, func(conditionType string) string {
+		if lo.Contains(machineConditionTypes, conditionType) {
+			return machineConditionPrefix + conditionType
+		}
+		return conditionType
+	})
+
+	namespace := machineObj.Namespace
+	converted := &v1alpha5.Machine{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, converted); err != nil {
+		return err
+	}
+	converted.SetGroupVersionKind(schema.GroupVersionKind{})
+	converted.DeepCopyInto(machineObj)
+	if machineObj.Namespace == "" {
+		machineObj.Namespace = namespace
+	}
+	return nil
+}
+
+func (a NodeClaimAPI) FromList(list client.ObjectList) ([]v1alpha5.Machine, error) {
+	u, ok := list.(*unstructured.UnstructuredList)
+	if !ok {
+		return nil, fmt.Errorf("expected an unstructured NodeClaimList, got %T", list)
+	}
+	machines := make([]v1alpha5.Machine, len(u.Items))
+	for i := range u.Items {
+		if err := a.FromObject(&u.Items[i], &machines[i]); err != nil {
+			return nil, err
+		}
+	}
+	return machines, nil
+}
+
+// machineConditionPrefix is the prefix of the condition types of the machines that the node claims dropped, e.g.,
+// MachineLaunched is Launched.
+const machineConditionPrefix = "Machine"
+
+// machineConditionTypes are the condition types of the node claims that are prefixed for the machines.
+var machineConditionTypes = lo.Map([]apis.ConditionType{v1alpha5.MachineLaunched, v1alpha5.MachineRegistered,
+	v1alpha5.MachineInitialized, v1alpha5.MachineDrifted, v1alpha5.MachineEmpty, v1alpha5.MachineExpired},
+	func(conditionType apis.ConditionType, _ int) string {
+		return strings.TrimPrefix(string(conditionType), machineConditionPrefix)
+	})
+
+// renameConditionTypes renames the types of the status conditions of the unstructured object.
+func renameConditionTypes(content map[string]interface{}, rename func(string) string) {
+	conditions, _, _ := unstructured.NestedSlice(content, "status", "conditions")
+	for _, condition := range conditions {
+		if c, ok := condition.(map[string]interface{}); ok {
+			if conditionType, ok := c["type"].(string); ok {
+				c["type"] = rename(conditionType)
+			}
+		}
+	}
+	if conditions != nil {
+		_ = unstructured.SetNestedSlice(content, conditions, "status", "conditions")
+	}
+}
+
+// groupOf returns the group of the API version, e.g., karpenter.azure.com of karpenter.azure.com/v1alpha2.
+func groupOf(apiVersion string) string {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return ""
+	}
+	return gv.Group
+}
+
+// getMachine reads the machine with the key from the API server, in the version of DefaultAPI.
+func getMachine(ctx context.Context, key client.ObjectKey, machineObj *v1alpha5.Machine, kubeClient client.Client) error {
+	obj, err := DefaultAPI.ToObject(machineObj)
+	if err != nil {
+		return err
+	}
+	if err := kubeClient.Get(ctx, key, obj, &client.GetOptions{}); err != nil {
+		return err
+	}
+	return DefaultAPI.FromObject(obj, machineObj)
+}
+
+// ListMachines lists the machines that match the options from the API server, in the version of DefaultAPI.
+func ListMachines(ctx context.Context, kubeClient client.Client, opts ...client.ListOption) ([]v1alpha5.Machine, error) {
+	list := DefaultAPI.NewList()
+	if err := kubeClient.List(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+	return DefaultAPI.FromList(list)
+}
+
+// updateMachine updates the machine on the API server, in the version of DefaultAPI.
+func updateMachine(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) error {
+	obj, err := DefaultAPI.ToObject(machineObj)
+	if err != nil {
+		return err
+	}
+	if err := kubeClient.Update(ctx, obj, &client.UpdateOptions{}); err != nil {
+		return err
+	}
+	return DefaultAPI.FromObject(obj, machineObj)
+}
+
+// DeleteMachine deletes the machine from the API server, in the version of DefaultAPI.
+func DeleteMachine(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) error {
+	obj, err := DefaultAPI.ToObject(machineObj)
+	if err != nil {
+		return err
+	}
+	return kubeClient.Delete(ctx, obj, &client.DeleteOptions{})
+}
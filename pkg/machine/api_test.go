@@ -0,0 +1,165 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/apis"
+)
+
+func TestAPIGeneratesMachineManifest(t *testing.T) {
+	testcases := map[string]struct {
+		api                  API
+		expectedAPIVersion   string
+		expectedKind         string
+		expectedNodeClassRef map[string]interface{}
+	}{
+		"v1alpha5 Machine": {
+			api:                MachineAPI{},
+			expectedAPIVersion: "karpenter.sh/v1alpha5",
+			expectedKind:       "Machine",
+		},
+		"v1beta1 NodeClaim": {
+			api:                  NodeClaimAPI{Version: NodeClaimV1Beta1},
+			expectedAPIVersion:   "karpenter.sh/v1beta1",
+			expectedKind:         "NodeClaim",
+			expectedNodeClassRef: map[string]interface{}{"name": "ws1a2b3c4d5", "kind": "AKSNodeClass", "apiVersion": "karpenter.azure.com/v1alpha2"},
+		},
+		"v1 NodeClaim": {
+			api:                  NodeClaimAPI{Version: NodeClaimV1},
+			expectedAPIVersion:   "karpenter.sh/v1",
+			expectedKind:         "NodeClaim",
+			expectedNodeClassRef: map[string]interface{}{"name": "ws1a2b3c4d5", "kind": "AKSNodeClass", "group": "karpenter.azure.com"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockWorkspace := utils.MockWorkspaceWithPreset
+			machineObj := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+			machineObj.Name = "ws1a2b3c4d5"
+			machineObj.Spec.MachineTemplateRef = &v1alpha5.MachineTemplateRef{Name: machineObj.Name, Kind: "AKSNodeClass", APIVersion: "karpenter.azure.com/v1alpha2"}
+			machineObj.Status.NodeName = "node-1"
+			machineObj.Status.Conditions = apis.Conditions{
+				{Type: v1alpha5.MachineLaunched, Status: corev1.ConditionTrue},
+				{Type: apis.ConditionReady, Status: corev1.ConditionTrue},
+			}
+			expected := machineObj.DeepCopy()
+
+			obj, err := tc.api.ToObject(machineObj)
+			assert.NilError(t, err)
+			gvk := tc.api.GroupVersionKind()
+			assert.Equal(t, gvk.GroupVersion().String(), tc.expectedAPIVersion)
+			assert.Equal(t, gvk.Kind, tc.expectedKind)
+
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				assert.Equal(t, u.GetAPIVersion(), tc.expectedAPIVersion)
+				assert.Equal(t, u.GetKind(), tc.expectedKind)
+				assert.Equal(t, u.GetNamespace(), "", "NodeClaims are cluster-scoped")
+				assert.DeepEqual(t, u.GetLabels(), machineObj.Labels)
+				nodeClassRef, _, _ := unstructured.NestedMap(u.Object, "spec", "nodeClassRef")
+				assert.DeepEqual(t, nodeClassRef, tc.expectedNodeClassRef)
+				_, found, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "machineTemplateRef")
+				assert.Check(t, !found, "NodeClaims must not have a machineTemplateRef")
+				requirements, _, _ := unstructured.NestedSlice(u.Object, "spec", "requirements")
+				assert.Equal(t, len(requirements), len(machineObj.Spec.Requirements))
+				storage, _, _ := unstructured.NestedString(u.Object, "spec", "resources", "requests", "storage")
+				assert.Equal(t, storage, "0")
+				conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+				assert.Equal(t, conditions[0].(map[string]interface{})["type"], "Launched")
+				assert.Equal(t, conditions[1].(map[string]interface{})["type"], "Ready")
+			}
+
+			// The machine is converted back as it was generated, except for the group of the node class in v1.
+			converted := &v1alpha5.Machine{ObjectMeta: metav1.ObjectMeta{Namespace: machineObj.Namespace}}
+			assert.NilError(t, tc.api.FromObject(obj, converted))
+			if tc.api == (NodeClaimAPI{Version: NodeClaimV1}) {
+				expected.Spec.MachineTemplateRef.APIVersion = "karpenter.azure.com"
+			}
+			assert.DeepEqual(t, converted, expected)
+		})
+	}
+}
+
+func TestNodeClaimAPIFromList(t *testing.T) {
+	api := NodeClaimAPI{Version: NodeClaimV1}
+	list := api.NewList().(*unstructured.UnstructuredList)
+	nodeClaim := api.NewObject().(*unstructured.Unstructured)
+	nodeClaim.SetName("ws1a2b3c4d5")
+	assert.NilError(t, unstructured.SetNestedSlice(nodeClaim.Object, []interface{}{
+		map[string]interface{}{"type": "Initialized", "status": "False", "observedGeneration": int64(1)},
+	}, "status", "conditions"))
+	list.Items = append(list.Items, *nodeClaim)
+
+	machines, err := api.FromList(list)
+	assert.NilError(t, err)
+	assert.Equal(t, len(machines), 1)
+	assert.Equal(t, machines[0].Name, "ws1a2b3c4d5")
+	assert.Equal(t, machines[0].GetConditions()[0].Type, v1alpha5.MachineInitialized)
+}
+
+func TestDeleteMachineWithNodeClaimAPI(t *testing.T) {
+	DefaultAPI = NodeClaimAPI{Version: NodeClaimV1}
+	defer func() { DefaultAPI = MachineAPI{} }()
+
+	mockClient := utils.NewClient()
+	mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&unstructured.Unstructured{}), mock.Anything).Return(nil)
+
+	machineObj := utils.MockMachine.DeepCopy()
+	assert.NilError(t, DeleteMachine(context.Background(), machineObj, mockClient))
+
+	deleted := mockClient.Calls[0].Arguments.Get(1).(*unstructured.Unstructured)
+	assert.Equal(t, deleted.GetKind(), "NodeClaim")
+	assert.Equal(t, deleted.GetAPIVersion(), "karpenter.sh/v1")
+	assert.Equal(t, deleted.GetName(), machineObj.Name)
+}
+
+func TestDetectAPI(t *testing.T) {
+	testcases := map[string]struct {
+		resources   []*metav1.APIResourceList
+		expectedAPI API
+	}{
+		"Cluster serves only machines": {
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: "karpenter.sh/v1alpha5", APIResources: []metav1.APIResource{{Name: "machines"}}},
+			},
+			expectedAPI: MachineAPI{},
+		},
+		"Cluster serves v1beta1 node claims": {
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: "karpenter.sh/v1alpha5", APIResources: []metav1.APIResource{{Name: "machines"}}},
+				{GroupVersion: "karpenter.sh/v1beta1", APIResources: []metav1.APIResource{{Name: "nodeclaims"}, {Name: "nodepools"}}},
+			},
+			expectedAPI: NodeClaimAPI{Version: NodeClaimV1Beta1},
+		},
+		"Cluster serves v1 node claims": {
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: "karpenter.sh/v1beta1", APIResources: []metav1.APIResource{{Name: "nodeclaims"}}},
+				{GroupVersion: "karpenter.sh/v1", APIResources: []metav1.APIResource{{Name: "nodeclaims"}}},
+			},
+			expectedAPI: NodeClaimAPI{Version: NodeClaimV1},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			discoveryClient := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: tc.resources}}
+
+			api, err := DetectAPI(discoveryClient)
+			assert.NilError(t, err)
+			assert.Equal(t, api, tc.expectedAPI)
+		})
+	}
+}
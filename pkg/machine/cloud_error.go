@@ -0,0 +1,76 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+var (
+	// The response body of the Azure SDK, e.g., {"error": {"code": "SkuNotAvailable", "message": "..."}}.
+	jsonErrorCodeRegex    = regexp.MustCompile(`"code"\s*:\s*"([^"]+)"`)
+	jsonErrorMessageRegex = regexp.MustCompile(`"message"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	// The error code line of the Azure SDK, e.g., ERROR CODE: SkuNotAvailable.
+	sdkErrorCodeRegex = regexp.MustCompile(`ERROR CODE:\s*([A-Za-z0-9_.]+)`)
+	// The error of the legacy Azure SDK, e.g., Code="OperationNotAllowed" Message="...".
+	legacyErrorCodeRegex    = regexp.MustCompile(`Code="([^"]+)"`)
+	legacyErrorMessageRegex = regexp.MustCompile(`Message="((?:[^"\\]|\\.)*)"`)
+)
+
+// machineFailureConditions are the conditions that report why a machine failed, in the order of its lifecycle.
+var machineFailureConditions = []apis.ConditionType{
+	v1alpha5.MachineLaunched,
+	v1alpha5.MachineRegistered,
+	v1alpha5.MachineInitialized,
+	apis.ConditionReady,
+}
+
+// ExtractCloudError returns the error code and message of the cloud provider that failed the machine, e.g.,
+// SkuNotAvailable if the instance type is out of capacity in the region or OperationNotAllowed if the GPU quota of
+// the subscription is exceeded. The code is empty if the conditions of the machine carry no cloud provider error,
+// the message is the condition message then.
+func ExtractCloudError(machineObj *v1alpha5.Machine) (code, message string) {
+	for _, conditionType := range machineFailureConditions {
+		condition := machineObj.StatusConditions().GetCondition(conditionType)
+		if condition == nil || condition.Status != corev1.ConditionFalse || condition.Message == "" {
+			continue
+		}
+		if code, message := parseCloudError(condition.Message); code != "" {
+			return code, message
+		}
+		if message == "" {
+			message = condition.Message
+		}
+	}
+	return "", message
+}
+
+// parseCloudError parses the error code and message of the cloud provider from a condition message. The message
+// defaults to the condition message if the error has no message of its own.
+func parseCloudError(conditionMessage string) (code, message string) {
+	switch {
+	case jsonErrorCodeRegex.MatchString(conditionMessage):
+		code = jsonErrorCodeRegex.FindStringSubmatch(conditionMessage)[1]
+		if match := jsonErrorMessageRegex.FindStringSubmatch(conditionMessage); match != nil {
+			message = strings.ReplaceAll(match[1], `\"`, `"`)
+		}
+	case sdkErrorCodeRegex.MatchString(conditionMessage):
+		code = sdkErrorCodeRegex.FindStringSubmatch(conditionMessage)[1]
+	case legacyErrorCodeRegex.MatchString(conditionMessage):
+		code = legacyErrorCodeRegex.FindStringSubmatch(conditionMessage)[1]
+		if match := legacyErrorMessageRegex.FindStringSubmatch(conditionMessage); match != nil {
+			message = strings.ReplaceAll(match[1], `\"`, `"`)
+		}
+	default:
+		return "", ""
+	}
+	if message == "" {
+		message = conditionMessage
+	}
+	return code, message
+}
@@ -0,0 +1,92 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestExtractCloudError(t *testing.T) {
+	testcases := map[string]struct {
+		conditions      apis.Conditions
+		expectedCode    string
+		expectedMessage string
+	}{
+		"SKU not available in the response body": {
+			conditions: apis.Conditions{
+				{
+					Type:   v1alpha5.MachineLaunched,
+					Status: corev1.ConditionFalse,
+					Message: "creating machine, creating instance, PUT https://management.azure.com/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks/agentPools/ws123\n" +
+						"--------------------------------------------------------------------------------\n" +
+						"RESPONSE 409: 409 Conflict\nERROR CODE: SkuNotAvailable\n" +
+						"--------------------------------------------------------------------------------\n" +
+						`{"error": {"code": "SkuNotAvailable", "message": "The requested VM size Standard_NC24ads_A100_v4 is currently not available in location eastus."}}`,
+				},
+			},
+			expectedCode:    "SkuNotAvailable",
+			expectedMessage: "The requested VM size Standard_NC24ads_A100_v4 is currently not available in location eastus.",
+		},
+		"Error code line without a response body": {
+			conditions: apis.Conditions{
+				{
+					Type:    v1alpha5.MachineLaunched,
+					Status:  corev1.ConditionFalse,
+					Message: "creating instance, RESPONSE 403: 403 Forbidden\nERROR CODE: AuthorizationFailed",
+				},
+			},
+			expectedCode:    "AuthorizationFailed",
+			expectedMessage: "creating instance, RESPONSE 403: 403 Forbidden\nERROR CODE: AuthorizationFailed",
+		},
+		"Quota exceeded in the legacy format": {
+			conditions: apis.Conditions{
+				{
+					Type:   v1alpha5.MachineLaunched,
+					Status: corev1.ConditionFalse,
+					Message: `compute.VirtualMachinesClient#CreateOrUpdate: Failure sending request: StatusCode=0 -- Original Error: autorest/azure: Service returned an error. ` +
+						`Status=<nil> Code="OperationNotAllowed" Message="Operation could not be completed as it results in exceeding approved standardNCADSA100v4Family Cores quota."`,
+				},
+			},
+			expectedCode:    "OperationNotAllowed",
+			expectedMessage: "Operation could not be completed as it results in exceeding approved standardNCADSA100v4Family Cores quota.",
+		},
+		"Registration failure without a cloud error": {
+			conditions: apis.Conditions{
+				{
+					Type:   v1alpha5.MachineLaunched,
+					Status: corev1.ConditionTrue,
+				},
+				{
+					Type:    v1alpha5.MachineRegistered,
+					Status:  corev1.ConditionFalse,
+					Message: "node not registered with cluster",
+				},
+			},
+			expectedMessage: "node not registered with cluster",
+		},
+		"Healthy machine": {
+			conditions: apis.Conditions{
+				{
+					Type:   apis.ConditionReady,
+					Status: corev1.ConditionTrue,
+				},
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			machineObj := &v1alpha5.Machine{}
+			machineObj.Status.Conditions = tc.conditions
+
+			code, message := ExtractCloudError(machineObj)
+			assert.Equal(t, code, tc.expectedCode)
+			assert.Equal(t, message, tc.expectedMessage)
+		})
+	}
+}
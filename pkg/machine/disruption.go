@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsMachineDisrupting returns true if karpenter is about to remove the node of the machine,
+// i.e. the machine is being terminated, has drifted or has expired.
+func IsMachineDisrupting(machineObj *v1alpha5.Machine) bool {
+	if !machineObj.DeletionTimestamp.IsZero() {
+		return true
+	}
+	return NeedsReplacement(machineObj)
+}
+
+// NeedsReplacement returns true if the node of the machine should be replaced by a new machine, i.e. the machine
+// has drifted from its provisioner or has lived longer than the TTL of its provisioner.
+func NeedsReplacement(machineObj *v1alpha5.Machine) bool {
+	return lo.ContainsBy(machineObj.GetConditions(), func(condition apis.Condition) bool {
+		return (condition.Type == v1alpha5.MachineDrifted || condition.Type == v1alpha5.MachineExpired) &&
+			condition.Status == corev1.ConditionTrue
+	})
+}
+
+// OnMachineDisrupting pre-drains the node of a disrupting machine. The node is cordoned and the workspace pods
+// running on it are deleted, so that they are rescheduled on other nodes before the node is removed.
+func OnMachineDisrupting(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) error {
+	nodeName := machineObj.Status.NodeName
+	workspaceName := machineObj.Labels[kaitov1alpha1.LabelWorkspaceName]
+	if !IsMachineDisrupting(machineObj) || nodeName == "" || workspaceName == "" {
+		return nil
+	}
+	klog.InfoS("machine is being disrupted, pre-draining its node", "machine", klog.KObj(machineObj), "node", nodeName)
+
+	if err := resources.CordonNode(ctx, nodeName, kubeClient); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	return drainWorkspacePods(ctx, nodeName, client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: workspaceName}, kubeClient)
+}
+
+// drainWorkspacePods deletes the workspace pods of the node that match the selector, so that their workloads
+// recreate them on other nodes.
+func drainWorkspacePods(ctx context.Context, nodeName string, selector client.ListOption, kubeClient client.Client) error {
+	pods := &corev1.PodList{}
+	if err := kubeClient.List(ctx, pods, client.MatchingFields{"spec.nodeName": nodeName}, selector); err != nil {
+		klog.ErrorS(err, "failed to list the pods of the node", "node", nodeName)
+		return err
+	}
+	for i := range pods.Items {
+		if !pods.Items[i].DeletionTimestamp.IsZero() {
+			continue
+		}
+		klog.InfoS("deleting pod from draining node", "pod", klog.KObj(&pods.Items[i]), "node", nodeName)
+		if err := kubeClient.Delete(ctx, &pods.Items[i], &client.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			klog.ErrorS(err, "failed to delete pod", "pod", klog.KObj(&pods.Items[i]))
+			return err
+		}
+	}
+	return nil
+}
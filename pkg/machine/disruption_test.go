@@ -0,0 +1,97 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestOnMachineDisrupting(t *testing.T) {
+	testcases := map[string]struct {
+		mutateMachine    func(m *v1alpha5.Machine)
+		expectedPreDrain bool
+	}{
+		"Drifted machine triggers pre-drain": {
+			mutateMachine: func(m *v1alpha5.Machine) {
+				m.Status.Conditions = apis.Conditions{
+					{
+						Type:   v1alpha5.MachineDrifted,
+						Status: corev1.ConditionTrue,
+					},
+				}
+			},
+			expectedPreDrain: true,
+		},
+		"Expired machine triggers pre-drain": {
+			mutateMachine: func(m *v1alpha5.Machine) {
+				m.Status.Conditions = apis.Conditions{
+					{
+						Type:   v1alpha5.MachineExpired,
+						Status: corev1.ConditionTrue,
+					},
+				}
+			},
+			expectedPreDrain: true,
+		},
+		"Terminating machine triggers pre-drain": {
+			mutateMachine: func(m *v1alpha5.Machine) {
+				now := metav1.Now()
+				m.DeletionTimestamp = &now
+			},
+			expectedPreDrain: true,
+		},
+		"Healthy machine is left alone": {
+			mutateMachine:    func(m *v1alpha5.Machine) {},
+			expectedPreDrain: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+
+			machineObj := utils.MockMachine.DeepCopy()
+			machineObj.Labels = map[string]string{kaitov1alpha1.LabelWorkspaceName: "testWorkspace"}
+			machineObj.Status.NodeName = "node1"
+			tc.mutateMachine(machineObj)
+
+			mockClient.CreateOrUpdateObjectInMap(utils.MockNodeList.Items[0].DeepCopy())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "inference-pod",
+					Namespace: "kaito",
+					Labels:    map[string]string{kaitov1alpha1.LabelWorkspaceName: "testWorkspace"},
+				},
+				Spec: corev1.PodSpec{NodeName: "node1"},
+			}
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			podMap[client.ObjectKeyFromObject(pod)] = pod
+
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+
+			err := OnMachineDisrupting(context.Background(), machineObj, mockClient)
+			assert.Check(t, err == nil, "Not expected to return error")
+			if tc.expectedPreDrain {
+				mockClient.AssertCalled(t, "Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything)
+				mockClient.AssertCalled(t, "Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				mockClient.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodeConditionGPUHardwareError is the node condition that the GPU health monitoring, e.g., the DCGM health checks
+// of the node problem detector, sets to True when a GPU of the node reports uncorrectable ECC or other hardware errors.
+const NodeConditionGPUHardwareError corev1.NodeConditionType = "GPUHardwareError"
+
+// HasGPUHardwareError returns true if the node reports a GPU hardware error.
+func HasGPUHardwareError(nodeObj *corev1.Node) bool {
+	return lo.ContainsBy(nodeObj.Status.Conditions, func(condition corev1.NodeCondition) bool {
+		return condition.Type == NodeConditionGPUHardwareError && condition.Status == corev1.ConditionTrue
+	})
+}
+
+// HandleGPUHardwareError takes a node that reports a GPU hardware error out of service. The node is cordoned, the
+// workspace pods running on it are deleted so that they are rescheduled on other nodes, and the machine of the node
+// is deleted, which the workspace replaces with a new machine in its next reconcile. A node that kaito did not
+// provision is only cordoned and drained. Nothing is done for a healthy node. The deleted machine is returned, if any.
+func HandleGPUHardwareError(ctx context.Context, nodeObj *corev1.Node, kubeClient client.Client) (*v1alpha5.Machine, error) {
+	if !HasGPUHardwareError(nodeObj) {
+		return nil, nil
+	}
+	klog.InfoS("node reports a GPU hardware error, replacing it", "node", klog.KObj(nodeObj))
+
+	if err := resources.CordonNode(ctx, nodeObj.Name, kubeClient); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if err := drainWorkspacePods(ctx, nodeObj.Name, client.HasLabels{kaitov1alpha1.LabelWorkspaceName}, kubeClient); err != nil {
+		return nil, err
+	}
+
+	machineObj, err := machineOfNode(ctx, nodeObj, kubeClient)
+	if err != nil || machineObj == nil || !machineObj.DeletionTimestamp.IsZero() {
+		return nil, err
+	}
+	klog.InfoS("deleting the machine of the faulty node", "machine", klog.KObj(machineObj), "node", klog.KObj(nodeObj))
+	if err := DeleteMachine(ctx, machineObj, kubeClient); client.IgnoreNotFound(err) != nil {
+		klog.ErrorS(err, "failed to delete the machine", "machine", klog.KObj(machineObj))
+		return nil, err
+	}
+	return machineObj, nil
+}
+
+// machineOfNode returns the workspace machine that provisioned the node, or nil if the node was not provisioned by kaito.
+func machineOfNode(ctx context.Context, nodeObj *corev1.Node, kubeClient client.Client) (*v1alpha5.Machine, error) {
+	workspaceName, nameFound := nodeObj.Labels[kaitov1alpha1.LabelWorkspaceName]
+	workspaceNamespace, namespaceFound := nodeObj.Labels[kaitov1alpha1.LabelWorkspaceNamespace]
+	if !nameFound || !namespaceFound {
+		return nil, nil
+	}
+	machines, err := ListMachines(ctx, kubeClient, client.MatchingLabels{
+		kaitov1alpha1.LabelWorkspaceName:      workspaceName,
+		kaitov1alpha1.LabelWorkspaceNamespace: workspaceNamespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	machineObj, found := lo.Find(machines, func(m v1alpha5.Machine) bool {
+		return m.Status.NodeName == nodeObj.Name
+	})
+	if !found {
+		return nil, nil
+	}
+	return &machineObj, nil
+}
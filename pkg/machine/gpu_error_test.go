@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestHandleGPUHardwareError(t *testing.T) {
+	testcases := map[string]struct {
+		conditions      []corev1.NodeCondition
+		expectedReplace bool
+	}{
+		"Node with a GPU hardware error is cordoned and replaced": {
+			conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: NodeConditionGPUHardwareError, Status: corev1.ConditionTrue, Reason: "DoubleBitECCError"},
+			},
+			expectedReplace: true,
+		},
+		"Node whose GPU error cleared is left alone": {
+			conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: NodeConditionGPUHardwareError, Status: corev1.ConditionFalse},
+			},
+		},
+		"Healthy node is left alone": {
+			conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+
+			nodeObj := utils.MockNodeList.Items[0].DeepCopy()
+			nodeObj.Labels[kaitov1alpha1.LabelWorkspaceName] = "testWorkspace"
+			nodeObj.Labels[kaitov1alpha1.LabelWorkspaceNamespace] = "kaito"
+			nodeObj.Status.Conditions = tc.conditions
+			mockClient.CreateOrUpdateObjectInMap(nodeObj)
+
+			machineObj := utils.MockMachine.DeepCopy()
+			machineObj.Status.NodeName = nodeObj.Name
+			otherMachine := utils.MockMachine.DeepCopy()
+			otherMachine.Name = "othermachine"
+			otherMachine.Status.NodeName = "node2"
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			machineMap[client.ObjectKeyFromObject(machineObj)] = machineObj
+			machineMap[client.ObjectKeyFromObject(otherMachine)] = otherMachine
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "inference-pod",
+					Namespace: "kaito",
+					Labels:    map[string]string{kaitov1alpha1.LabelWorkspaceName: "testWorkspace"},
+				},
+				Spec: corev1.PodSpec{NodeName: nodeObj.Name},
+			}
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			podMap[client.ObjectKeyFromObject(pod)] = pod
+
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+
+			replaced, err := HandleGPUHardwareError(context.Background(), nodeObj, mockClient)
+			assert.Check(t, err == nil, "Not expected to return error: %v", err)
+			assert.Equal(t, replaced != nil, tc.expectedReplace)
+			if !tc.expectedReplace {
+				mockClient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				mockClient.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			updatedNode := mockClient.Calls[1].Arguments.Get(1).(*corev1.Node)
+			assert.Check(t, updatedNode.Spec.Unschedulable, "Node must be cordoned")
+			mockClient.AssertCalled(t, "Delete", mock.IsType(context.Background()), pod, mock.Anything)
+			mockClient.AssertNumberOfCalls(t, "Delete", 2)
+			deletedMachine := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*v1alpha5.Machine)
+			assert.Equal(t, deletedMachine.Name, machineObj.Name)
+			assert.Equal(t, replaced.Name, machineObj.Name)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+)
+
+// PreProvisionHook is invoked before a machine is created for a workspace.
+// It allows operators to gate the provisioning, e.g. to business hours or on an approval.
+type PreProvisionHook interface {
+	// BeforeProvision decides whether a machine can be created for the workspace now.
+	BeforeProvision(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace) (PreProvisionResult, error)
+}
+
+// PreProvisionResult is the decision of a PreProvisionHook.
+type PreProvisionResult struct {
+	// Allowed is true if the machine can be created.
+	Allowed bool
+	// RetryAfter delays a disallowed provisioning by the given duration. Zero vetoes the provisioning.
+	RetryAfter time.Duration
+	// Reason explains why the provisioning is not allowed.
+	Reason string
+}
+
+// NoopPreProvisionHook allows every provisioning.
+type NoopPreProvisionHook struct{}
+
+func (NoopPreProvisionHook) BeforeProvision(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace) (PreProvisionResult, error) {
+	return PreProvisionResult{Allowed: true}, nil
+}
+
+// ProvisioningDelayedError is returned when a PreProvisionHook delays the provisioning of a machine.
+type ProvisioningDelayedError struct {
+	RetryAfter time.Duration
+	Reason     string
+}
+
+func (e *ProvisioningDelayedError) Error() string {
+	return fmt.Sprintf("machine provisioning is delayed for %s: %s", e.RetryAfter, e.Reason)
+}
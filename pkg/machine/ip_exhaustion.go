@@ -0,0 +1,72 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"strings"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FailureCategoryIPExhaustion is the category of the failures of machines whose nodes cannot get IP addresses, e.g.,
+// because the subnet of the nodes or the IP ranges of the CNI plugin are exhausted.
+const FailureCategoryIPExhaustion = "IPExhaustion"
+
+// ipExhaustionCloudErrorCodes are the error codes of the cloud provider for a subnet without free IP addresses.
+var ipExhaustionCloudErrorCodes = []string{"SubnetIsFull", "InsufficientSubnetSize"}
+
+// ipExhaustionMessages are the lower-case fragments of the failure messages of the cloud provider and of the CNI
+// plugins, e.g., azure-vnet, host-local and cilium, that report that no IP address could be allocated.
+var ipExhaustionMessages = []string{
+	"subnet is full",
+	"does not have enough capacity for",
+	"no ip addresses available",
+	"no ips available",
+	"no available ip",
+	"no available addresses",
+	"not enough ips available",
+	"no more ip addresses",
+	"out of ip addresses",
+	"ip addresses exhausted",
+	"ip pool exhausted",
+	"ipam pool exhausted",
+}
+
+// IsIPExhaustionMessage returns true if the failure message reports that no IP address could be allocated.
+func IsIPExhaustionMessage(message string) bool {
+	message = strings.ToLower(message)
+	return lo.ContainsBy(ipExhaustionMessages, func(fragment string) bool {
+		return strings.Contains(message, fragment)
+	})
+}
+
+// DetectIPExhaustion returns the failure message of the machine or of its node that falls into the IPExhaustion
+// category, i.e., the machine could not be launched because its subnet is full, or its node does not join the cluster
+// or get ready because the CNI plugin cannot allocate IP addresses. The node is nil if the machine has none yet.
+func DetectIPExhaustion(machineObj *v1alpha5.Machine, nodeObj *corev1.Node) (string, bool) {
+	for _, conditionType := range machineFailureConditions {
+		condition := machineObj.StatusConditions().GetCondition(conditionType)
+		if condition == nil || condition.Status != corev1.ConditionFalse || condition.Message == "" {
+			continue
+		}
+		if code, message := parseCloudError(condition.Message); lo.Contains(ipExhaustionCloudErrorCodes, code) {
+			return code + ": " + message, true
+		}
+		if IsIPExhaustionMessage(condition.Message) {
+			return condition.Message, true
+		}
+	}
+	if nodeObj == nil {
+		return "", false
+	}
+	for _, condition := range nodeObj.Status.Conditions {
+		failing := (condition.Type == corev1.NodeNetworkUnavailable && condition.Status == corev1.ConditionTrue) ||
+			(condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue)
+		if failing && IsIPExhaustionMessage(condition.Message) {
+			return condition.Message, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestDetectIPExhaustion(t *testing.T) {
+	testcases := map[string]struct {
+		conditions      apis.Conditions
+		nodeConditions  []corev1.NodeCondition
+		expectFound     bool
+		expectedMessage string
+	}{
+		"Full subnet fails the launch": {
+			conditions: apis.Conditions{
+				{
+					Type:   v1alpha5.MachineLaunched,
+					Status: corev1.ConditionFalse,
+					Message: "creating instance, RESPONSE 400: 400 Bad Request\nERROR CODE: SubnetIsFull\n" +
+						`{"error": {"code": "SubnetIsFull", "message": "Subnet aks-subnet with address prefix 10.224.0.0/24 does not have enough capacity for 31 IP addresses."}}`,
+				},
+			},
+			expectFound:     true,
+			expectedMessage: "SubnetIsFull: Subnet aks-subnet with address prefix 10.224.0.0/24 does not have enough capacity for 31 IP addresses.",
+		},
+		"Insufficient subnet size in the legacy format": {
+			conditions: apis.Conditions{
+				{
+					Type:    v1alpha5.MachineLaunched,
+					Status:  corev1.ConditionFalse,
+					Message: `Code="InsufficientSubnetSize" Message="Pre-allocated IPs 930 exceeds IPs available 251 in Subnet Cidr 10.240.0.0/24."`,
+				},
+			},
+			expectFound:     true,
+			expectedMessage: "InsufficientSubnetSize: Pre-allocated IPs 930 exceeds IPs available 251 in Subnet Cidr 10.240.0.0/24.",
+		},
+		"Node whose CNI plugin cannot allocate addresses is not ready": {
+			conditions: apis.Conditions{
+				{Type: v1alpha5.MachineLaunched, Status: corev1.ConditionTrue},
+				{Type: apis.ConditionReady, Status: corev1.ConditionFalse, Message: "node is not ready"},
+			},
+			nodeConditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: corev1.ConditionFalse,
+					Message: "container runtime network not ready: NetworkReady=false reason:NetworkPluginNotReady message:Network plugin returns error: " +
+						"plugin type=\"azure-vnet\" failed (add): IPAM Invoker Add failed with error: Failed to allocate pool: No available addresses",
+				},
+			},
+			expectFound: true,
+			expectedMessage: "container runtime network not ready: NetworkReady=false reason:NetworkPluginNotReady message:Network plugin returns error: " +
+				"plugin type=\"azure-vnet\" failed (add): IPAM Invoker Add failed with error: Failed to allocate pool: No available addresses",
+		},
+		"Node whose network is unavailable": {
+			conditions: apis.Conditions{
+				{Type: v1alpha5.MachineLaunched, Status: corev1.ConditionTrue},
+			},
+			nodeConditions: []corev1.NodeCondition{
+				{Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionTrue, Message: "failed to allocate for range 0: no IP addresses available in range set"},
+			},
+			expectFound:     true,
+			expectedMessage: "failed to allocate for range 0: no IP addresses available in range set",
+		},
+		"Registration failure that is not an IP exhaustion": {
+			conditions: apis.Conditions{
+				{Type: v1alpha5.MachineLaunched, Status: corev1.ConditionTrue},
+				{Type: v1alpha5.MachineRegistered, Status: corev1.ConditionFalse, Message: "node not registered with cluster"},
+			},
+			nodeConditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Message: "Network plugin returns error: cni plugin not initialized"},
+			},
+		},
+		"Quota error is not an IP exhaustion": {
+			conditions: apis.Conditions{
+				{
+					Type:    v1alpha5.MachineLaunched,
+					Status:  corev1.ConditionFalse,
+					Message: `Code="OperationNotAllowed" Message="Operation could not be completed as it results in exceeding approved standardNCADSA100v4Family Cores quota."`,
+				},
+			},
+		},
+		"Ready node that reported an exhaustion before": {
+			conditions: apis.Conditions{
+				{Type: apis.ConditionReady, Status: corev1.ConditionTrue},
+			},
+			nodeConditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue, Message: "no IP addresses available in range set"},
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			machineObj := &v1alpha5.Machine{}
+			machineObj.Status.Conditions = tc.conditions
+			var nodeObj *corev1.Node
+			if tc.nodeConditions != nil {
+				nodeObj = &corev1.Node{Status: corev1.NodeStatus{Conditions: tc.nodeConditions}}
+			}
+
+			message, found := DetectIPExhaustion(machineObj, nodeObj)
+			assert.Equal(t, found, tc.expectFound)
+			assert.Equal(t, message, tc.expectedMessage)
+		})
+	}
+}
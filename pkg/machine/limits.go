@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckProvisionerLimits returns an error if creating count machines of the instance type would exceed the resource
+// limits of the provisioner that kaito creates the machines with, in which case the machines would never be launched.
+// The current usage is the one reported in the status of the provisioner. The resources of a new machine are estimated
+// from the capacity of an existing machine of the instance type, the limits are not checked if there is none.
+func CheckProvisionerLimits(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, instanceType string, count int,
+	provider cloudprovider.CloudProvider, kubeClient client.Client) error {
+	provisioner := &v1alpha5.Provisioner{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: ProvisionerName}, provisioner, &client.GetOptions{}); err != nil {
+		// The machines are launched without limits if the provisioner does not exist.
+		return client.IgnoreNotFound(err)
+	}
+	if provisioner.Spec.Limits == nil || len(provisioner.Spec.Limits.Resources) == 0 {
+		return nil
+	}
+
+	machines, err := ListMachines(ctx, kubeClient, client.MatchingLabels{LabelProvisionerName: ProvisionerName})
+	if err != nil {
+		return err
+	}
+	reference, found := lo.Find(machines, func(m v1alpha5.Machine) bool {
+		return MachineInstanceType(&m, provider) == instanceType && len(m.Status.Capacity) > 0
+	})
+	if !found {
+		klog.InfoS("no machine of the instance type reports its capacity, skip checking the provisioner limits",
+			"workspace", klog.KObj(workspaceObj), "instanceType", instanceType)
+		return nil
+	}
+
+	usage := v1.ResourceList{}
+	for name, quantity := range provisioner.Status.Resources {
+		usage[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range reference.Status.Capacity {
+		if _, limited := provisioner.Spec.Limits.Resources[name]; !limited {
+			continue
+		}
+		total := usage[name]
+		for i := 0; i < count; i++ {
+			total.Add(quantity)
+		}
+		usage[name] = total
+	}
+	if err := provisioner.Spec.Limits.ExceededBy(usage); err != nil {
+		return fmt.Errorf("creating %d machines of instance type %s for workspace %s/%s exceeds the limits of provisioner %s: %w",
+			count, instanceType, workspaceObj.Namespace, workspaceObj.Name, ProvisionerName, err)
+	}
+	return nil
+}
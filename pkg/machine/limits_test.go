@@ -0,0 +1,94 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCheckProvisionerLimits(t *testing.T) {
+	gpuLimits := &v1alpha5.Limits{Resources: corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse("8")}}
+
+	testcases := map[string]struct {
+		provisionerNotFound bool
+		limits              *v1alpha5.Limits
+		usedGPUs            string
+		machineGPUs         string
+		count               int
+		expectedError       string
+	}{
+		"Within the limits": {
+			limits:      gpuLimits,
+			usedGPUs:    "4",
+			machineGPUs: "2",
+			count:       2,
+		},
+		"Over the limits": {
+			limits:        gpuLimits,
+			usedGPUs:      "4",
+			machineGPUs:   "2",
+			count:         3,
+			expectedError: "exceeds the limits of provisioner default",
+		},
+		"Provisioner without limits": {
+			usedGPUs:    "4",
+			machineGPUs: "2",
+			count:       3,
+		},
+		"No provisioner": {
+			provisionerNotFound: true,
+			count:               3,
+		},
+		"Capacity of the instance type is unknown": {
+			limits:   gpuLimits,
+			usedGPUs: "8",
+			count:    1,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+
+			if tc.provisionerNotFound {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Provisioner{}), mock.Anything).Return(utils.NotFoundError())
+			} else {
+				provisioner := &v1alpha5.Provisioner{
+					ObjectMeta: metav1.ObjectMeta{Name: ProvisionerName},
+					Spec:       v1alpha5.ProvisionerSpec{Limits: tc.limits},
+					Status:     v1alpha5.ProvisionerStatus{Resources: corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse(tc.usedGPUs)}},
+				}
+				mockClient.CreateOrUpdateObjectInMap(provisioner)
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Provisioner{}), mock.Anything).Return(nil)
+			}
+
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			if tc.machineGPUs != "" {
+				existing := utils.MockMachine.DeepCopy()
+				existing.Status.Capacity = corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse(tc.machineGPUs)}
+				machineMap[client.ObjectKeyFromObject(existing)] = existing
+			}
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+
+			err := CheckProvisionerLimits(context.Background(), workspace, "Standard_NC12s_v3", tc.count, cloudprovider.Default, mockClient)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -11,8 +11,11 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/resources"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -31,15 +34,23 @@ const (
 	ErrorInstanceTypesUnavailable = "all requested instance types were unavailable during launch"
 )
 
+// maxMachineNameAttempts is the number of names tried for a machine whose name is taken by another workspace.
+const maxMachineNameAttempts = 3
+
 var (
 	// machineStatusTimeoutInterval is the interval to check the machine status.
 	machineStatusTimeoutInterval = 240 * time.Second
 )
 
-// GenerateMachineManifest generates a machine object from the given workspace.
-func GenerateMachineManifest(ctx context.Context, storageRequirement string, workspaceObj *kaitov1alpha1.Workspace) *v1alpha5.Machine {
-	digest := sha256.Sum256([]byte(workspaceObj.Namespace + workspaceObj.Name + time.Now().Format("2006-01-02 15:04:05.000000000"))) // We make sure the machine name is not fixed to the a workspace
-	machineName := "ws" + hex.EncodeToString(digest[0:])[0:9]
+// CandidateInstanceTypes returns the instance types the machines of the workspace may use, in order of preference.
+func CandidateInstanceTypes(workspaceObj *kaitov1alpha1.Workspace) []string {
+	return append([]string{workspaceObj.Resource.InstanceType}, workspaceObj.Resource.FallbackInstanceTypes...)
+}
+
+// GenerateMachineManifest generates the machine object with the given index and instance type from the given workspace.
+func GenerateMachineManifest(ctx context.Context, storageRequirement string, workspaceObj *kaitov1alpha1.Workspace, index int,
+	instanceType string, provider cloudprovider.CloudProvider) *v1alpha5.Machine {
+	machineName := GenerateMachineName(workspaceObj, index)
 	machineLabels := map[string]string{
 		LabelProvisionerName:                  ProvisionerName,
 		kaitov1alpha1.LabelWorkspaceName:      workspaceObj.Name,
@@ -51,43 +62,81 @@ func GenerateMachineManifest(ctx context.Context, storageRequirement string, wor
 
 	}
 
+	requirements := []v1.NodeSelectorRequirement{
+		{
+			Key:      provider.InstanceTypeLabel(),
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{instanceType},
+		},
+		{
+			Key:      LabelProvisionerName,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{ProvisionerName},
+		},
+		{
+			Key:      LabelGPUProvisionerCustom,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{GPUString},
+		},
+		{
+			Key:      v1.LabelArchStable,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{provider.Architecture(instanceType)},
+		},
+		{
+			Key:      v1.LabelOSStable,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{"linux"},
+		},
+	}
+	// The capacity type is only constrained if the workspace asks for one via the labelSelector.
+	if capacityType, found := machineLabels[provider.CapacityTypeLabel()]; found {
+		requirements = append(requirements, v1.NodeSelectorRequirement{
+			Key:      provider.CapacityTypeLabel(),
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{capacityType},
+		})
+	}
+
+	// The presets that are only licensed or available in some regions constrain the region of the machine.
+	if regions, restricted := workspaceObj.AllowedRegions(); restricted {
+		if region, found := machineLabels[v1.LabelTopologyRegion]; found {
+			regions = []string{region}
+		}
+		requirements = append(requirements, v1.NodeSelectorRequirement{
+			Key:      v1.LabelTopologyRegion,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   regions,
+		})
+	}
+
+	machineAnnotations := map[string]string{}
+	if workspaceObj.Resource.RDMA {
+		machineAnnotations[kaitov1alpha1.AnnotationRDMAEnabled] = "true"
+	}
+	// The common labels and annotations of the workspace are stamped on the machine, Karpenter propagates the labels
+	// to its node.
+	machineLabels = resources.WithCommonLabels(workspaceObj, machineLabels)
+	machineAnnotations = resources.WithCommonAnnotations(workspaceObj, machineAnnotations)
+
+	// The pods are not scheduled on a GPU node before its GPUs are registered, see GateGPUStartupTaint.
+	var startupTaints []v1.Taint
+	if EnableGPUStartupTaint && provider.IsGPUInstanceType(instanceType) {
+		startupTaints = []v1.Taint{GPUStartupTaint}
+	}
+
 	return &v1alpha5.Machine{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      machineName,
-			Namespace: workspaceObj.Namespace,
-			Labels:    machineLabels,
+			Name:        machineName,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      machineLabels,
+			Annotations: machineAnnotations,
 		},
 		Spec: v1alpha5.MachineSpec{
 			MachineTemplateRef: &v1alpha5.MachineTemplateRef{
 				Name: machineName,
 			},
-			Requirements: []v1.NodeSelectorRequirement{
-				{
-					Key:      v1.LabelInstanceTypeStable,
-					Operator: v1.NodeSelectorOpIn,
-					Values:   []string{workspaceObj.Resource.InstanceType},
-				},
-				{
-					Key:      LabelProvisionerName,
-					Operator: v1.NodeSelectorOpIn,
-					Values:   []string{ProvisionerName},
-				},
-				{
-					Key:      LabelGPUProvisionerCustom,
-					Operator: v1.NodeSelectorOpIn,
-					Values:   []string{GPUString},
-				},
-				{
-					Key:      v1.LabelArchStable,
-					Operator: v1.NodeSelectorOpIn,
-					Values:   []string{"amd64"},
-				},
-				{
-					Key:      v1.LabelOSStable,
-					Operator: v1.NodeSelectorOpIn,
-					Values:   []string{"linux"},
-				},
-			},
+			Requirements: requirements,
 			Taints: []v1.Taint{
 				{
 					Key:    "sku",
@@ -95,6 +144,7 @@ func GenerateMachineManifest(ctx context.Context, storageRequirement string, wor
 					Effect: v1.TaintEffectNoSchedule,
 				},
 			},
+			StartupTaints: startupTaints,
 			Resources: v1alpha5.ResourceRequirements{
 				Requests: v1.ResourceList{
 					v1.ResourceStorage: resource.MustParse(storageRequirement),
@@ -104,20 +154,142 @@ func GenerateMachineManifest(ctx context.Context, storageRequirement string, wor
 	}
 }
 
+// GenerateMachineName returns the name of the machine with the given index in the workspace. The name is stable
+// for the same workspace and index, so that a machine is not created twice for the same index. It is short and
+// alphanumeric because the Azure provisioner uses it as the agent pool name, which is limited to 12 characters.
+// The workspace UID is hashed too, so that a recreated workspace does not reuse the names of terminating machines.
+func GenerateMachineName(workspaceObj *kaitov1alpha1.Workspace, index int) string {
+	return generateMachineName(workspaceObj, index, 0)
+}
+
+// generateMachineName returns the name of the machine of the workspace with the given index. The attempts after the
+// first one return alternative names, used if the name is taken by a machine of another workspace.
+func generateMachineName(workspaceObj *kaitov1alpha1.Workspace, index, attempt int) string {
+	seed := fmt.Sprintf("%s/%s/%s/%d", workspaceObj.Namespace, workspaceObj.Name, workspaceObj.UID, index)
+	if attempt > 0 {
+		seed = fmt.Sprintf("%s/%d", seed, attempt)
+	}
+	digest := sha256.Sum256([]byte(seed))
+	return "ws" + hex.EncodeToString(digest[0:])[0:9]
+}
+
+// FreeMachineIndices returns the count smallest indices of the workspace whose machine names are not taken by the given machines.
+func FreeMachineIndices(workspaceObj *kaitov1alpha1.Workspace, machines []v1alpha5.Machine, count int) []int {
+	taken := make(map[string]bool, len(machines))
+	for i := range machines {
+		taken[machines[i].Name] = true
+	}
+
+	indices := make([]int, 0, count)
+	for index := 0; len(indices) < count; index++ {
+		// The index is taken if the workspace has a machine with any of its names.
+		indexTaken := lo.ContainsBy(lo.Range(maxMachineNameAttempts), func(attempt int) bool {
+			return taken[generateMachineName(workspaceObj, index, attempt)]
+		})
+		if !indexTaken {
+			indices = append(indices, index)
+		}
+	}
+	return indices
+}
+
+// ProvisionedNodeLabels returns the labels that the node of a machine of the workspace with the given instance type carries.
+// These are the labels of the machine and the labels set by the single valued requirements of the machine.
+func ProvisionedNodeLabels(workspaceObj *kaitov1alpha1.Workspace, instanceType string, provider cloudprovider.CloudProvider) labels.Set {
+	machineObj := GenerateMachineManifest(context.Background(), "0", workspaceObj, 0, instanceType, provider)
+	nodeLabels := labels.Set{}
+	for key, value := range machineObj.Labels {
+		nodeLabels[key] = value
+	}
+	for _, requirement := range machineObj.Spec.Requirements {
+		if requirement.Operator == v1.NodeSelectorOpIn && len(requirement.Values) == 1 {
+			nodeLabels[requirement.Key] = requirement.Values[0]
+		}
+	}
+	return nodeLabels
+}
+
+// ValidateLabelSelectorMatchesNodes checks that the labelSelector of the workspace matches the nodes kaito provisions
+// for it. The matchLabels are always stamped on the nodes, but matchExpressions may exclude them, in which case
+// the workload pods would stay pending forever.
+func ValidateLabelSelectorMatchesNodes(workspaceObj *kaitov1alpha1.Workspace, provider cloudprovider.CloudProvider) error {
+	selector, err := metav1.LabelSelectorAsSelector(workspaceObj.Resource.LabelSelector)
+	if err != nil {
+		return fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	for _, instanceType := range CandidateInstanceTypes(workspaceObj) {
+		if selector.Matches(ProvisionedNodeLabels(workspaceObj, instanceType, provider)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("labelSelector %q does not match the labels %q of the nodes provisioned for the workspace, "+
+		"only use matchExpressions that are satisfied by the matchLabels", selector.String(),
+		ProvisionedNodeLabels(workspaceObj, workspaceObj.Resource.InstanceType, provider).String())
+}
+
+// DefaultMaxCreateAttempts is the number of attempts CreateMachine makes to create a machine.
+var DefaultMaxCreateAttempts = retry.DefaultBackoff.Steps
+
+// CreateAttemptsExhaustedError is returned when a machine could not be created within the attempts of a reconcile.
+// The creation is not failed for good, the workspace is requeued to create the machine again.
+type CreateAttemptsExhaustedError struct {
+	Machine  string
+	Attempts int
+	Err      error
+}
+
+func (e *CreateAttemptsExhaustedError) Error() string {
+	return fmt.Sprintf("machine %s is not created after %d attempts: %v", e.Machine, e.Attempts, e.Err)
+}
+
+func (e *CreateAttemptsExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// isRetriableCreateError returns true if creating the machine again may succeed.
+func isRetriableCreateError(err error) bool {
+	return err.Error() != ErrorInstanceTypesUnavailable
+}
+
 // CreateMachine creates a machine object.
 func CreateMachine(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) error {
+	return createMachine(ctx, machineObj, kubeClient, DefaultMaxCreateAttempts)
+}
+
+// CreateMachineWithAttempts creates a machine object in at most maxAttempts attempts, or DefaultMaxCreateAttempts if
+// it is not positive. A CreateAttemptsExhaustedError is returned if all attempts failed with a retriable error, so
+// that the reconcile returns control to the queue instead of blocking on the creation.
+func CreateMachineWithAttempts(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client, maxAttempts int) error {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxCreateAttempts
+	}
+	err := createMachine(ctx, machineObj, kubeClient, maxAttempts)
+	// A machine that already exists is not retried by the callers, which adopt or rename it instead.
+	if err != nil && isRetriableCreateError(err) && !apierrors.IsAlreadyExists(err) {
+		return &CreateAttemptsExhaustedError{Machine: machineObj.Name, Attempts: maxAttempts, Err: err}
+	}
+	return err
+}
+
+func createMachine(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client, maxAttempts int) error {
 	klog.InfoS("CreateMachine", "machine", klog.KObj(machineObj))
-	return retry.OnError(retry.DefaultBackoff, func(err error) bool {
-		return err.Error() != ErrorInstanceTypesUnavailable
-	}, func() error {
-		err := kubeClient.Create(ctx, machineObj, &client.CreateOptions{})
+	backoff := retry.DefaultBackoff
+	backoff.Steps = maxAttempts
+	return retry.OnError(backoff, isRetriableCreateError, func() error {
+		obj, err := DefaultAPI.ToObject(machineObj)
 		if err != nil {
 			return err
 		}
+		if err := kubeClient.Create(ctx, obj, &client.CreateOptions{}); err != nil {
+			return err
+		}
+		if err := DefaultAPI.FromObject(obj, machineObj); err != nil {
+			return err
+		}
 		time.Sleep(1 * time.Second)
 
 		updatedObj := &v1alpha5.Machine{}
-		err = kubeClient.Get(ctx, client.ObjectKey{Name: machineObj.Name, Namespace: machineObj.Namespace}, updatedObj, &client.GetOptions{})
+		err = getMachine(ctx, client.ObjectKey{Name: machineObj.Name, Namespace: machineObj.Namespace}, updatedObj, kubeClient)
 
 		// if SKU is not available, then exit.
 		_, conditionFound := lo.Find(updatedObj.GetConditions(), func(condition apis.Condition) bool {
@@ -132,6 +304,45 @@ func CreateMachine(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient
 	})
 }
 
+// CreateOrAdoptMachine creates the machine of the workspace with the given index. If a machine with the same name
+// already exists, e.g., created by an earlier reconcile, it is adopted if it carries the labels of the workspace.
+// Otherwise, the name is taken by another workspace and the machine is created with an alternative name of the index.
+// The alternative names are deterministic, so a later reconcile adopts the renamed machine.
+// Each name is tried in at most maxAttempts attempts, see CreateMachineWithAttempts.
+func CreateOrAdoptMachine(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, machineObj *v1alpha5.Machine, index int,
+	maxAttempts int, kubeClient client.Client) error {
+	for attempt := 0; attempt < maxMachineNameAttempts; attempt++ {
+		if attempt > 0 {
+			machineObj.Name = generateMachineName(workspaceObj, index, attempt)
+			machineObj.Spec.MachineTemplateRef.Name = machineObj.Name
+		}
+		err := CreateMachineWithAttempts(ctx, machineObj, kubeClient, maxAttempts)
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+
+		existing := &v1alpha5.Machine{}
+		if err := getMachine(ctx, client.ObjectKey{Name: machineObj.Name, Namespace: machineObj.Namespace}, existing, kubeClient); err != nil {
+			return err
+		}
+		if belongsToWorkspace(existing, workspaceObj) {
+			klog.InfoS("The machine already exists, adopting it", "machine", klog.KObj(existing), "workspace", klog.KObj(workspaceObj))
+			existing.DeepCopyInto(machineObj)
+			return nil
+		}
+		klog.InfoS("The machine name is taken by another workspace, trying an alternative name", "machine", klog.KObj(existing),
+			"workspace", klog.KObj(workspaceObj))
+	}
+	return fmt.Errorf("all %d names of machine %d of workspace %s/%s are taken by machines of other workspaces",
+		maxMachineNameAttempts, index, workspaceObj.Namespace, workspaceObj.Name)
+}
+
+// belongsToWorkspace returns true if the machine carries the labels of the workspace.
+func belongsToWorkspace(machineObj *v1alpha5.Machine, workspaceObj *kaitov1alpha1.Workspace) bool {
+	return machineObj.Labels[kaitov1alpha1.LabelWorkspaceName] == workspaceObj.Name &&
+		machineObj.Labels[kaitov1alpha1.LabelWorkspaceNamespace] == workspaceObj.Namespace
+}
+
 // WaitForPendingMachines checks if the there are any machines in provisioning condition. If so, wait until they are ready.
 func WaitForPendingMachines(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) error {
 	machines, err := ListMachinesByWorkspace(ctx, workspaceObj, kubeClient)
@@ -140,11 +351,11 @@ func WaitForPendingMachines(ctx context.Context, workspaceObj *kaitov1alpha1.Wor
 	}
 
 	for i := range machines.Items {
-		// check if the machine is being created has the requested workspace instance type.
+		// check if the machine is being created has one of the requested workspace instance types.
 		_, machineInstanceType := lo.Find(machines.Items[i].Spec.Requirements, func(requirement v1.NodeSelectorRequirement) bool {
 			return requirement.Key == v1.LabelInstanceTypeStable &&
 				requirement.Operator == v1.NodeSelectorOpIn &&
-				lo.Contains(requirement.Values, workspaceObj.Resource.InstanceType)
+				lo.Some(requirement.Values, CandidateInstanceTypes(workspaceObj))
 		})
 		if machineInstanceType {
 			_, found := lo.Find(machines.Items[i].GetConditions(), func(condition apis.Condition) bool {
@@ -162,9 +373,22 @@ func WaitForPendingMachines(ctx context.Context, workspaceObj *kaitov1alpha1.Wor
 	return nil
 }
 
+// IsMachineLaunching returns true if the instance of the machine has been launched, but its node has not joined the
+// cluster or is not ready yet.
+func IsMachineLaunching(machineObj *v1alpha5.Machine) bool {
+	conditions := machineObj.GetConditions()
+	return machineObj.DeletionTimestamp.IsZero() &&
+		lo.ContainsBy(conditions, func(condition apis.Condition) bool {
+			return condition.Type == v1alpha5.MachineLaunched && condition.Status == v1.ConditionTrue
+		}) &&
+		!lo.ContainsBy(conditions, func(condition apis.Condition) bool {
+			return condition.Type == apis.ConditionReady && condition.Status == v1.ConditionTrue
+		})
+}
+
 // ListMachines list all machine objects in the cluster that are created by the workspace identified by the label.
 func ListMachinesByWorkspace(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) (*v1alpha5.MachineList, error) {
-	machineList := &v1alpha5.MachineList{}
+	list := DefaultAPI.NewList()
 
 	ls := labels.Set{
 		kaitov1alpha1.LabelWorkspaceName:      workspaceObj.Name,
@@ -174,19 +398,32 @@ func ListMachinesByWorkspace(ctx context.Context, workspaceObj *kaitov1alpha1.Wo
 	err := retry.OnError(retry.DefaultBackoff, func(err error) bool {
 		return true
 	}, func() error {
-		return kubeClient.List(ctx, machineList, &client.MatchingLabelsSelector{Selector: ls.AsSelector()})
+		return kubeClient.List(ctx, list, &client.MatchingLabelsSelector{Selector: ls.AsSelector()})
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return machineList, nil
+	if machineList, ok := list.(*v1alpha5.MachineList); ok {
+		return machineList, nil
+	}
+	items, err := DefaultAPI.FromList(list)
+	if err != nil {
+		return nil, err
+	}
+	return &v1alpha5.MachineList{Items: items}, nil
 }
 
 // CheckMachineStatus checks the status of the machine. If the machine is not ready, then it will wait for the machine to be ready.
 // If the machine is not ready after the timeout, then it will return an error.
 // if the machine is ready, then it will return nil.
 func CheckMachineStatus(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) error {
+	return CheckMachineStatusWithLaunchedHook(ctx, machineObj, kubeClient, nil)
+}
+
+// CheckMachineStatusWithLaunchedHook checks the status of the machine like CheckMachineStatus. The hook is invoked once
+// the instance of the machine has been launched while its node has not joined the cluster yet, e.g., to report it.
+func CheckMachineStatusWithLaunchedHook(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client, onLaunched func()) error {
 	klog.InfoS("CheckMachineStatus", "machine", klog.KObj(machineObj))
 	timeClock := clock.RealClock{}
 	tick := timeClock.NewTicker(machineStatusTimeoutInterval)
@@ -198,21 +435,36 @@ func CheckMachineStatus(ctx context.Context, machineObj *v1alpha5.Machine, kubeC
 			return ctx.Err()
 
 		case <-tick.C():
+			// The error of the cloud provider tells the users how to fix the provisioning, e.g., to request quota.
+			if code, message := ExtractCloudError(machineObj); code != "" {
+				return fmt.Errorf("check machine status timed out. machine %s is not ready: %s: %s", machineObj.Name, code, message)
+			}
 			return fmt.Errorf("check machine status timed out. machine %s is not ready", machineObj.Name)
 
 		default:
 			time.Sleep(1 * time.Second)
-			err := kubeClient.Get(ctx, client.ObjectKey{Name: machineObj.Name, Namespace: machineObj.Namespace}, machineObj, &client.GetOptions{})
+			err := getMachine(ctx, client.ObjectKey{Name: machineObj.Name, Namespace: machineObj.Namespace}, machineObj, kubeClient)
 			if err != nil {
 				return err
 			}
 
+			// Karpenter reports the machine as ready only once the startup taint is removed from its node. The taint
+			// is removed by the reconcile of the workspace triggered by the node once its GPUs are registered.
+			if HasGPUStartupTaint(machineObj) && IsMachineRegistered(machineObj) {
+				klog.InfoS("machine is registered, its node waits for the GPUs to be registered", "machine", machineObj.Name)
+				return nil
+			}
+
 			// if machine is not ready, then continue.
 			_, conditionFound := lo.Find(machineObj.GetConditions(), func(condition apis.Condition) bool {
 				return condition.Type == apis.ConditionReady &&
 					condition.Status == v1.ConditionTrue
 			})
 			if !conditionFound {
+				if onLaunched != nil && IsMachineLaunching(machineObj) {
+					onLaunched()
+					onLaunched = nil
+				}
 				continue
 			}
 
@@ -8,11 +8,16 @@ import (
 	"testing"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
 	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/mock"
 	"gotest.tools/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -76,6 +81,104 @@ func TestCreateMachine(t *testing.T) {
 	}
 }
 
+func TestCreateMachineWithAttempts(t *testing.T) {
+	testcases := map[string]struct {
+		createError       error
+		maxAttempts       int
+		expectedAttempts  int
+		expectedExhausted bool
+	}{
+		"Transient errors exhaust the attempts of the reconcile": {
+			createError:       errors.New("Failed to create machine"),
+			maxAttempts:       2,
+			expectedAttempts:  2,
+			expectedExhausted: true,
+		},
+		"Attempts default if not set": {
+			createError:       errors.New("Failed to create machine"),
+			expectedAttempts:  DefaultMaxCreateAttempts,
+			expectedExhausted: true,
+		},
+		"Existing machine is not retried by the reconcile": {
+			createError:      utils.IsAlreadyExistsError(),
+			maxAttempts:      1,
+			expectedAttempts: 1,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(tc.createError)
+
+			err := CreateMachineWithAttempts(context.Background(), utils.MockMachine.DeepCopy(), mockClient, tc.maxAttempts)
+			mockClient.AssertNumberOfCalls(t, "Create", tc.expectedAttempts)
+			var exhaustedErr *CreateAttemptsExhaustedError
+			assert.Equal(t, errors.As(err, &exhaustedErr), tc.expectedExhausted)
+			if tc.expectedExhausted {
+				assert.Equal(t, exhaustedErr.Attempts, tc.expectedAttempts)
+				assert.Equal(t, errors.Unwrap(err), tc.createError)
+			} else {
+				assert.Equal(t, err, tc.createError)
+			}
+		})
+	}
+}
+
+func TestCreateOrAdoptMachine(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset
+	machineName := GenerateMachineName(workspace, 0)
+
+	testcases := map[string]struct {
+		existingLabels map[string]string
+		expectedName   string
+	}{
+		"Adopt the existing machine of the workspace": {
+			existingLabels: map[string]string{
+				kaitov1alpha1.LabelWorkspaceName:      workspace.Name,
+				kaitov1alpha1.LabelWorkspaceNamespace: workspace.Namespace,
+			},
+			expectedName: machineName,
+		},
+		"Regenerate the name if the machine belongs to another workspace": {
+			existingLabels: map[string]string{
+				kaitov1alpha1.LabelWorkspaceName:      "otherWorkspace",
+				kaitov1alpha1.LabelWorkspaceNamespace: workspace.Namespace,
+			},
+			expectedName: generateMachineName(workspace, 0, 1),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			existing := utils.MockMachine.DeepCopy()
+			existing.Name = machineName
+			existing.Namespace = workspace.Namespace
+			existing.Labels = tc.existingLabels
+			existing.Spec.MachineTemplateRef = &v1alpha5.MachineTemplateRef{Name: machineName}
+			mockClient.CreateOrUpdateObjectInMap(existing)
+
+			mockClient.On("Create", mock.IsType(context.Background()), mock.MatchedBy(func(m *v1alpha5.Machine) bool {
+				return m.Name == machineName
+			}), mock.Anything).Run(func(args mock.Arguments) {
+				// The mock client stores the created object, restore the machine that already exists.
+				mockClient.CreateOrUpdateObjectInMap(existing)
+			}).Return(utils.IsAlreadyExistsError())
+			mockClient.On("Create", mock.IsType(context.Background()), mock.MatchedBy(func(m *v1alpha5.Machine) bool {
+				return m.Name != machineName
+			}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+
+			newMachine := GenerateMachineManifest(context.Background(), "0", workspace, 0, workspace.Resource.InstanceType, cloudprovider.Default)
+			err := CreateOrAdoptMachine(context.Background(), workspace, newMachine, 0, DefaultMaxCreateAttempts, mockClient)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, newMachine.Name, tc.expectedName)
+			assert.Equal(t, newMachine.Spec.MachineTemplateRef.Name, tc.expectedName)
+		})
+	}
+}
+
 func TestWaitForPendingMachines(t *testing.T) {
 	testcases := map[string]struct {
 		callMocks         func(c *utils.MockClient)
@@ -163,13 +266,238 @@ func TestWaitForPendingMachines(t *testing.T) {
 	}
 }
 
+func TestGenerateMachineName(t *testing.T) {
+	t.Run("Should generate the same name for the same workspace and index", func(t *testing.T) {
+		name := GenerateMachineName(utils.MockWorkspaceWithPreset, 1)
+		assert.Equal(t, GenerateMachineName(utils.MockWorkspaceWithPreset.DeepCopy(), 1), name)
+		assert.Check(t, len(validation.IsDNS1123Label(name)) == 0, "Machine name %s must be DNS safe", name)
+	})
+
+	t.Run("Should generate different names across indices and workspaces", func(t *testing.T) {
+		otherWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+		otherWorkspace.Name = "otherWorkspace"
+		otherNamespace := utils.MockWorkspaceWithPreset.DeepCopy()
+		otherNamespace.Namespace = "other"
+		recreated := utils.MockWorkspaceWithPreset.DeepCopy()
+		recreated.UID = "new-uid"
+
+		names := map[string]bool{
+			GenerateMachineName(utils.MockWorkspaceWithPreset, 0): true,
+			GenerateMachineName(utils.MockWorkspaceWithPreset, 1): true,
+			GenerateMachineName(otherWorkspace, 0):                true,
+			GenerateMachineName(otherNamespace, 0):                true,
+			GenerateMachineName(recreated, 0):                     true,
+		}
+		assert.Equal(t, len(names), 5)
+	})
+}
+
+func TestFreeMachineIndices(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset
+	existing := []v1alpha5.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Name: GenerateMachineName(workspace, 0)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: GenerateMachineName(workspace, 2)}},
+	}
+
+	assert.DeepEqual(t, FreeMachineIndices(workspace, existing, 3), []int{1, 3, 4})
+
+	// A machine renamed because of a name collision takes its index.
+	existing = append(existing, v1alpha5.Machine{ObjectMeta: metav1.ObjectMeta{Name: generateMachineName(workspace, 1, 1)}})
+	assert.DeepEqual(t, FreeMachineIndices(workspace, existing, 3), []int{3, 4, 5})
+}
+
 func TestGenerateMachineManifiest(t *testing.T) {
 	t.Run("Should generate a machine object from the given workspace", func(t *testing.T) {
 		mockWorkspace := utils.MockWorkspaceWithPreset
 
-		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace)
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
 
 		assert.Check(t, machine != nil, "Machine must not be nil")
 		assert.Equal(t, machine.Namespace, mockWorkspace.Namespace, "Machine must have same namespace as workspace")
+		_, found := machine.Annotations[kaitov1alpha1.AnnotationRDMAEnabled]
+		assert.Check(t, !found, "Machine must not be annotated for RDMA")
+	})
+
+	t.Run("Should annotate the machine if RDMA is required", func(t *testing.T) {
+		mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+		mockWorkspace.Resource.InstanceType = "Standard_ND96asr_v4"
+		mockWorkspace.Resource.RDMA = true
+
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+
+		assert.Equal(t, machine.Annotations[kaitov1alpha1.AnnotationRDMAEnabled], "true")
+	})
+
+	t.Run("Should stamp the common metadata of the workspace on the machine", func(t *testing.T) {
+		mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+		mockWorkspace.CommonMetadata = &kaitov1alpha1.CommonMetadata{
+			Labels:      map[string]string{"cost-center": "1234", LabelProvisionerName: "other"},
+			Annotations: map[string]string{"owner": "ml-platform@example.com"},
+		}
+
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+
+		assert.Equal(t, machine.Labels["cost-center"], "1234")
+		assert.Equal(t, machine.Labels[LabelProvisionerName], ProvisionerName)
+		assert.Equal(t, machine.Labels[kaitov1alpha1.LabelWorkspaceName], mockWorkspace.Name)
+		assert.Equal(t, machine.Annotations["owner"], "ml-platform@example.com")
+	})
+
+	t.Run("Should constrain the machine to the regions of the preset", func(t *testing.T) {
+		utils.RegisterTestModel()
+		mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+		mockWorkspace.Inference.Preset.Name = "test-regional-model"
+
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+		requirement, found := lo.Find(machine.Spec.Requirements, func(r corev1.NodeSelectorRequirement) bool {
+			return r.Key == corev1.LabelTopologyRegion
+		})
+		assert.Check(t, found, "Machine must be constrained to the regions of the preset")
+		assert.DeepEqual(t, requirement.Values, []string{"eastus", "westeurope"})
+
+		// The region required by the workspace is one of them.
+		mockWorkspace.Resource.LabelSelector.MatchLabels[corev1.LabelTopologyRegion] = "westeurope"
+		machine = GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+		requirement, _ = lo.Find(machine.Spec.Requirements, func(r corev1.NodeSelectorRequirement) bool {
+			return r.Key == corev1.LabelTopologyRegion
+		})
+		assert.DeepEqual(t, requirement.Values, []string{"westeurope"})
+	})
+
+	t.Run("Should not constrain the region of the machine if the preset is available anywhere", func(t *testing.T) {
+		utils.RegisterTestModel()
+		mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+		_, found := lo.Find(machine.Spec.Requirements, func(r corev1.NodeSelectorRequirement) bool {
+			return r.Key == corev1.LabelTopologyRegion
+		})
+		assert.Check(t, !found, "Machine must not be constrained to a region")
+	})
+
+	t.Run("Should provision a GPU machine with the startup taint", func(t *testing.T) {
+		mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+		EnableGPUStartupTaint = true
+		defer func() { EnableGPUStartupTaint = false }()
+
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+
+		assert.DeepEqual(t, machine.Spec.StartupTaints, []corev1.Taint{GPUStartupTaint})
+	})
+
+	t.Run("Should not taint a machine without GPUs or if the startup taint is disabled", func(t *testing.T) {
+		mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+
+		EnableGPUStartupTaint = true
+		machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, "Standard_D4s_v3", cloudprovider.Default)
+		assert.Check(t, machine.Spec.StartupTaints == nil, "Machine without GPUs must not have a startup taint")
+
+		EnableGPUStartupTaint = false
+		machine = GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, cloudprovider.Default)
+		assert.Check(t, machine.Spec.StartupTaints == nil, "Machine must not have a startup taint if it is disabled")
 	})
+
+	testcases := map[string]struct {
+		provider     cloudprovider.CloudProvider
+		instanceType string
+		capacityType string
+		expectedArch string
+	}{
+		"Azure provider": {
+			provider:     &cloudprovider.AzureProvider{},
+			instanceType: "Standard_NC12s_v3",
+			expectedArch: "amd64",
+		},
+		"Azure provider with a Grace Hopper SKU": {
+			provider:     &cloudprovider.AzureProvider{},
+			instanceType: "Standard_ND_GH200_v6",
+			expectedArch: "arm64",
+		},
+		"AWS provider": {
+			provider:     &cloudprovider.AWSProvider{},
+			instanceType: "g5.12xlarge",
+			expectedArch: "amd64",
+		},
+		"AWS provider with a Graviton instance type": {
+			provider:     &cloudprovider.AWSProvider{},
+			instanceType: "g5g.16xlarge",
+			expectedArch: "arm64",
+		},
+		"AWS provider with spot capacity": {
+			provider:     &cloudprovider.AWSProvider{},
+			instanceType: "p4d.24xlarge",
+			capacityType: v1alpha5.CapacityTypeSpot,
+			expectedArch: "amd64",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			mockWorkspace.Resource.InstanceType = tc.instanceType
+			if tc.capacityType != "" {
+				mockWorkspace.Resource.LabelSelector.MatchLabels[tc.provider.CapacityTypeLabel()] = tc.capacityType
+			}
+
+			machine := GenerateMachineManifest(context.Background(), "0", mockWorkspace, 0, mockWorkspace.Resource.InstanceType, tc.provider)
+
+			requirements := map[string][]string{}
+			for _, r := range machine.Spec.Requirements {
+				requirements[r.Key] = r.Values
+			}
+			assert.DeepEqual(t, requirements[tc.provider.InstanceTypeLabel()], []string{tc.instanceType})
+			assert.DeepEqual(t, requirements[corev1.LabelArchStable], []string{tc.expectedArch})
+			capacityTypes, found := requirements[tc.provider.CapacityTypeLabel()]
+			assert.Equal(t, found, tc.capacityType != "")
+			if found {
+				assert.DeepEqual(t, capacityTypes, []string{tc.capacityType})
+			}
+		})
+	}
+}
+
+func TestValidateLabelSelectorMatchesNodes(t *testing.T) {
+	testcases := map[string]struct {
+		matchExpressions      []metav1.LabelSelectorRequirement
+		fallbackInstanceTypes []string
+		expectErr             bool
+	}{
+		"Only matchLabels": {},
+		"Expressions satisfied by the provisioned labels": {
+			matchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "apps", Operator: metav1.LabelSelectorOpIn, Values: []string{"test", "other"}},
+				{Key: corev1.LabelInstanceTypeStable, Operator: metav1.LabelSelectorOpExists},
+				{Key: "dedicated", Operator: metav1.LabelSelectorOpDoesNotExist},
+			},
+		},
+		"Expression matches a fallback instance type": {
+			matchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: corev1.LabelInstanceTypeStable, Operator: metav1.LabelSelectorOpIn, Values: []string{"Standard_NC24ads_A100_v4"}},
+			},
+			fallbackInstanceTypes: []string{"Standard_NC24ads_A100_v4"},
+		},
+		"Expression requires a label that is not provisioned": {
+			matchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "dedicated", Operator: metav1.LabelSelectorOpExists},
+			},
+			expectErr: true,
+		},
+		"Expression excludes the instance type": {
+			matchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: corev1.LabelInstanceTypeStable, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"Standard_NC12s_v3"}},
+			},
+			expectErr: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			mockWorkspace.Resource.LabelSelector.MatchExpressions = tc.matchExpressions
+			mockWorkspace.Resource.FallbackInstanceTypes = tc.fallbackInstanceTypes
+
+			err := ValidateLabelSelectorMatchesNodes(mockWorkspace, cloudprovider.Default)
+			assert.Equal(t, err != nil, tc.expectErr)
+		})
+	}
 }
@@ -0,0 +1,196 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MigrationPhase is the step of an instance type migration that the workspace waits in.
+type MigrationPhase string
+
+const (
+	// MigrationPhaseNone means that no machine of the workspace has to be migrated.
+	MigrationPhaseNone MigrationPhase = ""
+	// MigrationPhaseProvisioning means that the replacement machine of the new instance type is provisioned.
+	MigrationPhaseProvisioning MigrationPhase = "ProvisioningMachine"
+	// MigrationPhaseShifting means that a surge replica of the workload becomes ready on the replacement machine.
+	MigrationPhaseShifting MigrationPhase = "ShiftingReplicas"
+)
+
+// migrationTimeout is how long the replacement machine of a migration may take to become ready before it is removed
+// again, the replaced machine is kept in that case.
+var migrationTimeout = machineStatusTimeoutInterval
+
+// MachineInstanceType returns the instance type required by the machine.
+func MachineInstanceType(machineObj *v1alpha5.Machine, provider cloudprovider.CloudProvider) string {
+	requirement, found := lo.Find(machineObj.Spec.Requirements, func(requirement v1.NodeSelectorRequirement) bool {
+		return requirement.Key == provider.InstanceTypeLabel() && requirement.Operator == v1.NodeSelectorOpIn
+	})
+	if !found || len(requirement.Values) == 0 {
+		return ""
+	}
+	return requirement.Values[0]
+}
+
+// MigrateSKU replaces the machines of the workspace that have another instance type with machines of the new
+// instance type, one machine at a time and without downtime. It never waits: each call takes the next step that the
+// state of the machines and of the workload allows, and returns the phase the migration waits in until the workspace
+// is reconciled again:
+//  1. a machine of the new instance type is provisioned, annotated with the old machine that it replaces,
+//  2. once it is ready, the node of the old machine is cordoned and the inference Deployment is scaled up by one
+//     replica, which lands on the new node. The replicas before the surge are kept in an annotation of the Deployment,
+//  3. once the surge replica is ready, the replicas of the old node are deleted, the Deployment is scaled back and
+//     the old machine is deleted.
+//
+// The number of ready replicas never drops below the replicas of the Deployment. Only Deployments are supported,
+// the StatefulSet of a distributed model cannot run across instance types.
+func MigrateSKU(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, newSKU string, provider cloudprovider.CloudProvider,
+	kubeClient client.Client) (MigrationPhase, error) {
+	machines, err := ListMachinesByWorkspace(ctx, workspaceObj, kubeClient)
+	if err != nil {
+		return MigrationPhaseNone, err
+	}
+
+	// A replacement in progress is finished before the next machine is replaced.
+	for i := range machines.Items {
+		replacement := &machines.Items[i]
+		replacedName, found := replacement.Annotations[kaitov1alpha1.AnnotationMigrationReplaces]
+		if !found || replacement.DeletionTimestamp != nil {
+			continue
+		}
+		replaced, found := lo.Find(machines.Items, func(m v1alpha5.Machine) bool {
+			return m.Name == replacedName && m.DeletionTimestamp == nil
+		})
+		if !found {
+			continue
+		}
+		phase, err := finishMigration(ctx, workspaceObj, replacement, &replaced, kubeClient)
+		if err != nil || phase != MigrationPhaseNone {
+			return phase, err
+		}
+		replaced.DeletionTimestamp = lo.ToPtr(metav1.Now())
+		for j := range machines.Items {
+			if machines.Items[j].Name == replaced.Name {
+				machines.Items[j].DeletionTimestamp = replaced.DeletionTimestamp
+			}
+		}
+		break
+	}
+
+	oldMachines := lo.Filter(machines.Items, func(m v1alpha5.Machine, _ int) bool {
+		return m.DeletionTimestamp == nil && MachineInstanceType(&m, provider) != newSKU
+	})
+	if len(oldMachines) == 0 {
+		return MigrationPhaseNone, nil
+	}
+	sort.Slice(oldMachines, func(i, j int) bool { return oldMachines[i].Name < oldMachines[j].Name })
+	klog.InfoS("MigrateSKU", "workspace", klog.KObj(workspaceObj), "instanceType", newSKU, "machines", len(oldMachines))
+
+	// The replacement has the disk size of the old machine.
+	oldMachine := &oldMachines[0]
+	storage := oldMachine.Spec.Resources.Requests[v1.ResourceStorage]
+	index := FreeMachineIndices(workspaceObj, machines.Items, 1)[0]
+	newMachine := GenerateMachineManifest(ctx, storage.String(), workspaceObj, index, newSKU, provider)
+	newMachine.Annotations = lo.Assign(newMachine.Annotations, map[string]string{kaitov1alpha1.AnnotationMigrationReplaces: oldMachine.Name})
+	if err := CreateOrAdoptMachine(ctx, workspaceObj, newMachine, index, DefaultMaxCreateAttempts, kubeClient); err != nil {
+		return MigrationPhaseNone, err
+	}
+	return MigrationPhaseProvisioning, nil
+}
+
+// finishMigration shifts the replicas of the workload from the node of the replaced machine to the node of its
+// replacement, and deletes the replaced machine. It returns the phase the migration waits in, or MigrationPhaseNone
+// once the replaced machine is deleted. A replacement that is not ready within migrationTimeout is deleted instead,
+// the replaced machine is kept.
+func finishMigration(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, replacement, replaced *v1alpha5.Machine,
+	kubeClient client.Client) (MigrationPhase, error) {
+	if !isMachineReady(replacement) {
+		if time.Since(replacement.CreationTimestamp.Time) < migrationTimeout {
+			klog.InfoS("waiting for the migration replacement machine to be ready", "workspace", klog.KObj(workspaceObj),
+				"machine", klog.KObj(replacement))
+			return MigrationPhaseProvisioning, nil
+		}
+		klog.InfoS("the migration replacement machine is not ready in time, deleting it", "workspace", klog.KObj(workspaceObj),
+			"machine", klog.KObj(replacement), "timeout", migrationTimeout)
+		if err := DeleteMachine(ctx, replacement, kubeClient); client.IgnoreNotFound(err) != nil {
+			return MigrationPhaseNone, err
+		}
+		return MigrationPhaseNone, fmt.Errorf("the replacement machine %s of the new instance type is not ready within %s", replacement.Name, migrationTimeout)
+	}
+
+	// A workspace whose workload has not been created yet has no replicas to shift.
+	workload := &appsv1.Deployment{}
+	if err := resources.GetResource(ctx, workspaceObj.Name, workspaceObj.Namespace, kubeClient, workload); client.IgnoreNotFound(err) != nil {
+		return MigrationPhaseNone, err
+	} else if err != nil {
+		workload = nil
+	}
+	if workload != nil && replaced.Status.NodeName != "" {
+		shifted, err := shiftReplicas(ctx, workspaceObj, replaced.Status.NodeName, workload, kubeClient)
+		if err != nil || !shifted {
+			return MigrationPhaseShifting, err
+		}
+	}
+
+	klog.InfoS("deleting the machine of the old instance type", "machine", klog.KObj(replaced), "workspace", klog.KObj(workspaceObj))
+	if err := DeleteMachine(ctx, replaced, kubeClient); client.IgnoreNotFound(err) != nil {
+		klog.ErrorS(err, "failed to delete the machine", "machine", klog.KObj(replaced))
+		return MigrationPhaseNone, err
+	}
+	return MigrationPhaseNone, nil
+}
+
+// shiftReplicas moves the replicas of the workload away from the node, and returns true once they are moved. The
+// node is cordoned and a surge replica is scheduled on the new node, the replicas of the node are only deleted once
+// the surge replica is ready.
+func shiftReplicas(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, nodeName string, workload *appsv1.Deployment,
+	kubeClient client.Client) (bool, error) {
+	if err := resources.CordonNode(ctx, nodeName, kubeClient); client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+
+	value, surged := workload.Annotations[kaitov1alpha1.AnnotationMigrationReplicas]
+	if !surged {
+		replicas := lo.FromPtr(workload.Spec.Replicas)
+		workload.Annotations = lo.Assign(workload.Annotations, map[string]string{kaitov1alpha1.AnnotationMigrationReplicas: strconv.Itoa(int(replicas))})
+		workload.Spec.Replicas = lo.ToPtr(replicas + 1)
+		klog.InfoS("scaling up the workload by a surge replica", "workspace", klog.KObj(workspaceObj), "replicas", replicas+1)
+		return false, kubeClient.Update(ctx, workload, &client.UpdateOptions{})
+	}
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid annotation %s=%q of deployment %s: %w", kaitov1alpha1.AnnotationMigrationReplicas, value, workload.Name, err)
+	}
+	if workload.Status.ReadyReplicas < lo.FromPtr(workload.Spec.Replicas) {
+		klog.InfoS("waiting for the surge replica to be ready", "workspace", klog.KObj(workspaceObj),
+			"readyReplicas", workload.Status.ReadyReplicas, "replicas", lo.FromPtr(workload.Spec.Replicas))
+		return false, nil
+	}
+
+	if err := drainWorkspacePods(ctx, nodeName, client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name}, kubeClient); err != nil {
+		return false, err
+	}
+	// The replacements of the deleted pods cannot be scheduled and are the first to be removed by the scale down.
+	delete(workload.Annotations, kaitov1alpha1.AnnotationMigrationReplicas)
+	workload.Spec.Replicas = lo.ToPtr(int32(replicas))
+	if err := kubeClient.Update(ctx, workload, &client.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMachineInstanceType(t *testing.T) {
+	machineObj := utils.MockMachine.DeepCopy()
+	assert.Equal(t, MachineInstanceType(machineObj, cloudprovider.Default), "Standard_NC12s_v3")
+
+	machineObj.Spec.Requirements = nil
+	assert.Equal(t, MachineInstanceType(machineObj, cloudprovider.Default), "")
+}
+
+func TestMigrateSKU(t *testing.T) {
+	testcases := map[string]struct {
+		newSKU             string
+		withWorkload       bool
+		replacementTimeout bool
+		expectedPhases     []MigrationPhase
+		expectedSteps      []string
+		expectedError      bool
+	}{
+		"Machines already have the new instance type": {
+			newSKU:         "Standard_NC12s_v3",
+			withWorkload:   true,
+			expectedPhases: []MigrationPhase{MigrationPhaseNone},
+		},
+		"Provision new, shift the replicas and drain old": {
+			newSKU:         "Standard_NC24ads_A100_v4",
+			withWorkload:   true,
+			expectedPhases: []MigrationPhase{MigrationPhaseProvisioning, MigrationPhaseShifting, MigrationPhaseNone},
+			expectedSteps:  []string{"provision", "cordon", "scale 2", "shift", "scale 1", "drain"},
+		},
+		"Provision new and drain old without a workload": {
+			newSKU:         "Standard_NC24ads_A100_v4",
+			expectedPhases: []MigrationPhase{MigrationPhaseProvisioning, MigrationPhaseNone},
+			expectedSteps:  []string{"provision", "drain"},
+		},
+		"Remove the replacement that is not ready in time and keep old": {
+			newSKU:             "Standard_NC24ads_A100_v4",
+			withWorkload:       true,
+			replacementTimeout: true,
+			expectedPhases:     []MigrationPhase{MigrationPhaseProvisioning, MigrationPhaseNone},
+			expectedSteps:      []string{"provision", "remove"},
+			expectedError:      true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+
+			oldMachine := utils.MockMachine.DeepCopy()
+			oldMachine.Labels = map[string]string{
+				kaitov1alpha1.LabelWorkspaceName:      workspace.Name,
+				kaitov1alpha1.LabelWorkspaceNamespace: workspace.Namespace,
+			}
+			oldMachine.Status.NodeName = "old-node"
+			machines := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			machines[client.ObjectKeyFromObject(oldMachine)] = oldMachine
+			mockClient.CreateOrUpdateObjectInMap(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "old-node"}})
+			oldPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "old-pod", Namespace: workspace.Namespace},
+				Spec:       corev1.PodSpec{NodeName: "old-node"},
+			}
+			mockClient.CreateMapWithType(&corev1.PodList{})[client.ObjectKeyFromObject(oldPod)] = oldPod
+			if tc.withWorkload {
+				mockClient.CreateOrUpdateObjectInMap(&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: workspace.Name, Namespace: workspace.Namespace},
+					Spec:       appsv1.DeploymentSpec{Replicas: lo.ToPtr(int32(1))},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+				})
+			}
+
+			var steps []string
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			if tc.withWorkload {
+				mockClient.On("Get", mock.Anything, mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(nil)
+			} else {
+				mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&appsv1.Deployment{}), mock.Anything).Return(utils.NotFoundError())
+			}
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Run(func(args mock.Arguments) {
+				machineObj := args.Get(1).(*v1alpha5.Machine).DeepCopy()
+				assert.Equal(t, machineObj.Annotations[kaitov1alpha1.AnnotationMigrationReplaces], oldMachine.Name)
+				machineObj.CreationTimestamp = metav1.Now()
+				if tc.replacementTimeout {
+					machineObj.CreationTimestamp = metav1.NewTime(time.Now().Add(-migrationTimeout))
+				}
+				machines[client.ObjectKeyFromObject(machineObj)] = machineObj
+				steps = append(steps, "provision")
+			}).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Run(func(args mock.Arguments) {
+				assert.Check(t, args.Get(1).(*corev1.Node).Spec.Unschedulable, "The old node is expected to be cordoned")
+				mockClient.CreateOrUpdateObjectInMap(args.Get(1).(*corev1.Node).DeepCopy())
+				steps = append(steps, "cordon")
+			}).Return(nil)
+			// The replicas of the Deployment become ready once scheduled.
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&appsv1.Deployment{}), mock.Anything).Run(func(args mock.Arguments) {
+				deployment := args.Get(1).(*appsv1.Deployment).DeepCopy()
+				deployment.Status.ReadyReplicas = *deployment.Spec.Replicas
+				steps = append(steps, fmt.Sprintf("scale %d", *deployment.Spec.Replicas))
+				mockClient.CreateOrUpdateObjectInMap(deployment)
+			}).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Run(func(args mock.Arguments) {
+				deployment := mockClient.CreateMapWithType(&appsv1.Deployment{})[client.ObjectKeyFromObject(workspace)].(*appsv1.Deployment)
+				assert.Check(t, deployment.Status.ReadyReplicas-1 >= 1, "A ready replica is expected to remain while the old node is drained")
+				steps = append(steps, "shift")
+			}).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Run(func(args mock.Arguments) {
+				machineObj := machines[client.ObjectKeyFromObject(args.Get(1).(*v1alpha5.Machine))].(*v1alpha5.Machine)
+				machineObj.DeletionTimestamp = lo.ToPtr(metav1.Now())
+				steps = append(steps, lo.Ternary(machineObj == oldMachine, "drain", "remove"))
+			}).Return(nil)
+
+			// Each call is a reconcile of the workspace, the machines become ready on a new node in between.
+			var phases []MigrationPhase
+			var err error
+			for len(phases) < 5 {
+				var phase MigrationPhase
+				phase, err = MigrateSKU(context.Background(), workspace, tc.newSKU, cloudprovider.Default, mockClient)
+				phases = append(phases, phase)
+				if err != nil || phase == MigrationPhaseNone {
+					break
+				}
+				for _, obj := range machines {
+					if machineObj := obj.(*v1alpha5.Machine); machineObj != oldMachine && !tc.replacementTimeout {
+						machineObj.Status.NodeName = "new-node"
+						machineObj.SetConditions(apis.Conditions{{Type: apis.ConditionReady, Status: corev1.ConditionTrue}})
+					}
+				}
+			}
+			assert.Equal(t, err != nil, tc.expectedError)
+			assert.DeepEqual(t, phases, tc.expectedPhases)
+			assert.DeepEqual(t, steps, tc.expectedSteps)
+			assert.Check(t, (oldMachine.DeletionTimestamp == nil) == (len(tc.expectedSteps) == 0 || tc.replacementTimeout))
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ParallelismRetryAfter is how long the provisioning of a machine is delayed if its provisioner is provisioning as
+// many machines as it may at once.
+const ParallelismRetryAfter = 30 * time.Second
+
+// ProvisioningParallelism limits how many machines of each provisioner are provisioned at once, i.e., have been created
+// but are not ready yet, so that the provisioners with a high throughput provision quickly while the slow ones are
+// throttled. The limit of a provisioner is taken from its AnnotationProvisioningParallelism annotation, then from
+// Limits, then from Default. A provisioner is not limited if its limit is zero.
+type ProvisioningParallelism struct {
+	// Default is the limit of the provisioners that are neither annotated nor configured in Limits.
+	Default int
+	// Limits are the limits of the provisioners by name.
+	Limits map[string]int
+
+	mu sync.Mutex
+	// reserved counts the machines of each provisioner that are about to be created by the reconciles.
+	reserved map[string]int
+}
+
+// Acquire reserves the provisioning of the machine by the provisioner of its LabelProvisionerName label, which the
+// label selector of the workspace may set. It returns a ProvisioningDelayedError if the provisioner is already
+// provisioning as many machines as its limit allows. The returned release function must be called once the machine
+// has been created, or its creation failed.
+func (p *ProvisioningParallelism) Acquire(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) (func(), error) {
+	provisionerName := machineObj.Labels[LabelProvisionerName]
+	if provisionerName == "" {
+		return func() {}, nil
+	}
+	limit, err := p.limit(ctx, provisionerName, kubeClient)
+	if err != nil || limit <= 0 {
+		return func() {}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inFlight, err := ProvisioningMachineCount(ctx, provisionerName, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if inFlight+p.reserved[provisionerName] >= limit {
+		klog.InfoS("provisioner is provisioning as many machines as it may at once", "provisioner", provisionerName,
+			"provisioning", inFlight, "reserved", p.reserved[provisionerName], "limit", limit)
+		return nil, &ProvisioningDelayedError{
+			RetryAfter: ParallelismRetryAfter,
+			Reason:     fmt.Sprintf("provisioner %s is provisioning %d machines, at most %d may be provisioned at once", provisionerName, inFlight, limit),
+		}
+	}
+	if p.reserved == nil {
+		p.reserved = map[string]int{}
+	}
+	p.reserved[provisionerName]++
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.reserved[provisionerName]--
+	}, nil
+}
+
+// limit returns how many machines of the provisioner may be provisioned at once. The provisioner is read in the
+// version of DefaultAPI, i.e., as a NodePool if the machines are node claims.
+func (p *ProvisioningParallelism) limit(ctx context.Context, provisionerName string, kubeClient client.Client) (int, error) {
+	provisioner := DefaultAPI.NewProvisioner()
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: provisionerName}, provisioner, &client.GetOptions{}); client.IgnoreNotFound(err) != nil {
+		return 0, err
+	}
+	if value, found := provisioner.GetAnnotations()[kaitov1alpha1.AnnotationProvisioningParallelism]; found {
+		if limit, err := strconv.Atoi(value); err == nil && limit >= 0 {
+			return limit, nil
+		}
+		klog.InfoS("the provisioning parallelism annotation is not a non-negative integer, ignore it", "provisioner", provisionerName, "value", value)
+	}
+	if limit, found := p.Limits[provisionerName]; found {
+		return limit, nil
+	}
+	return p.Default, nil
+}
+
+// ProvisioningMachineCount returns the number of machines of the provisioner that have been created but are not
+// ready yet.
+func ProvisioningMachineCount(ctx context.Context, provisionerName string, kubeClient client.Client) (int, error) {
+	machines, err := ListMachines(ctx, kubeClient, client.MatchingLabels{LabelProvisionerName: provisionerName})
+	if err != nil {
+		return 0, err
+	}
+	return lo.CountBy(machines, func(m v1alpha5.Machine) bool {
+		return m.Labels[LabelProvisionerName] == provisionerName && m.DeletionTimestamp.IsZero() &&
+			!lo.ContainsBy(m.GetConditions(), func(condition apis.Condition) bool {
+				return condition.Type == apis.ConditionReady && condition.Status == v1.ConditionTrue
+			})
+	}), nil
+}
+
+// ParseProvisioningParallelism parses the limits of the provisioners from a comma separated list of
+// <provisioner>=<limit> pairs, e.g., "default=4,slow=1".
+func ParseProvisioningParallelism(value string) (map[string]int, error) {
+	limits := map[string]int{}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, limitValue, found := strings.Cut(pair, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(limitValue))
+		if !found || strings.TrimSpace(name) == "" || err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid provisioning parallelism %q, expected <provisioner>=<limit> with a non-negative limit", pair)
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func mockProvisionerMachine(name, provisionerName string, ready bool) *v1alpha5.Machine {
+	m := utils.MockMachine.DeepCopy()
+	m.Name = name
+	m.Labels = map[string]string{LabelProvisionerName: provisionerName}
+	m.Status.Conditions = apis.Conditions{
+		{Type: apis.ConditionReady, Status: map[bool]corev1.ConditionStatus{true: corev1.ConditionTrue, false: corev1.ConditionFalse}[ready]},
+	}
+	return m
+}
+
+func TestProvisioningParallelism(t *testing.T) {
+	// The fast pool is provisioning two machines, the slow pool one.
+	machines := []*v1alpha5.Machine{
+		mockProvisionerMachine("fast-1", "fast", false),
+		mockProvisionerMachine("fast-2", "fast", false),
+		mockProvisionerMachine("fast-3", "fast", true),
+		mockProvisionerMachine("slow-1", "slow", false),
+	}
+	provisioners := []*v1alpha5.Provisioner{
+		{ObjectMeta: metav1.ObjectMeta{Name: "fast", Annotations: map[string]string{kaitov1alpha1.AnnotationProvisioningParallelism: "3"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "slow"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "invalid", Annotations: map[string]string{kaitov1alpha1.AnnotationProvisioningParallelism: "many"}}},
+	}
+	parallelism := func(defaultLimit int) *ProvisioningParallelism {
+		return &ProvisioningParallelism{Default: defaultLimit, Limits: map[string]int{"slow": 1, "fast": 10}}
+	}
+
+	testcases := map[string]struct {
+		provisioner     string
+		defaultLimit    int
+		expectedAllowed int
+	}{
+		"Annotation of the pool takes precedence over the configured limit": {
+			provisioner:     "fast",
+			expectedAllowed: 1,
+		},
+		"Configured limit of the pool is honored": {
+			provisioner:     "slow",
+			expectedAllowed: 0,
+		},
+		"Pool without a limit of its own takes the default limit": {
+			provisioner:     "other",
+			defaultLimit:    2,
+			expectedAllowed: 2,
+		},
+		"Invalid annotation of the pool is ignored": {
+			provisioner:     "invalid",
+			defaultLimit:    1,
+			expectedAllowed: 1,
+		},
+		"Pool without any limit is not throttled": {
+			provisioner:     "other",
+			expectedAllowed: 3,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			for _, provisioner := range provisioners {
+				mockClient.CreateOrUpdateObjectInMap(provisioner)
+			}
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			for _, m := range machines {
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Provisioner{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+
+			p := parallelism(tc.defaultLimit)
+			var releases []func()
+			for attempt := 0; attempt < 3; attempt++ {
+				release, err := p.Acquire(context.Background(), mockProvisionerMachine("new", tc.provisioner, false), mockClient)
+				if attempt < tc.expectedAllowed {
+					assert.NilError(t, err)
+					releases = append(releases, release)
+					continue
+				}
+				var delayedErr *ProvisioningDelayedError
+				assert.Check(t, errors.As(err, &delayedErr), "expected the provisioning to be delayed, got %v", err)
+			}
+
+			// The reservations are released once the machines are created.
+			for _, release := range releases {
+				release()
+			}
+			if tc.expectedAllowed > 0 {
+				_, err := p.Acquire(context.Background(), mockProvisionerMachine("new", tc.provisioner, false), mockClient)
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func TestProvisioningParallelismOfUnlabeledMachine(t *testing.T) {
+	p := &ProvisioningParallelism{Default: 1}
+	machineObj := utils.MockMachine.DeepCopy()
+	machineObj.Labels = nil
+
+	// The machine of no provisioner is not throttled, the client is not called.
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := p.Acquire(context.Background(), machineObj, utils.NewClient())
+		assert.NilError(t, err)
+	}
+}
+
+func TestProvisioningParallelismOfNodePool(t *testing.T) {
+	DefaultAPI = NodeClaimAPI{Version: NodeClaimV1}
+	defer func() { DefaultAPI = MachineAPI{} }()
+
+	nodePool := DefaultAPI.NewProvisioner().(*unstructured.Unstructured)
+	nodePool.SetName("gpu")
+	nodePool.SetAnnotations(map[string]string{kaitov1alpha1.AnnotationProvisioningParallelism: "2"})
+	mockClient := utils.NewClient()
+	mockClient.CreateOrUpdateObjectInMap(nodePool)
+	mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&unstructured.Unstructured{}), mock.Anything).Return(nil)
+
+	limit, err := (&ProvisioningParallelism{Default: 5}).limit(context.Background(), "gpu", mockClient)
+	assert.NilError(t, err)
+	assert.Equal(t, limit, 2)
+	requested := mockClient.Calls[0].Arguments.Get(2).(*unstructured.Unstructured)
+	assert.Equal(t, requested.GetKind(), "NodePool")
+	assert.Equal(t, requested.GetAPIVersion(), "karpenter.sh/v1")
+}
+
+func TestParseProvisioningParallelism(t *testing.T) {
+	testcases := map[string]struct {
+		value          string
+		expectedLimits map[string]int
+		expectedError  bool
+	}{
+		"Empty value": {
+			value:          "",
+			expectedLimits: map[string]int{},
+		},
+		"Limits of several pools": {
+			value:          "default=4, slow=1",
+			expectedLimits: map[string]int{"default": 4, "slow": 1},
+		},
+		"Pool without a limit": {
+			value:         "default",
+			expectedError: true,
+		},
+		"Negative limit": {
+			value:         "default=-1",
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			limits, err := ParseProvisioningParallelism(tc.value)
+			assert.Equal(t, err != nil, tc.expectedError)
+			if !tc.expectedError {
+				assert.DeepEqual(t, limits, tc.expectedLimits)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GPUStartupTaintKey is the key of the startup taint of the GPU machines. The taint keeps the pods from being
+// scheduled on the node before the NVIDIA device plugin registers its GPUs, kaito removes it once they are registered.
+// The device plugin must tolerate the taint.
+const GPUStartupTaintKey = "kaito.sh/gpu-not-ready"
+
+// EnableGPUStartupTaint adds the startup taint to the machines of the GPU instance types, it is configured at startup.
+// It is disabled by default, since a device plugin that does not tolerate the taint never registers the GPUs.
+var EnableGPUStartupTaint = false
+
+// GPUStartupTimeout is how long the GPUs of a registered node may take to be registered before it is reported.
+const GPUStartupTimeout = 10 * time.Minute
+
+// GPUStartupTaint is the startup taint of the GPU machines. Karpenter only reports a machine as initialized once its
+// startup taints are removed from the node.
+var GPUStartupTaint = v1.Taint{
+	Key:    GPUStartupTaintKey,
+	Value:  "true",
+	Effect: v1.TaintEffectNoSchedule,
+}
+
+// HasGPUStartupTaint returns true if the node of the machine is provisioned with the GPU startup taint.
+func HasGPUStartupTaint(machineObj *v1alpha5.Machine) bool {
+	return lo.ContainsBy(machineObj.Spec.StartupTaints, func(taint v1.Taint) bool { return taint.Key == GPUStartupTaintKey })
+}
+
+// HasNodeGPUStartupTaint returns true if the startup taint has not been removed from the node yet.
+func HasNodeGPUStartupTaint(nodeObj *v1.Node) bool {
+	return lo.ContainsBy(nodeObj.Spec.Taints, func(taint v1.Taint) bool { return taint.Key == GPUStartupTaintKey })
+}
+
+// IsMachineRegistered returns true once the node of the machine has joined the cluster. A machine with the GPU startup
+// taint is only reported as ready by karpenter once the taint is removed from its registered node.
+func IsMachineRegistered(machineObj *v1alpha5.Machine) bool {
+	return lo.ContainsBy(machineObj.GetConditions(), func(condition apis.Condition) bool {
+		return condition.Type == v1alpha5.MachineRegistered && condition.Status == v1.ConditionTrue
+	})
+}
+
+// GPUStartupOverdue returns true if the node of the machine was registered longer than GPUStartupTimeout ago.
+func GPUStartupOverdue(machineObj *v1alpha5.Machine, now time.Time) bool {
+	registered, found := lo.Find(machineObj.GetConditions(), func(condition apis.Condition) bool {
+		return condition.Type == v1alpha5.MachineRegistered && condition.Status == v1.ConditionTrue
+	})
+	return found && now.Sub(registered.LastTransitionTime.Inner.Time) > GPUStartupTimeout
+}
+
+// GPUCapacityRegistered returns true once the NVIDIA device plugin has registered the GPUs of the node.
+func GPUCapacityRegistered(nodeObj *v1.Node) bool {
+	return !nodeObj.Status.Capacity.Name(resources.CapacityNvidiaGPU, "").IsZero()
+}
+
+// GateGPUStartupTaint removes the GPU startup taint from the node of the machine once the node reports its GPU
+// capacity. It returns true once the node is free of the taint, and false while the node is not registered yet or
+// its GPUs are not.
+func GateGPUStartupTaint(ctx context.Context, machineObj *v1alpha5.Machine, kubeClient client.Client) (bool, error) {
+	if !HasGPUStartupTaint(machineObj) {
+		return true, nil
+	}
+	if machineObj.Status.NodeName == "" {
+		return false, nil
+	}
+
+	nodeObj, err := resources.GetNode(ctx, machineObj.Status.NodeName, kubeClient)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !HasNodeGPUStartupTaint(nodeObj) {
+		return true, nil
+	}
+	if !GPUCapacityRegistered(nodeObj) {
+		return false, nil
+	}
+
+	klog.InfoS("the GPUs of the node are registered, removing the startup taint", "machine", klog.KObj(machineObj), "node", nodeObj.Name)
+	if err := resources.RemoveNodeTaint(ctx, nodeObj.Name, GPUStartupTaintKey, kubeClient); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGateGPUStartupTaint(t *testing.T) {
+	sku := corev1.Taint{Key: "sku", Value: GPUString, Effect: corev1.TaintEffectNoSchedule}
+	testcases := map[string]struct {
+		startupTaints      []corev1.Taint
+		nodeName           string
+		nodeTaints         []corev1.Taint
+		gpuCapacity        string
+		expectedFree       bool
+		expectTaintRemoval bool
+	}{
+		"Machine without the startup taint is not gated": {
+			nodeName:     "node1",
+			expectedFree: true,
+		},
+		"Machine whose node is not registered is gated": {
+			startupTaints: []corev1.Taint{GPUStartupTaint},
+		},
+		"Node whose GPUs are not registered keeps the startup taint": {
+			startupTaints: []corev1.Taint{GPUStartupTaint},
+			nodeName:      "node1",
+			nodeTaints:    []corev1.Taint{sku, GPUStartupTaint},
+		},
+		"Startup taint is removed once the GPUs are registered": {
+			startupTaints:      []corev1.Taint{GPUStartupTaint},
+			nodeName:           "node1",
+			nodeTaints:         []corev1.Taint{sku, GPUStartupTaint},
+			gpuCapacity:        "2",
+			expectedFree:       true,
+			expectTaintRemoval: true,
+		},
+		"Node whose startup taint is removed is not gated": {
+			startupTaints: []corev1.Taint{GPUStartupTaint},
+			nodeName:      "node1",
+			nodeTaints:    []corev1.Taint{sku},
+			expectedFree:  true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			machineObj := &v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine1", Namespace: "kaito"},
+				Spec:       v1alpha5.MachineSpec{StartupTaints: tc.startupTaints},
+				Status:     v1alpha5.MachineStatus{NodeName: tc.nodeName},
+			}
+			nodeObj := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+				Spec:       corev1.NodeSpec{Taints: tc.nodeTaints},
+			}
+			if tc.gpuCapacity != "" {
+				nodeObj.Status.Capacity = corev1.ResourceList{utils.CapacityNvidiaGPU: resource.MustParse(tc.gpuCapacity)}
+			}
+			mockClient.CreateOrUpdateObjectInMap(nodeObj)
+			mockClient.On("Get", mock.IsType(context.Background()), client.ObjectKeyFromObject(nodeObj), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+
+			free, err := GateGPUStartupTaint(context.Background(), machineObj, mockClient)
+			assert.NilError(t, err)
+			assert.Equal(t, free, tc.expectedFree)
+
+			if !tc.expectTaintRemoval {
+				mockClient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			updated := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*corev1.Node)
+			assert.DeepEqual(t, updated.Spec.Taints, []corev1.Taint{sku})
+		})
+	}
+}
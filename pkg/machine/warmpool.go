@@ -0,0 +1,163 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WarmPool is a pool of idle machines of an instance type that are kept for the workspaces of a preset.
+type WarmPool struct {
+	PresetName   string
+	InstanceType string
+	Count        int
+}
+
+// ParseWarmPools parses the warm pools from a comma separated list of <preset>:<instance type>=<count> entries,
+// e.g., "falcon-7b:Standard_NC12s_v3=2".
+func ParseWarmPools(value string) ([]WarmPool, error) {
+	var pools []WarmPool
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		key, countValue, found := strings.Cut(entry, "=")
+		presetName, instanceType, _ := strings.Cut(key, ":")
+		count, err := strconv.Atoi(strings.TrimSpace(countValue))
+		if !found || strings.TrimSpace(presetName) == "" || strings.TrimSpace(instanceType) == "" || err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid warm pool %q, expected <preset>:<instance type>=<count> with a non-negative count", entry)
+		}
+		pools = append(pools, WarmPool{
+			PresetName:   strings.TrimSpace(presetName),
+			InstanceType: strings.TrimSpace(instanceType),
+			Count:        count,
+		})
+	}
+	return pools, nil
+}
+
+// ProvisionWarmPool makes sure there are count idle machines of the instance type labeled for the preset.
+// Idle machines do not belong to any workspace until they are claimed by ClaimFromWarmPool.
+func ProvisionWarmPool(ctx context.Context, presetName, instanceType, namespace, storageRequirement string, count int,
+	kubeClient client.Client, provider cloudprovider.CloudProvider) error {
+	pool, err := listWarmPoolMachines(ctx, presetName, instanceType, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	// The pool is provisioned the same way as a workspace asking for the instance type.
+	poolWorkspace := &kaitov1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "warmpool-" + presetName,
+			Namespace: namespace,
+		},
+		Resource: kaitov1alpha1.ResourceSpec{
+			InstanceType: instanceType,
+		},
+	}
+	// Claimed machines keep their names, so the indices they took are skipped.
+	for index, poolSize := 0, len(pool); poolSize < count; index++ {
+		newMachine := GenerateMachineManifest(ctx, storageRequirement, poolWorkspace, index, poolWorkspace.Resource.InstanceType, provider)
+		delete(newMachine.Labels, kaitov1alpha1.LabelWorkspaceName)
+		delete(newMachine.Labels, kaitov1alpha1.LabelWorkspaceNamespace)
+		newMachine.Labels[kaitov1alpha1.LabelWarmPoolPreset] = presetName
+
+		if err := CreateMachine(ctx, newMachine, kubeClient); apierrors.IsAlreadyExists(err) {
+			continue
+		} else if err != nil {
+			klog.ErrorS(err, "failed to create warm pool machine", "preset", presetName, "machine", klog.KObj(newMachine))
+			return err
+		}
+		poolSize++
+	}
+	return nil
+}
+
+// ClaimFromWarmPool hands a ready idle machine of the workspace preset over to the workspace and returns its node.
+// It returns nil if the warm pool has no ready machine of the workspace instance type.
+func ClaimFromWarmPool(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) (*v1.Node, error) {
+	if workspaceObj.Inference == nil || workspaceObj.Inference.Preset == nil {
+		return nil, nil
+	}
+	pool, err := listWarmPoolMachines(ctx, string(workspaceObj.Inference.Preset.Name), workspaceObj.Resource.InstanceType, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	idleMachine, found := lo.Find(pool, func(m *v1alpha5.Machine) bool {
+		_, ready := lo.Find(m.GetConditions(), func(condition apis.Condition) bool {
+			return condition.Type == apis.ConditionReady && condition.Status == v1.ConditionTrue
+		})
+		return ready && m.Status.NodeName != ""
+	})
+	if !found {
+		klog.InfoS("no idle machine in the warm pool", "workspace", klog.KObj(workspaceObj))
+		return nil, nil
+	}
+	klog.InfoS("ClaimFromWarmPool", "workspace", klog.KObj(workspaceObj), "machine", klog.KObj(idleMachine))
+
+	workspaceLabels := map[string]string{
+		kaitov1alpha1.LabelWorkspaceName:      workspaceObj.Name,
+		kaitov1alpha1.LabelWorkspaceNamespace: workspaceObj.Namespace,
+	}
+	// The node must match the workspace labelSelector to be selected for the workspace.
+	if workspaceObj.Resource.LabelSelector != nil {
+		workspaceLabels = lo.Assign(workspaceLabels, workspaceObj.Resource.LabelSelector.MatchLabels)
+	}
+
+	delete(idleMachine.Labels, kaitov1alpha1.LabelWarmPoolPreset)
+	idleMachine.Labels = lo.Assign(idleMachine.Labels, workspaceLabels)
+	if err := updateMachine(ctx, idleMachine, kubeClient); err != nil {
+		klog.ErrorS(err, "failed to claim the warm pool machine", "machine", klog.KObj(idleMachine))
+		return nil, err
+	}
+
+	for key, value := range workspaceLabels {
+		if err := resources.UpdateNodeWithLabel(ctx, idleMachine.Status.NodeName, key, value, kubeClient); err != nil {
+			return nil, err
+		}
+	}
+	return resources.GetNode(ctx, idleMachine.Status.NodeName, kubeClient)
+}
+
+// listWarmPoolMachines lists the idle machines of the instance type that are labeled for the preset.
+func listWarmPoolMachines(ctx context.Context, presetName, instanceType string, kubeClient client.Client) ([]*v1alpha5.Machine, error) {
+	ls := labels.Set{
+		kaitov1alpha1.LabelWarmPoolPreset: presetName,
+	}
+	machines, err := ListMachines(ctx, kubeClient, &client.MatchingLabelsSelector{Selector: ls.AsSelector()})
+	if err != nil {
+		return nil, err
+	}
+
+	var pool []*v1alpha5.Machine
+	for i := range machines {
+		m := &machines[i]
+		if m.DeletionTimestamp != nil || m.Labels[kaitov1alpha1.LabelWarmPoolPreset] != presetName {
+			continue
+		}
+		_, matchInstanceType := lo.Find(m.Spec.Requirements, func(requirement v1.NodeSelectorRequirement) bool {
+			return requirement.Operator == v1.NodeSelectorOpIn && lo.Contains(requirement.Values, instanceType)
+		})
+		if matchInstanceType {
+			pool = append(pool, m)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/cloudprovider"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestClaimFromWarmPool(t *testing.T) {
+	testcases := map[string]struct {
+		callMocks     func(c *utils.MockClient)
+		expectedNode  string
+		expectedClaim bool
+	}{
+		"Claim an idle node from a non-empty pool": {
+			callMocks: func(c *utils.MockClient) {
+				idleMachine := utils.MockMachine.DeepCopy()
+				idleMachine.Labels = map[string]string{kaitov1alpha1.LabelWarmPoolPreset: "test-model"}
+				idleMachine.Status.NodeName = "node1"
+				idleMachine.Status.Conditions = apis.Conditions{
+					{
+						Type:   apis.ConditionReady,
+						Status: corev1.ConditionTrue,
+					},
+				}
+				machineMap := c.CreateMapWithType(&v1alpha5.MachineList{})
+				machineMap[client.ObjectKeyFromObject(idleMachine)] = idleMachine
+				c.CreateOrUpdateObjectInMap(&utils.MockNodeList.Items[0])
+
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+				c.On("Update", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+				c.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			},
+			expectedNode:  "node1",
+			expectedClaim: true,
+		},
+		"Fall back to provisioning when the pool is empty": {
+			callMocks: func(c *utils.MockClient) {
+				c.CreateMapWithType(&v1alpha5.MachineList{})
+				c.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			},
+			expectedClaim: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			tc.callMocks(mockClient)
+
+			node, err := ClaimFromWarmPool(context.Background(), utils.MockWorkspaceWithPreset, mockClient)
+			assert.Check(t, err == nil, "Not expected to return error")
+			assert.Equal(t, node != nil, tc.expectedClaim)
+			if tc.expectedClaim {
+				assert.Equal(t, node.Name, tc.expectedNode)
+				mockClient.AssertCalled(t, "Update", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Update", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything)
+			}
+		})
+	}
+}
+
+func TestProvisionWarmPool(t *testing.T) {
+	t.Run("Should only create the missing idle machines", func(t *testing.T) {
+		mockClient := utils.NewClient()
+		idleMachine := utils.MockMachine.DeepCopy()
+		idleMachine.Labels = map[string]string{kaitov1alpha1.LabelWarmPoolPreset: "test-model"}
+		machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+		machineMap[client.ObjectKeyFromObject(idleMachine)] = idleMachine
+
+		mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+		mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+		mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+
+		err := ProvisionWarmPool(context.Background(), "test-model", "Standard_NC12s_v3", "kaito", "0", 3, mockClient, cloudprovider.Default)
+		assert.Check(t, err == nil, "Not expected to return error")
+		mockClient.AssertNumberOfCalls(t, "Create", 2)
+	})
+}
+
+func TestParseWarmPools(t *testing.T) {
+	testcases := map[string]struct {
+		value         string
+		expectedPools []WarmPool
+		expectedError bool
+	}{
+		"Empty value": {
+			value: "",
+		},
+		"Pools of several presets": {
+			value: "falcon-7b:Standard_NC12s_v3=2, mistral-7b:Standard_NC24ads_A100_v4=1",
+			expectedPools: []WarmPool{
+				{PresetName: "falcon-7b", InstanceType: "Standard_NC12s_v3", Count: 2},
+				{PresetName: "mistral-7b", InstanceType: "Standard_NC24ads_A100_v4", Count: 1},
+			},
+		},
+		"Pool without an instance type": {
+			value:         "falcon-7b=2",
+			expectedError: true,
+		},
+		"Negative count": {
+			value:         "falcon-7b:Standard_NC12s_v3=-1",
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			pools, err := ParseWarmPools(tc.value)
+			assert.Equal(t, err != nil, tc.expectedError)
+			if !tc.expectedError {
+				assert.DeepEqual(t, pools, tc.expectedPools)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// zoneSkewThreshold is the maximum difference allowed between the number of ready machines
+	// in the most and the least populated zones of a workspace.
+	zoneSkewThreshold = 1
+)
+
+// zoneSpreadTimeout is how long the replacement machine of a zone spread may take to become ready before it is
+// removed again, the replaced machine is kept in that case.
+var zoneSpreadTimeout = machineStatusTimeoutInterval
+
+// EnsureZoneSpread checks the zone distribution of the ready machines of the workspace. If it is skewed beyond
+// zoneSkewThreshold, a replacement machine is provisioned in the least populated zone, annotated with the machine of
+// the most populated zone that it replaces. It never waits for the replacement: the workspace is reconciled again when
+// the replacement changes, and the replaced machine is drained and deleted once the replacement is ready. At most one
+// machine is moved at a time.
+func EnsureZoneSpread(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) error {
+	machines, err := ListMachinesByWorkspace(ctx, workspaceObj, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	// A move in progress is finished before the distribution is checked again.
+	for i := range machines.Items {
+		replacement := &machines.Items[i]
+		replacedName, found := replacement.Annotations[kaitov1alpha1.AnnotationZoneSpreadReplaces]
+		if !found || replacement.DeletionTimestamp != nil {
+			continue
+		}
+		replaced, found := lo.Find(machines.Items, func(m v1alpha5.Machine) bool {
+			return m.Name == replacedName && m.DeletionTimestamp == nil
+		})
+		if found {
+			return finishZoneSpread(ctx, workspaceObj, replacement, &replaced, kubeClient)
+		}
+	}
+
+	// The zones are discovered from the zone labels of the nodes the workspace may run on.
+	var matchLabels client.MatchingLabels
+	if workspaceObj.Resource.LabelSelector != nil {
+		matchLabels = workspaceObj.Resource.LabelSelector.MatchLabels
+	}
+	nodeList, err := resources.ListNodes(ctx, kubeClient, matchLabels)
+	if err != nil {
+		return err
+	}
+	nodeZones := map[string]string{}
+	machinesByZone := map[string][]*v1alpha5.Machine{}
+	for i := range nodeList.Items {
+		if zone, found := nodeList.Items[i].Labels[v1.LabelTopologyZone]; found {
+			nodeZones[nodeList.Items[i].Name] = zone
+			machinesByZone[zone] = nil
+		}
+	}
+	if len(machinesByZone) < 2 {
+		return nil
+	}
+
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		zone, found := nodeZones[m.Status.NodeName]
+		if m.DeletionTimestamp != nil || !isMachineReady(m) || !found {
+			continue
+		}
+		machinesByZone[zone] = append(machinesByZone[zone], m)
+	}
+
+	// Sort the zones by their number of machines, the zone name breaks the ties.
+	zones := lo.Keys(machinesByZone)
+	sort.Slice(zones, func(i, j int) bool {
+		if len(machinesByZone[zones[i]]) != len(machinesByZone[zones[j]]) {
+			return len(machinesByZone[zones[i]]) < len(machinesByZone[zones[j]])
+		}
+		return zones[i] < zones[j]
+	})
+	leastZone, mostZone := zones[0], zones[len(zones)-1]
+	if len(machinesByZone[mostZone])-len(machinesByZone[leastZone]) <= zoneSkewThreshold {
+		return nil
+	}
+
+	concentrated := machinesByZone[mostZone][0]
+	klog.InfoS("EnsureZoneSpread", "workspace", klog.KObj(workspaceObj), "machine", klog.KObj(concentrated),
+		"fromZone", mostZone, "toZone", leastZone)
+
+	replacement := &v1alpha5.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GenerateMachineName(workspaceObj, FreeMachineIndices(workspaceObj, machines.Items, 1)[0]),
+			Namespace: concentrated.Namespace,
+			Labels:    lo.Assign(map[string]string{}, concentrated.Labels),
+			Annotations: lo.Assign(map[string]string{}, concentrated.Annotations, map[string]string{
+				kaitov1alpha1.AnnotationZoneSpreadReplaces: concentrated.Name,
+			}),
+		},
+		Spec: *concentrated.Spec.DeepCopy(),
+	}
+	replacement.Spec.MachineTemplateRef = &v1alpha5.MachineTemplateRef{
+		Name: replacement.Name,
+	}
+	replacement.Spec.Requirements = append(lo.Filter(replacement.Spec.Requirements, func(requirement v1.NodeSelectorRequirement, _ int) bool {
+		return requirement.Key != v1.LabelTopologyZone
+	}), v1.NodeSelectorRequirement{
+		Key:      v1.LabelTopologyZone,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{leastZone},
+	})
+	return CreateMachine(ctx, replacement, kubeClient)
+}
+
+// finishZoneSpread drains the node of the replaced machine and deletes the machine once its replacement is ready.
+// A replacement that is not ready within zoneSpreadTimeout is deleted instead, the replaced machine is kept.
+func finishZoneSpread(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, replacement, replaced *v1alpha5.Machine,
+	kubeClient client.Client) error {
+	if !isMachineReady(replacement) {
+		if time.Since(replacement.CreationTimestamp.Time) < zoneSpreadTimeout {
+			klog.InfoS("waiting for the zone spread replacement machine to be ready", "workspace", klog.KObj(workspaceObj),
+				"machine", klog.KObj(replacement))
+			return nil
+		}
+		klog.InfoS("the zone spread replacement machine is not ready in time, deleting it", "workspace", klog.KObj(workspaceObj),
+			"machine", klog.KObj(replacement), "timeout", zoneSpreadTimeout)
+		return client.IgnoreNotFound(DeleteMachine(ctx, replacement, kubeClient))
+	}
+
+	// The pods are rescheduled on the replacement before the node of the replaced machine is removed.
+	if nodeName := replaced.Status.NodeName; nodeName != "" {
+		if err := resources.CordonNode(ctx, nodeName, kubeClient); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		if err := drainWorkspacePods(ctx, nodeName, client.MatchingLabels{kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name}, kubeClient); err != nil {
+			return err
+		}
+	}
+	if err := DeleteMachine(ctx, replaced, kubeClient); client.IgnoreNotFound(err) != nil {
+		klog.ErrorS(err, "failed to delete the machine", "machine", klog.KObj(replaced))
+		return err
+	}
+	return nil
+}
+
+// isMachineReady returns true if the machine reports the Ready condition.
+func isMachineReady(machineObj *v1alpha5.Machine) bool {
+	return lo.ContainsBy(machineObj.GetConditions(), func(condition apis.Condition) bool {
+		return condition.Type == apis.ConditionReady && condition.Status == v1.ConditionTrue
+	})
+}
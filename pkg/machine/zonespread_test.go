@@ -0,0 +1,169 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestEnsureZoneSpread(t *testing.T) {
+	readyConditions := apis.Conditions{
+		{
+			Type:   apis.ConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}
+
+	testcases := map[string]struct {
+		machineZones map[string]string
+		// replacement is the state of a replacement machine of machine-node1 that is in progress, if any.
+		replacement       *v1alpha5.Machine
+		expectedRebalance bool
+		expectedDeleted   string
+	}{
+		"Skewed distribution provisions a replacement without waiting for it": {
+			machineZones: map[string]string{
+				"node1": "eastus-1",
+				"node2": "eastus-1",
+			},
+			expectedRebalance: true,
+		},
+		"Balanced distribution is a no-op": {
+			machineZones: map[string]string{
+				"node1": "eastus-1",
+				"node2": "eastus-2",
+			},
+		},
+		"Pending replacement is waited for in a later reconcile": {
+			machineZones: map[string]string{
+				"node1": "eastus-1",
+				"node2": "eastus-1",
+			},
+			replacement: &v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+			},
+		},
+		"Ready replacement drains and deletes the replaced machine": {
+			machineZones: map[string]string{
+				"node1": "eastus-1",
+				"node2": "eastus-1",
+			},
+			replacement: &v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+				Status:     v1alpha5.MachineStatus{NodeName: "node3", Conditions: readyConditions},
+			},
+			expectedDeleted: "machine-node1",
+		},
+		"Replacement that is not ready in time is deleted": {
+			machineZones: map[string]string{
+				"node1": "eastus-1",
+				"node2": "eastus-1",
+			},
+			replacement: &v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * zoneSpreadTimeout))},
+			},
+			expectedDeleted: "machine-replacement",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+
+			nodeMap := mockClient.CreateMapWithType(&corev1.NodeList{})
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			podMap := mockClient.CreateMapWithType(&corev1.PodList{})
+			// The workspace may run in two zones, node3 is not used by the workspace.
+			zones := map[string]string{"node3": "eastus-2"}
+			for nodeName, zone := range tc.machineZones {
+				zones[nodeName] = zone
+			}
+			for nodeName, zone := range zones {
+				node := &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   nodeName,
+						Labels: map[string]string{corev1.LabelTopologyZone: zone},
+					},
+				}
+				nodeMap[client.ObjectKeyFromObject(node)] = node
+			}
+			for nodeName := range tc.machineZones {
+				m := utils.MockMachine.DeepCopy()
+				m.Name = "machine-" + nodeName
+				m.Status.NodeName = nodeName
+				m.Status.Conditions = readyConditions
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			if tc.replacement != nil {
+				m := utils.MockMachine.DeepCopy()
+				m.Name = "machine-replacement"
+				m.Annotations = map[string]string{kaitov1alpha1.AnnotationZoneSpreadReplaces: "machine-node1"}
+				m.CreationTimestamp = tc.replacement.CreationTimestamp
+				m.Status = tc.replacement.Status
+				machineMap[client.ObjectKeyFromObject(m)] = m
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "inference-pod",
+					Namespace: "kaito",
+					Labels:    map[string]string{kaitov1alpha1.LabelWorkspaceName: utils.MockWorkspaceWithPreset.Name},
+				},
+				Spec: corev1.PodSpec{NodeName: "node1"},
+			}
+			podMap[client.ObjectKeyFromObject(pod)] = pod
+
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.NodeList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&corev1.PodList{}), mock.Anything, mock.Anything).Return(nil)
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Update", mock.IsType(context.Background()), mock.IsType(&corev1.Node{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+
+			err := EnsureZoneSpread(context.Background(), utils.MockWorkspaceWithPreset, mockClient)
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			if tc.expectedRebalance {
+				mockClient.AssertCalled(t, "Create", mock.IsType(context.Background()), mock.MatchedBy(func(m *v1alpha5.Machine) bool {
+					replaced := m.Annotations[kaitov1alpha1.AnnotationZoneSpreadReplaces]
+					if replaced != "machine-node1" && replaced != "machine-node2" {
+						return false
+					}
+					for _, requirement := range m.Spec.Requirements {
+						if requirement.Key == corev1.LabelTopologyZone {
+							return requirement.Values[0] == "eastus-2"
+						}
+					}
+					return false
+				}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tc.expectedDeleted == "" {
+				mockClient.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			mockClient.AssertCalled(t, "Delete", mock.IsType(context.Background()), mock.MatchedBy(func(m *v1alpha5.Machine) bool {
+				return m.Name == tc.expectedDeleted
+			}), mock.Anything)
+			// The node of the replaced machine is drained before the machine is deleted.
+			if tc.expectedDeleted == "machine-node1" {
+				mockClient.AssertCalled(t, "Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package metrics
+
+import (
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "kaito"
+	metricsSubsystem = "workspace"
+
+	labelWorkspace = "workspace"
+	labelNamespace = "namespace"
+)
+
+var (
+	// WorkspaceNodesDesired is the number of nodes requested by a workspace.
+	WorkspaceNodesDesired = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "nodes_desired",
+		Help:      "Number of nodes requested by the workspace.",
+	}, []string{labelWorkspace, labelNamespace})
+
+	// WorkspaceNodesReady is the number of ready nodes selected for a workspace.
+	WorkspaceNodesReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "nodes_ready",
+		Help:      "Number of ready nodes selected for the workspace.",
+	}, []string{labelWorkspace, labelNamespace})
+
+	// WorkspaceProvisioning is 1 while new nodes are being provisioned for a workspace, 0 otherwise.
+	WorkspaceProvisioning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "provisioning",
+		Help:      "Whether new nodes are being provisioned for the workspace.",
+	}, []string{labelWorkspace, labelNamespace})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(WorkspaceNodesDesired, WorkspaceNodesReady, WorkspaceProvisioning)
+}
+
+func workspaceLabels(workspaceObj *kaitov1alpha1.Workspace) prometheus.Labels {
+	return prometheus.Labels{
+		labelWorkspace: workspaceObj.Name,
+		labelNamespace: workspaceObj.Namespace,
+	}
+}
+
+// UpdateWorkspaceNodes records the desired and ready node counts of the workspace and whether nodes are being provisioned.
+func UpdateWorkspaceNodes(workspaceObj *kaitov1alpha1.Workspace, desired, ready int, provisioning bool) {
+	labels := workspaceLabels(workspaceObj)
+	WorkspaceNodesDesired.With(labels).Set(float64(desired))
+	WorkspaceNodesReady.With(labels).Set(float64(ready))
+	if provisioning {
+		WorkspaceProvisioning.With(labels).Set(1)
+	} else {
+		WorkspaceProvisioning.With(labels).Set(0)
+	}
+}
+
+// DeleteWorkspaceMetrics removes the series of a deleted workspace.
+func DeleteWorkspaceMetrics(workspaceObj *kaitov1alpha1.Workspace) {
+	labels := workspaceLabels(workspaceObj)
+	WorkspaceNodesDesired.Delete(labels)
+	WorkspaceNodesReady.Delete(labels)
+	WorkspaceProvisioning.Delete(labels)
+}
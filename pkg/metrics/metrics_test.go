@@ -0,0 +1,37 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package metrics
+
+import (
+	"testing"
+
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func TestUpdateWorkspaceNodes(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset
+	labels := workspaceLabels(workspace)
+
+	UpdateWorkspaceNodes(workspace, 3, 1, true)
+	assert.Equal(t, testutil.ToFloat64(WorkspaceNodesDesired.With(labels)), float64(3))
+	assert.Equal(t, testutil.ToFloat64(WorkspaceNodesReady.With(labels)), float64(1))
+	assert.Equal(t, testutil.ToFloat64(WorkspaceProvisioning.With(labels)), float64(1))
+
+	UpdateWorkspaceNodes(workspace, 3, 3, false)
+	assert.Equal(t, testutil.ToFloat64(WorkspaceNodesReady.With(labels)), float64(3))
+	assert.Equal(t, testutil.ToFloat64(WorkspaceProvisioning.With(labels)), float64(0))
+}
+
+func TestDeleteWorkspaceMetrics(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset
+
+	UpdateWorkspaceNodes(workspace, 1, 1, false)
+	assert.Equal(t, testutil.CollectAndCount(WorkspaceNodesDesired), 1)
+
+	DeleteWorkspaceMetrics(workspace)
+	assert.Equal(t, testutil.CollectAndCount(WorkspaceNodesDesired), 0)
+	assert.Equal(t, testutil.CollectAndCount(WorkspaceNodesReady), 0)
+	assert.Equal(t, testutil.CollectAndCount(WorkspaceProvisioning), 0)
+}
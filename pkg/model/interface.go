@@ -30,7 +30,84 @@ type PresetParam struct {
 	// This timeout accommodates the size of the image, ensuring pull completion
 	// even under slower network conditions or unforeseen delays.
 	ReadinessTimeout time.Duration
+	// StartupTimeout is the time the model server is given to load the model before the kubelet restarts it.
+	// Larger models need longer to load. A default timeout is used if not specified.
+	StartupTimeout time.Duration
+	// TerminationGracePeriod is the time the model server is given to unload the model when the pod is terminated,
+	// before it is killed. The default grace period of Kubernetes is used if not specified.
+	TerminationGracePeriod time.Duration
+	// LivenessConfig configures the liveness probe that restarts a model server that stopped serving without
+	// crashing, e.g., after a deadlock. The default liveness probe is used if not specified.
+	LivenessConfig *LivenessConfig
+	// SupportsDrain is true if the model server exposes the /drain endpoint, which stops accepting new requests
+	// and waits for the in-flight requests to finish.
+	SupportsDrain bool
+	// DrainGracePeriod is the time the in-flight requests are given to finish before the pod is terminated.
+	// A default grace period is used if not specified.
+	DrainGracePeriod time.Duration
 	// WorldSize defines the number of processes required for distributed inference.
 	WorldSize int
 	Tag       string // The model image tag
+	// DefaultNodeCount is the node count of the workspaces that run the model and do not specify one, e.g., the
+	// nodes a distributed model is sharded across. MinNodeCount, or one node, is used if not specified.
+	DefaultNodeCount int
+	// MinNodeCount is the minimum node count of the workspaces that run the model, the model does not fit onto fewer
+	// nodes. Any count is allowed if not specified.
+	MinNodeCount int
+	// MinHostMemory is the host memory (e.g., "64Gi") the nodes need besides the GPU memory, e.g., to convert the
+	// model weights before they are loaded onto the GPUs. Any host memory is accepted if not specified.
+	MinHostMemory string
+	// MinDriverVersion is the minimum NVIDIA driver version (e.g., "535.104.05") required by the model image.
+	// An empty value means any driver version is accepted.
+	MinDriverVersion string
+	// MetricsPort is the port the model server exposes Prometheus metrics on, e.g., the in-flight requests and the GPU
+	// utilization, at MetricsPath. The metrics are not scraped if not specified.
+	MetricsPort int32
+	// WeightsPath is the directory of the model weights in the model image. The default path of the preset images is
+	// used if not specified.
+	WeightsPath string
+	// Embedding is true if the model server of the preset embeds texts rather than generating them, i.e., it answers
+	// POST /embed {"text": "..."} with {"embedding": [...]}. Only these presets can be the embedding model of RAG.
+	Embedding bool
+	// Checksum is the hex encoded sha256 checksum of the model weights in WeightsPath, i.e., the output of
+	// `find . -type f | LC_ALL=C sort | xargs -d '\n' sha256sum | sha256sum` in the directory. The weights are not
+	// verified if not specified, unless the workspace specifies a checksum.
+	Checksum string
+	// Runtimes are the inference runtimes other than transformers that the model can be served with, mapped to the
+	// additional arguments of their model servers, e.g., {"vllm": {"dtype": "float16"}}. The image of a runtime
+	// is the model image whose tag carries the runtime as a suffix, e.g., 0.0.4-vllm, only the runtimes whose images
+	// are published may be listed. No runtime other than transformers is supported if not specified.
+	Runtimes map[string]map[string]string
+	// SmokeTest is the canned inference request that verifies the model server answers once the workload is ready.
+	// The request of the kaito inference server of the transformers runtime is used if not specified.
+	SmokeTest *SmokeTest
+	// AllowedRegions are the regions the model is licensed or available in, e.g., ["eastus", "westeurope"]. The nodes
+	// of the workspaces that run the model are only provisioned in these regions. Any region is allowed if not specified.
+	AllowedRegions []string
+}
+
+// LivenessConfig configures the liveness probe of the model server. The probe only starts once the startup probe has
+// succeeded, i.e., once the model has been loaded. The unset fields take the values of the default liveness probe.
+type LivenessConfig struct {
+	// Path is the HTTP path of the health endpoint of the model server.
+	Path string
+	// InitialDelay delays the first probe after the model has been loaded, e.g., to let the server warm up.
+	InitialDelay time.Duration
+	// Period is the interval between two probes.
+	Period time.Duration
+	// Timeout is the time a probe waits for the response. A busy server may answer slowly.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed probes after which the server is restarted.
+	FailureThreshold int32
+}
+
+// SmokeTest is a canned inference request of the model server. The response is valid if its status is 2xx and its
+// JSON body has a non-empty ResultField.
+type SmokeTest struct {
+	// Path is the HTTP path the request is posted to, e.g., /chat.
+	Path string
+	// Body is the JSON body of the request. It should only ask for a few tokens to be generated.
+	Body string
+	// ResultField is the field of the JSON response that holds the generated text.
+	ResultField string
 }
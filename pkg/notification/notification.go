@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+)
+
+// ProvisioningFailure is the payload reported when the nodes of a workspace cannot be provisioned.
+type ProvisioningFailure struct {
+	Workspace string `json:"workspace"`
+	Namespace string `json:"namespace"`
+	SKU       string `json:"sku"`
+	Reason    string `json:"reason"`
+}
+
+// NotificationSink is where kaito reports failures that need an operator's attention.
+type NotificationSink interface {
+	NotifyProvisioningFailure(ctx context.Context, failure ProvisioningFailure) error
+}
+
+// WebhookSink posts the failures as JSON to a webhook URL.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+var _ NotificationSink = &WebhookSink{}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL:    url,
+		Client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (w *WebhookSink) NotifyProvisioningFailure(ctx context.Context, failure ProvisioningFailure) error {
+	klog.InfoS("NotifyProvisioningFailure", "workspace", failure.Workspace, "namespace", failure.Namespace, "sku", failure.SKU)
+	body, err := json.Marshal(failure)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook %s returned status code %d", w.URL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestWebhookSinkNotifyProvisioningFailure(t *testing.T) {
+	failure := ProvisioningFailure{
+		Workspace: "testWorkspace",
+		Namespace: "kaito",
+		SKU:       "Standard_NC12s_v3",
+		Reason:    "all requested instance types were unavailable during launch",
+	}
+
+	testcases := map[string]struct {
+		statusCode    int
+		expectedError bool
+	}{
+		"Webhook accepts the failure": {
+			statusCode:    http.StatusOK,
+			expectedError: false,
+		},
+		"Webhook rejects the failure": {
+			statusCode:    http.StatusInternalServerError,
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			var received map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, r.Method, http.MethodPost)
+				assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
+				assert.NilError(t, json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(tc.statusCode)
+			}))
+			defer server.Close()
+
+			err := NewWebhookSink(server.URL).NotifyProvisioningFailure(context.Background(), failure)
+			assert.Equal(t, err != nil, tc.expectedError)
+			assert.DeepEqual(t, received, map[string]string{
+				"workspace": "testWorkspace",
+				"namespace": "kaito",
+				"sku":       "Standard_NC12s_v3",
+				"reason":    "all requested instance types were unavailable during launch",
+			})
+		})
+	}
+}
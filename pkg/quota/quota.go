@@ -0,0 +1,66 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/samber/lo"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckGPUQuota returns an error if the GPUs requested by the workspace, together with the GPUs requested by the
+// other workspaces of the same team, exceed the GPU quota of the team. The team of a workspace is its namespace,
+// narrowed by the team label if the workspace has one. No quota object means no limit.
+func CheckGPUQuota(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) error {
+	listOpts := []client.ListOption{client.InNamespace(workspaceObj.Namespace)}
+	team, hasTeam := workspaceObj.Labels[kaitov1alpha1.LabelTeam]
+	if hasTeam {
+		listOpts = append(listOpts, client.MatchingLabels{kaitov1alpha1.LabelTeam: team})
+	}
+
+	quotaList := &kaitov1alpha1.GPUQuotaList{}
+	if err := kubeClient.List(ctx, quotaList, listOpts...); err != nil {
+		klog.ErrorS(err, "failed to list GPU quotas", "namespace", workspaceObj.Namespace)
+		return err
+	}
+	if len(quotaList.Items) == 0 {
+		return nil
+	}
+	// The most restrictive quota applies if several of them match.
+	maxGPUs := lo.MinBy(quotaList.Items, func(a, b kaitov1alpha1.GPUQuota) bool {
+		return a.Spec.MaxGPUs < b.Spec.MaxGPUs
+	}).Spec.MaxGPUs
+
+	workspaceList := &kaitov1alpha1.WorkspaceList{}
+	if err := kubeClient.List(ctx, workspaceList, listOpts...); err != nil {
+		klog.ErrorS(err, "failed to list workspaces", "namespace", workspaceObj.Namespace)
+		return err
+	}
+	consumedGPUs := 0
+	for i := range workspaceList.Items {
+		ws := &workspaceList.Items[i]
+		if ws.Name == workspaceObj.Name || !ws.DeletionTimestamp.IsZero() {
+			continue
+		}
+		consumedGPUs += requestedGPUs(ws)
+	}
+
+	if requested := requestedGPUs(workspaceObj); consumedGPUs+requested > maxGPUs {
+		return fmt.Errorf("workspace %s/%s requests %d GPUs, which exceeds the GPU quota of %d with %d GPUs already in use",
+			workspaceObj.Namespace, workspaceObj.Name, requested, maxGPUs, consumedGPUs)
+	}
+	return nil
+}
+
+// requestedGPUs returns the total number of GPUs of the machines requested by the workspace.
+func requestedGPUs(workspaceObj *kaitov1alpha1.Workspace) int {
+	gpuConfig, found := kaitov1alpha1.SupportedGPUConfigs[workspaceObj.Resource.InstanceType]
+	if !found {
+		return 0
+	}
+	return lo.FromPtr(workspaceObj.Resource.Count) * gpuConfig.GPUCount
+}
@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package quota
+
+import (
+	"context"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCheckGPUQuota(t *testing.T) {
+	testcases := map[string]struct {
+		maxGPUs       int
+		expectedError string
+	}{
+		"Workspace under quota is accepted": {
+			maxGPUs: 5,
+		},
+		"Workspace at quota is accepted": {
+			maxGPUs: 4,
+		},
+		"Workspace over quota is rejected": {
+			maxGPUs:       3,
+			expectedError: "workspace kaito/testWorkspace requests 2 GPUs, which exceeds the GPU quota of 3 with 2 GPUs already in use",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+
+			// Both workspaces request one Standard_NC12s_v3 machine with 2 GPUs.
+			// The workspace itself is not counted as consumed.
+			otherWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			otherWorkspace.Name = "otherWorkspace"
+			workspaceMap := mockClient.CreateMapWithType(&kaitov1alpha1.WorkspaceList{})
+			workspaceMap[client.ObjectKeyFromObject(otherWorkspace)] = otherWorkspace
+			workspaceMap[client.ObjectKeyFromObject(utils.MockWorkspaceWithPreset)] = utils.MockWorkspaceWithPreset.DeepCopy()
+
+			quotaObj := &kaitov1alpha1.GPUQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-quota", Namespace: "kaito"},
+				Spec:       kaitov1alpha1.GPUQuotaSpec{MaxGPUs: tc.maxGPUs},
+			}
+			quotaMap := mockClient.CreateMapWithType(&kaitov1alpha1.GPUQuotaList{})
+			quotaMap[client.ObjectKeyFromObject(quotaObj)] = quotaObj
+
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&kaitov1alpha1.GPUQuotaList{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&kaitov1alpha1.WorkspaceList{}), mock.Anything).Return(nil)
+
+			err := CheckGPUQuota(context.Background(), utils.MockWorkspaceWithPreset, mockClient)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Equal(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
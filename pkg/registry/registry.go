@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	requestTimeout = 30 * time.Second
+	// dockerHub is the registry of the images that do not name one.
+	dockerHub = "docker.io"
+)
+
+// manifestMediaTypes are the manifests the registry may serve for an image, the multi-arch indexes included.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Client checks the images in a container registry.
+type Client interface {
+	// ManifestExists returns true if the registry serves the manifest of the image.
+	ManifestExists(ctx context.Context, image string) (bool, error)
+}
+
+// MirrorClient checks the images in the mirror registry of an air-gapped cluster, which the container runtime pulls
+// the images from instead of their registries. An image is looked up by its repository in the mirror, regardless of
+// the registry it names.
+type MirrorClient struct {
+	// Registry is the host of the mirror, e.g., registry.internal:5000. HTTPS is used unless it starts with http://.
+	Registry string
+	Client   *http.Client
+}
+
+var _ Client = &MirrorClient{}
+
+func NewMirrorClient(registry string) *MirrorClient {
+	return &MirrorClient{
+		Registry: registry,
+		Client:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (m *MirrorClient) ManifestExists(ctx context.Context, image string) (bool, error) {
+	_, repository, reference, err := parseImage(image)
+	if err != nil {
+		return false, err
+	}
+	baseURL := strings.TrimSuffix(m.Registry, "/")
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "https://" + baseURL
+	}
+
+	// A HEAD request checks the manifest without downloading it.
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL, repository, reference), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		klog.InfoS("The image is missing in the mirror registry", "image", image, "registry", m.Registry)
+		return false, nil
+	default:
+		return false, fmt.Errorf("mirror registry %s returned status code %d for image %s", m.Registry, resp.StatusCode, image)
+	}
+}
+
+// parseImage splits the image into its registry, its repository and its tag or digest, which defaults to latest.
+// The images without a registry are normalized like docker does, e.g., nginx is docker.io/library/nginx:latest.
+func parseImage(image string) (registry, repository, reference string, err error) {
+	name, reference := image, "latest"
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, reference = name[:i], name[i+1:]
+	}
+
+	registry = dockerHub
+	if i := strings.Index(name, "/"); i >= 0 && (strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		registry, name = name[:i], name[i+1:]
+	} else if i < 0 {
+		name = "library/" + name
+	}
+	if name == "" || reference == "" || strings.HasPrefix(name, "/") {
+		return "", "", "", fmt.Errorf("invalid image %q", image)
+	}
+	return registry, name, reference, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestMirrorClientManifestExists(t *testing.T) {
+	testcases := map[string]struct {
+		image          string
+		statusCode     int
+		expectedPath   string
+		expectedExists bool
+		expectedError  bool
+	}{
+		"Image is present in the mirror": {
+			image:          "mcr.microsoft.com/aks/kaito/kaito-falcon-7b:0.0.4",
+			statusCode:     http.StatusOK,
+			expectedPath:   "/v2/aks/kaito/kaito-falcon-7b/manifests/0.0.4",
+			expectedExists: true,
+		},
+		"Image is missing in the mirror": {
+			image:        "mcr.microsoft.com/aks/kaito/kaito-falcon-7b:0.0.4-vllm",
+			statusCode:   http.StatusNotFound,
+			expectedPath: "/v2/aks/kaito/kaito-falcon-7b/manifests/0.0.4-vllm",
+		},
+		"Image of docker hub is looked up by its normalized repository": {
+			image:          "nginx",
+			statusCode:     http.StatusOK,
+			expectedPath:   "/v2/library/nginx/manifests/latest",
+			expectedExists: true,
+		},
+		"Image is looked up by its digest": {
+			image:          "localhost:5000/oras@sha256:abc",
+			statusCode:     http.StatusOK,
+			expectedPath:   "/v2/oras/manifests/sha256:abc",
+			expectedExists: true,
+		},
+		"Mirror requires credentials": {
+			image:         "mcr.microsoft.com/azure-cli:2.55.0",
+			statusCode:    http.StatusUnauthorized,
+			expectedPath:  "/v2/azure-cli/manifests/2.55.0",
+			expectedError: true,
+		},
+		"Image is invalid": {
+			image:         "/kaito-falcon-7b:0.0.4",
+			expectedError: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, r.Method, http.MethodHead)
+				path = r.URL.Path
+				w.WriteHeader(tc.statusCode)
+			}))
+			defer server.Close()
+
+			exists, err := NewMirrorClient(server.URL).ManifestExists(context.Background(), tc.image)
+			assert.Equal(t, path, tc.expectedPath)
+			assert.Equal(t, exists, tc.expectedExists)
+			assert.Equal(t, err != nil, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package resources
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	GPUHealthCheckImage = "nvcr.io/nvidia/cuda:12.2.0-base-ubuntu22.04"
+	// SystemNamespaceEnv is the environment variable holding the namespace kaito runs in.
+	SystemNamespaceEnv = "SYSTEM_NAMESPACE"
+)
+
+var (
+	// gpuHealthCheckTimeout is the time to wait for the GPU health check pod to complete.
+	gpuHealthCheckTimeout = 5 * time.Minute
+)
+
+// RunGPUHealthCheck runs nvidia-smi in a pod on the node and reports whether all the GPUs of the node are healthy.
+// The pod does not request GPU resources, so the check does not wait for the nvidia device plugin.
+func RunGPUHealthCheck(ctx context.Context, nodeName string, kubeClient client.Client) (bool, error) {
+	namespace := os.Getenv(SystemNamespaceEnv)
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("gpu-health-check-%s", nodeName),
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "gpu-health-check",
+					Image:   GPUHealthCheckImage,
+					Command: []string{"nvidia-smi"},
+					Env: []corev1.EnvVar{
+						{
+							Name:  "NVIDIA_VISIBLE_DEVICES",
+							Value: "all",
+						},
+					},
+				},
+			},
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				},
+			},
+		},
+	}
+
+	klog.InfoS("RunGPUHealthCheck", "node", nodeName, "pod", klog.KObj(pod))
+	if err := kubeClient.Create(ctx, pod, &client.CreateOptions{}); client.IgnoreAlreadyExists(err) != nil {
+		return false, err
+	}
+	defer func() {
+		if err := kubeClient.Delete(ctx, pod, &client.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			klog.ErrorS(err, "failed to delete the GPU health check pod", "pod", klog.KObj(pod))
+		}
+	}()
+
+	timeClock := clock.RealClock{}
+	tick := timeClock.NewTicker(gpuHealthCheckTimeout)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+
+		case <-tick.C():
+			return false, fmt.Errorf("GPU health check timed out on node %s", nodeName)
+
+		default:
+			time.Sleep(1 * time.Second)
+			if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(pod), pod, &client.GetOptions{}); err != nil {
+				return false, err
+			}
+
+			switch pod.Status.Phase {
+			case corev1.PodSucceeded:
+				klog.InfoS("GPU health check passed", "node", nodeName)
+				return true, nil
+			case corev1.PodFailed:
+				klog.InfoS("GPU health check failed", "node", nodeName)
+				return false, nil
+			}
+		}
+	}
+}
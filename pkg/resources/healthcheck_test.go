@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package resources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRunGPUHealthCheck(t *testing.T) {
+	testcases := map[string]struct {
+		callMocks       func(c *utils.MockClient)
+		expectedHealthy bool
+		expectedError   error
+	}{
+		"Fail to create the health check pod": {
+			callMocks: func(c *utils.MockClient) {
+				c.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(errors.New("Cannot create pod"))
+			},
+			expectedError: errors.New("Cannot create pod"),
+		},
+		"Health check pod succeeds": {
+			callMocks: func(c *utils.MockClient) {
+				c.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					args.Get(2).(*corev1.Pod).Status.Phase = corev1.PodSucceeded
+				})
+				c.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+			},
+			expectedHealthy: true,
+		},
+		"Health check pod fails": {
+			callMocks: func(c *utils.MockClient) {
+				c.On("Create", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+				c.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					args.Get(2).(*corev1.Pod).Status.Phase = corev1.PodFailed
+				})
+				c.On("Delete", mock.IsType(context.Background()), mock.IsType(&corev1.Pod{}), mock.Anything).Return(nil)
+			},
+			expectedHealthy: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			tc.callMocks(mockClient)
+
+			healthy, err := RunGPUHealthCheck(context.Background(), "mockNode", mockClient)
+			if tc.expectedError == nil {
+				assert.Check(t, err == nil, "Not expected to return error")
+				assert.Equal(t, healthy, tc.expectedHealthy)
+			} else {
+				assert.Equal(t, tc.expectedError.Error(), err.Error())
+			}
+		})
+	}
+}
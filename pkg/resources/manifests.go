@@ -5,18 +5,26 @@ package resources
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
 	"github.com/samber/lo"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var controller = true
 
+// MetricsPortName is the name of the container and service ports of the model server metrics.
+const MetricsPortName = "metrics"
+
 func GenerateHeadlessServiceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace) *corev1.Service {
 	serviceName := fmt.Sprintf("%s-headless", workspaceObj.Name)
 	selector := map[string]string{
@@ -25,8 +33,10 @@ func GenerateHeadlessServiceManifest(ctx context.Context, workspaceObj *kaitov1a
 
 	return &corev1.Service{
 		ObjectMeta: v1.ObjectMeta{
-			Name:      serviceName,
-			Namespace: workspaceObj.Namespace,
+			Name:        serviceName,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, nil),
 			OwnerReferences: []v1.OwnerReference{
 				{
 					APIVersion: kaitov1alpha1.GroupVersion.String(),
@@ -53,7 +63,10 @@ func GenerateHeadlessServiceManifest(ctx context.Context, workspaceObj *kaitov1a
 	}
 }
 
-func GenerateServiceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, serviceType corev1.ServiceType, isStatefulSet bool) *corev1.Service {
+// GenerateServiceManifest generates the service of the inference workload. The metrics port of the model server is
+// exposed if it is not 0.
+func GenerateServiceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, serviceType corev1.ServiceType, isStatefulSet bool,
+	metricsPort int32) *corev1.Service {
 	selector := map[string]string{
 		kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name,
 	}
@@ -63,10 +76,48 @@ func GenerateServiceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Wo
 		selector["statefulset.kubernetes.io/pod-name"] = podNameForIndex0
 	}
 
+	// The annotations configure the load balancer of the cloud provider.
+	var annotations map[string]string
+	if serviceType == corev1.ServiceTypeLoadBalancer && workspaceObj.Inference != nil {
+		annotations = lo.Assign(workspaceObj.Inference.ServiceAnnotations)
+	}
+
+	// The requests are routed through the auth proxy of the inference pods if the endpoint requires a token.
+	targetPort := workspaceObj.Inference.GetPort()
+	if workspaceObj.Inference.Auth != nil {
+		targetPort = kaitov1alpha1.AuthProxyPort
+	}
+	ports := []corev1.ServicePort{
+		// HTTP API Port
+		{
+			Name:       "http",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       80,
+			TargetPort: intstr.FromInt(int(targetPort)),
+		},
+		// Torch NCCL Port
+		{
+			Name:       "torch",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       29500,
+			TargetPort: intstr.FromInt(29500),
+		},
+	}
+	if metricsPort != 0 {
+		ports = append(ports, corev1.ServicePort{
+			Name:       MetricsPortName,
+			Protocol:   corev1.ProtocolTCP,
+			Port:       metricsPort,
+			TargetPort: intstr.FromString(MetricsPortName),
+		})
+	}
+
 	return &corev1.Service{
 		ObjectMeta: v1.ObjectMeta{
-			Name:      workspaceObj.Name,
-			Namespace: workspaceObj.Namespace,
+			Name:        workspaceObj.Name,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, annotations),
 			OwnerReferences: []v1.OwnerReference{
 				{
 					APIVersion: kaitov1alpha1.GroupVersion.String(),
@@ -78,23 +129,8 @@ func GenerateServiceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Wo
 			},
 		},
 		Spec: corev1.ServiceSpec{
-			Type: serviceType,
-			Ports: []corev1.ServicePort{
-				// HTTP API Port
-				{
-					Name:       "http",
-					Protocol:   corev1.ProtocolTCP,
-					Port:       80,
-					TargetPort: intstr.FromInt(5000),
-				},
-				// Torch NCCL Port
-				{
-					Name:       "torch",
-					Protocol:   corev1.ProtocolTCP,
-					Port:       29500,
-					TargetPort: intstr.FromInt(29500),
-				},
-			},
+			Type:     serviceType,
+			Ports:    ports,
 			Selector: selector,
 			// Added this to allow pods to discover each other
 			// (DNS Resolution) During their initialization phase
@@ -103,9 +139,70 @@ func GenerateServiceManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Wo
 	}
 }
 
+// GenerateEgressNetworkPolicyManifest generates the NetworkPolicy that restricts the egress traffic of the inference
+// pods to the allowed CIDRs, the DNS queries and the other pods of the workspace, or returns nil if the workspace
+// has no egress policy. The DNS queries are allowed to any destination, e.g., to a node local DNS cache.
+func GenerateEgressNetworkPolicyManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace) *networkingv1.NetworkPolicy {
+	if workspaceObj.Inference == nil || workspaceObj.Inference.EgressPolicy == nil {
+		return nil
+	}
+	egressPolicy := workspaceObj.Inference.EgressPolicy
+	podSelector := v1.LabelSelector{
+		MatchLabels: map[string]string{
+			kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name,
+		},
+	}
+
+	// The pods of distributed inference connect to each other.
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: []networkingv1.NetworkPolicyPeer{{PodSelector: &podSelector}},
+		},
+	}
+	if egressPolicy.IsDNSAllowed() {
+		dnsPort := intstr.FromInt(53)
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: lo.ToPtr(corev1.ProtocolUDP), Port: &dnsPort},
+				{Protocol: lo.ToPtr(corev1.ProtocolTCP), Port: &dnsPort},
+			},
+		})
+	}
+	if len(egressPolicy.AllowedCIDRs) != 0 {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To: lo.Map(egressPolicy.AllowedCIDRs, func(cidr string, _ int) networkingv1.NetworkPolicyPeer {
+				return networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}
+			}),
+		})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-egress", workspaceObj.Name),
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, nil),
+			OwnerReferences: []v1.OwnerReference{
+				{
+					APIVersion: kaitov1alpha1.GroupVersion.String(),
+					Kind:       "Workspace",
+					UID:        workspaceObj.UID,
+					Name:       workspaceObj.Name,
+					Controller: &controller,
+				},
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: podSelector,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+}
+
 func GenerateStatefulSetManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, imageName string,
 	imagePullSecretRefs []corev1.LocalObjectReference, replicas int, commands []string, containerPorts []corev1.ContainerPort,
-	livenessProbe, readinessProbe *corev1.Probe, resourceRequirements corev1.ResourceRequirements,
+	livenessProbe, readinessProbe, startupProbe *corev1.Probe, resourceRequirements corev1.ResourceRequirements,
 	tolerations []corev1.Toleration, volumes []corev1.Volume, volumeMount []corev1.VolumeMount) *appsv1.StatefulSet {
 
 	nodeRequirements := make([]corev1.NodeSelectorRequirement, 0, len(workspaceObj.Resource.LabelSelector.MatchLabels))
@@ -126,8 +223,10 @@ func GenerateStatefulSetManifest(ctx context.Context, workspaceObj *kaitov1alpha
 
 	ss := &appsv1.StatefulSet{
 		ObjectMeta: v1.ObjectMeta{
-			Name:      workspaceObj.Name,
-			Namespace: workspaceObj.Namespace,
+			Name:        workspaceObj.Name,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, nil),
 			OwnerReferences: []v1.OwnerReference{
 				{
 					APIVersion: kaitov1alpha1.GroupVersion.String(),
@@ -144,32 +243,29 @@ func GenerateStatefulSetManifest(ctx context.Context, workspaceObj *kaitov1alpha
 			Selector:            labelselector,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: v1.ObjectMeta{
-					Labels: selector,
+					Labels:      WithCommonLabels(workspaceObj, selector),
+					Annotations: WithCommonAnnotations(workspaceObj, nil),
 				},
 				Spec: corev1.PodSpec{
-					ImagePullSecrets: imagePullSecretRefs,
-					Affinity: &corev1.Affinity{
-						NodeAffinity: &corev1.NodeAffinity{
-							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-								NodeSelectorTerms: []corev1.NodeSelectorTerm{
-									{
-										MatchExpressions: nodeRequirements,
-									},
-								},
-							},
-						},
-					},
+					ImagePullSecrets:   imagePullSecretRefs,
+					PriorityClassName:  workspaceObj.Resource.PriorityClassName,
+					ServiceAccountName: inferenceServiceAccountName(workspaceObj),
+					Affinity:           inferenceAffinity(workspaceObj, nodeRequirements),
+					DNSPolicy:          workspaceObj.Inference.DNSPolicy,
+					DNSConfig:          workspaceObj.Inference.DNSConfig.DeepCopy(),
 
 					Containers: []corev1.Container{
 						{
-							Name:           workspaceObj.Name,
-							Image:          imageName,
-							Command:        commands,
-							Resources:      resourceRequirements,
-							LivenessProbe:  livenessProbe,
-							ReadinessProbe: readinessProbe,
-							Ports:          containerPorts,
-							VolumeMounts:   volumeMount,
+							Name:            workspaceObj.Name,
+							Image:           imageName,
+							ImagePullPolicy: inferenceImagePullPolicy(workspaceObj, imageName),
+							Command:         commands,
+							Resources:       resourceRequirements,
+							LivenessProbe:   livenessProbe,
+							ReadinessProbe:  readinessProbe,
+							StartupProbe:    startupProbe,
+							Ports:           containerPorts,
+							VolumeMounts:    volumeMount,
 						},
 					},
 					Tolerations: tolerations,
@@ -184,7 +280,7 @@ func GenerateStatefulSetManifest(ctx context.Context, workspaceObj *kaitov1alpha
 
 func GenerateDeploymentManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, imageName string,
 	imagePullSecretRefs []corev1.LocalObjectReference, replicas int, commands []string, containerPorts []corev1.ContainerPort,
-	livenessProbe, readinessProbe *corev1.Probe, resourceRequirements corev1.ResourceRequirements,
+	livenessProbe, readinessProbe, startupProbe *corev1.Probe, resourceRequirements corev1.ResourceRequirements,
 	tolerations []corev1.Toleration, volumes []corev1.Volume, volumeMount []corev1.VolumeMount) *appsv1.Deployment {
 
 	nodeRequirements := make([]corev1.NodeSelectorRequirement, 0, len(workspaceObj.Resource.LabelSelector.MatchLabels))
@@ -205,8 +301,10 @@ func GenerateDeploymentManifest(ctx context.Context, workspaceObj *kaitov1alpha1
 
 	return &appsv1.Deployment{
 		ObjectMeta: v1.ObjectMeta{
-			Name:      workspaceObj.Name,
-			Namespace: workspaceObj.Namespace,
+			Name:        workspaceObj.Name,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, nil),
 			OwnerReferences: []v1.OwnerReference{
 				{
 					APIVersion: kaitov1alpha1.GroupVersion.String(),
@@ -220,33 +318,32 @@ func GenerateDeploymentManifest(ctx context.Context, workspaceObj *kaitov1alpha1
 		Spec: appsv1.DeploymentSpec{
 			Replicas: lo.ToPtr(int32(replicas)),
 			Selector: labelselector,
+			Strategy: inferenceDeploymentStrategy(workspaceObj),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: v1.ObjectMeta{
-					Labels: selector,
+					Labels:      WithCommonLabels(workspaceObj, selector),
+					Annotations: WithCommonAnnotations(workspaceObj, nil),
 				},
 				Spec: corev1.PodSpec{
-					ImagePullSecrets: imagePullSecretRefs,
-					Affinity: &corev1.Affinity{
-						NodeAffinity: &corev1.NodeAffinity{
-							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-								NodeSelectorTerms: []corev1.NodeSelectorTerm{
-									{
-										MatchExpressions: nodeRequirements,
-									},
-								},
-							},
-						},
-					},
+					ImagePullSecrets:          imagePullSecretRefs,
+					PriorityClassName:         workspaceObj.Resource.PriorityClassName,
+					ServiceAccountName:        inferenceServiceAccountName(workspaceObj),
+					Affinity:                  inferenceAffinity(workspaceObj, nodeRequirements),
+					TopologySpreadConstraints: inferenceTopologySpreadConstraints(workspaceObj, replicas, labelselector),
+					DNSPolicy:                 workspaceObj.Inference.DNSPolicy,
+					DNSConfig:                 workspaceObj.Inference.DNSConfig.DeepCopy(),
 					Containers: []corev1.Container{
 						{
-							Name:           workspaceObj.Name,
-							Image:          imageName,
-							Command:        commands,
-							Resources:      resourceRequirements,
-							LivenessProbe:  livenessProbe,
-							ReadinessProbe: readinessProbe,
-							Ports:          containerPorts,
-							VolumeMounts:   volumeMount,
+							Name:            workspaceObj.Name,
+							Image:           imageName,
+							ImagePullPolicy: inferenceImagePullPolicy(workspaceObj, imageName),
+							Command:         commands,
+							Resources:       resourceRequirements,
+							LivenessProbe:   livenessProbe,
+							ReadinessProbe:  readinessProbe,
+							StartupProbe:    startupProbe,
+							Ports:           containerPorts,
+							VolumeMounts:    volumeMount,
 						},
 					},
 					Tolerations: tolerations,
@@ -273,22 +370,41 @@ func GenerateDeploymentManifestWithPodTemplate(ctx context.Context, workspaceObj
 		templateCopy.ObjectMeta.Labels = make(map[string]string)
 	}
 	templateCopy.ObjectMeta.Labels[kaitov1alpha1.LabelWorkspaceName] = workspaceObj.Name
+	// The labels and annotations of the template take precedence over the common ones.
+	templateCopy.ObjectMeta.Labels = WithCommonLabels(workspaceObj, templateCopy.ObjectMeta.Labels)
+	templateCopy.ObjectMeta.Annotations = WithCommonAnnotations(workspaceObj, templateCopy.ObjectMeta.Annotations)
 	labelselector := &v1.LabelSelector{
 		MatchLabels: map[string]string{
 			kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name,
 		},
 	}
 	// Overwrite affinity
-	templateCopy.Spec.Affinity = &corev1.Affinity{
-		NodeAffinity: &corev1.NodeAffinity{
-			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-				NodeSelectorTerms: []corev1.NodeSelectorTerm{
-					{
-						MatchExpressions: nodeRequirements,
-					},
-				},
-			},
-		},
+	templateCopy.Spec.Affinity = inferenceAffinity(workspaceObj, nodeRequirements)
+	if workspaceObj.Resource.PriorityClassName != "" {
+		templateCopy.Spec.PriorityClassName = workspaceObj.Resource.PriorityClassName
+	}
+	if workspaceObj.Inference.ServiceAccountName != "" {
+		templateCopy.Spec.ServiceAccountName = workspaceObj.Inference.ServiceAccountName
+	}
+	if policy := workspaceObj.Inference.ImagePullPolicy; policy != "" {
+		for i := range templateCopy.Spec.Containers {
+			if templateCopy.Spec.Containers[i].ImagePullPolicy == "" {
+				templateCopy.Spec.Containers[i].ImagePullPolicy = policy
+			}
+		}
+	}
+	if templateCopy.Spec.TerminationGracePeriodSeconds == nil && workspaceObj.Inference.TerminationGracePeriodSeconds != nil {
+		templateCopy.Spec.TerminationGracePeriodSeconds = lo.ToPtr(*workspaceObj.Inference.TerminationGracePeriodSeconds)
+	}
+	// The DNS settings of the template take precedence.
+	if templateCopy.Spec.DNSPolicy == "" {
+		templateCopy.Spec.DNSPolicy = workspaceObj.Inference.DNSPolicy
+	}
+	if templateCopy.Spec.DNSConfig == nil {
+		templateCopy.Spec.DNSConfig = workspaceObj.Inference.DNSConfig.DeepCopy()
+	}
+	if len(templateCopy.Spec.TopologySpreadConstraints) == 0 {
+		templateCopy.Spec.TopologySpreadConstraints = inferenceTopologySpreadConstraints(workspaceObj, lo.FromPtr(workspaceObj.Resource.Count), labelselector)
 	}
 
 	// append tolerations
@@ -300,8 +416,10 @@ func GenerateDeploymentManifestWithPodTemplate(ctx context.Context, workspaceObj
 
 	return &appsv1.Deployment{
 		ObjectMeta: v1.ObjectMeta{
-			Name:      workspaceObj.Name,
-			Namespace: workspaceObj.Namespace,
+			Name:        workspaceObj.Name,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, nil),
 			OwnerReferences: []v1.OwnerReference{
 				{
 					APIVersion: kaitov1alpha1.GroupVersion.String(),
@@ -313,10 +431,326 @@ func GenerateDeploymentManifestWithPodTemplate(ctx context.Context, workspaceObj
 			},
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: lo.ToPtr(int32(*workspaceObj.Resource.Count)),
+			Replicas: lo.ToPtr(int32(lo.FromPtr(workspaceObj.Resource.Count))),
 			Selector: labelselector,
+			Strategy: inferenceDeploymentStrategy(workspaceObj),
 			Template: *templateCopy,
 		},
 	}
 
 }
+
+func GenerateTuningJobManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, imageName string,
+	imagePullSecretRefs []corev1.LocalObjectReference, commands []string, resourceRequirements corev1.ResourceRequirements,
+	tolerations []corev1.Toleration, volumes []corev1.Volume, volumeMount []corev1.VolumeMount,
+	uploadContainers []corev1.Container) *batchv1.Job {
+
+	nodeRequirements := make([]corev1.NodeSelectorRequirement, 0, len(workspaceObj.Resource.LabelSelector.MatchLabels))
+	for key, value := range workspaceObj.Resource.LabelSelector.MatchLabels {
+		nodeRequirements = append(nodeRequirements, corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{value},
+		})
+	}
+
+	labels := WithCommonLabels(workspaceObj, map[string]string{
+		kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name,
+	})
+	annotations := WithCommonAnnotations(workspaceObj, nil)
+
+	tuningContainer := corev1.Container{
+		Name:         workspaceObj.Name,
+		Image:        imageName,
+		Command:      commands,
+		Resources:    resourceRequirements,
+		VolumeMounts: volumeMount,
+	}
+	// The output is uploaded after the tuning finishes, hence the tuning runs as an init container if there is an upload step.
+	var initContainers []corev1.Container
+	containers := []corev1.Container{tuningContainer}
+	if len(uploadContainers) > 0 {
+		initContainers = containers
+		containers = uploadContainers
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        workspaceObj.Name,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+			OwnerReferences: []v1.OwnerReference{
+				{
+					APIVersion: kaitov1alpha1.GroupVersion.String(),
+					Kind:       "Workspace",
+					UID:        workspaceObj.UID,
+					Name:       workspaceObj.Name,
+					Controller: &controller,
+				},
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: lo.ToPtr(int32(3)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{
+					Labels:      labels,
+					Annotations: annotations,
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets:  imagePullSecretRefs,
+					PriorityClassName: workspaceObj.Resource.PriorityClassName,
+					RestartPolicy:     corev1.RestartPolicyNever,
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{
+									{
+										MatchExpressions: nodeRequirements,
+									},
+								},
+							},
+						},
+					},
+					InitContainers: initContainers,
+					Containers:     containers,
+					Tolerations:    tolerations,
+					Volumes:        volumes,
+				},
+			},
+		},
+	}
+}
+
+// inferenceDeploymentStrategy returns the update strategy of the inference Deployment for the rollout strategy of the
+// workspace. The nodes of the workspace have no spare GPUs for a surge pod, so a rolling update deletes an old pod
+// before it creates a new one. The canary pod of a canary rollout also takes the GPUs of an old pod.
+func inferenceDeploymentStrategy(workspaceObj *kaitov1alpha1.Workspace) appsv1.DeploymentStrategy {
+	switch workspaceObj.Inference.RolloutStrategy {
+	case kaitov1alpha1.RolloutStrategyRecreate:
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	case kaitov1alpha1.RolloutStrategyRolling, kaitov1alpha1.RolloutStrategyCanary:
+		return appsv1.DeploymentStrategy{
+			Type: appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{
+				MaxSurge:       lo.ToPtr(intstr.FromInt(0)),
+				MaxUnavailable: lo.ToPtr(intstr.FromInt(1)),
+			},
+		}
+	}
+	return appsv1.DeploymentStrategy{}
+}
+
+// inferenceAffinity returns the affinity of the inference pods, which requires the GPU nodes of the workspace and
+// includes the affinity specified by the user.
+func inferenceAffinity(workspaceObj *kaitov1alpha1.Workspace, nodeRequirements []corev1.NodeSelectorRequirement) *corev1.Affinity {
+	affinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: nodeRequirements,
+					},
+				},
+			},
+		},
+	}
+	if workspaceObj.Inference == nil {
+		return affinity
+	}
+	return mergeAffinity(affinity, workspaceObj.Inference.Affinity)
+}
+
+// inferenceServiceAccountName returns the service account of the inference pods, or an empty name for the default
+// service account of the namespace.
+func inferenceServiceAccountName(workspaceObj *kaitov1alpha1.Workspace) string {
+	if workspaceObj.Inference == nil {
+		return ""
+	}
+	return workspaceObj.Inference.ServiceAccountName
+}
+
+// managedLabels returns the labels of the objects kaito creates for the workspace, which identify the objects of
+// the workspace together with its controller owner reference, along with the common labels of the workspace.
+func managedLabels(workspaceObj *kaitov1alpha1.Workspace) map[string]string {
+	return WithCommonLabels(workspaceObj, map[string]string{kaitov1alpha1.LabelWorkspaceName: workspaceObj.Name})
+}
+
+// WithCommonLabels returns the labels merged with the common labels of the workspace. The given labels take
+// precedence and the reserved keys of the common labels are skipped. The given labels are not changed, nil is
+// returned if there are no labels.
+func WithCommonLabels(workspaceObj *kaitov1alpha1.Workspace, labels map[string]string) map[string]string {
+	var common map[string]string
+	if workspaceObj.CommonMetadata != nil {
+		common = workspaceObj.CommonMetadata.Labels
+	}
+	return mergeCommonMetadata(common, labels)
+}
+
+// WithCommonAnnotations returns the annotations merged with the common annotations of the workspace, like
+// WithCommonLabels.
+func WithCommonAnnotations(workspaceObj *kaitov1alpha1.Workspace, annotations map[string]string) map[string]string {
+	var common map[string]string
+	if workspaceObj.CommonMetadata != nil {
+		common = workspaceObj.CommonMetadata.Annotations
+	}
+	return mergeCommonMetadata(common, annotations)
+}
+
+func mergeCommonMetadata(common, own map[string]string) map[string]string {
+	merged := lo.Assign(lo.OmitBy(common, func(key, _ string) bool {
+		return kaitov1alpha1.IsReservedMetadataKey(key)
+	}), own)
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// inferenceImagePullPolicy returns the pull policy specified by the user, or IfNotPresent if the image is pinned to a
+// tag or a digest. An empty policy leaves the image of the latest tag to the default policy of the cluster, Always.
+func inferenceImagePullPolicy(workspaceObj *kaitov1alpha1.Workspace, imageName string) corev1.PullPolicy {
+	if workspaceObj.Inference != nil && workspaceObj.Inference.ImagePullPolicy != "" {
+		return workspaceObj.Inference.ImagePullPolicy
+	}
+	if strings.Contains(imageName, "@") {
+		return corev1.PullIfNotPresent
+	}
+	// The registry host may have a port, the tag follows the last colon of the last path segment.
+	repository := imageName[strings.LastIndex(imageName, "/")+1:]
+	if i := strings.LastIndex(repository, ":"); i >= 0 {
+		if tag := repository[i+1:]; tag != "" && tag != "latest" {
+			return corev1.PullIfNotPresent
+		}
+	}
+	return ""
+}
+
+// inferenceTopologySpreadConstraints returns the constraints specified by the user, whose label selectors default to
+// the pods of the workspace, or spreads the replicas across zones if there are more than one. The default constraint
+// does not block scheduling because the GPU nodes of the workspace may all be provisioned in the same zone.
+func inferenceTopologySpreadConstraints(workspaceObj *kaitov1alpha1.Workspace, replicas int, selector *v1.LabelSelector) []corev1.TopologySpreadConstraint {
+	if workspaceObj.Inference != nil && len(workspaceObj.Inference.TopologySpreadConstraints) > 0 {
+		constraints := make([]corev1.TopologySpreadConstraint, 0, len(workspaceObj.Inference.TopologySpreadConstraints))
+		for _, constraint := range workspaceObj.Inference.TopologySpreadConstraints {
+			constraint := *constraint.DeepCopy()
+			if constraint.LabelSelector == nil {
+				constraint.LabelSelector = selector.DeepCopy()
+			}
+			constraints = append(constraints, constraint)
+		}
+		return constraints
+	}
+	if replicas <= 1 {
+		return nil
+	}
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelTopologyZone,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     selector.DeepCopy(),
+		},
+	}
+}
+
+// mergeAffinity merges the user affinity into the affinity required by kaito. The node selector terms are ORed,
+// so every user term is ANDed with each of kaito's terms to keep the GPU node requirements. The preferred
+// scheduling terms and the pod (anti-)affinity terms of the user are appended.
+func mergeAffinity(kaitoAffinity, userAffinity *corev1.Affinity) *corev1.Affinity {
+	merged := kaitoAffinity.DeepCopy()
+	if userAffinity == nil {
+		return merged
+	}
+	user := userAffinity.DeepCopy()
+
+	if user.NodeAffinity != nil {
+		if merged.NodeAffinity == nil {
+			merged.NodeAffinity = &corev1.NodeAffinity{}
+		}
+		if userRequired := user.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; userRequired != nil && len(userRequired.NodeSelectorTerms) != 0 {
+			kaitoRequired := merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+			if kaitoRequired == nil || len(kaitoRequired.NodeSelectorTerms) == 0 {
+				merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = userRequired
+			} else {
+				var terms []corev1.NodeSelectorTerm
+				for _, kaitoTerm := range kaitoRequired.NodeSelectorTerms {
+					for _, userTerm := range userRequired.NodeSelectorTerms {
+						terms = append(terms, corev1.NodeSelectorTerm{
+							MatchExpressions: append(append([]corev1.NodeSelectorRequirement{}, kaitoTerm.MatchExpressions...), userTerm.MatchExpressions...),
+							MatchFields:      append(append([]corev1.NodeSelectorRequirement{}, kaitoTerm.MatchFields...), userTerm.MatchFields...),
+						})
+					}
+				}
+				kaitoRequired.NodeSelectorTerms = terms
+			}
+		}
+		merged.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			merged.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, user.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+	}
+
+	if user.PodAffinity != nil {
+		if merged.PodAffinity == nil {
+			merged.PodAffinity = &corev1.PodAffinity{}
+		}
+		merged.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution, user.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+		merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution, user.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+	}
+
+	if user.PodAntiAffinity != nil {
+		if merged.PodAntiAffinity == nil {
+			merged.PodAntiAffinity = &corev1.PodAntiAffinity{}
+		}
+		merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, user.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+		merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+			merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, user.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+	}
+	return merged
+}
+
+// GenerateHorizontalPodAutoscalerManifest generates the HorizontalPodAutoscaler that scales the inference Deployment
+// of the workspace between minReplicas and maxReplicas, keeping the average of the per pod metric at targetAverage.
+func GenerateHorizontalPodAutoscalerManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, minReplicas, maxReplicas int32,
+	metricName string, targetAverage int64) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        workspaceObj.Name,
+			Namespace:   workspaceObj.Namespace,
+			Labels:      managedLabels(workspaceObj),
+			Annotations: WithCommonAnnotations(workspaceObj, nil),
+			OwnerReferences: []v1.OwnerReference{
+				{
+					APIVersion: kaitov1alpha1.GroupVersion.String(),
+					Kind:       "Workspace",
+					UID:        workspaceObj.UID,
+					Name:       workspaceObj.Name,
+					Controller: &controller,
+				},
+			},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+				Name:       workspaceObj.Name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{
+				{
+					Type: autoscalingv2.PodsMetricSourceType,
+					Pods: &autoscalingv2.PodsMetricSource{
+						Metric: autoscalingv2.MetricIdentifier{Name: metricName},
+						Target: autoscalingv2.MetricTarget{
+							Type:         autoscalingv2.AverageValueMetricType,
+							AverageValue: resource.NewQuantity(targetAverage, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -12,7 +12,12 @@ import (
 
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
 	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestGenerateStatefulSetManifest(t *testing.T) {
@@ -29,6 +34,7 @@ func TestGenerateStatefulSetManifest(t *testing.T) {
 			nil, //containerPorts
 			nil, //livenessProbe
 			nil, //readinessProbe
+			nil, //startupProbe
 			v1.ResourceRequirements{},
 			nil, //tolerations
 			nil, //volumes
@@ -73,6 +79,7 @@ func TestGenerateDeploymentManifest(t *testing.T) {
 			nil, //containerPorts
 			nil, //livenessProbe
 			nil, //readinessProbe
+			nil, //startupProbe
 			v1.ResourceRequirements{},
 			nil, //tolerations
 			nil, //volumes
@@ -143,7 +150,7 @@ func TestGenerateServiceManifest(t *testing.T) {
 	for _, isStatefulSet := range options {
 		t.Run(fmt.Sprintf("generate service, isStatefulSet %v", isStatefulSet), func(t *testing.T) {
 			workspace := utils.MockWorkspaceWithPreset
-			obj := GenerateServiceManifest(context.TODO(), workspace, v1.ServiceTypeClusterIP, isStatefulSet)
+			obj := GenerateServiceManifest(context.TODO(), workspace, v1.ServiceTypeClusterIP, isStatefulSet, 0)
 
 			svcSelector := map[string]string{
 				kaitov1alpha1.LabelWorkspaceName: workspace.Name,
@@ -178,3 +185,610 @@ func TestGenerateHeadlessServiceManifest(t *testing.T) {
 		}
 	})
 }
+
+func TestGenerateEgressNetworkPolicyManifest(t *testing.T) {
+	testcases := map[string]struct {
+		egressPolicy  *kaitov1alpha1.EgressPolicy
+		expectNil     bool
+		expectedRules int
+		expectDNS     bool
+		expectedCIDRs []string
+	}{
+		"No egress policy": {
+			expectNil: true,
+		},
+		"Allowed CIDRs and DNS": {
+			egressPolicy:  &kaitov1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/16", "20.60.0.0/16"}},
+			expectedRules: 3,
+			expectDNS:     true,
+			expectedCIDRs: []string{"10.0.0.0/16", "20.60.0.0/16"},
+		},
+		"DNS disabled": {
+			egressPolicy:  &kaitov1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/16"}, AllowDNS: lo.ToPtr(false)},
+			expectedRules: 2,
+			expectedCIDRs: []string{"10.0.0.0/16"},
+		},
+		"Only the pods of the workspace": {
+			egressPolicy:  &kaitov1alpha1.EgressPolicy{AllowDNS: lo.ToPtr(false)},
+			expectedRules: 1,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.EgressPolicy = tc.egressPolicy
+
+			obj := GenerateEgressNetworkPolicyManifest(context.TODO(), workspace)
+			if tc.expectNil {
+				if obj != nil {
+					t.Errorf("expect no network policy, got %v", obj)
+				}
+				return
+			}
+
+			if obj.Name != fmt.Sprintf("%s-egress", workspace.Name) {
+				t.Errorf("network policy name is %s", obj.Name)
+			}
+			podSelector := map[string]string{kaitov1alpha1.LabelWorkspaceName: workspace.Name}
+			if !reflect.DeepEqual(podSelector, obj.Spec.PodSelector.MatchLabels) {
+				t.Errorf("pod selector is wrong")
+			}
+			if !reflect.DeepEqual(obj.Spec.PolicyTypes, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}) {
+				t.Errorf("policy types are %v, expect Egress", obj.Spec.PolicyTypes)
+			}
+			if len(obj.Spec.Egress) != tc.expectedRules {
+				t.Fatalf("network policy has %d egress rules, expect %d", len(obj.Spec.Egress), tc.expectedRules)
+			}
+			if !reflect.DeepEqual(obj.Spec.Egress[0].To[0].PodSelector.MatchLabels, podSelector) {
+				t.Errorf("the pods of the workspace are not allowed")
+			}
+
+			var dnsProtocols []v1.Protocol
+			var cidrs []string
+			for _, rule := range obj.Spec.Egress {
+				for _, port := range rule.Ports {
+					if port.Port.IntValue() == 53 {
+						dnsProtocols = append(dnsProtocols, *port.Protocol)
+					}
+				}
+				for _, peer := range rule.To {
+					if peer.IPBlock != nil {
+						cidrs = append(cidrs, peer.IPBlock.CIDR)
+					}
+				}
+			}
+			if tc.expectDNS != (len(dnsProtocols) == 2) {
+				t.Errorf("DNS is allowed over %v, expect allowed %t", dnsProtocols, tc.expectDNS)
+			}
+			if !reflect.DeepEqual(cidrs, tc.expectedCIDRs) {
+				t.Errorf("allowed CIDRs are %v, expect %v", cidrs, tc.expectedCIDRs)
+			}
+		})
+	}
+}
+
+func TestGenerateServiceManifestWithPort(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.Port = 8080
+
+	obj := GenerateServiceManifest(context.TODO(), workspace, v1.ServiceTypeClusterIP, false, 0)
+
+	if obj.Spec.Ports[0].TargetPort.IntVal != 8080 {
+		t.Errorf("svc target port is %d, expect 8080", obj.Spec.Ports[0].TargetPort.IntVal)
+	}
+}
+
+func TestGenerateServiceManifestWithMetricsPort(t *testing.T) {
+	testcases := map[string]struct {
+		metricsPort   int32
+		expectedPorts []string
+	}{
+		"Preset without metrics support": {
+			expectedPorts: []string{"http", "torch"},
+		},
+		"Preset with metrics support": {
+			metricsPort:   9090,
+			expectedPorts: []string{"http", "torch", MetricsPortName},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			obj := GenerateServiceManifest(context.TODO(), utils.MockWorkspaceWithPreset, v1.ServiceTypeClusterIP, false, tc.metricsPort)
+
+			ports := lo.Map(obj.Spec.Ports, func(p v1.ServicePort, _ int) string { return p.Name })
+			if !reflect.DeepEqual(ports, tc.expectedPorts) {
+				t.Errorf("svc ports are %v, expect %v", ports, tc.expectedPorts)
+			}
+			if tc.metricsPort != 0 {
+				metricsPort := obj.Spec.Ports[len(obj.Spec.Ports)-1]
+				if metricsPort.Port != tc.metricsPort || metricsPort.TargetPort.StrVal != MetricsPortName {
+					t.Errorf("svc metrics port is %d targeting %s, expect %d targeting %s", metricsPort.Port,
+						metricsPort.TargetPort.String(), tc.metricsPort, MetricsPortName)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateServiceManifestWithServiceType(t *testing.T) {
+	lbAnnotations := map[string]string{"service.beta.kubernetes.io/azure-load-balancer-internal": "true"}
+
+	testcases := map[string]struct {
+		serviceType         v1.ServiceType
+		expectedAnnotations map[string]string
+	}{
+		"ClusterIP service": {
+			serviceType: v1.ServiceTypeClusterIP,
+		},
+		"LoadBalancer service with annotations": {
+			serviceType:         v1.ServiceTypeLoadBalancer,
+			expectedAnnotations: lbAnnotations,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.ServiceType = tc.serviceType
+			workspace.Inference.ServiceAnnotations = lbAnnotations
+
+			obj := GenerateServiceManifest(context.TODO(), workspace, tc.serviceType, false, 0)
+			if obj.Spec.Type != tc.serviceType {
+				t.Errorf("svc type is %s, expect %s", obj.Spec.Type, tc.serviceType)
+			}
+			if !reflect.DeepEqual(obj.Annotations, tc.expectedAnnotations) {
+				t.Errorf("svc annotations are %v, expect %v", obj.Annotations, tc.expectedAnnotations)
+			}
+		})
+	}
+}
+
+func TestGenerateDeploymentManifestWithAffinity(t *testing.T) {
+	dbTerm := v1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "vector-db"}},
+		TopologyKey:   v1.LabelHostname,
+	}
+	zoneRequirement := v1.NodeSelectorRequirement{
+		Key:      v1.LabelTopologyZone,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{"eastus-1", "eastus-2"},
+	}
+
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement}},
+				},
+			},
+		},
+		PodAffinity: &v1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{dbTerm},
+		},
+	}
+
+	obj := GenerateDeploymentManifest(context.TODO(), workspace,
+		"",  //imageName
+		nil, //imagePullSecretRefs
+		*workspace.Resource.Count,
+		nil, //commands
+		nil, //containerPorts
+		nil, //livenessProbe
+		nil, //readinessProbe
+		nil, //startupProbe
+		v1.ResourceRequirements{},
+		nil, //tolerations
+		nil, //volumes
+		nil, //volumeMount
+	)
+
+	affinity := obj.Spec.Template.Spec.Affinity
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 {
+		t.Fatalf("expect 1 node selector term, got %d", len(terms))
+	}
+	// The GPU node requirements of kaito must be kept in addition to the user requirements.
+	for key, value := range workspace.Resource.LabelSelector.MatchLabels {
+		if !kvInNodeRequirement(key, value, terms[0].MatchExpressions) {
+			t.Errorf("the node affinity of kaito is missing")
+		}
+	}
+	if !reflect.DeepEqual(terms[0].MatchExpressions[len(terms[0].MatchExpressions)-1], zoneRequirement) {
+		t.Errorf("the node affinity of the user is missing")
+	}
+	if affinity.PodAffinity == nil || !reflect.DeepEqual(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution, []v1.PodAffinityTerm{dbTerm}) {
+		t.Errorf("the pod affinity of the user is missing")
+	}
+	// The user affinity of the workspace is not changed.
+	if len(workspace.Inference.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions) != 1 {
+		t.Errorf("the user affinity of the workspace is modified")
+	}
+}
+
+func TestMergeAffinity(t *testing.T) {
+	kaitoTerm := v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+		{Key: "apps", Operator: v1.NodeSelectorOpIn, Values: []string{"test"}},
+	}}
+	userTerms := []v1.NodeSelectorTerm{
+		{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"1"}}}},
+		{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"2"}}}},
+	}
+	kaitoAffinity := &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{kaitoTerm}},
+		},
+	}
+
+	t.Run("no user affinity", func(t *testing.T) {
+		merged := mergeAffinity(kaitoAffinity, nil)
+		if !reflect.DeepEqual(merged, kaitoAffinity) {
+			t.Errorf("expect the affinity of kaito, got %v", merged)
+		}
+	})
+
+	t.Run("user node selector terms are ANDed with the terms of kaito", func(t *testing.T) {
+		merged := mergeAffinity(kaitoAffinity, &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: userTerms},
+			},
+		})
+		terms := merged.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		if len(terms) != len(userTerms) {
+			t.Fatalf("expect %d node selector terms, got %d", len(userTerms), len(terms))
+		}
+		for i, term := range terms {
+			expected := append(append([]v1.NodeSelectorRequirement{}, kaitoTerm.MatchExpressions...), userTerms[i].MatchExpressions...)
+			if !reflect.DeepEqual(term.MatchExpressions, expected) {
+				t.Errorf("node selector term %d is %v, expect %v", i, term.MatchExpressions, expected)
+			}
+		}
+		if len(kaitoAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) != 1 {
+			t.Errorf("the affinity of kaito is modified")
+		}
+	})
+
+	t.Run("user pod anti-affinity is added", func(t *testing.T) {
+		antiAffinityTerm := v1.WeightedPodAffinityTerm{
+			Weight: 10,
+			PodAffinityTerm: v1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "batch"}},
+				TopologyKey:   v1.LabelHostname,
+			},
+		}
+		merged := mergeAffinity(kaitoAffinity, &v1.Affinity{
+			PodAntiAffinity: &v1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{antiAffinityTerm},
+			},
+		})
+		if !reflect.DeepEqual(merged.NodeAffinity, kaitoAffinity.NodeAffinity) {
+			t.Errorf("the node affinity of kaito is changed")
+		}
+		if !reflect.DeepEqual(merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, []v1.WeightedPodAffinityTerm{antiAffinityTerm}) {
+			t.Errorf("the pod anti-affinity of the user is missing")
+		}
+	})
+}
+
+func TestGenerateManifestsWithPriorityClass(t *testing.T) {
+	presetWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	presetWorkspace.Resource.PriorityClassName = "production-inference"
+	templateWorkspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	templateWorkspace.Resource.PriorityClassName = "production-inference"
+
+	podSpecs := map[string]v1.PodSpec{
+		"deployment": GenerateDeploymentManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"statefulset": GenerateStatefulSetManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"pod template deployment": GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil).Spec.Template.Spec,
+		"tuning job": GenerateTuningJobManifest(context.TODO(), presetWorkspace, "", nil, nil, v1.ResourceRequirements{},
+			nil, nil, nil, nil).Spec.Template.Spec,
+	}
+	for name, podSpec := range podSpecs {
+		if podSpec.PriorityClassName != "production-inference" {
+			t.Errorf("%s: expected priority class production-inference, got %q", name, podSpec.PriorityClassName)
+		}
+	}
+
+	// The priority class of the Pod template is kept if the workspace does not specify one.
+	templateWorkspace.Resource.PriorityClassName = ""
+	templateWorkspace.Inference.Template.Spec.PriorityClassName = "custom"
+	obj := GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil)
+	if obj.Spec.Template.Spec.PriorityClassName != "custom" {
+		t.Errorf("expected the priority class of the pod template, got %q", obj.Spec.Template.Spec.PriorityClassName)
+	}
+}
+
+func TestGenerateDeploymentManifestWithTopologySpreadConstraints(t *testing.T) {
+	workspaceSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{kaitov1alpha1.LabelWorkspaceName: utils.MockWorkspaceWithPreset.Name},
+	}
+
+	testcases := map[string]struct {
+		replicas            int
+		userConstraints     []v1.TopologySpreadConstraint
+		expectedConstraints []v1.TopologySpreadConstraint
+	}{
+		"Single replica": {
+			replicas: 1,
+		},
+		"Multiple replicas are spread across zones": {
+			replicas: 3,
+			expectedConstraints: []v1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       v1.LabelTopologyZone,
+					WhenUnsatisfiable: v1.ScheduleAnyway,
+					LabelSelector:     workspaceSelector,
+				},
+			},
+		},
+		"User constraints": {
+			replicas: 3,
+			userConstraints: []v1.TopologySpreadConstraint{
+				{
+					MaxSkew:           2,
+					TopologyKey:       "topology.kubernetes.io/rack",
+					WhenUnsatisfiable: v1.DoNotSchedule,
+				},
+			},
+			expectedConstraints: []v1.TopologySpreadConstraint{
+				{
+					MaxSkew:           2,
+					TopologyKey:       "topology.kubernetes.io/rack",
+					WhenUnsatisfiable: v1.DoNotSchedule,
+					LabelSelector:     workspaceSelector,
+				},
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference.TopologySpreadConstraints = tc.userConstraints
+
+			obj := GenerateDeploymentManifest(context.TODO(), workspace, "", nil, tc.replicas,
+				nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil)
+			constraints := obj.Spec.Template.Spec.TopologySpreadConstraints
+			if !reflect.DeepEqual(constraints, tc.expectedConstraints) {
+				t.Errorf("expected topology spread constraints %v, got %v", tc.expectedConstraints, constraints)
+			}
+		})
+	}
+}
+
+func TestGenerateManifestsWithServiceAccount(t *testing.T) {
+	presetWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	presetWorkspace.Inference.ServiceAccountName = "model-reader"
+	templateWorkspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	templateWorkspace.Inference.ServiceAccountName = "model-reader"
+
+	podSpecs := map[string]v1.PodSpec{
+		"deployment": GenerateDeploymentManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"statefulset": GenerateStatefulSetManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"pod template deployment": GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil).Spec.Template.Spec,
+	}
+	for name, podSpec := range podSpecs {
+		if podSpec.ServiceAccountName != "model-reader" {
+			t.Errorf("%s: expected service account model-reader, got %q", name, podSpec.ServiceAccountName)
+		}
+	}
+
+	// The default service account of the namespace is used if the workspace does not specify one.
+	obj := GenerateDeploymentManifest(context.TODO(), utils.MockWorkspaceWithPreset, "", nil, 1,
+		nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil)
+	if obj.Spec.Template.Spec.ServiceAccountName != "" {
+		t.Errorf("expected the default service account, got %q", obj.Spec.Template.Spec.ServiceAccountName)
+	}
+}
+
+func TestGenerateManifestsWithImagePullPolicy(t *testing.T) {
+	presetWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	presetWorkspace.Inference.ImagePullPolicy = v1.PullNever
+	templateWorkspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	templateWorkspace.Inference.ImagePullPolicy = v1.PullNever
+	templateWorkspace.Inference.Template.Spec.Containers = []v1.Container{{Name: "model-server", Image: "model-server:0.1"}}
+
+	podSpecs := map[string]v1.PodSpec{
+		"deployment": GenerateDeploymentManifest(context.TODO(), presetWorkspace, "mcr.microsoft.com/aks/kaito/kaito-falcon-7b:0.0.4", nil,
+			*presetWorkspace.Resource.Count, nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"statefulset": GenerateStatefulSetManifest(context.TODO(), presetWorkspace, "mcr.microsoft.com/aks/kaito/kaito-falcon-7b:0.0.4", nil,
+			*presetWorkspace.Resource.Count, nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"pod template deployment": GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil).Spec.Template.Spec,
+	}
+	for name, podSpec := range podSpecs {
+		if policy := podSpec.Containers[0].ImagePullPolicy; policy != v1.PullNever {
+			t.Errorf("%s: expected image pull policy Never, got %q", name, policy)
+		}
+	}
+
+	defaults := map[string]v1.PullPolicy{
+		"mcr.microsoft.com/aks/kaito/kaito-falcon-7b:0.0.4":      v1.PullIfNotPresent,
+		"localhost:5000/kaito-falcon-7b@sha256:0123456789abcdef": v1.PullIfNotPresent,
+		"localhost:5000/kaito-falcon-7b":                         "",
+		"mcr.microsoft.com/aks/kaito/kaito-falcon-7b:latest":     "",
+	}
+	for image, expected := range defaults {
+		obj := GenerateDeploymentManifest(context.TODO(), utils.MockWorkspaceWithPreset, image, nil, 1,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil)
+		if policy := obj.Spec.Template.Spec.Containers[0].ImagePullPolicy; policy != expected {
+			t.Errorf("%s: expected image pull policy %q, got %q", image, expected, policy)
+		}
+	}
+}
+
+func TestGenerateManifestsWithDNSConfig(t *testing.T) {
+	dnsConfig := &v1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"models.internal.example.com"},
+		Options:     []v1.PodDNSConfigOption{{Name: "ndots", Value: lo.ToPtr("2")}},
+	}
+	presetWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	presetWorkspace.Inference.DNSPolicy = v1.DNSNone
+	presetWorkspace.Inference.DNSConfig = dnsConfig
+	templateWorkspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	templateWorkspace.Inference.DNSPolicy = v1.DNSNone
+	templateWorkspace.Inference.DNSConfig = dnsConfig
+
+	podSpecs := map[string]v1.PodSpec{
+		"deployment": GenerateDeploymentManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"statefulset": GenerateStatefulSetManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+			nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil).Spec.Template.Spec,
+		"pod template deployment": GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil).Spec.Template.Spec,
+	}
+	for name, podSpec := range podSpecs {
+		if podSpec.DNSPolicy != v1.DNSNone {
+			t.Errorf("%s: expected DNS policy None, got %q", name, podSpec.DNSPolicy)
+		}
+		if !reflect.DeepEqual(podSpec.DNSConfig, dnsConfig) {
+			t.Errorf("%s: expected DNS config %v, got %v", name, dnsConfig, podSpec.DNSConfig)
+		}
+	}
+
+	// The DNS settings of the template take precedence.
+	templateWorkspace.Inference.Template.Spec.DNSPolicy = v1.DNSDefault
+	podSpec := GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil).Spec.Template.Spec
+	if podSpec.DNSPolicy != v1.DNSDefault {
+		t.Errorf("expected the DNS policy of the template, got %q", podSpec.DNSPolicy)
+	}
+}
+
+func TestGenerateDeploymentManifestWithPodTemplateTerminationGracePeriod(t *testing.T) {
+	workspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	workspace.Inference.TerminationGracePeriodSeconds = lo.ToPtr(int64(300))
+
+	obj := GenerateDeploymentManifestWithPodTemplate(context.TODO(), workspace, nil)
+	if gracePeriod := obj.Spec.Template.Spec.TerminationGracePeriodSeconds; gracePeriod == nil || *gracePeriod != 300 {
+		t.Errorf("expected the termination grace period of the workspace, got %v", gracePeriod)
+	}
+
+	// The grace period of the template takes precedence.
+	workspace.Inference.Template.Spec.TerminationGracePeriodSeconds = lo.ToPtr(int64(60))
+	obj = GenerateDeploymentManifestWithPodTemplate(context.TODO(), workspace, nil)
+	if gracePeriod := obj.Spec.Template.Spec.TerminationGracePeriodSeconds; gracePeriod == nil || *gracePeriod != 60 {
+		t.Errorf("expected the termination grace period of the template, got %v", gracePeriod)
+	}
+}
+
+func TestGenerateServiceManifestWithAuth(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference.Auth = &kaitov1alpha1.InferenceAuth{SecretName: "inference-token"}
+
+	obj := GenerateServiceManifest(context.TODO(), workspace, v1.ServiceTypeClusterIP, false, 0)
+
+	if obj.Spec.Ports[0].TargetPort.IntVal != kaitov1alpha1.AuthProxyPort {
+		t.Errorf("svc target port is %d, expect %d", obj.Spec.Ports[0].TargetPort.IntVal, kaitov1alpha1.AuthProxyPort)
+	}
+}
+
+func TestGenerateDeploymentManifestWithRolloutStrategy(t *testing.T) {
+	tests := map[kaitov1alpha1.RolloutStrategy]struct {
+		strategyType   appsv1.DeploymentStrategyType
+		maxSurge       *intstr.IntOrString
+		maxUnavailable *intstr.IntOrString
+	}{
+		"": {},
+		kaitov1alpha1.RolloutStrategyRecreate: {
+			strategyType: appsv1.RecreateDeploymentStrategyType,
+		},
+		kaitov1alpha1.RolloutStrategyRolling: {
+			strategyType:   appsv1.RollingUpdateDeploymentStrategyType,
+			maxSurge:       lo.ToPtr(intstr.FromInt(0)),
+			maxUnavailable: lo.ToPtr(intstr.FromInt(1)),
+		},
+		kaitov1alpha1.RolloutStrategyCanary: {
+			strategyType:   appsv1.RollingUpdateDeploymentStrategyType,
+			maxSurge:       lo.ToPtr(intstr.FromInt(0)),
+			maxUnavailable: lo.ToPtr(intstr.FromInt(1)),
+		},
+	}
+
+	for rolloutStrategy, tc := range tests {
+		workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+		workspace.Inference.RolloutStrategy = rolloutStrategy
+		templateWorkspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+		templateWorkspace.Inference.RolloutStrategy = rolloutStrategy
+
+		objs := []*appsv1.Deployment{
+			GenerateDeploymentManifest(context.TODO(), workspace, "test-image", nil, 1, nil, nil, nil, nil, nil,
+				v1.ResourceRequirements{}, nil, nil, nil),
+			GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil),
+		}
+		for _, obj := range objs {
+			strategy := obj.Spec.Strategy
+			if strategy.Type != tc.strategyType {
+				t.Errorf("rollout strategy %q: expected strategy type %q, got %q", rolloutStrategy, tc.strategyType, strategy.Type)
+			}
+			if tc.maxSurge == nil {
+				if strategy.RollingUpdate != nil {
+					t.Errorf("rollout strategy %q: expected no rolling update, got %v", rolloutStrategy, strategy.RollingUpdate)
+				}
+				continue
+			}
+			if strategy.RollingUpdate == nil || !reflect.DeepEqual(strategy.RollingUpdate.MaxSurge, tc.maxSurge) ||
+				!reflect.DeepEqual(strategy.RollingUpdate.MaxUnavailable, tc.maxUnavailable) {
+				t.Errorf("rollout strategy %q: expected max surge %v and max unavailable %v, got %v",
+					rolloutStrategy, tc.maxSurge, tc.maxUnavailable, strategy.RollingUpdate)
+			}
+		}
+	}
+}
+
+func TestGenerateManifestsWithCommonMetadata(t *testing.T) {
+	commonMetadata := &kaitov1alpha1.CommonMetadata{
+		Labels:      map[string]string{"cost-center": "1234", "team": "ml", kaitov1alpha1.LabelWorkspaceName: "other"},
+		Annotations: map[string]string{"owner": "ml-platform@example.com"},
+	}
+	presetWorkspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	presetWorkspace.CommonMetadata = commonMetadata
+	presetWorkspace.Inference.EgressPolicy = &kaitov1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/8"}}
+	templateWorkspace := utils.MockWorkspaceWithInferenceTemplate.DeepCopy()
+	templateWorkspace.CommonMetadata = commonMetadata
+	templateWorkspace.Inference.Template.Labels = map[string]string{"team": "template"}
+
+	deployment := GenerateDeploymentManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+		nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil)
+	statefulSet := GenerateStatefulSetManifest(context.TODO(), presetWorkspace, "", nil, *presetWorkspace.Resource.Count,
+		nil, nil, nil, nil, nil, v1.ResourceRequirements{}, nil, nil, nil)
+	templateDeployment := GenerateDeploymentManifestWithPodTemplate(context.TODO(), templateWorkspace, nil)
+	job := GenerateTuningJobManifest(context.TODO(), presetWorkspace, "", nil, nil, v1.ResourceRequirements{}, nil, nil, nil, nil)
+	objects := map[string]metav1.Object{
+		"deployment":                           deployment,
+		"deployment pod template":              &deployment.Spec.Template,
+		"statefulset":                          statefulSet,
+		"statefulset pod template":             &statefulSet.Spec.Template,
+		"pod template deployment":              templateDeployment,
+		"pod template deployment pod template": &templateDeployment.Spec.Template,
+		"tuning job":                           job,
+		"tuning job pod template":              &job.Spec.Template,
+		"service":                              GenerateServiceManifest(context.TODO(), presetWorkspace, v1.ServiceTypeClusterIP, false, 0),
+		"headless service":                     GenerateHeadlessServiceManifest(context.TODO(), presetWorkspace),
+		"egress network policy":                GenerateEgressNetworkPolicyManifest(context.TODO(), presetWorkspace),
+	}
+	for name, obj := range objects {
+		labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+		if labels["cost-center"] != "1234" || annotations["owner"] != "ml-platform@example.com" {
+			t.Errorf("%s: expected the common labels and annotations, got labels %v and annotations %v", name, labels, annotations)
+		}
+		// The reserved keys of the common labels are skipped.
+		if labels[kaitov1alpha1.LabelWorkspaceName] != presetWorkspace.Name {
+			t.Errorf("%s: expected the workspace label %s, got %q", name, presetWorkspace.Name, labels[kaitov1alpha1.LabelWorkspaceName])
+		}
+	}
+
+	// The labels of the pod template take precedence over the common labels.
+	if team := templateDeployment.Spec.Template.Labels["team"]; team != "template" {
+		t.Errorf("expected the team label of the pod template, got %q", team)
+	}
+	// The selector of the workload does not select by the common labels.
+	if !reflect.DeepEqual(deployment.Spec.Selector.MatchLabels, map[string]string{kaitov1alpha1.LabelWorkspaceName: presetWorkspace.Name}) {
+		t.Errorf("expected the selector to only match the workspace label, got %v", deployment.Spec.Selector.MatchLabels)
+	}
+}
@@ -5,9 +5,12 @@ package resources
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -18,6 +21,11 @@ const (
 	CapacityNvidiaGPU       = "nvidia.com/gpu"
 	GPUProvisionerNamespace = "gpu-provisioner"
 	GPUString               = "gpu"
+
+	// The driver version labels are set by the GPU feature discovery of the NVIDIA GPU Operator.
+	LabelKeyNvidiaDriverMajor = "nvidia.com/cuda.driver.major"
+	LabelKeyNvidiaDriverMinor = "nvidia.com/cuda.driver.minor"
+	LabelKeyNvidiaDriverRev   = "nvidia.com/cuda.driver.rev"
 )
 
 // GetNode get kubernetes node object with a provided name
@@ -68,6 +76,43 @@ func UpdateNodeWithLabel(ctx context.Context, nodeName, labelKey, labelValue str
 	return nil
 }
 
+// CordonNode marks the node as unschedulable.
+func CordonNode(ctx context.Context, nodeName string, kubeClient client.Client) error {
+	klog.InfoS("CordonNode", "nodeName", nodeName)
+
+	freshNode, err := GetNode(ctx, nodeName, kubeClient)
+	if err != nil {
+		klog.ErrorS(err, "cannot get node", "node", nodeName)
+		return err
+	}
+	if freshNode.Spec.Unschedulable {
+		return nil
+	}
+
+	freshNode.Spec.Unschedulable = true
+	return kubeClient.Update(ctx, freshNode, &client.UpdateOptions{})
+}
+
+// RemoveNodeTaint removes the taints with the key from the node.
+func RemoveNodeTaint(ctx context.Context, nodeName, taintKey string, kubeClient client.Client) error {
+	klog.InfoS("RemoveNodeTaint", "nodeName", nodeName, "taintKey", taintKey)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		freshNode, err := GetNode(ctx, nodeName, kubeClient)
+		if err != nil {
+			klog.ErrorS(err, "cannot get node", "node", nodeName)
+			return err
+		}
+		taints := lo.Reject(freshNode.Spec.Taints, func(taint corev1.Taint, _ int) bool { return taint.Key == taintKey })
+		if len(taints) == len(freshNode.Spec.Taints) {
+			return nil
+		}
+
+		freshNode.Spec.Taints = taints
+		return kubeClient.Update(ctx, freshNode, &client.UpdateOptions{})
+	})
+}
+
 func CheckNvidiaPlugin(ctx context.Context, nodeObj *corev1.Node) bool {
 	// check if label accelerator=nvidia exists in the node
 	var foundLabel, foundCapacity bool
@@ -88,3 +133,53 @@ func CheckNvidiaPlugin(ctx context.Context, nodeObj *corev1.Node) bool {
 	}
 	return false
 }
+
+// CheckNvidiaDriverVersion verifies that the NVIDIA driver version reported in the node labels is at least minVersion.
+func CheckNvidiaDriverVersion(nodeObj *corev1.Node, minVersion string) error {
+	if minVersion == "" {
+		return nil
+	}
+	required, err := parseDriverVersion(minVersion)
+	if err != nil {
+		return fmt.Errorf("invalid minimum driver version %q: %w", minVersion, err)
+	}
+
+	major, found := nodeObj.Labels[LabelKeyNvidiaDriverMajor]
+	if !found {
+		return fmt.Errorf("node %s has no %s label, install the NVIDIA GPU Operator or GPU feature discovery so that the driver version can be verified",
+			nodeObj.Name, LabelKeyNvidiaDriverMajor)
+	}
+	nodeVersion := strings.Join(lo.Compact([]string{major, nodeObj.Labels[LabelKeyNvidiaDriverMinor], nodeObj.Labels[LabelKeyNvidiaDriverRev]}), ".")
+	actual, err := parseDriverVersion(nodeVersion)
+	if err != nil {
+		return fmt.Errorf("invalid driver version %q on node %s: %w", nodeVersion, nodeObj.Name, err)
+	}
+
+	for i := range required {
+		if actual[i] > required[i] {
+			return nil
+		}
+		if actual[i] < required[i] {
+			return fmt.Errorf("node %s has NVIDIA driver version %s but the model requires at least %s, use a node image with a newer driver or upgrade the driver with the NVIDIA GPU Operator",
+				nodeObj.Name, nodeVersion, minVersion)
+		}
+	}
+	return nil
+}
+
+// parseDriverVersion parses a driver version of the form major[.minor[.rev]], the missing parts are zero.
+func parseDriverVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	parts := strings.Split(version, ".")
+	if len(parts) > len(parsed) {
+		return parsed, fmt.Errorf("too many version parts")
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, fmt.Errorf("invalid version part %q", part)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
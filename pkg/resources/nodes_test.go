@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"gotest.tools/assert"
 	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -128,3 +129,58 @@ func TestCheckNvidiaPlugin(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckNvidiaDriverVersion(t *testing.T) {
+	driverLabels := func(major, minor, rev string) map[string]string {
+		return map[string]string{
+			LabelKeyNvidiaDriverMajor: major,
+			LabelKeyNvidiaDriverMinor: minor,
+			LabelKeyNvidiaDriverRev:   rev,
+		}
+	}
+	testcases := map[string]struct {
+		labels        map[string]string
+		minVersion    string
+		expectedError string
+	}{
+		"No minimum driver version": {
+			labels:     nil,
+			minVersion: "",
+		},
+		"Driver version meets the minimum": {
+			labels:     driverLabels("535", "104", "05"),
+			minVersion: "535.54.03",
+		},
+		"Driver version equals the minimum": {
+			labels:     driverLabels("535", "54", "03"),
+			minVersion: "535.54.03",
+		},
+		"Driver version is lower than the minimum": {
+			labels:        driverLabels("525", "105", "17"),
+			minVersion:    "535",
+			expectedError: "node mockNode has NVIDIA driver version 525.105.17 but the model requires at least 535, use a node image with a newer driver or upgrade the driver with the NVIDIA GPU Operator",
+		},
+		"Driver version labels are missing": {
+			labels:        map[string]string{},
+			minVersion:    "535",
+			expectedError: "node mockNode has no nvidia.com/cuda.driver.major label, install the NVIDIA GPU Operator or GPU feature discovery so that the driver version can be verified",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			nodeObj := &corev1.Node{
+				ObjectMeta: v1.ObjectMeta{
+					Name:   "mockNode",
+					Labels: tc.labels,
+				},
+			}
+			err := CheckNvidiaDriverVersion(nodeObj, tc.minVersion)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Equal(t, tc.expectedError, err.Error())
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
@@ -22,6 +24,10 @@ func CreateResource(ctx context.Context, resource client.Object, kubeClient clie
 		klog.InfoS("CreateStatefulSet", "statefulset", klog.KObj(r))
 	case *corev1.Service:
 		klog.InfoS("CreateService", "service", klog.KObj(r))
+	case *batchv1.Job:
+		klog.InfoS("CreateJob", "job", klog.KObj(r))
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		klog.InfoS("CreateHorizontalPodAutoscaler", "hpa", klog.KObj(r))
 	}
 
 	// Create the resource.
@@ -0,0 +1,306 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package tuning
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The formats of the datasets that can be sampled before the tuning job runs.
+const (
+	DatasetFormatJSONLines = "jsonl"
+	DatasetFormatJSON      = "json"
+	DatasetFormatCSV       = "csv"
+)
+
+const (
+	// datasetSampleSize is the number of bytes that are sampled from the beginning of a dataset.
+	datasetSampleSize = 64 * 1024
+	// datasetSampleTimeout is the time a dataset is given to be sampled.
+	datasetSampleTimeout = 30 * time.Second
+)
+
+// TuningConfigFile is the key of the tuning ConfigMap that holds the arguments of the tuning job.
+const TuningConfigFile = "training_config.yaml"
+
+// DatasetColumns are the columns of the dataset that the tuning job reads, every record must have a value for both.
+type DatasetColumns struct {
+	Context  string `yaml:"context_column"`
+	Response string `yaml:"response_column"`
+}
+
+// DefaultDatasetColumns are the columns that the tuning API reads if the tuning ConfigMap does not set them.
+var DefaultDatasetColumns = DatasetColumns{Context: "Context", Response: "Response"}
+
+// InvalidDatasetError is returned if a sampled dataset does not match the schema of the tuning job. Unlike the
+// failures to sample a dataset, it does not go away until the spec of the workspace is changed.
+type InvalidDatasetError struct {
+	URL string
+	Err error
+}
+
+func (e *InvalidDatasetError) Error() string {
+	return fmt.Sprintf("the dataset %s does not match the tuning job: %v", e.URL, e.Err)
+}
+
+func (e *InvalidDatasetError) Unwrap() error {
+	return e.Err
+}
+
+// datasetSampleClient only connects to public addresses, so that the URLs of a workspace cannot make the controller
+// reach the services of the cluster or the metadata endpoint of its nodes. No proxy is used, since it could.
+var datasetSampleClient = &http.Client{
+	Timeout: datasetSampleTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: datasetSampleTimeout, Control: dialPublicAddress}).DialContext,
+	},
+}
+
+// dialPublicAddress rejects the connections to addresses that are not public, it is checked on every connection,
+// i.e., after the name of the host is resolved and for every redirect.
+func dialPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("the address %s is not public", host)
+	}
+	return nil
+}
+
+// GetDatasetColumns returns the columns of the dataset that the tuning job of the workspace reads, i.e., those of the
+// DatasetConfig of its tuning ConfigMap. The default ConfigMaps of the tuning methods do not set them.
+func GetDatasetColumns(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) (DatasetColumns, error) {
+	columns := DefaultDatasetColumns
+	if workspaceObj.Tuning == nil || workspaceObj.Tuning.Config == "" {
+		return columns, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := resources.GetResource(ctx, workspaceObj.Tuning.Config, workspaceObj.Namespace, kubeClient, cm); err != nil {
+		return columns, fmt.Errorf("failed to get the tuning ConfigMap %s: %w", workspaceObj.Tuning.Config, err)
+	}
+	var config struct {
+		TrainingConfig struct {
+			DatasetConfig DatasetColumns `yaml:"DatasetConfig"`
+		} `yaml:"training_config"`
+	}
+	if err := yaml.Unmarshal([]byte(cm.Data[TuningConfigFile]), &config); err != nil {
+		return columns, fmt.Errorf("failed to parse %s of the tuning ConfigMap %s: %w", TuningConfigFile, workspaceObj.Tuning.Config, err)
+	}
+	if column := config.TrainingConfig.DatasetConfig.Context; column != "" {
+		columns.Context = column
+	}
+	if column := config.TrainingConfig.DatasetConfig.Response; column != "" {
+		columns.Response = column
+	}
+	return columns, nil
+}
+
+// DatasetFormatOf returns the format of the dataset at the URL by its file extension, or an empty string if the
+// format cannot be sampled, e.g., a parquet file.
+func DatasetFormatOf(datasetURL string) string {
+	datasetPath := datasetURL
+	if parsed, err := url.Parse(datasetURL); err == nil {
+		datasetPath = parsed.Path
+	}
+	switch strings.ToLower(path.Ext(datasetPath)) {
+	case ".jsonl":
+		return DatasetFormatJSONLines
+	case ".json":
+		return DatasetFormatJSON
+	case ".csv":
+		return DatasetFormatCSV
+	}
+	return ""
+}
+
+// ValidateDatasetFormat checks that the sample of a dataset in the given format matches the schema of the tuning
+// job, i.e., that it holds at least one record and that every record has a non-empty value for each of the columns.
+// The sample of a JSON array may be cut off within a record, the samples of the other formats must end with a
+// complete line.
+func ValidateDatasetFormat(columns DatasetColumns, format string, sample []byte) error {
+	var records []map[string]string
+	var err error
+	switch format {
+	case DatasetFormatJSONLines:
+		records, err = jsonLinesRecords(sample)
+	case DatasetFormatJSON:
+		records, err = jsonRecords(sample)
+	case DatasetFormatCSV:
+		records, err = csvRecords(sample)
+	default:
+		return fmt.Errorf("dataset format %q is not supported, the supported formats are %s, %s and %s",
+			format, DatasetFormatJSONLines, DatasetFormatJSON, DatasetFormatCSV)
+	}
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("the dataset has no records")
+	}
+	for i, record := range records {
+		for _, column := range []string{columns.Context, columns.Response} {
+			if strings.TrimSpace(record[column]) == "" {
+				return fmt.Errorf("record %d has no %s, the tuning job reads the columns %s and %s", i+1, column,
+					columns.Context, columns.Response)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonLinesRecords parses the records of a JSON Lines sample.
+func jsonLinesRecords(sample []byte) ([]map[string]string, error) {
+	var records []map[string]string
+	for i, line := range bytes.Split(sample, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		record, err := jsonRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d is not a JSON object: %v", i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// jsonRecords parses the records of a sample of a JSON array, the last record is skipped if it is cut off.
+func jsonRecords(sample []byte) ([]map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(sample))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("the dataset is not a JSON array of records")
+	}
+	var records []map[string]string
+	for decoder.More() {
+		raw := json.RawMessage{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) && len(records) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("record %d is not valid JSON: %v", len(records)+1, err)
+		}
+		record, err := jsonRecord(raw)
+		if err != nil {
+			return nil, fmt.Errorf("record %d is not a JSON object: %v", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// jsonRecord parses a JSON object, the values that are not strings are kept in their JSON encoding.
+func jsonRecord(data []byte) (map[string]string, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	record := make(map[string]string, len(fields))
+	for key, value := range fields {
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			text = string(value)
+		}
+		if text == "null" {
+			text = ""
+		}
+		record[key] = text
+	}
+	return record, nil
+}
+
+// csvRecords parses the records of a CSV sample with a header row. The rows must have as many columns as the header.
+func csvRecords(sample []byte) ([]map[string]string, error) {
+	rows, err := csv.NewReader(bytes.NewReader(sample)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("the dataset is not valid CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]string, len(header))
+		for j, column := range header {
+			record[column] = row[j]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ValidateInputDataset samples the datasets of the tuning input that are downloaded from URLs and checks that they
+// have the given columns, so that a malformed dataset fails the workspace before the tuning job runs. A dataset that
+// does not match returns an InvalidDatasetError. The datasets are only checked if the workspace asks for it, and those
+// in a format that cannot be sampled or that are read from an image or a host path are not checked.
+func ValidateInputDataset(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, columns DatasetColumns) error {
+	tuningSpec := workspaceObj.Tuning
+	if tuningSpec == nil || !tuningSpec.ValidateDataset || tuningSpec.Input == nil {
+		return nil
+	}
+	for _, datasetURL := range tuningSpec.Input.URLs {
+		format := DatasetFormatOf(datasetURL)
+		if format == "" {
+			klog.InfoS("the format of the dataset cannot be sampled, skipping its validation", "workspace", klog.KObj(workspaceObj),
+				"url", datasetURL)
+			continue
+		}
+		sample, err := sampleDataset(ctx, datasetURL)
+		if err != nil {
+			return fmt.Errorf("failed to sample the dataset %s: %w", datasetURL, err)
+		}
+		// The line that the sample cuts off is not checked.
+		if len(sample) == datasetSampleSize && format != DatasetFormatJSON {
+			if end := bytes.LastIndexByte(sample, '\n'); end >= 0 {
+				sample = sample[:end+1]
+			}
+		}
+		if err := ValidateDatasetFormat(columns, format, sample); err != nil {
+			return &InvalidDatasetError{URL: datasetURL, Err: err}
+		}
+	}
+	return nil
+}
+
+// sampleDataset downloads the beginning of the dataset at the URL.
+func sampleDataset(ctx context.Context, datasetURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, datasetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The servers that do not support ranges return the whole dataset, of which only the sample is read.
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", datasetSampleSize-1))
+	resp, err := datasetSampleClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, datasetSampleSize))
+}
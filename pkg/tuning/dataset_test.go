@@ -0,0 +1,220 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package tuning
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateDatasetFormat(t *testing.T) {
+	testcases := map[string]struct {
+		columns       DatasetColumns
+		format        string
+		sample        string
+		expectedError string
+	}{
+		"Well-formed JSON Lines": {
+			format: DatasetFormatJSONLines,
+			sample: `{"Context": "I feel anxious.", "Response": "Let's talk about it."}` + "\n" +
+				`{"Context": "I can't sleep.", "Response": "How long has this been going on?", "Source": 3}` + "\n",
+		},
+		"Well-formed JSON array cut off within a record": {
+			format: DatasetFormatJSON,
+			sample: `[{"Context": "I feel anxious.", "Response": "Let's talk about it."}, {"Context": "I can't`,
+		},
+		"Well-formed CSV": {
+			format: DatasetFormatCSV,
+			sample: "Context,Response\n\"I feel anxious, often.\",Let's talk about it.\n",
+		},
+		"JSON Lines record without a response": {
+			format:        DatasetFormatJSONLines,
+			sample:        `{"Context": "I feel anxious.", "Response": "Let's talk about it."}` + "\n" + `{"Context": "I can't sleep."}` + "\n",
+			expectedError: "record 2 has no Response, the tuning job reads the columns Context and Response",
+		},
+		"CSV with the columns of the tuning ConfigMap": {
+			columns: DatasetColumns{Context: "question", Response: "answer"},
+			format:  DatasetFormatCSV,
+			sample:  "question,answer\nI feel anxious.,Let's talk about it.\n",
+		},
+		"JSON Lines with a line that is not JSON": {
+			format:        DatasetFormatJSONLines,
+			sample:        "Context: I feel anxious.\n",
+			expectedError: "line 1 is not a JSON object",
+		},
+		"JSON object instead of an array": {
+			format:        DatasetFormatJSON,
+			sample:        `{"Context": "I feel anxious.", "Response": "Let's talk about it."}`,
+			expectedError: "the dataset is not a JSON array of records",
+		},
+		"CSV with other columns": {
+			format:        DatasetFormatCSV,
+			sample:        "question,answer\nI feel anxious.,Let's talk about it.\n",
+			expectedError: "record 1 has no Context",
+		},
+		"Empty dataset": {
+			format:        DatasetFormatJSONLines,
+			sample:        "\n",
+			expectedError: "the dataset has no records",
+		},
+		"Unsupported format": {
+			format:        "parquet",
+			sample:        "PAR1",
+			expectedError: `dataset format "parquet" is not supported`,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			columns := lo.Ternary(tc.columns != DatasetColumns{}, tc.columns, DefaultDatasetColumns)
+			err := ValidateDatasetFormat(columns, tc.format, []byte(tc.sample))
+			if tc.expectedError == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "expected error %q, got %v", tc.expectedError, err)
+		})
+	}
+}
+
+func TestValidateInputDataset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/valid.jsonl":
+			_, _ = w.Write([]byte(`{"Context": "I feel anxious.", "Response": "Let's talk about it."}` + "\n"))
+		case "/malformed.jsonl":
+			_, _ = w.Write([]byte(`{"prompt": "I feel anxious.", "completion": "Let's talk about it."}` + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defaultClient := datasetSampleClient
+	datasetSampleClient = server.Client()
+	defer func() { datasetSampleClient = defaultClient }()
+
+	testcases := map[string]struct {
+		urls            []string
+		validateDataset bool
+		expectedError   string
+	}{
+		"Well-formed dataset": {
+			urls:            []string{server.URL + "/valid.jsonl"},
+			validateDataset: true,
+		},
+		"Malformed dataset": {
+			urls:            []string{server.URL + "/valid.jsonl", server.URL + "/malformed.jsonl"},
+			validateDataset: true,
+			expectedError:   "the dataset " + server.URL + "/malformed.jsonl does not match the tuning job: record 1 has no Context",
+		},
+		"Missing dataset": {
+			urls:            []string{server.URL + "/missing.csv"},
+			validateDataset: true,
+			expectedError:   "failed to sample the dataset " + server.URL + "/missing.csv: status code 404",
+		},
+		"Dataset in a format that cannot be sampled": {
+			urls:            []string{server.URL + "/missing.parquet"},
+			validateDataset: true,
+		},
+		"Dataset is not validated unless asked for": {
+			urls: []string{server.URL + "/malformed.jsonl"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Tuning = &kaitov1alpha1.TuningSpec{
+				Method:          kaitov1alpha1.TuningMethodLora,
+				Input:           &kaitov1alpha1.DataSource{URLs: tc.urls},
+				ValidateDataset: tc.validateDataset,
+			}
+
+			err := ValidateInputDataset(context.Background(), workspace, DefaultDatasetColumns)
+			if tc.expectedError == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "expected error %q, got %v", tc.expectedError, err)
+		})
+	}
+}
+
+func TestSampleDatasetOfPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Context": "I feel anxious.", "Response": "Let's talk about it."}` + "\n"))
+	}))
+	defer server.Close()
+
+	_, err := sampleDataset(context.Background(), server.URL+"/valid.jsonl")
+	assert.Check(t, err != nil && strings.Contains(err.Error(), "the address 127.0.0.1 is not public"),
+		"expected the private address to be rejected, got %v", err)
+}
+
+func TestGetDatasetColumns(t *testing.T) {
+	testcases := map[string]struct {
+		config          string
+		trainingConfig  string
+		expectedColumns DatasetColumns
+		expectedError   string
+	}{
+		"Default ConfigMap": {
+			expectedColumns: DefaultDatasetColumns,
+		},
+		"ConfigMap with the columns": {
+			config: "custom-params",
+			trainingConfig: `training_config:
+  DatasetConfig:
+    shuffle_dataset: true
+    context_column: "question"
+    response_column: "answer"
+`,
+			expectedColumns: DatasetColumns{Context: "question", Response: "answer"},
+		},
+		"ConfigMap without the columns": {
+			config: "custom-params",
+			trainingConfig: `training_config:
+  DatasetConfig:
+    shuffle_dataset: true
+`,
+			expectedColumns: DefaultDatasetColumns,
+		},
+		"ConfigMap that is not YAML": {
+			config:         "custom-params",
+			trainingConfig: "training_config: [",
+			expectedError:  "failed to parse training_config.yaml of the tuning ConfigMap custom-params",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.CreateOrUpdateObjectInMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom-params", Namespace: utils.MockWorkspaceWithPreset.Namespace},
+				Data:       map[string]string{TuningConfigFile: tc.trainingConfig},
+			})
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.ConfigMap{}), mock.Anything).Return(nil)
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Tuning = &kaitov1alpha1.TuningSpec{Method: kaitov1alpha1.TuningMethodLora, Config: tc.config}
+
+			columns, err := GetDatasetColumns(context.Background(), workspace, mockClient)
+			if tc.expectedError != "" {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "expected error %q, got %v", tc.expectedError, err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, columns, tc.expectedColumns)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package tuning
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultOutputVolumePath = "/mnt/output"
+	ImagePushSecretPath     = "/etc/registry-auth"
+
+	// OrasImage is used to push the tuning output as an OCI image.
+	OrasImage = "ghcr.io/oras-project/oras:v1.1.0"
+	// AzureCLIImage is used to upload the tuning output to an object storage URL.
+	AzureCLIImage = "mcr.microsoft.com/azure-cli:2.55.0"
+)
+
+// configOutputVolume returns the volume the tuning output is written to, which is the host path if specified.
+func configOutputVolume(workspaceObj *kaitov1alpha1.Workspace) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: kaitov1alpha1.OutputVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+	if output := workspaceObj.Tuning.Output; output != nil && output.HostPath != "" {
+		volume.VolumeSource = corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: output.HostPath,
+			},
+		}
+	}
+
+	volumeMount := corev1.VolumeMount{
+		Name:      volume.Name,
+		MountPath: DefaultOutputVolumePath,
+	}
+	return volume, volumeMount
+}
+
+// prepareUploadContainers returns the containers that upload the tuning output to the image and/or the URL
+// specified in the workspace, along with the volumes they need.
+func prepareUploadContainers(workspaceObj *kaitov1alpha1.Workspace, outputVolumeMount corev1.VolumeMount) ([]corev1.Container, []corev1.Volume) {
+	var containers []corev1.Container
+	var volumes []corev1.Volume
+
+	output := workspaceObj.Tuning.Output
+	if output == nil {
+		return containers, volumes
+	}
+
+	if output.Image != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: kaitov1alpha1.ImagePushSecretVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: output.ImagePushSecret,
+				},
+			},
+		})
+		registryConfig := filepath.Join(ImagePushSecretPath, corev1.DockerConfigJsonKey)
+		containers = append(containers, corev1.Container{
+			Name:  "image-uploader",
+			Image: OrasImage,
+			Command: utils.ShellCmd(fmt.Sprintf("cd %s && oras push --registry-config %s %s .",
+				outputVolumeMount.MountPath, registryConfig, output.Image)),
+			VolumeMounts: []corev1.VolumeMount{
+				outputVolumeMount,
+				{
+					Name:      kaitov1alpha1.ImagePushSecretVolumeName,
+					MountPath: ImagePushSecretPath,
+					ReadOnly:  true,
+				},
+			},
+		})
+	}
+
+	if output.URL != "" {
+		containers = append(containers, corev1.Container{
+			Name:  "url-uploader",
+			Image: AzureCLIImage,
+			Command: utils.ShellCmd(fmt.Sprintf("az storage copy --source '%s/*' --destination '%s' --recursive",
+				outputVolumeMount.MountPath, output.URL)),
+			EnvFrom: []corev1.EnvFromSource{
+				{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: output.URLSecret},
+					},
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{outputVolumeMount},
+		})
+	}
+
+	return containers, volumes
+}
+
+// checkOutputSecrets returns an error if a secret referenced by the tuning output does not exist.
+func checkOutputSecrets(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) error {
+	output := workspaceObj.Tuning.Output
+	if output == nil {
+		return nil
+	}
+
+	var secretNames []string
+	if output.Image != "" {
+		secretNames = append(secretNames, output.ImagePushSecret)
+	}
+	if output.URL != "" {
+		secretNames = append(secretNames, output.URLSecret)
+	}
+	for _, name := range secretNames {
+		secret := &corev1.Secret{}
+		if err := resources.GetResource(ctx, name, workspaceObj.Namespace, kubeClient, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("secret %s referenced by the tuning output is not found", name)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package tuning
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestCreatePresetTuningWithOutput(t *testing.T) {
+	testcases := map[string]struct {
+		output            *kaitov1alpha1.DataDestination
+		secretErr         error
+		expectedUploader  string
+		expectedImage     string
+		expectedCommand   string
+		expectedSecretRef string
+		expectedError     string
+	}{
+		"Image output pushes the adapter as an OCI image": {
+			output:           &kaitov1alpha1.DataDestination{Image: "myregistry.azurecr.io/adapter:0.0.1", ImagePushSecret: "push-secret"},
+			expectedUploader: "image-uploader",
+			expectedImage:    OrasImage,
+			expectedCommand:  "oras push --registry-config /etc/registry-auth/.dockerconfigjson myregistry.azurecr.io/adapter:0.0.1 .",
+		},
+		"URL output uploads the adapter to object storage": {
+			output:            &kaitov1alpha1.DataDestination{URL: "https://account.blob.core.windows.net/adapters", URLSecret: "storage-secret"},
+			expectedUploader:  "url-uploader",
+			expectedImage:     AzureCLIImage,
+			expectedCommand:   "--destination 'https://account.blob.core.windows.net/adapters'",
+			expectedSecretRef: "storage-secret",
+		},
+		"Missing credentials of the destination are rejected": {
+			output:        &kaitov1alpha1.DataDestination{URL: "https://account.blob.core.windows.net/adapters", URLSecret: "storage-secret"},
+			secretErr:     apierrors.NewNotFound(corev1.Resource("secrets"), "storage-secret"),
+			expectedError: "secret storage-secret referenced by the tuning output is not found",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&corev1.Secret{}), mock.Anything).Return(tc.secretErr)
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&batchv1.Job{}), mock.Anything).Return(nil)
+
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference = nil
+			workspace.Tuning = &kaitov1alpha1.TuningSpec{
+				Preset: &kaitov1alpha1.PresetSpec{PresetMeta: kaitov1alpha1.PresetMeta{Name: "test-model"}},
+				Method: kaitov1alpha1.TuningMethodLora,
+				Output: tc.output,
+			}
+			tuningParam := &model.PresetParam{
+				GPUCountRequirement: "1",
+				BaseCommand:         "accelerate launch",
+				Tag:                 "0.0.1",
+			}
+
+			obj, err := CreatePresetTuning(context.Background(), workspace, tuningParam, mockClient)
+			if tc.expectedError != "" {
+				assert.Equal(t, err.Error(), tc.expectedError)
+				mockClient.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.Check(t, err == nil, "Not expected to return error")
+
+			podSpec := obj.(*batchv1.Job).Spec.Template.Spec
+			// The tuning runs before the upload.
+			assert.Equal(t, len(podSpec.InitContainers), 1)
+			assert.Equal(t, podSpec.InitContainers[0].Name, workspace.Name)
+			assert.Equal(t, len(podSpec.Containers), 1)
+
+			uploader := podSpec.Containers[0]
+			assert.Equal(t, uploader.Name, tc.expectedUploader)
+			assert.Equal(t, uploader.Image, tc.expectedImage)
+			assert.Check(t, strings.Contains(uploader.Command[2], tc.expectedCommand),
+				"Expected command %s to contain %s", uploader.Command[2], tc.expectedCommand)
+			assert.Equal(t, uploader.VolumeMounts[0].MountPath, DefaultOutputVolumePath)
+			if tc.expectedSecretRef != "" {
+				assert.Equal(t, uploader.EnvFrom[0].SecretRef.Name, tc.expectedSecretRef)
+			}
+		})
+	}
+}
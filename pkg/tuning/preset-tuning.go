@@ -2,13 +2,202 @@ package tuning
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"time"
+
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/machine"
 	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/resources"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/azure/kaito/pkg/utils/plugin"
+	"github.com/samber/lo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	TuningFile = "fine_tuning_api.py"
+
+	// OutputUploadPollInterval is the interval a completed tuning Job is checked at while its output is uploaded.
+	OutputUploadPollInterval = 30 * time.Second
+)
+
+var (
+	tolerations = []corev1.Toleration{
+		{
+			Effect:   corev1.TaintEffectNoSchedule,
+			Operator: corev1.TolerationOpEqual,
+			Key:      resources.GPUString,
+		},
+		{
+			Effect: corev1.TaintEffectNoSchedule,
+			Value:  resources.GPUString,
+			Key:    "sku",
+		},
+	}
+)
+
+func GetTuningImageInfo(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, presetObj *model.PresetParam) (string, []corev1.LocalObjectReference) {
+	imagePullSecretRefs := []corev1.LocalObjectReference{}
+	if presetObj.ImageAccessMode == "private" {
+		imageName := workspaceObj.Tuning.Preset.PresetOptions.Image
+		for _, secretName := range workspaceObj.Tuning.Preset.PresetOptions.ImagePullSecrets {
+			imagePullSecretRefs = append(imagePullSecretRefs, corev1.LocalObjectReference{Name: secretName})
+		}
+		return imageName, imagePullSecretRefs
+	}
+	imageName, _ := plugin.KaitoModelRegister.Lookup(string(workspaceObj.Tuning.Preset.Name))
+	imageTag := presetObj.Tag
+	if workspaceObj.Tuning.Preset.Version != "" {
+		imageTag = workspaceObj.Tuning.Preset.Version
+	}
+	registryName := os.Getenv("PRESET_REGISTRY_NAME")
+	return fmt.Sprintf("%s/kaito-tuning-%s:%s", registryName, imageName, imageTag), imagePullSecretRefs
+}
+
+// CreatePresetTuning creates the Job that runs the tuning of the workspace preset.
 func CreatePresetTuning(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace,
 	tuningObj *model.PresetParam, kubeClient client.Client) (client.Object, error) {
-	// TODO
-	return nil, nil
+	if err := checkOutputSecrets(ctx, workspaceObj, kubeClient); err != nil {
+		return nil, err
+	}
+
+	jobObj := GeneratePresetTuningManifest(ctx, workspaceObj, tuningObj)
+	err := resources.CreateResource(ctx, jobObj, kubeClient)
+	if client.IgnoreAlreadyExists(err) != nil {
+		return nil, err
+	}
+	return jobObj, nil
+}
+
+// GeneratePresetTuningManifest returns the Job that runs the tuning of the workspace preset.
+func GeneratePresetTuningManifest(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, tuningObj *model.PresetParam) *batchv1.Job {
+	var volumes []corev1.Volume
+	var volumeMounts []corev1.VolumeMount
+	volume, volumeMount := utils.ConfigSHMVolume(workspaceObj)
+	if volume.Name != "" {
+		volumes = append(volumes, volume)
+	}
+	if volumeMount.Name != "" {
+		volumeMounts = append(volumeMounts, volumeMount)
+	}
+	// The checkpoints and the final output share the same directory, the checkpoint volume is used if specified.
+	outputVolume, outputVolumeMount := utils.ConfigCheckpointVolume(workspaceObj)
+	if outputVolume.Name == "" {
+		outputVolume, outputVolumeMount = configOutputVolume(workspaceObj)
+	}
+	volumes = append(volumes, outputVolume)
+	volumeMounts = append(volumeMounts, outputVolumeMount)
+	// The volumes of the workspace, e.g., with shared datasets, are mounted into the tuning container.
+	volumes = append(volumes, workspaceObj.Tuning.Volumes...)
+	volumeMounts = append(volumeMounts, workspaceObj.Tuning.VolumeMounts...)
+
+	uploadContainers, uploadVolumes := prepareUploadContainers(workspaceObj, outputVolumeMount)
+	volumes = append(volumes, uploadVolumes...)
+
+	commands, resourceReq := prepareTuningParameters(ctx, workspaceObj, tuningObj, outputVolumeMount.MountPath)
+	image, imagePullSecrets := GetTuningImageInfo(ctx, workspaceObj, tuningObj)
+
+	jobObj := resources.GenerateTuningJobManifest(ctx, workspaceObj, image, imagePullSecrets, commands, resourceReq,
+		tolerations, volumes, volumeMounts, uploadContainers)
+	utils.ConfigModelCacheVolume(workspaceObj, &jobObj.Spec.Template.Spec)
+	return jobObj
+}
+
+// prepareTuningParameters builds the command:
+// <BaseCommand> <TORCH_PARAMS> fine_tuning_api.py <MODEL_PARAMS>
+// and sets the GPU resources required for tuning.
+// The checkpoints and the output are written to the output directory and, when resume is requested,
+// the run continues from the latest checkpoint found in it.
+func prepareTuningParameters(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, tuningObj *model.PresetParam,
+	outputDir string) ([]string, corev1.ResourceRequirements) {
+	modelRunParams := make(map[string]string, len(tuningObj.ModelRunParams))
+	for key, value := range tuningObj.ModelRunParams {
+		modelRunParams[key] = value
+	}
+	modelRunParams["output_dir"] = outputDir
+	if workspaceObj.Tuning.Resume && workspaceObj.Tuning.Checkpoint != nil {
+		modelRunParams["resume_from_checkpoint"] = "True"
+	}
+
+	torchCommand := utils.BuildCmdStr(tuningObj.BaseCommand, tuningObj.TorchRunParams)
+	modelCommand := utils.BuildCmdStr(TuningFile, modelRunParams)
+	commands := utils.ShellCmd(torchCommand + " " + modelCommand)
+
+	resourceRequirements := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceName(resources.CapacityNvidiaGPU): resource.MustParse(tuningObj.GPUCountRequirement),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceName(resources.CapacityNvidiaGPU): resource.MustParse(tuningObj.GPUCountRequirement),
+		},
+	}
+
+	return commands, resourceRequirements
+}
+
+// WatchTuningJobCompletion checks the tuning Job of the workspace. Once the Job succeeds, the machines of the
+// workspace are deleted to release the GPU nodes and true is returned. A failed Job is reported as an error
+// and the machines are kept, so that the failure can be investigated and the Job retried.
+// The machines of a succeeded Job are kept until its cleanup is due, see cleanupWait; the returned duration is
+// the time after which the Job is checked again, it is zero once the machines are released.
+func WatchTuningJobCompletion(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, kubeClient client.Client) (bool, time.Duration, error) {
+	jobObj := &batchv1.Job{}
+	if err := resources.GetResource(ctx, workspaceObj.Name, workspaceObj.Namespace, kubeClient, jobObj); err != nil {
+		return false, 0, err
+	}
+
+	for _, condition := range jobObj.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobFailed:
+			return false, 0, fmt.Errorf("tuning job %s failed: %s", jobObj.Name, condition.Message)
+		case batchv1.JobComplete:
+			if wait := cleanupWait(workspaceObj, jobObj, condition.LastTransitionTime.Time, time.Now()); wait > 0 {
+				klog.InfoS("tuning job completed, keeping the machines until the cleanup is due", "workspace", klog.KObj(workspaceObj),
+					"wait", wait)
+				return true, wait, nil
+			}
+			klog.InfoS("tuning job completed, releasing the machines", "workspace", klog.KObj(workspaceObj))
+			machines, err := machine.ListMachinesByWorkspace(ctx, workspaceObj, kubeClient)
+			if err != nil {
+				return false, 0, err
+			}
+			for i := range machines.Items {
+				if err := machine.DeleteMachine(ctx, &machines.Items[i], kubeClient); client.IgnoreNotFound(err) != nil {
+					klog.ErrorS(err, "failed to delete the machine", "machine", klog.KObj(&machines.Items[i]))
+					return false, 0, err
+				}
+			}
+			return true, 0, nil
+		}
+	}
+	return false, 0, nil
+}
+
+// cleanupWait returns how long the machines of the completed tuning Job are kept, e.g., until an upload of the output
+// that runs outside of the Job finishes. The machines are kept while the Job is annotated with an upload in progress,
+// and released once it is annotated with a complete upload. Otherwise, they are kept for the cleanup delay of the
+// workspace after the Job completed.
+func cleanupWait(workspaceObj *kaitov1alpha1.Workspace, jobObj *batchv1.Job, completedAt, now time.Time) time.Duration {
+	switch jobObj.Annotations[kaitov1alpha1.AnnotationOutputUpload] {
+	case kaitov1alpha1.OutputUploadComplete:
+		return 0
+	case kaitov1alpha1.OutputUploadInProgress:
+		return OutputUploadPollInterval
+	}
+	if workspaceObj.Tuning == nil || workspaceObj.Tuning.CleanupDelay == nil {
+		return 0
+	}
+	if jobObj.Status.CompletionTime != nil {
+		completedAt = jobObj.Status.CompletionTime.Time
+	}
+	return lo.Max([]time.Duration{completedAt.Add(workspaceObj.Tuning.CleanupDelay.Duration).Sub(now), 0})
 }
@@ -0,0 +1,239 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package tuning
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/azure/kaito/pkg/model"
+	"github.com/azure/kaito/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	"gotest.tools/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestWatchTuningJobCompletion(t *testing.T) {
+	testcases := map[string]struct {
+		jobCondition      batchv1.JobConditionType
+		completedAgo      time.Duration
+		cleanupDelay      *metav1.Duration
+		uploadAnnotation  string
+		expectedCompleted bool
+		expectedRequeue   bool
+		expectedError     string
+	}{
+		"Succeeded job releases the machines": {
+			jobCondition:      batchv1.JobComplete,
+			expectedCompleted: true,
+		},
+		"Failed job keeps the machines": {
+			jobCondition:  batchv1.JobFailed,
+			expectedError: "out of memory",
+		},
+		"Succeeded job keeps the machines for the cleanup delay": {
+			jobCondition:      batchv1.JobComplete,
+			completedAgo:      time.Minute,
+			cleanupDelay:      &metav1.Duration{Duration: 10 * time.Minute},
+			expectedCompleted: true,
+			expectedRequeue:   true,
+		},
+		"Succeeded job releases the machines once the cleanup delay has passed": {
+			jobCondition:      batchv1.JobComplete,
+			completedAgo:      time.Hour,
+			cleanupDelay:      &metav1.Duration{Duration: 10 * time.Minute},
+			expectedCompleted: true,
+		},
+		"Succeeded job keeps the machines while the output is uploaded": {
+			jobCondition:      batchv1.JobComplete,
+			completedAgo:      time.Hour,
+			cleanupDelay:      &metav1.Duration{Duration: 10 * time.Minute},
+			uploadAnnotation:  kaitov1alpha1.OutputUploadInProgress,
+			expectedCompleted: true,
+			expectedRequeue:   true,
+		},
+		"Succeeded job releases the machines once the output is uploaded": {
+			jobCondition:      batchv1.JobComplete,
+			completedAgo:      time.Minute,
+			cleanupDelay:      &metav1.Duration{Duration: 10 * time.Minute},
+			uploadAnnotation:  kaitov1alpha1.OutputUploadComplete,
+			expectedCompleted: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Tuning = &kaitov1alpha1.TuningSpec{CleanupDelay: tc.cleanupDelay}
+
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      workspace.Name,
+					Namespace: workspace.Namespace,
+				},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{
+						{
+							Type:    tc.jobCondition,
+							Status:  corev1.ConditionTrue,
+							Message: "out of memory",
+						},
+					},
+				},
+			}
+			if tc.completedAgo > 0 {
+				job.Status.CompletionTime = &metav1.Time{Time: time.Now().Add(-tc.completedAgo)}
+			}
+			if tc.uploadAnnotation != "" {
+				job.Annotations = map[string]string{kaitov1alpha1.AnnotationOutputUpload: tc.uploadAnnotation}
+			}
+			mockClient.CreateOrUpdateObjectInMap(job)
+			machineMap := mockClient.CreateMapWithType(&v1alpha5.MachineList{})
+			machineObj := utils.MockMachine.DeepCopy()
+			machineMap[client.ObjectKeyFromObject(machineObj)] = machineObj
+
+			mockClient.On("Get", mock.IsType(context.Background()), mock.Anything, mock.IsType(&batchv1.Job{}), mock.Anything).Return(nil)
+			mockClient.On("List", mock.IsType(context.Background()), mock.IsType(&v1alpha5.MachineList{}), mock.Anything).Return(nil)
+			mockClient.On("Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything).Return(nil)
+
+			completed, requeueAfter, err := WatchTuningJobCompletion(context.Background(), workspace, mockClient)
+			assert.Equal(t, completed, tc.expectedCompleted)
+			assert.Equal(t, requeueAfter > 0, tc.expectedRequeue)
+			if tc.expectedError == "" {
+				assert.Check(t, err == nil, "Not expected to return error")
+			} else {
+				assert.Check(t, err != nil && strings.Contains(err.Error(), tc.expectedError), "Expected the job failure to be returned")
+			}
+			if tc.expectedError == "" && !tc.expectedRequeue {
+				mockClient.AssertCalled(t, "Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything)
+			} else {
+				mockClient.AssertNotCalled(t, "Delete", mock.IsType(context.Background()), mock.IsType(&v1alpha5.Machine{}), mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreatePresetTuningWithCheckpoint(t *testing.T) {
+	testcases := map[string]struct {
+		resume          bool
+		createErr       error
+		expectedCommand string
+	}{
+		"Mount checkpoint volume for a new run": {
+			expectedCommand: "--output_dir=/mnt/checkpoints",
+		},
+		"Resume from the latest checkpoint": {
+			resume:          true,
+			expectedCommand: "--resume_from_checkpoint=True",
+		},
+		"Keep the existing job and its checkpoint volume on restart": {
+			resume:          true,
+			createErr:       apierrors.NewAlreadyExists(batchv1.Resource("jobs"), "testWorkspace"),
+			expectedCommand: "--resume_from_checkpoint=True",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mockClient := utils.NewClient()
+			mockClient.On("Create", mock.IsType(context.Background()), mock.IsType(&batchv1.Job{}), mock.Anything).Return(tc.createErr)
+
+			workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+			workspace.Inference = nil
+			workspace.Tuning = &kaitov1alpha1.TuningSpec{
+				Preset:     &kaitov1alpha1.PresetSpec{PresetMeta: kaitov1alpha1.PresetMeta{Name: "test-model"}},
+				Method:     kaitov1alpha1.TuningMethodLora,
+				Checkpoint: &kaitov1alpha1.CheckpointSpec{PersistentVolumeClaim: "checkpoints"},
+				Resume:     tc.resume,
+			}
+			tuningParam := &model.PresetParam{
+				GPUCountRequirement: "1",
+				BaseCommand:         "accelerate launch",
+				Tag:                 "0.0.1",
+			}
+
+			obj, err := CreatePresetTuning(context.Background(), workspace, tuningParam, mockClient)
+			assert.Check(t, err == nil, "Not expected to return error")
+			mockClient.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+
+			job := obj.(*batchv1.Job)
+			podSpec := job.Spec.Template.Spec
+			assert.Equal(t, len(podSpec.Volumes), 1)
+			assert.Check(t, podSpec.Volumes[0].PersistentVolumeClaim != nil, "Expected the checkpoint volume to be backed by a persistent volume claim")
+			assert.Equal(t, podSpec.Volumes[0].PersistentVolumeClaim.ClaimName, "checkpoints")
+			assert.Equal(t, podSpec.Containers[0].VolumeMounts[0].MountPath, kaitov1alpha1.DefaultCheckpointMountPath)
+			assert.Check(t, strings.Contains(podSpec.Containers[0].Command[2], tc.expectedCommand),
+				"Expected command %s to contain %s", podSpec.Containers[0].Command[2], tc.expectedCommand)
+			if !tc.resume {
+				assert.Check(t, !strings.Contains(podSpec.Containers[0].Command[2], "resume_from_checkpoint"))
+			}
+		})
+	}
+}
+
+func TestGeneratePresetTuningManifestWithVolumes(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference = nil
+	workspace.Tuning = &kaitov1alpha1.TuningSpec{
+		Preset: &kaitov1alpha1.PresetSpec{PresetMeta: kaitov1alpha1.PresetMeta{Name: "test-model"}},
+		Method: kaitov1alpha1.TuningMethodLora,
+		Volumes: []corev1.Volume{{
+			Name:         "datasets",
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shared-datasets"}},
+		}},
+		VolumeMounts: []corev1.VolumeMount{{Name: "datasets", MountPath: "/mnt/datasets", ReadOnly: true}},
+	}
+	tuningParam := &model.PresetParam{
+		GPUCountRequirement: "1",
+		BaseCommand:         "accelerate launch",
+		Tag:                 "0.0.1",
+	}
+
+	job := GeneratePresetTuningManifest(context.Background(), workspace, tuningParam)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, podSpec.Volumes[len(podSpec.Volumes)-1].Name, "datasets")
+	assert.Equal(t, podSpec.Volumes[len(podSpec.Volumes)-1].PersistentVolumeClaim.ClaimName, "shared-datasets")
+	mounts := podSpec.Containers[0].VolumeMounts
+	assert.DeepEqual(t, mounts[len(mounts)-1], corev1.VolumeMount{Name: "datasets", MountPath: "/mnt/datasets", ReadOnly: true})
+}
+
+func TestGeneratePresetTuningManifestWithModelCache(t *testing.T) {
+	workspace := utils.MockWorkspaceWithPreset.DeepCopy()
+	workspace.Inference = nil
+	workspace.ModelCache = &kaitov1alpha1.ModelCacheSpec{PersistentVolumeClaim: "hf-cache"}
+	workspace.Tuning = &kaitov1alpha1.TuningSpec{
+		Preset: &kaitov1alpha1.PresetSpec{PresetMeta: kaitov1alpha1.PresetMeta{Name: "test-model"}},
+		Method: kaitov1alpha1.TuningMethodLora,
+		Output: &kaitov1alpha1.DataDestination{URL: "https://account.blob.core.windows.net/outputs/run"},
+	}
+	tuningParam := &model.PresetParam{
+		GPUCountRequirement: "1",
+		BaseCommand:         "accelerate launch",
+		Tag:                 "0.0.1",
+	}
+
+	// The tuning runs as an init container before the output is uploaded.
+	job := GeneratePresetTuningManifest(context.Background(), workspace, tuningParam)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, len(podSpec.InitContainers), 1)
+	volume := podSpec.Volumes[len(podSpec.Volumes)-1]
+	assert.Equal(t, volume.Name, kaitov1alpha1.ModelCacheVolumeName)
+	assert.Equal(t, volume.PersistentVolumeClaim.ClaimName, "hf-cache")
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		assert.DeepEqual(t, container.VolumeMounts[len(container.VolumeMounts)-1],
+			corev1.VolumeMount{Name: kaitov1alpha1.ModelCacheVolumeName, MountPath: kaitov1alpha1.DefaultModelCacheMountPath})
+		assert.DeepEqual(t, container.Env, []corev1.EnvVar{
+			{Name: utils.EnvHFHome, Value: kaitov1alpha1.DefaultModelCacheMountPath},
+			{Name: utils.EnvTransformersCache, Value: kaitov1alpha1.DefaultModelCacheMountPath + "/hub"},
+		})
+	}
+}
@@ -4,12 +4,19 @@ package utils
 
 import (
 	"fmt"
+	"path"
+
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 )
 
 const (
 	DefaultVolumeMountPath = "/dev/shm"
+
+	// The environment variables that point the Hugging Face libraries to the cache.
+	EnvHFHome            = "HF_HOME"
+	EnvTransformersCache = "TRANSFORMERS_CACHE"
 )
 
 func ConfigSHMVolume(wObj *kaitov1alpha1.Workspace) (corev1.Volume, corev1.VolumeMount) {
@@ -17,10 +24,10 @@ func ConfigSHMVolume(wObj *kaitov1alpha1.Workspace) (corev1.Volume, corev1.Volum
 	volumeMount := corev1.VolumeMount{}
 
 	// Signifies multinode inference requirement
-	if *wObj.Resource.Count > 1 {
+	if lo.FromPtr(wObj.Resource.Count) > 1 {
 		// Append share memory volume to any existing volumes
 		volume = corev1.Volume{
-			Name: "dshm",
+			Name: kaitov1alpha1.SHMVolumeName,
 			VolumeSource: corev1.VolumeSource{
 				EmptyDir: &corev1.EmptyDirVolumeSource{
 					Medium: "Memory",
@@ -37,18 +44,82 @@ func ConfigSHMVolume(wObj *kaitov1alpha1.Workspace) (corev1.Volume, corev1.Volum
 	return volume, volumeMount
 }
 
+// ConfigCheckpointVolume mounts the persistent volume claim used to store the tuning checkpoints.
+// The claim is owned by the user, so the checkpoints survive restarts of the tuning pod.
+func ConfigCheckpointVolume(wObj *kaitov1alpha1.Workspace) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{}
+	volumeMount := corev1.VolumeMount{}
+
+	if wObj.Tuning != nil && wObj.Tuning.Checkpoint != nil {
+		volume = corev1.Volume{
+			Name: kaitov1alpha1.CheckpointVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: wObj.Tuning.Checkpoint.PersistentVolumeClaim,
+				},
+			},
+		}
+
+		mountPath := wObj.Tuning.Checkpoint.MountPath
+		if mountPath == "" {
+			mountPath = kaitov1alpha1.DefaultCheckpointMountPath
+		}
+		volumeMount = corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: mountPath,
+		}
+	}
+
+	return volume, volumeMount
+}
+
+// ConfigModelCacheVolume mounts the Hugging Face cache of the workspace into all containers and init containers of
+// the pod, and points HF_HOME and TRANSFORMERS_CACHE to it, so that the inference, tuning and the containers that
+// load the models before them share the downloaded models. The pod is not changed if the workspace has no cache.
+func ConfigModelCacheVolume(wObj *kaitov1alpha1.Workspace, podSpec *corev1.PodSpec) {
+	if wObj.ModelCache == nil {
+		return
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: kaitov1alpha1.ModelCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: wObj.ModelCache.PersistentVolumeClaim,
+			},
+		},
+	})
+
+	mountPath := wObj.ModelCache.GetMountPath()
+	volumeMount := corev1.VolumeMount{
+		Name:      kaitov1alpha1.ModelCacheVolumeName,
+		MountPath: mountPath,
+	}
+	env := []corev1.EnvVar{
+		{Name: EnvHFHome, Value: mountPath},
+		{Name: EnvTransformersCache, Value: path.Join(mountPath, "hub")},
+	}
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			containers[i].VolumeMounts = append(containers[i].VolumeMounts, volumeMount)
+			containers[i].Env = append(lo.Reject(containers[i].Env, func(e corev1.EnvVar, _ int) bool {
+				return e.Name == EnvHFHome || e.Name == EnvTransformersCache
+			}), env...)
+		}
+	}
+}
+
 func ConfigDataVolume() ([]corev1.Volume, []corev1.VolumeMount) {
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
 	volumes = append(volumes, corev1.Volume{
-		Name: "data-volume",
+		Name: kaitov1alpha1.DataVolumeName,
 		VolumeSource: corev1.VolumeSource{
 			EmptyDir: &corev1.EmptyDirVolumeSource{},
 		},
 	})
 
 	volumeMounts = append(volumeMounts, corev1.VolumeMount{
-		Name:      "data-volume",
+		Name:      kaitov1alpha1.DataVolumeName,
 		MountPath: "/data",
 	})
 	return volumes, volumeMounts
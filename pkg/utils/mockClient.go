@@ -8,8 +8,11 @@ import (
 	"reflect"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/azure/kaito/api/v1alpha1"
 	"github.com/stretchr/testify/mock"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -112,6 +115,78 @@ func (m *MockClient) getObjectListFromMap(list k8sClient.ObjectList) k8sClient.O
 			}
 		}
 		return machineList
+	case *corev1.PodList:
+		podList := &corev1.PodList{}
+		for _, obj := range relevantMap {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				podList.Items = append(podList.Items, *pod)
+			}
+		}
+		return podList
+	case *corev1.EventList:
+		eventList := &corev1.EventList{}
+		for _, obj := range relevantMap {
+			if event, ok := obj.(*corev1.Event); ok {
+				eventList.Items = append(eventList.Items, *event)
+			}
+		}
+		return eventList
+	case *appsv1.DeploymentList:
+		deploymentList := &appsv1.DeploymentList{}
+		for _, obj := range relevantMap {
+			if deployment, ok := obj.(*appsv1.Deployment); ok {
+				deploymentList.Items = append(deploymentList.Items, *deployment)
+			}
+		}
+		return deploymentList
+	case *appsv1.StatefulSetList:
+		statefulSetList := &appsv1.StatefulSetList{}
+		for _, obj := range relevantMap {
+			if statefulSet, ok := obj.(*appsv1.StatefulSet); ok {
+				statefulSetList.Items = append(statefulSetList.Items, *statefulSet)
+			}
+		}
+		return statefulSetList
+	case *corev1.ServiceList:
+		serviceList := &corev1.ServiceList{}
+		for _, obj := range relevantMap {
+			if service, ok := obj.(*corev1.Service); ok {
+				serviceList.Items = append(serviceList.Items, *service)
+			}
+		}
+		return serviceList
+	case *networkingv1.NetworkPolicyList:
+		networkPolicyList := &networkingv1.NetworkPolicyList{}
+		for _, obj := range relevantMap {
+			if networkPolicy, ok := obj.(*networkingv1.NetworkPolicy); ok {
+				networkPolicyList.Items = append(networkPolicyList.Items, *networkPolicy)
+			}
+		}
+		return networkPolicyList
+	case *v1alpha1.WorkspaceList:
+		workspaceList := &v1alpha1.WorkspaceList{}
+		for _, obj := range relevantMap {
+			if ws, ok := obj.(*v1alpha1.Workspace); ok {
+				workspaceList.Items = append(workspaceList.Items, *ws)
+			}
+		}
+		return workspaceList
+	case *v1alpha1.GPUQuotaList:
+		quotaList := &v1alpha1.GPUQuotaList{}
+		for _, obj := range relevantMap {
+			if q, ok := obj.(*v1alpha1.GPUQuota); ok {
+				quotaList.Items = append(quotaList.Items, *q)
+			}
+		}
+		return quotaList
+	case *corev1.ResourceQuotaList:
+		resourceQuotaList := &corev1.ResourceQuotaList{}
+		for _, obj := range relevantMap {
+			if q, ok := obj.(*corev1.ResourceQuota); ok {
+				resourceQuotaList.Items = append(resourceQuotaList.Items, *q)
+			}
+		}
+		return resourceQuotaList
 	}
 	//add additional object lists as needed
 	return nil
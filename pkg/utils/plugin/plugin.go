@@ -6,16 +6,22 @@ import (
 	"sync"
 
 	"github.com/azure/kaito/pkg/model"
+	"github.com/samber/lo"
 )
 
 type Registration struct {
 	Name     string
 	Instance model.Model
+	// Aliases are alternative names that resolve to the model.
+	Aliases []string
+	// Versions are the model image versions that can be pinned besides the default image tag of the model.
+	Versions []string
 }
 
 type ModelRegister struct {
 	sync.RWMutex
-	models map[string]*Registration
+	models  map[string]*Registration
+	aliases map[string]string
 }
 
 var KaitoModelRegister ModelRegister
@@ -31,15 +37,40 @@ func (reg *ModelRegister) Register(r *Registration) {
 	if reg.models == nil {
 		reg.models = make(map[string]*Registration)
 	}
+	if reg.aliases == nil {
+		reg.aliases = make(map[string]string)
+	}
 
 	reg.models[r.Name] = r
+	for _, alias := range r.Aliases {
+		reg.aliases[alias] = r.Name
+	}
+}
+
+// lookup returns the registration of the model name or alias. The caller must hold the lock.
+func (reg *ModelRegister) lookup(name string) (*Registration, bool) {
+	if canonicalName, ok := reg.aliases[name]; ok {
+		name = canonicalName
+	}
+	r, ok := reg.models[name]
+	return r, ok
+}
+
+// Lookup resolves a model name or alias to the canonical model name.
+func (reg *ModelRegister) Lookup(name string) (string, bool) {
+	reg.Lock()
+	defer reg.Unlock()
+	if r, ok := reg.lookup(name); ok {
+		return r.Name, true
+	}
+	return "", false
 }
 
 func (reg *ModelRegister) MustGet(name string) model.Model {
 	reg.Lock()
 	defer reg.Unlock()
-	if _, ok := reg.models[name]; ok {
-		return reg.models[name].Instance
+	if r, ok := reg.lookup(name); ok {
+		return r.Instance
 	}
 	panic("model is not registered")
 }
@@ -57,6 +88,17 @@ func (reg *ModelRegister) ListModelNames() []string {
 func (reg *ModelRegister) Has(name string) bool {
 	reg.Lock()
 	defer reg.Unlock()
-	_, ok := reg.models[name]
+	_, ok := reg.lookup(name)
 	return ok
 }
+
+// HasVersion checks if the model image version can be pinned for the model name or alias.
+func (reg *ModelRegister) HasVersion(name, version string) bool {
+	reg.Lock()
+	defer reg.Unlock()
+	r, ok := reg.lookup(name)
+	if !ok {
+		return false
+	}
+	return version == r.Instance.GetInferenceParameters().Tag || lo.Contains(r.Versions, version)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package plugin
+
+import (
+	"testing"
+
+	"github.com/azure/kaito/pkg/model"
+	"gotest.tools/assert"
+)
+
+type testModel struct{}
+
+func (*testModel) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		Tag: "0.0.2",
+	}
+}
+func (*testModel) GetTuningParameters() *model.PresetParam {
+	return &model.PresetParam{}
+}
+func (*testModel) SupportDistributedInference() bool {
+	return false
+}
+func (*testModel) SupportTuning() bool {
+	return false
+}
+
+func TestLookup(t *testing.T) {
+	var reg ModelRegister
+	reg.Register(&Registration{
+		Name:     "test-model-7b",
+		Instance: &testModel{},
+		Aliases:  []string{"test-model"},
+		Versions: []string{"0.0.1"},
+	})
+
+	testcases := map[string]struct {
+		name          string
+		expectedName  string
+		expectedFound bool
+	}{
+		"Canonical name": {
+			name:          "test-model-7b",
+			expectedName:  "test-model-7b",
+			expectedFound: true,
+		},
+		"Alias": {
+			name:          "test-model",
+			expectedName:  "test-model-7b",
+			expectedFound: true,
+		},
+		"Unknown name": {
+			name:          "unknown-model",
+			expectedName:  "",
+			expectedFound: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			name, found := reg.Lookup(tc.name)
+			assert.Equal(t, name, tc.expectedName)
+			assert.Equal(t, found, tc.expectedFound)
+			assert.Equal(t, reg.Has(tc.name), tc.expectedFound)
+		})
+	}
+}
+
+func TestHasVersion(t *testing.T) {
+	var reg ModelRegister
+	reg.Register(&Registration{
+		Name:     "test-model-7b",
+		Instance: &testModel{},
+		Aliases:  []string{"test-model"},
+		Versions: []string{"0.0.1"},
+	})
+
+	assert.Check(t, reg.HasVersion("test-model-7b", "0.0.2"), "The default image tag must be a valid version")
+	assert.Check(t, reg.HasVersion("test-model", "0.0.1"), "A pinned version must be valid through an alias")
+	assert.Check(t, !reg.HasVersion("test-model-7b", "9.9.9"), "An unknown version must not be valid")
+	assert.Check(t, !reg.HasVersion("unknown-model", "0.0.1"), "An unknown model must not have versions")
+}
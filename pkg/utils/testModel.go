@@ -14,8 +14,10 @@ type testModel struct{}
 
 func (*testModel) GetInferenceParameters() *model.PresetParam {
 	return &model.PresetParam{
-		GPUCountRequirement: "1",
-		ReadinessTimeout:    time.Duration(30) * time.Minute,
+		GPUCountRequirement:       "1",
+		TotalGPUMemoryRequirement: "8Gi",
+		PerGPUMemoryRequirement:   "8Gi",
+		ReadinessTimeout:          time.Duration(30) * time.Minute,
 	}
 }
 func (*testModel) GetTuningParameters() *model.PresetParam {
@@ -52,6 +54,76 @@ func (*testDistributedModel) SupportTuning() bool {
 	return true
 }
 
+type testRegionalModel struct {
+	testModel
+}
+
+func (*testRegionalModel) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		GPUCountRequirement: "1",
+		ReadinessTimeout:    time.Duration(30) * time.Minute,
+		AllowedRegions:      []string{"eastus", "westeurope"},
+	}
+}
+
+type testDriverModel struct {
+	testModel
+}
+
+func (*testDriverModel) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		GPUCountRequirement: "1",
+		ReadinessTimeout:    time.Duration(30) * time.Minute,
+		MinDriverVersion:    "535.104.05",
+	}
+}
+
+type testMetricsModel struct {
+	testModel
+}
+
+func (*testMetricsModel) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		GPUCountRequirement: "1",
+		ReadinessTimeout:    time.Duration(30) * time.Minute,
+		MetricsPort:         9090,
+	}
+}
+
+type testLargeModel struct {
+	testModel
+}
+
+func (*testLargeModel) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		GPUCountRequirement:       "2",
+		TotalGPUMemoryRequirement: "28Gi",
+		PerGPUMemoryRequirement:   "14Gi",
+		ReadinessTimeout:          time.Duration(30) * time.Minute,
+	}
+}
+
+type testLargeDistributedModel struct {
+	testLargeModel
+}
+
+func (*testLargeDistributedModel) SupportDistributedInference() bool {
+	return true
+}
+
+type testEmbeddingModel struct {
+	testModel
+}
+
+func (*testEmbeddingModel) GetInferenceParameters() *model.PresetParam {
+	return &model.PresetParam{
+		BaseCommand:      "python3",
+		ModelRunParams:   map[string]string{"port": "5000"},
+		ReadinessTimeout: time.Duration(30) * time.Minute,
+		Embedding:        true,
+	}
+}
+
 func RegisterTestModel() {
 	var test testModel
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
@@ -65,4 +137,40 @@ func RegisterTestModel() {
 		Instance: &testDistributed,
 	})
 
+	var testRegional testRegionalModel
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "test-regional-model",
+		Instance: &testRegional,
+	})
+
+	var testDriver testDriverModel
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "test-driver-model",
+		Instance: &testDriver,
+	})
+
+	var testMetrics testMetricsModel
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "test-metrics-model",
+		Instance: &testMetrics,
+	})
+
+	var testLarge testLargeModel
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "test-large-model",
+		Instance: &testLarge,
+	})
+
+	var testLargeDistributed testLargeDistributedModel
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "test-large-distributed-model",
+		Instance: &testLargeDistributed,
+	})
+
+	var testEmbedding testEmbeddingModel
+	plugin.KaitoModelRegister.Register(&plugin.Registration{
+		Name:     "test-embedding-model",
+		Instance: &testEmbedding,
+	})
+
 }
@@ -0,0 +1,51 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package webhooks
+
+import (
+	"context"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/system"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+)
+
+// skuAllowListStore keeps the latest SKU allow-list of the ConfigMap, which the workspaces are validated against.
+type skuAllowListStore struct {
+	allowList atomic.Value
+}
+
+// newSKUAllowListStore watches the SKU allow-list ConfigMap. The ConfigMap is optional, no namespace is restricted
+// if it does not exist.
+func newSKUAllowListStore(cmw configmap.Watcher) *skuAllowListStore {
+	s := &skuAllowListStore{}
+	s.allowList.Store(kaitov1alpha1.SKUAllowList{})
+	if watcher, ok := cmw.(configmap.DefaultingWatcher); ok {
+		watcher.WatchWithDefault(corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: kaitov1alpha1.SKUAllowListConfigMapName, Namespace: system.Namespace()},
+		}, s.onConfigMapChanged)
+	} else {
+		cmw.Watch(kaitov1alpha1.SKUAllowListConfigMapName, s.onConfigMapChanged)
+	}
+	return s
+}
+
+func (s *skuAllowListStore) onConfigMapChanged(cm *corev1.ConfigMap) {
+	allowList, err := kaitov1alpha1.NewSKUAllowListFromConfigMap(cm)
+	if err != nil {
+		klog.ErrorS(err, "invalid SKU allow-list, keeping the previous one", "configmap", klog.KObj(cm))
+		return
+	}
+	klog.InfoS("updated the SKU allow-list", "configmap", klog.KObj(cm), "namespaces", len(allowList))
+	s.allowList.Store(allowList)
+}
+
+// ToContext adds the SKU allow-list to the context of an admission request.
+func (s *skuAllowListStore) ToContext(ctx context.Context) context.Context {
+	return kaitov1alpha1.WithSKUAllowList(ctx, s.allowList.Load().(kaitov1alpha1.SKUAllowList))
+}
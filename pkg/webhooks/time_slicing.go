@@ -0,0 +1,51 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package webhooks
+
+import (
+	"context"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/system"
+
+	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
+)
+
+// timeSlicingConfigStore keeps the latest time-slicing config of the ConfigMap, which the workspaces are validated against.
+type timeSlicingConfigStore struct {
+	config atomic.Value
+}
+
+// newTimeSlicingConfigStore watches the time-slicing ConfigMap. The ConfigMap is optional, the time-sliced GPUs
+// requested by the workspaces are not checked if it does not exist.
+func newTimeSlicingConfigStore(cmw configmap.Watcher) *timeSlicingConfigStore {
+	s := &timeSlicingConfigStore{}
+	s.config.Store(kaitov1alpha1.TimeSlicingConfig{})
+	if watcher, ok := cmw.(configmap.DefaultingWatcher); ok {
+		watcher.WatchWithDefault(corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: kaitov1alpha1.TimeSlicingConfigMapName, Namespace: system.Namespace()},
+		}, s.onConfigMapChanged)
+	} else {
+		cmw.Watch(kaitov1alpha1.TimeSlicingConfigMapName, s.onConfigMapChanged)
+	}
+	return s
+}
+
+func (s *timeSlicingConfigStore) onConfigMapChanged(cm *corev1.ConfigMap) {
+	config, err := kaitov1alpha1.NewTimeSlicingConfigFromConfigMap(cm)
+	if err != nil {
+		klog.ErrorS(err, "invalid time-slicing config, keeping the previous one", "configmap", klog.KObj(cm))
+		return
+	}
+	klog.InfoS("updated the time-slicing config", "configmap", klog.KObj(cm), "resources", len(config))
+	s.config.Store(config)
+}
+
+// ToContext adds the time-slicing config to the context of an admission request.
+func (s *timeSlicingConfigStore) ToContext(ctx context.Context) context.Context {
+	return kaitov1alpha1.WithTimeSlicingConfig(ctx, s.config.Load().(kaitov1alpha1.TimeSlicingConfig))
+}
@@ -11,6 +11,7 @@ import (
 	knativeinjection "knative.dev/pkg/injection"
 	"knative.dev/pkg/webhook/certificates"
 	"knative.dev/pkg/webhook/resourcesemantics"
+	"knative.dev/pkg/webhook/resourcesemantics/defaulting"
 	"knative.dev/pkg/webhook/resourcesemantics/validation"
 
 	kaitov1alpha1 "github.com/azure/kaito/api/v1alpha1"
@@ -19,16 +20,34 @@ import (
 func NewWebhooks() []knativeinjection.ControllerConstructor {
 	return []knativeinjection.ControllerConstructor{
 		certificates.NewController,
+		NewCRDDefaultingWebhook,
 		NewCRDValidationWebhook,
 	}
 }
 
-func NewCRDValidationWebhook(ctx context.Context, _ configmap.Watcher) *controller.Impl {
+// NewCRDDefaultingWebhook sets the defaults of the workspaces, e.g., the node count of their preset.
+func NewCRDDefaultingWebhook(ctx context.Context, _ configmap.Watcher) *controller.Impl {
+	return defaulting.NewAdmissionController(ctx,
+		"defaulting.workspace.kaito.sh",
+		"/default/workspace.kaito.sh",
+		Resources,
+		func(ctx context.Context) context.Context {
+			return ctx
+		},
+		true,
+	)
+}
+
+func NewCRDValidationWebhook(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	skuAllowList := newSKUAllowListStore(cmw)
+	timeSlicingConfig := newTimeSlicingConfigStore(cmw)
 	return validation.NewAdmissionController(ctx,
 		"validation.workspace.kaito.sh",
 		"/validate/workspace.kaito.sh",
 		Resources,
-		func(ctx context.Context) context.Context { return ctx },
+		func(ctx context.Context) context.Context {
+			return timeSlicingConfig.ToContext(skuAllowList.ToContext(ctx))
+		},
 		true,
 	)
 }
@@ -25,6 +25,8 @@ parser.add_argument("--tokenizer_path", default="weights/tokenizer.model", help=
 parser.add_argument("--max_seq_len", type=int, default=128, help="Maximum sequence length.")
 parser.add_argument("--max_batch_size", type=int, default=4, help="Maximum batch size.")
 parser.add_argument("--model_parallel_size", type=int, default=int(os.environ.get("WORLD_SIZE", 1)), help="Model parallel size.")
+parser.add_argument("--host", default="0.0.0.0", help="Address the HTTP server listens on.")
+parser.add_argument("--port", type=int, default=5000, help="Port the HTTP server listens on.")
 args = parser.parse_args()
 
 should_shutdown = False
@@ -210,8 +212,8 @@ def setup_worker_routes():
             return {"error": str(e)}
 
 def start_worker_server():
-    print(f"Worker {dist.get_rank()} HTTP health server started at port 5000\n")
-    uvicorn.run(app=app_worker, host='0.0.0.0', port=5000)
+    print(f"Worker {dist.get_rank()} HTTP health server started at port {args.port}\n")
+    uvicorn.run(app=app_worker, host=args.host, port=args.port)
 
 def worker_listen_tasks():
     while True:
@@ -257,7 +259,7 @@ if __name__ == "__main__":
         # This is the main server that handles the main logic of our application.
         app_main = FastAPI()
         setup_main_routes()
-        uvicorn.run(app=app_main, host='0.0.0.0', port=5000)  # Use the app_main instance.
+        uvicorn.run(app=app_main, host=args.host, port=args.port)  # Use the app_main instance.
     else:
         # This code is executed by all processes that aren't the globally ranked 0.
         # This includes processes on the main node as well as on other nodes.
@@ -1,6 +1,11 @@
 # Copyright (c) Microsoft Corporation.
 # Licensed under the MIT license.
+import json
 import os
+import threading
+import time
+import urllib.request
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
 from dataclasses import asdict, dataclass, field
 from typing import Annotated, Any, Dict, List, Optional
 
@@ -9,8 +14,9 @@ import psutil
 import torch
 import transformers
 import uvicorn
-from fastapi import Body, FastAPI, HTTPException
-from fastapi.responses import Response
+from fastapi import Body, FastAPI, HTTPException, Request
+from huggingface_hub import snapshot_download
+from fastapi.responses import JSONResponse, Response
 from pydantic import BaseModel, Extra, Field
 from transformers import (AutoModelForCausalLM, AutoTokenizer,
                           GenerationConfig, HfArgumentParser)
@@ -37,6 +43,11 @@ class ModelConfig:
     load_in_8bit: bool = field(default=False, metadata={"help": "Load model in 8-bit mode"})
     torch_dtype: Optional[str] = field(default=None, metadata={"help": "The torch dtype for the pre-trained model"})
     device_map: str = field(default="auto", metadata={"help": "The device map for the pre-trained model"})
+    host: str = field(default="0.0.0.0", metadata={"help": "Address the model server listens on"})
+    port: int = field(default=5000, metadata={"help": "Port the model server listens on, the local rank is added to it"})
+    metrics_port: int = field(default=0, metadata={"help": "Port the Prometheus metrics are served on, they are not served if 0"})
+    workers: int = field(default=1, metadata={"help": "Number of model server processes that share the GPUs, each loads its own copy of the model"})
+    download_workers: int = field(default=1, metadata={"help": "Number of model files downloaded concurrently when remote files are allowed"})
 
     # Method to process additional arguments
     def process_additional_args(self, addt_args: List[str]):
@@ -78,9 +89,217 @@ args, additional_args = parser.parse_args_into_dataclasses(
 
 args.process_additional_args(additional_args)
 
+local_rank = int(os.environ.get("LOCAL_RANK", 0)) # Default to 0 if not set
+port = int(args.port) + local_rank # Adjust port based on local rank
+
+DRAIN_POLL_INTERVAL = 0.5 # Seconds between the checks of the in-flight requests while draining
+
+class DrainState:
+    """
+    Tracks the in-flight inference requests of all server processes in the pod, so that the pod
+    can stop accepting new requests and wait for the in-flight ones to finish before it is terminated.
+    The uvicorn workers and the accelerate ranks are separate processes, so the state is shared
+    through a directory of the pod: a marker file that puts all processes into draining and a file
+    per process with its number of in-flight requests.
+    """
+    def __init__(self, state_dir):
+        self.state_dir = state_dir
+        os.makedirs(state_dir, exist_ok=True)
+        self.drain_file = os.path.join(state_dir, "draining")
+        self.in_flight_file = os.path.join(state_dir, f"in-flight-{os.getpid()}")
+        self.in_flight = 0
+        self.lock = threading.Lock()
+        self._write_in_flight()
+
+    @property
+    def draining(self):
+        return os.path.exists(self.drain_file)
+
+    def _write_in_flight(self):
+        # The caller holds the lock. The file is replaced atomically, so that it is never read half-written.
+        tmp_file = self.in_flight_file + ".tmp"
+        with open(tmp_file, "w") as f:
+            f.write(str(self.in_flight))
+        os.replace(tmp_file, self.in_flight_file)
+
+    def start_request(self):
+        with self.lock:
+            # The request is counted before the marker is checked, so that a concurrent drain either waits
+            # for it or the request is rejected.
+            self.in_flight += 1
+            self._write_in_flight()
+            if self.draining:
+                self.in_flight -= 1
+                self._write_in_flight()
+                return False
+            return True
+
+    def finish_request(self):
+        with self.lock:
+            self.in_flight -= 1
+            self._write_in_flight()
+
+    def pod_in_flight(self):
+        total = 0
+        for name in os.listdir(self.state_dir):
+            if not name.startswith("in-flight-") or name.endswith(".tmp"):
+                continue
+            try:
+                # The requests of a process that has exited are not in flight anymore.
+                if not psutil.pid_exists(int(name[len("in-flight-"):])):
+                    continue
+                with open(os.path.join(self.state_dir, name)) as f:
+                    total += int(f.read() or 0)
+            except (OSError, ValueError):
+                continue
+        return total
+
+    def drain(self, timeout):
+        open(self.drain_file, "a").close()
+        deadline = time.monotonic() + timeout
+        while self.pod_in_flight() > 0:
+            if time.monotonic() >= deadline:
+                return False
+            time.sleep(DRAIN_POLL_INTERVAL)
+        return True
+
+drain_state = DrainState(os.environ.get("DRAIN_STATE_DIR", "/tmp/kaito-drain"))
+
+RETRIEVAL_TIMEOUT = 10 # Seconds the embedding sidecar and the vector store are given to answer
+
+class Retriever:
+    """
+    Retrieves the documents of a prompt for the RAG setup of the workspace. The prompt is embedded by the
+    embedding sidecar, and the closest documents are queried from the vector store and prepended to the prompt.
+    """
+    def __init__(self, embedding_endpoint, vector_store_url, collection=None, api_key=None, top_k=5):
+        self.embedding_endpoint = embedding_endpoint.rstrip('/')
+        self.vector_store_url = vector_store_url.rstrip('/')
+        self.collection = collection
+        self.api_key = api_key
+        self.top_k = top_k
+
+    def _post(self, url, body, headers=None):
+        request = urllib.request.Request(url, data=json.dumps(body).encode(), method="POST",
+                                         headers={"Content-Type": "application/json", **(headers or {})})
+        with urllib.request.urlopen(request, timeout=RETRIEVAL_TIMEOUT) as response:
+            return json.load(response)
+
+    def retrieve(self, text):
+        embedding = self._post(f"{self.embedding_endpoint}/embed", {"text": text})["embedding"]
+        query = {"vector": embedding, "top_k": self.top_k}
+        if self.collection:
+            query["collection"] = self.collection
+        headers = {"api-key": self.api_key} if self.api_key else None
+        return self._post(f"{self.vector_store_url}/query", query, headers).get("documents", [])
+
+    def augment(self, prompt):
+        documents = self.retrieve(prompt)
+        if not documents:
+            return prompt
+        context = "\n".join(documents)
+        return f"Context:\n{context}\n\nQuestion: {prompt}"
+
+def load_retriever():
+    """
+    Returns the retriever of the RAG setup, or None if the workspace does not specify RAG.
+    """
+    embedding_endpoint = os.environ.get("EMBEDDING_ENDPOINT")
+    vector_store_url = os.environ.get("VECTOR_STORE_URL")
+    if not embedding_endpoint or not vector_store_url:
+        return None
+    return Retriever(embedding_endpoint, vector_store_url, os.environ.get("VECTOR_STORE_COLLECTION"),
+                     os.environ.get("VECTOR_STORE_API_KEY"), int(os.environ.get("RAG_TOP_K", 5)))
+
+retriever = load_retriever()
+
+def augment_prompt(prompt):
+    """
+    Prepends the retrieved documents to the prompt if the workspace specifies RAG.
+    """
+    if retriever is None:
+        return prompt
+    try:
+        return retriever.augment(prompt)
+    except Exception as e:
+        raise HTTPException(status_code=502, detail=f"Failed to retrieve the documents: {e}")
+
+def render_prometheus_metrics():
+    """
+    Renders the metrics of the model server processes of the pod in the Prometheus text format.
+    """
+    lines = [
+        "# HELP kaito_inference_requests_in_flight The inference requests the model server processes of the pod are serving.",
+        "# TYPE kaito_inference_requests_in_flight gauge",
+        f"kaito_inference_requests_in_flight {drain_state.pod_in_flight()}",
+        "# HELP kaito_inference_draining Whether the model server stopped accepting requests before the pod is terminated.",
+        "# TYPE kaito_inference_draining gauge",
+        f"kaito_inference_draining {int(drain_state.draining)}",
+    ]
+    if torch.cuda.is_available():
+        gpus = GPUtil.getGPUs()
+        gpu_metrics = [
+            ("kaito_gpu_utilization_ratio", "The utilization of the GPU.", lambda gpu: gpu.load),
+            ("kaito_gpu_memory_used_bytes", "The GPU memory in use.", lambda gpu: gpu.memoryUsed * 1024 ** 2),
+            ("kaito_gpu_memory_total_bytes", "The total GPU memory.", lambda gpu: gpu.memoryTotal * 1024 ** 2),
+            ("kaito_gpu_temperature_celsius", "The temperature of the GPU.", lambda gpu: gpu.temperature),
+        ]
+        for name, help_text, value in gpu_metrics:
+            lines.append(f"# HELP {name} {help_text}")
+            lines.append(f"# TYPE {name} gauge")
+            lines.extend(f'{name}{{gpu="{gpu.id}",uuid="{gpu.uuid}"}} {value(gpu)}' for gpu in gpus)
+    return "\n".join(lines) + "\n"
+
+class MetricsHandler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        if self.path.split("?")[0] != "/metrics":
+            self.send_error(404)
+            return
+        try:
+            body = render_prometheus_metrics().encode()
+        except Exception as e:
+            self.send_error(500, str(e))
+            return
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, format, *args):
+        pass # The metrics are scraped periodically, the scrapes are not logged.
+
+def serve_metrics(metrics_port):
+    """
+    Serves the Prometheus metrics on their own port in a background thread, so that the scrapes
+    do not compete with the inference requests.
+    """
+    server = ThreadingHTTPServer(('0.0.0.0', metrics_port), MetricsHandler)
+    threading.Thread(target=server.serve_forever, daemon=True).start()
+
+# Only the first rank serves the metrics of the pod, in the parent process if there are multiple workers.
+if __name__ == "__main__" and int(args.metrics_port) and local_rank == 0:
+    serve_metrics(int(args.metrics_port))
+
+# The parent process only supervises the workers, each worker imports this module and loads its own copy of the model.
+if __name__ == "__main__" and int(args.workers) > 1:
+    uvicorn.run("inference_api:app", host=args.host, port=port, workers=int(args.workers))
+    raise SystemExit(0)
+
 model_args = asdict(args)
 model_args["local_files_only"] = not model_args.pop('allow_remote_files')
 model_pipeline = model_args.pop('pipeline')
+model_args.pop('workers')
+model_args.pop('host')
+model_args.pop('port')
+model_args.pop('metrics_port')
+model_args.pop('download_workers')
+
+# The files of a model on the Hub, e.g., the shards of a large model, are downloaded concurrently into the cache,
+# from_pretrained then loads them from the cache instead of downloading them one at a time.
+if not model_args["local_files_only"] and args.download_workers > 1 and not os.path.isdir(args.pretrained_model_name_or_path):
+    snapshot_download(repo_id=args.pretrained_model_name_or_path, revision=args.revision, cache_dir=args.cache_dir,
+                      max_workers=args.download_workers)
 
 app = FastAPI()
 tokenizer = AutoTokenizer.from_pretrained(**model_args)
@@ -110,6 +329,19 @@ try:
 except Exception as e:
     default_generate_config = {}
 
+INFERENCE_PATHS = ("/chat",)
+
+@app.middleware("http")
+async def track_inference_requests(request: Request, call_next):
+    if request.url.path not in INFERENCE_PATHS:
+        return await call_next(request)
+    if not drain_state.start_request():
+        return JSONResponse(status_code=503, content={"detail": "Server is draining"})
+    try:
+        return await call_next(request)
+    finally:
+        drain_state.finish_request()
+
 class HomeResponse(BaseModel):
     message: str = Field(..., example="Server is running")
 @app.get('/', response_model=HomeResponse, summary="Home Endpoint")
@@ -155,12 +387,32 @@ class HealthStatus(BaseModel):
     }
 )
 def health_check():
+    if drain_state.draining:
+        # Fail the readiness probe so that no new requests are routed to the draining server.
+        raise HTTPException(status_code=503, detail="Server is draining")
     if not model:
         raise HTTPException(status_code=500, detail="Model not initialized")
     if not pipeline:
         raise HTTPException(status_code=500, detail="Pipeline not initialized")
     return {"status": "Healthy"}
 
+class DrainStatus(BaseModel):
+    status: str = Field(..., example="Drained")
+    in_flight: int = Field(..., example=0)
+@app.get(
+    "/drain",
+    response_model=DrainStatus,
+    summary="Drain Endpoint",
+)
+def drain(timeout: float = 300):
+    """
+    Stops accepting new inference requests in all server processes of the pod and waits up to timeout
+    seconds for their in-flight requests to finish. It is called by the preStop hook of the pod before
+    the server is terminated.
+    """
+    drained = drain_state.drain(timeout)
+    return {"status": "Drained" if drained else "Timeout", "in_flight": drain_state.pod_in_flight()}
+
 class GenerateKwargs(BaseModel):
     max_length: int = 200 # Length of input prompt+max_new_tokens
     min_length: int = 0
@@ -303,7 +555,7 @@ def generate_text(
         if not request_model.prompt:
             raise HTTPException(status_code=400, detail="Text generation parameter prompt required")
         sequences = pipeline(
-            request_model.prompt,
+            augment_prompt(request_model.prompt),
             # return_tensors=request_model.return_tensors,
             # return_text=request_model.return_text,
             return_full_text=request_model.return_full_text,
@@ -324,8 +576,14 @@ def generate_text(
         if not request_model.messages:
             raise HTTPException(status_code=400, detail="Conversational parameter messages required")
 
+        messages = request_model.messages_to_dict_list()
+        # The documents are retrieved for the latest message of the user.
+        for message in reversed(messages):
+            if message["role"] == "user":
+                message["content"] = augment_prompt(message["content"])
+                break
         response = pipeline(
-            request_model.messages_to_dict_list(),
+            messages,
             clean_up_tokenization_spaces=request_model.clean_up_tokenization_spaces,
             **generate_kwargs
         )
@@ -429,6 +687,4 @@ def get_metrics():
         raise HTTPException(status_code=500, detail=str(e))
 
 if __name__ == "__main__":
-    local_rank = int(os.environ.get("LOCAL_RANK", 0)) # Default to 0 if not set
-    port = 5000 + local_rank # Adjust port based on local rank
-    uvicorn.run(app=app, host='0.0.0.0', port=port)
\ No newline at end of file
+    uvicorn.run(app=app, host=args.host, port=port)
\ No newline at end of file
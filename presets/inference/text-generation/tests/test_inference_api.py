@@ -16,7 +16,9 @@ sys.path.append(parent_dir)
     {"pipeline": "text-generation", "model_path": "stanford-crfm/alias-gpt2-small-x21"},
     {"pipeline": "conversational", "model_path": "stanford-crfm/alias-gpt2-small-x21"},
 ])
-def configured_app(request):
+def configured_app(request, tmp_path, monkeypatch):
+    # Each test drains its own server.
+    monkeypatch.setenv("DRAIN_STATE_DIR", str(tmp_path))
     original_argv = sys.argv.copy()
     # Use request.param to set correct test arguments for each configuration
     test_args = [
@@ -98,6 +100,37 @@ def test_missing_prompt(configured_app):
     assert response.status_code == 400  # Expecting a Bad Request response due to missing prompt
     assert "Text generation parameter prompt required" in response.json().get("detail", "")
 
+def test_retriever_augments_prompt(configured_app):
+    from inference_api import Retriever
+    retriever = Retriever("http://localhost:5001/", "http://vector-db:6333", "docs", "secret", 2)
+    calls = []
+    def post(url, body, headers=None):
+        calls.append((url, body, headers))
+        if url.endswith("/embed"):
+            return {"embedding": [0.1, 0.2]}
+        return {"documents": ["Mayonnaise is an emulsion.", "It needs egg yolks."]}
+
+    with patch.object(retriever, "_post", side_effect=post):
+        prompt = retriever.augment("Do you have mayonnaise recipes?")
+
+    assert calls == [
+        ("http://localhost:5001/embed", {"text": "Do you have mayonnaise recipes?"}, None),
+        ("http://vector-db:6333/query", {"vector": [0.1, 0.2], "top_k": 2, "collection": "docs"}, {"api-key": "secret"}),
+    ]
+    assert prompt == "Context:\nMayonnaise is an emulsion.\nIt needs egg yolks.\n\nQuestion: Do you have mayonnaise recipes?"
+
+def test_retrieval_failure(configured_app, monkeypatch):
+    if configured_app.test_config['pipeline'] != 'text-generation':
+        pytest.skip("Skipping non-text-generation tests")
+    import inference_api
+    failing_retriever = inference_api.Retriever("http://localhost:5001", "http://vector-db:6333")
+    monkeypatch.setattr(inference_api, "retriever", failing_retriever)
+    client = TestClient(configured_app)
+    with patch.object(failing_retriever, "_post", side_effect=OSError("connection refused")):
+        response = client.post("/chat", json={"prompt": "Hello, world!"})
+    assert response.status_code == 502
+    assert "Failed to retrieve the documents: connection refused" in response.json().get("detail", "")
+
 def test_read_main(configured_app):
     client = TestClient(configured_app)
     response = client.get("/")
@@ -110,6 +143,40 @@ def test_health_check(configured_app):
     assert response.status_code == 200
     assert response.json() == {"status": "Healthy"}
 
+def test_drain(configured_app):
+    client = TestClient(configured_app)
+    response = client.get("/drain", params={"timeout": 1})
+    assert response.status_code == 200
+    assert response.json() == {"status": "Drained", "in_flight": 0}
+
+    # A draining server rejects new requests and fails the health check.
+    response = client.post("/chat", json={"prompt": "Hello", "return_full_text": False})
+    assert response.status_code == 503
+    response = client.get("/healthz")
+    assert response.status_code == 503
+
+def test_drain_waits_for_other_processes(configured_app, tmp_path):
+    import inference_api
+
+    # Another worker process of the pod still serves a request.
+    other_worker = tmp_path / f"in-flight-{inference_api.os.getppid()}"
+    other_worker.write_text("1")
+    client = TestClient(configured_app)
+    response = client.get("/drain", params={"timeout": 1})
+    assert response.json() == {"status": "Timeout", "in_flight": 1}
+
+    other_worker.write_text("0")
+    response = client.get("/drain", params={"timeout": 1})
+    assert response.json() == {"status": "Drained", "in_flight": 0}
+
+def test_prometheus_metrics(configured_app):
+    import inference_api
+
+    metrics = inference_api.render_prometheus_metrics()
+    assert "# TYPE kaito_inference_requests_in_flight gauge\n" in metrics
+    assert "kaito_inference_requests_in_flight 0\n" in metrics
+    assert "kaito_inference_draining 0\n" in metrics
+
 def test_get_metrics(configured_app):
     client = TestClient(configured_app)
     response = client.get("/metrics")
@@ -15,18 +15,22 @@ func init() {
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetFalcon7BModel,
 		Instance: &falconA,
+		Versions: PresetFalconVersions["Falcon7B"],
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetFalcon7BInstructModel,
 		Instance: &falconB,
+		Versions: PresetFalconVersions["Falcon7BInstruct"],
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetFalcon40BModel,
 		Instance: &falconC,
+		Versions: PresetFalconVersions["Falcon40B"],
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetFalcon40BInstructModel,
 		Instance: &falconD,
+		Versions: PresetFalconVersions["Falcon40BInstruct"],
 	})
 }
 
@@ -42,6 +46,13 @@ var (
 		"Falcon40B":         "0.0.5",
 		"Falcon40BInstruct": "0.0.5",
 	}
+	// PresetFalconVersions are the earlier published image tags that can be pinned, see supported_models.yaml.
+	PresetFalconVersions = map[string][]string{
+		"Falcon7B":          {"0.0.1", "0.0.2", "0.0.3"},
+		"Falcon7BInstruct":  {"0.0.1", "0.0.2", "0.0.3"},
+		"Falcon40B":         {"0.0.1", "0.0.2", "0.0.3"},
+		"Falcon40BInstruct": {"0.0.1", "0.0.2", "0.0.3"},
+	}
 
 	baseCommandPresetFalcon = "accelerate launch"
 	falconRunParams         = map[string]string{
@@ -65,7 +76,10 @@ func (*falcon7b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            falconRunParams,
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
 		BaseCommand:               baseCommandPresetFalcon,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetFalconTagMap["Falcon7B"],
 	}
 }
@@ -81,6 +95,7 @@ func (*falcon7b) GetTuningParameters() *model.PresetParam {
 		//ModelRunPrams:             falconRunTuningParams, // TODO
 		ReadinessTimeout: time.Duration(30) * time.Minute,
 		BaseCommand:      baseCommandPresetFalcon,
+		MinDriverVersion: inference.DefaultMinDriverVersion,
 		Tag:              PresetFalconTagMap["Falcon7B"],
 	}
 }
@@ -107,7 +122,10 @@ func (*falcon7bInst) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            falconRunParams,
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
 		BaseCommand:               baseCommandPresetFalcon,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetFalconTagMap["Falcon7BInstruct"],
 	}
 
@@ -136,8 +154,13 @@ func (*falcon40b) GetInferenceParameters() *model.PresetParam {
 		PerGPUMemoryRequirement:   "0Gi", // We run Falcon using native vertical model parallel, no per GPU memory requirement.
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            falconRunParams,
+		MinHostMemory:             "96Gi", // The weights are loaded into host memory before they are spread across the GPUs.
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
+		StartupTimeout:            time.Duration(20) * time.Minute,
 		BaseCommand:               baseCommandPresetFalcon,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetFalconTagMap["Falcon40B"],
 	}
 
@@ -154,6 +177,7 @@ func (*falcon40b) GetTuningParameters() *model.PresetParam {
 		//ModelRunPrams:             falconRunTuningParams, // TODO
 		ReadinessTimeout: time.Duration(30) * time.Minute,
 		BaseCommand:      baseCommandPresetFalcon,
+		MinDriverVersion: inference.DefaultMinDriverVersion,
 		Tag:              PresetFalconTagMap["Falcon40B"],
 	}
 }
@@ -178,8 +202,13 @@ func (*falcon40bInst) GetInferenceParameters() *model.PresetParam {
 		PerGPUMemoryRequirement:   "0Gi", // We run Falcon using native vertical model parallel, no per GPU memory requirement.
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            falconRunParams,
+		MinHostMemory:             "96Gi", // The weights are loaded into host memory before they are spread across the GPUs.
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
+		StartupTimeout:            time.Duration(20) * time.Minute,
 		BaseCommand:               baseCommandPresetFalcon,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetFalconTagMap["Falcon40BInstruct"],
 	}
 }
@@ -15,23 +15,34 @@ func init() {
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "llama-2-7b",
 		Instance: &llama2A,
+		Aliases:  []string{"llama2-7b"},
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "llama-2-13b",
 		Instance: &llama2B,
+		Aliases:  []string{"llama2-13b"},
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "llama-2-70b",
 		Instance: &llama2C,
+		Aliases:  []string{"llama2-70b"},
 	})
 }
 
 var (
 	baseCommandPresetLlama = "cd /workspace/llama/llama-2 && torchrun"
-	llamaRunParams         = map[string]string{
+	// The llama images keep the weights next to the llama server rather than at the default path of the preset images.
+	llamaWeightsPath = "/workspace/llama/llama-2/weights"
+	llamaRunParams   = map[string]string{
 		"max_seq_len":    "512",
 		"max_batch_size": "8",
 	}
+	// The llama servers do not serve the requests of the kaito inference server of the transformers runtime.
+	llamaSmokeTest = &model.SmokeTest{
+		Path:        "/generate",
+		Body:        `{"prompts": ["Kubernetes is"], "parameters": {"max_gen_len": 32}}`,
+		ResultField: "results",
+	}
 )
 
 var llama2A llama2Text7b
@@ -49,8 +60,11 @@ func (*llama2Text7b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultTorchRunParams,
 		TorchRunRdzvParams:        inference.DefaultTorchRunRdzvParams,
 		ModelRunParams:            llamaRunParams,
+		WeightsPath:               llamaWeightsPath,
 		ReadinessTimeout:          time.Duration(10) * time.Minute,
 		BaseCommand:               baseCommandPresetLlama,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
+		SmokeTest:                 llamaSmokeTest,
 		WorldSize:                 1,
 		// Tag:  llama has private image access mode. The image tag is determined by the user.
 	}
@@ -81,8 +95,13 @@ func (*llama2Text13b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultTorchRunParams,
 		TorchRunRdzvParams:        inference.DefaultTorchRunRdzvParams,
 		ModelRunParams:            llamaRunParams,
+		WeightsPath:               llamaWeightsPath,
 		ReadinessTimeout:          time.Duration(20) * time.Minute,
+		StartupTimeout:            time.Duration(20) * time.Minute,
+		TerminationGracePeriod:    time.Duration(1) * time.Minute,
 		BaseCommand:               baseCommandPresetLlama,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
+		SmokeTest:                 llamaSmokeTest,
 		WorldSize:                 2,
 		// Tag:  llama has private image access mode. The image tag is determined by the user.
 	}
@@ -112,8 +131,15 @@ func (*llama2Text70b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultTorchRunParams,
 		TorchRunRdzvParams:        inference.DefaultTorchRunRdzvParams,
 		ModelRunParams:            llamaRunParams,
+		WeightsPath:               llamaWeightsPath,
+		MinHostMemory:             "160Gi", // Each rank loads its checkpoint shard into host memory before it is moved onto the GPU.
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		StartupTimeout:            time.Duration(30) * time.Minute,
+		TerminationGracePeriod:    time.Duration(2) * time.Minute,
+		LivenessConfig:            &model.LivenessConfig{Timeout: time.Duration(10) * time.Second, FailureThreshold: 12}, // The ranks answer slowly while they synchronize a large generation.
 		BaseCommand:               baseCommandPresetLlama,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
+		SmokeTest:                 llamaSmokeTest,
 		WorldSize:                 8,
 		// Tag:  llama has private image access mode. The image tag is determined by the user.
 	}
@@ -15,23 +15,34 @@ func init() {
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "llama-2-7b-chat",
 		Instance: &llama2chatA,
+		Aliases:  []string{"llama2-7b-chat"},
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "llama-2-13b-chat",
 		Instance: &llama2chatB,
+		Aliases:  []string{"llama2-13b-chat"},
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     "llama-2-70b-chat",
 		Instance: &llama2chatC,
+		Aliases:  []string{"llama2-70b-chat"},
 	})
 }
 
 var (
 	baseCommandPresetLlama = "cd /workspace/llama/llama-2 && torchrun"
-	llamaRunParams         = map[string]string{
+	// The llama images keep the weights next to the llama server rather than at the default path of the preset images.
+	llamaWeightsPath = "/workspace/llama/llama-2/weights"
+	llamaRunParams   = map[string]string{
 		"max_seq_len":    "512",
 		"max_batch_size": "8",
 	}
+	// The llama servers do not serve the requests of the kaito inference server of the transformers runtime.
+	llamaSmokeTest = &model.SmokeTest{
+		Path:        "/chat",
+		Body:        `{"input_data": {"input_string": [[{"role": "user", "content": "What is Kubernetes?"}]]}, "parameters": {"max_gen_len": 32}}`,
+		ResultField: "results",
+	}
 )
 
 var llama2chatA llama2Chat7b
@@ -49,8 +60,11 @@ func (*llama2Chat7b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultTorchRunParams,
 		TorchRunRdzvParams:        inference.DefaultTorchRunRdzvParams,
 		ModelRunParams:            llamaRunParams,
+		WeightsPath:               llamaWeightsPath,
 		ReadinessTimeout:          time.Duration(10) * time.Minute,
 		BaseCommand:               baseCommandPresetLlama,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
+		SmokeTest:                 llamaSmokeTest,
 		WorldSize:                 1,
 		// Tag:  llama has private image access mode. The image tag is determined by the user.
 	}
@@ -80,8 +94,13 @@ func (*llama2Chat13b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultTorchRunParams,
 		TorchRunRdzvParams:        inference.DefaultTorchRunRdzvParams,
 		ModelRunParams:            llamaRunParams,
+		WeightsPath:               llamaWeightsPath,
 		ReadinessTimeout:          time.Duration(20) * time.Minute,
+		StartupTimeout:            time.Duration(20) * time.Minute,
+		TerminationGracePeriod:    time.Duration(1) * time.Minute,
 		BaseCommand:               baseCommandPresetLlama,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
+		SmokeTest:                 llamaSmokeTest,
 		WorldSize:                 2,
 		// Tag:  llama has private image access mode. The image tag is determined by the user.
 	}
@@ -111,8 +130,15 @@ func (*llama2Chat70b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultTorchRunParams,
 		TorchRunRdzvParams:        inference.DefaultTorchRunRdzvParams,
 		ModelRunParams:            llamaRunParams,
+		WeightsPath:               llamaWeightsPath,
+		MinHostMemory:             "160Gi", // Each rank loads its checkpoint shard into host memory before it is moved onto the GPU.
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		StartupTimeout:            time.Duration(30) * time.Minute,
+		TerminationGracePeriod:    time.Duration(2) * time.Minute,
+		LivenessConfig:            &model.LivenessConfig{Timeout: time.Duration(10) * time.Second, FailureThreshold: 12}, // The ranks answer slowly while they synchronize a large generation.
 		BaseCommand:               baseCommandPresetLlama,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
+		SmokeTest:                 llamaSmokeTest,
 		WorldSize:                 8,
 		// Tag:  llama has private image access mode. The image tag is determined by the user.
 	}
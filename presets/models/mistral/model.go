@@ -15,10 +15,12 @@ func init() {
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetMistral7BModel,
 		Instance: &mistralA,
+		Versions: PresetMistralVersions["Mistral7B"],
 	})
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetMistral7BInstructModel,
 		Instance: &mistralB,
+		Versions: PresetMistralVersions["Mistral7BInstruct"],
 	})
 }
 
@@ -30,6 +32,11 @@ var (
 		"Mistral7B":         "0.0.4",
 		"Mistral7BInstruct": "0.0.4",
 	}
+	// PresetMistralVersions are the earlier published image tags that can be pinned, see supported_models.yaml.
+	PresetMistralVersions = map[string][]string{
+		"Mistral7B":         {"0.0.1", "0.0.2", "0.0.3"},
+		"Mistral7BInstruct": {"0.0.1", "0.0.2", "0.0.3"},
+	}
 
 	baseCommandPresetMistral = "accelerate launch"
 	mistralRunParams         = map[string]string{
@@ -53,7 +60,10 @@ func (*mistral7b) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            mistralRunParams,
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
 		BaseCommand:               baseCommandPresetMistral,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetMistralTagMap["Mistral7B"],
 	}
 
@@ -70,6 +80,7 @@ func (*mistral7b) GetTuningParameters() *model.PresetParam {
 		//ModelRunParams:            mistralRunParams,
 		ReadinessTimeout: time.Duration(30) * time.Minute,
 		BaseCommand:      baseCommandPresetMistral,
+		MinDriverVersion: inference.DefaultMinDriverVersion,
 		Tag:              PresetMistralTagMap["Mistral7B"],
 	}
 }
@@ -96,7 +107,10 @@ func (*mistral7bInst) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            mistralRunParams,
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
 		BaseCommand:               baseCommandPresetMistral,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetMistralTagMap["Mistral7BInstruct"],
 	}
 
@@ -15,6 +15,7 @@ func init() {
 	plugin.KaitoModelRegister.Register(&plugin.Registration{
 		Name:     PresetPhi2Model,
 		Instance: &phiA,
+		Versions: PresetPhiVersions["Phi2"],
 	})
 }
 
@@ -24,6 +25,10 @@ var (
 	PresetPhiTagMap = map[string]string{
 		"Phi2": "0.0.3",
 	}
+	// PresetPhiVersions are the earlier published image tags that can be pinned, see supported_models.yaml.
+	PresetPhiVersions = map[string][]string{
+		"Phi2": {"0.0.1", "0.0.2"},
+	}
 
 	baseCommandPresetPhi = "accelerate launch"
 	phiRunParams         = map[string]string{
@@ -47,7 +52,10 @@ func (*phi2) GetInferenceParameters() *model.PresetParam {
 		TorchRunParams:            inference.DefaultAccelerateParams,
 		ModelRunParams:            phiRunParams,
 		ReadinessTimeout:          time.Duration(30) * time.Minute,
+		SupportsDrain:             true,
+		MetricsPort:               inference.DefaultMetricsPort,
 		BaseCommand:               baseCommandPresetPhi,
+		MinDriverVersion:          inference.DefaultMinDriverVersion,
 		Tag:                       PresetPhiTagMap["Phi2"],
 	}
 }
@@ -63,6 +71,7 @@ func (*phi2) GetTuningParameters() *model.PresetParam {
 		// ModelRunParams:            phiRunParams,
 		ReadinessTimeout: time.Duration(30) * time.Minute,
 		BaseCommand:      baseCommandPresetPhi,
+		MinDriverVersion: inference.DefaultMinDriverVersion,
 		Tag:              PresetPhiTagMap["Phi2"],
 	}
 }